@@ -0,0 +1,57 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/urfave/cli"
+)
+
+// ACL prints who can decrypt the given secret and flags any difference
+// between the recipients of the secret and the keys it's encrypted for
+func (s *Action) ACL(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return exitError(ExitUsage, "provide a secret name")
+	}
+
+	acl, err := s.Store.ACL(name)
+	if err != nil {
+		return err
+	}
+
+	header := acl.Name
+	if acl.Override {
+		header += " (recipient override)"
+	}
+	fmt.Println(color.GreenString(header))
+	for _, e := range acl.Recipients {
+		line := " - " + formatRecipient(recipient{ID: e.ID, Key: e.Key})
+		if !e.Encrypted {
+			line += color.RedString(" [not encrypted for this key]")
+		}
+		fmt.Println(line)
+	}
+	if len(acl.Extra) > 0 {
+		fmt.Println(color.RedString("Encrypted for keys that are not recipients:"))
+		for _, id := range acl.Extra {
+			fmt.Println(" - " + formatKeyID(id))
+		}
+	}
+
+	if acl.Diverges() {
+		return fmt.Errorf("%s is not encrypted for exactly its recipients. Run `gopass fsck --repair` to fix it", name)
+	}
+	return nil
+}
+
+// formatKeyID returns the key ID along with the owner of the key, if it's in
+// the keyring
+func formatKeyID(id string) string {
+	kl, err := gpg.ListPublicKeys(id)
+	if err != nil || len(kl) < 1 {
+		return "0x" + id + " [key missing]"
+	}
+	return kl[0].OneLine()
+}
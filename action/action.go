@@ -9,8 +9,9 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/ghodss/yaml"
+	"github.com/justwatchcom/gopass/audit"
 	"github.com/justwatchcom/gopass/fsutil"
-	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/hooks"
 	"github.com/justwatchcom/gopass/password"
 )
 
@@ -18,14 +19,16 @@ import (
 type Action struct {
 	Name  string
 	Store *password.RootStore
+	// profile is the name of the active profile, profileKey its key
+	profile    string
+	profileKey string
+	// color is true if the output is colored, see UseColor
+	color bool
 }
 
 // New returns a new Action wrapper
 func New(v string) *Action {
-	if gdb := os.Getenv("GOPASS_DEBUG"); gdb == "true" {
-		gpg.Debug = true
-	}
-	if nc := os.Getenv("GOPASS_NOCOLOR"); nc == "true" {
+	if mode, err := colorMode("", false, os.Getenv); err == nil && mode == ColorNever {
 		color.NoColor = true
 	}
 	name := "gopass"
@@ -38,11 +41,21 @@ func New(v string) *Action {
 	// try to read config (if it exists)
 	if cfg, err := newFromFile(configFile()); err == nil && cfg != nil {
 		cfg.ImportFunc = askForKeyImport
+		cfg.FsckFunc = askForConfirmation
+		cfg.BulkFunc = askForBulkConfirmation
+		cfg.SetPassphraseFunc(promptPassphrase)
 		cfg.Version = v
-		return &Action{
+		if cfg.HookDir != "" {
+			hooks.Dir = fsutil.CleanPath(cfg.HookDir)
+		}
+		audit.File = cfg.AuditLog
+		s := &Action{
 			Name:  name,
 			Store: cfg,
+			color: !color.NoColor,
 		}
+		s.useOSKeyring()
+		return s
 	}
 
 	cfg, err := password.NewRootStore(pwDir)
@@ -52,11 +65,16 @@ func New(v string) *Action {
 
 	cfg.ImportFunc = askForKeyImport
 	cfg.FsckFunc = askForConfirmation
+	cfg.BulkFunc = askForBulkConfirmation
+	cfg.SetPassphraseFunc(promptPassphrase)
 	cfg.Version = v
-	return &Action{
+	s := &Action{
 		Name:  name,
 		Store: cfg,
+		color: !color.NoColor,
 	}
+	s.useOSKeyring()
+	return s
 }
 
 // newFromFile creates a new RootStore instance by unmarsahling a config file.
@@ -1,9 +1,12 @@
 package action
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestPwStoreDir(t *testing.T) {
@@ -22,3 +25,115 @@ func TestPwStoreDir(t *testing.T) {
 		}
 	}
 }
+
+func TestEditorArgs(t *testing.T) {
+	for _, tc := range []struct {
+		in  []string
+		out []string
+	}{
+		{[]string{"vim"}, []string{"vim"}},
+		{[]string{"code"}, []string{"code", "--wait"}},
+		{[]string{"/usr/bin/subl", "-w"}, []string{"/usr/bin/subl", "-w"}},
+		{[]string{"gvim", "--nofork"}, []string{"gvim", "--nofork"}},
+	} {
+		got := editorArgs(tc.in)
+		if strings.Join(got, " ") != strings.Join(tc.out, " ") {
+			t.Errorf("Mismatch for %+v: %+v != %+v", tc.in, got, tc.out)
+		}
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	td, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(td)
+	}()
+
+	for _, bin := range []string{"nano", "code"} {
+		if err := ioutil.WriteFile(filepath.Join(td, bin), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("Failed to write %s: %s", bin, err)
+		}
+	}
+
+	env := map[string]string{"PATH": td}
+	for _, e := range editorEnv {
+		env[e] = ""
+	}
+	for k, v := range env {
+		old, found := os.LookupEnv(k)
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatalf("Failed to set %s: %s", k, err)
+		}
+		defer func(k, old string, found bool) {
+			if found {
+				_ = os.Setenv(k, old)
+				return
+			}
+			_ = os.Unsetenv(k)
+		}(k, old, found)
+	}
+
+	for _, tc := range []struct {
+		env  map[string]string
+		cmd  string
+		args []string
+	}{
+		// nothing set, first fallback in PATH
+		{map[string]string{}, "nano", []string{}},
+		// $VISUAL is used if $EDITOR is unset
+		{map[string]string{"VISUAL": "code"}, "code", []string{"--wait"}},
+		// $EDITOR takes precedence over $VISUAL
+		{map[string]string{"EDITOR": "nano -R", "VISUAL": "code"}, "nano", []string{"-R"}},
+		// $GOPASS_EDITOR takes precedence over everything
+		{map[string]string{"GOPASS_EDITOR": "code", "EDITOR": "nano"}, "code", []string{"--wait"}},
+		// editors not in PATH are skipped
+		{map[string]string{"GOPASS_EDITOR": "emacs", "EDITOR": "code"}, "code", []string{"--wait"}},
+	} {
+		for _, e := range editorEnv {
+			_ = os.Setenv(e, tc.env[e])
+		}
+		cmd, args, err := editorCommand()
+		if err != nil {
+			t.Errorf("Failed to resolve editor for %+v: %s", tc.env, err)
+			continue
+		}
+		if cmd != tc.cmd || strings.Join(args, " ") != strings.Join(tc.args, " ") {
+			t.Errorf("Mismatch for %+v: %s %+v != %s %+v", tc.env, cmd, args, tc.cmd, tc.args)
+		}
+	}
+
+	if err := os.Setenv("PATH", ""); err != nil {
+		t.Fatalf("Failed to set PATH: %s", err)
+	}
+	if _, _, err := editorCommand(); err == nil {
+		t.Errorf("Should fail without any editor in PATH")
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2017, 5, 3, 14, 0, 0, 0, time.Local)
+	for in, out := range map[string]time.Time{
+		"2017-06-01 12:30":     time.Date(2017, 6, 1, 12, 30, 0, 0, time.Local),
+		"2017-06-01":           time.Date(2017, 6, 1, 0, 0, 0, 0, time.Local),
+		"2017-06-01T12:30:00Z": time.Date(2017, 6, 1, 12, 30, 0, 0, time.UTC),
+		"12h":                  now.Add(12 * time.Hour),
+		" 7d ":                 now.AddDate(0, 0, 7),
+	} {
+		got, err := parseDate(in, now)
+		if err != nil {
+			t.Errorf("Failed to parse %s: %s", in, err)
+			continue
+		}
+		if !got.Equal(out) {
+			t.Errorf("Mismatch for %s: %s != %s", in, got, out)
+		}
+	}
+	for _, in := range []string{"", "tomorrow", "7 days"} {
+		if _, err := parseDate(in, now); err == nil {
+			t.Errorf("Parsed invalid date %s", in)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// Alias creates a secret that refers to an existing secret
+func (s *Action) Alias(c *cli.Context) error {
+	if len(c.Args()) != 2 {
+		return exitError(ExitUsage, "Usage: gopass alias name target")
+	}
+	name := c.Args()[0]
+	target := c.Args()[1]
+
+	found, err := s.Store.Exists(name)
+	if err != nil {
+		return fmt.Errorf("failed to see if %s exists: %s", name, err)
+	}
+	if found && !c.Bool("force") {
+		if !askForConfirmation(fmt.Sprintf("An entry already exists for %s. Overwrite it?", name)) {
+			return fmt.Errorf("not overwriting your current secret")
+		}
+	}
+
+	if err := s.Store.SetAlias(name, target, s.confirmRecipients); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is now an alias of %s\n", color.YellowString(name), color.YellowString(target))
+	return nil
+}
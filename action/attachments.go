@@ -0,0 +1,77 @@
+package action
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// AttachmentsList lists all attachments of a secret
+func (s *Action) AttachmentsList(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return exitError(ExitUsage, "Usage: gopass attachments secret")
+	}
+
+	atts, err := s.Store.ListAttachments(name)
+	if err != nil {
+		return err
+	}
+	if len(atts) < 1 {
+		fmt.Printf("%s has no attachments\n", name)
+		return nil
+	}
+	for _, att := range atts {
+		fmt.Println(att)
+	}
+	return nil
+}
+
+// AttachmentsAdd adds a file as an attachment to a secret
+func (s *Action) AttachmentsAdd(c *cli.Context) error {
+	name := c.Args().First()
+	file := c.Args().Get(1)
+	if name == "" || file == "" {
+		return exitError(ExitUsage, "Usage: gopass attachments add secret file")
+	}
+
+	s.Store.SetNoCompress(c.Bool("no-compress"))
+
+	att := c.String("name")
+	if att == "" {
+		att = filepath.Base(file)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %s", file, err)
+	}
+
+	if err := s.Store.AddAttachment(name, att, data); err != nil {
+		return err
+	}
+
+	fmt.Printf("Attached %s to %s\n", color.YellowString(att), color.YellowString(name))
+	return nil
+}
+
+// AttachmentsShow writes the plaintext of an attachment to stdout
+func (s *Action) AttachmentsShow(c *cli.Context) error {
+	name := c.Args().First()
+	att := c.Args().Get(1)
+	if name == "" || att == "" {
+		return exitError(ExitUsage, "Usage: gopass attachments show secret attachment")
+	}
+
+	data, err := s.Store.GetAttachment(name, att)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(data)
+	return err
+}
@@ -0,0 +1,42 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// AuditKey lists every secret of all stores the given key can decrypt. The
+// recipients are read from the ciphertexts, so this works without being
+// able to decrypt the secrets.
+func (s *Action) AuditKey(c *cli.Context) error {
+	key := c.Args().First()
+	if key == "" || len(c.Args()) > 1 {
+		return exitError(ExitUsage, "Usage: %s audit key <fingerprint>", s.Name)
+	}
+
+	a, err := s.Store.AuditKey(key)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		buf, err := json.MarshalIndent(a, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit: %s", err)
+		}
+		fmt.Println(string(buf))
+		return nil
+	}
+
+	for _, name := range a.Readable {
+		fmt.Println(name)
+	}
+	for _, name := range a.Anonymous {
+		fmt.Println(color.YellowString("%s", name) + " (possibly readable, anonymous recipients)")
+	}
+	fmt.Printf("%d secrets readable by %s, %d possibly readable (anonymous)\n", len(a.Readable), a.Key, len(a.Anonymous))
+	return nil
+}
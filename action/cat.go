@@ -0,0 +1,120 @@
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli"
+)
+
+// sniffLen is the amount of content checked for binary data before it's
+// written to a terminal
+const sniffLen = 8000
+
+// CatSecret writes the exact content of a secret to stdout. Binary content
+// isn't written to a terminal unless forced.
+func (s *Action) CatSecret(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" || len(c.Args()) > 1 {
+		return exitError(ExitUsage, "Usage: %s cat [--force] <name>", s.Name)
+	}
+
+	var w io.Writer = os.Stdout
+	var guard *binaryGuard
+	if !c.Bool("force") && isatty.IsTerminal(os.Stdout.Fd()) {
+		guard = &binaryGuard{w: os.Stdout}
+		w = guard
+	}
+
+	err := s.Cat(name, w)
+	if guard == nil {
+		return err
+	}
+	if err == nil {
+		err = guard.Flush()
+	}
+	if guard.binary {
+		return fmt.Errorf("%s holds binary content, not writing it to the terminal. Redirect the output or use --force", name)
+	}
+	return err
+}
+
+// Cat writes the exact content of a secret to w, without parsing, masking or
+// adding a newline. The content is streamed from gpg where possible.
+func (s *Action) Cat(name string, w io.Writer) error {
+	if s.Store.IsDir(name) {
+		return exitError(ExitUsage, "%s is a folder", name)
+	}
+	return s.Store.GetTo(name, w)
+}
+
+// binaryGuard holds back the first sniffLen bytes written to it and only
+// passes them on to w if they don't look binary
+type binaryGuard struct {
+	w      io.Writer
+	buf    []byte
+	passed bool
+	binary bool
+}
+
+// Write implements io.Writer
+func (g *binaryGuard) Write(p []byte) (int, error) {
+	if g.binary {
+		return 0, errBinary
+	}
+	if g.passed {
+		return g.w.Write(p)
+	}
+	g.buf = append(g.buf, p...)
+	if len(g.buf) < sniffLen {
+		return len(p), nil
+	}
+	if err := g.Flush(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush decides on the content held back and writes it to w, unless it's
+// binary
+func (g *binaryGuard) Flush() error {
+	if g.binary {
+		return errBinary
+	}
+	if g.passed {
+		return nil
+	}
+	if isBinary(g.buf) {
+		g.binary = true
+		return errBinary
+	}
+	g.passed = true
+	_, err := g.w.Write(g.buf)
+	g.buf = nil
+	return err
+}
+
+// errBinary stops writing binary content to a terminal
+var errBinary = fmt.Errorf("binary content")
+
+// isBinary returns true if the given content has NUL bytes or isn't valid
+// UTF-8, ignoring a rune cut off at the end
+func isBinary(b []byte) bool {
+	if bytes.IndexByte(b, 0) >= 0 {
+		return true
+	}
+	// the content might continue after the held back part
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				b = b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return !utf8.Valid(b)
+}
@@ -0,0 +1,44 @@
+package action
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaryGuard(t *testing.T) {
+	// text is passed on once it's checked
+	buf := &bytes.Buffer{}
+	g := &binaryGuard{w: buf}
+	_, err := g.Write([]byte("-----BEGIN CERTIFICATE-----\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, buf.Len())
+	assert.NoError(t, g.Flush())
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----\n", buf.String())
+	_, err = g.Write([]byte("MIIB"))
+	assert.NoError(t, err)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----\nMIIB", buf.String())
+
+	// binary content is never written
+	buf.Reset()
+	g = &binaryGuard{w: buf}
+	_, err = g.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00"))
+	assert.NoError(t, err)
+	assert.Equal(t, errBinary, g.Flush())
+	assert.True(t, g.binary)
+	assert.Equal(t, 0, buf.Len())
+
+	// the check happens as soon as enough content is held back
+	buf.Reset()
+	g = &binaryGuard{w: buf}
+	_, err = g.Write([]byte(strings.Repeat("a", sniffLen-1) + "\xff"))
+	assert.Equal(t, errBinary, err)
+	assert.Equal(t, 0, buf.Len())
+
+	// a rune cut off at the end of the held back part is fine
+	assert.False(t, isBinary([]byte(strings.Repeat("a", sniffLen-1)+"\xc3")))
+	assert.False(t, isBinary([]byte("grüße")))
+	assert.True(t, isBinary([]byte("gr\xfcße")))
+}
@@ -2,43 +2,111 @@ package action
 
 import (
 	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/clipboard"
 	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/log"
+	"github.com/mattn/go-isatty"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
-// confirmRecipients asks the user to confirm a given set of recipients
+const (
+	// dateLayout is used to read and print dates
+	dateLayout = "2006-01-02 15:04"
+)
+
+// confirmRecipients asks the user to confirm the recipients before the given
+// secret is written. Every recipientreviewevery writes to a store its
+// recipients have to be reviewed, see askRecipients. A declined review is
+// asked for again on the next write.
 func (s *Action) confirmRecipients(name string, recipients []string) ([]string, error) {
-	if s.Store.NoConfirm {
+	if err := s.Store.CheckRecipientPins(name, recipients); err != nil {
+		return recipients, err
+	}
+	review := s.Store.RecipientReviewDue(name)
+	recipients, err := s.askRecipients(os.Stdout, name, recipients, review)
+	if err != nil {
+		return recipients, err
+	}
+	s.countWrite(name, review)
+	return recipients, nil
+}
+
+// countWrite counts a confirmed write to the store of the given secret
+// towards the recipient review. The counter is kept in the config.
+func (s *Action) countWrite(name string, reviewed bool) {
+	if s.Store.RecipientReviewEvery < 1 {
+		return
+	}
+	s.Store.CountWrite(name, reviewed)
+	if err := writeConfig(s.Store); err != nil {
+		fmt.Println(color.YellowString("Warning: Failed to save the recipient review counter: %s", err))
+	}
+}
+
+// askRecipients asks the user to confirm a given set of recipients, printing
+// the recipients and the question to w. A review shows the trust and expiry
+// of every recipient and has to be confirmed explicitly, even if noconfirm is
+// set.
+func (s *Action) askRecipients(w io.Writer, name string, recipients []string, review bool) ([]string, error) {
+	if s.Store.NoConfirm && !review {
 		return recipients, nil
 	}
 	for {
-		fmt.Printf("gopass: Encrypting %s for these recipients:\n", name)
+		if review {
+			fmt.Fprintln(w, color.CyanString("Periodic recipient review: Please check that all recipients of %s should still be able to read it", name))
+		}
+		if s.Store.HasRecipientOverride(name) {
+			fmt.Fprintln(w, color.YellowString("Note: %s has a recipient override. The recipients of the store are not used", name))
+		}
+		fmt.Fprintf(w, "gopass: Encrypting %s for these recipients:\n", name)
 		sort.Strings(recipients)
+		required := s.Store.RequiredRecipients(name)
+		// gpg expands groups itself, they are only resolved for display
+		groups, _ := gpg.ListGroups()
 		for _, r := range recipients {
-			kl, err := gpg.ListPublicKeys(r)
-			if err != nil {
-				fmt.Println(err)
+			members, found := groups[r]
+			if !found {
+				s.printRecipient(w, " ", r, required, review)
 				continue
 			}
-			if len(kl) < 1 {
-				fmt.Println("key not found", r)
-				continue
+			fmt.Fprintf(w, " - group %s:\n", r)
+			for _, m := range members {
+				s.printRecipient(w, "   ", m, required, review)
 			}
-			fmt.Printf(" - %s\n", kl[0].OneLine())
 		}
-		fmt.Println("")
+		// age recipients get a copy encrypted with age, they aren't passed
+		// to gpg
+		for _, r := range s.Store.AgeRecipients(name) {
+			fmt.Fprintf(w, " - age: %s\n", r)
+		}
+		fmt.Fprintln(w, "")
 
-		yes, err := askForBool("Do you want to continue?", true)
+		if err := s.confirmExpired(w, recipients); err != nil {
+			return recipients, err
+		}
+
+		question, def := "Do you want to continue?", true
+		if review {
+			question, def = "Are these recipients still correct?", false
+		}
+		yes, err := askForBoolTo(w, question, def)
 		if err != nil {
 			return recipients, err
 		}
@@ -47,46 +115,245 @@ func (s *Action) confirmRecipients(name string, recipients []string) ([]string,
 			return recipients, nil
 		}
 
-		return recipients, fmt.Errorf("user aborted")
+		return recipients, errAborted
 	}
 }
 
+// confirmExpired fails if any of the recipients has an expired key, unless
+// encrypting for expired keys is allowed and the user confirms it
+func (s *Action) confirmExpired(w io.Writer, recipients []string) error {
+	expired := gpg.ExpiredKeys(recipients)
+	if len(expired) < 1 {
+		return nil
+	}
+	if !s.Store.AllowExpired {
+		return fmt.Errorf("%s\nAsk the recipients to renew their keys and run `%s keys refresh`, or pass --allow-expired to encrypt for them anyway", &gpg.ExpiredKeysError{Keys: expired}, s.Name)
+	}
+	fmt.Fprintln(w, color.RedString("WARNING: Encrypting for %d expired key(s).", len(expired)))
+	fmt.Fprintln(w, color.RedString("An expired key may have been abandoned or compromised, and its owner may no longer be able to decrypt the secret."))
+	fmt.Fprintln(w, color.RedString("Only continue if you know the key will be renewed."))
+	yes, err := askForBoolTo(w, "Do you really want to encrypt for expired keys?", false)
+	if err != nil {
+		return err
+	}
+	if !yes {
+		return errAborted
+	}
+	return nil
+}
+
+// printRecipient prints the key of a single recipient and warnings about it.
+// Required recipients are marked. For a review the trust and expiry of the
+// key are shown as well.
+func (s *Action) printRecipient(w io.Writer, indent, r string, required []string, review bool) {
+	kl, err := gpg.ListPublicKeys(r)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	if len(kl) < 1 {
+		fmt.Fprintln(w, "key not found", r)
+		return
+	}
+	line := kl[0].OneLine()
+	if review {
+		line = formatRecipient(recipient{ID: r, Key: &kl[0]})
+		if kl[0].ExpirationDate.IsZero() {
+			line += " [expires: never]"
+		}
+		line += fmt.Sprintf(" [trust: %s]", kl[0].ValidityName())
+	}
+	for _, id := range required {
+		if id == r || strings.HasSuffix(kl[0].Fingerprint, strings.TrimPrefix(id, "0x")) {
+			line += " [required]"
+			break
+		}
+	}
+	fmt.Fprintf(w, "%s- %s\n", indent, line)
+	if !s.Store.AlwaysTrust && !kl[0].IsUseable() {
+		fmt.Fprintln(w, color.YellowString("%s  Warning: This key is not trusted. Run `%s trust %s full` if you trust it", indent, s.Name, kl[0].Fingerprint))
+	}
+	if weak := kl[0].Weakness(s.Store.MinKeyBits); weak != "" {
+		fmt.Fprintln(w, color.YellowString("%s  Warning: This key is weak, %s", indent, weak))
+	}
+}
+
+var (
+	// clipboardBackend returns the system clipboard
+	clipboardBackend = clipboard.Detect
+
+	// clipboardRead and clipboardWrite access the system clipboard
+	clipboardRead = func() (string, error) {
+		b, err := clipboardBackend()
+		if err != nil {
+			return "", err
+		}
+		return b.Read()
+	}
+	clipboardWrite = func(text string) error {
+		b, err := clipboardBackend()
+		if err != nil {
+			return err
+		}
+		return b.Write(text)
+	}
+)
+
 // clearClipboard will spwan a copy of gopass that waits in a detached background
 // process group until the timeout is expired. It will then compare the contents
 // of the clipboard and erase it if it still contains the data gopass copied
-// to it.
+// to it, with the same clipboard backend. If GOPASS_DEBUG_UNCLIP is set the copy runs in the foreground instead
+// and this only returns after it is done. With GOPASS_UNCLIP_SYSTEMD set the
+// clear is scheduled as a transient systemd timer instead, if possible.
 func clearClipboard(content []byte, timeout int) error {
-	hash := fmt.Sprintf("%x", sha256.Sum256(content))
+	hash := clipboardHash(content)
+	recordClipHash(hash, timeout, time.Now())
 
+	// the checksum and its key are only passed in the environment, which
+	// other users can't read, unlike the command line
 	cmd := exec.Command(os.Args[0], "unclip", "--timeout", strconv.Itoa(timeout))
+	cmd.Env = append(os.Environ(), "GOPASS_UNCLIP_CHECKSUM="+hash, "GOPASS_UNCLIP_KEY="+hex.EncodeToString(clipboardKey()))
+	backend := ""
+	if b, err := clipboardBackend(); err == nil {
+		backend = b.Name()
+		cmd.Env = append(cmd.Env, clipboard.EnvBackend+"="+backend)
+	}
+
+	log.Debugf("clipboard: will clear in %d seconds if the checksum is still %s", timeout, hash)
+	if unclipDebugEnabled() {
+		unclipDebug("running %s in the foreground", strings.Join(cmd.Args, " "))
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+
+	if systemdUnclipEnabled() {
+		err := systemdClearClipboard(cmd.Env, backend, timeout)
+		if err == nil {
+			return nil
+		}
+		log.Debugf("clipboard: falling back to a detached process: %s", err)
+	}
+
 	// https://groups.google.com/d/msg/golang-nuts/shST-SDqIp4/za4oxEiVtI0J
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true,
 	}
-	cmd.Env = append(os.Environ(), "GOPASS_UNCLIP_CHECKSUM="+hash)
 	return cmd.Start()
 }
 
+// waitClearClipboard waits in the foreground until the timeout expired and
+// then clears the clipboard, unless it has been changed meanwhile. On a
+// terminal the remaining seconds are shown. Ctrl-C clears the clipboard
+// right away.
+func waitClearClipboard(content []byte, timeout int) error {
+	recordClipHash(clipboardHash(content), timeout, time.Now())
+	countdown := isatty.IsTerminal(os.Stdout.Fd())
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	defer signal.Stop(sigc)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+COUNTDOWN:
+	for left := timeout; left > 0; left-- {
+		if countdown {
+			fmt.Printf("\rClearing the clipboard in %d seconds ", left)
+		}
+		select {
+		case <-ticker.C:
+		case <-sigc:
+			break COUNTDOWN
+		}
+	}
+	if countdown {
+		fmt.Printf("\r%s\r", strings.Repeat(" ", 40))
+	}
+
+	log.Debugf("clipboard: waiting %d seconds in the foreground", timeout)
+	cleared, err := clearClipboardIfUnchanged(clipboardHash(content))
+	if err != nil {
+		return err
+	}
+	if cleared {
+		fmt.Println("Cleared the clipboard")
+	}
+	return nil
+}
+
+var (
+	clipKey     []byte
+	clipKeyOnce sync.Once
+)
+
+// clipboardKey returns the key of the checksums of our clipboard content. It's
+// random for every gopass process, except for unclip which gets the key of the
+// process that copied the content in GOPASS_UNCLIP_KEY.
+func clipboardKey() []byte {
+	clipKeyOnce.Do(func() {
+		if k, err := hex.DecodeString(os.Getenv("GOPASS_UNCLIP_KEY")); err == nil && len(k) == sha256.Size {
+			clipKey = k
+			return
+		}
+		clipKey = make([]byte, sha256.Size)
+		if _, err := rand.Read(clipKey); err != nil {
+			log.Debugf("clipboard: failed to create the checksum key: %s", err)
+		}
+	})
+	return clipKey
+}
+
+// clipboardHash returns the checksum used to recognize our own clipboard
+// content. It's keyed, so it can't be used to guess the password.
+func clipboardHash(content []byte) string {
+	return clipboardHashWith(clipboardKey(), content)
+}
+
+// clipboardHashWith returns the checksum of the content with the given key
+func clipboardHashWith(key, content []byte) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(content)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // askForConfirmation asks a yes/no question until the user
 // replies yes or no
 func askForConfirmation(text string) bool {
+	return askForConfirmationTo(os.Stdout, text)
+}
+
+// askForConfirmationTo is askForConfirmation printing the question to w
+func askForConfirmationTo(w io.Writer, text string) bool {
 	for {
-		if choice, err := askForBool(text, false); err == nil {
+		if choice, err := askForBoolTo(w, text, false); err == nil {
 			return choice
 		}
 	}
 }
 
+// askForBulkConfirmation shows the summary of a change affecting multiple
+// stores and asks the user to confirm it
+func askForBulkConfirmation(summary string) bool {
+	fmt.Println(summary)
+	return askForConfirmation("Do you want to continue?")
+}
+
 // askForBool ask for a bool (yes or no) exactly once.
 // The empty answer uses the specified default, any other answer
 // is an error.
 func askForBool(text string, def bool) (bool, error) {
+	return askForBoolTo(os.Stdout, text, def)
+}
+
+// askForBoolTo is askForBool printing the question to w
+func askForBoolTo(w io.Writer, text string, def bool) (bool, error) {
 	choices := "y/N"
 	if def {
 		choices = "Y/n"
 	}
 
-	str, err := askForString(text, choices)
+	str, err := askForStringTo(w, text, choices)
 	if err != nil {
 		return false, err
 	}
@@ -111,9 +378,14 @@ func askForBool(text string, def bool) (bool, error) {
 // askForString asks for a string once, using the default if the
 // anser is empty. Errors are only returned on I/O errors
 func askForString(text, def string) (string, error) {
+	return askForStringTo(os.Stdout, text, def)
+}
+
+// askForStringTo is askForString printing the question to w
+func askForStringTo(w io.Writer, text, def string) (string, error) {
 	reader := bufio.NewReader(os.Stdin)
 
-	fmt.Printf("%s [%s]: ", text, def)
+	fmt.Fprintf(w, "%s [%s]: ", text, def)
 	input, err := reader.ReadString('\n')
 	if err != nil {
 		return "", err
@@ -139,6 +411,36 @@ func askForInt(text string, def int) (int, error) {
 	return intVal, nil
 }
 
+// askForDate asks for a date, either absolute like 2017-05-03 or
+// 2017-05-03 14:00, or relative to now like 12h or 7d
+func askForDate(text string, def time.Time) (time.Time, error) {
+	str, err := askForString(text, def.Format(dateLayout))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseDate(str, time.Now())
+}
+
+// parseDate parses an absolute date in the local time zone or a duration
+// relative to now
+func parseDate(str string, now time.Time) (time.Time, error) {
+	str = strings.TrimSpace(str)
+	for _, layout := range []string{dateLayout, "2006-01-02", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, str, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if strings.HasSuffix(str, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(str, "d")); err == nil {
+			return now.AddDate(0, 0, days), nil
+		}
+	}
+	if d, err := time.ParseDuration(str); err == nil {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("Not a date or duration: %s", str)
+}
+
 // askForPassword prompts for a password twice until both match
 func askForPassword(name string, askFn func(string) (string, error)) (string, error) {
 	if askFn == nil {
@@ -163,17 +465,85 @@ func askForPassword(name string, askFn func(string) (string, error)) (string, er
 	}
 }
 
+// askForPassphrase prompts for a new passphrase twice until both match
+func askForPassphrase(name string) (string, error) {
+	for {
+		pass, err := promptPass(fmt.Sprintf("Enter passphrase for %s", name))
+		if err != nil {
+			return "", err
+		}
+
+		passAgain, err := promptPass(fmt.Sprintf("Retype passphrase for %s", name))
+		if err != nil {
+			return "", err
+		}
+
+		if pass == passAgain {
+			return pass, nil
+		}
+
+		fmt.Println("Error: the entered passphrases do not match")
+	}
+}
+
+// askForMasterSeed prompts for the master seed of deterministic passwords
+// twice, a typo would silently derive a different password
+func askForMasterSeed() (string, error) {
+	for {
+		seed, err := promptPass("Enter the master seed")
+		if err != nil {
+			return "", err
+		}
+		if seed == "" {
+			return "", fmt.Errorf("the master seed must not be empty")
+		}
+
+		seedAgain, err := promptPass("Retype the master seed")
+		if err != nil {
+			return "", err
+		}
+
+		if seed == seedAgain {
+			return seed, nil
+		}
+
+		fmt.Println("Error: the entered master seeds do not match")
+	}
+}
+
+// promptPassphrase asks once for the passphrase of an existing symmetric secret
+func promptPassphrase(name string) (string, error) {
+	return promptPass(fmt.Sprintf("Enter passphrase for %s", name))
+}
+
+// setSymmetric asks for a passphrase and encrypts the secret with it
+func (s *Action) setSymmetric(name string, content []byte) error {
+	fmt.Printf("gopass: Encrypting %s with a passphrase only. It will not be encrypted for any recipients.\n", name)
+	pass, err := askForPassphrase(name)
+	if err != nil {
+		return fmt.Errorf("failed to ask for passphrase: %v", err)
+	}
+	return s.Store.SetSymmetric(name, content, pass)
+}
+
 // askForKeyImport asks for permissions to import the named key
 func askForKeyImport(key string) bool {
-	ok, err := askForBool("Do you want to import the public key '%s' into your keyring?", false)
+	ok, err := askForBool(fmt.Sprintf("Do you want to import the public key '%s' into your keyring?", key), false)
 	if err != nil {
 		return false
 	}
 	return ok
 }
 
-// askforPrivateKey promts the user to select from a list of private keys
-func askForPrivateKey(prompt string) (string, error) {
+// askForPrivateKey promts the user to select from a list of private keys.
+// The key of the active profile is used without asking. Keys on a hardware
+// token are marked and, with prefercard set, offered first. If one of them is
+// chosen the card must be inserted.
+func (s *Action) askForPrivateKey(prompt string) (string, error) {
+	if s.profileKey != "" {
+		fmt.Printf("Using the key %s of profile %s\n", s.profileKey, s.profile)
+		return s.profileKey, nil
+	}
 	kl, err := gpg.ListPrivateKeys()
 	if err != nil {
 		return "", err
@@ -182,17 +552,50 @@ func askForPrivateKey(prompt string) (string, error) {
 	if len(kl) < 1 {
 		return "", fmt.Errorf("No useable private keys found")
 	}
+	if s.Store.PreferCard {
+		kl.SortBy(gpg.ByHardwareToken)
+	}
 	for {
 		fmt.Println(prompt)
 		for i, k := range kl {
-			fmt.Printf("[%d] %s\n", i, k.OneLine())
+			line := k.OneLine()
+			if k.OnHardwareToken() {
+				line += " [on card]"
+			}
+			fmt.Printf("[%d] %s\n", i, line)
 		}
 		iv, err := askForInt(fmt.Sprintf("Please enter the number of a key (0-%d)", len(kl)-1), 0)
 		if err != nil {
 			continue
 		}
-		if iv >= 0 && iv < len(kl) {
-			return kl[iv].Fingerprint, nil
+		if iv < 0 || iv >= len(kl) {
+			continue
+		}
+		if kl[iv].OnHardwareToken() && !askForCard(kl[iv]) {
+			continue
+		}
+		return kl[iv].Fingerprint, nil
+	}
+}
+
+// askForCard makes sure the card holding the secret key of the given key is
+// inserted, asking to insert it until it is. It returns false if another key
+// should be chosen instead.
+func askForCard(k gpg.Key) bool {
+	for {
+		ci, err := gpg.CardStatus()
+		if err == nil && ci.Holds(k) {
+			fmt.Printf("Found the key on card %s in %s\n", ci.Serial, ci.Reader)
+			return true
+		}
+		if err == nil {
+			fmt.Println(color.YellowString("Warning: the inserted card %s doesn't hold the key %s", ci.Serial, k.Fingerprint))
+		} else {
+			fmt.Println(color.YellowString("Warning: the card holding the key %s isn't available: %s", k.Fingerprint, err))
+		}
+		ok, err := askForBool("Did you insert the card? Answer no to choose another key", true)
+		if err != nil || !ok {
+			return false
 		}
 	}
 }
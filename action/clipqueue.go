@@ -0,0 +1,118 @@
+package action
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/log"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/urfave/cli"
+)
+
+// defaultQueue are the fields queued if none are given, the first of the
+// user fields a secret has and then the password
+var defaultQueue = []string{"user", "username", "login", "email"}
+
+// Next copies the next field of the clipboard queue of this session, see
+// copyQueue
+func (s *Action) Next(c *cli.Context) error {
+	if c.Args().Present() {
+		return exitError(ExitUsage, "Usage: %s next", s.Name)
+	}
+	session := clipSession()
+	q, err := s.Store.LoadClipQueue(session)
+	if err != nil {
+		return err
+	}
+	if q == nil {
+		return exitError(ExitNotFound, "nothing to copy. Queue the fields of a secret with `%s show --queue <name> [<field>...]`", s.Name)
+	}
+	content, err := s.Store.GetResolved(q.Name, true)
+	if err != nil {
+		return err
+	}
+	return s.copyQueued(session, q, content, c.BoolT("verify"))
+}
+
+// copyQueue copies the first of the given fields of a secret to the
+// clipboard and queues the others, to be copied one after another with
+// gopass next. Each field is cleared after the timeout, unless the next one
+// replaced it, and the queue expires with it. Without fields the user field
+// and the password are queued.
+func (s *Action) copyQueue(name string, content []byte, fields []string, verify bool) error {
+	if len(fields) < 1 {
+		for _, f := range defaultQueue {
+			if _, found := secretField(content, f); found {
+				fields = append(fields, f)
+				break
+			}
+		}
+		fields = append(fields, "password")
+	}
+	for _, f := range fields {
+		if _, found := secretField(content, f); !found {
+			return exitError(ExitNotFound, "%s has no field %s", name, f)
+		}
+	}
+	return s.copyQueued(clipSession(), &password.ClipQueue{Name: name, Fields: fields}, content, verify)
+}
+
+// copyQueued copies the first field of the queue and saves the rest
+func (s *Action) copyQueued(session string, q *password.ClipQueue, content []byte, verify bool) error {
+	field := q.Fields[0]
+	value, found := secretField(content, field)
+	if !found {
+		_ = s.Store.RemoveClipQueue(session)
+		return exitError(ExitNotFound, "%s has no field %s", q.Name, field)
+	}
+	if err := clipboardWrite(value); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %v", err)
+	}
+	log.Debugf("clipboard: copied %s of %s", field, q.Name)
+	if verify {
+		if err := verifyClipboard([]byte(value)); err != nil {
+			return err
+		}
+	}
+	if err := clearClipboard([]byte(value), s.Store.ClipTimeout); err != nil {
+		return err
+	}
+
+	q.Fields = q.Fields[1:]
+	q.Expires = time.Now().Add(time.Duration(s.Store.ClipTimeout) * time.Second)
+	if err := s.Store.SaveClipQueue(session, q); err != nil {
+		return fmt.Errorf("failed to save the clipboard queue: %s", err)
+	}
+	msg := fmt.Sprintf("Copied %s of %s to clipboard. Will clear in %d seconds.", field, color.YellowString(q.Name), s.Store.ClipTimeout)
+	if len(q.Fields) > 0 {
+		msg += fmt.Sprintf(" Run `%s next` to copy %s.", s.Name, q.Fields[0])
+	}
+	fmt.Println(msg)
+	return nil
+}
+
+// secretField returns the value of a field of a secret. The password is
+// the first line, other fields are "key: value" lines of the body.
+func secretField(content []byte, field string) (string, bool) {
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	for i, line := range lines {
+		if key, value := splitField(line, i); key == field {
+			return value, i > 0 || value != ""
+		}
+	}
+	return "", false
+}
+
+// clipSession identifies the terminal session gopass runs in, i.e. the
+// shell it was started from. GOPASS_CLIP_SESSION overrides it.
+func clipSession() string {
+	id := os.Getenv("GOPASS_CLIP_SESSION")
+	if id == "" {
+		id = fmt.Sprintf("%s-%d", os.Getenv("XDG_SESSION_ID"), os.Getppid())
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(id)))[:16]
+}
@@ -0,0 +1,40 @@
+package action
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretField(t *testing.T) {
+	content := []byte("secret\nurl: example.com\nuser: gopher\nfree text\n")
+	for field, want := range map[string]string{
+		"password": "secret",
+		"url":      "example.com",
+		"user":     "gopher",
+	} {
+		got, found := secretField(content, field)
+		assert.True(t, found, field)
+		assert.Equal(t, want, got, field)
+	}
+	_, found := secretField(content, "login")
+	assert.False(t, found)
+	_, found = secretField([]byte("\nuser: gopher\n"), "password")
+	assert.False(t, found)
+}
+
+func TestClipSession(t *testing.T) {
+	old := os.Getenv("GOPASS_CLIP_SESSION")
+	defer func() {
+		_ = os.Setenv("GOPASS_CLIP_SESSION", old)
+	}()
+
+	assert.NoError(t, os.Setenv("GOPASS_CLIP_SESSION", ""))
+	session := clipSession()
+	assert.Len(t, session, 16)
+	assert.Equal(t, session, clipSession())
+
+	assert.NoError(t, os.Setenv("GOPASS_CLIP_SESSION", "test"))
+	assert.NotEqual(t, session, clipSession())
+}
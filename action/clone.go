@@ -4,15 +4,17 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/log"
 	"github.com/urfave/cli"
 )
 
 // Clone will fetch and mount a new password store from a git repo
 func (s *Action) Clone(c *cli.Context) error {
 	if len(c.Args()) < 1 {
-		return fmt.Errorf("Usage: gopass clone repo [mount]")
+		return exitError(ExitUsage, "Usage: gopass clone repo [mount]")
 	}
 
 	repo := c.Args()[0]
@@ -30,6 +32,13 @@ func (s *Action) Clone(c *cli.Context) error {
 		return fmt.Errorf("Can not clone %s to the root store, as this store is already initialized. Please try cloning to a submount: `gopass clone %s sub`", repo, repo)
 	}
 
+	s.Store.AllowNesting = c.Bool("allow-nesting")
+	if mount != "" {
+		if err := s.Store.CheckNesting(path); err != nil {
+			return err
+		}
+	}
+
 	// clone repo
 	if err := gitClone(repo, path); err != nil {
 		return err
@@ -51,6 +60,12 @@ func (s *Action) Clone(c *cli.Context) error {
 		return err
 	}
 
+	if c.Bool("diff-driver") {
+		if err := s.setupDiffDriver(mount); err != nil {
+			return err
+		}
+	}
+
 	fmt.Printf("Your password store is ready to use! Has a look around: `gopass %s`\n", mount)
 
 	return nil
@@ -64,6 +79,7 @@ func gitClone(repo, path string) error {
 	fmt.Printf("Cloning repository %s to %s ...\n", repo, path)
 
 	cmd := exec.Command("git", "clone", repo, path)
+	log.Debugf("git: %s", strings.Join(log.RedactArgs(cmd.Args), " "))
 	cmd.Stdout = os.Stdout
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
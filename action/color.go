@@ -0,0 +1,75 @@
+package action
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli"
+)
+
+const (
+	// ColorAuto colors the output if stdout is a terminal
+	ColorAuto = "auto"
+	// ColorAlways colors the output, even if it's piped
+	ColorAlways = "always"
+	// ColorNever never colors the output
+	ColorNever = "never"
+)
+
+// colorEnv are the environment variables disabling colors. NO_COLOR follows
+// https://no-color.org, GOPASS_NOCOLOR is kept for compatibility.
+var colorEnv = []string{"GOPASS_NO_COLOR", "NO_COLOR"}
+
+// colorMode returns the color mode selected by the given value of --color
+// and --no-color or, if neither is set, the environment
+func colorMode(flag string, noColor bool, getenv func(string) string) (string, error) {
+	switch flag {
+	case "", ColorAuto, ColorAlways, ColorNever:
+	default:
+		return "", fmt.Errorf("invalid color mode %q, use auto, always or never", flag)
+	}
+	if noColor {
+		if flag != "" && flag != ColorNever {
+			return "", fmt.Errorf("--no-color conflicts with --color=%s", flag)
+		}
+		return ColorNever, nil
+	}
+	if flag != "" {
+		return flag, nil
+	}
+	for _, name := range colorEnv {
+		if getenv(name) != "" {
+			return ColorNever, nil
+		}
+	}
+	if getenv("GOPASS_NOCOLOR") == "true" {
+		return ColorNever, nil
+	}
+	return ColorAuto, nil
+}
+
+// useColor returns true if output should be colored in the given mode
+func useColor(mode string, terminal bool) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return terminal
+}
+
+// UseColor resolves the color mode from --color, --no-color, the environment
+// and whether stdout is a terminal, in that order, and applies it to all
+// output. It runs before every command.
+func (s *Action) UseColor(c *cli.Context) error {
+	mode, err := colorMode(c.GlobalString("color"), c.GlobalBool("no-color"), os.Getenv)
+	if err != nil {
+		return exitError(ExitUsage, "%s", err)
+	}
+	s.color = useColor(mode, isatty.IsTerminal(os.Stdout.Fd()))
+	color.NoColor = !s.color
+	return nil
+}
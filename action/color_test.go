@@ -0,0 +1,50 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColorMode(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(name string) string {
+		return env[name]
+	}
+
+	for _, tc := range []struct {
+		flag     string
+		noColor  bool
+		env      string
+		mode     string
+		terminal bool
+		piped    bool
+	}{
+		{mode: ColorAuto, terminal: true, piped: false},
+		{flag: "auto", mode: ColorAuto, terminal: true, piped: false},
+		{flag: "always", mode: ColorAlways, terminal: true, piped: true},
+		{flag: "never", mode: ColorNever, terminal: false, piped: false},
+		{noColor: true, mode: ColorNever, terminal: false, piped: false},
+		{flag: "never", noColor: true, mode: ColorNever, terminal: false, piped: false},
+		{env: "NO_COLOR", mode: ColorNever, terminal: false, piped: false},
+		{env: "GOPASS_NO_COLOR", mode: ColorNever, terminal: false, piped: false},
+		{env: "GOPASS_NOCOLOR", mode: ColorNever, terminal: false, piped: false},
+		// the flag takes precedence over the environment
+		{flag: "always", env: "NO_COLOR", mode: ColorAlways, terminal: true, piped: true},
+	} {
+		env = map[string]string{}
+		if tc.env != "" {
+			env[tc.env] = "true"
+		}
+		mode, err := colorMode(tc.flag, tc.noColor, getenv)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.mode, mode, "%+v", tc)
+		assert.Equal(t, tc.terminal, useColor(mode, true), "%+v", tc)
+		assert.Equal(t, tc.piped, useColor(mode, false), "%+v", tc)
+	}
+
+	_, err := colorMode("sometimes", false, getenv)
+	assert.Error(t, err)
+	_, err = colorMode("always", true, getenv)
+	assert.Error(t, err)
+}
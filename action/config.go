@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -27,7 +28,7 @@ func (s *Action) Config(c *cli.Context) error {
 	}
 
 	if len(c.Args()) > 2 {
-		return fmt.Errorf("Usage: gopass config key value")
+		return exitError(ExitUsage, "Usage: gopass config key value")
 	}
 
 	return s.setConfigValue(c.Args()[0], c.Args()[1])
@@ -77,13 +78,35 @@ func contains(haystack []string, needle string) bool {
 	return false
 }
 
+// caseSensitiveKeys are the config keys whose values are paths, key ids or
+// patterns and are stored as given instead of in lower case
+var caseSensitiveKeys = map[string]bool{
+	"ageidentity": true,
+	"auditlog":    true,
+	"hookdir":     true,
+	"keyserver":   true,
+	"nameschema":  true,
+	"path":        true,
+	"signkey":     true,
+}
+
 func (s *Action) setConfigValue(key, value string) error {
 	if key == "version" {
 		return fmt.Errorf("Can not change version")
 	}
-	if key != "path" {
+	if !caseSensitiveKeys[key] {
 		value = strings.ToLower(value)
 	}
+	if key == "nameschema" {
+		if _, err := regexp.Compile(value); err != nil {
+			return fmt.Errorf("invalid regular expression: %s", err)
+		}
+	}
+	if key == "filemode" {
+		if _, err := password.ParseFileMode(value); err != nil {
+			return err
+		}
+	}
 	o := reflect.ValueOf(s.Store).Elem()
 	for i := 0; i < o.NumField(); i++ {
 		jsonArg := o.Type().Field(i).Tag.Get("json")
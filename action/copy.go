@@ -9,9 +9,10 @@ import (
 // Copy the contents of a file to another one
 func (s *Action) Copy(c *cli.Context) error {
 	force := c.Bool("force")
+	s.Store.SetAllowAnyName(force)
 
 	if len(c.Args()) != 2 {
-		return fmt.Errorf("Usage: gopass cp old-path new-path")
+		return exitError(ExitUsage, "Usage: gopass cp old-path new-path")
 	}
 
 	from := c.Args()[0]
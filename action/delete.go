@@ -14,7 +14,7 @@ func (s *Action) Delete(c *cli.Context) error {
 
 	name := c.Args().First()
 	if name == "" {
-		return fmt.Errorf("provide a secret name")
+		return exitError(ExitUsage, "provide a secret name")
 	}
 
 	found, err := s.Store.Exists(name)
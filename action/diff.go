@@ -0,0 +1,85 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// Diff lists the secrets that differ between two mounts. The root store is
+// named by an empty string.
+func (s *Action) Diff(c *cli.Context) error {
+	if len(c.Args()) != 2 {
+		return exitError(ExitUsage, "Usage: %s diff <mount> <mount>", s.Name)
+	}
+	a, b := c.Args().Get(0), c.Args().Get(1)
+	deep := c.Bool("deep")
+
+	if deep && !c.Bool("force") && !s.Store.NoConfirm {
+		ok, err := askForBool("gopass diff --deep will decrypt every secret that differs. Do you want to continue?", false)
+		if err != nil || !ok {
+			return errAborted
+		}
+	}
+
+	res, err := s.Store.Diff(a, b, deep)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		buf, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal diff: %s", err)
+		}
+		fmt.Println(string(buf))
+		return nil
+	}
+
+	a, b = mountName(a), mountName(b)
+	for _, name := range res.OnlyA {
+		fmt.Println(color.GreenString("only in %s: %s", a, name))
+	}
+	for _, name := range res.OnlyB {
+		fmt.Println(color.RedString("only in %s: %s", b, name))
+	}
+	for _, e := range res.Changed {
+		newer := a
+		if e.Newer() == "b" {
+			newer = b
+		}
+		line := color.YellowString("changed: %s", e.Name) + fmt.Sprintf(" (newer in %s)", newer)
+		if len(e.Lines) > 0 {
+			line += " lines " + formatLines(e.Lines)
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("%d only in %s, %d only in %s, %d changed, %d same\n", len(res.OnlyA), a, len(res.OnlyB), b, len(res.Changed), res.Same)
+	return nil
+}
+
+// mountName returns the name of the mount for display
+func mountName(alias string) string {
+	if alias == "" {
+		return "gopass"
+	}
+	return alias
+}
+
+// formatLines returns a comma separated list of line numbers. The first
+// line holds the password.
+func formatLines(lines []int) string {
+	out := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if l == 1 {
+			out = append(out, "1 (password)")
+			continue
+		}
+		out = append(out, strconv.Itoa(l))
+	}
+	return strings.Join(out, ", ")
+}
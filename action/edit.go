@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/log"
 	"github.com/justwatchcom/gopass/password"
 	shellquote "github.com/kballard/go-shellquote"
 	"github.com/urfave/cli"
@@ -18,7 +23,23 @@ import (
 func (s *Action) Edit(c *cli.Context) error {
 	name := c.Args().First()
 	if name == "" {
-		return fmt.Errorf("provide a secret name")
+		return exitError(ExitUsage, "provide a secret name")
+	}
+	s.Store.SetAllowExpired(c.Bool("allow-expired"))
+	s.Store.SetNoCompress(c.Bool("no-compress"))
+
+	// edit the target of an alias unless asked to edit the alias itself
+	if !c.Bool("edit-alias") {
+		target, err := s.Store.ResolveAlias(name)
+		if err != nil && err != password.ErrNotFound {
+			return fmt.Errorf("failed to resolve alias %s: %s", name, err)
+		}
+		if err == nil && target != name {
+			if ok, err := askForBool(fmt.Sprintf("%s is an alias of %s. Do you want to edit %s?", name, target, target), true); err != nil || !ok {
+				return fmt.Errorf("not editing %s", target)
+			}
+			name = target
+		}
 	}
 
 	exists, err := s.Store.Exists(name)
@@ -28,7 +49,7 @@ func (s *Action) Edit(c *cli.Context) error {
 
 	var content []byte
 	if exists {
-		content, err = s.Store.Get(name)
+		content, err = s.Store.GetRaw(name)
 		if err != nil {
 			return fmt.Errorf("failed to decrypt %s: %v", name, err)
 		}
@@ -38,57 +59,211 @@ func (s *Action) Edit(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if tags := c.StringSlice("tag"); len(tags) > 0 {
+		nContent = password.AddTags(nContent, tags)
+	}
 
 	// If content is equal, nothing changed, exiting
 	if bytes.Equal(content, nContent) {
 		return nil
 	}
 
+	if s.Store.Changelog {
+		reason := ""
+		if !c.Bool("no-reason") {
+			if reason, err = askForString("Reason for this change", ""); err != nil {
+				return fmt.Errorf("failed to read the reason: %s", err)
+			}
+		}
+		nContent = password.AppendChangelog(nContent, password.ChangelogEntry(time.Now(), s.changelogIdentity(), reason))
+	}
+
+	// keep symmetric secrets symmetric
+	if exists && s.Store.IsSymmetric(name) {
+		return s.setSymmetric(name, nContent)
+	}
+
 	return s.Store.SetConfirm(name, nContent, s.confirmRecipients)
 }
 
+// changelogIdentity returns the user ID of the effective key changing a
+// secret, see effectiveKey
+func (s *Action) changelogIdentity() string {
+	ek, err := s.effectiveKey()
+	if err != nil || ek.Key.Fingerprint == "" {
+		return "unknown"
+	}
+	return keyIdentity(ek.Key)
+}
+
+// keyIdentity returns the user ID of a key, or its fingerprint if it has
+// none
+func keyIdentity(k gpg.Key) string {
+	if uid := k.UID(); uid != "" {
+		return uid
+	}
+	return k.Fingerprint
+}
+
+// editorWaitArgs contains the arguments that keep some well known editors
+// from forking into the background. Without them gopass would read the
+// tempfile before the user actually finished editing.
+var editorWaitArgs = map[string]string{
+	"atom":  "--wait",
+	"code":  "--wait",
+	"gedit": "--wait",
+	"gvim":  "--nofork",
+	"mate":  "--wait",
+	"mvim":  "--nofork",
+	"subl":  "--wait",
+}
+
+// editorEnv lists the environment variables that may contain the editor
+// command, in order of precedence
+var editorEnv = []string{"GOPASS_EDITOR", "EDITOR", "VISUAL"}
+
+// editorFallbacks are tried in order if none of the editorEnv variables
+// point to a usable editor
+var editorFallbacks = []string{"editor", "vi", "nano", "notepad"}
+
+// editor opens the given content in the editor returned by editorCommand.
+// The content is written to a tempfile, preferably on a tmpfs, which is
+// shredded afterwards regardless of the editor succeeding or not. With
+// memfile set it is kept in a memory backed file instead, which is handed to
+// the editor as /proc/self/fd/N, so the plaintext never touches persistent
+// storage. Not every editor can write to such a path.
 func (s *Action) editor(content []byte) ([]byte, error) {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		return []byte{}, fmt.Errorf("failed to edit, please set $EDITOR")
+	editor, editorBaseArgs, err := editorCommand()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if s.Store.MemFile {
+		memfile, err := fsutil.MemFile("gopass-edit")
+		if err == nil {
+			return editMemFile(editor, editorBaseArgs, memfile, content)
+		}
+		fmt.Println(color.YellowString("Warning: Failed to create a memory backed file, using a tempfile: %s", err))
 	}
 
-	tmpfile, err := ioutil.TempFile(fsutil.Tempdir(), "gopass-edit")
+	tmpdir := fsutil.Tempdir()
+	if tmpdir == "" {
+		fmt.Println(color.YellowString("Warning: No tmpfs available. The decrypted secret will be written to %s", os.TempDir()))
+	}
+
+	// ioutil.TempFile creates the file with 0600
+	tmpfile, err := ioutil.TempFile(tmpdir, "gopass-edit")
 	if err != nil {
-		return []byte{}, fmt.Errorf("failed to create tmpfile to start with %s: %v", editor, tmpfile.Name())
+		return []byte{}, fmt.Errorf("failed to create tmpfile to start with %s: %v", editor, err)
 	}
 	defer func() {
-		if err := os.Remove(tmpfile.Name()); err != nil {
-			log.Fatal(err)
+		if err := fsutil.Shred(tmpfile.Name(), 3); err != nil {
+			fmt.Println(color.RedString("Failed to shred tmpfile %s: %s", tmpfile.Name(), err))
 		}
 	}()
 
 	if _, err := tmpfile.Write([]byte(content)); err != nil {
-		return []byte{}, fmt.Errorf("failed to create tmpfile to start with %s: %v", editor, tmpfile.Name())
+		_ = tmpfile.Close()
+		return []byte{}, fmt.Errorf("failed to write tmpfile %s: %v", tmpfile.Name(), err)
 	}
 	if err := tmpfile.Close(); err != nil {
-		return []byte{}, fmt.Errorf("failed to create tmpfile to start with %s: %v", editor, tmpfile.Name())
+		return []byte{}, fmt.Errorf("failed to close tmpfile %s: %v", tmpfile.Name(), err)
+	}
+
+	if err := runEditor(editor, append(editorBaseArgs, tmpfile.Name())); err != nil {
+		return []byte{}, err
 	}
 
-	cmdArgs, err := shellquote.Split(editor)
+	nContent, err := ioutil.ReadFile(tmpfile.Name())
 	if err != nil {
-		return []byte{}, fmt.Errorf("failed to parse EDITOR command `%s`", editor)
+		return []byte{}, fmt.Errorf("failed to read from tmpfile: %v", err)
 	}
 
-	editor = cmdArgs[0]
-	args := append(cmdArgs[1:], tmpfile.Name())
+	return nContent, nil
+}
+
+// editMemFile runs the editor on the given memory backed file. The file is
+// passed to the editor as its first extra file descriptor.
+func editMemFile(editor string, args []string, memfile *os.File, content []byte) ([]byte, error) {
+	defer func() {
+		_ = memfile.Close()
+	}()
+
+	if _, err := memfile.Write(content); err != nil {
+		return []byte{}, fmt.Errorf("failed to write memfile: %v", err)
+	}
+
+	// ExtraFiles start at fd 3 in the child
+	if err := runEditor(editor, append(args, "/proc/self/fd/3"), memfile); err != nil {
+		return []byte{}, err
+	}
+
+	if _, err := memfile.Seek(0, 0); err != nil {
+		return []byte{}, fmt.Errorf("failed to seek memfile: %v", err)
+	}
+	nContent, err := ioutil.ReadAll(memfile)
+	if err != nil {
+		return []byte{}, fmt.Errorf("failed to read from memfile: %v", err)
+	}
+
+	return nContent, nil
+}
+
+// runEditor runs the editor attached to the current terminal
+func runEditor(editor string, args []string, extraFiles ...*os.File) error {
 	cmd := exec.Command(editor, args...)
+	log.Debugf("edit: %s", strings.Join(log.RedactArgs(cmd.Args), " "))
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = extraFiles
 	if err := cmd.Run(); err != nil {
-		return []byte{}, fmt.Errorf("failed to run %s with %s file", editor, tmpfile.Name())
+		return fmt.Errorf("failed to run %s with %s file", editor, args[len(args)-1])
 	}
+	return nil
+}
 
-	nContent, err := ioutil.ReadFile(tmpfile.Name())
-	if err != nil {
-		return []byte{}, fmt.Errorf("failed to read from tmpfile: %v", err)
+// editorCommand resolves the editor to use. It tries $GOPASS_EDITOR, $EDITOR
+// and $VISUAL before falling back to a list of common editors and returns
+// the first one found in PATH along with its base arguments.
+func editorCommand() (string, []string, error) {
+	for _, env := range editorEnv {
+		editor := os.Getenv(env)
+		if editor == "" {
+			continue
+		}
+		cmdArgs, err := shellquote.Split(editor)
+		if err != nil || len(cmdArgs) < 1 {
+			return "", nil, fmt.Errorf("failed to parse $%s command `%s`", env, editor)
+		}
+		if _, err := exec.LookPath(cmdArgs[0]); err != nil {
+			fmt.Println(color.YellowString("Warning: Editor %s from $%s not found in PATH", cmdArgs[0], env))
+			continue
+		}
+		cmdArgs = editorArgs(cmdArgs)
+		return cmdArgs[0], cmdArgs[1:], nil
 	}
 
-	return nContent, nil
+	for _, editor := range editorFallbacks {
+		if _, err := exec.LookPath(editor); err == nil {
+			return editor, []string{}, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("failed to find an editor, please set $EDITOR (tried %s)", strings.Join(editorFallbacks, ", "))
+}
+
+// editorArgs appends the wait argument for editors that would otherwise fork
+// into the background, unless the user already specified it
+func editorArgs(cmdArgs []string) []string {
+	wait, found := editorWaitArgs[filepath.Base(cmdArgs[0])]
+	if !found {
+		return cmdArgs
+	}
+	for _, arg := range cmdArgs[1:] {
+		if arg == wait || arg == "-w" || arg == "-f" {
+			return cmdArgs
+		}
+	}
+	return append(cmdArgs, wait)
 }
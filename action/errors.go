@@ -0,0 +1,112 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/urfave/cli"
+)
+
+// Exit codes of gopass. Scripts rely on these, so never change their meaning.
+const (
+	// ExitOK means the command succeeded
+	ExitOK = 0
+	// ExitUnknown is used for all errors without a more specific code
+	ExitUnknown = 1
+	// ExitUsage means the command was called with invalid flags or arguments
+	ExitUsage = 2
+	// ExitNotFound means the secret doesn't exist
+	ExitNotFound = 3
+	// ExitDecrypt means gpg failed to decrypt or encrypt a secret
+	ExitDecrypt = 4
+	// ExitAborted means the user declined a confirmation
+	ExitAborted = 5
+	// ExitLocked means another process holds the lock of the store
+	ExitLocked = 6
+)
+
+// errAborted is returned if the user declines a confirmation
+var errAborted = fmt.Errorf("user aborted")
+
+// ExitError is an error that makes gopass exit with the given code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error implements error
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// ExitCode implements cli.ExitCoder
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// exitError returns an ExitError with the given code and message
+func exitError(code int, format string, args ...interface{}) error {
+	return &ExitError{
+		Code: code,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+// ExitCode returns the code gopass exits with after the given error
+func ExitCode(err error) int {
+	switch e := err.(type) {
+	case nil:
+		return ExitOK
+	case *ExitError:
+		return e.Code
+	case *password.LockedError:
+		return ExitLocked
+	case *gpg.ExpiredKeysError, *password.PinError:
+		return ExitDecrypt
+	}
+	switch err {
+	case errAborted, password.ErrAborted:
+		return ExitAborted
+	case password.ErrNotFound:
+		return ExitNotFound
+	case password.ErrDecrypt, password.ErrEncrypt, gpg.ErrNoSecretKey, gpg.ErrBadPassphrase, gpg.ErrDecryptFailed:
+		return ExitDecrypt
+	}
+	return ExitUnknown
+}
+
+// WithExitCode wraps the action of a command, converting its errors to
+// ExitErrors carrying the code returned by ExitCode
+func WithExitCode(fn func(*cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		err := fn(c)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*ExitError); ok {
+			return err
+		}
+		return &ExitError{
+			Code: ExitCode(err),
+			Err:  err,
+		}
+	}
+}
+
+// UsageError returns the handler for invalid flags of the named command, or
+// of gopass itself for an empty name. It shows the help of the command and
+// makes gopass exit with ExitUsage.
+func UsageError(name string) cli.OnUsageErrorFunc {
+	return func(c *cli.Context, err error, _ bool) error {
+		if name == "" {
+			_ = cli.ShowAppHelp(c)
+		} else {
+			_ = cli.ShowCommandHelp(c, name)
+		}
+		return &ExitError{
+			Code: ExitUsage,
+			Err:  fmt.Errorf("Incorrect usage: %s", err),
+		}
+	}
+}
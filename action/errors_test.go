@@ -0,0 +1,55 @@
+package action
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{nil, ExitOK},
+		{fmt.Errorf("something failed"), ExitUnknown},
+		{exitError(ExitUsage, "Usage: gopass foo"), ExitUsage},
+		{password.ErrNotFound, ExitNotFound},
+		{password.ErrDecrypt, ExitDecrypt},
+		{password.ErrEncrypt, ExitDecrypt},
+		{&gpg.ExpiredKeysError{}, ExitDecrypt},
+		{gpg.ErrNoSecretKey, ExitDecrypt},
+		{gpg.ErrBadPassphrase, ExitDecrypt},
+		{errAborted, ExitAborted},
+		{password.ErrAborted, ExitAborted},
+		{&password.LockedError{Path: "/tmp/store", PID: 42}, ExitLocked},
+	} {
+		assert.Equal(t, tc.code, ExitCode(tc.err), "%v", tc.err)
+	}
+}
+
+func TestWithExitCode(t *testing.T) {
+	fn := WithExitCode(func(*cli.Context) error {
+		return password.ErrNotFound
+	})
+	err := fn(nil)
+	if assert.IsType(t, &ExitError{}, err) {
+		assert.Equal(t, ExitNotFound, err.(*ExitError).ExitCode())
+		assert.Equal(t, password.ErrNotFound.Error(), err.Error())
+	}
+
+	// explicit codes are kept
+	fn = WithExitCode(func(*cli.Context) error {
+		return exitError(ExitUsage, "Usage: gopass foo")
+	})
+	assert.Equal(t, ExitUsage, ExitCode(fn(nil)))
+
+	fn = WithExitCode(func(*cli.Context) error {
+		return nil
+	})
+	assert.NoError(t, fn(nil))
+}
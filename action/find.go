@@ -2,6 +2,7 @@ package action
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/urfave/cli"
@@ -10,18 +11,25 @@ import (
 // Find a string in the secret file's name
 func (s *Action) Find(c *cli.Context) error {
 	if !c.Args().Present() {
-		return fmt.Errorf("Usage: gopass find arg")
+		return exitError(ExitUsage, "Usage: gopass find arg")
 	}
 
 	l, err := s.Store.List()
 	if err != nil {
 		return err
 	}
+	found := make([]string, 0, 10)
 	for _, value := range l {
 		if strings.Contains(value, c.Args().First()) {
-			fmt.Println(value)
+			found = append(found, value)
 		}
 	}
+	if c.Bool("porcelain") {
+		return writePorcelain(os.Stdout, found, c.Bool("null"))
+	}
+	for _, value := range found {
+		fmt.Println(value)
+	}
 
 	return nil
 }
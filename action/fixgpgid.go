@@ -0,0 +1,41 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// FixGPGID removes the recipients whose key is missing from the keyring, has
+// been revoked or has expired from the .gpg-id files below the prefix, and
+// re-encrypts the secrets affected
+func (s *Action) FixGPGID(c *cli.Context) error {
+	prefix := c.Args().First()
+	report, err := s.Store.PruneRecipients(prefix, true)
+	if err != nil {
+		return err
+	}
+	if len(report.Stale) < 1 {
+		fmt.Println("No stale recipients")
+		return nil
+	}
+
+	fmt.Println("These recipients are stale:")
+	for _, sr := range report.Stale {
+		fmt.Printf(" - %s (%s) in %s\n", sr.ID, sr.Reason, sr.File)
+	}
+	fmt.Printf("%d secrets will be re-encrypted\n", len(report.Reencrypt))
+	for _, fn := range report.Lockout {
+		fmt.Println(color.YellowString("Warning: None of the remaining recipients of %s has a private key in your keyring. You won't be able to decrypt its secrets anymore", fn))
+	}
+	if !c.Bool("force") && !s.Store.NoConfirm && !askForConfirmation(fmt.Sprintf("Do you want to remove %d stale recipients?", len(report.Stale))) {
+		return fmt.Errorf("not removing the stale recipients")
+	}
+
+	if _, err := s.Store.PruneRecipients(prefix, false); err != nil {
+		return err
+	}
+	fmt.Println(color.GreenString("Removed %d stale recipients and re-encrypted %d secrets", len(report.Stale), len(report.Reencrypt)))
+	return nil
+}
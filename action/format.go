@@ -0,0 +1,119 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	reJSONKey = regexp.MustCompile(`^(\s*)("(?:[^"\\]|\\.)*")(:.*)$`)
+	reYAMLKey = regexp.MustCompile(`^(\s*(?:- )?)([^\s:#-][^:]*)(:(?:\s.*)?)$`)
+)
+
+// formatOpts control how formatSecretBody formats a secret
+type formatOpts struct {
+	color bool
+}
+
+// formatSecretBody pretty-prints structured secrets. The first line is the
+// password and never reformatted, the body following it is indented if it's
+// JSON, or reformatted if it's nested YAML. Anything that doesn't parse
+// cleanly is returned unchanged.
+func formatSecretBody(b []byte, opts formatOpts) []byte {
+	content := string(b)
+	p := strings.SplitN(content, "\n", 2)
+	if len(p) < 2 || strings.TrimSpace(p[1]) == "" {
+		return b
+	}
+	pw, body := p[0], []byte(p[1])
+
+	out, ok := formatBody(body, opts)
+	if !ok {
+		return b
+	}
+	if opts.color {
+		pw = color.YellowString(pw)
+	}
+	return append([]byte(pw+"\n"), out...)
+}
+
+// formatBody pretty-prints the body of a secret, i.e. everything but the
+// password, if it's JSON or nested YAML
+func formatBody(body []byte, opts formatOpts) ([]byte, bool) {
+	if out, ok := formatJSON(body); ok {
+		return colorize(out, reJSONKey, opts), true
+	}
+	if out, ok := formatYAML(body); ok {
+		return colorize(out, reYAMLKey, opts), true
+	}
+	return nil, false
+}
+
+// formatJSON indents b if it's a JSON object or array
+func formatJSON(b []byte) ([]byte, bool) {
+	b = bytes.TrimSpace(b)
+	if len(b) < 2 || (b[0] != '{' && b[0] != '[') {
+		return nil, false
+	}
+	buf := &bytes.Buffer{}
+	if err := json.Indent(buf, b, "", "  "); err != nil {
+		return nil, false
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), true
+}
+
+// formatYAML re-indents b if it's a YAML mapping containing nested values.
+// Flat key-value pairs, like the ones gopass stores in secrets, are readable
+// already. Comments and multiple documents would be lost, so those are never
+// reformatted.
+func formatYAML(b []byte) ([]byte, bool) {
+	for i, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			return nil, false
+		}
+		if i > 0 && strings.HasPrefix(line, "---") {
+			return nil, false
+		}
+	}
+
+	m := yaml.MapSlice{}
+	if err := yaml.Unmarshal(b, &m); err != nil || len(m) < 1 {
+		return nil, false
+	}
+	nested := false
+	for _, item := range m {
+		switch item.Value.(type) {
+		case yaml.MapSlice, []interface{}:
+			nested = true
+		}
+	}
+	if !nested {
+		return nil, false
+	}
+
+	out, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// colorize highlights the keys of formatted JSON or YAML
+func colorize(b []byte, re *regexp.Regexp, opts formatOpts) []byte {
+	if !opts.color {
+		return b
+	}
+	lines := strings.Split(string(b), "\n")
+	for i, line := range lines {
+		if m := re.FindStringSubmatch(line); m != nil {
+			lines[i] = m[1] + color.CyanString(m[2]) + m[3]
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
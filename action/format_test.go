@@ -0,0 +1,77 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSecretBody(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{
+			name: "plain",
+			in:   "secret\nuser: gopher\nurl: https://golang.org\n",
+			out:  "secret\nuser: gopher\nurl: https://golang.org\n",
+		},
+		{
+			name: "password only",
+			in:   "{secret",
+			out:  "{secret",
+		},
+		{
+			name: "json password",
+			in:   `{"user":"gopher","tags":["a","b"]}`,
+			out:  `{"user":"gopher","tags":["a","b"]}`,
+		},
+		{
+			name: "json password with body",
+			in:   "{\"a\":1}\n{\"user\":\"gopher\"}\n",
+			out:  "{\"a\":1}\n{\n  \"user\": \"gopher\"\n}\n",
+		},
+		{
+			name: "json body",
+			in:   "secret\n{\"user\":\"gopher\"}\n",
+			out:  "secret\n{\n  \"user\": \"gopher\"\n}\n",
+		},
+		{
+			name: "broken json",
+			in:   "secret\n{\"user\":\"gopher\"\n",
+			out:  "secret\n{\"user\":\"gopher\"\n",
+		},
+		{
+			name: "yaml body",
+			in:   "secret\nuser: gopher\ndb:\n    host: localhost\n    ports: [5432, 5433]\n",
+			out:  "secret\nuser: gopher\ndb:\n  host: localhost\n  ports:\n  - 5432\n  - 5433\n",
+		},
+		{
+			name: "yaml with comments",
+			in:   "secret\ndb:\n    # the primary\n    host: localhost\n",
+			out:  "secret\ndb:\n    # the primary\n    host: localhost\n",
+		},
+		{
+			name: "not yaml",
+			in:   "secret\nsome notes: with: colons\n  and: [broken\n",
+			out:  "secret\nsome notes: with: colons\n  and: [broken\n",
+		},
+	} {
+		out := formatSecretBody([]byte(tc.in), formatOpts{})
+		assert.Equal(t, tc.out, string(out), tc.name)
+	}
+}
+
+func TestFormatSecretBodyColor(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() {
+		color.NoColor = noColor
+	}()
+
+	out := string(formatSecretBody([]byte("secret\n{\"user\":\"gopher\"}"), formatOpts{color: true}))
+	assert.Contains(t, out, color.YellowString("secret")+"\n")
+	assert.Contains(t, out, color.CyanString(`"user"`)+`: "gopher"`)
+}
@@ -1,13 +1,38 @@
 package action
 
-import "github.com/urfave/cli"
+import (
+	"encoding/json"
+	"fmt"
+	"os"
 
-// Fsck checks the store integrity
+	"github.com/urfave/cli"
+)
+
+// Fsck checks the store integrity. With --repair the problems found are
+// fixed after confirmation, --force doesn't ask. With --json the report is
+// printed as JSON and all other output goes to stderr.
 func (s *Action) Fsck(c *cli.Context) error {
-	check := c.Bool("check")
 	force := c.Bool("force")
-	if check {
-		force = false
+	repair := (c.Bool("repair") || force) && !c.Bool("check")
+	if force {
+		s.Store.SetFsckFunc(func(string) bool { return true })
+	}
+	if !c.Bool("json") {
+		_, err := s.Store.Fsck(repair, os.Stdout)
+		return err
+	}
+
+	if !force {
+		s.Store.SetFsckFunc(func(q string) bool { return askForConfirmationTo(os.Stderr, q) })
+	}
+	report, err := s.Store.Fsck(repair, os.Stderr)
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %s", err)
 	}
-	return s.Store.Fsck(check, force)
+	fmt.Println(string(buf))
+	return nil
 }
@@ -1,22 +1,31 @@
 package action
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
 	"github.com/justwatchcom/gopass/pwgen"
+	"github.com/mattn/go-isatty"
 	"github.com/urfave/cli"
 )
 
 const (
 	defaultLength = 24
+	// rulesKey prefixes the line in a secret's body holding its password rules
+	rulesKey = "password-rules"
 )
 
-// Generate & save a password
+// Generate a password, save it and copy it to the clipboard
 func (s *Action) Generate(c *cli.Context) error {
 	force := c.Bool("force")
+	s.Store.SetAllowAnyName(force)
 	noSymbols := c.Bool("no-symbols")
+	s.Store.SetAllowExpired(c.Bool("allow-expired"))
 
 	name := c.Args().Get(0)
 	length := c.Args().Get(1)
@@ -25,7 +34,7 @@ func (s *Action) Generate(c *cli.Context) error {
 		var err error
 		name, err = askForString("Which name do you want to use?", "")
 		if err != nil || name == "" {
-			return fmt.Errorf(color.RedString("provide a password name"))
+			return exitError(ExitUsage, "%s", color.RedString("provide a password name"))
 		}
 	}
 
@@ -34,41 +43,221 @@ func (s *Action) Generate(c *cli.Context) error {
 		return fmt.Errorf("failed to see if %s exists: %s", name, err)
 	}
 
-	if !force { // don't check if it's force anyway
-		if replacing && !askForConfirmation(fmt.Sprintf("An entry already exists for %s. Overwrite it?", name)) {
-			return fmt.Errorf("not overwriting your current password")
+	// reuse the password rules of the existing secret
+	spec := c.String("password-rules")
+	var old []byte
+	if replacing {
+		if old, err = s.Store.GetRaw(name); err != nil {
+			fmt.Println(color.YellowString("Warning: Failed to decrypt %s. Can not reuse its password rules: %s", name, err))
+		}
+		if spec == "" {
+			if spec = secretRules(old); spec != "" {
+				fmt.Printf("Using the password rules of %s: %s\n", name, color.CyanString(spec))
+			}
 		}
 	}
 
-	if length == "" {
-		length = strconv.Itoa(defaultLength)
-		if l, err := askForInt("How long should the password be?", defaultLength); err == nil {
-			length = strconv.Itoa(l)
+	// deterministic passwords are derived from a master seed and the name,
+	// the generator is chosen before asking for the length
+	gen := pwgen.GenerateWithRules
+	deterministic := c.Bool("deterministic")
+	if deterministic {
+		seed, err := askForMasterSeed()
+		if err != nil {
+			return err
+		}
+		gen = func(rules pwgen.Rules) (string, error) {
+			return pwgen.GenerateDeterministic(seed, name, rules)
 		}
 	}
 
-	pwlen, err := strconv.Atoi(length)
-	if err != nil {
-		return fmt.Errorf("password length must be a number")
+	var password, content []byte
+	var bits float64
+	if spec != "" {
+		pw, e, err := generateWithRules(spec, length, gen)
+		if err != nil {
+			return err
+		}
+		password = []byte(pw)
+		bits = e
+		content = withRules(password, old, spec)
+	} else {
+		if length == "" {
+			length = strconv.Itoa(defaultLength)
+			if l, err := askForInt("How long should the password be?", defaultLength); err == nil {
+				length = strconv.Itoa(l)
+			}
+		}
+
+		pwlen, err := strconv.Atoi(length)
+		if err != nil {
+			return fmt.Errorf("password length must be a number")
+		}
+		if pwlen < 1 {
+			return fmt.Errorf("password length must be bigger than 0")
+		}
+
+		if deterministic {
+			pw, err := gen(deterministicRules(pwlen, !noSymbols))
+			if err != nil {
+				return err
+			}
+			password = []byte(pw)
+		} else {
+			password = pwgen.GeneratePassword(pwlen, !noSymbols)
+			bits = pwgen.Entropy(pwlen, !noSymbols)
+		}
+		content = password
 	}
-	if pwlen < 1 {
-		return fmt.Errorf("password length must be bigger than 0")
+
+	// new secrets get the template of their directory
+	if !replacing && !c.Bool("no-template") {
+		if content, err = s.withTemplate(name, content); err != nil {
+			return err
+		}
 	}
 
-	password := pwgen.GeneratePassword(pwlen, !noSymbols)
+	if replacing && !force { // don't check if it's force anyway
+		fmt.Printf("An entry already exists for %s:\n%s", name, maskedDiff(old, content))
+		if !askForConfirmation("Overwrite it?") {
+			return fmt.Errorf("not overwriting your current password")
+		}
+	}
 
-	if err := s.Store.SetConfirm(name, password, s.confirmRecipients); err != nil {
+	if c.Bool("symmetric") {
+		if err := s.setSymmetric(name, content); err != nil {
+			return err
+		}
+	} else if err := s.Store.SetConfirm(name, content, s.confirmRecipients); err != nil {
 		return err
 	}
 
-	if c.Bool("clip") {
-		return s.copyToClipboard(name, password)
+	if deterministic {
+		// the entropy is limited by the master seed
+		fmt.Printf("Derived a password for %s from the master seed\n", color.YellowString(name))
+	} else {
+		fmt.Printf("Generated a password for %s with %d bits of entropy\n", color.YellowString(name), int(bits))
 	}
 
-	fmt.Printf(
-		"The generated password for %s is:\n%s\n", name,
-		color.YellowString(string(password)),
-	)
+	if c.Bool("print") {
+		if isatty.IsTerminal(os.Stdout.Fd()) {
+			fmt.Printf("The generated password for %s is:\n%s\n", name, color.YellowString(string(password)))
+		} else {
+			fmt.Println(color.YellowString("Warning: Not printing the password, stdout is not a terminal"))
+		}
+	}
+
+	if err := s.copyToClipboard(name, password, c.BoolT("verify"), c.Bool("wait")); err != nil {
+		fmt.Println(color.YellowString("Warning: %s. Use `%s show %s` to see the password", err, s.Name, name))
+	}
 
 	return nil
 }
+
+// maskedDiff returns a line based diff of two secrets with the password
+// masked
+func maskedDiff(old, next []byte) string {
+	mask := func(lines []string) []string {
+		if len(lines) > 0 {
+			lines[0] = "password: " + strings.Repeat("*", 8)
+		}
+		return lines
+	}
+	split := func(content []byte) []string {
+		content = []byte(strings.TrimRight(string(content), "\n"))
+		if len(content) < 1 {
+			return nil
+		}
+		return strings.Split(string(content), "\n")
+	}
+	from := mask(split(old))
+	to := mask(split(next))
+
+	seen := make(map[string]bool, len(to))
+	for _, line := range to {
+		seen[line] = true
+	}
+	kept := make(map[string]bool, len(from))
+	buf := &bytes.Buffer{}
+	for i, line := range from {
+		if i > 0 && seen[line] {
+			kept[line] = true
+			fmt.Fprintf(buf, "  %s\n", line)
+			continue
+		}
+		fmt.Fprintf(buf, "%s\n", color.RedString("- %s", line))
+	}
+	for i, line := range to {
+		if i > 0 && kept[line] {
+			continue
+		}
+		fmt.Fprintf(buf, "%s\n", color.GreenString("+ %s", line))
+	}
+	return buf.String()
+}
+
+// generateWithRules generates a password satisfying the given password rules
+// using gen and returns it along with its estimated entropy. An explicit
+// length must be permitted by the rules.
+func generateWithRules(spec, length string, gen func(pwgen.Rules) (string, error)) (string, float64, error) {
+	rules, err := pwgen.ParseRules(spec)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid password rules: %s", err)
+	}
+	if length != "" {
+		pwlen, err := strconv.Atoi(length)
+		if err != nil {
+			return "", 0, fmt.Errorf("password length must be a number")
+		}
+		if pwlen < rules.MinLength || (rules.MaxLength > 0 && pwlen > rules.MaxLength) {
+			return "", 0, fmt.Errorf("password length %d is not permitted by the password rules '%s'", pwlen, spec)
+		}
+		rules.MinLength = pwlen
+		rules.MaxLength = pwlen
+	}
+	pw, err := gen(rules)
+	if err != nil {
+		return "", 0, err
+	}
+	return pw, rules.Entropy(len(pw)), nil
+}
+
+// deterministicRules returns the rules matching the passwords of
+// pwgen.GeneratePassword, for deterministic passwords
+func deterministicRules(length int, symbols bool) pwgen.Rules {
+	spec := fmt.Sprintf("minlength: %d; maxlength: %d; allowed: upper, lower, digit", length, length)
+	if symbols {
+		spec += ", special"
+	}
+	rules, _ := pwgen.ParseRules(spec)
+	return rules
+}
+
+// secretRules returns the password rules stored in the body of a secret
+func secretRules(content []byte) string {
+	spec, _ := password.Field(content, rulesKey)
+	return spec
+}
+
+// withRules returns the new password followed by the body of the old
+// content, with the password rules set to spec
+func withRules(password, old []byte, spec string) []byte {
+	out := []string{string(password)}
+	found := false
+	if lines := strings.Split(string(old), "\n"); len(old) > 0 && len(lines) > 1 {
+		for _, line := range lines[1:] {
+			if strings.HasPrefix(line, rulesKey+":") {
+				if found {
+					continue
+				}
+				line = rulesKey + ": " + spec
+				found = true
+			}
+			out = append(out, line)
+		}
+	}
+	if !found {
+		out = append(out, rulesKey+": "+spec)
+	}
+	return []byte(strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n")
+}
@@ -0,0 +1,54 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/pwgen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskedDiff(t *testing.T) {
+	color.NoColor = true
+
+	for _, tc := range []struct {
+		old  string
+		next string
+		out  string
+	}{
+		{
+			old:  "secret\n",
+			next: "geheim",
+			out:  "- password: ********\n+ password: ********\n",
+		},
+		{
+			old:  "secret\nuser: john\nurl: example.com\n",
+			next: "geheim\nuser: john\npassword-rules: required: digit\n",
+			out: "- password: ********\n" +
+				"  user: john\n" +
+				"- url: example.com\n" +
+				"+ password: ********\n" +
+				"+ password-rules: required: digit\n",
+		},
+		{
+			old:  "",
+			next: "geheim",
+			out:  "+ password: ********\n",
+		},
+	} {
+		assert.Equal(t, tc.out, maskedDiff([]byte(tc.old), []byte(tc.next)))
+	}
+}
+
+func TestDeterministicRules(t *testing.T) {
+	for _, symbols := range []bool{true, false} {
+		rules := deterministicRules(16, symbols)
+		assert.Equal(t, 16, rules.Length())
+		pw, err := pwgen.GenerateDeterministic("seed", "foo/bar", rules)
+		assert.NoError(t, err)
+		assert.Len(t, pw, 16)
+		if !symbols {
+			assert.Regexp(t, "^[a-zA-Z0-9]+$", pw)
+		}
+	}
+}
@@ -2,8 +2,13 @@ package action
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
 	"github.com/urfave/cli"
 )
 
@@ -18,9 +23,9 @@ func (s *Action) GitInit(c *cli.Context) error {
 	store := c.String("store")
 	sk := c.String("sign-key")
 	if sk == "" {
-		s, err := askForPrivateKey("Please select a key for signing Git Commits")
+		k, err := s.askForPrivateKey("Please select a key for signing Git Commits")
 		if err == nil {
-			sk = s
+			sk = k
 		}
 	}
 
@@ -28,5 +33,54 @@ func (s *Action) GitInit(c *cli.Context) error {
 		return err
 	}
 	fmt.Println(color.GreenString("Git initialized"))
+	if c.Bool("diff-driver") {
+		return s.setupDiffDriver(store)
+	}
 	return nil
 }
+
+// GitDiffDriver configures git diff to show the plaintext of secrets, see
+// GitTextconv
+func (s *Action) GitDiffDriver(c *cli.Context) error {
+	return s.setupDiffDriver(c.String("store"))
+}
+
+// setupDiffDriver configures git diff of the given store to run secrets
+// through gopass git-textconv
+func (s *Action) setupDiffDriver(store string) error {
+	bin, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return fmt.Errorf("failed to find the gopass binary: %s", err)
+	}
+	if bin, err = filepath.Abs(bin); err != nil {
+		return err
+	}
+	// git runs the command with the shell
+	textconv := "'" + strings.Replace(bin, "'", `'\''`, -1) + "' git-textconv"
+	if err := s.Store.GitSetupDiffDriver(store, textconv); err != nil {
+		return fmt.Errorf("failed to set up the diff driver: %s", err)
+	}
+	fmt.Println(color.YellowString("Note: git diff and log decrypt secrets in this store now. Run `%s git config --local --unset diff.gpg.textconv` to turn it off", s.Name))
+	return nil
+}
+
+// GitTextconv prints the plaintext of an encrypted file for git diff, which
+// runs it as the textconv command of the gpg diff driver. Symmetric secrets
+// aren't decrypted, that would ask for their passphrase on every diff.
+func (s *Action) GitTextconv(c *cli.Context) error {
+	fn := c.Args().First()
+	if fn == "" {
+		return exitError(ExitUsage, "Usage: %s git-textconv <file>", s.Name)
+	}
+	if sym, err := gpg.IsSymmetric(fn); err == nil && sym {
+		fmt.Println("[symmetrically encrypted secret]")
+		return nil
+	}
+	hidden, _ := gpg.HasHiddenRecipients(fn)
+	content, err := gpg.DecryptWith(fn, gpg.DecryptOpts{TryAllSecrets: hidden})
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %s", fn, err)
+	}
+	_, err = os.Stdout.Write(content)
+	return err
+}
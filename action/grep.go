@@ -2,36 +2,76 @@ package action
 
 import (
 	"fmt"
-	"strings"
+	"regexp"
 
 	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
 	"github.com/urfave/cli"
 )
 
 // Grep searches a string inside the content of all files
 func (s *Action) Grep(c *cli.Context) error {
 	if !c.Args().Present() {
-		return fmt.Errorf("Usage: gopass grep arg")
+		return exitError(ExitUsage, "Usage: gopass grep arg")
 	}
 
 	search := c.Args().First()
+	query := ""
+	if !c.Bool("regexp") {
+		query = search
+		search = regexp.QuoteMeta(search)
+	}
+	if c.Bool("ignore-case") {
+		search = "(?i)" + search
+	}
+	re, err := regexp.Compile(search)
+	if err != nil {
+		return fmt.Errorf("Invalid search pattern: %s", err)
+	}
 
-	l, err := s.Store.List()
+	// with search indexes only the matching secrets are decrypted
+	indexed := query != "" && s.Store.HasIndex()
+	if !c.Bool("force") && !s.Store.NoConfirm && !indexed {
+		ok, err := askForBool("gopass grep will decrypt every secret in the store. Do you want to continue?", false)
+		if err != nil || !ok {
+			return errAborted
+		}
+	}
+
+	matches, err := s.Store.Grep(re, password.GrepOpts{
+		Unmask: c.Bool("unmask"),
+		Query:  query,
+	})
 	if err != nil {
 		return err
 	}
 
-	for _, v := range l {
-		content, err := s.Store.Get(v)
-		if err != nil {
-			fmt.Printf("failed to decrypt %s: %v", v, err)
-			continue
-		}
+	for _, m := range matches {
+		fmt.Printf("%s:%d: %s\n", color.BlueString(m.Name), m.Line, m.Text)
+	}
+
+	return nil
+}
 
-		if strings.Contains(string(content), search) {
-			fmt.Printf("%s:\n%s", color.BlueString(v), string(content))
+// Index builds the search indexes used by grep, or removes them
+func (s *Action) Index(c *cli.Context) error {
+	if c.Bool("remove") {
+		if err := s.Store.RemoveIndex(); err != nil {
+			return fmt.Errorf("failed to remove the search index: %s", err)
 		}
+		fmt.Println("Removed the search indexes")
+		return nil
 	}
 
+	if !c.Bool("force") && !s.Store.NoConfirm {
+		ok, err := askForBool("gopass index will decrypt every secret in the store. Do you want to continue?", false)
+		if err != nil || !ok {
+			return errAborted
+		}
+	}
+	if err := s.Store.BuildIndex(); err != nil {
+		return err
+	}
+	fmt.Println(color.GreenString("Built the search indexes, grep uses them from now on"))
 	return nil
 }
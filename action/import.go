@@ -0,0 +1,81 @@
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/justwatchcom/gopass/importer/csv"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/urfave/cli"
+)
+
+// ImportCSV imports the passwords of a CSV export of a browser. The columns
+// are recognized by the header of the export, see csv.Profiles. Every
+// password is written to a secret named after the hostname of its URL,
+// below the given folder. Existing secrets are only replaced with --force.
+// The plan is confirmed before anything is written.
+func (s *Action) ImportCSV(c *cli.Context) error {
+	fn := c.Args().First()
+	if fn == "" {
+		return exitError(ExitUsage, "Usage: %s import csv [--folder <folder>] [--force] <file>", s.Name)
+	}
+
+	f, err := os.Open(fn)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %s", fn, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	p, entries, err := csv.Parse(f)
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %s", fn, err)
+	}
+	if len(entries) < 1 {
+		fmt.Printf("%s holds no passwords\n", fn)
+		return nil
+	}
+
+	folder := strings.Trim(c.String("folder"), "/")
+	batch := make([]password.BatchEntry, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name
+		if folder != "" {
+			name = folder + "/" + name
+		}
+		batch = append(batch, password.BatchEntry{
+			Name:      name,
+			Content:   e.Content(),
+			Overwrite: c.Bool("force"),
+		})
+	}
+
+	plan := formatImportPlan(p, fn, batch, entries)
+	confirm := func(summary string) bool {
+		return askForBulkConfirmation(plan + summary)
+	}
+	if s.Store.NoConfirm {
+		fmt.Print(plan)
+		confirm = nil
+	}
+	results, err := s.Store.SetBatch(batch, fmt.Sprintf("Import %d passwords from %s.", len(batch), fn), confirm)
+	if err != nil {
+		return err
+	}
+	return reportBatch(results, "use --force to replace it")
+}
+
+// formatImportPlan lists where every imported password comes from
+func formatImportPlan(p csv.Profile, fn string, batch []password.BatchEntry, entries []csv.Entry) string {
+	buf := bytes.NewBufferString(fmt.Sprintf("Importing %d passwords of a %s export from %s:\n", len(entries), p.Name, fn))
+	for i, e := range entries {
+		from := e.URL
+		if e.User != "" {
+			from = e.User + " at " + from
+		}
+		fmt.Fprintf(buf, " - %s <- %s (line %d)\n", batch[i].Name, from, e.Line)
+	}
+	return buf.String()
+}
@@ -34,19 +34,27 @@ func (s *Action) Init(c *cli.Context) error {
 	}
 
 	keys := c.Args()
+	if rf := c.String("recipients-from"); rf != "" {
+		rk, err := s.recipientsFromFile(store, rf)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, rk...)
+	}
 	if len(keys) < 1 {
-		nk, err := askForPrivateKey("Please select a private Key for encryption:")
+		nk, err := s.askForPrivateKey("Please select a private Key for encryption:")
 		if err != nil {
 			return err
 		}
 		keys = []string{nk}
 	}
 
+	s.Store.AllowNesting = c.Bool("allow-nesting")
 	if err := s.Store.Init(store, keys...); err != nil {
 		return err
 	}
 
-	fmt.Printf(color.GreenString("Password store initialized for: "))
+	fmt.Print(color.GreenString("Password store initialized for: "))
 	for _, recipient := range s.Store.ListRecipients(store) {
 		r := "0x" + recipient
 		if kl, err := gpg.ListPublicKeys(recipient); err == nil && len(kl) > 0 {
@@ -1,12 +1,17 @@
 package action
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/justwatchcom/gopass/password"
+	"github.com/justwatchcom/gopass/pwgen"
 	"github.com/urfave/cli"
 )
 
@@ -15,10 +20,43 @@ func (s *Action) Insert(c *cli.Context) error {
 	echo := c.Bool("echo")
 	multiline := c.Bool("multiline")
 	force := c.Bool("force")
+	s.Store.SetAllowAnyName(force)
+	s.Store.SetAllowExpired(c.Bool("allow-expired"))
+	s.Store.SetNoCompress(c.Bool("no-compress"))
+
+	if fn := c.String("from"); fn != "" {
+		if c.Args().Present() {
+			return exitError(ExitUsage, "Usage: %s insert --from <manifest>", s.Name)
+		}
+		return s.InsertManifest(fn)
+	}
 
 	name := c.Args().Get(0)
 	if name == "" {
-		return fmt.Errorf("provide a secret name")
+		return exitError(ExitUsage, "provide a secret name")
+	}
+
+	confirm := s.confirmRecipients
+	if rf := c.String("recipients-from"); rf != "" {
+		recipients, err := s.recipientsFromFile(name, rf)
+		if err != nil {
+			return err
+		}
+		confirm = func(name string, _ []string) ([]string, error) {
+			return s.confirmRecipients(name, recipients)
+		}
+	}
+
+	save := func(content []byte) error {
+		return s.Store.SetConfirm(name, content, confirm)
+	}
+	if c.Bool("symmetric") {
+		if c.String("recipients-from") != "" {
+			return fmt.Errorf("symmetric secrets have no recipients")
+		}
+		save = func(content []byte) error {
+			return s.setSymmetric(name, content)
+		}
 	}
 
 	replacing, err := s.Store.Exists(name)
@@ -32,20 +70,77 @@ func (s *Action) Insert(c *cli.Context) error {
 		}
 	}
 
+	if src := c.String("template-from"); src != "" {
+		if c.Bool("symmetric") || c.String("recipients-from") != "" {
+			return exitError(ExitUsage, "--template-from can not be combined with --symmetric or --recipients-from")
+		}
+		return s.InsertFrom(src, name)
+	}
+
 	info, err := os.Stdin.Stat()
 	if err != nil {
 		return fmt.Errorf("Failed to stat stdin: %s", err)
 	}
+	piped := info.Mode()&os.ModeCharDevice == 0
+
+	if c.Bool("expires") {
+		if piped {
+			return fmt.Errorf("can not ask for the expiry while reading the secret from stdin. Add a line 'expires_at: <date>' instead")
+		}
+		expires, err := askForDate("When should the secret expire? Enter a date or a duration like 12h or 7d", time.Now().Add(24*time.Hour))
+		if err != nil {
+			return fmt.Errorf("failed to ask for the expiry: %s", err)
+		}
+		orig := save
+		save = func(content []byte) error {
+			return orig(password.SetExpiry(content, expires))
+		}
+	}
+
+	if note := c.String("note"); note != "" {
+		orig := save
+		save = func(content []byte) error {
+			return orig(password.AddNote(content, note))
+		}
+	}
+
+	if tags := c.StringSlice("tag"); len(tags) > 0 {
+		orig := save
+		save = func(content []byte) error {
+			return orig(password.AddTags(content, tags))
+		}
+	}
+
+	// new secrets get the template of their directory
+	if !replacing && !c.Bool("no-template") {
+		orig := save
+		save = func(content []byte) error {
+			content, err := s.withTemplate(name, content)
+			if err != nil {
+				return err
+			}
+			return orig(content)
+		}
+	}
+
+	// the size of the input is checked before anything is added to it
+	orig := save
+	save = func(content []byte) error {
+		if err := s.confirmSecretSize(name, content, piped, c.Bool("allow-large")); err != nil {
+			return err
+		}
+		return orig(content)
+	}
 
 	// if content is piped to stdin, read and save it
-	if info.Mode()&os.ModeCharDevice == 0 {
+	if piped {
 		content := &bytes.Buffer{}
 
 		if written, err := io.Copy(content, os.Stdin); err != nil {
 			return fmt.Errorf("Failed to copy after %d bytes: %s", written, err)
 		}
 
-		return s.Store.SetConfirm(name, content.Bytes(), s.confirmRecipients)
+		return save(content.Bytes())
 	}
 
 	// if multi-line input is requested start an editor
@@ -54,7 +149,7 @@ func (s *Action) Insert(c *cli.Context) error {
 		if err != nil {
 			return err
 		}
-		return s.Store.SetConfirm(name, []byte(content), s.confirmRecipients)
+		return save([]byte(content))
 	}
 
 	// if echo mode is requested use a simple string input function
@@ -70,5 +165,145 @@ func (s *Action) Insert(c *cli.Context) error {
 		return fmt.Errorf("failed to ask for password: %v", err)
 	}
 
-	return s.Store.SetConfirm(name, []byte(content), s.confirmRecipients)
+	return save([]byte(content))
+}
+
+// confirmSecretSize asks before storing a secret larger than maxsecretsize,
+// large input is usually a file added by accident. Input piped to stdin
+// can't be confirmed, it's refused unless allowLarge is set. Secrets larger
+// than password.SecretSizeCap are always refused.
+func (s *Action) confirmSecretSize(name string, content []byte, piped, allowLarge bool) error {
+	if len(content) > password.SecretSizeCap {
+		return password.ErrSecretTooLarge
+	}
+	limit := s.Store.MaxSize()
+	if len(content) <= limit || allowLarge {
+		return nil
+	}
+	fmt.Println(color.YellowString("Warning: %s has %d bytes, more than maxsecretsize (%d bytes). Large secrets bloat the git history and slow down gopass.", name, len(content), limit))
+	if isBinary(content) {
+		fmt.Printf("This looks like a binary file. Consider adding it to a secret with `%s attachments add <secret> <file>` instead.\n", s.Name)
+	}
+	if piped {
+		return exitError(ExitAborted, "not storing %s read from stdin, add --allow-large to store it anyway", name)
+	}
+	if !askForConfirmation(fmt.Sprintf("Do you really want to store %s?", name)) {
+		return errAborted
+	}
+	return nil
+}
+
+// withTemplate applies the template of the directory of a new secret to
+// its content, if there is one
+func (s *Action) withTemplate(name string, content []byte) ([]byte, error) {
+	tpl, ok := s.Store.TemplateFor(name)
+	if !ok {
+		return content, nil
+	}
+	out, err := password.ApplyTemplate(tpl, name, content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return out, nil
+}
+
+// InsertFrom creates dst from the metadata of src with a new password. The
+// password is read from stdin if it's piped, otherwise it's generated using
+// the password rules of src or typed in. The password history, expiry, TOTP
+// secret and changelog of src aren't copied and src itself is never modified. The recipients are confirmed for
+// dst.
+func (s *Action) InsertFrom(src, dst string) error {
+	found, err := s.Store.Exists(src)
+	if err != nil {
+		return fmt.Errorf("failed to see if %s exists: %s", src, err)
+	}
+	if !found {
+		return exitError(ExitNotFound, "%s does not exist", src)
+	}
+	old, err := s.Store.GetRaw(src)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %s", src, err)
+	}
+
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return fmt.Errorf("Failed to stat stdin: %s", err)
+	}
+	var pw string
+	generated := false
+	if info.Mode()&os.ModeCharDevice == 0 {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read the password: %s", err)
+		}
+		pw = strings.TrimSpace(line)
+	} else if generated, err = askForBool("Generate a new password?", true); err != nil {
+		return err
+	} else if generated {
+		if spec := secretRules(old); spec != "" {
+			if pw, _, err = generateWithRules(spec, "", pwgen.GenerateWithRules); err != nil {
+				return err
+			}
+		} else {
+			pw = string(pwgen.GeneratePassword(defaultLength, true))
+		}
+	} else if pw, err = askForPassword(dst, nil); err != nil {
+		return fmt.Errorf("failed to ask for password: %v", err)
+	}
+	if pw == "" {
+		return fmt.Errorf("the new password is empty")
+	}
+
+	if err := s.Store.SetConfirm(dst, templateContent(old, pw), s.confirmRecipients); err != nil {
+		return err
+	}
+	fmt.Printf("Created %s from the metadata of %s\n", color.YellowString(dst), src)
+	if generated {
+		if err := s.copyToClipboard(dst, []byte(pw), true, false); err != nil {
+			fmt.Println(color.YellowString("Warning: %s. Use `%s show %s` to see the password", err, s.Name, dst))
+		}
+	}
+	return nil
+}
+
+// credentialKeys are the fields belonging to the credential rather than the
+// service, i.e. its expiry, TOTP secret and changelog. They aren't copied to
+// a secret created from a template.
+var credentialKeys = []string{"expires_at", "totp", "changelog"}
+
+// templateContent returns the content of a secret with the metadata of old
+// and the given password, without the password history and the other
+// credential fields of old
+func templateContent(old []byte, pw string) []byte {
+	_, body := password.SplitSecret(old)
+	body = withHistory(body, "", 0)
+
+	out := make([]string, 0, 10)
+	inBlock := false
+	for _, line := range strings.Split(string(body), "\n") {
+		if inBlock && strings.HasPrefix(line, "  ") {
+			continue
+		}
+		inBlock = false
+		if strings.HasPrefix(strings.TrimSpace(line), "otpauth://") {
+			continue
+		}
+		if key := credentialKey(line); key != "" {
+			inBlock = strings.TrimSpace(strings.TrimPrefix(line, key+":")) == "|"
+			continue
+		}
+		out = append(out, line)
+	}
+	return append([]byte(pw+"\n"), strings.Join(out, "\n")...)
+}
+
+// credentialKey returns the credential key of a key: value line, see
+// credentialKeys, or an empty string for other lines
+func credentialKey(line string) string {
+	for _, key := range credentialKeys {
+		if strings.HasPrefix(line, key+":") {
+			return key
+		}
+	}
+	return ""
 }
@@ -0,0 +1,55 @@
+package action
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/justwatchcom/gopass/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmSecretSize(t *testing.T) {
+	s := &Action{Name: "gopass", Store: &password.RootStore{MaxSecretSize: 10}}
+	small := []byte("secret")
+	large := bytes.Repeat([]byte("x"), 11)
+
+	assert.NoError(t, s.confirmSecretSize("foo", small, true, false))
+	assert.NoError(t, s.confirmSecretSize("foo", large, true, true))
+
+	// piped input can't be confirmed
+	err := s.confirmSecretSize("foo", large, true, false)
+	assert.Error(t, err)
+	assert.Equal(t, ExitAborted, ExitCode(err))
+
+	// otherwise it's asked for
+	stdin := os.Stdin
+	defer func() {
+		os.Stdin = stdin
+	}()
+	for _, tc := range []struct {
+		answer string
+		err    error
+	}{
+		{"y\n", nil},
+		{"n\n", errAborted},
+	} {
+		r, w, err := os.Pipe()
+		assert.NoError(t, err)
+		_, err = w.WriteString(tc.answer)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+		os.Stdin = r
+		assert.Equal(t, tc.err, s.confirmSecretSize("foo", large, false, false))
+		assert.NoError(t, r.Close())
+	}
+
+	// the cap can't be bypassed
+	huge := make([]byte, password.SecretSizeCap+1)
+	assert.Equal(t, password.ErrSecretTooLarge, s.confirmSecretSize("foo", huge, false, true))
+
+	// without maxsecretsize the default is used
+	s.Store.MaxSecretSize = 0
+	assert.NoError(t, s.confirmSecretSize("foo", large, true, false))
+	assert.Equal(t, password.DefaultMaxSecretSize, s.Store.MaxSize())
+}
@@ -0,0 +1,59 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/keyring"
+	"github.com/justwatchcom/gopass/password"
+)
+
+// useOSKeyring makes the loopback pinentry take the passphrase of the
+// private key from the OS keyring, if useoskeyring is set
+func (s *Action) useOSKeyring() {
+	if !s.Store.UseOSKeyring {
+		return
+	}
+	kr, err := keyring.Default()
+	if err != nil {
+		fmt.Println(color.YellowString("Warning: Can not use the OS keyring: %s", err))
+		return
+	}
+	s.Store.SetKeyPassphraseFunc(keyringPassphrase(kr, s.keyringKey, promptPassphrase))
+}
+
+// keyringKey returns the key of the passphrase in the OS keyring. Profiles
+// may use different private keys, so each gets its own entry.
+func (s *Action) keyringKey() string {
+	if s.profile != "" {
+		return "gpg:" + s.profile
+	}
+	return "gpg"
+}
+
+// keyringPassphrase returns a callback for the passphrase of the private key
+// that takes it from the OS keyring. If the keyring doesn't hold it, it
+// fails or the passphrase was wrong, ask is used and the entered passphrase
+// is stored in the keyring once gpg accepted it.
+func keyringPassphrase(kr keyring.Backend, key func() string, ask password.PassphraseCallback) password.KeyPassphraseCallback {
+	return func(prompt string, retry bool) (password.KeyPassphrase, error) {
+		if !retry {
+			pass, err := kr.Get(key())
+			if err == nil {
+				return password.KeyPassphrase{Passphrase: pass, Cached: true}, nil
+			}
+			if err != keyring.ErrNotFound {
+				fmt.Println(color.YellowString("Warning: %s", err))
+			}
+		}
+		pass, err := ask(prompt)
+		if err != nil {
+			return password.KeyPassphrase{}, err
+		}
+		return password.KeyPassphrase{Passphrase: pass, Accepted: func() {
+			if err := kr.Set(key(), pass); err != nil {
+				fmt.Println(color.YellowString("Warning: %s", err))
+			}
+		}}, nil
+	}
+}
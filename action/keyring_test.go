@@ -0,0 +1,69 @@
+package action
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/justwatchcom/gopass/keyring"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingKeyring is a keyring that can't be read or written
+type failingKeyring struct{}
+
+func (failingKeyring) Get(string) (string, error) {
+	return "", fmt.Errorf("keyring locked")
+}
+
+func (failingKeyring) Set(string, string) error {
+	return fmt.Errorf("keyring locked")
+}
+
+func TestKeyringPassphrase(t *testing.T) {
+	asked := 0
+	typed := "passphrase"
+	ask := func(string) (string, error) {
+		asked++
+		return typed, nil
+	}
+	key := func() string {
+		return "gpg"
+	}
+
+	kr := keyring.Memory{}
+	fn := keyringPassphrase(kr, key, ask)
+
+	// the first passphrase is asked for and remembered once it's accepted
+	kp, err := fn("the private key", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", kp.Passphrase)
+	assert.Equal(t, 1, asked)
+	assert.NotContains(t, kr, "gpg")
+	kp.Accepted()
+	assert.Equal(t, "passphrase", kr["gpg"])
+
+	// then it's taken from the keyring
+	kp, err = fn("the private key", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", kp.Passphrase)
+	assert.True(t, kp.Cached)
+	assert.Nil(t, kp.Accepted)
+	assert.Equal(t, 1, asked)
+
+	// a wrong passphrase is asked for again and replaced
+	typed = "changed"
+	kp, err = fn("the private key", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", kp.Passphrase)
+	assert.Equal(t, 2, asked)
+	assert.Equal(t, "passphrase", kr["gpg"])
+	kp.Accepted()
+	assert.Equal(t, "changed", kr["gpg"])
+
+	// without a working keyring it's always asked for
+	fn = keyringPassphrase(failingKeyring{}, key, ask)
+	kp, err = fn("the private key", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", kp.Passphrase)
+	assert.Equal(t, 3, asked)
+}
@@ -0,0 +1,129 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/audit"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/urfave/cli"
+)
+
+// KeysRefresh fetches all recipient keys of all stores from the keyserver and
+// reports keys that were extended, got new subkeys or were revoked
+func (s *Action) KeysRefresh(c *cli.Context) error {
+	all, err := s.Store.AllRecipients()
+	if err != nil {
+		return err
+	}
+
+	// a key is often used in more than one store, only refresh it once
+	stores := make(map[string][]string, 10)
+	for alias, ids := range all {
+		for _, id := range ids {
+			stores[id] = append(stores[id], alias)
+		}
+	}
+	ids := make([]string, 0, len(stores))
+	for id := range stores {
+		ids = append(ids, id)
+		sort.Strings(stores[id])
+	}
+	sort.Strings(ids)
+
+	keyserver := c.String("keyserver")
+	if keyserver == "" {
+		keyserver = s.Store.KeyServer
+	}
+
+	// stop refreshing on Ctrl-C, but still report what was refreshed so far
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigch)
+	go func() {
+		select {
+		case <-sigch:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fmt.Printf("Refreshing %d keys ...\n", len(ids))
+	res, err := gpg.RefreshKeysContext(ctx, ids, keyserver)
+	cancelled := err == context.Canceled
+	if err != nil && !cancelled {
+		return err
+	}
+
+	source := "keyserver " + keyserver
+	if keyserver == "" {
+		source = "keyserver of gpg"
+	}
+	for _, k := range res.Keys {
+		if err := audit.LogKeyImport(audit.NewImportRecord(k, source, audit.Refreshed)); err != nil {
+			fmt.Println(color.RedString("Failed to log the refresh of %s: %s", k.Fingerprint, err))
+		}
+	}
+
+	for _, id := range ids {
+		if err, found := res.Failed[id]; found {
+			fmt.Println(color.YellowString("Failed to refresh %s: %s", id, err))
+		}
+	}
+	for _, kc := range res.Changed {
+		fmt.Println(formatKeyChange(kc))
+		if kc.Revoked {
+			for _, alias := range stores[kc.ID] {
+				fmt.Println(color.RedString("  It should be removed from the store %s: %s", storeName(alias), removeCmd(alias, kc.ID)))
+			}
+		}
+	}
+
+	fmt.Printf("Refreshed %d keys, %d changed\n", res.Refreshed, len(res.Changed))
+	if cancelled {
+		return fmt.Errorf("refresh cancelled")
+	}
+	return nil
+}
+
+// formatKeyChange returns a one line description of the changes to a key
+func formatKeyChange(kc gpg.KeyChange) string {
+	out := kc.Key.OneLine()
+	if kc.Revoked {
+		return out + color.RedString(" has been revoked")
+	}
+	if kc.ExpiryChanged() {
+		if kc.NewExpiry.IsZero() {
+			out += " does not expire anymore"
+		} else {
+			out += fmt.Sprintf(" now expires on %s", kc.NewExpiry.Format("2006-01-02"))
+		}
+	}
+	if len(kc.NewSubKeys) > 0 {
+		out += fmt.Sprintf(" [%d new subkeys]", len(kc.NewSubKeys))
+	}
+	return out
+}
+
+// storeName returns the name of the store for display
+func storeName(alias string) string {
+	if alias == "" {
+		return "gopass"
+	}
+	return alias
+}
+
+// removeCmd returns the command removing the recipient from the store
+func removeCmd(alias, id string) string {
+	if alias == "" {
+		return "gopass recipients remove " + id
+	}
+	return "gopass recipients remove --store " + alias + " " + id
+}
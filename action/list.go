@@ -2,7 +2,11 @@ package action
 
 import (
 	"fmt"
+	"os"
+	"sort"
 
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
 	"github.com/urfave/cli"
 )
 
@@ -10,11 +14,40 @@ import (
 func (s *Action) List(c *cli.Context) error {
 	filter := c.Args().First()
 
+	if c.Bool("porcelain") {
+		return s.listPorcelain(c, filter)
+	}
+
+	if id := c.String("recipient"); id != "" {
+		return s.listForRecipient(filter, id, c.Bool("verify"))
+	}
+
+	if tag := c.String("tag"); tag != "" {
+		return s.listTagged(filter, tag)
+	}
+
+	if by := c.String("sort"); by != "" {
+		return s.listSorted(filter, by)
+	}
+
 	l, err := s.Store.Tree()
 	if err != nil {
 		return err
 	}
 
+	// marking aliases requires decrypting every entry
+	if c.Bool("aliases") {
+		for _, name := range l.List() {
+			target, err := s.Store.AliasOf(name)
+			if err != nil || target == "" {
+				continue
+			}
+			if err := l.AddNote(name, "alias of "+target); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+
 	if filter == "" {
 		fmt.Println(l.Format())
 		return nil
@@ -29,3 +62,119 @@ func (s *Action) List(c *cli.Context) error {
 
 	return nil
 }
+
+// listPorcelain prints the secrets below filter for scripts, see
+// writePorcelain. --tag and --recipient select the secrets like they do for
+// the flat lists, the flags changing the order or decoration can't be used.
+func (s *Action) listPorcelain(c *cli.Context, filter string) error {
+	if c.Bool("aliases") || c.Bool("verify") || c.String("sort") != "" {
+		return exitError(ExitUsage, "--aliases, --sort and --verify can't be combined with --porcelain")
+	}
+
+	var l []string
+	var err error
+	switch {
+	case c.String("recipient") != "":
+		l, err = s.Store.SecretsForRecipient(c.String("recipient"))
+	case c.String("tag") != "":
+		l, err = s.Store.ListByTag(c.String("tag"))
+	default:
+		l, err = s.Store.List()
+	}
+	if err != nil {
+		return err
+	}
+
+	return writePorcelain(os.Stdout, password.NamesBelow(l, filter), c.Bool("null"))
+}
+
+// listTagged prints a flat list of the secrets tagged with tag
+func (s *Action) listTagged(filter, tag string) error {
+	l, err := s.Store.ListByTag(tag)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range password.NamesBelow(l, filter) {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// listForRecipient prints a flat list of the secrets encrypted for the given
+// key according to their .gpg-id. With verify the recipients of the
+// ciphertexts are listed instead, and secrets where they differ from the
+// .gpg-id are reported.
+func (s *Action) listForRecipient(filter, id string, verify bool) error {
+	l, err := s.Store.SecretsForRecipient(id)
+	if err != nil {
+		return err
+	}
+
+	var missing, extra []string
+	if verify {
+		a, err := s.Store.AuditKey(id)
+		if err != nil {
+			return err
+		}
+		missing = subtract(l, a.Readable)
+		extra = subtract(a.Readable, l)
+		l = a.Readable
+	}
+
+	for _, name := range password.NamesBelow(l, filter) {
+		fmt.Println(name)
+	}
+	for _, name := range missing {
+		if password.IsBelow(name, filter) {
+			fmt.Println(color.YellowString("Warning: %s is not encrypted for %s, but its .gpg-id lists it. Run `%s reencrypt %s`", name, id, s.Name, name))
+		}
+	}
+	for _, name := range extra {
+		if password.IsBelow(name, filter) {
+			fmt.Println(color.YellowString("Warning: %s is still encrypted for %s, but its .gpg-id doesn't list it. Run `%s reencrypt %s`", name, id, s.Name, name))
+		}
+	}
+	return nil
+}
+
+// subtract returns the sorted names in a which aren't in b
+func subtract(a, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, name := range b {
+		in[name] = struct{}{}
+	}
+	out := make([]string, 0, len(a))
+	for _, name := range a {
+		if _, found := in[name]; !found {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// listSorted prints a flat list of all secrets along with the time of
+// their last change
+func (s *Action) listSorted(filter, by string) error {
+	l, err := s.Store.ListWithMeta()
+	if err != nil {
+		return err
+	}
+
+	switch by {
+	case "name":
+		// already sorted by name
+	case "mtime":
+		sort.Stable(password.MetaByTime(l))
+	default:
+		return fmt.Errorf("Unknown sort order '%s'. Use name or mtime", by)
+	}
+
+	for _, m := range l {
+		if !password.IsBelow(m.Name, filter) {
+			continue
+		}
+		fmt.Printf("%s  %s\n", m.Time().Format("2006-01-02 15:04:05"), m.Name)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package action
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/justwatchcom/gopass/pwgen"
+	"gopkg.in/yaml.v2"
+)
+
+// manifestEntry is one secret of an insert manifest. A manifest maps the
+// names of secrets to their content, either just the password or a mapping
+// like this:
+//
+//	web/site:
+//	  password: hunter2
+//	  meta:
+//	    user: gopher
+//	  overwrite: true
+//	db/prod:
+//	  generate: true
+//	  length: 32
+//	  symbols: false
+type manifestEntry struct {
+	name     string
+	password string
+	// generate replaces the password by a generated one, using the
+	// password rules if they are set
+	generate  bool
+	length    int
+	symbols   bool
+	rules     string
+	meta      yaml.MapSlice
+	overwrite bool
+}
+
+// InsertManifest inserts all secrets defined in the given YAML or JSON
+// manifest at once. Existing secrets are only replaced if the entry sets
+// overwrite. The recipients of all secrets are confirmed once.
+func (s *Action) InsertManifest(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %s", err)
+	}
+	entries, err := parseManifest(buf)
+	if err != nil {
+		return err
+	}
+
+	batch := make([]password.BatchEntry, 0, len(entries))
+	for _, e := range entries {
+		content, err := e.content()
+		if err != nil {
+			return fmt.Errorf("manifest: %s: %s", e.name, err)
+		}
+		batch = append(batch, password.BatchEntry{
+			Name:      e.name,
+			Content:   content,
+			Overwrite: e.overwrite,
+		})
+	}
+
+	var confirm password.BulkCallback
+	if !s.Store.NoConfirm {
+		confirm = askForBulkConfirmation
+	}
+	results, err := s.Store.SetBatch(batch, fmt.Sprintf("Insert %d secrets from %s.", len(batch), path), confirm)
+	if err != nil {
+		return err
+	}
+	return reportBatch(results, "set overwrite to replace it")
+}
+
+// reportBatch prints the outcome of every secret written by SetBatch and a
+// summary. An error is returned if any of them failed.
+func reportBatch(results []password.BatchResult, skipHint string) error {
+	counts := make(map[string]int, 4)
+	for _, r := range results {
+		counts[r.Status]++
+		switch r.Status {
+		case password.BatchCreated:
+			fmt.Println(color.GreenString("created: %s", r.Name))
+		case password.BatchReplaced:
+			fmt.Println(color.YellowString("replaced: %s", r.Name))
+		case password.BatchSkipped:
+			fmt.Println(color.CyanString("skipped: %s", r.Name) + " (exists, " + skipHint + ")")
+		case password.BatchFailed:
+			fmt.Println(color.RedString("failed: %s: %s", r.Name, r.Err))
+		}
+	}
+	fmt.Printf("%d created, %d replaced, %d skipped, %d failed\n", counts[password.BatchCreated], counts[password.BatchReplaced], counts[password.BatchSkipped], counts[password.BatchFailed])
+	if n := counts[password.BatchFailed]; n > 0 {
+		return fmt.Errorf("failed to insert %d secrets", n)
+	}
+	return nil
+}
+
+// parseManifest parses and validates an insert manifest. The entries are
+// returned in the order of the manifest.
+func parseManifest(buf []byte) ([]manifestEntry, error) {
+	m := yaml.MapSlice{}
+	if err := yaml.Unmarshal(buf, &m); err != nil {
+		return nil, fmt.Errorf("manifest must map secret names to their content: %s", err)
+	}
+	if len(m) < 1 {
+		return nil, fmt.Errorf("manifest holds no secrets")
+	}
+
+	entries := make([]manifestEntry, 0, len(m))
+	seen := make(map[string]struct{}, len(m))
+	for _, item := range m {
+		name, ok := item.Key.(string)
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("manifest: invalid secret name %v", item.Key)
+		}
+		name = strings.Trim(name, "/")
+		if _, found := seen[name]; found {
+			return nil, fmt.Errorf("manifest: %s is defined twice", name)
+		}
+		seen[name] = struct{}{}
+
+		e, err := parseManifestEntry(name, item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("manifest: %s: %s", name, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// parseManifestEntry validates the content of a single secret
+func parseManifestEntry(name string, value interface{}) (manifestEntry, error) {
+	e := manifestEntry{name: name, symbols: true}
+	if pw, ok := scalarString(value); ok {
+		e.password = pw
+		return e, nil
+	}
+	fields, ok := value.(yaml.MapSlice)
+	if !ok {
+		return e, fmt.Errorf("must be a password or a mapping")
+	}
+
+	hasPassword, hasLength, hasSymbols := false, false, false
+	for _, f := range fields {
+		key, _ := f.Key.(string)
+		var ok bool
+		switch key {
+		case "password":
+			e.password, ok = scalarString(f.Value)
+			hasPassword = true
+		case "generate":
+			e.generate, ok = f.Value.(bool)
+		case "length":
+			e.length, ok = f.Value.(int)
+			ok = ok && e.length > 0
+			hasLength = true
+		case "symbols":
+			e.symbols, ok = f.Value.(bool)
+			hasSymbols = true
+		case "rules":
+			e.rules, ok = f.Value.(string)
+		case "meta":
+			e.meta, ok = f.Value.(yaml.MapSlice)
+			for _, kv := range e.meta {
+				k, kok := kv.Key.(string)
+				if _, vok := scalarString(kv.Value); !kok || !vok {
+					return e, fmt.Errorf("meta: the value of %v must be a string, number or bool", kv.Key)
+				}
+				if k == "" || strings.ContainsAny(k, ":\n") {
+					return e, fmt.Errorf("meta: invalid key %q", k)
+				}
+			}
+		case "overwrite":
+			e.overwrite, ok = f.Value.(bool)
+		default:
+			return e, fmt.Errorf("unknown field %v", f.Key)
+		}
+		if !ok {
+			return e, fmt.Errorf("invalid value for %s: %v", key, f.Value)
+		}
+	}
+
+	switch {
+	case hasPassword && e.generate:
+		return e, fmt.Errorf("password and generate are mutually exclusive")
+	case !hasPassword && !e.generate:
+		return e, fmt.Errorf("either password or generate is required")
+	case !e.generate && (hasLength || hasSymbols || e.rules != ""):
+		return e, fmt.Errorf("length, symbols and rules require generate")
+	case strings.Contains(e.password, "\n"):
+		return e, fmt.Errorf("the password must be a single line")
+	}
+	if e.rules != "" {
+		if _, err := pwgen.ParseRules(e.rules); err != nil {
+			return e, fmt.Errorf("invalid password rules: %s", err)
+		}
+	}
+	return e, nil
+}
+
+// scalarString returns strings, numbers and bools as string
+func scalarString(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case int, int64, uint64, float64, bool:
+		return fmt.Sprintf("%v", x), true
+	}
+	return "", false
+}
+
+// content returns the content of the secret, generating the password if
+// requested. The metadata follows the password as key-value pairs.
+func (e manifestEntry) content() ([]byte, error) {
+	var content []byte
+	switch {
+	case e.generate && e.rules != "":
+		length := ""
+		if e.length > 0 {
+			length = strconv.Itoa(e.length)
+		}
+		p, _, err := generateWithRules(e.rules, length, pwgen.GenerateWithRules)
+		if err != nil {
+			return nil, err
+		}
+		content = withRules([]byte(p), nil, e.rules)
+	case e.generate:
+		length := e.length
+		if length < 1 {
+			length = defaultLength
+		}
+		content = append(pwgen.GeneratePassword(length, e.symbols), '\n')
+	default:
+		content = []byte(e.password + "\n")
+	}
+
+	for _, kv := range e.meta {
+		v, _ := scalarString(kv.Value)
+		content = append(content, []byte(fmt.Sprintf("%s: %s\n", kv.Key, v))...)
+	}
+	return content, nil
+}
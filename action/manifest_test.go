@@ -0,0 +1,89 @@
+package action
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseManifest(t *testing.T) {
+	in := `web/site:
+  password: hunter2
+  meta:
+    user: gopher
+    port: 8080
+  overwrite: true
+db/prod:
+  generate: true
+  length: 32
+  symbols: false
+api/token: s3cr3t
+ci/deploy:
+  generate: true
+  rules: "minlength: 12; maxlength: 12; required: digit; allowed: lower"
+`
+	entries, err := parseManifest([]byte(in))
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 4) {
+		return
+	}
+
+	assert.Equal(t, "web/site", entries[0].name)
+	assert.True(t, entries[0].overwrite)
+	content, err := entries[0].content()
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2\nuser: gopher\nport: 8080\n", string(content))
+
+	assert.Equal(t, "db/prod", entries[1].name)
+	assert.False(t, entries[1].overwrite)
+	content, err = entries[1].content()
+	assert.NoError(t, err)
+	pw := strings.TrimSuffix(string(content), "\n")
+	assert.Len(t, pw, 32)
+	assert.False(t, strings.ContainsAny(pw, "!#$%&*+-=?@^_~"), pw)
+
+	content, err = entries[2].content()
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t\n", string(content))
+
+	content, err = entries[3].content()
+	assert.NoError(t, err)
+	lines := strings.Split(string(content), "\n")
+	assert.Len(t, lines[0], 12)
+	assert.Equal(t, "password-rules: minlength: 12; maxlength: 12; required: digit; allowed: lower", lines[1])
+
+	// JSON is YAML as well
+	entries, err = parseManifest([]byte(`{"web/site": {"password": "hunter2", "meta": {"user": "gopher"}}}`))
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		content, err := entries[0].content()
+		assert.NoError(t, err)
+		assert.Equal(t, "hunter2\nuser: gopher\n", string(content))
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		err string
+	}{
+		{"", "manifest holds no secrets"},
+		{"- foo\n- bar\n", "manifest must map secret names to their content"},
+		{"foo: bar\nfoo: baz\n", "manifest: foo is defined twice"},
+		{"foo:\n  pasword: bar\n", "manifest: foo: unknown field pasword"},
+		{"foo:\n  - bar\n", "manifest: foo: must be a password or a mapping"},
+		{"foo:\n  password: bar\n  generate: true\n", "manifest: foo: password and generate are mutually exclusive"},
+		{"foo:\n  overwrite: true\n", "manifest: foo: either password or generate is required"},
+		{"foo:\n  password: bar\n  length: 12\n", "manifest: foo: length, symbols and rules require generate"},
+		{"foo:\n  generate: true\n  length: -1\n", "manifest: foo: invalid value for length: -1"},
+		{"foo:\n  generate: yes please\n", "manifest: foo: invalid value for generate: yes please"},
+		{"foo:\n  password: bar\n  meta:\n    user:\n      name: gopher\n", "manifest: foo: meta: the value of user must be a string, number or bool"},
+		{"foo:\n  generate: true\n  rules: 'bogus: 1'\n", "manifest: foo: invalid password rules"},
+	} {
+		_, err := parseManifest([]byte(tc.in))
+		if assert.Error(t, err, tc.in) {
+			assert.True(t, strings.HasPrefix(err.Error(), tc.err), "%q: %s", tc.in, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/urfave/cli"
+)
+
+// maxSplitCollisions is the number of overwritten files listed before
+// splitting a folder off
+const maxSplitCollisions = 10
+
+// MigrateSplit moves a folder of a store into a new store and mounts it in
+// its place, see password.RootStore.SplitMount
+func (s *Action) MigrateSplit(c *cli.Context) error {
+	if len(c.Args()) != 2 {
+		return exitError(ExitUsage, "Usage: %s migrate split [--keep] <prefix> <path>", s.Name)
+	}
+	prefix, path := c.Args()[0], c.Args()[1]
+	p, err := s.Store.SplitPlan(prefix, path)
+	if err != nil {
+		return err
+	}
+	if !c.Bool("force") && !askForBulkConfirmation(formatSplitPlan(p, c.Bool("keep"))) {
+		return fmt.Errorf("not splitting %s off", p.Prefix)
+	}
+
+	// only collisions which were confirmed, or with --force, are overwritten
+	opts := password.SplitOptions{
+		Keep:      c.Bool("keep"),
+		Overwrite: c.Bool("force") || len(p.Collisions) > 0,
+	}
+	if err := s.Store.SplitMount(p.Prefix, p.Path, opts); err != nil {
+		return err
+	}
+	if err := writeConfig(s.Store); err != nil {
+		return err
+	}
+	fmt.Println(color.GreenString("Moved %d secrets to %s and mounted it as %s", len(p.Secrets), p.Path, p.Prefix))
+	return nil
+}
+
+// formatSplitPlan returns the summary of a split confirmed by the user
+func formatSplitPlan(p password.SplitPlan, keep bool) string {
+	lines := []string{
+		fmt.Sprintf("%d secrets below %s are moved from %s to %s, which is mounted as %s", len(p.Secrets), p.Prefix, p.Origin, p.Path, p.Prefix),
+	}
+	if p.Init {
+		lines = append(lines, fmt.Sprintf(" - the new store is initialized for %s", strings.Join(p.Recipients, ", ")))
+	} else {
+		lines = append(lines, fmt.Sprintf(" - the existing store is encrypted for %s", strings.Join(p.Recipients, ", ")))
+	}
+	if len(p.Reencrypt) > 0 {
+		lines = append(lines, fmt.Sprintf(" - %d secrets are re-encrypted for its recipients", len(p.Reencrypt)))
+	}
+	if p.History {
+		lines = append(lines, " - their git history is carried over")
+	}
+	if len(p.Collisions) > 0 {
+		lines = append(lines, color.YellowString(" - %d files exist in %s already and are overwritten:", len(p.Collisions), p.Path))
+		for i, fn := range p.Collisions {
+			if i >= maxSplitCollisions {
+				lines = append(lines, fmt.Sprintf("   ... and %d more", len(p.Collisions)-maxSplitCollisions))
+				break
+			}
+			lines = append(lines, "   "+fn)
+		}
+	}
+	if keep {
+		lines = append(lines, color.YellowString(" - the secrets are kept in %s, hidden by the mount", p.Origin))
+	} else {
+		lines = append(lines, fmt.Sprintf(" - the secrets are removed from %s", p.Origin))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -58,6 +58,7 @@ func (s *Action) MountAdd(c *cli.Context) error {
 	if k := c.String("init"); k != "" {
 		keys = append(keys, k)
 	}
+	s.Store.AllowNesting = c.Bool("allow-nesting")
 	if err := s.Store.AddMount(c.Args()[0], c.Args()[1], keys...); err != nil {
 		return err
 	}
@@ -9,9 +9,10 @@ import (
 // Move the content from one secret to another
 func (s *Action) Move(c *cli.Context) error {
 	force := c.Bool("force")
+	s.Store.SetAllowAnyName(force)
 
 	if len(c.Args()) != 2 {
-		return fmt.Errorf("Usage: gopass mv old-path new-path")
+		return exitError(ExitUsage, "Usage: gopass mv old-path new-path")
 	}
 
 	from := c.Args()[0]
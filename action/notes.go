@@ -0,0 +1,66 @@
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/urfave/cli"
+)
+
+// Notes prints the notes of a secret
+func (s *Action) Notes(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return exitError(ExitUsage, "Usage: %s notes secret", s.Name)
+	}
+
+	content, err := s.Store.Get(name)
+	if err != nil {
+		return err
+	}
+	notes, ok := password.ParseNotes(content)
+	if !ok {
+		fmt.Printf("%s has no notes\n", name)
+		return nil
+	}
+	fmt.Println(notes)
+	return nil
+}
+
+// NotesAppend appends a note to a secret. The note is read from stdin unless
+// it's given as arguments.
+func (s *Action) NotesAppend(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return exitError(ExitUsage, "Usage: %s notes append secret [note]", s.Name)
+	}
+
+	note := strings.Join(c.Args().Tail(), " ")
+	if note == "" {
+		buf := &bytes.Buffer{}
+		if _, err := io.Copy(buf, os.Stdin); err != nil {
+			return fmt.Errorf("failed to read the note from stdin: %s", err)
+		}
+		note = strings.TrimRight(buf.String(), "\n")
+	}
+	if note == "" {
+		return exitError(ExitUsage, "provide a note")
+	}
+
+	if err := s.AppendNote(name, note); err != nil {
+		return err
+	}
+	fmt.Printf("Added a note to %s\n", color.YellowString(name))
+	return nil
+}
+
+// AppendNote appends a note to the notes of a secret, keeping its password
+// and all other content
+func (s *Action) AppendNote(name, note string) error {
+	return s.Store.AppendNote(name, note, s.confirmRecipients)
+}
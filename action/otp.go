@@ -0,0 +1,79 @@
+package action
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/otp"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli"
+)
+
+// otpBarWidth is the width of the bar showing the time left of a code
+const otpBarWidth = 30
+
+// OTP prints the current TOTP code of a secret. With --watch the code is
+// shown along with the time until it rotates, updating in place until
+// Ctrl-C. If stdout isn't a terminal the code is printed once.
+func (s *Action) OTP(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return exitError(ExitUsage, "Usage: %s otp [--watch] secret", s.Name)
+	}
+
+	content, err := s.Store.GetResolved(name, true)
+	if err != nil {
+		return err
+	}
+	o, err := otp.Parse(content)
+	if err == otp.ErrNoOTP {
+		return exitError(ExitNotFound, "%s has no TOTP secret", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the TOTP secret of %s: %s", name, err)
+	}
+
+	if c.Bool("watch") && isatty.IsTerminal(os.Stdout.Fd()) {
+		return watchOTP(os.Stdout, o)
+	}
+	fmt.Println(o.Now())
+	return nil
+}
+
+// watchOTP shows the current code of o and the seconds until it rotates,
+// updating the line in place every second. On Ctrl-C the line is cleared
+// and the cursor, which is hidden meanwhile, is restored.
+func watchOTP(w io.Writer, o otp.OTP) error {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+
+	fmt.Fprint(w, "\x1b[?25l")
+	defer fmt.Fprint(w, "\r\x1b[K\x1b[?25h")
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		fmt.Fprintf(w, "\r%s", otpLine(o, time.Now()))
+		select {
+		case <-ticker.C:
+		case <-sigc:
+			return nil
+		}
+	}
+}
+
+// otpLine returns the code of o valid at t along with a bar and the number
+// of seconds it remains valid
+func otpLine(o otp.OTP, t time.Time) string {
+	left := o.Left(t)
+	filled := left * otpBarWidth / o.Period
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", otpBarWidth-filled)
+	return fmt.Sprintf("%s [%s] %2ds", color.YellowString(o.At(t)), bar, left)
+}
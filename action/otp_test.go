@@ -0,0 +1,22 @@
+package action
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/otp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOTPLine(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() {
+		color.NoColor = noColor
+	}()
+
+	o := otp.OTP{Secret: []byte("12345678901234567890"), Digits: 8, Period: 30, Algorithm: "SHA1"}
+	assert.Equal(t, "94287082 [#-----------------------------]  1s", otpLine(o, time.Unix(59, 0)))
+	assert.Equal(t, "07081804 [###########################---] 27s", otpLine(o, time.Unix(1111111083, 0)))
+}
@@ -0,0 +1,72 @@
+package action
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/justwatchcom/gopass/log"
+	"github.com/justwatchcom/gopass/plugin"
+	"github.com/urfave/cli"
+)
+
+// ResolvePlugin returns the plugin for the unknown subcommand name, see
+// plugin.Resolve. Secrets and folders of the store take precedence, so
+// gopass <name> keeps showing them.
+func (s *Action) ResolvePlugin(name string) (string, bool) {
+	path, ok := plugin.Resolve(name)
+	if !ok {
+		return "", false
+	}
+	if s.Store.Initialized() {
+		if found, err := s.Store.Exists(name); err != nil || found || s.Store.IsDir(name) {
+			return "", false
+		}
+	}
+	return path, true
+}
+
+// Plugin runs the plugin at path with the arguments following the
+// subcommand. It learns about the store from the GOPASS_ variables of
+// pluginEnv, never about the secrets in it, and gopass exits with its
+// exit code.
+func (s *Action) Plugin(c *cli.Context, path string) error {
+	err := plugin.Run(path, c.Args().Tail(), s.pluginEnv())
+	if ee, ok := err.(*exec.ExitError); ok {
+		code := ExitUnknown
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.ExitStatus() > 0 {
+			code = ws.ExitStatus()
+		}
+		// the plugin printed its own error
+		return exitError(code, "")
+	}
+	return err
+}
+
+// pluginEnv returns the environment variables of a plugin: the path of the
+// root store, the key gopass uses, the config file, the gopass version and
+// binary
+func (s *Action) pluginEnv() map[string]string {
+	env := map[string]string{
+		"GOPASS_STORE":   s.Store.Path,
+		"GOPASS_KEY":     s.pluginKey(),
+		"GOPASS_CONFIG":  configFile(),
+		"GOPASS_VERSION": s.Store.Version,
+	}
+	if bin, err := exec.LookPath(os.Args[0]); err == nil {
+		if bin, err = filepath.Abs(bin); err == nil {
+			env["GOPASS_BINARY"] = bin
+		}
+	}
+	return env
+}
+
+// pluginKey returns the effective key, see effectiveKey
+func (s *Action) pluginKey() string {
+	ek, err := s.effectiveKey()
+	if err != nil {
+		log.Debugf("plugin: %s", err)
+	}
+	return ek.ID
+}
@@ -0,0 +1,26 @@
+package action
+
+import (
+	"io"
+	"sort"
+)
+
+// writePorcelain writes the names of secrets for scripts. This output is
+// stable across versions: every name is followed by a newline, or a NUL
+// byte with nul, sorted bytewise and without any decoration or color.
+func writePorcelain(w io.Writer, names []string, nul bool) error {
+	sorted := make([]string, len(names))
+	copy(sorted, names)
+	sort.Strings(sorted)
+
+	end := "\n"
+	if nul {
+		end = "\x00"
+	}
+	for _, name := range sorted {
+		if _, err := io.WriteString(w, name+end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
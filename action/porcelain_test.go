@@ -0,0 +1,26 @@
+package action
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePorcelain(t *testing.T) {
+	names := []string{"foo/bar", "Baz", "foo", "fixed/secret with space"}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, writePorcelain(buf, names, false))
+	assert.Equal(t, "Baz\nfixed/secret with space\nfoo\nfoo/bar\n", buf.String())
+	// the names given are left alone
+	assert.Equal(t, "foo/bar", names[0])
+
+	buf.Reset()
+	assert.NoError(t, writePorcelain(buf, names, true))
+	assert.Equal(t, "Baz\x00fixed/secret with space\x00foo\x00foo/bar\x00", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, writePorcelain(buf, nil, true))
+	assert.Equal(t, "", buf.String())
+}
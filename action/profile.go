@@ -0,0 +1,165 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/log"
+	"github.com/justwatchcom/gopass/profile"
+	"github.com/urfave/cli"
+)
+
+// UseProfile activates the profile selected by --profile, GOPASS_PROFILE or
+// the default of the profiles file. It sets up gpg and git for the profile
+// and switches to its store. It runs before every command.
+func (s *Action) UseProfile(c *cli.Context) error {
+	cfg, err := profile.Load(profile.File())
+	if err != nil {
+		return err
+	}
+	name, p, err := cfg.Active(c.String("profile"))
+	if err != nil || name == "" {
+		return err
+	}
+	if err := p.Apply(); err != nil {
+		return fmt.Errorf("failed to apply profile %s: %s", name, err)
+	}
+	if p.Path != "" || p.Key != "" {
+		if err := s.Store.UseProfile(p.Path, p.Key); err != nil {
+			return fmt.Errorf("failed to open the store of profile %s: %s", name, err)
+		}
+	}
+	s.profile, s.profileKey = name, p.Key
+	log.Debugf("profile: using %s", name)
+	return nil
+}
+
+// Profiles lists all profiles, marking the active one
+func (s *Action) Profiles(c *cli.Context) error {
+	cfg, err := profile.Load(profile.File())
+	if err != nil {
+		return err
+	}
+	if len(cfg.Profiles) < 1 {
+		fmt.Printf("No profiles defined. Add one with `%s profile add <name>`\n", s.Name)
+		return nil
+	}
+	for _, name := range cfg.Names() {
+		p := cfg.Profiles[name]
+		line := "  " + name
+		if name == s.profile {
+			line = "* " + color.GreenString(name)
+		}
+		settings := make([]string, 0, 5)
+		for _, kv := range [][2]string{
+			{"gnupghome", p.GnupgHome},
+			{"key", p.Key},
+			{"path", p.Path},
+			{"git-name", p.GitName},
+			{"git-email", p.GitEmail},
+		} {
+			if kv[1] != "" {
+				settings = append(settings, kv[0]+": "+kv[1])
+			}
+		}
+		if len(settings) > 0 {
+			line += " (" + strings.Join(settings, ", ") + ")"
+		}
+		if name == cfg.Default {
+			line += " [default]"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// ProfileAdd adds a profile or replaces the settings of an existing one
+func (s *Action) ProfileAdd(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" || len(c.Args()) > 1 {
+		return exitError(ExitUsage, "Usage: %s profile add <name>", s.Name)
+	}
+	cfg, err := profile.Load(profile.File())
+	if err != nil {
+		return err
+	}
+	p := profile.Profile{
+		GnupgHome: c.String("gnupghome"),
+		Key:       strings.TrimPrefix(c.String("key"), "0x"),
+		Path:      c.String("path"),
+		GitName:   c.String("git-name"),
+		GitEmail:  c.String("git-email"),
+	}
+	cfg.Profiles[name] = p
+	if c.Bool("default") {
+		cfg.Default = name
+	}
+	if err := cfg.Save(profile.File()); err != nil {
+		return fmt.Errorf("failed to save profiles: %s", err)
+	}
+	fmt.Printf("Saved profile %s\n", color.GreenString(name))
+	return nil
+}
+
+// ProfileRemove removes a profile
+func (s *Action) ProfileRemove(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" || len(c.Args()) > 1 {
+		return exitError(ExitUsage, "Usage: %s profile remove <name>", s.Name)
+	}
+	cfg, err := profile.Load(profile.File())
+	if err != nil {
+		return err
+	}
+	if _, found := cfg.Profiles[name]; !found {
+		return exitError(ExitNotFound, "profile %s does not exist", name)
+	}
+	delete(cfg.Profiles, name)
+	if cfg.Default == name {
+		cfg.Default = ""
+	}
+	if err := cfg.Save(profile.File()); err != nil {
+		return fmt.Errorf("failed to save profiles: %s", err)
+	}
+	fmt.Printf("Removed profile %s\n", color.GreenString(name))
+	return nil
+}
+
+// ProfileDefault sets the profile used unless another one is selected. An
+// empty name clears it.
+func (s *Action) ProfileDefault(c *cli.Context) error {
+	if len(c.Args()) > 1 {
+		return exitError(ExitUsage, "Usage: %s profile default [name]", s.Name)
+	}
+	name := c.Args().First()
+	cfg, err := profile.Load(profile.File())
+	if err != nil {
+		return err
+	}
+	if _, found := cfg.Profiles[name]; name != "" && !found {
+		return exitError(ExitNotFound, "profile %s does not exist", name)
+	}
+	cfg.Default = name
+	return cfg.Save(profile.File())
+}
+
+// isOwnKey returns true if the given key belongs to the user. With a profile
+// that's the key of the profile, otherwise any key with a secret key in the
+// keyring.
+func (s *Action) isOwnKey(id string) bool {
+	id = strings.TrimPrefix(id, "0x")
+	if s.profileKey == "" {
+		kl, err := gpg.ListPrivateKeys(id)
+		return err == nil && len(kl) > 0
+	}
+	if strings.HasSuffix(strings.ToUpper(s.profileKey), strings.ToUpper(id)) {
+		return true
+	}
+	kl, err := gpg.ListPublicKeys(id)
+	if err != nil || len(kl) < 1 {
+		return false
+	}
+	return strings.HasSuffix(kl[0].Fingerprint, strings.ToUpper(s.profileKey))
+}
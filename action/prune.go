@@ -0,0 +1,36 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// PruneExpired removes all secrets that have expired
+func (s *Action) PruneExpired(c *cli.Context) error {
+	expired, err := s.Store.ExpiredSecrets()
+	if err != nil {
+		return err
+	}
+	if len(expired) < 1 {
+		fmt.Println("No expired secrets")
+		return nil
+	}
+
+	fmt.Println("These secrets have expired:")
+	for _, name := range expired {
+		fmt.Printf(" - %s\n", name)
+	}
+	if !c.Bool("force") && !askForConfirmation(fmt.Sprintf("Do you want to remove %d expired secrets?", len(expired))) {
+		return fmt.Errorf("not removing the expired secrets")
+	}
+
+	for _, name := range expired {
+		if err := s.Store.Delete(name); err != nil {
+			return fmt.Errorf("failed to remove %s: %s", name, err)
+		}
+		fmt.Println(color.GreenString("Removed %s", name))
+	}
+	return nil
+}
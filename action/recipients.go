@@ -1,10 +1,18 @@
 package action
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/age"
 	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/password"
 	"github.com/urfave/cli"
 )
 
@@ -27,16 +35,130 @@ credentials.
 `
 )
 
-// RecipientsPrint prints all recipients per store
+// storeRecipients is the JSON representation of the recipients of one store
+type storeRecipients struct {
+	Store         string      `json:"store"`
+	Path          string      `json:"path"`
+	Recipients    []recipient `json:"recipients"`
+	AgeRecipients []string    `json:"agerecipients,omitempty"`
+}
+
+// recipient is a single recipient. Key is nil if the public key is missing
+// from the keyring.
+type recipient struct {
+	ID  string   `json:"id"`
+	Key *gpg.Key `json:"key"`
+}
+
+// RecipientsPrint prints all recipients grouped by store
 func (s *Action) RecipientsPrint(c *cli.Context) error {
-	tree, err := s.Store.RecipientsTree(true)
+	all, err := s.Store.AllRecipients()
 	if err != nil {
 		return err
 	}
-	fmt.Println(tree.Format())
+
+	stores := make([]string, 0, len(all))
+	for alias := range all {
+		stores = append(stores, alias)
+	}
+	sort.Strings(stores)
+
+	// gpg groups are listed by their members
+	groups, _ := gpg.ListGroups()
+	out := make([]storeRecipients, 0, len(stores))
+	for _, alias := range stores {
+		sr := storeRecipients{
+			Store:         alias,
+			Path:          s.Store.Path,
+			Recipients:    make([]recipient, 0, len(all[alias])),
+			AgeRecipients: s.Store.AgeRecipients(alias),
+		}
+		if alias != "" {
+			sr.Path = s.Store.Mount[alias]
+		}
+		for _, id := range gpg.ExpandGroups(all[alias], groups) {
+			sr.Recipients = append(sr.Recipients, s.recipient(id))
+		}
+		out = append(out, sr)
+	}
+
+	if c.Bool("json") {
+		buf, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal recipients: %s", err)
+		}
+		fmt.Println(string(buf))
+		return nil
+	}
+
+	for _, sr := range out {
+		name := sr.Store
+		if name == "" {
+			name = "gopass"
+		}
+		fmt.Println(color.GreenString("%s (%s)", name, sr.Path))
+		for _, rec := range sortByExpiry(sr.Recipients) {
+			fmt.Println(" - " + formatRecipient(rec))
+		}
+		for _, r := range sr.AgeRecipients {
+			fmt.Println(" - age: " + r)
+		}
+	}
+
+	weak, err := s.Store.WeakRecipientKeys()
+	if err != nil {
+		fmt.Println(color.YellowString("Warning: %s", err))
+		return nil
+	}
+	for _, k := range weak {
+		fmt.Println(color.YellowString("Warning: %s is weak, %s", k.OneLine(), k.Weakness(s.Store.MinKeyBits)))
+	}
 	return nil
 }
 
+// sortByExpiry returns the recipients ordered by the expiry of their keys,
+// soonest first, to surface problems first. Recipients with missing keys come
+// before all others.
+func sortByExpiry(recs []recipient) []recipient {
+	out := make([]recipient, 0, len(recs))
+	kl := make(gpg.KeyList, 0, len(recs))
+	byFpr := make(map[string][]recipient, len(recs))
+	for _, rec := range recs {
+		if rec.Key == nil {
+			out = append(out, rec)
+			continue
+		}
+		if _, found := byFpr[rec.Key.Fingerprint]; !found {
+			kl = append(kl, *rec.Key)
+		}
+		byFpr[rec.Key.Fingerprint] = append(byFpr[rec.Key.Fingerprint], rec)
+	}
+	kl.SortBy(gpg.ByExpiry)
+	for _, k := range kl {
+		out = append(out, byFpr[k.Fingerprint]...)
+	}
+	return out
+}
+
+// formatRecipient returns a one line description of the recipient with
+// markers for missing, expired and untrusted keys
+func formatRecipient(rec recipient) string {
+	if rec.Key == nil {
+		return rec.ID + color.RedString(" [key missing]")
+	}
+	out := rec.Key.OneLine()
+	if rec.Key.IsExpired() {
+		return out + color.RedString(" [expired: %s]", rec.Key.ExpirationDate.Format("2006-01-02"))
+	}
+	if !rec.Key.IsUseable() {
+		out += color.YellowString(" [untrusted]")
+	}
+	if !rec.Key.ExpirationDate.IsZero() {
+		out += fmt.Sprintf(" [expires: %s]", rec.Key.ExpirationDate.Format("2006-01-02"))
+	}
+	return out
+}
+
 // RecipientsComplete will print a list of recipients for bash
 // completion
 func (s *Action) RecipientsComplete(*cli.Context) {
@@ -54,9 +176,38 @@ func (s *Action) RecipientsComplete(*cli.Context) {
 
 // RecipientsAdd adds new recipients
 func (s *Action) RecipientsAdd(c *cli.Context) error {
+	if c.Bool("all") && c.Bool("dry-run") {
+		return exitError(ExitUsage, "--dry-run can't be combined with --all")
+	}
+	if c.Bool("all") {
+		for _, r := range c.Args() {
+			if age.IsRecipient(r) {
+				return exitError(ExitUsage, "age recipients can't be added to all stores at once, use --store")
+			}
+			if err := s.Store.AddRecipientToAll(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	store := c.String("store")
 	added := 0
 	for _, r := range c.Args() {
+		if age.IsRecipient(r) {
+			if c.Bool("dry-run") {
+				continue
+			}
+			if !askForConfirmation(fmt.Sprintf("Do you want to add the age recipient '%s'?", r)) {
+				continue
+			}
+			if err := s.Store.AddAgeRecipient(store, r); err != nil {
+				return err
+			}
+			added++
+			continue
+		}
+
 		keys, err := gpg.ListPublicKeys(r)
 		if err != nil {
 			return fmt.Errorf("Failed to list public keys: %s", err)
@@ -64,6 +215,19 @@ func (s *Action) RecipientsAdd(c *cli.Context) error {
 		if len(keys) < 1 {
 			return fmt.Errorf("no matching key found in keyring")
 		}
+		if err := s.Store.CheckRecipientPins(store, []string{r}); err != nil {
+			return err
+		}
+
+		rs := append(append([]string{}, s.Store.ListRecipients(store)...), keys[0].Fingerprint)
+		plan, err := s.Store.RecipientChangePlan(store, rs)
+		if err != nil {
+			return fmt.Errorf("failed to check the recipient change: %s", err)
+		}
+		printPlan(plan)
+		if c.Bool("dry-run") {
+			continue
+		}
 
 		if !askForConfirmation(fmt.Sprintf("Do you want to add '%s' as an recipient?", keys[0].OneLine())) {
 			continue
@@ -74,21 +238,61 @@ func (s *Action) RecipientsAdd(c *cli.Context) error {
 		}
 		added++
 	}
-	fmt.Printf("Added %d recipients\n", added)
+	if !c.Bool("dry-run") {
+		fmt.Printf("Added %d recipients\n", added)
+	}
 	return nil
 }
 
 // RecipientsRemove removes recipients
 func (s *Action) RecipientsRemove(c *cli.Context) error {
+	s.Store.SetDropRequired(c.Bool("drop-required"))
+	if c.Bool("all") && c.Bool("dry-run") {
+		return exitError(ExitUsage, "--dry-run can't be combined with --all")
+	}
+	if c.Bool("all") {
+		for _, r := range c.Args() {
+			if age.IsRecipient(r) {
+				return exitError(ExitUsage, "age recipients can't be removed from all stores at once, use --store")
+			}
+			if err := s.Store.RemoveRecipientFromAll(r); err != nil {
+				return err
+			}
+			fmt.Printf(removalWarning, r)
+		}
+		return nil
+	}
+
 	store := c.String("store")
 	removed := 0
 	for _, r := range c.Args() {
-		kl, err := gpg.ListPrivateKeys(r)
-		if err == nil {
-			if len(kl) > 0 {
-				if !askForConfirmation(fmt.Sprintf("Do you want to remove yourself (%s) from the recipients?", r)) {
-					continue
-				}
+		if age.IsRecipient(r) {
+			if c.Bool("dry-run") {
+				continue
+			}
+			if !askForConfirmation(fmt.Sprintf("Do you want to remove the age recipient '%s'?", r)) {
+				continue
+			}
+			if err := s.Store.RemoveAgeRecipient(store, r); err != nil {
+				return err
+			}
+			fmt.Printf(removalWarning, r)
+			removed++
+			continue
+		}
+
+		plan, err := s.Store.RecipientChangePlan(store, withoutRecipient(s.Store.ListRecipients(store), r))
+		if err != nil {
+			return fmt.Errorf("failed to check the recipient change: %s", err)
+		}
+		printPlan(plan)
+		if c.Bool("dry-run") {
+			continue
+		}
+
+		if s.isOwnKey(r) || plan.LocksOut {
+			if !askForConfirmation(fmt.Sprintf("Do you want to remove yourself (%s) from the recipients?", r)) {
+				continue
 			}
 		}
 		if err := s.Store.RemoveRecipient(store, strings.TrimPrefix(r, "0x")); err != nil {
@@ -97,6 +301,256 @@ func (s *Action) RecipientsRemove(c *cli.Context) error {
 		fmt.Printf(removalWarning, r)
 		removed++
 	}
-	fmt.Printf("Removed %d recipients\n", removed)
+	if !c.Bool("dry-run") {
+		fmt.Printf("Removed %d recipients\n", removed)
+	}
+	return nil
+}
+
+// RecipientsPin pins the given recipients of a store, or all of them, to
+// the fingerprint of their key in the keyring
+func (s *Action) RecipientsPin(c *cli.Context) error {
+	store := c.String("store")
+	ids := []string(c.Args())
+	if len(ids) < 1 {
+		ids = s.Store.ListRecipients(store)
+	}
+	if len(ids) < 1 {
+		return exitError(ExitUsage, "Usage: %s recipients pin [--store <store>] [recipient...]", s.Name)
+	}
+	for _, id := range ids {
+		keys, err := gpg.ListPublicKeys(id)
+		if err != nil || len(keys) != 1 {
+			continue
+		}
+		fmt.Printf(" - %s: %s\n", id, keys[0].OneLine())
+	}
+	if !s.Store.NoConfirm && !askForConfirmation(fmt.Sprintf("Did you verify the fingerprints of these %d keys with their owners?", len(ids))) {
+		return errAborted
+	}
+	if err := s.Store.PinRecipients(store, ids...); err != nil {
+		return err
+	}
+	if err := writeConfig(s.Store); err != nil {
+		return fmt.Errorf("failed to save the key pins: %s", err)
+	}
+	fmt.Printf("Pinned the keys of %d recipients\n", len(ids))
 	return nil
 }
+
+// RecipientsRequire makes the given recipients of a store required
+func (s *Action) RecipientsRequire(c *cli.Context) error {
+	store := c.String("store")
+	ids := []string(c.Args())
+	if len(ids) < 1 {
+		return exitError(ExitUsage, "Usage: %s recipients require [--store <store>] <recipient>...", s.Name)
+	}
+	if err := s.Store.RequireRecipients(store, ids...); err != nil {
+		return err
+	}
+	fmt.Printf("Required recipients: %s\n", strings.Join(s.Store.RequiredRecipients(store), ", "))
+	return nil
+}
+
+// RecipientsOverride shows or sets the recipients of a single secret
+func (s *Action) RecipientsOverride(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return exitError(ExitUsage, "Usage: gopass recipients override secret [recipient...]")
+	}
+
+	if c.Bool("clear") {
+		if err := s.Store.RemoveRecipientOverride(name); err != nil {
+			return err
+		}
+		fmt.Printf("%s is encrypted for the recipients of its store again\n", color.YellowString(name))
+		return nil
+	}
+
+	if len(c.Args()) < 2 {
+		rs := s.Store.RecipientOverride(name)
+		if len(rs) < 1 {
+			fmt.Printf("%s has no recipient override\n", name)
+			return nil
+		}
+		for _, r := range rs {
+			fmt.Println(" - " + formatRecipient(s.recipient(r)))
+		}
+		return nil
+	}
+
+	ids := make([]string, 0, len(c.Args())-1)
+	for _, r := range c.Args()[1:] {
+		kl, err := gpg.ListPublicKeys(r)
+		if err != nil {
+			return fmt.Errorf("Failed to list public keys: %s", err)
+		}
+		if len(kl) < 1 {
+			return fmt.Errorf("no matching key found in keyring for %s", r)
+		}
+		ids = append(ids, kl[0].Fingerprint)
+	}
+
+	if err := s.Store.SetRecipientOverride(name, ids); err != nil {
+		return err
+	}
+	fmt.Printf("%s is now only encrypted for %d recipients\n", color.YellowString(name), len(ids))
+	return nil
+}
+
+// withoutRecipient returns the recipients except the given one, which may
+// be given by any ID of its key
+func withoutRecipient(rs []string, id string) []string {
+	id = strings.TrimPrefix(id, "0x")
+	kl, _ := gpg.ListPublicKeys(id)
+	out := make([]string, 0, len(rs))
+	for _, r := range rs {
+		if r == id || (len(kl) > 0 && strings.HasSuffix(kl[0].Fingerprint, r)) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// printPlan prints what a recipient change would do, see
+// password.Store.RecipientChangePlan
+func printPlan(p password.Plan) {
+	fmt.Printf("%d secrets will be re-encrypted, which takes %s\n", p.Secrets, roughDuration(p.Duration))
+	if p.Overrides > 0 {
+		fmt.Printf(" - %d of them keep their recipient override\n", p.Overrides)
+	}
+	if p.Symmetric > 0 {
+		fmt.Printf(" - %d passphrase-only secrets are skipped\n", p.Symmetric)
+	}
+	for _, r := range p.Added {
+		fmt.Println(color.GreenString(" + %s", r))
+	}
+	for _, r := range p.Removed {
+		fmt.Println(color.RedString(" - %s", r))
+	}
+	for _, r := range p.Missing {
+		fmt.Println(color.RedString("Warning: no public key of %s in the keyring", r))
+	}
+	for _, k := range p.Expired {
+		fmt.Println(color.RedString("Warning: %s has expired", k.OneLine()))
+	}
+	for _, k := range p.Untrusted {
+		fmt.Println(color.YellowString("Warning: %s is not trusted", k.OneLine()))
+	}
+	for _, k := range p.Weak {
+		fmt.Println(color.YellowString("Warning: %s is weak", k.OneLine()))
+	}
+	for _, r := range p.RemovedRequired {
+		fmt.Println(color.RedString("Warning: %s is a required recipient", r))
+	}
+	if p.LocksOut {
+		fmt.Println(color.RedString("Warning: none of the new recipients has a secret key in your keyring. You won't be able to decrypt the secrets afterwards"))
+	}
+}
+
+// roughDuration formats an estimated duration to the second
+func roughDuration(d time.Duration) string {
+	if d < time.Second {
+		return "less than a second"
+	}
+	secs := int((d + time.Second/2) / time.Second)
+	if secs < 120 {
+		return fmt.Sprintf("about %d seconds", secs)
+	}
+	return fmt.Sprintf("about %d minutes", (secs+30)/60)
+}
+
+// recipient looks up the public key of the given recipient
+func (s *Action) recipient(id string) recipient {
+	rec := recipient{ID: id}
+	if kl, err := gpg.ListPublicKeys(id); err == nil && len(kl) > 0 {
+		rec.Key = &kl[0]
+	}
+	return rec
+}
+
+// parseRecipientFile reads a newline separated list of key IDs from the given
+// file. Blank lines and comments (#) are ignored. Entries starting with an @
+// are expanded to the members of the gpg group with that name.
+func parseRecipientFile(path string) ([]string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+
+	var groups map[string][]string
+	recipients := make([]string, 0, 10)
+	problems := make([]string, 0, 1)
+
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "@") {
+			recipients = append(recipients, line)
+			continue
+		}
+		if groups == nil {
+			groups, err = gpg.ListGroups()
+			if err != nil {
+				return nil, fmt.Errorf("Failed to list gpg groups: %s", err)
+			}
+		}
+		members, found := groups[strings.TrimPrefix(line, "@")]
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: unknown group", line))
+			continue
+		}
+		recipients = append(recipients, members...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("Invalid recipients in %s:\n - %s", path, strings.Join(problems, "\n - "))
+	}
+	if len(recipients) < 1 {
+		return nil, fmt.Errorf("No recipients found in %s", path)
+	}
+	return recipients, nil
+}
+
+// recipientsFromFile reads the recipients from the given file and makes sure
+// that a public key is available for each of them, importing it from the given
+// store if necessary. It returns the fingerprints of the recipients.
+func (s *Action) recipientsFromFile(store, path string) ([]string, error) {
+	ids, err := parseRecipientFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]string, 0, len(ids))
+	problems := make([]string, 0, 1)
+	for _, id := range ids {
+		if err := s.Store.ImportMissingPublicKey(store, id); err != nil {
+			fmt.Println(err)
+		}
+		kl, err := gpg.ListPublicKeys(id)
+		if err != nil || len(kl) < 1 {
+			problems = append(problems, fmt.Sprintf("%s: no public key found", id))
+			continue
+		}
+		if !s.Store.AlwaysTrust && len(kl.UseableKeys()) < 1 {
+			problems = append(problems, fmt.Sprintf("%s: public key is not useable", id))
+			continue
+		}
+		recipients = append(recipients, kl[0].Fingerprint)
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("Invalid recipients in %s:\n - %s", path, strings.Join(problems, "\n - "))
+	}
+	return recipients, nil
+}
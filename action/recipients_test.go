@@ -0,0 +1,73 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecipientFile(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	fn := filepath.Join(tempdir, "recipients")
+	content := "# team\n0xDEADBEEF\n\n  FEEDBEEF  \n# gopher@golang.org\n"
+	if err := ioutil.WriteFile(fn, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write recipients file: %s", err)
+	}
+	recs, err := parseRecipientFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0xDEADBEEF", "FEEDBEEF"}, recs)
+
+	if err := ioutil.WriteFile(fn, []byte("# nobody\n"), 0600); err != nil {
+		t.Fatalf("Failed to write recipients file: %s", err)
+	}
+	_, err = parseRecipientFile(fn)
+	assert.Error(t, err)
+
+	_, err = parseRecipientFile(filepath.Join(tempdir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestFormatRecipient(t *testing.T) {
+	color.NoColor = true
+
+	assert.Equal(t, "DEADBEEF [key missing]", formatRecipient(recipient{ID: "DEADBEEF"}))
+
+	k := gpg.Key{
+		Validity:    "u",
+		Fingerprint: "AB919DBF9BF0DE74896397F282EBD945BE73F104",
+		Identities: map[string]gpg.Identity{
+			"John Doe <john.doe@example.com>": {Name: "John Doe", Email: "john.doe@example.com"},
+		},
+	}
+	assert.Equal(t, "0x82EBD945BE73F104 - John Doe <john.doe@example.com>", formatRecipient(recipient{ID: "BE73F104", Key: &k}))
+
+	k.Validity = "-"
+	assert.Equal(t, "0x82EBD945BE73F104 - John Doe <john.doe@example.com> [untrusted]", formatRecipient(recipient{ID: "BE73F104", Key: &k}))
+
+	k.ExpirationDate = time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "0x82EBD945BE73F104 - John Doe <john.doe@example.com> [expired: 2010-01-01]", formatRecipient(recipient{ID: "BE73F104", Key: &k}))
+}
+
+func TestRoughDuration(t *testing.T) {
+	for d, out := range map[time.Duration]string{
+		300 * time.Millisecond:          "less than a second",
+		1600 * time.Millisecond:         "about 2 seconds",
+		90 * time.Second:                "about 90 seconds",
+		10*time.Minute + 20*time.Second: "about 10 minutes",
+	} {
+		assert.Equal(t, out, roughDuration(d))
+	}
+}
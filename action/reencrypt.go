@@ -0,0 +1,42 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/urfave/cli"
+)
+
+// Reencrypt re-encrypts a secret, or all secrets below a folder, for their
+// current recipients without changing their content. This picks up the
+// current keys of the recipients, e.g. after a key rotation.
+func (s *Action) Reencrypt(c *cli.Context) error {
+	name := c.Args().First()
+	s.Store.SetAllowExpired(c.Bool("allow-expired"))
+	if name != "" && !s.Store.IsDir(name) {
+		found, err := s.Store.Exists(name)
+		if err != nil {
+			return fmt.Errorf("failed to see if %s exists: %s", name, err)
+		}
+		if !found {
+			return exitError(ExitNotFound, "%s is not in the password store", name)
+		}
+	}
+
+	if _, err := s.confirmRecipients(name, s.Store.ListRecipients(name)); err != nil {
+		return err
+	}
+
+	if err := s.Store.Reencrypt(name); err != nil {
+		if err == password.ErrNotFound {
+			return exitError(ExitNotFound, "no secrets found below %s", name)
+		}
+		return err
+	}
+	if name == "" {
+		name = "all secrets"
+	}
+	fmt.Println(color.GreenString("Re-encrypted %s", name))
+	return nil
+}
@@ -0,0 +1,98 @@
+package action
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/urfave/cli"
+)
+
+// maxReplacePreview is the number of secrets listed before replacing a field
+const maxReplacePreview = 10
+
+// Replace replaces a value of a field, e.g. the domain of the url, in all
+// secrets below the prefix. The secrets changed are listed, without their
+// content, and confirmed before anything is written. The stores stay locked
+// in between, so nothing else can change them.
+func (s *Action) Replace(c *cli.Context) error {
+	rp := password.Replacement{
+		Field:  c.String("field"),
+		Old:    c.Args().Get(0),
+		New:    c.Args().Get(1),
+		Prefix: c.Args().Get(2),
+		Exact:  c.Bool("exact"),
+	}
+	if rp.Field == "" || rp.Old == "" || len(c.Args()) < 2 {
+		return exitError(ExitUsage, "Usage: %s replace [--exact] --field <field> <old> <new> [prefix]", s.Name)
+	}
+
+	reported := make(map[string]bool, 1)
+	declined := false
+	confirm := func(report password.ReplaceReport) bool {
+		printReplaceSkipped(report.Skipped)
+		printReplaceFailures(report.Failed, reported)
+		fmt.Printf("Replacing %s with %s in %d %s fields of %d secrets:\n", rp.Old, rp.New, report.Replaced, rp.Field, len(report.Changed))
+		for i, name := range report.Changed {
+			if i >= maxReplacePreview {
+				fmt.Printf(" ... and %d more\n", len(report.Changed)-maxReplacePreview)
+				break
+			}
+			fmt.Printf(" - %s\n", name)
+		}
+		if c.Bool("force") || s.Store.NoConfirm || askForConfirmation("Do you want to continue?") {
+			return true
+		}
+		declined = true
+		return false
+	}
+
+	report, err := s.Store.ReplaceField(rp, confirm)
+	if declined {
+		return errAborted
+	}
+	if err != nil {
+		return err
+	}
+	if len(report.Changed) < 1 {
+		printReplaceSkipped(report.Skipped)
+		printReplaceFailures(report.Failed, reported)
+		if rp.Exact {
+			fmt.Printf("No %s field is %s\n", rp.Field, rp.Old)
+		} else {
+			fmt.Printf("No %s field contains %s\n", rp.Field, rp.Old)
+		}
+		return nil
+	}
+	printReplaceFailures(report.Failed, reported)
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("failed to replace %s in %d secrets", rp.Field, len(report.Failed))
+	}
+	fmt.Println(color.GreenString("Replaced %s in %d secrets", rp.Field, len(report.Changed)))
+	return nil
+}
+
+// printReplaceSkipped reports the passphrase encrypted secrets, which aren't
+// searched
+func printReplaceSkipped(skipped []string) {
+	for _, name := range skipped {
+		fmt.Println(color.YellowString("Skipping passphrase encrypted secret %s", name))
+	}
+}
+
+// printReplaceFailures reports the secrets that couldn't be read or written
+// and aren't in reported yet
+func printReplaceFailures(failed map[string]error, reported map[string]bool) {
+	names := make([]string, 0, len(failed))
+	for name := range failed {
+		if !reported[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(color.RedString("Failed to replace in %s: %s", name, failed[name]))
+		reported[name] = true
+	}
+}
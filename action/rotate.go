@@ -0,0 +1,184 @@
+package action
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/justwatchcom/gopass/pwgen"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli"
+)
+
+const (
+	// historyKey labels the list of previous passwords in a secret's body
+	historyKey = "previous_passwords"
+	// defaultHistory is the number of previous passwords kept by rotate
+	defaultHistory = 3
+)
+
+// Rotate replaces the password of an existing secret with a generated one.
+// The old password is printed once, so it can be changed on the service,
+// and kept in the history of the secret. The new one is copied to the
+// clipboard.
+func (s *Action) Rotate(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return exitError(ExitUsage, "Usage: %s rotate [--keep N] [--prune] <name> [length]", s.Name)
+	}
+	keep := c.Int("keep")
+	if keep < 0 {
+		return exitError(ExitUsage, "--keep must not be negative")
+	}
+	s.Store.SetAllowExpired(c.Bool("allow-expired"))
+
+	found, err := s.Store.Exists(name)
+	if err != nil {
+		return fmt.Errorf("failed to see if %s exists: %s", name, err)
+	}
+	if !found {
+		return exitError(ExitNotFound, "%s does not exist. Use `%s generate %s` to create it", name, s.Name, name)
+	}
+	old, err := s.Store.GetRaw(name)
+	if err != nil {
+		return err
+	}
+	oldPassword, body := password.SplitSecret(old)
+
+	if c.Bool("prune") {
+		n := len(passwordHistory(body))
+		if n < 1 {
+			fmt.Printf("%s has no previous passwords\n", name)
+			return nil
+		}
+		content := append([]byte(string(oldPassword)+"\n"), withHistory(body, "", 0)...)
+		if err := s.setRotated(name, content); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d previous passwords of %s\n", n, color.YellowString(name))
+		return nil
+	}
+
+	// password rules given now are stored with the secret
+	content := old
+	spec := c.String("password-rules")
+	if spec != "" {
+		content = withRules(oldPassword, old, spec)
+	} else {
+		spec = secretRules(old)
+	}
+
+	var pw string
+	var bits float64
+	if spec != "" {
+		if pw, bits, err = generateWithRules(spec, c.Args().Get(1), pwgen.GenerateWithRules); err != nil {
+			return err
+		}
+	} else {
+		pwlen := defaultLength
+		if l := c.Args().Get(1); l != "" {
+			if pwlen, err = strconv.Atoi(l); err != nil {
+				return fmt.Errorf("password length must be a number")
+			}
+		}
+		if pwlen < 1 {
+			return fmt.Errorf("password length must be bigger than 0")
+		}
+		pw = string(pwgen.GeneratePassword(pwlen, !c.Bool("no-symbols")))
+		bits = pwgen.Entropy(pwlen, !c.Bool("no-symbols"))
+	}
+
+	_, body = password.SplitSecret(content)
+	content = append([]byte(pw+"\n"), withHistory(body, string(oldPassword), keep)...)
+	if err := s.setRotated(name, content); err != nil {
+		return err
+	}
+	fmt.Printf("Rotated the password of %s, the new one has %d bits of entropy\n", color.YellowString(name), int(bits))
+
+	if isatty.IsTerminal(os.Stdout.Fd()) {
+		fmt.Printf("The old password of %s was:\n%s\n", name, color.YellowString(string(oldPassword)))
+	} else {
+		fmt.Println(color.YellowString("Warning: Not printing the old password, stdout is not a terminal"))
+	}
+
+	if err := s.copyToClipboard(name, []byte(pw), c.BoolT("verify"), c.Bool("wait")); err != nil {
+		fmt.Println(color.YellowString("Warning: %s. Use `%s show %s` to see the password", err, s.Name, name))
+	}
+	return nil
+}
+
+// setRotated stores the rotated content, keeping symmetric secrets symmetric
+func (s *Action) setRotated(name string, content []byte) error {
+	if s.Store.IsSymmetric(name) {
+		return s.setSymmetric(name, content)
+	}
+	return s.Store.SetConfirm(name, content, s.confirmRecipients)
+}
+
+// passwordHistory returns the previous passwords listed in the body of a
+// secret, the most recent first
+func passwordHistory(body []byte) []string {
+	history := make([]string, 0, defaultHistory)
+	inHistory := false
+	for _, line := range strings.Split(string(body), "\n") {
+		if line == historyKey+":" {
+			inHistory = true
+			continue
+		}
+		if !inHistory {
+			continue
+		}
+		if !strings.HasPrefix(line, "  - ") {
+			break
+		}
+		pw, err := strconv.Unquote(strings.TrimPrefix(line, "  - "))
+		if err != nil {
+			continue
+		}
+		history = append(history, pw)
+	}
+	return history
+}
+
+// withHistory returns the body with old added to the front of its
+// password history, which is cut to the keep most recent passwords. The
+// history is a YAML list at the end of the body. Without any passwords to
+// keep it's removed.
+func withHistory(body []byte, old string, keep int) []byte {
+	history := passwordHistory(body)
+	if old != "" {
+		history = append([]string{old}, history...)
+	}
+	if len(history) > keep {
+		history = history[:keep]
+	}
+
+	out := make([]string, 0, 10)
+	inHistory := false
+	for _, line := range strings.Split(strings.TrimRight(string(body), "\n"), "\n") {
+		if line == historyKey+":" {
+			inHistory = true
+			continue
+		}
+		if inHistory && strings.HasPrefix(line, "  - ") {
+			continue
+		}
+		inHistory = false
+		if line != "" || len(out) > 0 {
+			out = append(out, line)
+		}
+	}
+	if len(history) > 0 {
+		out = append(out, historyKey+":")
+		for _, pw := range history {
+			out = append(out, "  - "+strconv.Quote(pw))
+		}
+	}
+	if len(out) < 1 {
+		return nil
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
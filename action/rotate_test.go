@@ -0,0 +1,32 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHistory(t *testing.T) {
+	body := []byte("user: gopher\n")
+
+	body = withHistory(body, "first", 2)
+	assert.Equal(t, "user: gopher\nprevious_passwords:\n  - \"first\"\n", string(body))
+	assert.Equal(t, []string{"first"}, passwordHistory(body))
+
+	// the most recent password comes first, the oldest are dropped
+	body = withHistory(body, `sec"ond`, 2)
+	body = withHistory(body, "third: 3", 2)
+	assert.Equal(t, []string{"third: 3", `sec"ond`}, passwordHistory(body))
+	assert.Equal(t, "user: gopher\nprevious_passwords:\n  - \"third: 3\"\n  - \"sec\\\"ond\"\n", string(body))
+
+	// lines after the history are kept
+	body = append(body, []byte("url: example.org\n")...)
+	body = withHistory(body, "fourth", 3)
+	assert.Equal(t, []string{"fourth", "third: 3", `sec"ond`}, passwordHistory(body))
+	assert.Equal(t, "user: gopher\nurl: example.org\nprevious_passwords:\n  - \"fourth\"\n  - \"third: 3\"\n  - \"sec\\\"ond\"\n", string(body))
+
+	// pruning removes the history
+	assert.Equal(t, "user: gopher\nurl: example.org\n", string(withHistory(body, "", 0)))
+	assert.Nil(t, withHistory([]byte("previous_passwords:\n  - \"first\"\n"), "", 0))
+	assert.Equal(t, "previous_passwords:\n  - \"first\"\n", string(withHistory(nil, "first", 3)))
+}
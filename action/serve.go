@@ -0,0 +1,46 @@
+package action
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/httpapi"
+	"github.com/urfave/cli"
+)
+
+const (
+	// envAPIToken holds the token of the HTTP API, so it doesn't show up in
+	// the process list
+	envAPIToken = "GOPASS_API_TOKEN"
+	// apiTokenBytes is the length of generated tokens
+	apiTokenBytes = 32
+)
+
+// Serve serves the secrets to local tools over HTTP, see httpapi.Serve.
+// Unix sockets authenticate clients by their user, TCP addresses by a
+// token. Without one in GOPASS_API_TOKEN a random token is generated.
+func (s *Action) Serve(c *cli.Context) error {
+	addr := c.String("addr")
+	var auth httpapi.Authenticator
+	switch token := os.Getenv(envAPIToken); {
+	case token != "":
+		auth = httpapi.Token(token)
+	case strings.HasPrefix(addr, "unix:"):
+		auth = httpapi.PeerUser()
+	default:
+		buf := make([]byte, apiTokenBytes)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("failed to generate a token: %s", err)
+		}
+		token = hex.EncodeToString(buf)
+		auth = httpapi.Token(token)
+		fmt.Fprintf(os.Stderr, "Token: %s\n", token)
+	}
+
+	fmt.Fprintln(os.Stderr, color.GreenString("Serving secrets on %s, press Ctrl-C to stop", addr))
+	return httpapi.Serve(s.Store, addr, auth)
+}
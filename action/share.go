@@ -0,0 +1,212 @@
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/share"
+	"github.com/urfave/cli"
+)
+
+// Share writes a copy of a secret encrypted only for the given recipients,
+// e.g. to send it to someone who has no access to the store. The recipients
+// are not added to the store. With --expire the copy is wrapped in a bundle
+// signed by the sharer, which unshare refuses to open after it expired.
+func (s *Action) Share(c *cli.Context) error {
+	name := c.Args().First()
+	recipients := c.Args().Tail()
+	if name == "" || len(recipients) < 1 {
+		return exitError(ExitUsage, "Usage: %s share <secret> <recipient>...", s.Name)
+	}
+
+	var expires time.Time
+	if e := c.String("expire"); e != "" {
+		t, err := parseDate(e, time.Now())
+		if err != nil {
+			return err
+		}
+		if !t.After(time.Now()) {
+			return fmt.Errorf("the expiry must be in the future")
+		}
+		expires = t
+	}
+
+	found, err := s.Store.Exists(name)
+	if err != nil {
+		return fmt.Errorf("failed to see if %s exists: %s", name, err)
+	}
+	if !found {
+		return exitError(ExitNotFound, "%s is not in the password store", name)
+	}
+
+	// keep the messages out of a shared secret written to stdout
+	output := c.String("output")
+	var msgs io.Writer = os.Stdout
+	if output == "-" {
+		msgs = os.Stderr
+	}
+
+	for _, r := range recipients {
+		if err := s.Store.ImportMissingPublicKey(name, r); err != nil {
+			fmt.Fprintln(msgs, color.YellowString("Warning: %s", err))
+		}
+	}
+
+	export := func(w io.Writer) error {
+		if expires.IsZero() {
+			return s.Store.ExportEncrypted(name, recipients, w)
+		}
+		return s.exportBundle(w, name, recipients, expires)
+	}
+
+	if err := s.confirmShareRecipients(msgs, name, recipients); err != nil {
+		return err
+	}
+	if output == "-" {
+		return export(os.Stdout)
+	}
+
+	if output == "" {
+		td, err := ioutil.TempDir("", "gopass-share-")
+		if err != nil {
+			return err
+		}
+		ext := ".gpg"
+		if !expires.IsZero() {
+			ext = ".share"
+		}
+		output = filepath.Join(td, path.Base(name)+ext)
+	}
+
+	fh, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := export(fh); err != nil {
+		_ = fh.Close()
+		_ = os.Remove(output)
+		return err
+	}
+	if err := fh.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s encrypted for %d recipients to %s\n", color.YellowString(name), len(recipients), output)
+	if !expires.IsZero() {
+		fmt.Printf("The share expires on %s. Open it with `%s unshare`\n", expires.Local().Format(dateLayout), s.Name)
+	}
+	return nil
+}
+
+// confirmShareRecipients checks the pins of the recipients of a share and
+// asks the user to confirm them, printing the recipients and the question to
+// w. The share is encrypted for these recipients only, so neither the age
+// recipients nor a recipient override of the secret are listed.
+func (s *Action) confirmShareRecipients(w io.Writer, name string, recipients []string) error {
+	if err := s.Store.CheckRecipientPins(name, recipients); err != nil {
+		return err
+	}
+	if s.Store.NoConfirm {
+		return nil
+	}
+	fmt.Fprintf(w, "gopass: Sharing %s with these recipients:\n", name)
+	sort.Strings(recipients)
+	groups, _ := gpg.ListGroups()
+	for _, r := range recipients {
+		members, found := groups[r]
+		if !found {
+			s.printRecipient(w, " ", r, nil, false)
+			continue
+		}
+		fmt.Fprintf(w, " - group %s:\n", r)
+		for _, m := range members {
+			s.printRecipient(w, "   ", m, nil, false)
+		}
+	}
+	fmt.Fprintln(w, "")
+
+	if err := s.confirmExpired(w, recipients); err != nil {
+		return err
+	}
+	yes, err := askForBoolTo(w, "Do you want to continue?", true)
+	if err != nil {
+		return err
+	}
+	if !yes {
+		return errAborted
+	}
+	return nil
+}
+
+// exportBundle writes a signed share bundle of the secret, which expires at
+// the given time, to w
+func (s *Action) exportBundle(w io.Writer, name string, recipients []string, expires time.Time) error {
+	buf := &bytes.Buffer{}
+	if err := s.Store.ExportEncrypted(name, recipients, buf); err != nil {
+		return err
+	}
+	b := share.New(name, buf.Bytes(), expires)
+	if err := b.Sign(s.Store.SignKey); err != nil {
+		return err
+	}
+	out, err := b.Encode()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// Unshare opens a share bundle created by share --expire and prints the
+// secret, unless the bundle has expired or its signature is invalid
+func (s *Action) Unshare(c *cli.Context) error {
+	fn := c.Args().First()
+	if fn == "" {
+		return exitError(ExitUsage, "Usage: %s unshare <file>", s.Name)
+	}
+
+	var buf []byte
+	var err error
+	if fn == "-" {
+		buf, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		buf, err = ioutil.ReadFile(fn)
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := share.Decode(buf)
+	if err != nil {
+		return err
+	}
+	signer, err := b.Verify()
+	if err != nil {
+		return err
+	}
+	if kl, err := gpg.ListPublicKeys(signer); err == nil && len(kl) > 0 {
+		signer = kl[0].OneLine()
+	}
+	fmt.Printf("%s was shared by %s\n", color.YellowString(b.Metadata.Path), signer)
+
+	content, err := b.Open(time.Now())
+	if err == share.ErrExpired {
+		return fmt.Errorf("the share expired on %s", b.Metadata.Expires.Local().Format(dateLayout))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("The share expires on %s\n", b.Metadata.Expires.Local().Format(dateLayout))
+
+	color.Yellow(string(content))
+	return nil
+}
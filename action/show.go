@@ -3,38 +3,281 @@ package action
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
-	"github.com/atotto/clipboard"
 	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/log"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/justwatchcom/gopass/pwgen"
+	"github.com/mattn/go-isatty"
 	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // Show the content of a secret file
 func (s *Action) Show(c *cli.Context) error {
 	name := c.Args().First()
 	if name == "" {
-		return fmt.Errorf("provide a secret name")
+		return exitError(ExitUsage, "provide a secret name")
 	}
 
 	if s.Store.IsDir(name) {
 		return s.List(c)
 	}
 
-	content, err := s.Store.Get(name)
+	if fpr := c.String("as"); fpr != "" {
+		return s.showAs(name, fpr)
+	}
+
+	// references to other fields are resolved, unless the secret is
+	// printed as it is stored
+	content, err := s.Store.GetResolved(name, !c.Bool("raw"))
 	if err != nil {
 		return err
 	}
 
+	if t, ok := password.ParseExpiry(content); ok && !time.Now().Before(t) {
+		if c.Bool("strict") {
+			return fmt.Errorf("%s expired on %s", name, t.Local().Format(dateLayout))
+		}
+		fmt.Println(color.YellowString("Warning: %s expired on %s. Run `%s prune` to remove expired secrets", name, t.Local().Format(dateLayout), s.Name))
+	}
+
+	if c.Bool("queue") {
+		if c.Bool("wait") {
+			return exitError(ExitUsage, "--queue can not be combined with --wait")
+		}
+		return s.copyQueue(name, content, c.Args().Tail(), c.BoolT("verify"))
+	}
+
 	if c.Bool("clip") {
-		return s.copyToClipboard(name, content)
+		return s.copyToClipboard(name, content, c.BoolT("verify"), c.Bool("wait"))
+	}
+
+	if n := c.Int("reveal"); n > 0 {
+		return revealPassword(content, time.Duration(n)*time.Second)
+	}
+
+	opts := showOpts{
+		safe:     s.safeContent(c),
+		password: c.Bool("password"),
+		raw:      c.Bool("raw"),
+		terminal: isatty.IsTerminal(os.Stdout.Fd()),
+	}
+	newline := !c.Bool("no-newline") && !s.Store.NoNewline
+	// the stats are about the password, which is only shown if asked for
+	if c.Bool("stats") {
+		opts.safe = true
+		if opts.terminal {
+			defer s.printStats(name, content, c.Bool("reused"))
+		}
+	}
+	out, withPassword := showContent(content, opts)
+	if !withPassword && len(bytes.TrimSpace(out)) < 1 {
+		fmt.Println(color.YellowString("%s only holds a password. Use --password or --clip to show it", name))
+		return nil
+	}
+
+	// scripts get the secret as it is stored
+	if !opts.raw && opts.terminal {
+		fopts := formatOpts{color: s.color}
+		if withPassword {
+			if f := formatSecretBody(out, fopts); !bytes.Equal(f, out) {
+				printValue(os.Stdout, strings.TrimRight(string(f), "\n"), fmt.Sprintf, newline)
+				return nil
+			}
+		} else if f, ok := formatBody(out, fopts); ok {
+			printValue(os.Stdout, strings.TrimRight(string(f), "\n"), fmt.Sprintf, newline)
+			return nil
+		}
+	}
+
+	printValue(os.Stdout, string(out), color.YellowString, newline)
+	return nil
+}
+
+// printValue prints the output of show. A newline is appended unless the
+// value already ends with one or newline is unset, so the secret is printed
+// as it's stored and piping it into insert again doesn't change it.
+func printValue(w io.Writer, value string, paint func(string, ...interface{}) string, newline bool) {
+	end := ""
+	if newline && !strings.HasSuffix(value, "\n") {
+		end = "\n"
+	}
+	fmt.Fprint(w, paint("%s", value)+end)
+}
+
+// revealPassword prints the password of a secret and blanks it again after
+// the given time or on Ctrl-C, so it doesn't linger on the screen. It only
+// works on a terminal.
+func revealPassword(content []byte, d time.Duration) error {
+	fd := int(os.Stdout.Fd())
+	if !isatty.IsTerminal(os.Stdout.Fd()) {
+		return exitError(ExitUsage, "--reveal only works if stdout is a terminal")
+	}
+	width, _, err := terminal.GetSize(fd)
+	if err != nil {
+		width = 80
 	}
 
-	color.Yellow(string(content))
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigc)
 
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	pw, _ := password.SplitSecret(content)
+	reveal(os.Stdout, string(pw), width, timer.C, sigc)
 	return nil
 }
 
-func (s *Action) copyToClipboard(name string, content []byte) error {
+// reveal prints value and, once done fires or a signal arrives, moves the
+// cursor back up and clears every line it took up at the given terminal
+// width
+func reveal(w io.Writer, value string, width int, done <-chan time.Time, sigc <-chan os.Signal) {
+	fmt.Fprintln(w, color.YellowString("%s", value))
+	select {
+	case <-done:
+	case <-sigc:
+	}
+	fmt.Fprint(w, strings.Repeat("\x1b[1A\x1b[2K", revealLines(value, width))+"\r")
+}
+
+// revealLines returns the number of lines value takes up on a terminal of
+// the given width, including the wrapped ones
+func revealLines(value string, width int) int {
+	n := 0
+	for _, line := range strings.Split(value, "\n") {
+		l := utf8.RuneCountInString(line)
+		if width < 1 || l <= width {
+			n++
+			continue
+		}
+		n += (l + width - 1) / width
+	}
+	return n
+}
+
+// showAs reports whether a secret decrypts with the given secret key, without
+// printing it
+func (s *Action) showAs(name, fpr string) error {
+	if _, err := s.Store.DecryptAs(name, fpr); err != nil {
+		switch err.(type) {
+		case *gpg.NoSecretKeyError:
+			return exitError(ExitNotFound, "can't test %s with %s, the secret key is not in your keyring", name, fpr)
+		case *exec.ExitError:
+			return exitError(ExitDecrypt, "%s does not decrypt with %s", name, fpr)
+		}
+		if err == password.ErrNotFound || err == gpg.ErrAgentUnavailable {
+			return err
+		}
+		return exitError(ExitDecrypt, "%s does not decrypt with %s: %s", name, fpr, err)
+	}
+	fmt.Println(color.GreenString("%s decrypts with %s", name, fpr))
+	return nil
+}
+
+// printStats prints the length, character classes and entropy of the
+// password of a secret and whether other secrets use it as well. The other
+// secrets are only named if paths is set. Without search indexes looking for
+// reuse decrypts every secret, which is confirmed first.
+func (s *Action) printStats(name string, content []byte, paths bool) {
+	fmt.Println(formatStats(content, s.reusedBy(name, content), paths))
+}
+
+// reusedBy returns the other secrets using the password of the given secret,
+// nil if that's unknown
+func (s *Action) reusedBy(name string, content []byte) []string {
+	if !s.Store.HasIndex() && !s.Store.NoConfirm {
+		ok, err := askForBool("Looking for reused passwords will decrypt every secret in the store. Do you want to continue?", false)
+		if err != nil || !ok {
+			return nil
+		}
+	}
+	reused, err := s.Store.ReusedBy(name, content)
+	if err != nil {
+		fmt.Println(color.YellowString("Warning: failed to look for reused passwords: %s", err))
+		return nil
+	}
+	return reused
+}
+
+// formatStats returns the footer of show --stats. reused are the other
+// secrets using the same password, nil if they're unknown.
+func formatStats(content []byte, reused []string, paths bool) string {
+	pw, _ := password.SplitSecret(content)
+	st := pwgen.Analyze(pw)
+	lines := []string{"", "Password: no password"}
+	if st.Length > 0 {
+		lines[1] = fmt.Sprintf("Password: %d chars (%s), about %d bits of entropy", st.Length, strings.Join(st.Classes, ", "), int(st.Entropy))
+	}
+	switch {
+	case st.Length < 1:
+	case reused == nil:
+		lines = append(lines, "Reuse: unknown. Add a search index with `gopass index` to check it without decrypting every secret")
+	case len(reused) < 1:
+		lines = append(lines, "Reuse: not used by any other secret")
+	case paths:
+		lines = append(lines, color.YellowString("Reuse: also used by %s", strings.Join(reused, ", ")))
+	default:
+		lines = append(lines, color.YellowString("Reuse: also used by %d other secrets. Add --reused to list them", len(reused)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// showOpts control which parts of a secret Show prints
+type showOpts struct {
+	// safe hides the password unless it's asked for explicitly
+	safe bool
+	// password prints only the password
+	password bool
+	// raw prints the secret as it is stored
+	raw bool
+	// terminal is set if stdout is a terminal
+	terminal bool
+}
+
+// showContent returns the part of the secret Show prints and whether that
+// includes the password. In safe mode only the body is printed, unless the
+// password is asked for or the raw secret is piped to another program.
+func showContent(content []byte, opts showOpts) ([]byte, bool) {
+	pw, body := password.SplitSecret(content)
+	if opts.password {
+		return pw, true
+	}
+	if opts.safe && (opts.terminal || !opts.raw) {
+		return body, false
+	}
+	return content, true
+}
+
+// safeContent returns true if show must not print passwords unless they're
+// asked for. It's enabled by the --safe flag, the safecontent setting or
+// GOPASS_SAFECONTENT.
+func (s *Action) safeContent(c *cli.Context) bool {
+	if c.Bool("safe") || s.Store.SafeContent {
+		return true
+	}
+	sc, err := strconv.ParseBool(os.Getenv("GOPASS_SAFECONTENT"))
+	return err == nil && sc
+}
+
+// copyToClipboard puts the first line of content on the clipboard and
+// schedules it to be cleared. If verify is set the clipboard is read back
+// to make sure the write actually succeeded. If wait is set this waits for
+// the timeout and clears the clipboard itself instead of leaving that to a
+// background process.
+func (s *Action) copyToClipboard(name string, content []byte, verify, wait bool) error {
 	content = bytes.TrimSpace(content)
 
 	// only copy the first line to the clipboard
@@ -44,12 +287,31 @@ func (s *Action) copyToClipboard(name string, content []byte) error {
 	}
 	line := lines[0]
 
-	if err := clipboard.WriteAll(string(line)); err != nil {
-		return err
+	if err := clipboardWrite(string(line)); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %v", err)
 	}
-	if err := clearClipboard(line, s.Store.ClipTimeout); err != nil {
-		return err
+	log.Debugf("clipboard: copied the first line of %s", name)
+	if verify {
+		if err := verifyClipboard(line); err != nil {
+			return err
+		}
 	}
 	fmt.Printf("Copied %s to clipboard. Will clear in %d seconds.\n", color.YellowString(name), s.Store.ClipTimeout)
+	if wait {
+		return waitClearClipboard(line, s.Store.ClipTimeout)
+	}
+	return clearClipboard(line, s.Store.ClipTimeout)
+}
+
+// verifyClipboard reads back the clipboard and compares it against the
+// expected content, using the same checksum as unclip
+func verifyClipboard(content []byte) error {
+	cur, err := clipboardRead()
+	if err != nil {
+		return fmt.Errorf("failed to read back clipboard: %v", err)
+	}
+	if clipboardHash([]byte(cur)) != clipboardHash(content) {
+		return fmt.Errorf("failed to copy to clipboard: content does not match after write")
+	}
 	return nil
 }
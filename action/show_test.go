@@ -0,0 +1,161 @@
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClipboard replaces the system clipboard for the duration of a test.
+// The recent copies are recorded in a temporary runtime dir.
+func fakeClipboard() (*string, func()) {
+	content := ""
+	runtimeDir, _ := ioutil.TempDir("", "gopass-")
+	oldDir, hadDir := os.LookupEnv("XDG_RUNTIME_DIR")
+	_ = os.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	oldRead, oldWrite := clipboardRead, clipboardWrite
+	clipboardRead = func() (string, error) {
+		return content, nil
+	}
+	clipboardWrite = func(s string) error {
+		content = s
+		return nil
+	}
+	return &content, func() {
+		clipboardRead, clipboardWrite = oldRead, oldWrite
+		if hadDir {
+			_ = os.Setenv("XDG_RUNTIME_DIR", oldDir)
+		} else {
+			_ = os.Unsetenv("XDG_RUNTIME_DIR")
+		}
+		_ = os.RemoveAll(runtimeDir)
+	}
+}
+
+func TestCopyToClipboardWait(t *testing.T) {
+	content, cleanup := fakeClipboard()
+	defer cleanup()
+
+	s := &Action{Store: &password.RootStore{ClipTimeout: 1}}
+	start := time.Now()
+	assert.NoError(t, s.copyToClipboard("foo", []byte("secret\nuser: gopher\n"), true, true))
+	assert.True(t, time.Since(start) >= time.Second, "returned after %s", time.Since(start))
+	assert.Equal(t, "", *content)
+}
+
+func TestWaitClearClipboardChanged(t *testing.T) {
+	content, cleanup := fakeClipboard()
+	defer cleanup()
+
+	// content copied by someone else is kept
+	*content = "other"
+	assert.NoError(t, waitClearClipboard([]byte("secret"), 0))
+	assert.Equal(t, "other", *content)
+}
+
+func TestShowContent(t *testing.T) {
+	content := []byte("secret\nuser: gopher\n")
+	for _, tc := range []struct {
+		opts         showOpts
+		out          string
+		withPassword bool
+	}{
+		{showOpts{}, "secret\nuser: gopher\n", true},
+		{showOpts{terminal: true}, "secret\nuser: gopher\n", true},
+		{showOpts{password: true}, "secret", true},
+		{showOpts{safe: true}, "user: gopher\n", false},
+		{showOpts{safe: true, terminal: true}, "user: gopher\n", false},
+		{showOpts{safe: true, password: true, terminal: true}, "secret", true},
+		// scripts may still read the raw secret
+		{showOpts{safe: true, raw: true}, "secret\nuser: gopher\n", true},
+		{showOpts{safe: true, raw: true, terminal: true}, "user: gopher\n", false},
+	} {
+		out, withPassword := showContent(content, tc.opts)
+		assert.Equal(t, tc.out, string(out), "%+v", tc.opts)
+		assert.Equal(t, tc.withPassword, withPassword, "%+v", tc.opts)
+	}
+}
+
+func TestFormatStats(t *testing.T) {
+	content := []byte("Passw0rdPassw0rdPass\nuser: gopher\n")
+	assert.Equal(t, "\nPassword: 20 chars (upper, lower, digit), about 119 bits of entropy\nReuse: not used by any other secret", formatStats(content, []string{}, false))
+	assert.Equal(t, "\nPassword: 20 chars (upper, lower, digit), about 119 bits of entropy\nReuse: also used by 2 other secrets. Add --reused to list them", formatStats(content, []string{"bar", "foo"}, false))
+	assert.Equal(t, "\nPassword: 20 chars (upper, lower, digit), about 119 bits of entropy\nReuse: also used by bar, foo", formatStats(content, []string{"bar", "foo"}, true))
+	// the reuse may be unknown without a search index
+	assert.Equal(t, "\nPassword: 20 chars (upper, lower, digit), about 119 bits of entropy\nReuse: unknown. Add a search index with `gopass index` to check it without decrypting every secret", formatStats(content, nil, false))
+	assert.Equal(t, "\nPassword: no password", formatStats([]byte("\nuser: gopher\n"), []string{}, false))
+}
+
+func TestPrintValue(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		newline bool
+		out     string
+	}{
+		{"secret", true, "secret\n"},
+		{"secret\n", true, "secret\n"},
+		{"secret\nuser: gopher\n", true, "secret\nuser: gopher\n"},
+		{"secret\n\n", true, "secret\n\n"},
+		{"secret", false, "secret"},
+		{"secret\n", false, "secret\n"},
+		{"secret\nuser: gopher", false, "secret\nuser: gopher"},
+		{"100%", true, "100%\n"},
+	} {
+		buf := &bytes.Buffer{}
+		printValue(buf, tc.value, fmt.Sprintf, tc.newline)
+		assert.Equal(t, tc.out, buf.String(), "%q newline %t", tc.value, tc.newline)
+	}
+}
+
+// chanWriter passes every write on to a channel
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestReveal(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() {
+		color.NoColor = noColor
+	}()
+
+	for _, fire := range []string{"timer", "signal"} {
+		w := make(chanWriter, 2)
+		done := make(chan time.Time)
+		sigc := make(chan os.Signal)
+		go reveal(w, "secret", 80, done, sigc)
+
+		assert.Equal(t, "secret\n", <-w)
+		select {
+		case out := <-w:
+			t.Errorf("cleared before the time was up: %q", out)
+		case <-time.After(50 * time.Millisecond):
+		}
+		if fire == "timer" {
+			done <- time.Now()
+		} else {
+			sigc <- os.Interrupt
+		}
+		assert.Equal(t, "\x1b[1A\x1b[2K\r", <-w, fire)
+	}
+}
+
+func TestRevealLines(t *testing.T) {
+	assert.Equal(t, 1, revealLines("secret", 80))
+	assert.Equal(t, 1, revealLines(strings.Repeat("x", 80), 80))
+	assert.Equal(t, 2, revealLines(strings.Repeat("x", 81), 80))
+	assert.Equal(t, 3, revealLines(strings.Repeat("ä", 20), 8))
+	assert.Equal(t, 1, revealLines("", 80))
+	assert.Equal(t, 1, revealLines("secret", 0))
+}
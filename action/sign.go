@@ -0,0 +1,130 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/urfave/cli"
+)
+
+// Sign writes a detached signature of the content of a secret to stdout or
+// the given file. The plaintext is only held in memory.
+func (s *Action) Sign(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return exitError(ExitUsage, "Usage: %s sign [--output <file>] <name>", s.Name)
+	}
+
+	content, err := s.Store.Get(name)
+	if err != nil {
+		return err
+	}
+
+	key := c.String("key")
+	if key == "" {
+		key = s.Store.SignKey
+	}
+	sig, err := gpg.SignDetached(content, key)
+	if err != nil {
+		return fmt.Errorf("failed to sign %s: %s", name, err)
+	}
+
+	output := c.String("output")
+	if output == "" || output == "-" {
+		_, err := os.Stdout.Write(sig)
+		return err
+	}
+	if err := ioutil.WriteFile(output, sig, 0644); err != nil {
+		return fmt.Errorf("failed to write the signature to %s: %s", output, err)
+	}
+	fmt.Printf("Wrote the signature of %s to %s\n", color.YellowString(name), output)
+	return nil
+}
+
+// Verify checks a detached signature created by Sign against the content of
+// a secret and prints the signer. With --all it checks that every secret can
+// be decrypted instead, see VerifyStore.
+func (s *Action) Verify(c *cli.Context) error {
+	if c.Bool("all") {
+		if len(c.Args()) > 0 {
+			return exitError(ExitUsage, "--all checks every secret and takes no arguments")
+		}
+		return s.VerifyStore(c)
+	}
+	if len(c.Args()) != 2 {
+		return exitError(ExitUsage, "Usage: %s verify <name> <signature> | --all", s.Name)
+	}
+	name, fn := c.Args()[0], c.Args()[1]
+
+	var sig []byte
+	var err error
+	if fn == "-" {
+		sig, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		sig, err = ioutil.ReadFile(fn)
+	}
+	if err != nil {
+		return err
+	}
+
+	content, err := s.Store.Get(name)
+	if err != nil {
+		return err
+	}
+
+	signer, err := gpg.Verify(content, sig)
+	if err != nil {
+		return err
+	}
+	if kl, err := gpg.ListPublicKeys(signer); err == nil && len(kl) > 0 {
+		signer = kl[0].OneLine()
+	}
+	fmt.Printf("Good signature of %s by %s\n", color.YellowString(name), signer)
+	return nil
+}
+
+// VerifyStore decrypts every secret of all stores with the keys of the user
+// and reports those that fail, without printing any plaintext. It stops on
+// Ctrl-C.
+func (s *Action) VerifyStore(c *cli.Context) error {
+	names, err := s.Store.List()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigch)
+	go func() {
+		select {
+		case <-sigch:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fmt.Printf("Decrypting %d secrets ...\n", len(names))
+	failed, err := s.Store.VerifyDecryptableContext(ctx)
+	for _, name := range failed {
+		fmt.Println(color.RedString("Can not decrypt %s", name))
+	}
+	if err == context.Canceled {
+		return fmt.Errorf("verify cancelled, %d secrets failed to decrypt so far", len(failed))
+	}
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return exitError(ExitDecrypt, "%d of %d secrets can not be decrypted with your keys", len(failed), len(names))
+	}
+	fmt.Println(color.GreenString("All secrets can be decrypted with your keys"))
+	return nil
+}
@@ -0,0 +1,36 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/urfave/cli"
+)
+
+// Trust sets the ownertrust of a recipients public key
+func (s *Action) Trust(c *cli.Context) error {
+	if len(c.Args()) != 2 {
+		return exitError(ExitUsage, "Usage: gopass trust fingerprint unknown|never|marginal|full|ultimate")
+	}
+
+	level, err := gpg.ParseTrustLevel(c.Args()[1])
+	if err != nil {
+		return err
+	}
+
+	kl, err := gpg.ListPublicKeys(c.Args()[0])
+	if err != nil {
+		return fmt.Errorf("Failed to list public keys: %s", err)
+	}
+	if len(kl) != 1 {
+		return fmt.Errorf("%s must match exactly one public key, found %d", c.Args()[0], len(kl))
+	}
+
+	if err := gpg.SetOwnerTrust(kl[0].Fingerprint, level); err != nil {
+		return err
+	}
+
+	fmt.Println(color.GreenString("Set ownertrust of %s to %s", kl[0].OneLine(), level))
+	return nil
+}
@@ -0,0 +1,394 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"unicode"
+
+	"github.com/justwatchcom/gopass/cache"
+	"github.com/justwatchcom/gopass/log"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyEnter     = 13
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// browser is the state of the interactive secret browser
+type browser struct {
+	names   []string
+	filter  string
+	matches []string
+	cursor  int
+	offset  int
+	secret  string
+	fields  []string
+	field   int
+	reveal  bool
+	status  string
+	quit    bool
+	cache   *cache.Cache
+	getFn   func(string) ([]byte, error)
+	copyFn  func(string, []byte) error
+}
+
+// UI starts an interactive browser for the secrets in the store. Secrets
+// are only decrypted when selected and only kept in memory until the browser
+// exits or the screen is locked.
+func (s *Action) UI(c *cli.Context) error {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return fmt.Errorf("gopass ui requires a terminal")
+	}
+
+	l, err := s.Store.List()
+	if err != nil {
+		return err
+	}
+
+	b := newBrowser(l, s.Store.Get, s.copyField)
+	defer b.purge()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	locks, err := cache.LockEvents(ctx)
+	if err != nil {
+		log.Debugf("ui: can not flush secrets on screen lock: %s", err)
+	}
+
+	oldState, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("Could not set terminal to raw mode: %s", err)
+	}
+	restore := func() {
+		fmt.Print("\x1b[H\x1b[2J\x1b[?25h")
+		if err := terminal.Restore(fd, oldState); err != nil {
+			fmt.Printf("Failed to restore terminal: %s\n", err)
+		}
+	}
+	defer restore()
+
+	// signals end the loop below, which purges the secrets and restores the
+	// terminal on return
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigch)
+
+	// the browser is only changed by this loop, keys, screen locks and
+	// signals are handled in turn
+	keys, readErr := readKeys(os.Stdin)
+	for !b.quit {
+		_, height, err := terminal.GetSize(fd)
+		if err != nil {
+			height = 24
+		}
+		fmt.Print("\x1b[H\x1b[2J\x1b[?25l")
+		b.render(os.Stdout, height)
+
+		select {
+		case key := <-keys:
+			b.handleKey(key)
+		case err := <-readErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case _, ok := <-locks:
+			if !ok {
+				// the lock signal can't be watched any more
+				locks = nil
+				continue
+			}
+			b.locked()
+		case sig := <-sigch:
+			return exitError(ExitUnknown, "gopass ui interrupted by %s", sig)
+		}
+	}
+	return nil
+}
+
+// readKeys reads from the terminal in the background. Every read is sent to
+// the first channel, the error ending the reads to the second.
+func readKeys(r io.Reader) (<-chan []byte, <-chan error) {
+	keys := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := r.Read(buf)
+			if err != nil {
+				errs <- err
+				return
+			}
+			keys <- append([]byte{}, buf[:n]...)
+		}
+	}()
+	return keys, errs
+}
+
+// copyField puts a single field on the clipboard and schedules it to be
+// cleared after the configured timeout
+func (s *Action) copyField(name string, content []byte) error {
+	if err := clipboardWrite(string(content)); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %v", err)
+	}
+	if err := clearClipboard(content, s.Store.ClipTimeout); err != nil {
+		return err
+	}
+	return nil
+}
+
+func newBrowser(names []string, getFn func(string) ([]byte, error), copyFn func(string, []byte) error) *browser {
+	b := &browser{
+		names:  names,
+		cache:  cache.New(),
+		getFn:  getFn,
+		copyFn: copyFn,
+	}
+	b.applyFilter()
+	return b
+}
+
+// applyFilter updates the list of matching secrets after the filter changed
+func (b *browser) applyFilter() {
+	b.matches = b.matches[:0]
+	for _, name := range b.names {
+		if fuzzyMatch(b.filter, name) {
+			b.matches = append(b.matches, name)
+		}
+	}
+	b.cursor = 0
+	b.offset = 0
+}
+
+// handleKey processes one read from the terminal
+func (b *browser) handleKey(key []byte) {
+	b.status = ""
+	if len(key) < 1 {
+		return
+	}
+	switch {
+	case key[0] == keyCtrlC || key[0] == keyCtrlD:
+		b.quit = true
+	case bytes.Equal(key, []byte("\x1b[A")):
+		b.move(-1)
+	case bytes.Equal(key, []byte("\x1b[B")):
+		b.move(1)
+	case bytes.Equal(key, []byte("\x1b[C")):
+		if b.secret == "" {
+			b.open()
+		}
+	case bytes.Equal(key, []byte("\x1b[D")):
+		b.close()
+	case key[0] == keyEscape:
+		if b.secret != "" {
+			b.close()
+			return
+		}
+		b.filter = ""
+		b.applyFilter()
+	case key[0] == keyEnter:
+		if b.secret == "" {
+			b.open()
+			return
+		}
+		b.copySelected()
+	case b.secret != "":
+		b.handleSecretKey(key[0])
+	case key[0] == keyBackspace || key[0] == keyDelete:
+		if len(b.filter) > 0 {
+			b.filter = b.filter[:len(b.filter)-1]
+			b.applyFilter()
+		}
+	case key[0] >= ' ' && key[0] < keyDelete:
+		b.filter += string(key[0])
+		b.applyFilter()
+	}
+}
+
+// handleSecretKey processes the keys that are only valid when a secret is open
+func (b *browser) handleSecretKey(key byte) {
+	switch key {
+	case 'c':
+		b.copySelected()
+	case 'r':
+		b.reveal = !b.reveal
+	case 'q':
+		b.close()
+	}
+}
+
+// move the cursor by the given offset, either within the list of secrets
+// or within the fields of the open secret
+func (b *browser) move(delta int) {
+	if b.secret != "" {
+		b.field = clamp(b.field+delta, len(b.fields))
+		return
+	}
+	b.cursor = clamp(b.cursor+delta, len(b.matches))
+}
+
+// open decrypts the selected secret, unless it's already cached
+func (b *browser) open() {
+	if len(b.matches) < 1 {
+		return
+	}
+	name := b.matches[b.cursor]
+	content, found := b.cache.Get(name)
+	if !found {
+		var err error
+		content, err = b.getFn(name)
+		if err != nil {
+			b.status = fmt.Sprintf("Failed to decrypt %s: %s", name, err)
+			return
+		}
+		b.cache.Set(name, content)
+	}
+	b.secret = name
+	b.fields = strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	b.field = 0
+	b.reveal = false
+}
+
+// close returns to the list of secrets
+func (b *browser) close() {
+	b.secret = ""
+	b.fields = nil
+	b.field = 0
+	b.reveal = false
+}
+
+// copySelected copies the value of the selected field to the clipboard
+func (b *browser) copySelected() {
+	if len(b.fields) < 1 {
+		return
+	}
+	key, value := splitField(b.fields[b.field], b.field)
+	if err := b.copyFn(b.secret, []byte(value)); err != nil {
+		b.status = err.Error()
+		return
+	}
+	b.status = fmt.Sprintf("Copied %s of %s to clipboard", key, b.secret)
+}
+
+// purge drops all decrypted secrets
+func (b *browser) purge() {
+	b.cache.Flush()
+	b.fields = nil
+}
+
+// locked drops all decrypted secrets after the screen was locked and closes
+// the open secret, so none of it is shown once the screen is unlocked
+func (b *browser) locked() {
+	open := b.secret != ""
+	b.purge()
+	b.close()
+	if open {
+		b.status = "Secrets were dropped when the screen was locked"
+	}
+}
+
+// render draws the current state to w using at most height lines
+func (b *browser) render(w io.Writer, height int) {
+	if height < 5 {
+		height = 5
+	}
+	if b.secret != "" {
+		b.renderSecret(w)
+		return
+	}
+
+	fmt.Fprintf(w, "Filter: %s\r\n\r\n", b.filter)
+	rows := height - 4
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+rows {
+		b.offset = b.cursor - rows + 1
+	}
+	for i := b.offset; i < len(b.matches) && i < b.offset+rows; i++ {
+		b.renderLine(w, i == b.cursor, b.matches[i])
+	}
+	if len(b.matches) < 1 {
+		fmt.Fprint(w, "  no matching secrets\r\n")
+	}
+	b.renderFooter(w, "up/down: select  enter: open  esc: clear filter  ctrl-c: quit")
+}
+
+// renderSecret draws the fields of the open secret
+func (b *browser) renderSecret(w io.Writer) {
+	fmt.Fprintf(w, "%s\r\n\r\n", b.secret)
+	for i, line := range b.fields {
+		key, value := splitField(line, i)
+		if !b.reveal {
+			value = strings.Repeat("*", 8)
+		}
+		b.renderLine(w, i == b.field, fmt.Sprintf("%s: %s", key, value))
+	}
+	b.renderFooter(w, "up/down: select  enter/c: copy  r: reveal  esc: back  ctrl-c: quit")
+}
+
+func (b *browser) renderLine(w io.Writer, selected bool, line string) {
+	if !selected {
+		fmt.Fprintf(w, "  %s\r\n", line)
+		return
+	}
+	fmt.Fprintf(w, "\x1b[7m> %s\x1b[0m\r\n", line)
+}
+
+func (b *browser) renderFooter(w io.Writer, help string) {
+	fmt.Fprintf(w, "\r\n%s\r\n", help)
+	if b.status != "" {
+		fmt.Fprintf(w, "%s\r\n", b.status)
+	}
+}
+
+// splitField returns the name and value of a line of a secret. The first
+// line is the password, other lines may have the form "key: value".
+func splitField(line string, idx int) (string, string) {
+	if idx == 0 {
+		return "password", line
+	}
+	if p := strings.SplitN(line, ": ", 2); len(p) == 2 && p[0] != "" && !strings.ContainsAny(p[0], " \t") {
+		return p[0], p[1]
+	}
+	return fmt.Sprintf("line %d", idx+1), line
+}
+
+// fuzzyMatch returns true if all characters of pattern appear in name in
+// the same order. The match is case insensitive.
+func fuzzyMatch(pattern, name string) bool {
+	name = strings.ToLower(name)
+	for _, r := range strings.ToLower(pattern) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		i := strings.IndexRune(name, r)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(string(r)):]
+	}
+	return true
+}
+
+func clamp(i, n int) int {
+	if i >= n {
+		i = n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
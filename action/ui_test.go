@@ -0,0 +1,121 @@
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"", "web/example.com", true},
+		{"wex", "web/example.com", true},
+		{"WEB ex", "web/example.com", true},
+		{"exw", "web/example.com", false},
+		{"mail", "web/example.com", false},
+	} {
+		assert.Equal(t, tc.match, fuzzyMatch(tc.pattern, tc.name), "%s ~ %s", tc.pattern, tc.name)
+	}
+}
+
+func TestSplitField(t *testing.T) {
+	for _, tc := range []struct {
+		line  string
+		idx   int
+		key   string
+		value string
+	}{
+		{"user: foo", 0, "password", "user: foo"},
+		{"user: foo", 1, "user", "foo"},
+		{"url: https://example.com", 2, "url", "https://example.com"},
+		{"some note: here", 3, "line 4", "some note: here"},
+	} {
+		key, value := splitField(tc.line, tc.idx)
+		assert.Equal(t, tc.key, key)
+		assert.Equal(t, tc.value, value)
+	}
+}
+
+func TestBrowser(t *testing.T) {
+	decrypted := 0
+	copied := ""
+	b := newBrowser(
+		[]string{"mail/example.com", "web/example.com", "web/other.org"},
+		func(name string) ([]byte, error) {
+			decrypted++
+			if name == "web/other.org" {
+				return nil, fmt.Errorf("no secret key")
+			}
+			return []byte("secret\nuser: john\n"), nil
+		},
+		func(name string, content []byte) error {
+			copied = string(content)
+			return nil
+		},
+	)
+
+	// filter as you type
+	b.handleKey([]byte("w"))
+	b.handleKey([]byte("e"))
+	assert.Equal(t, []string{"web/example.com", "web/other.org"}, b.matches)
+	b.handleKey([]byte{keyDelete})
+	b.handleKey([]byte{keyDelete})
+	assert.Len(t, b.matches, 3)
+
+	// open a secret and copy a field
+	b.handleKey([]byte("\x1b[B"))
+	b.handleKey([]byte{keyEnter})
+	assert.Equal(t, "web/example.com", b.secret)
+	buf := &bytes.Buffer{}
+	b.render(buf, 24)
+	assert.NotContains(t, buf.String(), "secret\r\n")
+	assert.Contains(t, buf.String(), "password: ********")
+
+	b.handleKey([]byte("\x1b[B"))
+	b.handleKey([]byte("c"))
+	assert.Equal(t, "john", copied)
+	assert.Equal(t, "Copied user of web/example.com to clipboard", b.status)
+
+	b.handleKey([]byte("r"))
+	buf.Reset()
+	b.render(buf, 24)
+	assert.Contains(t, buf.String(), "user: john")
+
+	// reopening uses the cache
+	b.handleKey([]byte{keyEscape})
+	assert.Equal(t, "", b.secret)
+	b.handleKey([]byte{keyEnter})
+	assert.Equal(t, 1, decrypted)
+
+	// a screen lock closes the secret
+	b.locked()
+	assert.Equal(t, "", b.secret)
+	assert.Nil(t, b.fields)
+	assert.Equal(t, 0, b.cache.Len())
+	buf.Reset()
+	b.render(buf, 24)
+	assert.NotContains(t, buf.String(), "john")
+	assert.Contains(t, buf.String(), "Secrets were dropped when the screen was locked")
+	b.handleKey([]byte{keyEnter})
+	assert.Equal(t, "web/example.com", b.secret)
+	assert.Equal(t, 2, decrypted)
+
+	// decryption failures are shown
+	b.handleKey([]byte{keyEscape})
+	b.handleKey([]byte("\x1b[B"))
+	b.handleKey([]byte{keyEnter})
+	assert.Equal(t, "", b.secret)
+	assert.Contains(t, b.status, "Failed to decrypt web/other.org")
+
+	b.purge()
+	assert.Equal(t, 0, b.cache.Len())
+
+	b.handleKey([]byte{keyCtrlC})
+	assert.True(t, b.quit)
+}
@@ -1,35 +1,285 @@
 package action
 
 import (
-	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
 	"time"
 
-	"github.com/atotto/clipboard"
+	"github.com/justwatchcom/gopass/clipboard"
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/log"
 	"github.com/urfave/cli"
 )
 
-// Unclip tries to erase the content of the clipboard
+const (
+	// maxClipHashes is the number of recent copies unclip --force recognizes
+	maxClipHashes = 10
+	// clipHashTTL is how long a copy is remembered after it should have been
+	// cleared, e.g. if the process clearing it was killed
+	clipHashTTL = 10 * time.Minute
+)
+
+// clipHash is the checksum of something gopass copied to the clipboard and
+// the key of the process that computed it
+type clipHash struct {
+	Hash    string    `json:"hash"`
+	Key     string    `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+// matches returns true if the checksum is the one of the given content
+func (h clipHash) matches(content string) bool {
+	key, err := hex.DecodeString(h.Key)
+	if err != nil {
+		return false
+	}
+	return clipboardHashWith(key, []byte(content)) == h.Hash
+}
+
+// Unclip tries to erase the content of the clipboard. With --force it's
+// erased right away if it holds anything gopass copied recently.
 func (s *Action) Unclip(c *cli.Context) error {
+	if c.Bool("force") {
+		cleared, err := clearClipboardIfRecent(time.Now())
+		if err != nil {
+			return err
+		}
+		if !cleared {
+			fmt.Println("The clipboard holds nothing gopass copied recently")
+			return nil
+		}
+		fmt.Println("Cleared the clipboard")
+		return nil
+	}
+
 	timeout := c.Int("timeout")
 	checksum := os.Getenv("GOPASS_UNCLIP_CHECKSUM")
 
+	unclipDebug("expecting checksum %s", checksum)
+	unclipDebug("waiting %d seconds", timeout)
 	time.Sleep(time.Second * time.Duration(timeout))
 
-	cur, err := clipboard.ReadAll()
+	_, err := clearClipboardIfUnchanged(checksum)
+	return err
+}
+
+// clearClipboardIfUnchanged erases the clipboard if its content still has
+// one of the given checksums, i.e. it hasn't been replaced since gopass
+// copied to it. It returns true if the clipboard was cleared.
+func clearClipboardIfUnchanged(checksums ...string) (bool, error) {
+	key := hex.EncodeToString(clipboardKey())
+	marks := make([]clipHash, 0, len(checksums))
+	for _, checksum := range checksums {
+		marks = append(marks, clipHash{Hash: checksum, Key: key})
+	}
+	return clearClipboardIfMatches(marks)
+}
+
+// clearClipboardIfMatches erases the clipboard if its content matches one of
+// the given checksums. It returns true if the clipboard was cleared.
+func clearClipboardIfMatches(marks []clipHash) (bool, error) {
+	cur, err := clipboardRead()
 	if err != nil {
-		return err
+		unclipDebug("failed to read clipboard: %s", err)
+		return false, err
 	}
+	unclipDebug("read back clipboard with checksum %s", clipboardHash([]byte(cur)))
 
-	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(cur)))
+	found := false
+	for _, m := range marks {
+		if m.matches(cur) {
+			found = true
+		}
+	}
+	if !found {
+		unclipDebug("checksum mismatch, clipboard was changed. Not clearing it")
+		return false, nil
+	}
+	if err := clipboardWrite(""); err != nil {
+		unclipDebug("failed to clear clipboard: %s", err)
+		return false, err
+	}
+	unclipDebug("cleared clipboard")
 
-	if hash != checksum {
-		return nil
+	return true, nil
+}
+
+// clearClipboardIfRecent erases the clipboard if it holds any of the recent
+// copies of gopass, even if they've been replaced by another copy meanwhile.
+// The recorded copies are forgotten once it's cleared.
+func clearClipboardIfRecent(now time.Time) (bool, error) {
+	recent := loadClipHashes(now)
+	unclipDebug("expecting any of %d recent checksums", len(recent))
+	cleared, err := clearClipboardIfMatches(recent)
+	if err != nil || !cleared {
+		return cleared, err
 	}
-	if err := clipboard.WriteAll(""); err != nil {
-		return err
+	if err := os.Remove(clipHashFile()); err != nil && !os.IsNotExist(err) {
+		log.Debugf("clipboard: failed to remove the recent copies: %s", err)
+	}
+	return true, nil
+}
+
+// clipHashFile returns the file holding the checksums of the recent copies.
+// It's kept in the runtime dir of the user or in /dev/shm, so it doesn't
+// outlive the session.
+func clipHashFile() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gopass-clip-hashes")
 	}
+	dir := fsutil.Tempdir()
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, fmt.Sprintf("gopass-clip-hashes-%d", os.Getuid()))
+}
+
+// loadClipHashes returns the checksums of the recent copies that haven't
+// expired yet
+func loadClipHashes(now time.Time) []clipHash {
+	recent := make([]clipHash, 0, maxClipHashes)
+	buf, err := ioutil.ReadFile(clipHashFile())
+	if err != nil {
+		return recent
+	}
+	all := make([]clipHash, 0, maxClipHashes)
+	if err := json.Unmarshal(buf, &all); err != nil {
+		log.Debugf("clipboard: failed to read the recent copies: %s", err)
+		return recent
+	}
+	for _, h := range all {
+		if now.Before(h.Expires) {
+			recent = append(recent, h)
+		}
+	}
+	return recent
+}
 
+// recordClipHash remembers the checksum of a copy that will be cleared after
+// the timeout, keeping the last maxClipHashes. Failures are only logged,
+// copying works without.
+func recordClipHash(checksum string, timeout int, now time.Time) {
+	recent := append(loadClipHashes(now), clipHash{
+		Hash:    checksum,
+		Key:     hex.EncodeToString(clipboardKey()),
+		Expires: now.Add(time.Duration(timeout)*time.Second + clipHashTTL),
+	})
+	if len(recent) > maxClipHashes {
+		recent = recent[len(recent)-maxClipHashes:]
+	}
+	buf, err := json.Marshal(recent)
+	if err != nil {
+		log.Debugf("clipboard: failed to record the copy: %s", err)
+		return
+	}
+	// /dev/shm is shared with other users, so the temporary file must be a
+	// new one and not e.g. a symlink someone else put there
+	fn := clipHashFile()
+	tmp := fn + ".tmp"
+	_ = os.Remove(tmp)
+	fh, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Debugf("clipboard: failed to record the copy: %s", err)
+		return
+	}
+	_, err = fh.Write(buf)
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, fn)
+	}
+	if err != nil {
+		log.Debugf("clipboard: failed to record the copy: %s", err)
+	}
+}
+
+// systemdRunBin is the name and possibly location of systemd-run
+var systemdRunBin = "systemd-run"
+
+// systemdUnclipEnv are the variables passed on to the transient unit, which
+// doesn't inherit the environment. The clipboard tools need the display.
+var systemdUnclipEnv = []string{"DISPLAY", "WAYLAND_DISPLAY", "XAUTHORITY", "GOPASS_CONFIG", clipboard.EnvBackend}
+
+// systemdUnclipEnabled returns true if the clipboard should be cleared by a
+// transient systemd timer, which survives the teardown of the session
+func systemdUnclipEnabled() bool {
+	d := os.Getenv("GOPASS_UNCLIP_SYSTEMD")
+	return d == "1" || d == "true"
+}
+
+// systemdClearClipboard schedules gopass unclip with systemd-run to clear
+// the clipboard after the timeout, if it still has the checksum in the given
+// environment. An error is returned if systemd-run isn't available or failed.
+func systemdClearClipboard(env []string, backend string, timeout int) error {
+	bin, err := exec.LookPath(systemdRunBin)
+	if err != nil {
+		return err
+	}
+	exe, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.Abs(exe); err != nil {
+		return err
+	}
+	args := systemdUnclipArgs(bin, exe, timeout, func(k string) string {
+		if k == clipboard.EnvBackend && backend != "" {
+			return backend
+		}
+		return os.Getenv(k)
+	})
+	log.Debugf("clipboard: scheduling the clear with %s", bin)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, out)
+	}
 	return nil
 }
+
+// systemdUnclipArgs returns the systemd-run command line running gopass
+// unclip after the timeout. The timer itself waits, so unclip doesn't. The
+// checksum is passed on from the environment of systemd-run, it must not show
+// up in the command line.
+func systemdUnclipArgs(bin, exe string, timeout int, getenv func(string) string) []string {
+	args := []string{
+		bin,
+		"--user",
+		"--quiet",
+		"--on-active=" + strconv.Itoa(timeout) + "s",
+		"--timer-property=AccuracySec=1s",
+		"--setenv=GOPASS_UNCLIP_CHECKSUM",
+		"--setenv=GOPASS_UNCLIP_KEY",
+	}
+	for _, k := range systemdUnclipEnv {
+		if v := getenv(k); v != "" {
+			args = append(args, "--setenv="+k+"="+v)
+		}
+	}
+	return append(args, exe, "unclip", "--timeout", "0")
+}
+
+// unclipDebugEnabled returns true if the clipboard clear process should run
+// in the foreground and log what it's doing
+func unclipDebugEnabled() bool {
+	d := os.Getenv("GOPASS_DEBUG_UNCLIP")
+	return d == "1" || d == "true"
+}
+
+// unclipDebug logs a step of the clipboard clear process to stderr if
+// GOPASS_DEBUG_UNCLIP is set, or at the debug level otherwise
+func unclipDebug(format string, args ...interface{}) {
+	if !unclipDebugEnabled() {
+		log.Debugf("unclip: "+format, args...)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[unclip] "+format+"\n", args...)
+}
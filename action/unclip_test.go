@@ -0,0 +1,197 @@
+package action
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justwatchcom/gopass/clipboard"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClearClipboardIfUnchanged(t *testing.T) {
+	mem := &clipboard.Memory{}
+	oldBackend := clipboardBackend
+	clipboardBackend = func() (clipboard.Backend, error) {
+		return mem, nil
+	}
+	defer func() {
+		clipboardBackend = oldBackend
+	}()
+
+	// what's copied is read back and cleared with the same backend
+	assert.NoError(t, clipboardWrite("secret"))
+	assert.Equal(t, "secret", mem.Content)
+	cleared, err := clearClipboardIfUnchanged(clipboardHash([]byte("secret")))
+	assert.NoError(t, err)
+	assert.True(t, cleared)
+	assert.Equal(t, "", mem.Content)
+
+	// a changed clipboard is left alone
+	assert.NoError(t, clipboardWrite("secret"))
+	mem.Content = "other"
+	cleared, err = clearClipboardIfUnchanged(clipboardHash([]byte("secret")))
+	assert.NoError(t, err)
+	assert.False(t, cleared)
+	assert.Equal(t, "other", mem.Content)
+
+	// and so is any clipboard without a backend
+	clipboardBackend = func() (clipboard.Backend, error) {
+		return nil, fmt.Errorf("no clipboard tool found")
+	}
+	assert.Error(t, clipboardWrite("secret"))
+	_, err = clearClipboardIfUnchanged(clipboardHash([]byte("secret")))
+	assert.Error(t, err)
+}
+
+func TestClearClipboardIfRecent(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	oldDir, hadDir := os.LookupEnv("XDG_RUNTIME_DIR")
+	_ = os.Setenv("XDG_RUNTIME_DIR", tempdir)
+	defer func() {
+		if hadDir {
+			_ = os.Setenv("XDG_RUNTIME_DIR", oldDir)
+			return
+		}
+		_ = os.Unsetenv("XDG_RUNTIME_DIR")
+	}()
+
+	mem := &clipboard.Memory{}
+	oldBackend := clipboardBackend
+	clipboardBackend = func() (clipboard.Backend, error) {
+		return mem, nil
+	}
+	defer func() {
+		clipboardBackend = oldBackend
+	}()
+
+	// overlapping copies, the clear of the first one doesn't match anymore
+	now := time.Now()
+	for _, s := range []string{"first", "second", "third"} {
+		recordClipHash(clipboardHash([]byte(s)), 45, now)
+	}
+	assert.Len(t, loadClipHashes(now), 3)
+	mem.Content = "second"
+	cleared, err := clearClipboardIfUnchanged(clipboardHash([]byte("first")))
+	assert.NoError(t, err)
+	assert.False(t, cleared)
+
+	// but any of the recent copies is cleared with force
+	cleared, err = clearClipboardIfRecent(now)
+	assert.NoError(t, err)
+	assert.True(t, cleared)
+	assert.Equal(t, "", mem.Content)
+	assert.Len(t, loadClipHashes(now), 0)
+
+	// content gopass didn't copy is left alone
+	recordClipHash(clipboardHash([]byte("first")), 45, now)
+	mem.Content = "other"
+	cleared, err = clearClipboardIfRecent(now)
+	assert.NoError(t, err)
+	assert.False(t, cleared)
+	assert.Equal(t, "other", mem.Content)
+
+	// and so are expired copies
+	mem.Content = "first"
+	cleared, err = clearClipboardIfRecent(now.Add(45*time.Second + clipHashTTL))
+	assert.NoError(t, err)
+	assert.False(t, cleared)
+	assert.Equal(t, "first", mem.Content)
+
+	// only the last copies are kept
+	for i := 0; i < maxClipHashes+5; i++ {
+		recordClipHash(clipboardHash([]byte(fmt.Sprintf("copy %d", i))), 45, now)
+	}
+	recent := loadClipHashes(now)
+	assert.Len(t, recent, maxClipHashes)
+	assert.Equal(t, clipboardHash([]byte(fmt.Sprintf("copy %d", maxClipHashes+4))), recent[maxClipHashes-1].Hash)
+}
+
+func TestSystemdUnclipArgs(t *testing.T) {
+	env := map[string]string{
+		"DISPLAY": ":0",
+		"HOME":    "/home/gopher",
+	}
+	getenv := func(k string) string {
+		return env[k]
+	}
+	assert.Equal(t, []string{
+		"/usr/bin/systemd-run",
+		"--user",
+		"--quiet",
+		"--on-active=45s",
+		"--timer-property=AccuracySec=1s",
+		"--setenv=GOPASS_UNCLIP_CHECKSUM",
+		"--setenv=GOPASS_UNCLIP_KEY",
+		"--setenv=DISPLAY=:0",
+		"/usr/bin/gopass",
+		"unclip",
+		"--timeout",
+		"0",
+	}, systemdUnclipArgs("/usr/bin/systemd-run", "/usr/bin/gopass", 45, getenv))
+}
+
+func TestSystemdClearClipboard(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	oldBin := systemdRunBin
+	defer func() {
+		systemdRunBin = oldBin
+	}()
+
+	// without systemd-run the detached process is used
+	systemdRunBin = filepath.Join(tempdir, "missing")
+	env := []string{"GOPASS_UNCLIP_CHECKSUM=abcd", "GOPASS_UNCLIP_KEY=ef01"}
+	assert.Error(t, systemdClearClipboard(env, "xclip", 45))
+
+	argsFile := filepath.Join(tempdir, "args")
+	systemdRunBin = filepath.Join(tempdir, "systemd-run")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\necho \"$GOPASS_UNCLIP_CHECKSUM $GOPASS_UNCLIP_KEY\" >> " + argsFile + "\n"
+	assert.NoError(t, ioutil.WriteFile(systemdRunBin, []byte(script), 0755))
+	assert.NoError(t, systemdClearClipboard(env, "xclip", 45))
+	buf, err := ioutil.ReadFile(argsFile)
+	assert.NoError(t, err)
+	lines := strings.Split(string(buf), "\n")
+	assert.True(t, strings.HasPrefix(lines[0], "--user --quiet --on-active=45s "), lines[0])
+	assert.NotContains(t, lines[0], "abcd")
+	assert.Contains(t, lines[0], "--setenv=GOPASS_UNCLIP_CHECKSUM ")
+	assert.Contains(t, lines[0], "--setenv=GOPASS_CLIPBOARD_BACKEND=xclip")
+	assert.True(t, strings.HasSuffix(lines[0], " unclip --timeout 0"), lines[0])
+	// the checksum is inherited from the environment instead
+	assert.Equal(t, "abcd ef01", lines[1])
+
+	// a failing systemd-run is reported as well
+	assert.NoError(t, ioutil.WriteFile(systemdRunBin, []byte("#!/bin/sh\nexit 1\n"), 0755))
+	assert.Error(t, systemdClearClipboard(env, "xclip", 45))
+}
+
+func TestClipboardHash(t *testing.T) {
+	// the checksum is keyed, not the plain sha256 of the content
+	assert.NotEqual(t, "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", clipboardHash([]byte("secret")))
+	assert.Equal(t, clipboardHash([]byte("secret")), clipboardHash([]byte("secret")))
+	assert.NotEqual(t, clipboardHashWith([]byte("other key"), []byte("secret")), clipboardHash([]byte("secret")))
+
+	// copies of other processes are recognized by their key
+	h := clipHash{Hash: clipboardHashWith([]byte("other key"), []byte("secret")), Key: hex.EncodeToString([]byte("other key"))}
+	assert.True(t, h.matches("secret"))
+	assert.False(t, h.matches("other"))
+	h.Key = "not hex"
+	assert.False(t, h.matches("secret"))
+}
@@ -0,0 +1,161 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/urfave/cli"
+)
+
+// Identity is who gopass acts as: the gpg home, the key used for signing
+// and the active profile
+type Identity struct {
+	GnupgHome string `json:"gnupghome"`
+	Profile   string `json:"profile,omitempty"`
+	// Key is the fingerprint of the key, Source where it was taken from:
+	// profile, signkey, recipient or default
+	Key    string `json:"key,omitempty"`
+	UID    string `json:"uid,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Secret is set if the secret key is in the keyring
+	Secret bool `json:"secret"`
+	// CardSerial is the serial number of the hardware token holding the key
+	CardSerial string `json:"card,omitempty"`
+	Agent      bool   `json:"agent"`
+	// Others are the fingerprints of the other useable private keys
+	Others []string `json:"others,omitempty"`
+}
+
+// Whoami returns the effective identity. The key is the one of the active
+// profile, the configured signing key, the first recipient of the root store
+// with a secret key or the first useable private key, in that order.
+func (s *Action) Whoami() (Identity, error) {
+	id := Identity{
+		GnupgHome: gpg.HomeDir(),
+		Profile:   s.profile,
+		Agent:     gpg.AgentRunning(),
+		Others:    make([]string, 0, 1),
+	}
+	ek, err := s.effectiveKey()
+	if err != nil {
+		return id, err
+	}
+	id.Key, id.Source, id.Secret = ek.ID, ek.Source, ek.Secret
+	if ek.Key.Fingerprint != "" {
+		id.UID = ek.Key.UID()
+		id.CardSerial = ek.Key.CardSerial
+	}
+	for _, k := range ek.Useable {
+		if k.Fingerprint != ek.Key.Fingerprint {
+			id.Others = append(id.Others, k.Fingerprint)
+		}
+	}
+	return id, nil
+}
+
+// effective is the key gopass acts as, see effectiveKey
+type effective struct {
+	// ID is the fingerprint of the key, or the configured ID if it isn't in
+	// the keyring
+	ID string
+	// Source is where the key was taken from, see Identity
+	Source string
+	// Key is the key, if it's in the keyring
+	Key gpg.Key
+	// Secret is set if the secret key is in the keyring
+	Secret bool
+	// Useable are all useable private keys
+	Useable gpg.KeyList
+}
+
+// effectiveKey returns the key gopass acts as, e.g. to sign or to identify
+// the user. It's the key of the active profile, the configured signing key,
+// the first recipient of the root store with a secret key or the first
+// useable private key, in that order.
+func (s *Action) effectiveKey() (effective, error) {
+	ek := effective{}
+	kl, err := gpg.ListPrivateKeys()
+	if err != nil {
+		return ek, fmt.Errorf("failed to list private keys: %s", err)
+	}
+	ek.Useable = kl.UseableKeys()
+
+	switch {
+	case s.profileKey != "":
+		ek.ID, ek.Source = s.profileKey, "profile"
+	case s.Store.SignKey != "":
+		ek.ID, ek.Source = s.Store.SignKey, "signkey"
+	}
+	if ek.ID != "" {
+		if k, err := ek.Useable.FindKey(ek.ID); err == nil {
+			ek.Key, ek.Secret = k, true
+		} else if pl, err := gpg.ListPublicKeys(ek.ID); err == nil && len(pl) > 0 {
+			ek.Key = pl[0]
+		}
+	} else {
+		for _, r := range s.Store.ListRecipients("") {
+			if k, err := ek.Useable.FindKey(r); err == nil {
+				ek.Key, ek.Secret, ek.Source = k, true, "recipient"
+				break
+			}
+		}
+		if !ek.Secret && len(ek.Useable) > 0 {
+			ek.Key, ek.Secret, ek.Source = ek.Useable[0], true, "default"
+		}
+	}
+	if ek.Key.Fingerprint != "" {
+		ek.ID = ek.Key.Fingerprint
+	}
+	return ek, nil
+}
+
+// WhoamiPrint prints the effective identity, see Whoami
+func (s *Action) WhoamiPrint(c *cli.Context) error {
+	id, err := s.Whoami()
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		buf, err := json.MarshalIndent(id, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal identity: %s", err)
+		}
+		fmt.Println(string(buf))
+		return nil
+	}
+
+	profile := id.Profile
+	if profile == "" {
+		profile = "none"
+	}
+	fmt.Printf("GNUPGHOME: %s\n", id.GnupgHome)
+	fmt.Printf("Profile:   %s\n", profile)
+	if id.Key == "" {
+		fmt.Println(color.YellowString("Key:       none, no useable private key found"))
+	} else {
+		fmt.Printf("Key:       %s (%s)\n", color.GreenString(id.Key), id.Source)
+		if id.UID != "" {
+			fmt.Printf("UID:       %s\n", id.UID)
+		}
+		if !id.Secret {
+			fmt.Println(color.YellowString("Warning: the secret key of %s is not in your keyring", id.Key))
+		}
+		card := "no"
+		if id.CardSerial != "" {
+			card = "yes (" + id.CardSerial + ")"
+		}
+		fmt.Printf("On card:   %s\n", card)
+	}
+	agent := "running"
+	if !id.Agent {
+		agent = "not running"
+	}
+	fmt.Printf("Agent:     %s\n", agent)
+	for _, fpr := range id.Others {
+		fmt.Printf("Also useable: %s\n", fpr)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhoami(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	if err := ioutil.WriteFile(filepath.Join(tempdir, ".gpg-id"), []byte(fpr+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .gpg-id: %s", err)
+	}
+	store, err := password.NewRootStore(tempdir)
+	assert.NoError(t, err)
+	s := &Action{Store: store}
+
+	// without any configuration the recipient with a secret key is used
+	id, err := s.Whoami()
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getenv("GNUPGHOME"), id.GnupgHome)
+	assert.Equal(t, "", id.Profile)
+	assert.Equal(t, fpr, id.Key)
+	assert.Equal(t, "recipient", id.Source)
+	assert.Equal(t, gpgtest.Name+" <"+gpgtest.Email+">", id.UID)
+	assert.True(t, id.Secret)
+	assert.Equal(t, "", id.CardSerial)
+	assert.Equal(t, gpg.AgentRunning(), id.Agent)
+	assert.Empty(t, id.Others)
+
+	// a configured signing key is reported without its secret key
+	s.Store.SignKey = "DEADBEEF"
+	id, err = s.Whoami()
+	assert.NoError(t, err)
+	assert.Equal(t, "DEADBEEF", id.Key)
+	assert.Equal(t, "signkey", id.Source)
+	assert.False(t, id.Secret)
+	assert.Equal(t, "", id.UID)
+
+	// the key of the profile takes precedence over the signing key
+	s.profile, s.profileKey = "work", fpr[len(fpr)-8:]
+	id, err = s.Whoami()
+	assert.NoError(t, err)
+	assert.Equal(t, "work", id.Profile)
+	assert.Equal(t, fpr, id.Key)
+	assert.Equal(t, "profile", id.Source)
+	assert.True(t, id.Secret)
+
+	// plugins and the changelog use the same key
+	assert.Equal(t, fpr, s.pluginKey())
+	assert.Equal(t, gpgtest.Name+" <"+gpgtest.Email+">", s.changelogIdentity())
+	s.profileKey = ""
+	assert.Equal(t, "DEADBEEF", s.pluginKey())
+	assert.Equal(t, "unknown", s.changelogIdentity())
+}
@@ -0,0 +1,96 @@
+// Package age encrypts and decrypts files for age (https://age-encryption.org)
+// recipients using the age command line tool. It exists to help with a
+// gradual migration from gpg, see password.Store.AgeRecipients.
+package age
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+const (
+	// recipientPrefix is the human readable part of age X25519 recipients
+	recipientPrefix = "age1"
+	// recipientLen is the length of a bech32 encoded X25519 recipient
+	recipientLen = 62
+	// bech32Charset are the characters of the data part of bech32 strings
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	dirPerm       = 0700
+)
+
+// Bin is the age binary, it may be a full path
+var Bin = "age"
+
+// IsRecipient returns true if the given ID looks like an age X25519
+// recipient, i.e. a public key like age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p.
+// The checksum isn't verified, age does that when encrypting.
+func IsRecipient(id string) bool {
+	if len(id) != recipientLen || !strings.HasPrefix(id, recipientPrefix) {
+		return false
+	}
+	for _, c := range id[len(recipientPrefix):] {
+		if !strings.ContainsRune(bech32Charset, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Available returns true if the age binary can be found
+func Available() bool {
+	_, err := exec.LookPath(Bin)
+	return err == nil
+}
+
+// Encrypt encrypts the content for the given recipients and writes the
+// ciphertext to path. The content is handed to age on stdin.
+func Encrypt(path string, content []byte, recipients []string) error {
+	if len(recipients) < 1 {
+		return fmt.Errorf("no age recipients")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return err
+	}
+
+	args := []string{"--encrypt", "--output", path}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	cmd := command(args...)
+	cmd.Stdin = bytes.NewReader(content)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return log.SanitizeError(fmt.Errorf("failed to encrypt for age recipients: %s: %s", err, strings.TrimSpace(stderr.String())), content)
+	}
+	return nil
+}
+
+// Decrypt decrypts the file at path with the keys of the given identity
+// file and returns the plaintext
+func Decrypt(path, identity string) ([]byte, error) {
+	if identity == "" {
+		return nil, fmt.Errorf("no age identity")
+	}
+	cmd := command("--decrypt", "--identity", identity, path)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, log.SanitizeError(fmt.Errorf("failed to decrypt with age: %s: %s", err, strings.TrimSpace(stderr.String())), out)
+	}
+	return out, nil
+}
+
+// command creates a new age command and logs its argv at the debug level
+func command(args ...string) *exec.Cmd {
+	cmd := exec.Command(Bin, args...)
+	log.Debugf("age: %s", strings.Join(cmd.Args, " "))
+	return cmd
+}
@@ -0,0 +1,53 @@
+package age_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/age"
+	"github.com/justwatchcom/gopass/age/agetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRecipient(t *testing.T) {
+	for id, ok := range map[string]bool{
+		agetest.Recipient: true,
+		"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8":   false,
+		"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8b":  false,
+		"AGE1QL3Z7HJY54PW3HYWW5AYYFG7ZQGVC7W3J2ELW8ZMRJ2KG5SFN9AQMCAC8P":  false,
+		"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8pq": false,
+		"DEADBEEF": false,
+	} {
+		assert.Equal(t, ok, age.IsRecipient(id), id)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	rec, identity, cleanup := agetest.NewIdentity(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	fn := filepath.Join(tempdir, "foo", "bar.age")
+	assert.Error(t, age.Encrypt(fn, []byte("secret"), nil))
+	assert.NoError(t, age.Encrypt(fn, []byte("secret\nbody"), []string{rec}))
+	buf, err := age.Decrypt(fn, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nbody", string(buf))
+
+	// another identity can't decrypt it
+	other := filepath.Join(tempdir, "other.txt")
+	assert.NoError(t, ioutil.WriteFile(other, []byte("# public key: age1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq\nAGE-SECRET-KEY-OTHER\n"), 0600))
+	_, err = age.Decrypt(fn, other)
+	assert.Error(t, err)
+	_, err = age.Decrypt(fn, "")
+	assert.Error(t, err)
+}
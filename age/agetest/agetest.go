@@ -0,0 +1,105 @@
+// Package agetest provides an age identity for tests. If age and age-keygen
+// aren't installed a stand-in script is used instead of the real age, which
+// only pretends to encrypt.
+package agetest
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justwatchcom/gopass/age"
+)
+
+// Recipient is the public key of the identity written if age-keygen is
+// missing
+const Recipient = "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+
+// fakeAge writes the recipients and the plaintext to the output file and
+// only decrypts files listing the public key of the given identity
+const fakeAge = `#!/bin/sh
+set -e
+mode=
+out=
+id=
+rs=
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--encrypt) mode=e ;;
+	--decrypt) mode=d ;;
+	--output) out="$2"; shift ;;
+	--identity) id="$2"; shift ;;
+	--recipient) rs="$rs $2"; shift ;;
+	*) in="$1" ;;
+	esac
+	shift
+done
+if [ "$mode" = e ]; then
+	{ echo "fake-age$rs"; cat; } > "$out"
+	exit 0
+fi
+pub=$(sed -n 's/^# public key: //p' "$id")
+if ! head -n 1 "$in" | grep -q " $pub"; then
+	echo "no identity matched any of the recipients" >&2
+	exit 1
+fi
+tail -n +2 "$in"
+`
+
+// NewIdentity creates an age identity in a temporary directory. It returns
+// the public key, the path of the identity file and a cleanup func, which
+// restores age.Bin.
+func NewIdentity(t testing.TB) (string, string, func()) {
+	dir, err := ioutil.TempDir("", "agetest-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	oldBin := age.Bin
+	cleanup := func() {
+		age.Bin = oldBin
+		_ = os.RemoveAll(dir)
+	}
+	identity := filepath.Join(dir, "identity.txt")
+
+	if _, err := exec.LookPath("age-keygen"); err == nil && age.Available() {
+		if err := exec.Command("age-keygen", "-o", identity).Run(); err != nil {
+			cleanup()
+			t.Fatalf("Failed to generate age identity: %s", err)
+		}
+		return publicKey(t, identity), identity, cleanup
+	}
+
+	age.Bin = filepath.Join(dir, "age")
+	if err := ioutil.WriteFile(age.Bin, []byte(fakeAge), 0700); err != nil {
+		cleanup()
+		t.Fatalf("Failed to write fake age: %s", err)
+	}
+	buf := "# public key: " + Recipient + "\nAGE-SECRET-KEY-FAKE\n"
+	if err := ioutil.WriteFile(identity, []byte(buf), 0600); err != nil {
+		cleanup()
+		t.Fatalf("Failed to write age identity: %s", err)
+	}
+	return Recipient, identity, cleanup
+}
+
+// publicKey reads the public key from the comment age-keygen writes to the
+// identity file
+func publicKey(t testing.TB, identity string) string {
+	buf, err := ioutil.ReadFile(identity)
+	if err != nil {
+		t.Fatalf("Failed to read age identity: %s", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "# public key: ") {
+			return strings.TrimPrefix(line, "# public key: ")
+		}
+	}
+	t.Fatalf("No public key found in %s", identity)
+	return ""
+}
@@ -0,0 +1,170 @@
+// Package audit keeps an append-only log of events affecting which keys are
+// trusted, i.e. the import of public keys. Each line holds the hash of the
+// line before it, so changing or removing a line breaks the chain.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// Decisions about a key import
+const (
+	// Imported keys were imported after the user confirmed it
+	Imported = "imported"
+	// AutoImported keys were imported without asking, see autoimport
+	AutoImported = "auto-imported"
+	// Declined keys were not imported because the user said no
+	Declined = "declined"
+	// Failed keys were to be imported, but gpg failed
+	Failed = "failed"
+	// Refreshed keys were fetched from a keyserver
+	Refreshed = "refreshed"
+)
+
+var (
+	// File is the key import log. If empty nothing is logged.
+	File = ""
+	// now returns the current time, it's replaced by tests
+	now = time.Now
+)
+
+// ImportRecord is one key import
+type ImportRecord struct {
+	Time        time.Time `json:"time"`
+	Fingerprint string    `json:"fingerprint"`
+	UID         string    `json:"uid,omitempty"`
+	// Source is where the key came from, e.g. the key file of a store or a
+	// keyserver
+	Source   string `json:"source"`
+	Decision string `json:"decision"`
+	// Prev is the hash of the previous line, empty for the first one
+	Prev string `json:"prev"`
+}
+
+// NewImportRecord returns a record for the given key. The first user ID
+// of the key is used.
+func NewImportRecord(k gpg.Key, source, decision string) ImportRecord {
+	uids := make([]string, 0, len(k.Identities))
+	for uid := range k.Identities {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	rec := ImportRecord{
+		Fingerprint: k.Fingerprint,
+		Source:      source,
+		Decision:    decision,
+	}
+	if len(uids) > 0 {
+		rec.UID = uids[0]
+	}
+	return rec
+}
+
+// Enabled returns true if key imports are logged
+func Enabled() bool {
+	return File != ""
+}
+
+// LogKeyImport appends the record to File, chained to the last line. The
+// time is set unless the record has one. The file is locked while it's
+// written, so concurrent gopass processes don't break the chain.
+func LogKeyImport(rec ImportRecord) error {
+	if File == "" {
+		return nil
+	}
+	fn := fsutil.CleanPath(File)
+	if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return err
+	}
+	fh, err := os.OpenFile(fn, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the key import log: %s", err)
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	if err := syscall.Flock(int(fh.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock the key import log: %s", err)
+	}
+	defer func() {
+		_ = syscall.Flock(int(fh.Fd()), syscall.LOCK_UN)
+	}()
+
+	buf, err := ioutil.ReadAll(fh)
+	if err != nil {
+		return err
+	}
+	rec.Prev = hashLine(lastLine(buf))
+	if rec.Time.IsZero() {
+		rec.Time = now()
+	}
+	rec.Time = rec.Time.UTC()
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := fh.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write the key import log: %s", err)
+	}
+	return nil
+}
+
+// Verify checks the hash chain of the given log and returns its records.
+// The first broken link is reported with its line number.
+func Verify(fn string) ([]ImportRecord, error) {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+
+	recs := make([]ImportRecord, 0, 10)
+	var prev []byte
+	scanner := bufio.NewScanner(fh)
+	for i := 1; scanner.Scan(); i++ {
+		line := scanner.Bytes()
+		rec := ImportRecord{}
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return recs, fmt.Errorf("line %d: %s", i, err)
+		}
+		if rec.Prev != hashLine(prev) {
+			return recs, fmt.Errorf("line %d: hash chain broken, the previous line was changed or removed", i)
+		}
+		recs = append(recs, rec)
+		prev = append(prev[:0], line...)
+	}
+	return recs, scanner.Err()
+}
+
+// lastLine returns the last line of buf, without the newline
+func lastLine(buf []byte) []byte {
+	buf = bytes.TrimRight(buf, "\n")
+	if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+		return buf[i+1:]
+	}
+	return buf
+}
+
+// hashLine returns the hex encoded SHA-256 of a line, or the empty string
+// for no line at all
+func hashLine(line []byte) string {
+	if len(line) < 1 {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(line))
+}
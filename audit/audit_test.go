@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogKeyImport(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	oldFile, oldNow := File, now
+	defer func() {
+		File, now = oldFile, oldNow
+	}()
+	now = func() time.Time {
+		return time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	// disabled without a file
+	File = ""
+	assert.False(t, Enabled())
+	assert.NoError(t, LogKeyImport(ImportRecord{Fingerprint: "CAFEBABE"}))
+
+	File = filepath.Join(tempdir, "logs", "key-imports.log")
+	assert.True(t, Enabled())
+	k := gpg.Key{
+		Fingerprint: "AB919DBF9BF0DE74896397F282EBD945BE73F104",
+		Identities: map[string]gpg.Identity{
+			"John Doe <john.doe@gopass.pw>": {},
+		},
+	}
+	assert.NoError(t, LogKeyImport(NewImportRecord(k, "/store/.gpg-keys/BE73F104", Imported)))
+	assert.NoError(t, LogKeyImport(ImportRecord{Fingerprint: "DEADBEEF", Source: "/store/.gpg-keys/DEADBEEF", Decision: Declined}))
+	assert.NoError(t, LogKeyImport(NewImportRecord(k, "keyserver of gpg", Refreshed)))
+
+	recs, err := Verify(File)
+	assert.NoError(t, err)
+	assert.Len(t, recs, 3)
+	assert.Equal(t, "John Doe <john.doe@gopass.pw>", recs[0].UID)
+	assert.Equal(t, Declined, recs[1].Decision)
+	assert.Equal(t, now(), recs[2].Time)
+
+	// each line is chained to the one before it
+	buf, err := ioutil.ReadFile(File)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "", recs[0].Prev)
+	assert.Equal(t, hashLine([]byte(lines[0])), recs[1].Prev)
+	assert.Equal(t, hashLine([]byte(lines[1])), recs[2].Prev)
+
+	// changing a line breaks the chain at the next one
+	changed := strings.Replace(string(buf), Declined, Imported, 1)
+	assert.NoError(t, ioutil.WriteFile(File, []byte(changed), 0600))
+	_, err = Verify(File)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 3: hash chain broken")
+
+	// so does removing one
+	assert.NoError(t, ioutil.WriteFile(File, []byte(lines[1]+"\n"+lines[2]+"\n"), 0600))
+	_, err = Verify(File)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1: hash chain broken")
+}
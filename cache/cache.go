@@ -0,0 +1,82 @@
+// Package cache keeps decrypted secrets in memory for the duration of a
+// session, e.g. while browsing with gopass ui, and wipes them once the
+// screen is locked.
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// lockSignal is the signal of logind announcing that a session is locked,
+// as printed by gdbus monitor
+const lockSignal = "org.freedesktop.login1.Session.Lock "
+
+// lockEvents returns a channel receiving a value every time the screen is
+// locked, until ctx is done. It's nil if the platform has no lock signal.
+var lockEvents = systemLockEvents
+
+// Cache maps the names of secrets to their decrypted content. It is safe
+// for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+// New returns an empty cache
+func New() *Cache {
+	return &Cache{entries: make(map[string][]byte, 10)}
+}
+
+// Get returns the cached content of a secret
+func (c *Cache) Get(name string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	content, found := c.entries[name]
+	return content, found
+}
+
+// Set caches the content of a secret
+func (c *Cache) Set(name string, content []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[name] = content
+}
+
+// Len returns the number of cached secrets
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Flush overwrites the content of all secrets with zeros and drops them
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, content := range c.entries {
+		for i := range content {
+			content[i] = 0
+		}
+		delete(c.entries, name)
+	}
+}
+
+// LockEvents returns a channel receiving a value every time the screen is
+// locked, so the cache can be flushed and secrets are only kept while the
+// user is present. On Linux the lock signal of logind
+// (org.freedesktop.login1) is used, if gdbus is installed. It's sent for
+// locks requested through logind, e.g. by `loginctl lock-session` or desktops
+// using it, but not by screen lockers started directly. Elsewhere, including
+// macOS, there's no lock signal and the channel is nil. It's closed once ctx
+// is done or the signal can't be watched any longer.
+func LockEvents(ctx context.Context) (<-chan struct{}, error) {
+	return lockEvents(ctx)
+}
+
+// isLockSignal returns true if a line printed by gdbus monitor is the lock
+// signal of a session
+func isLockSignal(line string) bool {
+	return strings.Contains(line, lockSignal)
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	c := New()
+	content := []byte("secret\nuser: gopher\n")
+	c.Set("foo", content)
+	got, found := c.Get("foo")
+	assert.True(t, found)
+	assert.Equal(t, "secret\nuser: gopher\n", string(got))
+	_, found = c.Get("bar")
+	assert.False(t, found)
+	assert.Equal(t, 1, c.Len())
+
+	// the content is wiped, not just dropped
+	c.Flush()
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, make([]byte, len(content)), content)
+}
+
+func TestLockEvents(t *testing.T) {
+	events := make(chan struct{})
+	oldEvents := lockEvents
+	lockEvents = func(context.Context) (<-chan struct{}, error) {
+		return events, nil
+	}
+	defer func() {
+		lockEvents = oldEvents
+	}()
+
+	ch, err := LockEvents(context.Background())
+	assert.NoError(t, err)
+	go func() {
+		events <- struct{}{}
+	}()
+	_, ok := <-ch
+	assert.True(t, ok)
+}
+
+func TestIsLockSignal(t *testing.T) {
+	assert.True(t, isLockSignal("/org/freedesktop/login1/session/_32: org.freedesktop.login1.Session.Lock ()"))
+	assert.False(t, isLockSignal("/org/freedesktop/login1/session/_32: org.freedesktop.login1.Session.Unlock ()"))
+	assert.False(t, isLockSignal("/org/freedesktop/login1: org.freedesktop.login1.Manager.SessionNew ('3', objectpath '/org/freedesktop/login1/session/_33')"))
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// GDBusBin is the name and possibly location of gdbus, used to listen for
+// the lock signal of logind
+var GDBusBin = "gdbus"
+
+// systemLockEvents watches the system bus for the lock signal of logind.
+// Without gdbus there's no lock signal.
+func systemLockEvents(ctx context.Context) (<-chan struct{}, error) {
+	if _, err := exec.LookPath(GDBusBin); err != nil {
+		log.Debugf("cache: can not listen for screen locks: %s", err)
+		return nil, nil
+	}
+	cmd := exec.CommandContext(ctx, GDBusBin, "monitor", "--system", "--dest", "org.freedesktop.login1")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			if !isLockSignal(scanner.Text()) {
+				continue
+			}
+			select {
+			case ch <- struct{}{}:
+			default:
+				// a flush is pending already
+			}
+		}
+		log.Debugf("cache: gdbus monitor exited: %v", cmd.Wait())
+	}()
+	return ch, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package cache
+
+import "context"
+
+// systemLockEvents has no lock signal to listen for on this platform. On
+// macOS that would be the com.apple.screenIsLocked distributed
+// notification, which can't be observed without cgo.
+func systemLockEvents(ctx context.Context) (<-chan struct{}, error) {
+	return nil, nil
+}
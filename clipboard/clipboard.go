@@ -0,0 +1,236 @@
+// Package clipboard copies to and reads back from the system clipboard with
+// the clipboard tool of the environment, e.g. wl-copy on Wayland, xclip or
+// xsel on X11, pbcopy on macOS or clip.exe on WSL. The backend is detected,
+// unless it's selected with GOPASS_CLIPBOARD_BACKEND.
+package clipboard
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	sysclip "github.com/atotto/clipboard"
+	"github.com/justwatchcom/gopass/log"
+)
+
+// EnvBackend selects the backend by name instead of detecting it
+const EnvBackend = "GOPASS_CLIPBOARD_BACKEND"
+
+// Backend is a clipboard
+type Backend interface {
+	// Name is the name the backend is selected by
+	Name() string
+	// Read returns the content of the clipboard
+	Read() (string, error)
+	// Write replaces the content of the clipboard
+	Write(text string) error
+}
+
+// Command is a backend using a pair of command line tools
+type Command struct {
+	name  string
+	copy  []string
+	paste []string
+	// trim is removed from the end of the pasted text, for tools adding a
+	// line break
+	trim string
+	// env is an environment variable required by the tool, e.g. DISPLAY
+	env string
+}
+
+// Backends are the command line backends in the order they're detected
+var Backends = []Command{
+	{name: "wl-copy", copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}, env: "WAYLAND_DISPLAY"},
+	{name: "xclip", copy: []string{"xclip", "-in", "-selection", "clipboard"}, paste: []string{"xclip", "-out", "-selection", "clipboard"}, env: "DISPLAY"},
+	{name: "xsel", copy: []string{"xsel", "--input", "--clipboard"}, paste: []string{"xsel", "--output", "--clipboard"}, env: "DISPLAY"},
+	{name: "pbcopy", copy: []string{"pbcopy"}, paste: []string{"pbpaste"}},
+	// Get-Clipboard ends its output with a line break
+	{name: "clip.exe", copy: []string{"clip.exe"}, paste: []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"}, trim: "\r\n"},
+}
+
+// Name implements Backend
+func (c Command) Name() string {
+	return c.name
+}
+
+// Available returns true if both tools of the backend are installed
+func (c Command) Available() bool {
+	for _, bin := range []string{c.copy[0], c.paste[0]} {
+		if _, err := exec.LookPath(bin); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Read implements Backend
+func (c Command) Read() (string, error) {
+	cmd := exec.Command(c.paste[0], c.paste[1:]...)
+	stderr, readStderr := captureStderr()
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", log.SanitizeError(fmt.Errorf("failed to read the clipboard with %s: %s: %s", c.paste[0], err, readStderr()), out)
+	}
+	_ = readStderr()
+	return strings.TrimSuffix(string(out), c.trim), nil
+}
+
+// Write implements Backend. The text is handed to the tool on stdin, so it
+// doesn't show up in the process list. Tools like xclip and wl-copy fork a
+// child serving the selection, which keeps the stdout and stderr of the tool
+// open. Neither is read through a pipe, so Write returns once the tool itself
+// exited.
+func (c Command) Write(text string) error {
+	cmd := exec.Command(c.copy[0], c.copy[1:]...)
+	stderr, readStderr := captureStderr()
+	cmd.Stderr = stderr
+	fail := func(err error) error {
+		return log.SanitizeError(fmt.Errorf("failed to write to the clipboard with %s: %s: %s", c.copy[0], err, readStderr()), []byte(text))
+	}
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return fail(err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fail(err)
+	}
+	_, err = in.Write([]byte(text))
+	if cerr := in.Close(); err == nil {
+		err = cerr
+	}
+	if werr := cmd.Wait(); err == nil {
+		err = werr
+	}
+	if err != nil {
+		return fail(err)
+	}
+	_ = readStderr()
+	return nil
+}
+
+// captureStderr returns a file for the stderr of a clipboard tool and a
+// function returning what it wrote, which closes the file. It's a file and
+// not a buffer, a pipe would block until the forked child of the tool exits.
+func captureStderr() (*os.File, func() string) {
+	fh, err := ioutil.TempFile("", "gopass-clipboard-")
+	if err != nil {
+		log.Debugf("clipboard: failed to capture stderr: %s", err)
+		return nil, func() string { return "" }
+	}
+	_ = os.Remove(fh.Name())
+	return fh, func() string {
+		defer func() {
+			_ = fh.Close()
+		}()
+		if _, err := fh.Seek(0, 0); err != nil {
+			return ""
+		}
+		buf, err := ioutil.ReadAll(fh)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(buf))
+	}
+}
+
+// System is the clipboard API of Windows
+type System struct{}
+
+// Name implements Backend
+func (System) Name() string {
+	return "windows"
+}
+
+// Read implements Backend
+func (System) Read() (string, error) {
+	return sysclip.ReadAll()
+}
+
+// Write implements Backend
+func (System) Write(text string) error {
+	return sysclip.WriteAll(text)
+}
+
+// Memory is a clipboard only held in memory, e.g. for tests
+type Memory struct {
+	Content string
+}
+
+// Name implements Backend
+func (*Memory) Name() string {
+	return "memory"
+}
+
+// Read implements Backend
+func (m *Memory) Read() (string, error) {
+	return m.Content, nil
+}
+
+// Write implements Backend
+func (m *Memory) Write(text string) error {
+	m.Content = text
+	return nil
+}
+
+// Detect returns the backend selected by GOPASS_CLIPBOARD_BACKEND or, if
+// it's unset, the first available one. Tools whose display isn't set, e.g.
+// xclip on Wayland, are only used if nothing else is available.
+func Detect() (Backend, error) {
+	if name := os.Getenv(EnvBackend); name != "" {
+		return ByName(name)
+	}
+	if runtime.GOOS == "windows" {
+		return System{}, nil
+	}
+	var fallback Backend
+	for _, c := range Backends {
+		if !c.Available() {
+			continue
+		}
+		if c.env == "" || os.Getenv(c.env) != "" {
+			log.Debugf("clipboard: using %s", c.name)
+			return c, nil
+		}
+		if fallback == nil {
+			fallback = c
+		}
+	}
+	if fallback != nil {
+		log.Debugf("clipboard: using %s", fallback.Name())
+		return fallback, nil
+	}
+	return nil, fmt.Errorf("no clipboard tool found. Install one of %s or set %s", strings.Join(Names()[:len(Backends)], ", "), EnvBackend)
+}
+
+// ByName returns the backend with the given name, if it's available
+func ByName(name string) (Backend, error) {
+	if name == (System{}).Name() {
+		if runtime.GOOS != "windows" {
+			return nil, fmt.Errorf("the clipboard backend %s is only available on Windows", name)
+		}
+		return System{}, nil
+	}
+	for _, c := range Backends {
+		if c.name != name {
+			continue
+		}
+		if !c.Available() {
+			return nil, fmt.Errorf("the clipboard backend %s is not installed", name)
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown clipboard backend '%s'. Use one of %s", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of all backends, in the order they're detected
+func Names() []string {
+	names := make([]string, 0, len(Backends)+1)
+	for _, c := range Backends {
+		names = append(names, c.name)
+	}
+	return append(names, System{}.Name())
+}
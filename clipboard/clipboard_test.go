@@ -0,0 +1,144 @@
+package clipboard
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTools installs the given clipboard tools in a new PATH, sharing the
+// clipboard in a file. Tools called with paste in their name or with an
+// output flag paste, all others copy.
+func fakeTools(t *testing.T, names ...string) func() {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	cat, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skipf("cat not found: %s", err)
+	}
+	clip := filepath.Join(tempdir, "clipboard")
+	script := "#!/bin/sh\ncase \"$0 $*\" in\n*paste*|*-out*) " + cat + " " + clip + " ;;\n*) " + cat + " > " + clip + " ;;\nesac\n"
+	for _, name := range names {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, name), []byte(script), 0755))
+	}
+	oldPath := os.Getenv("PATH")
+	assert.NoError(t, os.Setenv("PATH", tempdir))
+	return func() {
+		_ = os.Setenv("PATH", oldPath)
+		_ = os.RemoveAll(tempdir)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	cleanup := fakeTools(t, "pbcopy", "pbpaste")
+	defer cleanup()
+
+	var b Backend = Command{name: "pbcopy", copy: []string{"pbcopy"}, paste: []string{"pbpaste"}}
+	assert.NoError(t, b.Write("secret\nuser: gopher"))
+	out, err := b.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nuser: gopher", out)
+	assert.NoError(t, b.Write(""))
+	out, err = b.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "", out)
+
+	// the line break some tools add is removed
+	b = Command{name: "crlf", copy: []string{"pbcopy"}, paste: []string{"pbpaste"}, trim: "\r\n"}
+	assert.NoError(t, b.Write("secret\r\n"))
+	out, err = b.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", out)
+
+	b = Command{name: "missing", copy: []string{"missing"}, paste: []string{"missing"}}
+	assert.Error(t, b.Write("secret"))
+	_, err = b.Read()
+	assert.Error(t, err)
+}
+
+func TestCommandForking(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skipf("sleep not found: %s", err)
+	}
+	cleanup := fakeTools(t, "xclip")
+	defer cleanup()
+
+	// like xclip the copy forks a child serving the selection, which keeps
+	// the stdout and stderr of the tool open
+	fork := filepath.Join(os.Getenv("PATH"), "forking")
+	script := "#!/bin/sh\n" + filepath.Join(os.Getenv("PATH"), "xclip") + " \"$@\"\n(" + sleep + " 4) &\necho 'serving the selection' >&2\n"
+	assert.NoError(t, ioutil.WriteFile(fork, []byte(script), 0755))
+
+	b := Command{name: "forking", copy: []string{"forking", "-in"}, paste: []string{"xclip", "-out"}}
+	start := time.Now()
+	assert.NoError(t, b.Write("secret"))
+	assert.True(t, time.Since(start) < 2*time.Second, "write waited for the forked child")
+	out, err := b.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", out)
+}
+
+func TestDetect(t *testing.T) {
+	cleanup := fakeTools(t, "xclip", "wl-copy", "wl-paste")
+	defer cleanup()
+
+	for _, k := range []string{EnvBackend, "DISPLAY", "WAYLAND_DISPLAY"} {
+		old, found := os.LookupEnv(k)
+		defer func(k string) {
+			if found {
+				_ = os.Setenv(k, old)
+				return
+			}
+			_ = os.Unsetenv(k)
+		}(k)
+		assert.NoError(t, os.Unsetenv(k))
+	}
+
+	// without any display the first available tool is used
+	b, err := Detect()
+	assert.NoError(t, err)
+	assert.Equal(t, "wl-copy", b.Name())
+
+	// otherwise the tool of the display
+	assert.NoError(t, os.Setenv("DISPLAY", ":0"))
+	b, err = Detect()
+	assert.NoError(t, err)
+	assert.Equal(t, "xclip", b.Name())
+	assert.NoError(t, os.Setenv("WAYLAND_DISPLAY", "wayland-0"))
+	b, err = Detect()
+	assert.NoError(t, err)
+	assert.Equal(t, "wl-copy", b.Name())
+
+	// unless one is selected
+	assert.NoError(t, os.Setenv(EnvBackend, "xclip"))
+	b, err = Detect()
+	assert.NoError(t, err)
+	assert.Equal(t, "xclip", b.Name())
+
+	// the copy of one backend can be read back with the same backend
+	assert.NoError(t, b.Write("secret"))
+	out, err := b.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", out)
+
+	assert.NoError(t, os.Setenv(EnvBackend, "xsel"))
+	_, err = Detect()
+	assert.Error(t, err)
+	assert.NoError(t, os.Setenv(EnvBackend, "foo"))
+	_, err = Detect()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Use one of wl-copy, xclip, xsel, pbcopy, clip.exe, windows")
+
+	assert.NoError(t, os.Unsetenv(EnvBackend))
+	assert.NoError(t, os.Setenv("PATH", ""))
+	_, err = Detect()
+	assert.Error(t, err)
+}
@@ -1,7 +1,9 @@
 package fsutil
 
 import (
+	"crypto/rand"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -77,3 +79,52 @@ func Tempdir() string {
 	}
 	return ""
 }
+
+// Shred overwrites the given file multiple times with random data before
+// removing it. This is a best effort attempt to not leave any plaintext on
+// disk. It will not work reliably on copy-on-write or journaling filesystems.
+func Shred(path string, runs int) error {
+	return shred(path, runs, rand.Reader)
+}
+
+// shred overwrites the file with data from rnd. The file is removed even if
+// overwriting it fails, the errors are combined then.
+func shred(path string, runs int, rnd io.Reader) error {
+	fh, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %s", path, err)
+	}
+	err = overwrite(fh, path, runs, rnd)
+	if cerr := fh.Close(); cerr != nil && err == nil {
+		err = fmt.Errorf("failed to close file %s: %s", path, cerr)
+	}
+	if rerr := os.Remove(path); rerr != nil {
+		if err == nil {
+			return rerr
+		}
+		return fmt.Errorf("%s and failed to remove it: %s", err, rerr)
+	}
+	return err
+}
+
+// overwrite overwrites the content of the open file runs times
+func overwrite(fh *os.File, path string, runs int, rnd io.Reader) error {
+	fi, err := fh.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %s", path, err)
+	}
+
+	buf := make([]byte, fi.Size())
+	for i := 0; i < runs; i++ {
+		if _, err := io.ReadFull(rnd, buf); err != nil {
+			return fmt.Errorf("failed to read random data: %s", err)
+		}
+		if _, err := fh.WriteAt(buf, 0); err != nil {
+			return fmt.Errorf("failed to overwrite file %s: %s", path, err)
+		}
+		if err := fh.Sync(); err != nil {
+			return fmt.Errorf("failed to sync file %s: %s", path, err)
+		}
+	}
+	return nil
+}
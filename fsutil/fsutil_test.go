@@ -1,10 +1,12 @@
 package fsutil
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -72,3 +74,51 @@ func TestTempdir(t *testing.T) {
 		_ = os.RemoveAll(tempdir)
 	}()
 }
+
+func TestShred(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	fn := filepath.Join(tempdir, "foo")
+	if err := ioutil.WriteFile(fn, []byte("secret"), 0600); err != nil {
+		t.Fatalf("Failed to write test file: %s", err)
+	}
+	if err := Shred(fn, 3); err != nil {
+		t.Fatalf("Failed to shred %s: %s", fn, err)
+	}
+	if IsFile(fn) {
+		t.Errorf("Should be removed: %s", fn)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("no entropy")
+}
+
+func TestShredError(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	fn := filepath.Join(tempdir, "foo")
+	if err := ioutil.WriteFile(fn, []byte("secret"), 0600); err != nil {
+		t.Fatalf("Failed to write test file: %s", err)
+	}
+	// the file is removed even if overwriting it fails
+	err = shred(fn, 3, failingReader{})
+	if err == nil || !strings.Contains(err.Error(), "no entropy") {
+		t.Errorf("Should fail to read random data: %v", err)
+	}
+	if IsFile(fn) {
+		t.Errorf("Should be removed: %s", fn)
+	}
+}
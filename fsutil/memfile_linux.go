@@ -0,0 +1,45 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// memfdCreate contains the syscall number of memfd_create for the
+// architectures we know about. The vendored x/sys/unix predates it.
+var memfdCreate = map[string]uintptr{
+	"386":     356,
+	"amd64":   319,
+	"arm":     385,
+	"arm64":   279,
+	"ppc64le": 360,
+	"s390x":   350,
+}
+
+// mfdCloexec is MFD_CLOEXEC. Use exec.Cmd.ExtraFiles to pass the file on.
+const mfdCloexec = 0x1
+
+// MemFile creates an anonymous, memory backed file using memfd_create. The
+// content of this file never touches persistent storage and is gone once
+// the last reference to it is closed.
+func MemFile(name string) (*os.File, error) {
+	nr, found := memfdCreate[runtime.GOARCH]
+	if !found {
+		return nil, fmt.Errorf("memfd_create is not supported on %s", runtime.GOARCH)
+	}
+	if !IsDir("/proc/self/fd") {
+		return nil, fmt.Errorf("/proc/self/fd is not available")
+	}
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	fd, _, errno := syscall.Syscall(nr, uintptr(unsafe.Pointer(p)), mfdCloexec, 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("memfd_create failed: %s", errno)
+	}
+	return os.NewFile(fd, name), nil
+}
@@ -0,0 +1,32 @@
+package fsutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMemFile(t *testing.T) {
+	fh, err := MemFile("gopass-test")
+	if err != nil {
+		t.Skipf("memfd_create not available: %s", err)
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+
+	if _, err := fh.Write([]byte("secret")); err != nil {
+		t.Fatalf("Failed to write memfile: %s", err)
+	}
+
+	// reopening through /proc must yield the same content, that's what
+	// the editor will do
+	path := fmt.Sprintf("/proc/self/fd/%d", fh.Fd())
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %s", path, err)
+	}
+	if string(buf) != "secret" {
+		t.Errorf("Wrong content: %s", buf)
+	}
+}
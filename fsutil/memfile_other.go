@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// MemFile is not supported outside of linux
+func MemFile(name string) (*os.File, error) {
+	return nil, fmt.Errorf("memory backed files are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,110 @@
+package gpg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+var (
+	// ConnectAgentBin is the name and possibly location of gpg-connect-agent,
+	// used to see if gpg-agent is running
+	ConnectAgentBin = "gpg-connect-agent"
+	// GPGConfBin is the name and possibly location of gpgconf, used to
+	// launch gpg-agent
+	GPGConfBin = "gpgconf"
+	// AgentTimeout limits how long probing and launching gpg-agent may take
+	AgentTimeout = 3 * time.Second
+
+	// ErrAgentUnavailable is returned by EnsureAgent if gpg-agent isn't
+	// running and couldn't be started
+	ErrAgentUnavailable = errors.New("gpg-agent is not running and could not be started. " +
+		"Start it with `gpgconf --launch gpg-agent`, make sure GNUPGHOME is writable " +
+		"and that gpg-agent is installed, or run with GOPASS_DEBUG=true for details")
+
+	// agentHomes are the GNUPGHOMEs an agent was found running for
+	agentHomes   = make(map[string]bool, 1)
+	agentHomesMu sync.Mutex
+)
+
+// EnsureAgent makes sure gpg-agent is running and responsive, which gpg 2.1
+// and later need for every operation involving a secret key. A missing agent
+// is launched with gpgconf. Both the probe and the launch are limited by
+// AgentTimeout, so an unresponsive agent can't hang gopass. Once an agent was
+// found running for a GNUPGHOME it isn't probed again. If the agent can't be
+// probed at all, e.g. because gpg-connect-agent isn't installed, it's left to
+// gpg to report any problems.
+func EnsureAgent() error {
+	if !agentRequired() {
+		return nil
+	}
+	home := os.Getenv("GNUPGHOME")
+	agentHomesMu.Lock()
+	defer agentHomesMu.Unlock()
+	if agentHomes[home] {
+		return nil
+	}
+
+	if _, err := exec.LookPath(ConnectAgentBin); err != nil {
+		log.Debugf("gpg.EnsureAgent: can not probe gpg-agent: %s", err)
+		return nil
+	}
+	if err := runAgentCmd(ConnectAgentBin, "--no-autostart", "/bye"); err == nil {
+		agentHomes[home] = true
+		return nil
+	}
+
+	log.Debugf("gpg.EnsureAgent: gpg-agent is not running, launching it")
+	if err := runAgentCmd(GPGConfBin, "--launch", "gpg-agent"); err != nil {
+		return ErrAgentUnavailable
+	}
+	if err := runAgentCmd(ConnectAgentBin, "--no-autostart", "/bye"); err != nil {
+		return ErrAgentUnavailable
+	}
+	agentHomes[home] = true
+	return nil
+}
+
+// AgentRunning returns true if gpg-agent is running for the current
+// GNUPGHOME. Unlike EnsureAgent it never launches one.
+func AgentRunning() bool {
+	if _, err := exec.LookPath(ConnectAgentBin); err != nil {
+		return false
+	}
+	return runAgentCmd(ConnectAgentBin, "--no-autostart", "/bye") == nil
+}
+
+// HomeDir returns the gpg home directory in use, GNUPGHOME or ~/.gnupg
+func HomeDir() string {
+	if home := os.Getenv("GNUPGHOME"); home != "" {
+		return filepath.Clean(home)
+	}
+	return filepath.Join(os.Getenv("HOME"), ".gnupg")
+}
+
+// agentRequired returns true if the gpg binary always uses gpg-agent for
+// secret keys, i.e. it is gpg 2.1 or newer
+func agentRequired() bool {
+	return SupportsLoopback()
+}
+
+// runAgentCmd runs one of the gpg-agent tools, killing it after AgentTimeout.
+// Its output is discarded, so a daemon it spawns can't keep it from
+// returning.
+func runAgentCmd(bin string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), AgentTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin, args...)
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = ctx.Err()
+	}
+	log.Debugf("gpg.EnsureAgent: %s %v: %v", bin, args, err)
+	return err
+}
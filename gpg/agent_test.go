@@ -0,0 +1,64 @@
+package gpg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAgentTools puts fake gpg-connect-agent and gpgconf scripts first in
+// PATH and uses a fresh GNUPGHOME. The returned func restores both.
+func fakeAgentTools(t *testing.T, connect, conf string) (string, func()) {
+	dir, err := ioutil.TempDir("", "gopass-agent-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	for name, script := range map[string]string{
+		"gpg-connect-agent": connect,
+		"gpgconf":           conf,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0700); err != nil {
+			t.Fatalf("Failed to write %s: %s", name, err)
+		}
+	}
+	oldPath, oldHome := os.Getenv("PATH"), os.Getenv("GNUPGHOME")
+	_ = os.Setenv("PATH", dir+string(os.PathListSeparator)+oldPath)
+	_ = os.Setenv("GNUPGHOME", dir)
+	return dir, func() {
+		_ = os.Setenv("PATH", oldPath)
+		_ = os.Setenv("GNUPGHOME", oldHome)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestEnsureAgent(t *testing.T) {
+	if !agentRequired() {
+		t.Skip("gpg doesn't need gpg-agent")
+	}
+
+	// neither running nor startable
+	_, cleanup := fakeAgentTools(t, "exit 1", "exit 1")
+	assert.Equal(t, ErrAgentUnavailable, EnsureAgent())
+	cleanup()
+
+	// an agent that doesn't respond doesn't hang
+	oldTimeout := AgentTimeout
+	AgentTimeout = 200 * time.Millisecond
+	_, cleanup = fakeAgentTools(t, "exec /bin/sleep 10", "exit 0")
+	start := time.Now()
+	assert.Equal(t, ErrAgentUnavailable, EnsureAgent())
+	assert.True(t, time.Since(start) < 5*time.Second)
+	cleanup()
+	AgentTimeout = oldTimeout
+
+	// a missing agent is launched and not probed again afterwards
+	dir, cleanup := fakeAgentTools(t, `test -f "$GNUPGHOME/running"`, `touch "$GNUPGHOME/running"`)
+	defer cleanup()
+	assert.NoError(t, EnsureAgent())
+	assert.NoError(t, os.Remove(filepath.Join(dir, "running")))
+	assert.NoError(t, EnsureAgent())
+}
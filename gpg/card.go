@@ -0,0 +1,98 @@
+package gpg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// ErrNoCard is returned by CardStatus if no OpenPGP card, e.g. a YubiKey, is
+// inserted
+var ErrNoCard = errors.New("no OpenPGP card found, please insert your card")
+
+// CardInfo is what gpg reports about the inserted OpenPGP card
+type CardInfo struct {
+	Reader string `json:"reader"`
+	// AID is the application ID of the card, gpg lists it as the serial
+	// number of the keys on the card
+	AID          string `json:"aid"`
+	Serial       string `json:"serial"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	// Fingerprints are the fingerprints of the signing, encryption and
+	// authentication keys stored on the card
+	Fingerprints []string `json:"fingerprints"`
+}
+
+// Holds returns true if the card holds the secret key, or one of its
+// subkeys, of the given key
+func (c CardInfo) Holds(k Key) bool {
+	if c.AID != "" && c.AID == k.CardSerial {
+		return true
+	}
+	for _, fpr := range c.Fingerprints {
+		if fpr == k.Fingerprint {
+			return true
+		}
+		for id := range k.SubKeys {
+			if len(id) > 0 && strings.HasSuffix(fpr, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CardStatus returns the OpenPGP card currently inserted. ErrNoCard is
+// returned if there is none, or scdaemon can't access it.
+func CardStatus() (CardInfo, error) {
+	cmd := newCommand("CardStatus", "--with-colons", "--card-status")
+	out, err := cmd.Output()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return CardInfo{}, err
+	}
+	ci := parseCardStatus(bytes.NewReader(out))
+	if ci.AID == "" {
+		return ci, ErrNoCard
+	}
+	return ci, nil
+}
+
+// parseCardStatus parses the `--with-colons --card-status` output of gpg, e.g.
+// Reader:Yubico YubiKey OTP FIDO CCID:AID:D2760001240102010006069404750000:openpgp-card
+func parseCardStatus(r io.Reader) CardInfo {
+	ci := CardInfo{Fingerprints: make([]string, 0, 3)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Reader":
+			ci.Reader = fields[1]
+			if len(fields) > 3 && fields[2] == "AID" {
+				ci.AID = fields[3]
+			}
+		case "AID":
+			// gpg prints an empty AID record without a card
+			ci.AID = fields[1]
+		case "serial":
+			ci.Serial = fields[1]
+		case "vendor":
+			if len(fields) > 2 {
+				ci.Manufacturer = fields[2]
+			}
+		case "fpr":
+			// gpg 1.4 prints them in lower case
+			for _, fpr := range fields[1:] {
+				if fpr != "" {
+					ci.Fingerprints = append(ci.Fingerprints, strings.ToUpper(fpr))
+				}
+			}
+		}
+	}
+	return ci
+}
@@ -0,0 +1,89 @@
+package gpg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// yubikeyStatus is the card status of a YubiKey as printed by gpg 2.2
+const yubikeyStatus = `Reader:Yubico YubiKey OTP FIDO CCID:AID:D2760001240102010006069404750000:openpgp-card
+version:0201:
+vendor:0006:Yubico:
+serial:06940475:
+name:John:Doe:
+lang::
+sex:u:
+url::
+login::
+forcepin:1:::
+keyattr:1:1:2048:
+keyattr:2:1:2048:
+keyattr:3:1:2048:
+maxpinlen:127:127:127:
+pinretry:3:0:3:
+sigcount:12:::
+cafpr::::
+fpr:ab919dbf9bf0de74896397f282ebd945be73f104:C95AD2862697E25DABC74EE41B75EF8B2E3A897A::
+fprtime:1500000000:1500000000:0:
+`
+
+func TestCardStatus(t *testing.T) {
+	ci := parseCardStatus(strings.NewReader(yubikeyStatus))
+	assert.Equal(t, CardInfo{
+		Reader:       "Yubico YubiKey OTP FIDO CCID",
+		AID:          "D2760001240102010006069404750000",
+		Serial:       "06940475",
+		Manufacturer: "Yubico",
+		Fingerprints: []string{"AB919DBF9BF0DE74896397F282EBD945BE73F104", "C95AD2862697E25DABC74EE41B75EF8B2E3A897A"},
+	}, ci)
+
+	// the key is found by the card listed for it, its fingerprint or the ID
+	// of a subkey
+	assert.True(t, ci.Holds(Key{Fingerprint: "DEADBEEF", CardSerial: "D2760001240102010006069404750000"}))
+	assert.True(t, ci.Holds(Key{Fingerprint: "AB919DBF9BF0DE74896397F282EBD945BE73F104"}))
+	assert.True(t, ci.Holds(Key{Fingerprint: "DEADBEEF", SubKeys: map[string]struct{}{"1B75EF8B2E3A897A": {}}}))
+	assert.False(t, ci.Holds(Key{Fingerprint: "DEADBEEF", CardSerial: "D2760001240102010006012345670000", SubKeys: map[string]struct{}{"82EBD945BE73F105": {}}}))
+
+	// without a card gpg prints an empty AID
+	assert.Equal(t, "", parseCardStatus(strings.NewReader("AID:::\n")).AID)
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	oldBin := GPGBin
+	defer func() {
+		GPGBin = oldBin
+	}()
+	GPGBin = filepath.Join(tempdir, "gpg")
+	fake := func(out string, code int) {
+		script := "#!/bin/sh\ncat <<'EOF'\n" + out + "EOF\nexit " + strconv.Itoa(code) + "\n"
+		if err := ioutil.WriteFile(GPGBin, []byte(script), 0700); err != nil {
+			t.Fatalf("Failed to write fake gpg: %s", err)
+		}
+	}
+
+	fake(yubikeyStatus, 0)
+	ci, err = CardStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, "06940475", ci.Serial)
+
+	fake("AID:::\n", 2)
+	_, err = CardStatus()
+	assert.Equal(t, ErrNoCard, err)
+
+	// gpg itself missing isn't reported as a missing card
+	GPGBin = filepath.Join(tempdir, "missing")
+	_, err = CardStatus()
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrNoCard, err)
+}
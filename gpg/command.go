@@ -0,0 +1,85 @@
+package gpg
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// command is a gpg invocation which logs its argv and exit code at the
+// debug level
+type command struct {
+	*exec.Cmd
+	fn string
+}
+
+// newCommand creates a new gpg command. Every gpg invocation must use this
+// to make sure the configured binary is used and the environment, e.g.
+// GNUPGHOME, is passed on to gpg.
+func newCommand(fn string, args ...string) *command {
+	return newCommandContext(context.Background(), fn, args...)
+}
+
+// newCommandContext is newCommand for a gpg invocation which is killed once
+// ctx is done, see exec.CommandContext
+func newCommandContext(ctx context.Context, fn string, args ...string) *command {
+	cmd := &command{
+		Cmd: exec.CommandContext(ctx, GPGBin, args...),
+		fn:  fn,
+	}
+	log.Debugf("gpg.%s: %s", fn, strings.Join(log.RedactArgs(cmd.Args), " "))
+	return cmd
+}
+
+// Run runs the command, see exec.Cmd.Run
+func (c *command) Run() error {
+	err := c.Cmd.Run()
+	c.logExit(err)
+	return err
+}
+
+// Output runs the command and returns its stdout, see exec.Cmd.Output
+func (c *command) Output() ([]byte, error) {
+	out, err := c.Cmd.Output()
+	c.logExit(err)
+	return out, err
+}
+
+// CombinedOutput runs the command and returns its stdout and stderr, see
+// exec.Cmd.CombinedOutput
+func (c *command) CombinedOutput() ([]byte, error) {
+	out, err := c.Cmd.CombinedOutput()
+	c.logExit(err)
+	return out, err
+}
+
+// Wait waits for a started command to exit, see exec.Cmd.Wait
+func (c *command) Wait() error {
+	err := c.Cmd.Wait()
+	c.logExit(err)
+	return err
+}
+
+// logExit logs how the command exited
+func (c *command) logExit(err error) {
+	if c.ProcessState == nil {
+		log.Debugf("gpg.%s: failed to run: %s", c.fn, err)
+		return
+	}
+	log.Debugf("gpg.%s: exit code %d", c.fn, exitCode(c.ProcessState))
+}
+
+// exitCode returns the exit code of a process, or -1 if it was killed
+func exitCode(ps *os.ProcessState) int {
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok {
+		return ws.ExitStatus()
+	}
+	if ps.Success() {
+		return 0
+	}
+	return 1
+}
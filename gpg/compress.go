@@ -0,0 +1,61 @@
+package gpg
+
+import (
+	"bytes"
+	"compress/flate"
+	"strings"
+)
+
+const (
+	// sampleChunk is the size of the chunks of content compressed to tell if
+	// it compresses. Larger content is sampled at the start, middle and end.
+	sampleChunk = 1024
+	// minSample is the size below which content is too small to tell
+	minSample = 64
+	// maxRatio is the ratio of compressed to uncompressed size of the sample
+	// above which content is considered incompressible
+	maxRatio = 0.9
+)
+
+// Incompressible returns true if the content doesn't compress, most likely
+// because it's already compressed, e.g. a zip file or an image. It's an
+// estimate from compressing a sample of at most 3 KiB.
+func Incompressible(content []byte) bool {
+	if len(content) < minSample {
+		return false
+	}
+	sample := content
+	if len(content) > 3*sampleChunk {
+		mid := len(content)/2 - sampleChunk/2
+		sample = make([]byte, 0, 3*sampleChunk)
+		sample = append(sample, content[:sampleChunk]...)
+		sample = append(sample, content[mid:mid+sampleChunk]...)
+		sample = append(sample, content[len(content)-sampleChunk:]...)
+	}
+
+	buf := &bytes.Buffer{}
+	w, err := flate.NewWriter(buf, flate.BestSpeed)
+	if err != nil {
+		return false
+	}
+	if _, err := w.Write(sample); err != nil {
+		return false
+	}
+	if err := w.Close(); err != nil {
+		return false
+	}
+	return float64(buf.Len()) > maxRatio*float64(len(sample))
+}
+
+// forContent returns the options to encrypt the given content with.
+// Compression is disabled for incompressible content if
+// AutoDisableCompression is set.
+func (o EncryptOpts) forContent(content []byte) EncryptOpts {
+	if !o.AutoDisableCompression || o.CompressAlgo == "" || strings.EqualFold(o.CompressAlgo, "none") {
+		return o
+	}
+	if Incompressible(content) {
+		o.CompressAlgo = "none"
+	}
+	return o
+}
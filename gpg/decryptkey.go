@@ -0,0 +1,130 @@
+package gpg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// NoSecretKeyError is returned by DecryptWithKey if the secret key isn't in
+// the keyring
+type NoSecretKeyError struct {
+	ID string
+}
+
+// Error implements error
+func (e *NoSecretKeyError) Error() string {
+	return fmt.Sprintf("no secret key for %s in the keyring", e.ID)
+}
+
+// DecryptWithKey decrypts the given file with the given secret key only, to
+// test if it can be read by the holder of that key. It fails with a
+// NoSecretKeyError if the key isn't in the keyring and never retrieves keys
+// or falls back to another secret key: gpg is only handed the session keys
+// encrypted for this key and those of hidden recipients.
+func DecryptWithKey(path, fpr string) ([]byte, error) {
+	kl, err := ListPrivateKeys()
+	if err != nil {
+		return nil, err
+	}
+	key, err := kl.FindKey(fpr)
+	if err != nil {
+		return nil, &NoSecretKeyError{ID: fpr}
+	}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	msg, kept, ok := keepRecipients(buf, func(id string) bool {
+		return id == AnonymousKeyID || key.HasID(id)
+	})
+	if ok && kept < 1 {
+		return nil, fmt.Errorf("%s is not encrypted for %s", path, key.Fingerprint)
+	}
+	if err := EnsureAgent(); err != nil {
+		return nil, err
+	}
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = pr.Close()
+	}()
+	status := make(chan string, 1)
+	go func() {
+		status <- decryptionKey(pr)
+	}()
+
+	// ExtraFiles[0] becomes fd 3 in the child
+	args := append(GPGArgs, "--batch", "--no-auto-key-retrieve", "--status-fd", "3",
+		"--default-key", key.Fingerprint, "--try-secret-key", key.Fingerprint, "--decrypt")
+	cmd := newCommand("DecryptWithKey", args...)
+	cmd.ExtraFiles = []*os.File{pw}
+	cmd.Stdin = bytes.NewReader(msg)
+	out, err := cmd.Output()
+	_ = pw.Close()
+	used := <-status
+	if err != nil {
+		return nil, err
+	}
+	// armored files are passed on as they are, so gpg may pick another key
+	if used != "" && used != key.Fingerprint {
+		return nil, fmt.Errorf("gpg decrypted %s with %s instead of %s", path, used, key.Fingerprint)
+	}
+	return out, nil
+}
+
+// keepRecipients drops the public key encrypted session key packets of a
+// binary OpenPGP message which aren't for one of the keys to keep. It
+// returns the message, the number of session keys kept and whether the
+// packets could be parsed. Otherwise, e.g. for ASCII armored messages, the
+// message is returned as it is.
+func keepRecipients(msg []byte, keep func(id string) bool) ([]byte, int, bool) {
+	out := make([]byte, 0, len(msg))
+	kept := 0
+	rest := msg
+	for len(rest) > 0 {
+		r := bytes.NewReader(rest)
+		tag, blen, err := readPacketHeader(r)
+		// the session keys come first, the encrypted data follows
+		if tag > 0 && tag != packetPKESK {
+			break
+		}
+		if err != nil {
+			return msg, 0, false
+		}
+		hlen := len(rest) - r.Len()
+		// only v3 packets list the key ID right after the version
+		if len(rest) < hlen+blen || blen < 9 || rest[hlen] != 3 {
+			return msg, 0, false
+		}
+		if keep(fmt.Sprintf("%X", rest[hlen+1:hlen+9])) {
+			out = append(out, rest[:hlen+blen]...)
+			kept++
+		}
+		rest = rest[hlen+blen:]
+	}
+	return append(out, rest...), kept, true
+}
+
+// decryptionKey returns the fingerprint of the primary key gpg decrypted
+// with from the DECRYPTION_KEY line of its status output, if any
+func decryptionKey(r io.Reader) string {
+	var fpr string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 3 && fields[0] == "[GNUPG:]" && fields[1] == "DECRYPTION_KEY" {
+			fpr = fields[3]
+		}
+	}
+	// drain the output, so gpg doesn't block on an overlong line
+	_, _ = io.Copy(ioutil.Discard, r)
+	return fpr
+}
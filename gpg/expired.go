@@ -0,0 +1,71 @@
+package gpg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExpiredKeysError is returned when encrypting for recipients whose key has
+// expired, unless EncryptOpts.AllowExpired is set
+type ExpiredKeysError struct {
+	Keys KeyList
+}
+
+// Error implements error
+func (e *ExpiredKeysError) Error() string {
+	lines := make([]string, 0, len(e.Keys))
+	for _, k := range e.Keys {
+		lines = append(lines, fmt.Sprintf("%s (expired on %s)", k.OneLine(), k.ExpirationDate.Format("2006-01-02")))
+	}
+	return "the keys of these recipients have expired:\n - " + strings.Join(lines, "\n - ")
+}
+
+// ExpiredKeys returns the keys of the given recipients that have expired.
+// Groups are expanded, recipients without a public key are skipped.
+func ExpiredKeys(recipients []string) KeyList {
+	groups, _ := ListGroups()
+	expired := make(KeyList, 0, 1)
+	for _, r := range ExpandGroups(recipients, groups) {
+		kl, err := ListPublicKeys(r)
+		if err != nil || len(kl) < 1 {
+			continue
+		}
+		if kl[0].IsExpired() {
+			expired = append(expired, kl[0])
+		}
+	}
+	return expired
+}
+
+// encryptError replaces the error of a failed encryption with an
+// ExpiredKeysError if any of the recipients has expired
+func encryptError(err error, recipients []string) error {
+	if expired := ExpiredKeys(recipients); len(expired) > 0 {
+		return &ExpiredKeysError{Keys: expired}
+	}
+	return err
+}
+
+// allowExpired returns the options to encrypt for the given recipients even
+// if some of their keys have expired. gpg refuses to encrypt for expired
+// keys, so it's told the time is shortly before the first of them expired.
+// Keys created after that are used nevertheless, see args.
+func (o EncryptOpts) allowExpired(recipients []string) EncryptOpts {
+	if !o.AllowExpired {
+		return o
+	}
+	expired := ExpiredKeys(recipients)
+	if len(expired) < 1 {
+		return o
+	}
+
+	t := expired[0].ExpirationDate
+	for _, k := range expired[1:] {
+		if k.ExpirationDate.Before(t) {
+			t = k.ExpirationDate
+		}
+	}
+	o.fakedTime = t.Add(-time.Minute)
+	return o
+}
@@ -33,8 +33,6 @@ var (
 	// GPGArgs contains the default GPG args for non-interactive use. Note: Do not use '--batch'
 	// as this will disable (necessary) passphrase questions!
 	GPGArgs = []string{"--quiet", "--yes", "--compress-algo=none", "--no-encrypt-to", "--no-auto-check-trustdb"}
-	// Debug prints all the commands executed
-	Debug = false
 	// GPGBin is the name and possibly location of the gpg binary
 	GPGBin = "gpg"
 )
@@ -156,16 +154,27 @@ func ParseColons(reader io.Reader) KeyList {
 				KeyType:        fields[0],
 				Validity:       validity,
 				KeyLength:      parseInt(fields[2]),
+				PubKeyAlgo:     parseInt(fields[3]),
 				CreationDate:   parseTS(fields[5]),
 				ExpirationDate: parseTS(fields[6]),
 				Ownertrust:     fields[8],
 				Identities:     make(map[string]Identity, 1),
 				SubKeys:        make(map[string]struct{}, 1),
 			}
+			if len(fields) > 16 {
+				cur.Curve = fields[16]
+			}
+			cur.CardSerial = tokenSerial(fields)
 		case "sub":
 			fallthrough
 		case "ssb":
 			cur.SubKeys[fields[4]] = struct{}{}
+			if sk, ok := encryptionSubKey(fields); ok {
+				cur.EncryptionSubKeys = append(cur.EncryptionSubKeys, sk)
+			}
+			if sn := tokenSerial(fields); sn != "" && cur.CardSerial == "" {
+				cur.CardSerial = sn
+			}
 		case "fpr":
 			if cur.Fingerprint == "" {
 				cur.Fingerprint = fields[9]
@@ -197,6 +206,47 @@ func ParseColons(reader io.Reader) KeyList {
 	return kl
 }
 
+// encryptionSubKey returns the sub or ssb record as a subkey if it can be used
+// to encrypt, i.e. it has the encrypt capability and isn't revoked, expired,
+// invalid or disabled
+func encryptionSubKey(fields []string) (SubKey, bool) {
+	if len(fields) < 12 || !strings.Contains(fields[11], "e") {
+		return SubKey{}, false
+	}
+	switch fields[1] {
+	case "r", "e", "i", "d", "n":
+		return SubKey{}, false
+	}
+	sk := SubKey{
+		ID:             fields[4],
+		KeyLength:      parseInt(fields[2]),
+		PubKeyAlgo:     parseInt(fields[3]),
+		ExpirationDate: parseTS(fields[6]),
+	}
+	if len(fields) > 16 {
+		sk.Curve = fields[16]
+	}
+	if !sk.ExpirationDate.IsZero() && sk.ExpirationDate.Before(time.Now()) {
+		return SubKey{}, false
+	}
+	return sk, true
+}
+
+// tokenSerial returns the serial number of the hardware token holding the
+// secret part of a sec or ssb record. For keys on disk the field is empty or
+// "+", for stubs of keys that aren't available at all it's "#".
+func tokenSerial(fields []string) string {
+	if len(fields) < 15 {
+		return ""
+	}
+	switch sn := fields[14]; sn {
+	case "", "+", "#":
+		return ""
+	default:
+		return sn
+	}
+}
+
 // parseTS parses the passed string as an Epoch int and returns
 // the time struct or the zero time struct
 func parseTS(str string) time.Time {
@@ -223,20 +273,104 @@ func parseInt(str string) int {
 
 // Key is a GPG key (public or secret)
 type Key struct {
-	KeyType        string
-	KeyLength      int
-	Validity       string
-	CreationDate   time.Time
-	ExpirationDate time.Time
-	Ownertrust     string
-	Fingerprint    string
-	Identities     map[string]Identity
-	SubKeys        map[string]struct{}
+	KeyType        string              `json:"type"`
+	KeyLength      int                 `json:"length"`
+	PubKeyAlgo     int                 `json:"algo"`
+	Curve          string              `json:"curve,omitempty"`
+	Validity       string              `json:"validity"`
+	CreationDate   time.Time           `json:"created"`
+	ExpirationDate time.Time           `json:"expires"`
+	Ownertrust     string              `json:"ownertrust"`
+	Fingerprint    string              `json:"fingerprint"`
+	Identities     map[string]Identity `json:"identities"`
+	SubKeys        map[string]struct{} `json:"subkeys"`
+	// CardSerial is the serial number of the hardware token (e.g. a YubiKey)
+	// holding the secret key or one of its subkeys
+	CardSerial string `json:"card,omitempty"`
+	// EncryptionSubKeys are the usable subkeys gpg may encrypt to
+	EncryptionSubKeys []SubKey `json:"encryption_subkeys,omitempty"`
+}
+
+// SubKey is a subkey of a GPG key
+type SubKey struct {
+	ID             string    `json:"id"`
+	KeyLength      int       `json:"length"`
+	PubKeyAlgo     int       `json:"algo"`
+	Curve          string    `json:"curve,omitempty"`
+	ExpirationDate time.Time `json:"expires"`
+}
+
+// IsExpired returns true if the key has an expiration date in the past
+func (k Key) IsExpired() bool {
+	return !k.ExpirationDate.IsZero() && k.ExpirationDate.Before(time.Now())
+}
+
+// HasID returns true if the given ID is the fingerprint, the long or short key
+// ID of the key or the key ID of one of its subkeys, ignoring a 0x prefix.
+// Any other suffix of the fingerprint isn't an ID of the key.
+func (k Key) HasID(id string) bool {
+	id = strings.ToUpper(strings.TrimPrefix(id, "0x"))
+	if id == "" {
+		return false
+	}
+	if isKeyID(k.Fingerprint, id) {
+		return true
+	}
+	for sk := range k.SubKeys {
+		if isKeyID(sk, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeyID returns true if id is the given fingerprint or key ID, or the long
+// or short key ID derived from it
+func isKeyID(fpr, id string) bool {
+	switch len(id) {
+	case len(fpr), 16, 8:
+		return strings.HasSuffix(fpr, id)
+	}
+	return false
+}
+
+// OnHardwareToken returns true if the secret key, or one of its subkeys, is
+// stored on a hardware token. Using it requires the token to be present.
+func (k Key) OnHardwareToken() bool {
+	return k.CardSerial != ""
+}
+
+// validityNames are the names of gpg's validity flags
+var validityNames = map[string]string{
+	"o": "unknown",
+	"-": "unknown",
+	"q": "unknown",
+	"i": "invalid",
+	"d": "disabled",
+	"r": "revoked",
+	"e": "expired",
+	"n": "never",
+	"m": "marginal",
+	"f": "full",
+	"u": "ultimate",
+}
+
+// ValidityName returns the name of the key's validity, e.g. full
+func (k Key) ValidityName() string {
+	if name, found := validityNames[k.Validity]; found {
+		return name
+	}
+	return "unknown"
+}
+
+// IsRevoked returns true if the key has been revoked
+func (k Key) IsRevoked() bool {
+	return k.Validity == "r"
 }
 
 // IsUseable returns true if GPG would assume this key is useable for encryption
 func (k Key) IsUseable() bool {
-	if !k.ExpirationDate.IsZero() && k.ExpirationDate.Before(time.Now()) {
+	if k.IsExpired() {
 		return false
 	}
 	switch k.Validity {
@@ -263,7 +397,7 @@ func (k Key) String() string {
 	}
 	out += "\n      Key fingerprint = " + k.Fingerprint
 	for _, id := range k.Identities {
-		out += fmt.Sprintf("\n" + id.String())
+		out += "\n" + id.String()
 	}
 	return out
 }
@@ -281,9 +415,9 @@ func (k Key) OneLine() string {
 
 // Identity is a GPG identity, one key can have many IDs
 type Identity struct {
-	Name    string
-	Comment string
-	Email   string
+	Name    string `json:"name"`
+	Comment string `json:"comment,omitempty"`
+	Email   string `json:"email"`
 }
 
 // ID returns the GPG ID format
@@ -306,10 +440,7 @@ func (i Identity) String() string {
 func listKeys(typ string, search ...string) (KeyList, error) {
 	args := []string{"--with-colons", "--with-fingerprint", "--fixed-list-mode", "--list-" + typ + "-keys"}
 	args = append(args, search...)
-	cmd := exec.Command(GPGBin, args...)
-	if Debug {
-		fmt.Printf("gpg.listKeys: %s %+v\n", cmd.Path, cmd.Args)
-	}
+	cmd := newCommand("listKeys", args...)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		if bytes.Contains(out, []byte("secret key not available")) {
@@ -331,16 +462,77 @@ func ListPrivateKeys(search ...string) (KeyList, error) {
 	return listKeys("secret", search...)
 }
 
+// KeysInFile returns the keys in the given file without importing them. It
+// needs gpg 2.1.14 or newer.
+func KeysInFile(filename string) (KeyList, error) {
+	args := []string{"--with-colons", "--with-fingerprint", "--fixed-list-mode", "--import-options", "show-only", "--import", filename}
+	cmd := newCommand("KeysInFile", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		return KeyList{}, err
+	}
+
+	return ParseColons(bytes.NewBuffer(out)), nil
+}
+
+// ListGroups returns the recipient groups defined in the gpg configuration,
+// e.g. by `group team = 0xDEADBEEF 0xFEEDBEEF` in gpg.conf
+func ListGroups() (map[string][]string, error) {
+	args := []string{"--with-colons", "--list-config", "group"}
+	cmd := newCommand("ListGroups", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		return map[string][]string{}, err
+	}
+
+	return parseGroups(bytes.NewBuffer(out)), nil
+}
+
+// parseGroups parses the `--with-colons --list-config group` output of GPG,
+// e.g. cfg:group:team:0xDEADBEEF;0xFEEDBEEF
+func parseGroups(reader io.Reader) map[string][]string {
+	groups := make(map[string][]string, 5)
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), ":")
+		if len(fields) < 4 || fields[0] != "cfg" || fields[1] != "group" {
+			continue
+		}
+		members := make([]string, 0, 5)
+		for _, m := range strings.Split(fields[3], ";") {
+			if m != "" {
+				members = append(members, m)
+			}
+		}
+		groups[fields[2]] = members
+	}
+
+	return groups
+}
+
+// ExpandGroups replaces the names of groups in ids by the members of the
+// group, like gpg does when encrypting for a group
+func ExpandGroups(ids []string, groups map[string][]string) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if members, found := groups[id]; found {
+			out = append(out, members...)
+			continue
+		}
+		out = append(out, id)
+	}
+	return out
+}
+
 // GetRecipients returns a list of recipient IDs for a given file
 func GetRecipients(file string) ([]string, error) {
-	_ = os.Setenv("LANGUAGE", "C")
 	recp := make([]string, 0, 5)
 
 	args := []string{"--batch", "--list-only", "--no-default-keyring", "--secret-keyring", "/dev/null", file}
-	cmd := exec.Command(GPGBin, args...)
-	if Debug {
-		fmt.Printf("gpg.GetRecipients: %s %+v\n", cmd.Path, cmd.Args)
-	}
+	cmd := newCommand("GetRecipients", args...)
+	// we need to parse the output, so make sure it's not localized
+	cmd.Env = append(os.Environ(), "LANGUAGE=C")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return []string{}, err
@@ -366,56 +558,200 @@ func GetRecipients(file string) ([]string, error) {
 	return recp, nil
 }
 
-// Encrypt will encrypt the given content for the recipients. If alwaysTrust is true
-// the trust-model will be set to always as to avoid (annoying) "unuseable public key"
-// errors when encrypting.
-func Encrypt(path string, content []byte, recipients []string, alwaysTrust bool) error {
-	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
-		return err
+// EncryptOpts are the options used when encrypting
+type EncryptOpts struct {
+	// AlwaysTrust sets the trust-model to always as to avoid (annoying)
+	// "unuseable public key" errors when encrypting
+	AlwaysTrust bool
+	// CipherAlgo, DigestAlgo and CompressAlgo override the gpg defaults
+	// if set. They are validated against the supported algorithms.
+	CipherAlgo   string
+	DigestAlgo   string
+	CompressAlgo string
+	// AutoDisableCompression encrypts incompressible content without
+	// compression, see Incompressible
+	AutoDisableCompression bool
+	// AllowExpired allows encrypting for recipients whose key has expired
+	AllowExpired bool
+	// fakedTime is the system time gpg is told to allow expired keys
+	fakedTime time.Time
+}
+
+// Validate checks that all configured algorithms are supported by gpg
+func (o EncryptOpts) Validate() error {
+	if o.CipherAlgo == "" && o.DigestAlgo == "" && o.CompressAlgo == "" {
+		return nil
 	}
+	algos, err := SupportedAlgorithms()
+	if err != nil {
+		return fmt.Errorf("Failed to list supported algorithms: %s", err)
+	}
+	for _, c := range []struct {
+		kind      string
+		algo      string
+		supported []string
+	}{
+		{"cipher", o.CipherAlgo, algos.Cipher},
+		{"digest", o.DigestAlgo, algos.Hash},
+		// gpg calls it "Uncompressed" in the version output but expects "none"
+		{"compression", o.CompressAlgo, append(algos.Compression, "none")},
+	} {
+		if c.algo == "" {
+			continue
+		}
+		if !containsFold(c.supported, c.algo) {
+			return fmt.Errorf("Unsupported %s algorithm %s. Supported are: %s", c.kind, c.algo, strings.Join(c.supported, ", "))
+		}
+	}
+	return nil
+}
 
-	args := append(GPGArgs, "--encrypt", "--output", path)
-	if alwaysTrust {
+// args returns the gpg arguments for these options
+func (o EncryptOpts) args() []string {
+	args := make([]string, 0, 4)
+	if o.AlwaysTrust {
 		// changing the trustmodel is possibly dangerous. A user should always
 		// explicitly opt-in to do this
 		args = append(args, "--trust-model=always")
 	}
-	for _, r := range recipients {
-		args = append(args, "--recipient", r)
+	if o.CipherAlgo != "" {
+		args = append(args, "--cipher-algo="+o.CipherAlgo)
 	}
+	if o.DigestAlgo != "" {
+		args = append(args, "--digest-algo="+o.DigestAlgo)
+	}
+	if o.CompressAlgo != "" {
+		// this overrides the --compress-algo in GPGArgs
+		args = append(args, "--compress-algo="+o.CompressAlgo)
+	}
+	if !o.fakedTime.IsZero() {
+		// keys created after the faked time must still be usable
+		args = append(args, "--faked-system-time="+strconv.FormatInt(o.fakedTime.Unix(), 10), "--ignore-valid-from", "--ignore-time-conflict")
+	}
+	return args
+}
 
-	cmd := exec.Command(GPGBin, args...)
-	if Debug {
-		fmt.Printf("gpg.Encrypt: %s %+v\n", cmd.Path, cmd.Args)
+// Encrypt will encrypt the given content for the recipients. The options are
+// validated before invoking gpg.
+func Encrypt(path string, content []byte, recipients []string, opts EncryptOpts) error {
+	if err := opts.Validate(); err != nil {
+		return err
 	}
+	opts = opts.allowExpired(recipients).forContent(content)
+
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return err
+	}
+
+	cmd := newCommand("Encrypt", encryptArgs(path, recipients, opts)...)
 	cmd.Stdin = bytes.NewReader(content)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
+		return encryptError(err, recipients)
+	}
+
+	return nil
+}
+
+// EncryptTo encrypts the content for the given recipients and writes the
+// ciphertext to w instead of a file
+func EncryptTo(w io.Writer, content []byte, recipients []string, opts EncryptOpts) error {
+	if err := opts.Validate(); err != nil {
 		return err
 	}
+	opts = opts.allowExpired(recipients).forContent(content)
+
+	cmd := newCommand("EncryptTo", encryptArgs("-", recipients, opts)...)
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return encryptError(err, recipients)
+	}
+	return nil
+}
+
+// encryptArgs returns the gpg arguments to encrypt to the given path
+func encryptArgs(path string, recipients []string, opts EncryptOpts) []string {
+	args := make([]string, 0, len(GPGArgs)+3+len(recipients)*2)
+	args = append(args, GPGArgs...)
+	args = append(args, "--encrypt", "--output", path)
+	args = append(args, opts.args()...)
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	return args
+}
 
+// DecryptOpts are the options of DecryptWith and DecryptLoopback
+type DecryptOpts struct {
+	// TryAllSecrets makes gpg try all secret keys instead of only those of
+	// the listed recipients, for secrets encrypted with hidden recipients,
+	// see HasHiddenRecipients
+	TryAllSecrets bool
+}
+
+// args returns the gpg arguments for these options
+func (o DecryptOpts) args() []string {
+	if o.TryAllSecrets && SupportsTryAllSecrets() {
+		return []string{"--try-all-secrets"}
+	}
 	return nil
 }
 
+// SupportsTryAllSecrets returns true if the gpg binary supports
+// --try-all-secrets, i.e. it is gpg 1.4 or newer
+func SupportsTryAllSecrets() bool {
+	major, minor, err := Version()
+	if err != nil {
+		return false
+	}
+	return major > 1 || (major == 1 && minor >= 4)
+}
+
 // Decrypt will try to decrypt the given file
 func Decrypt(path string) ([]byte, error) {
-	args := append(GPGArgs, "--decrypt", path)
-	cmd := exec.Command(GPGBin, args...)
-	if Debug {
-		fmt.Printf("gpg.Decrypt: %s %+v\n", cmd.Path, cmd.Args)
+	return DecryptWith(path, DecryptOpts{})
+}
+
+// DecryptWith decrypts the given file with the given options
+func DecryptWith(path string, opts DecryptOpts) ([]byte, error) {
+	if err := EnsureAgent(); err != nil {
+		return nil, err
 	}
-	return cmd.Output()
+	args := append(append(GPGArgs, opts.args()...), "--decrypt", path)
+	cmd := newCommand("Decrypt", args...)
+	status, err := withDecryptStatus(cmd)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.Output()
+	return out, decryptError(err, status())
+}
+
+// DecryptTo decrypts the given file and writes the plaintext to w while it's
+// decrypted, instead of buffering it
+func DecryptTo(w io.Writer, path string, opts DecryptOpts) error {
+	if err := EnsureAgent(); err != nil {
+		return err
+	}
+	args := append(append(GPGArgs, opts.args()...), "--decrypt", path)
+	cmd := newCommand("DecryptTo", args...)
+	cmd.Stdout = w
+	status, err := withDecryptStatus(cmd)
+	if err != nil {
+		return err
+	}
+	return decryptError(cmd.Run(), status())
 }
 
 // ExportPublicKey will export the named public key to the location given
 func ExportPublicKey(id, filename string) error {
 	args := append(GPGArgs, "--armor", "--export", id)
-	cmd := exec.Command(GPGBin, args...)
-	if Debug {
-		fmt.Printf("gpg.ExportPublicKey: %s %+v\n", cmd.Path, cmd.Args)
-	}
+	cmd := newCommand("ExportPublicKey", args...)
 	out, err := cmd.Output()
 	if err != nil {
 		return err
@@ -432,13 +768,112 @@ func ImportPublicKey(filename string) error {
 	}
 
 	args := append(GPGArgs, "--import")
-	cmd := exec.Command(GPGBin, args...)
-	if Debug {
-		fmt.Printf("gpg.ImportPublicKey: %s %+v\n", cmd.Path, cmd.Args)
-	}
+	cmd := newCommand("ImportPublicKey", args...)
 	cmd.Stdin = bytes.NewReader(buf)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	return cmd.Run()
 }
+
+// TrustLevel is the ownertrust GPG assigns to a key
+type TrustLevel int
+
+// These are the ownertrust levels as used by gpg --import-ownertrust
+const (
+	TrustUnknown  TrustLevel = 2
+	TrustNever    TrustLevel = 3
+	TrustMarginal TrustLevel = 4
+	TrustFull     TrustLevel = 5
+	TrustUltimate TrustLevel = 6
+)
+
+var trustLevelNames = map[TrustLevel]string{
+	TrustUnknown:  "unknown",
+	TrustNever:    "never",
+	TrustMarginal: "marginal",
+	TrustFull:     "full",
+	TrustUltimate: "ultimate",
+}
+
+// String implement fmt.Stringer
+func (t TrustLevel) String() string {
+	if name, found := trustLevelNames[t]; found {
+		return name
+	}
+	return fmt.Sprintf("TrustLevel(%d)", int(t))
+}
+
+// ParseTrustLevel parses the name of a trust level, i.e. one of unknown,
+// never, marginal, full or ultimate
+func ParseTrustLevel(name string) (TrustLevel, error) {
+	for t, n := range trustLevelNames {
+		if n == strings.ToLower(name) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown trust level: %s", name)
+}
+
+// SetOwnerTrust sets the ownertrust of the key with the given (full)
+// fingerprint
+func SetOwnerTrust(fpr string, level TrustLevel) error {
+	fpr = strings.TrimPrefix(fpr, "0x")
+	if len(fpr) != 40 {
+		return fmt.Errorf("Need a full fingerprint to set the ownertrust: %s", fpr)
+	}
+	if _, found := trustLevelNames[level]; !found {
+		return fmt.Errorf("Invalid trust level: %d", level)
+	}
+
+	args := append(GPGArgs, "--import-ownertrust")
+	cmd := newCommand("SetOwnerTrust", args...)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("%s:%d:\n", strings.ToUpper(fpr), level))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// GenerateKey creates a new key pair in batch mode. If the passphrase is empty
+// the private key will be unprotected. This should only be used for testing.
+func GenerateKey(name, email, passphrase string) error {
+	params := &bytes.Buffer{}
+	if passphrase == "" {
+		_, _ = params.WriteString("%no-protection\n")
+	}
+	_, _ = params.WriteString("Key-Type: RSA\n" +
+		"Key-Length: 2048\n" +
+		"Subkey-Type: RSA\n" +
+		"Subkey-Length: 2048\n" +
+		"Name-Real: " + name + "\n" +
+		"Name-Email: " + email + "\n" +
+		"Expire-Date: 0\n")
+	// the passphrase must be part of the parameter block started by Key-Type
+	if passphrase != "" {
+		_, _ = params.WriteString("Passphrase: " + passphrase + "\n")
+	}
+	_, _ = params.WriteString("%commit\n")
+
+	args := []string{"--batch", "--gen-key"}
+	if passphrase != "" && SupportsLoopback() {
+		// gpg 2.1+ would ask the pinentry to confirm the passphrase
+		args = append([]string{"--pinentry-mode", "loopback"}, args...)
+	}
+	cmd := newCommand("GenerateKey", args...)
+	cmd.Stdin = params
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// containsFold returns true if the haystack contains the needle, ignoring case
+func containsFold(haystack []string, needle string) bool {
+	for _, blade := range haystack {
+		if strings.EqualFold(blade, needle) {
+			return true
+		}
+	}
+	return false
+}
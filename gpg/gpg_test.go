@@ -0,0 +1,339 @@
+package gpg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGroups(t *testing.T) {
+	in := `cfg:version:2.1.18
+cfg:group:ops:ops@example.com
+cfg:group:team:CAFEBABE;0xDEADBEEF
+`
+	assert.Equal(t, map[string][]string{
+		"ops":  {"ops@example.com"},
+		"team": {"CAFEBABE", "0xDEADBEEF"},
+	}, parseGroups(strings.NewReader(in)))
+}
+
+func TestExpandGroups(t *testing.T) {
+	groups := map[string][]string{
+		"team": {"CAFEBABE", "0xDEADBEEF"},
+	}
+	assert.Equal(t, []string{"FEEDBEEF", "CAFEBABE", "0xDEADBEEF"}, ExpandGroups([]string{"FEEDBEEF", "team"}, groups))
+	assert.Equal(t, []string{"team"}, ExpandGroups([]string{"team"}, nil))
+}
+
+func TestParseTrustLevel(t *testing.T) {
+	for in, out := range map[string]TrustLevel{
+		"unknown":  TrustUnknown,
+		"never":    TrustNever,
+		"Marginal": TrustMarginal,
+		"full":     TrustFull,
+		"ultimate": TrustUltimate,
+	} {
+		got, err := ParseTrustLevel(in)
+		assert.NoError(t, err)
+		assert.Equal(t, out, got)
+		assert.Equal(t, strings.ToLower(in), got.String())
+	}
+	_, err := ParseTrustLevel("some")
+	assert.Error(t, err)
+}
+
+func TestParseAlgorithms(t *testing.T) {
+	in := `gpg (GnuPG) 2.1.18
+Home: ~/.gnupg
+Supported algorithms:
+Pubkey: RSA, ELG, DSA, ECDH, ECDSA, EDDSA
+Cipher: IDEA, 3DES, CAST5, BLOWFISH, AES, AES192, AES256, TWOFISH,
+        CAMELLIA128, CAMELLIA192, CAMELLIA256
+Hash: SHA1, RIPEMD160, SHA256, SHA384, SHA512, SHA224
+Compression: Uncompressed, ZIP, ZLIB, BZIP2
+`
+	algos := parseAlgorithms(strings.NewReader(in))
+	assert.Equal(t, []string{"RSA", "ELG", "DSA", "ECDH", "ECDSA", "EDDSA"}, algos.Pubkey)
+	assert.Equal(t, []string{"IDEA", "3DES", "CAST5", "BLOWFISH", "AES", "AES192", "AES256", "TWOFISH", "CAMELLIA128", "CAMELLIA192", "CAMELLIA256"}, algos.Cipher)
+	assert.Equal(t, []string{"SHA1", "RIPEMD160", "SHA256", "SHA384", "SHA512", "SHA224"}, algos.Hash)
+	assert.Equal(t, []string{"Uncompressed", "ZIP", "ZLIB", "BZIP2"}, algos.Compression)
+}
+
+func TestEncryptArgs(t *testing.T) {
+	args := encryptArgs("/tmp/foo.gpg", []string{"DEADBEEF"}, EncryptOpts{
+		AlwaysTrust:  true,
+		CipherAlgo:   "AES256",
+		DigestAlgo:   "SHA256",
+		CompressAlgo: "ZLIB",
+	})
+	for _, arg := range []string{
+		"--trust-model=always",
+		"--cipher-algo=AES256",
+		"--digest-algo=SHA256",
+		"--compress-algo=ZLIB",
+	} {
+		assert.Contains(t, args, arg)
+	}
+	assert.Equal(t, []string{"--recipient", "DEADBEEF"}, args[len(args)-2:])
+
+	args = encryptArgs("/tmp/foo.gpg", nil, EncryptOpts{})
+	assert.Equal(t, append(GPGArgs, "--encrypt", "--output", "/tmp/foo.gpg"), args)
+}
+
+func TestAutoDisableCompression(t *testing.T) {
+	text := &bytes.Buffer{}
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(text, "user: gopher%d\nurl: https://example.com/login?id=%d\n", i, i*7)
+	}
+	random := make([]byte, 8192)
+	_, _ = rand.New(rand.NewSource(42)).Read(random)
+	gz := &bytes.Buffer{}
+	zw := gzip.NewWriter(gz)
+	_, _ = zw.Write(text.Bytes())
+	_, _ = zw.Write(random)
+	assert.NoError(t, zw.Close())
+
+	assert.False(t, Incompressible(text.Bytes()))
+	assert.False(t, Incompressible([]byte("short")))
+	assert.True(t, Incompressible(random))
+	assert.True(t, Incompressible(random[:100]))
+	assert.True(t, Incompressible(gz.Bytes()))
+
+	opts := EncryptOpts{CompressAlgo: "ZLIB", AutoDisableCompression: true}
+	assert.Contains(t, opts.forContent(gz.Bytes()).args(), "--compress-algo=none")
+	assert.Contains(t, opts.forContent(text.Bytes()).args(), "--compress-algo=ZLIB")
+	// compression stays disabled by default and on if not asked for
+	assert.Equal(t, "", EncryptOpts{AutoDisableCompression: true}.forContent(random).CompressAlgo)
+	assert.Equal(t, "ZLIB", EncryptOpts{CompressAlgo: "ZLIB"}.forContent(random).CompressAlgo)
+}
+
+func TestParseVersion(t *testing.T) {
+	for in, out := range map[string][]int{
+		"gpg (GnuPG) 2.1.18\nlibgcrypt 1.7.6\n": {2, 1},
+		"gpg (GnuPG) 1.4.21\n":                  {1, 4},
+		"gpg (GnuPG/MacGPG2) 2.0.30":            {2, 0},
+	} {
+		major, minor, err := parseVersion(strings.NewReader(in))
+		assert.NoError(t, err)
+		assert.Equal(t, out, []int{major, minor})
+	}
+	_, _, err := parseVersion(strings.NewReader("foo"))
+	assert.Error(t, err)
+}
+
+func TestCompareKeys(t *testing.T) {
+	before := Key{
+		Validity:       "u",
+		ExpirationDate: time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC),
+		SubKeys:        map[string]struct{}{"AAAA": {}},
+	}
+
+	_, changed := compareKeys("foo", before, before)
+	assert.False(t, changed)
+
+	after := before
+	after.ExpirationDate = time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	after.SubKeys = map[string]struct{}{"AAAA": {}, "BBBB": {}}
+	c, changed := compareKeys("foo", before, after)
+	assert.True(t, changed)
+	assert.True(t, c.ExpiryChanged())
+	assert.False(t, c.Revoked)
+	assert.Equal(t, []string{"BBBB"}, c.NewSubKeys)
+
+	after = before
+	after.Validity = "r"
+	c, changed = compareKeys("foo", before, after)
+	assert.True(t, changed)
+	assert.True(t, c.Revoked)
+	assert.False(t, c.ExpiryChanged())
+}
+
+func TestParseValidSig(t *testing.T) {
+	in := `[GNUPG:] NEWSIG
+[GNUPG:] GOODSIG 764391A906BC40E6 gopass test <test@gopass.pw>
+[GNUPG:] VALIDSIG D6729E9CD3A497B5BB03B962764391A906BC40E6 2017-05-12 1494583889 0 4 0 1 8 00 83DED95142AAA16DB8D65E2B0B9CD7524E158C3E
+[GNUPG:] TRUST_ULTIMATE 0 pgp
+`
+	assert.Equal(t, "83DED95142AAA16DB8D65E2B0B9CD7524E158C3E", parseValidSig(strings.NewReader(in)))
+	assert.Equal(t, "", parseValidSig(strings.NewReader("[GNUPG:] BADSIG 764391A906BC40E6 gopass test\n")))
+}
+
+func TestParsePacketRecipients(t *testing.T) {
+	in := `# off=0 ctb=85 tag=1 hlen=3 plen=268
+:pubkey enc packet: version 3, algo 1, keyid 3C19E1A7B8E4B40A
+	data: [2048 bits]
+# off=271 ctb=84 tag=1 hlen=2 plen=94
+:pubkey enc packet: version 3, algo 18, keyid 0000000000000000
+	data: [263 bits]
+	data: [392 bits]
+# off=367 ctb=d2 tag=18 hlen=2 plen=60 new-ctb
+:encrypted data packet:
+	length: 60
+	mdc_method: 2
+`
+	ids, err := RecipientsOfStream(strings.NewReader(in))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3C19E1A7B8E4B40A", AnonymousKeyID}, ids)
+	ids, err = RecipientsOfStream(strings.NewReader(":symkey enc packet: version 4, cipher 9, s2k 3, hash 2\n"))
+	assert.NoError(t, err)
+	assert.Len(t, ids, 0)
+
+	// v6 packets list the fingerprint of the key, or nothing if it's hidden
+	in = `# off=0 ctb=c1 tag=1 hlen=2 plen=94 new-ctb
+:pubkey enc packet: version 6, algo 25, fpr 5a1c8e3f9b2d4e6a70c1b3d56f3b2c1a9e8d7f60bb0e4c21d7a5f3e9c8b61d42
+	data: [263 bits]
+# off=96 ctb=c1 tag=1 hlen=2 plen=62 new-ctb
+:pubkey enc packet: version 6, algo 25, anonymous
+	data: [263 bits]
+`
+	ids, err = RecipientsOfStream(strings.NewReader(in))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"5A1C8E3F9B2D4E6A70C1B3D56F3B2C1A9E8D7F60BB0E4C21D7A5F3E9C8B61D42", AnonymousKeyID}, ids)
+
+	_, err = RecipientsOfStream(strings.NewReader(":pubkey enc packet: version 3, algo 1\n"))
+	assert.Error(t, err)
+}
+
+func TestScanPacketsOffsets(t *testing.T) {
+	in := `# off=0 ctb=85 tag=1 hlen=3 plen=396
+:pubkey enc packet: version 3, algo 1, keyid B955CF5D8CA60A64
+	data: [3071 bits]
+# off=399 ctb=d2 tag=18 hlen=2 plen=68 new-ctb
+:encrypted data packet:
+	length: 68
+	mdc_method: 2
+# off=469 ctb=85 tag=1 hlen=3 plen=396
+:pubkey enc packet: version 3, algo 1, keyid 3C19E1A7B8E4B40A
+	data: [3072 bits]
+:pubkey enc packet: version 3, algo 1, keyid 0000000000000000
+`
+	offs := []int64{}
+	ids := []string{}
+	assert.NoError(t, scanPackets(strings.NewReader(in), func(off int64, id string) {
+		offs = append(offs, off)
+		ids = append(ids, id)
+	}))
+	assert.Equal(t, []int64{0, 469, 469}, offs)
+	assert.Equal(t, []string{"B955CF5D8CA60A64", "3C19E1A7B8E4B40A", AnonymousKeyID}, ids)
+}
+
+func TestParseColonsHardwareToken(t *testing.T) {
+	// the primary key is an offline stub, the subkeys live on a YubiKey
+	in := `sec:u:4096:1:6F3B2C1A9E8D7F60:1500000000:::u:::scESC:::#:::23::0:
+fpr:::::::::5A1C8E3F9B2D4E6A70C1B3D56F3B2C1A9E8D7F60:
+grp:::::::::3F1B5C7D9E2A4B6C8D0E1F2A3B4C5D6E7F8A9B0C:
+uid:u::::1500000000::0B1C2D3E4F5A6B7C8D9E0F1A2B3C4D5E6F7A8B9C::Card User <card@example.com>::::::::::0:
+ssb:u:4096:1:1A2B3C4D5E6F7081:1500000000::::::s:::D2760001240102010006054321000000:::23:
+fpr:::::::::0C1D2E3F4A5B6C7D8E9F0A1B1A2B3C4D5E6F7081:
+ssb:u:4096:1:2B3C4D5E6F708192:1500000000::::::e:::D2760001240102010006054321000000:::23:
+fpr:::::::::1D2E3F4A5B6C7D8E9F0A1B2C2B3C4D5E6F708192:
+sec:u:2048:1:3C19E1A7B8E4B40A:1500000000:::u:::scESC:::+:::23::0:
+fpr:::::::::83DED95142AAA16DB8D65E2B3C19E1A7B8E4B40A:
+uid:u::::1500000000::1C2D3E4F5A6B7C8D9E0F1A2B3C4D5E6F7A8B9C0D::Disk User <disk@example.com>::::::::::0:
+ssb:u:2048:1:4D5E6F708192A3B4:1500000000::::::e:::+:::23:
+fpr:::::::::2E3F4A5B6C7D8E9F0A1B2C3D4D5E6F708192A3B4:
+`
+	kl := ParseColons(strings.NewReader(in))
+	assert.Len(t, kl, 2)
+	assert.True(t, kl[0].OnHardwareToken())
+	assert.Equal(t, "D2760001240102010006054321000000", kl[0].CardSerial)
+	assert.False(t, kl[1].OnHardwareToken())
+
+	kl.SortBy(ByHardwareToken)
+	assert.Equal(t, "5A1C8E3F9B2D4E6A70C1B3D56F3B2C1A9E8D7F60", kl[0].Fingerprint)
+	kl[0], kl[1] = kl[1], kl[0]
+	kl.SortBy(ByHardwareToken)
+	assert.Equal(t, "5A1C8E3F9B2D4E6A70C1B3D56F3B2C1A9E8D7F60", kl[0].Fingerprint)
+}
+
+func TestHiddenRecipients(t *testing.T) {
+	pkesk := func(keyID byte) []byte {
+		body := append([]byte{3}, bytes.Repeat([]byte{keyID}, 8)...)
+		return append(body, 1, 0xAA, 0xBB)
+	}
+	for _, tc := range []struct {
+		name   string
+		in     []byte
+		hidden bool
+	}{
+		// old format, one byte length
+		{"listed", append([]byte{0x84, 12}, pkesk(0x42)...), false},
+		{"hidden", append([]byte{0x84, 12}, pkesk(0)...), true},
+		// new format, followed by encrypted data of partial length
+		{"hidden second", append(append(append([]byte{0xc1, 12}, pkesk(0x42)...), append([]byte{0xc1, 12}, pkesk(0)...)...), 0xd2, 0xe0), true},
+		{"listed before data", append(append([]byte{0xc1, 12}, pkesk(0x42)...), 0xd2, 0xe0), false},
+		// symmetric key encrypted session key packets are skipped
+		{"symmetric", []byte{0x8c, 4, 4, 9, 3, 0}, false},
+	} {
+		hidden, err := hiddenRecipients(bufio.NewReader(bytes.NewReader(tc.in)))
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, tc.hidden, hidden, tc.name)
+	}
+
+	// a truncated packet is an error
+	_, err := hiddenRecipients(bufio.NewReader(bytes.NewReader([]byte{0x84, 12, 3, 0, 0})))
+	assert.Error(t, err)
+
+	assert.True(t, anonymousPKESK([]byte{6, 0, 1}))
+	assert.False(t, anonymousPKESK([]byte{6, 33, 6}))
+}
+
+func TestKeepRecipients(t *testing.T) {
+	pkesk := func(ctb byte, id byte) []byte {
+		return []byte{ctb, 10, 3, 0, 0, 0, 0, 0, 0, 0, id, 1}
+	}
+	msg := append(append(pkesk(0x84, 0x0a), pkesk(0xc1, 0)...), 0xd2, 1, 'x')
+	for _, tc := range []struct {
+		keep string
+		out  []byte
+		kept int
+	}{
+		{"000000000000000A", append(pkesk(0x84, 0x0a), 0xd2, 1, 'x'), 1},
+		{"000000000000000B", []byte{0xd2, 1, 'x'}, 0},
+	} {
+		out, kept, ok := keepRecipients(msg, func(id string) bool {
+			return id == tc.keep
+		})
+		assert.True(t, ok, tc.keep)
+		assert.Equal(t, tc.out, out, tc.keep)
+		assert.Equal(t, tc.kept, kept, tc.keep)
+	}
+
+	// armored or otherwise unknown messages are passed on as they are
+	for _, in := range [][]byte{
+		[]byte("-----BEGIN PGP MESSAGE-----\n"),
+		{0xc1, 10, 6, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0x84, 10, 3},
+	} {
+		out, _, ok := keepRecipients(in, func(string) bool { return false })
+		assert.False(t, ok)
+		assert.Equal(t, in, out)
+	}
+}
+
+func TestKeyHasID(t *testing.T) {
+	k := Key{
+		Fingerprint: "AB919DBF9BF0DE74896397F282EBD945BE73F104",
+		SubKeys:     map[string]struct{}{"1B75EF8B2E3A897A": {}},
+	}
+	for _, id := range []string{
+		"AB919DBF9BF0DE74896397F282EBD945BE73F104",
+		"0x82EBD945BE73F104",
+		"be73f104",
+		"1B75EF8B2E3A897A",
+		"2E3A897A",
+	} {
+		assert.True(t, k.HasID(id), id)
+	}
+	// other parts of the fingerprint aren't an ID of the key
+	for _, id := range []string{"", "F104", "945BE73F104", "AB919DBF", "0x"} {
+		assert.False(t, k.HasID(id), id)
+	}
+}
@@ -0,0 +1,61 @@
+// Package gpgtest provides helpers to run tests against a disposable GPG
+// keyring instead of the users real one.
+package gpgtest
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+const (
+	// Name is the name of the generated key
+	Name = "gopass test"
+	// Email is the email of the generated key
+	Email = "test@gopass.pw"
+)
+
+// NewKeyring creates a temporary GNUPGHOME containing a freshly generated,
+// unprotected key pair. It returns the fingerprint of the new key and a
+// cleanup func that restores the old GNUPGHOME and removes the keyring.
+func NewKeyring(t testing.TB) (string, func()) {
+	// gpg-agent sockets are placed inside GNUPGHOME and their path length
+	// is limited, so we create it directly below /tmp
+	dir, err := ioutil.TempDir("/tmp", "gpgtest-")
+	if err != nil {
+		t.Fatalf("Failed to create GNUPGHOME: %s", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatalf("Failed to set permissions of GNUPGHOME: %s", err)
+	}
+
+	oldHome, hadHome := os.LookupEnv("GNUPGHOME")
+	_ = os.Setenv("GNUPGHOME", dir)
+
+	cleanup := func() {
+		// stop any agent spawned for this keyring
+		_ = exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		if hadHome {
+			_ = os.Setenv("GNUPGHOME", oldHome)
+		} else {
+			_ = os.Unsetenv("GNUPGHOME")
+		}
+		_ = os.RemoveAll(dir)
+	}
+
+	if err := gpg.GenerateKey(Name, Email, ""); err != nil {
+		cleanup()
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+
+	kl, err := gpg.ListPrivateKeys(Email)
+	if err != nil || len(kl) < 1 {
+		cleanup()
+		t.Fatalf("Failed to find generated key: %s", err)
+	}
+
+	return kl[0].Fingerprint, cleanup
+}
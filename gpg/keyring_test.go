@@ -0,0 +1,521 @@
+package gpg_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListKeys(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	kl, err := gpg.ListPublicKeys()
+	assert.NoError(t, err)
+	if assert.Len(t, kl, 1) {
+		assert.Equal(t, fpr, kl[0].Fingerprint)
+		assert.True(t, kl[0].IsUseable())
+	}
+
+	kl, err = gpg.ListPrivateKeys(gpgtest.Email)
+	assert.NoError(t, err)
+	assert.Len(t, kl, 1)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	fn := filepath.Join(tempdir, "secret.gpg")
+	assert.NoError(t, gpg.Encrypt(fn, []byte("moar"), []string{fpr}, gpg.EncryptOpts{}))
+
+	content, err := gpg.Decrypt(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", string(content))
+
+	recps, err := gpg.GetRecipients(fn)
+	assert.NoError(t, err)
+	assert.Len(t, recps, 1)
+}
+
+func TestSetOwnerTrust(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	assert.NoError(t, gpg.SetOwnerTrust(fpr, gpg.TrustMarginal))
+	kl, err := gpg.ListPublicKeys(fpr)
+	assert.NoError(t, err)
+	if assert.Len(t, kl, 1) {
+		assert.Equal(t, "m", kl[0].Ownertrust)
+	}
+
+	assert.Error(t, gpg.SetOwnerTrust(fpr[32:], gpg.TrustFull))
+}
+
+func TestEncryptAlgorithms(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	fn := filepath.Join(tempdir, "secret.gpg")
+	opts := gpg.EncryptOpts{
+		CipherAlgo: "AES256",
+		DigestAlgo: "sha256",
+	}
+	assert.NoError(t, gpg.Encrypt(fn, []byte("moar"), []string{fpr}, opts))
+
+	content, err := gpg.Decrypt(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", string(content))
+
+	opts.CipherAlgo = "ROT13"
+	assert.Error(t, gpg.Encrypt(fn+".rot13", []byte("moar"), []string{fpr}, opts))
+	_, err = os.Stat(fn + ".rot13")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSymmetric(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	buf, err := gpg.EncryptSymmetric("passphrase", []byte("moar"))
+	assert.NoError(t, err)
+
+	fn := filepath.Join(tempdir, "secret.gpg")
+	assert.NoError(t, ioutil.WriteFile(fn, buf, 0600))
+	sym, err := gpg.IsSymmetric(fn)
+	assert.NoError(t, err)
+	assert.True(t, sym)
+
+	content, err := gpg.DecryptSymmetric("passphrase", buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", string(content))
+
+	_, err = gpg.DecryptSymmetric("wrong", buf)
+	assert.Equal(t, gpg.ErrBadPassphrase, err)
+
+	fn = filepath.Join(tempdir, "asymmetric.gpg")
+	assert.NoError(t, gpg.Encrypt(fn, []byte("moar"), []string{fpr}, gpg.EncryptOpts{}))
+	sym, err = gpg.IsSymmetric(fn)
+	assert.NoError(t, err)
+	assert.False(t, sym)
+}
+
+func TestDecryptLoopback(t *testing.T) {
+	_, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if !gpg.SupportsLoopback() {
+		t.Skip("gpg does not support the loopback pinentry")
+	}
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	// unprotected keys don't need a passphrase
+	kl, err := gpg.ListPrivateKeys(gpgtest.Email)
+	assert.NoError(t, err)
+	fn := filepath.Join(tempdir, "secret.gpg")
+	assert.NoError(t, gpg.Encrypt(fn, []byte("moar"), []string{kl[0].Fingerprint}, gpg.EncryptOpts{}))
+	content, err := gpg.DecryptLoopback(fn, gpg.DecryptOpts{}, func() (string, error) {
+		t.Errorf("Asked for a passphrase of an unprotected key")
+		return "", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", string(content))
+
+	assert.NoError(t, gpg.GenerateKey("protected", "protected@gopass.pw", "passphrase"))
+	kl, err = gpg.ListPrivateKeys("protected@gopass.pw")
+	assert.NoError(t, err)
+	if !assert.Len(t, kl, 1) {
+		return
+	}
+	// make sure the agent doesn't have the passphrase cached
+	_ = exec.Command("gpgconf", "--reload", "gpg-agent").Run()
+
+	fn = filepath.Join(tempdir, "protected.gpg")
+	assert.NoError(t, gpg.Encrypt(fn, []byte("geheim"), []string{kl[0].Fingerprint}, gpg.EncryptOpts{}))
+
+	_, err = gpg.DecryptLoopback(fn, gpg.DecryptOpts{}, func() (string, error) { return "wrong", nil })
+	assert.Equal(t, gpg.ErrBadPassphrase, err)
+
+	asked := 0
+	content, err = gpg.DecryptLoopback(fn, gpg.DecryptOpts{}, func() (string, error) {
+		asked++
+		return "passphrase", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "geheim", string(content))
+	assert.Equal(t, 1, asked)
+}
+
+func TestRefreshKeys(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	oldInterval := gpg.RefreshInterval
+	gpg.RefreshInterval = 0
+	defer func() {
+		gpg.RefreshInterval = oldInterval
+	}()
+
+	// unknown keys are reported but don't stop the refresh
+	res, err := gpg.RefreshKeys([]string{"0xDEADBEEF", "nobody@example.com"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, res.Refreshed)
+	assert.Len(t, res.Failed, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err = gpg.RefreshKeysContext(ctx, []string{fpr}, "")
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, res.Refreshed)
+}
+
+func TestEncryptExpired(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	// a key that expired at the end of 2020
+	cmd := exec.Command("gpg", "--batch", "--faked-system-time", "20200101T000000!", "--passphrase", "", "--quick-gen-key", "expired <expired@gopass.pw>", "default", "default", "365d")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to generate expired key: %s: %s", err, out)
+	}
+	kl, err := gpg.ListPublicKeys("expired@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list expired key: %s", err)
+	}
+	expired := kl[0]
+	assert.True(t, expired.IsExpired())
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	recipients := []string{fpr, expired.Fingerprint}
+	assert.Len(t, gpg.ExpiredKeys(recipients), 1)
+
+	fn := filepath.Join(tempdir, "secret.gpg")
+	err = gpg.Encrypt(fn, []byte("moar"), recipients, gpg.EncryptOpts{})
+	if assert.IsType(t, &gpg.ExpiredKeysError{}, err) {
+		ekErr := err.(*gpg.ExpiredKeysError)
+		if assert.Len(t, ekErr.Keys, 1) {
+			assert.Equal(t, expired.Fingerprint, ekErr.Keys[0].Fingerprint)
+		}
+	}
+
+	assert.NoError(t, gpg.Encrypt(fn, []byte("moar"), recipients, gpg.EncryptOpts{AllowExpired: true}))
+	content, err := gpg.Decrypt(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", string(content))
+	recps, err := gpg.GetRecipients(fn)
+	assert.NoError(t, err)
+	assert.Len(t, recps, 2)
+}
+
+func TestListPacketRecipientsBatch(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	encrypt := func(fn string, args ...string) string {
+		fn = filepath.Join(tempdir, fn)
+		args = append([]string{"--batch", "--trust-model", "always", "--encrypt", "--recipient", fpr, "--output", fn}, args...)
+		cmd := exec.Command(gpg.GPGBin, args...)
+		cmd.Stdin = strings.NewReader("secret")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Failed to encrypt %s: %s: %s", fn, err, out)
+		}
+		return fn
+	}
+	first := encrypt("first.gpg")
+	armored := encrypt("armored.gpg", "--armor")
+	hidden := encrypt("hidden.gpg", "--throw-keyids")
+	last := encrypt("last.gpg")
+	sym, err := gpg.EncryptSymmetric("passphrase", []byte("secret"))
+	assert.NoError(t, err)
+	symmetric := filepath.Join(tempdir, "sym.gpg")
+	assert.NoError(t, ioutil.WriteFile(symmetric, sym, 0600))
+	missing := filepath.Join(tempdir, "missing.gpg")
+
+	ids, err := gpg.ListPacketRecipients(first)
+	assert.NoError(t, err)
+	if assert.Len(t, ids, 1) {
+		assert.Len(t, ids[0], 16)
+	}
+	kid := ids[0]
+
+	res := gpg.ListPacketRecipientsBatch([]string{first, armored, hidden, symmetric, missing, last})
+	if !assert.Len(t, res, 6) {
+		return
+	}
+	for i, want := range [][]string{{kid}, {kid}, {gpg.AnonymousKeyID}, {}, nil, {kid}} {
+		if i == 4 {
+			assert.Error(t, res[i].Err)
+			continue
+		}
+		assert.NoError(t, res[i].Err, res[i].Path)
+		assert.Equal(t, len(want), len(res[i].IDs), res[i].Path)
+		if len(want) > 0 {
+			assert.Equal(t, want, res[i].IDs, res[i].Path)
+		}
+	}
+	assert.Equal(t, missing, res[4].Path)
+}
+
+// benchPackets creates a synthetic store of n copies of one ciphertext
+func benchPackets(b *testing.B, n int) ([]string, func()) {
+	fpr, cleanup := gpgtest.NewKeyring(b)
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		cleanup()
+		b.Fatalf("Failed to create tempdir: %s", err)
+	}
+	done := func() {
+		_ = os.RemoveAll(tempdir)
+		cleanup()
+	}
+	buf := &bytes.Buffer{}
+	if err := gpg.EncryptTo(buf, []byte("secret"), []string{fpr}, gpg.EncryptOpts{}); err != nil {
+		done()
+		b.Fatalf("Failed to encrypt: %s", err)
+	}
+	paths := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		fn := filepath.Join(tempdir, fmt.Sprintf("secret%d.gpg", i))
+		if err := ioutil.WriteFile(fn, buf.Bytes(), 0600); err != nil {
+			done()
+			b.Fatalf("Failed to write %s: %s", fn, err)
+		}
+		paths = append(paths, fn)
+	}
+	return paths, done
+}
+
+func BenchmarkListPacketRecipients(b *testing.B) {
+	paths, cleanup := benchPackets(b, 200)
+	defer cleanup()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range paths {
+			if ids, err := gpg.ListPacketRecipients(p); err != nil || len(ids) != 1 {
+				b.Fatalf("Failed to list %s: %v %s", p, ids, err)
+			}
+		}
+	}
+}
+
+func BenchmarkListPacketRecipientsBatch(b *testing.B) {
+	paths, cleanup := benchPackets(b, 200)
+	defer cleanup()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, r := range gpg.ListPacketRecipientsBatch(paths) {
+			if r.Err != nil || len(r.IDs) != 1 {
+				b.Fatalf("Failed to list %s: %v %s", r.Path, r.IDs, r.Err)
+			}
+		}
+	}
+}
+
+func TestSignDetached(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	data := []byte("secret config")
+	sig, err := gpg.SignDetached(data, fpr)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(sig, []byte("-----BEGIN PGP SIGNATURE-----")))
+	assert.False(t, bytes.Contains(sig, data))
+
+	signer, err := gpg.Verify(data, sig)
+	assert.NoError(t, err)
+	assert.Equal(t, fpr, signer)
+
+	_, err = gpg.Verify([]byte("other config"), sig)
+	assert.Error(t, err)
+}
+
+func TestDecryptHiddenRecipients(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	encrypt := func(fn string, args ...string) string {
+		fn = filepath.Join(tempdir, fn)
+		args = append([]string{"--batch", "--trust-model", "always", "--encrypt", "--recipient", fpr, "--output", fn}, args...)
+		cmd := exec.Command(gpg.GPGBin, args...)
+		cmd.Stdin = strings.NewReader("secret")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Failed to encrypt %s: %s: %s", fn, err, out)
+		}
+		return fn
+	}
+
+	for _, tc := range []struct {
+		fn     string
+		hidden bool
+	}{
+		{encrypt("listed.gpg"), false},
+		{encrypt("hidden.gpg", "--throw-keyids"), true},
+		{encrypt("armored.gpg", "--throw-keyids", "--armor"), true},
+	} {
+		hidden, err := gpg.HasHiddenRecipients(tc.fn)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.hidden, hidden, tc.fn)
+
+		content, err := gpg.DecryptWith(tc.fn, gpg.DecryptOpts{TryAllSecrets: hidden})
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", string(content))
+	}
+
+	_, err = gpg.HasHiddenRecipients(filepath.Join(tempdir, "missing.gpg"))
+	assert.Error(t, err)
+}
+
+func TestDecryptWithKey(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass second", "second@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("second@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list second key: %s", err)
+	}
+	second := kl[0].Fingerprint
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	fn := filepath.Join(tempdir, "secret.gpg")
+	assert.NoError(t, gpg.Encrypt(fn, []byte("moar"), []string{fpr}, gpg.EncryptOpts{}))
+
+	content, err := gpg.DecryptWithKey(fn, fpr)
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", string(content))
+
+	// a key held, but not a recipient, is not replaced by the recipient
+	_, err = gpg.DecryptWithKey(fn, second)
+	assert.Error(t, err)
+
+	// a key not held is reported as such
+	_, err = gpg.DecryptWithKey(fn, "DEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF")
+	if assert.Error(t, err) {
+		assert.IsType(t, &gpg.NoSecretKeyError{}, err)
+	}
+
+	both := filepath.Join(tempdir, "both.gpg")
+	assert.NoError(t, gpg.Encrypt(both, []byte("moar"), []string{fpr, second}, gpg.EncryptOpts{}))
+	for _, id := range []string{fpr, second} {
+		content, err = gpg.DecryptWithKey(both, id)
+		assert.NoError(t, err, id)
+		assert.Equal(t, "moar", string(content))
+	}
+}
+
+func TestDecryptNoSecretKey(t *testing.T) {
+	_, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	assert.NoError(t, gpg.GenerateKey("public", "public@gopass.pw", ""))
+	kl, err := gpg.ListPublicKeys("public@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list the key: %s", err)
+	}
+	fpr := kl[0].Fingerprint
+	cmd := exec.Command(gpg.GPGBin, "--batch", "--yes", "--delete-secret-keys", fpr)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to delete the secret key: %s: %s", err, out)
+	}
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	fn := filepath.Join(tempdir, "secret.gpg")
+	assert.NoError(t, gpg.Encrypt(fn, []byte("moar"), []string{fpr}, gpg.EncryptOpts{}))
+	_, err = gpg.Decrypt(fn)
+	assert.Equal(t, gpg.ErrNoSecretKey, err)
+	assert.Equal(t, gpg.ErrNoSecretKey, gpg.DecryptTo(&bytes.Buffer{}, fn, gpg.DecryptOpts{}))
+	if gpg.SupportsLoopback() {
+		_, err = gpg.DecryptLoopback(fn, gpg.DecryptOpts{}, func() (string, error) {
+			t.Errorf("Asked for a passphrase without a secret key")
+			return "", nil
+		})
+		assert.Equal(t, gpg.ErrNoSecretKey, err)
+	}
+
+	// it's not corrupted
+	assert.NoError(t, ioutil.WriteFile(fn, []byte("garbage"), 0600))
+	_, err = gpg.Decrypt(fn)
+	assert.Equal(t, gpg.ErrDecryptFailed, err)
+}
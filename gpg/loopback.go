@@ -0,0 +1,55 @@
+package gpg
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+)
+
+// SupportsLoopback returns true if the gpg binary supports
+// --pinentry-mode loopback, i.e. it is gpg 2.1 or newer
+func SupportsLoopback() bool {
+	major, minor, err := Version()
+	if err != nil {
+		return false
+	}
+	return major > 2 || (major == 2 && minor >= 1)
+}
+
+// DecryptLoopback decrypts the given file using the loopback pinentry. If
+// the private key needs a passphrase that isn't cached by gpg-agent, passFn
+// is called to ask for it. The passphrase is handed to gpg through a pipe.
+func DecryptLoopback(path string, opts DecryptOpts, passFn func() (string, error)) ([]byte, error) {
+	if !SupportsLoopback() {
+		return nil, fmt.Errorf("gpg %s does not support the loopback pinentry", GPGBin)
+	}
+	if err := EnsureAgent(); err != nil {
+		return nil, err
+	}
+
+	// unprotected keys and passphrases cached by the agent don't need a prompt
+	args := append(append(GPGArgs, opts.args()...), "--batch", "--pinentry-mode", "loopback", "--decrypt", path)
+	cmd := newCommand("DecryptLoopback", args...)
+	cmd.Stdin = &bytes.Buffer{}
+	status, err := withDecryptStatus(cmd)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.Output()
+	st := status()
+	if err == nil {
+		return out, nil
+	} else if _, ok := err.(*exec.ExitError); !ok {
+		return nil, err
+	}
+	// asking for a passphrase doesn't help without the secret key
+	if st.err() == ErrNoSecretKey {
+		return nil, ErrNoSecretKey
+	}
+
+	pass, err := passFn()
+	if err != nil {
+		return nil, err
+	}
+	return runWithPassphrase("DecryptLoopback", pass, nil, append(append(GPGArgs, opts.args()...), "--decrypt", path)...)
+}
@@ -0,0 +1,405 @@
+package gpg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// AnonymousKeyID is the key ID gpg lists for recipients hidden with
+// --throw-keyids
+const AnonymousKeyID = "0000000000000000"
+
+// PacketRecipients are the recipients of one file, see
+// ListPacketRecipientsBatch
+type PacketRecipients struct {
+	Path string
+	IDs  []string
+	// Err is set if the recipients couldn't be listed
+	Err error
+}
+
+// ListPacketRecipients returns the IDs of the keys the given file is
+// encrypted for, as listed in its public key encrypted session key packets.
+// Recipients hidden with --throw-keyids are listed as AnonymousKeyID. Nothing
+// is decrypted, so no secret key is needed.
+func ListPacketRecipients(path string) ([]string, error) {
+	args := []string{"--batch", "--list-only", "--list-packets", path}
+	cmd := newCommand("ListPacketRecipients", args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	ids, perr := RecipientsOfStream(out)
+	// drain the output, so gpg doesn't block on a parse error
+	_, _ = io.Copy(ioutil.Discard, out)
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+	return ids, perr
+}
+
+// RecipientsOfStream parses the output of gpg --list-packets while it's
+// read, without buffering it. The key ID is the last field of each
+// ":pubkey enc packet:" line.
+func RecipientsOfStream(r io.Reader) ([]string, error) {
+	ids := make([]string, 0, 5)
+	err := scanPackets(r, func(_ int64, id string) {
+		ids = append(ids, id)
+	})
+	return ids, err
+}
+
+// ListPacketRecipientsBatch lists the recipients of many files like
+// ListPacketRecipients, but with a single gpg process. The files are fed to
+// gpg as one stream and the recipients are associated with the files by the
+// offsets of their packets. Files listing no recipients that way, e.g. ASCII
+// armored ones, are listed one by one. The results are in the order of the
+// given paths.
+func ListPacketRecipientsBatch(paths []string) []PacketRecipients {
+	res := make([]PacketRecipients, len(paths))
+	batch := make([]batchFile, 0, len(paths))
+	var offset int64
+	for i, p := range paths {
+		res[i].Path = p
+		size, binary, err := packetFile(p)
+		if err != nil {
+			res[i].Err = err
+			continue
+		}
+		if !binary {
+			continue
+		}
+		batch = append(batch, batchFile{index: i, path: p, start: offset, size: size})
+		offset += size
+	}
+
+	if err := listBatch(batch, res); err != nil {
+		log.Debugf("gpg.ListPacketRecipientsBatch: listing %d files one by one: %s", len(batch), err)
+		for _, f := range batch {
+			res[f.index].IDs = nil
+		}
+	}
+	for i := range res {
+		if res[i].Err != nil || len(res[i].IDs) > 0 {
+			continue
+		}
+		res[i].IDs, res[i].Err = ListPacketRecipients(res[i].Path)
+	}
+	return res
+}
+
+// batchFile is one file of the stream fed to gpg by listBatch
+type batchFile struct {
+	index int
+	path  string
+	start int64
+	size  int64
+}
+
+// packetFile returns the size of the given file and whether it starts with
+// a binary OpenPGP packet
+func packetFile(path string) (int64, bool, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	fi, err := fh.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+	buf := make([]byte, 1)
+	if _, err := fh.Read(buf); err != nil {
+		return fi.Size(), false, nil
+	}
+	// the first bit of a packet header is always set
+	return fi.Size(), buf[0]&0x80 != 0, nil
+}
+
+// listBatch lists the packets of all files with one gpg process and records
+// the recipients found in res
+func listBatch(files []batchFile, res []PacketRecipients) error {
+	if len(files) < 1 {
+		return nil
+	}
+	cmd := newCommand("ListPacketRecipientsBatch", "--batch", "--list-only", "--list-packets")
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// the files are written one at a time, so only one is open at once
+	written := make(chan error, 1)
+	go func() {
+		written <- writeBatch(in, files)
+	}()
+
+	perr := scanPackets(out, func(off int64, id string) {
+		if off < 0 {
+			return
+		}
+		i := sort.Search(len(files), func(i int) bool {
+			return files[i].start+files[i].size > off
+		})
+		if i < len(files) {
+			res[files[i].index].IDs = append(res[files[i].index].IDs, id)
+		}
+	})
+	_, _ = io.Copy(ioutil.Discard, out)
+	werr := <-written
+	if err := cmd.Wait(); err != nil {
+		return err
+	}
+	if werr != nil {
+		return werr
+	}
+	return perr
+}
+
+// writeBatch copies the given files to w and closes it
+func writeBatch(w io.WriteCloser, files []batchFile) error {
+	defer func() {
+		_ = w.Close()
+	}()
+	for _, f := range files {
+		fh, err := os.Open(f.path)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(w, io.LimitReader(fh, f.size))
+		_ = fh.Close()
+		if err != nil {
+			return err
+		}
+		if n != f.size {
+			return fmt.Errorf("%s changed while listing its packets", f.path)
+		}
+	}
+	return nil
+}
+
+// scanPackets parses the output of gpg --list-packets line by line and
+// calls fn with the offset and key ID of each public key encrypted session
+// key packet. The offset is taken from the "# off=" header preceding the
+// packet, which gpg 2.1 and later print for old and new style packets, and
+// is -1 if it's missing. Both "keyid" and, for v6 packets, "fpr" lines are
+// understood.
+func scanPackets(r io.Reader, fn func(off int64, id string)) error {
+	scanner := bufio.NewScanner(r)
+	off := int64(-1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "# off=") {
+			off = parseOffset(line)
+			continue
+		}
+		if !strings.HasPrefix(line, ":pubkey enc packet:") {
+			continue
+		}
+		id, err := packetKeyID(line)
+		if err != nil {
+			return err
+		}
+		fn(off, id)
+	}
+	return scanner.Err()
+}
+
+// parseOffset returns the offset of a "# off=" header or -1
+func parseOffset(line string) int64 {
+	p := strings.Fields(strings.TrimPrefix(line, "# off="))
+	if len(p) < 1 {
+		return -1
+	}
+	off, err := strconv.ParseInt(p[0], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return off
+}
+
+// packetKeyID returns the key ID of a ":pubkey enc packet:" line
+func packetKeyID(line string) (string, error) {
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	for i, f := range fields {
+		switch f {
+		case "keyid", "fpr":
+			if i+1 < len(fields) {
+				return strings.ToUpper(fields[i+1]), nil
+			}
+		case "anonymous":
+			return AnonymousKeyID, nil
+		}
+	}
+	return "", fmt.Errorf("no key ID found in %q", line)
+}
+
+// HasHiddenRecipients returns true if the given file is encrypted for a
+// recipient hidden with --throw-keyids. For binary files only the session
+// key packets at the start are parsed, without running gpg. ASCII armored
+// files are listed with ListPacketRecipients.
+func HasHiddenRecipients(path string) (bool, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	r := bufio.NewReader(fh)
+	b, err := r.Peek(1)
+	if err != nil {
+		return false, err
+	}
+	if b[0]&0x80 == 0 {
+		ids, err := ListPacketRecipients(path)
+		if err != nil {
+			return false, err
+		}
+		for _, id := range ids {
+			if id == AnonymousKeyID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return hiddenRecipients(r)
+}
+
+// packetPKESK and packetSKESK are the tags of public key and symmetric key
+// encrypted session key packets, which precede the encrypted data
+const (
+	packetPKESK = 1
+	packetSKESK = 3
+	// maxSessionKeyPacket is far more than even RSA 16384 needs
+	maxSessionKeyPacket = 1 << 16
+)
+
+// hiddenRecipients reads the session key packets of a binary OpenPGP
+// message and returns true if one has a zero key ID, i.e. an anonymous
+// recipient. Reading stops at the first other packet.
+func hiddenRecipients(r *bufio.Reader) (bool, error) {
+	for {
+		tag, length, err := readPacketHeader(r)
+		if err == io.EOF {
+			return false, nil
+		}
+		// the encrypted data may well have a partial length
+		if tag > 0 && tag != packetPKESK && tag != packetSKESK {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if length > maxSessionKeyPacket {
+			return false, fmt.Errorf("session key packet of %d bytes is too long", length)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return false, err
+		}
+		if tag == packetPKESK && anonymousPKESK(body) {
+			return true, nil
+		}
+	}
+}
+
+// anonymousPKESK returns true if the body of a public key encrypted session
+// key packet has no key ID (v3) or fingerprint (v6)
+func anonymousPKESK(body []byte) bool {
+	switch {
+	case len(body) >= 9 && body[0] == 3:
+		for _, b := range body[1:9] {
+			if b != 0 {
+				return false
+			}
+		}
+		return true
+	case len(body) >= 2 && body[0] == 6:
+		return body[1] == 0
+	}
+	return false
+}
+
+// readPacketHeader reads the header of an old or new format packet and
+// returns its tag and body length. Packets of indeterminate or partial
+// length, which session key packets never have, are reported as an error.
+// The tag is -1 if no header could be read.
+func readPacketHeader(r io.ByteReader) (int, int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return -1, 0, err
+	}
+	if b&0x80 == 0 {
+		return -1, 0, fmt.Errorf("invalid packet header %#x", b)
+	}
+
+	readLength := func(n int) (int, error) {
+		length := 0
+		for i := 0; i < n; i++ {
+			c, err := r.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			length = length<<8 | int(c)
+		}
+		return length, nil
+	}
+
+	// old format: tag in bits 5-2, length type in bits 1-0
+	if b&0x40 == 0 {
+		tag := int(b>>2) & 0x0f
+		switch b & 0x03 {
+		case 0:
+			l, err := readLength(1)
+			return tag, l, err
+		case 1:
+			l, err := readLength(2)
+			return tag, l, err
+		case 2:
+			l, err := readLength(4)
+			return tag, l, err
+		}
+		return tag, 0, fmt.Errorf("packet of indeterminate length")
+	}
+
+	// new format: tag in bits 5-0
+	tag := int(b & 0x3f)
+	l1, err := readLength(1)
+	if err != nil {
+		return tag, 0, err
+	}
+	switch {
+	case l1 < 192:
+		return tag, l1, nil
+	case l1 < 224:
+		l2, err := readLength(1)
+		return tag, (l1-192)<<8 + l2 + 192, err
+	case l1 == 255:
+		l, err := readLength(4)
+		return tag, l, err
+	}
+	return tag, 0, fmt.Errorf("packet of partial length")
+}
@@ -0,0 +1,156 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// RefreshInterval is the minimum time between two requests to the
+	// keyserver, so refreshing many keys doesn't hammer it
+	RefreshInterval = time.Second
+	// RefreshTimeout limits how long refreshing a single key may take, so
+	// an unreachable keyserver can't hang the refresh
+	RefreshTimeout = time.Minute
+)
+
+// KeyChange describes how a key changed when it was refreshed
+type KeyChange struct {
+	ID         string
+	Key        Key
+	OldExpiry  time.Time
+	NewExpiry  time.Time
+	Revoked    bool
+	NewSubKeys []string
+}
+
+// ExpiryChanged returns true if the expiration date of the key changed
+func (c KeyChange) ExpiryChanged() bool {
+	return !c.OldExpiry.Equal(c.NewExpiry)
+}
+
+// RefreshResult is the outcome of refreshing a set of keys
+type RefreshResult struct {
+	Refreshed int
+	// Keys are the refreshed keys, as they are after refreshing them
+	Keys    KeyList
+	Changed []KeyChange
+	Failed  map[string]error
+}
+
+// RefreshKeys fetches the given keys from the keyserver and reports any
+// changes. If keyserver is empty the one configured for gpg is used.
+func RefreshKeys(ids []string, keyserver string) (RefreshResult, error) {
+	return RefreshKeysContext(context.Background(), ids, keyserver)
+}
+
+// RefreshKeysContext is like RefreshKeys but stops as soon as ctx is done.
+// The keys are refreshed one after another, waiting RefreshInterval in between.
+// Keys that fail to refresh are recorded in the result, they don't stop the
+// refresh of the remaining keys.
+func RefreshKeysContext(ctx context.Context, ids []string, keyserver string) (RefreshResult, error) {
+	res := RefreshResult{
+		Keys:    make(KeyList, 0, len(ids)),
+		Changed: make([]KeyChange, 0, len(ids)),
+		Failed:  make(map[string]error, len(ids)),
+	}
+
+	for i, id := range ids {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return res, ctx.Err()
+			case <-time.After(RefreshInterval):
+			}
+		} else if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		before, err := findPublicKey(id)
+		if err != nil {
+			res.Failed[id] = err
+			continue
+		}
+		if err := refreshKey(ctx, before.Fingerprint, keyserver); err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			res.Failed[id] = err
+			continue
+		}
+		after, err := findPublicKey(before.Fingerprint)
+		if err != nil {
+			res.Failed[id] = err
+			continue
+		}
+
+		res.Refreshed++
+		res.Keys = append(res.Keys, after)
+		if c, changed := compareKeys(id, before, after); changed {
+			res.Changed = append(res.Changed, c)
+		}
+	}
+
+	return res, nil
+}
+
+// findPublicKey returns the public key matching id
+func findPublicKey(id string) (Key, error) {
+	kl, err := ListPublicKeys(id)
+	if err != nil {
+		return Key{}, err
+	}
+	if len(kl) < 1 {
+		return Key{}, fmt.Errorf("public key not found")
+	}
+	return kl.FindKey(id)
+}
+
+// refreshKey runs gpg --refresh-keys for a single key. The gpg process is
+// killed if ctx is done or RefreshTimeout passed before it finishes.
+func refreshKey(ctx context.Context, fpr, keyserver string) error {
+	ctx, cancel := context.WithTimeout(ctx, RefreshTimeout)
+	defer cancel()
+
+	args := append(GPGArgs, "--batch")
+	if keyserver != "" {
+		args = append(args, "--keyserver", keyserver)
+	}
+	args = append(args, "--refresh-keys", fpr)
+	cmd := newCommandContext(ctx, "refreshKey", args...)
+	buf := &bytes.Buffer{}
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(buf.String()))
+	}
+	return nil
+}
+
+// compareKeys returns the differences between before and after and
+// whether there are any
+func compareKeys(id string, before, after Key) (KeyChange, bool) {
+	c := KeyChange{
+		ID:         id,
+		Key:        after,
+		OldExpiry:  before.ExpirationDate,
+		NewExpiry:  after.ExpirationDate,
+		Revoked:    after.IsRevoked() && !before.IsRevoked(),
+		NewSubKeys: make([]string, 0, len(after.SubKeys)),
+	}
+	for sk := range after.SubKeys {
+		if _, found := before.SubKeys[sk]; !found {
+			c.NewSubKeys = append(c.NewSubKeys, sk)
+		}
+	}
+	sort.Strings(c.NewSubKeys)
+	return c, c.Revoked || c.ExpiryChanged() || len(c.NewSubKeys) > 0
+}
@@ -0,0 +1,88 @@
+package gpg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// SignDetached creates an armored detached signature of data with the given
+// key. If signerFpr is empty gpg's default key is used. The data is passed on
+// stdin, the passphrase is asked for by the agent.
+func SignDetached(data []byte, signerFpr string) ([]byte, error) {
+	if err := EnsureAgent(); err != nil {
+		return nil, err
+	}
+	args := append(GPGArgs, "--armor", "--detach-sign")
+	if signerFpr != "" {
+		args = append(args, "--local-user", signerFpr)
+	}
+	cmd := newCommand("SignDetached", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+// Verify checks the detached signature of data and returns the fingerprint
+// of the signing key. The public key of the signer must be in the keyring.
+func Verify(data, sig []byte) (string, error) {
+	fh, err := ioutil.TempFile("", "gopass-sig-")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.Remove(fh.Name())
+	}()
+	if _, err := fh.Write(sig); err != nil {
+		_ = fh.Close()
+		return "", err
+	}
+	if err := fh.Close(); err != nil {
+		return "", err
+	}
+
+	args := []string{"--batch", "--status-fd", "1", "--verify", fh.Name(), "-"}
+	cmd := newCommand("Verify", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if fpr := parseValidSig(bytes.NewReader(out)); err == nil && fpr != "" {
+		return fpr, nil
+	}
+	return "", fmt.Errorf("invalid signature: %s", strings.TrimSpace(stderr.String()))
+}
+
+// parseValidSig returns the fingerprint of the primary key from the VALIDSIG
+// status line, if any. The signature might have been made by a subkey.
+func parseValidSig(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) < 3 || f[0] != "[GNUPG:]" || f[1] != "VALIDSIG" {
+			continue
+		}
+		// VALIDSIG <fpr> <date> <ts> <expire> <version> <reserved> <pk-algo> <hash-algo> <class> <primary-fpr>
+		if len(f) > 11 {
+			return f[11]
+		}
+		return f[2]
+	}
+	return ""
+}
+
+// DecryptFrom decrypts the ciphertext read from r
+func DecryptFrom(r io.Reader) ([]byte, error) {
+	if err := EnsureAgent(); err != nil {
+		return nil, err
+	}
+	args := append(GPGArgs, "--decrypt")
+	cmd := newCommand("DecryptFrom", args...)
+	cmd.Stdin = r
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
@@ -0,0 +1,59 @@
+package gpg
+
+import "sort"
+
+// keySorter sorts a KeyList using a less func
+type keySorter struct {
+	kl   KeyList
+	less func(a, b Key) bool
+}
+
+func (s keySorter) Len() int           { return len(s.kl) }
+func (s keySorter) Less(i, j int) bool { return s.less(s.kl[i], s.kl[j]) }
+func (s keySorter) Swap(i, j int)      { s.kl[i], s.kl[j] = s.kl[j], s.kl[i] }
+
+// SortBy sorts the keys in place. The sort is stable, so sorting by one key
+// after another sorts by multiple keys, the last one taking precedence.
+func (kl KeyList) SortBy(less func(a, b Key) bool) {
+	sort.Stable(keySorter{kl: kl, less: less})
+}
+
+// ByExpiry orders keys by their expiration date, the soonest first. Keys
+// that never expire come last.
+func ByExpiry(a, b Key) bool {
+	if a.ExpirationDate.IsZero() {
+		return false
+	}
+	if b.ExpirationDate.IsZero() {
+		return true
+	}
+	return a.ExpirationDate.Before(b.ExpirationDate)
+}
+
+// ByCreated orders keys by their creation date, the oldest first
+func ByCreated(a, b Key) bool {
+	return a.CreationDate.Before(b.CreationDate)
+}
+
+// ByUID orders keys by their user ID, see Key.UID
+func ByUID(a, b Key) bool {
+	return a.UID() < b.UID()
+}
+
+// ByHardwareToken orders keys on a hardware token before the others
+func ByHardwareToken(a, b Key) bool {
+	return a.OnHardwareToken() && !b.OnHardwareToken()
+}
+
+// UID returns the alphabetically first user ID of the key, or an empty
+// string if it has none. Unlike the identity used by OneLine, it doesn't
+// depend on map iteration order.
+func (k Key) UID() string {
+	uid := ""
+	for _, id := range k.Identities {
+		if cur := id.ID(); uid == "" || cur < uid {
+			uid = cur
+		}
+	}
+	return uid
+}
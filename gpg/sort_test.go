@@ -0,0 +1,68 @@
+package gpg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKey(fpr, name string, created, expires time.Time) Key {
+	return Key{
+		Fingerprint:    fpr,
+		CreationDate:   created,
+		ExpirationDate: expires,
+		Identities: map[string]Identity{
+			"": {Name: name, Email: name + "@example.com"},
+		},
+	}
+}
+
+func fingerprints(kl KeyList) []string {
+	fprs := make([]string, 0, len(kl))
+	for _, k := range kl {
+		fprs = append(fprs, k.Fingerprint)
+	}
+	return fprs
+}
+
+func TestKeyListSortBy(t *testing.T) {
+	now := time.Now()
+	kl := KeyList{
+		testKey("A", "carol", now.Add(-time.Hour), time.Time{}),
+		testKey("B", "alice", now.Add(-3*time.Hour), now.Add(48*time.Hour)),
+		testKey("C", "bob", now.Add(-2*time.Hour), now.Add(24*time.Hour)),
+		testKey("D", "alice", now.Add(-4*time.Hour), time.Time{}),
+	}
+
+	kl.SortBy(ByExpiry)
+	assert.Equal(t, []string{"C", "B", "A", "D"}, fingerprints(kl))
+
+	kl.SortBy(ByCreated)
+	assert.Equal(t, []string{"D", "B", "C", "A"}, fingerprints(kl))
+
+	// stable, so equal UIDs keep the previous order
+	kl.SortBy(ByUID)
+	assert.Equal(t, []string{"D", "B", "C", "A"}, fingerprints(kl))
+
+	kl.SortBy(ByExpiry)
+	kl.SortBy(ByUID)
+	assert.Equal(t, []string{"B", "D", "C", "A"}, fingerprints(kl))
+
+	// nil and empty lists
+	var nilList KeyList
+	nilList.SortBy(ByExpiry)
+	assert.Len(t, nilList, 0)
+	empty := KeyList{}
+	empty.SortBy(ByUID)
+	assert.Len(t, empty, 0)
+}
+
+func TestKeyUID(t *testing.T) {
+	k := Key{Identities: map[string]Identity{
+		"2": {Name: "zed", Email: "zed@example.com"},
+		"1": {Name: "amy", Email: "amy@example.com"},
+	}}
+	assert.Equal(t, "amy <amy@example.com>", k.UID())
+	assert.Equal(t, "", Key{}.UID())
+}
@@ -0,0 +1,175 @@
+package gpg
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNoSecretKey is returned if none of the keys a file is encrypted for
+	// has its secret key in the keyring
+	ErrNoSecretKey = errors.New("Failed to decrypt: no secret key in your keyring")
+	// ErrBadPassphrase is returned if the passphrase of the secret key, or of
+	// a passphrase-only file, was wrong
+	ErrBadPassphrase = errors.New("Failed to decrypt: bad passphrase")
+	// ErrDecryptFailed is returned if gpg failed to decrypt a file for any
+	// other reason, e.g. because it's corrupted
+	ErrDecryptFailed = errors.New("Failed to decrypt")
+)
+
+// decryptStatus is what gpg reported about a decryption on its status fd,
+// see doc/DETAILS of GnuPG
+type decryptStatus struct {
+	// encTo are the key IDs the file is encrypted for
+	encTo map[string]bool
+	// noSecKey are the key IDs without a secret key
+	noSecKey map[string]bool
+	// considered is set once gpg tried one of its secret keys, used once
+	// it decrypted the session key with one
+	considered    bool
+	used          bool
+	badPassphrase bool
+	failed        bool
+}
+
+// errCodeBadPassphrase is GPG_ERR_BAD_PASSPHRASE of libgpg-error
+const errCodeBadPassphrase = 11
+
+// errorCode returns the code of a libgpg-error value as found in ERROR
+// status lines, either a number holding the error source in the upper bits
+// or a code followed by its name, e.g. 11_BAD_PASSPHRASE
+func errorCode(s string) int {
+	if i := strings.IndexByte(s, '_'); i > 0 {
+		s = s[:i]
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return -1
+	}
+	return int(n & 0xffff)
+}
+
+// parseDecryptStatus reads the status output of a gpg decryption
+func parseDecryptStatus(r io.Reader) decryptStatus {
+	st := decryptStatus{
+		encTo:    make(map[string]bool, 2),
+		noSecKey: make(map[string]bool, 2),
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "[GNUPG:]" {
+			continue
+		}
+		switch fields[1] {
+		case "ENC_TO":
+			if len(fields) > 2 {
+				st.encTo[fields[2]] = true
+			}
+		case "NO_SECKEY":
+			if len(fields) > 2 {
+				st.noSecKey[fields[2]] = true
+			}
+		case "KEY_CONSIDERED", "NEED_PASSPHRASE":
+			st.considered = true
+		case "DECRYPTION_KEY":
+			st.considered = true
+			st.used = true
+		case "BAD_PASSPHRASE":
+			st.badPassphrase = true
+		case "ERROR":
+			// gpg 2.1+ only reports a wrong passphrase as the error code of
+			// the failed operation, e.g. ERROR pkdecrypt_failed 67108875
+			if len(fields) > 3 && errorCode(fields[3]) == errCodeBadPassphrase {
+				st.badPassphrase = true
+			}
+		case "DECRYPTION_FAILED", "NODATA":
+			st.failed = true
+		}
+	}
+	// drain the output, so gpg doesn't block on an overlong line
+	_, _ = io.Copy(ioutil.Discard, r)
+	return st
+}
+
+// err returns the error matching the status, or nil if gpg didn't report a
+// failed decryption. A wrong passphrase takes precedence, since gpg reports
+// the keys it doesn't have even if it could try another one. The secret key
+// is only missing if that's the case for every key the file is encrypted
+// for. gpg 2.1+ doesn't report NO_SECKEY if it has the public key, it then
+// just doesn't consider any secret key.
+func (st decryptStatus) err() error {
+	if st.badPassphrase {
+		return ErrBadPassphrase
+	}
+	if st.failed && len(st.encTo) > 0 && !st.considered {
+		return ErrNoSecretKey
+	}
+	if len(st.noSecKey) > 0 {
+		missing := true
+		for id := range st.encTo {
+			if !st.noSecKey[id] {
+				missing = false
+			}
+		}
+		if missing {
+			return ErrNoSecretKey
+		}
+	}
+	if st.failed {
+		return ErrDecryptFailed
+	}
+	return nil
+}
+
+// passphraseErr returns the error matching the status of a decryption gpg
+// was handed a passphrase for, see err. gpg 2.1+ doesn't always report a
+// wrong passphrase passed on a file descriptor, but it then fails after
+// considering a secret key without using it.
+func (st decryptStatus) passphraseErr() error {
+	if st.failed && st.considered && !st.used {
+		return ErrBadPassphrase
+	}
+	return st.err()
+}
+
+// withDecryptStatus makes gpg write its status output to an extra file
+// descriptor of the command. The returned function must be called after the
+// command ran, it returns the status.
+func withDecryptStatus(cmd *command) (func() decryptStatus, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	// ExtraFiles[i] becomes fd 3+i in the child
+	fd := 3 + len(cmd.ExtraFiles)
+	cmd.ExtraFiles = append(cmd.ExtraFiles, pw)
+	cmd.Args = append([]string{cmd.Args[0], "--status-fd", strconv.Itoa(fd)}, cmd.Args[1:]...)
+
+	status := make(chan decryptStatus, 1)
+	go func() {
+		status <- parseDecryptStatus(pr)
+		_ = pr.Close()
+	}()
+	return func() decryptStatus {
+		_ = pw.Close()
+		return <-status
+	}, nil
+}
+
+// decryptError returns the error of a failed decryption. The one matching
+// the status output of gpg is preferred over the error of the command.
+func decryptError(err error, st decryptStatus) error {
+	if err == nil {
+		return nil
+	}
+	if serr := st.err(); serr != nil {
+		return serr
+	}
+	return err
+}
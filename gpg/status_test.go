@@ -0,0 +1,136 @@
+package gpg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status string
+		err    error
+	}{
+		{
+			name: "no secret key",
+			status: `[GNUPG:] ENC_TO 36491DAB8B69CE8B 16 0
+[GNUPG:] NO_SECKEY 36491DAB8B69CE8B
+[GNUPG:] BEGIN_DECRYPTION
+[GNUPG:] DECRYPTION_FAILED
+[GNUPG:] END_DECRYPTION
+`,
+			err: ErrNoSecretKey,
+		},
+		{
+			// the second key is there, but the decryption failed anyway
+			name: "one secret key missing",
+			status: `[GNUPG:] ENC_TO 36491DAB8B69CE8B 16 0
+[GNUPG:] ENC_TO F7780C19D1D1DA1A 1 0
+[GNUPG:] NO_SECKEY 36491DAB8B69CE8B
+[GNUPG:] KEY_CONSIDERED 437F4886B2558869A556C2F5F7780C19D1D1DA1A 0
+[GNUPG:] BEGIN_DECRYPTION
+[GNUPG:] DECRYPTION_FAILED
+[GNUPG:] END_DECRYPTION
+`,
+			err: ErrDecryptFailed,
+		},
+		{
+			// gpg 2.1+ has the public key, but not the secret key
+			name: "public key only",
+			status: `[GNUPG:] ENC_TO 86B29284CF5AA9F3 1 0
+[GNUPG:] BEGIN_DECRYPTION
+[GNUPG:] DECRYPTION_FAILED
+[GNUPG:] END_DECRYPTION
+`,
+			err: ErrNoSecretKey,
+		},
+		{
+			name: "bad passphrase of the secret key",
+			status: `[GNUPG:] ENC_TO F7780C19D1D1DA1A 1 0
+[GNUPG:] KEY_CONSIDERED 437F4886B2558869A556C2F5F7780C19D1D1DA1A 0
+[GNUPG:] KEY_CONSIDERED 437F4886B2558869A556C2F5F7780C19D1D1DA1A 0
+[GNUPG:] ERROR pkdecrypt_failed 67108875
+[GNUPG:] BEGIN_DECRYPTION
+[GNUPG:] DECRYPTION_FAILED
+[GNUPG:] END_DECRYPTION
+`,
+			err: ErrBadPassphrase,
+		},
+		{
+			name: "bad passphrase with gpg 1.4",
+			status: `[GNUPG:] ENC_TO F7780C19D1D1DA1A 1 0
+[GNUPG:] USERID_HINT F7780C19D1D1DA1A prot <prot@x>
+[GNUPG:] NEED_PASSPHRASE F7780C19D1D1DA1A F7780C19D1D1DA1A 1 0
+[GNUPG:] BAD_PASSPHRASE F7780C19D1D1DA1A
+[GNUPG:] NO_SECKEY F7780C19D1D1DA1A
+[GNUPG:] BEGIN_DECRYPTION
+[GNUPG:] DECRYPTION_FAILED
+[GNUPG:] END_DECRYPTION
+`,
+			err: ErrBadPassphrase,
+		},
+		{
+			name: "bad symmetric passphrase",
+			status: `[GNUPG:] NEED_PASSPHRASE_SYM 9 3 2
+[GNUPG:] BEGIN_DECRYPTION
+[GNUPG:] DECRYPTION_INFO 2 9 0
+[GNUPG:] ERROR symkey_decrypt.maybe_error 11_BAD_PASSPHRASE
+[GNUPG:] DECRYPTION_FAILED
+[GNUPG:] END_DECRYPTION
+`,
+			err: ErrBadPassphrase,
+		},
+		{
+			name:   "corrupted",
+			status: "[GNUPG:] NODATA 3\n",
+			err:    ErrDecryptFailed,
+		},
+		{
+			name: "decrypted",
+			status: `[GNUPG:] ENC_TO 82EBD945BE73F104 1 0
+[GNUPG:] KEY_CONSIDERED AB919DBF9BF0DE74896397F282EBD945BE73F104 0
+[GNUPG:] DECRYPTION_KEY AB919DBF9BF0DE74896397F282EBD945BE73F104 AB919DBF9BF0DE74896397F282EBD945BE73F104 u
+[GNUPG:] BEGIN_DECRYPTION
+[GNUPG:] DECRYPTION_OKAY
+[GNUPG:] GOODMDC
+[GNUPG:] END_DECRYPTION
+`,
+			err: nil,
+		},
+	} {
+		st := parseDecryptStatus(strings.NewReader(tc.status))
+		assert.Equal(t, tc.err, st.err(), tc.name)
+	}
+
+	// a wrong passphrase passed on a file descriptor
+	st := parseDecryptStatus(strings.NewReader(`[GNUPG:] ENC_TO 7EFAC6896C0BB142 1 0
+[GNUPG:] KEY_CONSIDERED C95AD2862697E25DABC74EE41B75EF8B2E3A897A 0
+[GNUPG:] BEGIN_DECRYPTION
+[GNUPG:] DECRYPTION_FAILED
+[GNUPG:] END_DECRYPTION
+`))
+	assert.Equal(t, ErrDecryptFailed, st.err())
+	assert.Equal(t, ErrBadPassphrase, st.passphraseErr())
+	// the session key was decrypted, the data is corrupted
+	st = parseDecryptStatus(strings.NewReader(`[GNUPG:] ENC_TO 7EFAC6896C0BB142 1 0
+[GNUPG:] KEY_CONSIDERED C95AD2862697E25DABC74EE41B75EF8B2E3A897A 0
+[GNUPG:] DECRYPTION_KEY A02A54620FFC7320B3F210EF7EFAC6896C0BB142 C95AD2862697E25DABC74EE41B75EF8B2E3A897A u
+[GNUPG:] BEGIN_DECRYPTION
+[GNUPG:] DECRYPTION_FAILED
+[GNUPG:] END_DECRYPTION
+`))
+	assert.Equal(t, ErrDecryptFailed, st.passphraseErr())
+
+	// without a status the error of the command is kept
+	err := fmt.Errorf("exit status 2")
+	assert.Equal(t, err, decryptError(err, parseDecryptStatus(strings.NewReader(""))))
+	assert.Equal(t, ErrNoSecretKey, decryptError(err, parseDecryptStatus(strings.NewReader("[GNUPG:] ENC_TO 36491DAB8B69CE8B 16 0\n[GNUPG:] NO_SECKEY 36491DAB8B69CE8B\n"))))
+	assert.Nil(t, decryptError(nil, parseDecryptStatus(strings.NewReader("[GNUPG:] NODATA 3\n"))))
+
+	assert.Equal(t, 11, errorCode("67108875"))
+	assert.Equal(t, 11, errorCode("11_BAD_PASSPHRASE"))
+	assert.Equal(t, -1, errorCode("foo"))
+}
@@ -0,0 +1,106 @@
+package gpg
+
+import "fmt"
+
+// Public key algorithms as listed in field 4 of the `--with-colons` output,
+// see RFC 4880 and RFC 6637
+const (
+	AlgoRSA        = 1
+	AlgoRSAEncrypt = 2
+	AlgoRSASign    = 3
+	AlgoElgamal    = 16
+	AlgoDSA        = 17
+	AlgoECDH       = 18
+	AlgoECDSA      = 19
+	AlgoElgamalOld = 20
+	AlgoEdDSA      = 22
+)
+
+const (
+	// DefaultMinKeyBits is the minimum length of RSA and Elgamal keys which
+	// are not considered weak
+	DefaultMinKeyBits = 2048
+	// minCurveBits is the minimum size of elliptic curves which are not
+	// considered weak. Curve25519 has 255 bits.
+	minCurveBits = 255
+)
+
+// Algorithm returns the name of the public key algorithm of the primary key
+func (k Key) Algorithm() string {
+	return algorithmName(k.PubKeyAlgo)
+}
+
+// algorithmName returns the name of a public key algorithm
+func algorithmName(algo int) string {
+	switch algo {
+	case AlgoRSA, AlgoRSAEncrypt, AlgoRSASign:
+		return "RSA"
+	case AlgoElgamal, AlgoElgamalOld:
+		return "ELG"
+	case AlgoDSA:
+		return "DSA"
+	case AlgoECDH:
+		return "ECDH"
+	case AlgoECDSA:
+		return "ECDSA"
+	case AlgoEdDSA:
+		return "EdDSA"
+	case 0:
+		return "unknown"
+	}
+	return fmt.Sprintf("algo %d", algo)
+}
+
+// BitLength returns the length of the primary key in bits. For elliptic curve
+// keys this is the size of the curve.
+func (k Key) BitLength() int {
+	return k.KeyLength
+}
+
+// Weakness returns why the key is considered weak, or an empty string if it
+// isn't. The primary key and the usable encryption subkeys, which the secrets
+// are actually encrypted to, are checked. RSA and Elgamal keys must have at
+// least minBits bits, if minBits is less than one DefaultMinKeyBits is used.
+// DSA keys and the deprecated sign and encrypt Elgamal keys are always weak.
+func (k Key) Weakness(minBits int) string {
+	if minBits < 1 {
+		minBits = DefaultMinKeyBits
+	}
+	if weak := weakness(k.PubKeyAlgo, k.KeyLength, k.Curve, minBits); weak != "" {
+		return weak
+	}
+	for _, sk := range k.EncryptionSubKeys {
+		if weak := weakness(sk.PubKeyAlgo, sk.KeyLength, sk.Curve, minBits); weak != "" {
+			return fmt.Sprintf("the encryption subkey %s: %s", sk.ID, weak)
+		}
+	}
+	return ""
+}
+
+// weakness returns why a key with the given algorithm, length and curve is
+// considered weak, see Key.Weakness
+func weakness(algo, length int, curve string, minBits int) string {
+	switch algo {
+	case AlgoRSA, AlgoRSAEncrypt, AlgoRSASign, AlgoElgamal:
+		if length < minBits {
+			return fmt.Sprintf("%s-%d is shorter than %d bits", algorithmName(algo), length, minBits)
+		}
+	case AlgoDSA:
+		return "DSA keys are deprecated"
+	case AlgoElgamalOld:
+		return "sign and encrypt Elgamal keys are deprecated"
+	case AlgoECDH, AlgoECDSA, AlgoEdDSA:
+		if length < minCurveBits {
+			if curve == "" {
+				curve = algorithmName(algo)
+			}
+			return fmt.Sprintf("the curve %s is smaller than %d bits", curve, minCurveBits)
+		}
+	}
+	return ""
+}
+
+// IsWeak returns true if the key is considered weak, see Weakness
+func (k Key) IsWeak(minBits int) bool {
+	return k.Weakness(minBits) != ""
+}
@@ -0,0 +1,66 @@
+package gpg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyStrength(t *testing.T) {
+	in := `tru::1:1496913597:0:3:1:5
+pub:-:1024:1:5A4C0E6BAD1C3A41:1262304000:::-:::scESC::::::23::0:
+fpr:::::::::1F6E174A8395C0E0C1C5CC905A4C0E6BAD1C3A41:
+uid:-::::1262304000::AA1D8A1BC2AC4FC8E1F22A5AA4EAF6F00D00DF9C::Old RSA <rsa1024@example.com>::::::::::0:
+pub:-:4096:1:3C19E1A7B8E4B40A:1496913597:::-:::scESC::::::23::0:
+fpr:::::::::C7B8C0A6A3B4E80C6C2D2A1D3C19E1A7B8E4B40A:
+uid:-::::1496913597::AB1D8A1BC2AC4FC8E1F22A5AA4EAF6F00D00DF9C::Big RSA <rsa4096@example.com>::::::::::0:
+pub:-:1024:17:A8D6B3E3F5DD1E4C:1104537600:::-:::scaSCA::::::23::0:
+fpr:::::::::E2E8F7C3DE8C0A2B19C8E9A4A8D6B3E3F5DD1E4C:
+uid:-::::1104537600::AC1D8A1BC2AC4FC8E1F22A5AA4EAF6F00D00DF9C::Old DSA <dsa@example.com>::::::::::0:
+sub:-:2048:16:E6B0B53F3A10F2B1:1104537600::::::e::::::23:
+pub:-:255:22:9F4C7D8A1E1B2C3D:1496913597:::-:::scESC:::::ed25519:::0:
+fpr:::::::::0A1B2C3D4E5F60718293A4B5C6D7E8F99F4C7D8A1E1B2C3D:
+uid:-::::1496913597::AD1D8A1BC2AC4FC8E1F22A5AA4EAF6F00D00DF9C::Edward <ed25519@example.com>::::::::::0:
+sub:-:255:18:1F2E3D4C5B6A7988:1496913597::::::e:::::cv25519::
+pub:-:4096:1:4B2A9C8D7E6F5A41:1496913597:::-:::scESC::::::23::0:
+fpr:::::::::D1C2B3A4F5E6D7C8B9A0F1E2D3C4B5A64B2A9C8D7E6F5A41:
+uid:-::::1496913597::AE1D8A1BC2AC4FC8E1F22A5AA4EAF6F00D00DF9C::Weak Sub <weaksub@example.com>::::::::::0:
+sub:-:1024:1:6C5B4A3928171605:1496913597::::::e::::::23:
+pub:-:4096:1:5C3B0D9E8F7A6B52:1496913597:::-:::scESC::::::23::0:
+fpr:::::::::E2D3C4B5A6F7E8D9C0B1A2F3E4D5C6B75C3B0D9E8F7A6B52:
+uid:-::::1496913597::AF1D8A1BC2AC4FC8E1F22A5AA4EAF6F00D00DF9C::Rotated Sub <rotated@example.com>::::::::::0:
+sub:r:1024:1:7D6C5B4A39281716:1496913597::::::e::::::23:
+sub:-:1024:1:8E7D6C5B4A392817:1262304000:1262390400:::::e::::::23:
+sub:-:1024:17:9F8E7D6C5B4A3928:1496913597::::::s::::::23:
+sub:-:4096:1:0A9F8E7D6C5B4A39:1496913597::::::e::::::23:
+`
+	kl := ParseColons(strings.NewReader(in))
+	assert.Len(t, kl, 6)
+
+	for i, tc := range []struct {
+		algo     string
+		bits     int
+		weakness string
+	}{
+		{"RSA", 1024, "RSA-1024 is shorter than 2048 bits"},
+		{"RSA", 4096, ""},
+		{"DSA", 1024, "DSA keys are deprecated"},
+		{"EdDSA", 255, ""},
+		// the secrets are encrypted to the weak subkey
+		{"RSA", 4096, "the encryption subkey 6C5B4A3928171605: RSA-1024 is shorter than 2048 bits"},
+		// revoked, expired and signing subkeys aren't used to encrypt
+		{"RSA", 4096, ""},
+	} {
+		assert.Equal(t, tc.algo, kl[i].Algorithm())
+		assert.Equal(t, tc.bits, kl[i].BitLength())
+		assert.Equal(t, tc.weakness, kl[i].Weakness(0))
+		assert.Equal(t, tc.weakness != "", kl[i].IsWeak(0))
+	}
+	assert.Equal(t, "ed25519", kl[3].Curve)
+	assert.Equal(t, []SubKey{{ID: "1F2E3D4C5B6A7988", KeyLength: 255, PubKeyAlgo: AlgoECDH, Curve: "cv25519"}}, kl[3].EncryptionSubKeys)
+	assert.Len(t, kl[5].EncryptionSubKeys, 1)
+
+	// a higher minimum flags the big RSA key as well
+	assert.True(t, kl[1].IsWeak(8192))
+}
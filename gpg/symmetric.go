@@ -0,0 +1,122 @@
+package gpg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// EncryptSymmetric encrypts the given content with a passphrase only. No
+// recipients are involved, anyone knowing the passphrase can decrypt it.
+func EncryptSymmetric(pass string, in []byte) ([]byte, error) {
+	args := append(GPGArgs, "--symmetric", "--output", "-")
+	return runWithPassphrase("EncryptSymmetric", pass, in, args...)
+}
+
+// DecryptSymmetric decrypts content that was encrypted with EncryptSymmetric
+func DecryptSymmetric(pass string, in []byte) ([]byte, error) {
+	args := append(GPGArgs, "--decrypt", "--output", "-")
+	return runWithPassphrase("DecryptSymmetric", pass, in, args...)
+}
+
+const (
+	// tagSymKeyEnc is the packet tag of a Symmetric-Key Encrypted Session
+	// Key Packet as defined in RFC 4880, section 4.3
+	tagSymKeyEnc = 3
+)
+
+// IsSymmetric returns true if the given file is only encrypted with a
+// passphrase and not for any recipient. To avoid invoking gpg for every
+// secret this only looks at the tag of the first packet of binary files.
+func IsSymmetric(path string) (bool, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+
+	buf := make([]byte, 1)
+	if _, err := fh.Read(buf); err != nil {
+		return false, err
+	}
+
+	// the first bit of a packet header is always set, if it isn't this is
+	// probably an ASCII armored file and we have to ask gpg
+	if buf[0]&0x80 == 0 {
+		return isSymmetricPackets(path)
+	}
+	tag := (buf[0] >> 2) & 0x0f
+	if buf[0]&0x40 != 0 {
+		// new packet format
+		tag = buf[0] & 0x3f
+	}
+	return tag == tagSymKeyEnc, nil
+}
+
+// isSymmetricPackets uses gpg --list-packets to check if the given file is
+// only encrypted with a passphrase
+func isSymmetricPackets(path string) (bool, error) {
+	args := []string{"--batch", "--list-only", "--list-packets", path}
+	cmd := newCommand("IsSymmetric", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		return false, err
+	}
+	sym := bytes.Contains(out, []byte(":symkey enc packet:"))
+	pub := bytes.Contains(out, []byte(":pubkey enc packet:"))
+	return sym && !pub, nil
+}
+
+// runWithPassphrase runs gpg in batch mode and hands it the passphrase through
+// an extra file descriptor, so it never shows up in the process list
+func runWithPassphrase(fn, pass string, in []byte, args ...string) ([]byte, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = pr.Close()
+	}()
+
+	// ExtraFiles[0] becomes fd 3 in the child
+	pargs := []string{"--batch", "--passphrase-fd", "3"}
+	if SupportsLoopback() {
+		// gpg 2.1+ would ask the pinentry instead of reading the fd
+		pargs = append(pargs, "--pinentry-mode", "loopback")
+	}
+
+	cmd := newCommand(fn, append(pargs, args...)...)
+	cmd.ExtraFiles = []*os.File{pr}
+	cmd.Stdin = bytes.NewReader(in)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	status, err := withDecryptStatus(cmd)
+	if err != nil {
+		_ = pw.Close()
+		return nil, err
+	}
+
+	go func() {
+		_, _ = pw.WriteString(pass + "\n")
+		_ = pw.Close()
+	}()
+
+	out, err := cmd.Output()
+	if serr := status().passphraseErr(); err != nil && serr != nil {
+		return nil, serr
+	}
+	if err != nil {
+		// gpg might echo the passphrase or parts of the content
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, log.SanitizeError(fmt.Errorf("%s: %s", err, bytes.TrimSpace(stderr.Bytes())), []byte(pass), in, out)
+		}
+		return nil, log.SanitizeError(err, []byte(pass), in, out)
+	}
+	return out, nil
+}
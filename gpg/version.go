@@ -0,0 +1,115 @@
+package gpg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	reVersion = regexp.MustCompile(`^gpg \(GnuPG[^)]*\) (\d+)\.(\d+)`)
+	// versionOut and versionErr cache the result of gpg --version
+	versionOut  []byte
+	versionErr  error
+	versionOnce sync.Once
+)
+
+// versionOutput returns the output of gpg --version. It's only invoked once.
+func versionOutput() ([]byte, error) {
+	versionOnce.Do(func() {
+		cmd := newCommand("versionOutput", "--version")
+		versionOut, versionErr = cmd.Output()
+	})
+	return versionOut, versionErr
+}
+
+// Version returns the major and minor version of the gpg binary
+func Version() (int, int, error) {
+	out, err := versionOutput()
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseVersion(bytes.NewBuffer(out))
+}
+
+// parseVersion parses the first line of gpg --version,
+// e.g. gpg (GnuPG) 2.1.18
+func parseVersion(reader io.Reader) (int, int, error) {
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, 0, err
+	}
+	m := reVersion.FindStringSubmatch(strings.TrimSpace(line))
+	if len(m) < 3 {
+		return 0, 0, fmt.Errorf("Failed to parse gpg version: %s", line)
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	return major, minor, nil
+}
+
+// Algorithms are the algorithms supported by gpg
+type Algorithms struct {
+	Pubkey      []string
+	Cipher      []string
+	Hash        []string
+	Compression []string
+}
+
+// SupportedAlgorithms returns the algorithms supported by the gpg binary as
+// listed by gpg --version
+func SupportedAlgorithms() (Algorithms, error) {
+	out, err := versionOutput()
+	if err != nil {
+		return Algorithms{}, err
+	}
+
+	return parseAlgorithms(bytes.NewBuffer(out)), nil
+}
+
+// parseAlgorithms parses the "Supported algorithms" section of gpg --version.
+// Long lists are continued on indented lines.
+func parseAlgorithms(reader io.Reader) Algorithms {
+	algos := Algorithms{}
+
+	var cur *[]string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			cur = nil
+			p := strings.SplitN(line, ":", 2)
+			if len(p) < 2 {
+				continue
+			}
+			switch strings.TrimSpace(p[0]) {
+			case "Pubkey":
+				cur = &algos.Pubkey
+			case "Cipher":
+				cur = &algos.Cipher
+			case "Hash":
+				cur = &algos.Hash
+			case "Compression":
+				cur = &algos.Compression
+			default:
+				continue
+			}
+			line = p[1]
+		}
+		if cur == nil {
+			continue
+		}
+		for _, a := range strings.Split(line, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				*cur = append(*cur, a)
+			}
+		}
+	}
+
+	return algos
+}
@@ -0,0 +1,75 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/log"
+)
+
+// Events gopass runs hooks for
+const (
+	PreCommit  = "pre-commit"
+	PostInsert = "post-insert"
+	PostSync   = "post-sync"
+)
+
+var (
+	// Dir is the directory containing the hooks. If empty ~/.gopass/hooks
+	// is used
+	Dir = ""
+)
+
+// Run executes the hook for the given event, if there is one. Hooks are
+// executables named after the event, similar to git hooks. They receive the
+// event in GOPASS_HOOK and everything in env as additional environment
+// variables. Callers must never put secret contents into env.
+// A failing pre-hook returns an error and should abort the operation, a
+// failing post-hook only prints a warning.
+func Run(event string, env map[string]string) error {
+	fn := filepath.Join(dir(), event)
+	fi, err := os.Stat(fn)
+	if err != nil || !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 {
+		// no hook installed
+		return nil
+	}
+
+	cmd := exec.Command(fn)
+	log.Debugf("hooks: running %s hook %s", event, fn)
+	cmd.Env = append(os.Environ(), environ(event, env)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if strings.HasPrefix(event, "pre-") {
+			return fmt.Errorf("%s hook failed: %s", event, err)
+		}
+		fmt.Println(color.YellowString("Warning: %s hook failed: %s", event, err))
+	}
+	return nil
+}
+
+// dir returns the hook directory
+func dir() string {
+	if Dir != "" {
+		return Dir
+	}
+	return filepath.Join(os.Getenv("HOME"), ".gopass", "hooks")
+}
+
+// environ returns the hook environment in the KEY=value format, sorted to
+// have a stable order
+func environ(event string, env map[string]string) []string {
+	out := make([]string, 0, len(env)+1)
+	out = append(out, "GOPASS_HOOK="+event)
+	for k, v := range env {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out[1:])
+	return out
+}
@@ -0,0 +1,50 @@
+package hooks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeHook(t *testing.T, dir, event, script string) {
+	fn := filepath.Join(dir, event)
+	assert.NoError(t, ioutil.WriteFile(fn, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	assert.NoError(t, os.Chmod(fn, 0755))
+}
+
+func TestRun(t *testing.T) {
+	td, err := ioutil.TempDir("", "gopass-")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(td)
+	}()
+
+	oldDir := Dir
+	Dir = td
+	defer func() {
+		Dir = oldDir
+	}()
+
+	out := filepath.Join(td, "out")
+	writeHook(t, td, PostInsert, `echo "$GOPASS_HOOK $GOPASS_SECRET" > `+out)
+	assert.NoError(t, Run(PostInsert, map[string]string{"GOPASS_SECRET": "foo/bar"}))
+	buf, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "post-insert foo/bar\n", string(buf))
+
+	// missing hooks are fine
+	assert.NoError(t, Run(PostSync, nil))
+
+	// not executable hooks are ignored
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(td, PreCommit), []byte("#!/bin/sh\nexit 1\n"), 0644))
+	assert.NoError(t, Run(PreCommit, nil))
+
+	// failing pre-hooks abort, failing post-hooks don't
+	writeHook(t, td, PreCommit, "exit 1")
+	assert.Error(t, Run(PreCommit, nil))
+	writeHook(t, td, PostSync, "exit 1")
+	assert.NoError(t, Run(PostSync, nil))
+}
@@ -0,0 +1,60 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Authenticator decides whether a request may read secrets
+type Authenticator interface {
+	// Authenticate returns an error if the request must be refused
+	Authenticate(r *http.Request) error
+}
+
+// tokenAuth authenticates requests by a bearer token
+type tokenAuth []byte
+
+// Token returns an Authenticator accepting requests with the header
+// "Authorization: Bearer <token>". An empty token accepts nothing.
+func Token(token string) Authenticator {
+	return tokenAuth(token)
+}
+
+// Authenticate implements Authenticator
+func (t tokenAuth) Authenticate(r *http.Request) error {
+	h := r.Header.Get("Authorization")
+	if len(t) < 1 || !strings.HasPrefix(h, "Bearer ") {
+		return fmt.Errorf("invalid token")
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(h, "Bearer ")), t) != 1 {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+// peerUser authenticates requests by the user of the connection
+type peerUser struct{}
+
+// PeerUser returns an Authenticator accepting all requests made over a unix
+// socket. Serve only accepts connections to unix sockets from processes of
+// the same user, see peerListener. It can't be used with TCP addresses.
+func PeerUser() Authenticator {
+	return peerUser{}
+}
+
+// Authenticate implements Authenticator
+func (peerUser) Authenticate(r *http.Request) error {
+	if !overUnix(r) {
+		return fmt.Errorf("only requests over a unix socket are accepted")
+	}
+	return nil
+}
+
+// overUnix returns true if the request was made over a unix socket
+func overUnix(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
+}
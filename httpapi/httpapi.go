@@ -0,0 +1,259 @@
+// Package httpapi serves secrets to other local tools over HTTP, so they
+// don't need to talk to gpg themselves. The server only listens on loopback
+// addresses or unix sockets, authenticates every request and is rate limited.
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/justwatchcom/gopass/log"
+	"github.com/justwatchcom/gopass/password"
+)
+
+const (
+	// unixPrefix marks an address as the path of a unix socket
+	unixPrefix = "unix:"
+	// secretPrefix is the path prefix of the secret endpoint
+	secretPrefix = "/secret/"
+	// defaultRate and defaultBurst limit the requests per second
+	defaultRate  = 10
+	defaultBurst = 20
+)
+
+// Serve serves the secrets of the store on the given address until it's
+// interrupted. The address is either a loopback address with a port, e.g.
+// 127.0.0.1:8421 or localhost:8421, or the path of a new unix socket
+// prefixed by unix:, e.g. unix:/run/user/1000/gopass.sock. Other addresses
+// are refused. Unix sockets only accept connections of the current user.
+//
+// Secrets are decrypted on every request like the command line does, so
+// the passphrase cache of gpg-agent applies. Nothing is written to disk and
+// neither secrets nor request bodies are logged.
+func Serve(store *password.RootStore, addr string, auth Authenticator) error {
+	if auth == nil {
+		return fmt.Errorf("the API needs an authenticator")
+	}
+	l, err := listen(addr, auth)
+	if err != nil {
+		return err
+	}
+
+	// close the listener on Ctrl-C, which also removes the unix socket
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	closed := make(chan struct{})
+	go func() {
+		if _, ok := <-sigs; ok {
+			close(closed)
+			_ = l.Close()
+		}
+	}()
+
+	log.Debugf("httpapi: listening on %s", l.Addr())
+	err = http.Serve(l, Handler(store, auth))
+	select {
+	case <-closed:
+		return nil
+	default:
+		_ = l.Close()
+		return err
+	}
+}
+
+// listen opens the listener for the given address, refusing anything but
+// loopback addresses and unix sockets
+func listen(addr string, auth Authenticator) (net.Listener, error) {
+	if strings.HasPrefix(addr, unixPrefix) {
+		return listenUnix(strings.TrimPrefix(addr, unixPrefix))
+	}
+	if _, ok := auth.(peerUser); ok {
+		return nil, fmt.Errorf("peer credentials can only be checked on unix sockets")
+	}
+	if err := checkLoopback(addr); err != nil {
+		return nil, err
+	}
+	return net.Listen("tcp", addr)
+}
+
+// checkLoopback returns an error unless the host of the address only
+// resolves to loopback addresses
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %s", addr, err)
+	}
+	if host == "" {
+		return fmt.Errorf("refusing to listen on all interfaces, use 127.0.0.1 or a unix socket")
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if ips, err = net.LookupIP(host); err != nil {
+			return fmt.Errorf("failed to resolve %s: %s", host, err)
+		}
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return fmt.Errorf("refusing to listen on %s, it's not a loopback address", addr)
+		}
+	}
+	return nil
+}
+
+// listenUnix creates a unix socket, which is only accessible by and only
+// accepts connections of the current user
+func listenUnix(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the path of the unix socket is missing")
+	}
+	if _, err := os.Lstat(path); err == nil {
+		return nil, fmt.Errorf("%s already exists", path)
+	}
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return &peerListener{UnixListener: l, uid: os.Getuid()}, nil
+}
+
+// handler serves the API, see Handler
+type handler struct {
+	store   *password.RootStore
+	auth    Authenticator
+	limiter *limiter
+}
+
+// Handler returns the handler of the API. It serves these endpoints:
+//
+//	GET /ls                         the names of all secrets as a JSON list
+//	GET /secret/<name>              the password of the secret
+//	GET /secret/<name>?field=<key>  the value of the line "<key>: <value>"
+//
+// Requests come from local tools only, so the Host header must name a
+// loopback address. That stops websites from reaching the API through DNS
+// rebinding.
+func Handler(store *password.RootStore, auth Authenticator) http.Handler {
+	return &handler{
+		store:   store,
+		auth:    auth,
+		limiter: newLimiter(defaultRate, defaultBurst),
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	status := h.serve(w, r)
+	// only the name of a secret is logged, never its content
+	log.Debugf("httpapi: %s %s: %d", r.Method, r.URL.Path, status)
+}
+
+// serve handles a request and returns the status code of the response
+func (h *handler) serve(w http.ResponseWriter, r *http.Request) int {
+	if !h.limiter.allow() {
+		return fail(w, http.StatusTooManyRequests, "too many requests")
+	}
+	if !overUnix(r) && !isLocalHost(r.Host) {
+		return fail(w, http.StatusForbidden, "invalid host")
+	}
+	if err := h.auth.Authenticate(r); err != nil {
+		return fail(w, http.StatusUnauthorized, err.Error())
+	}
+	if r.Method != http.MethodGet {
+		return fail(w, http.StatusMethodNotAllowed, "only GET is supported")
+	}
+
+	switch {
+	case r.URL.Path == "/ls":
+		return h.list(w)
+	case strings.HasPrefix(r.URL.Path, secretPrefix):
+		return h.secret(w, strings.TrimPrefix(r.URL.Path, secretPrefix), r.URL.Query().Get("field"))
+	}
+	return fail(w, http.StatusNotFound, "not found")
+}
+
+// list writes the names of all secrets
+func (h *handler) list(w http.ResponseWriter) int {
+	names, err := h.store.List()
+	if err != nil {
+		return fail(w, http.StatusInternalServerError, "failed to list secrets")
+	}
+	buf, err := json.Marshal(names)
+	if err != nil {
+		return fail(w, http.StatusInternalServerError, "failed to list secrets")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(buf)
+	return http.StatusOK
+}
+
+// secret writes the password or the given field of a secret
+func (h *handler) secret(w http.ResponseWriter, name, key string) int {
+	if name == "" || h.store.IsDir(name) {
+		return fail(w, http.StatusNotFound, "not found")
+	}
+	// the passphrase can't be asked for
+	if h.store.IsSymmetric(name) {
+		return fail(w, http.StatusForbidden, "passphrase-only secrets can't be read over the API")
+	}
+	// references to other fields are served with the value they refer to,
+	// like show does
+	content, err := h.store.GetResolved(name, true)
+	switch err {
+	case nil:
+	case password.ErrNotFound:
+		return fail(w, http.StatusNotFound, "not found")
+	case password.ErrSneaky:
+		return fail(w, http.StatusBadRequest, "invalid name")
+	default:
+		return fail(w, http.StatusInternalServerError, "failed to read the secret")
+	}
+
+	value, found := field(content, key)
+	if !found {
+		return fail(w, http.StatusNotFound, "field not found")
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(value))
+	return http.StatusOK
+}
+
+// field returns the password for the empty key, otherwise the value of the
+// field, see password.Field
+func field(content []byte, key string) (string, bool) {
+	if key == "" {
+		key = "password"
+	}
+	return password.Field(content, key)
+}
+
+// isLocalHost returns true if the Host header of a request names a loopback
+// address
+func isLocalHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "" || host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// fail writes an error response and returns its status code
+func fail(w http.ResponseWriter, status int, msg string) int {
+	http.Error(w, msg, status)
+	return status
+}
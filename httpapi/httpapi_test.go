@@ -0,0 +1,254 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/justwatchcom/gopass/password"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStore returns a store holding a few secrets
+func newStore(t *testing.T) (*password.RootStore, func()) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		cleanup()
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	done := func() {
+		_ = os.RemoveAll(tempdir)
+		cleanup()
+	}
+	if err := ioutil.WriteFile(filepath.Join(tempdir, ".gpg-id"), []byte(fpr+"\n"), 0600); err != nil {
+		done()
+		t.Fatalf("Failed to write .gpg-id: %s", err)
+	}
+	store, err := password.NewRootStore(tempdir)
+	if err != nil {
+		done()
+		t.Fatalf("Failed to open store: %s", err)
+	}
+	assert.NoError(t, store.Set("web/golang", []byte("secret\nuser: gopher\nurl: golang.org\nlogin: gopass://#user\nmailpw: gopass://mail\npassword: shadowed\n")))
+	assert.NoError(t, store.Set("mail", []byte("other")))
+	assert.NoError(t, store.SetSymmetric("sym", []byte("secret"), "passphrase"))
+	return store, done
+}
+
+// get sends a GET request with the given token and returns the status and
+// body of the response
+func get(t *testing.T, client *http.Client, url, token string) (int, string) {
+	req, err := http.NewRequest("GET", url, nil)
+	assert.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request to %s failed: %s", url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	buf, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, string(buf)
+}
+
+func TestHandler(t *testing.T) {
+	store, cleanup := newStore(t)
+	defer cleanup()
+
+	ts := httptest.NewServer(Handler(store, Token("sesame")))
+	defer ts.Close()
+	client := http.DefaultClient
+
+	status, body := get(t, client, ts.URL+"/ls", "sesame")
+	assert.Equal(t, http.StatusOK, status)
+	names := []string{}
+	assert.NoError(t, json.Unmarshal([]byte(body), &names))
+	assert.Equal(t, []string{"mail", "sym", "web/golang"}, names)
+
+	for path, want := range map[string]struct {
+		status int
+		body   string
+	}{
+		"/secret/web/golang":            {http.StatusOK, "secret"},
+		"/secret/web/golang?field=user": {http.StatusOK, "gopher"},
+		"/secret/web/golang?field=url":  {http.StatusOK, "golang.org"},
+		"/secret/web/golang?field=pin":  {http.StatusNotFound, "field not found\n"},
+		// references are resolved, password is always the first line
+		"/secret/web/golang?field=login":    {http.StatusOK, "gopher"},
+		"/secret/web/golang?field=mailpw":   {http.StatusOK, "other"},
+		"/secret/web/golang?field=password": {http.StatusOK, "secret"},
+		"/secret/mail":                      {http.StatusOK, "other"},
+		"/secret/web":                       {http.StatusNotFound, "not found\n"},
+		"/secret/missing":                   {http.StatusNotFound, "not found\n"},
+		"/secret/sym":                       {http.StatusForbidden, "passphrase-only secrets can't be read over the API\n"},
+		"/other":                            {http.StatusNotFound, "not found\n"},
+	} {
+		status, body := get(t, client, ts.URL+path, "sesame")
+		assert.Equal(t, want.status, status, path)
+		assert.Equal(t, want.body, body, path)
+	}
+
+	// a wrong or missing token is refused
+	status, body = get(t, client, ts.URL+"/secret/web/golang", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.NotContains(t, body, "secret")
+	status, _ = get(t, client, ts.URL+"/ls", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// the token must be sent as a bearer token
+	req, err := http.NewRequest("GET", ts.URL+"/ls", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "sesame")
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// only GET is supported
+	req, err = http.NewRequest("DELETE", ts.URL+"/secret/mail", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer sesame")
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// websites reaching the API through DNS rebinding send their own host
+	req, err = http.NewRequest("GET", ts.URL+"/ls", nil)
+	assert.NoError(t, err)
+	req.Host = "evil.example.com"
+	req.Header.Set("Authorization", "Bearer sesame")
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestHandlerRateLimit(t *testing.T) {
+	store, cleanup := newStore(t)
+	defer cleanup()
+
+	h := Handler(store, Token("sesame")).(*handler)
+	now := time.Now()
+	h.limiter = newLimiter(1, 2)
+	h.limiter.now = func() time.Time { return now }
+	h.limiter.last = now
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	// guessing the token counts as well
+	status, _ := get(t, http.DefaultClient, ts.URL+"/ls", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = get(t, http.DefaultClient, ts.URL+"/ls", "sesame")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = get(t, http.DefaultClient, ts.URL+"/ls", "sesame")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+
+	now = now.Add(time.Second)
+	status, _ = get(t, http.DefaultClient, ts.URL+"/ls", "sesame")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestUnixSocket(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("peer credentials are not supported on %s", runtime.GOOS)
+	}
+	store, cleanup := newStore(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	sock := filepath.Join(tempdir, "api.sock")
+
+	l, err := listen(unixPrefix+sock, PeerUser())
+	if err != nil {
+		t.Skipf("Failed to listen on a unix socket: %s", err)
+	}
+	defer func() {
+		_ = l.Close()
+	}()
+	go func() {
+		_ = http.Serve(l, Handler(store, PeerUser()))
+	}()
+	fi, err := os.Stat(sock)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// the socket exists already
+	_, err = listen(unixPrefix+sock, PeerUser())
+	assert.Error(t, err)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", sock)
+		},
+	}}
+	status, body := get(t, client, "http://gopass/secret/web/golang?field=user", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "gopher", body)
+}
+
+func TestPeerUserOverTCP(t *testing.T) {
+	_, err := listen("127.0.0.1:0", PeerUser())
+	assert.Error(t, err)
+
+	req := httptest.NewRequest("GET", "/ls", nil)
+	assert.Error(t, PeerUser().Authenticate(req))
+}
+
+func TestCheckLoopback(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"127.0.0.1:8421": true,
+		"[::1]:8421":     true,
+		"localhost:8421": true,
+		":8421":          false,
+		"0.0.0.0:8421":   false,
+		"192.0.2.1:8421": false,
+		"127.0.0.1":      false,
+	} {
+		err := checkLoopback(addr)
+		assert.Equal(t, ok, err == nil, addr)
+	}
+}
+
+func TestToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/ls", nil)
+	assert.Error(t, Token("sesame").Authenticate(req))
+	req.Header.Set("Authorization", "Bearer sesame")
+	assert.NoError(t, Token("sesame").Authenticate(req))
+	assert.Error(t, Token("sesam").Authenticate(req))
+	assert.Error(t, Token("").Authenticate(httptest.NewRequest("GET", "/ls", nil)))
+}
+
+func TestIsLocalHost(t *testing.T) {
+	for host, ok := range map[string]bool{
+		"127.0.0.1:8421":   true,
+		"localhost:8421":   true,
+		"[::1]:8421":       true,
+		"localhost":        true,
+		"example.com:8421": false,
+		"192.0.2.1":        false,
+	} {
+		assert.Equal(t, ok, isLocalHost(host), host)
+	}
+}
@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket limiting the requests of all clients. Refused
+// requests, e.g. with a wrong token, count as well, which slows down
+// guessing the token.
+type limiter struct {
+	mutex  sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // maximum number of tokens
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newLimiter returns a full bucket allowing rate requests per second and
+// bursts of up to burst requests
+func newLimiter(rate, burst int) *limiter {
+	return &limiter{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// allow takes a token from the bucket and returns false if it's empty
+func (l *limiter) allow() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if now.After(l.last) {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+	}
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
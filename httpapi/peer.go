@@ -0,0 +1,34 @@
+package httpapi
+
+import (
+	"net"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// peerListener only accepts unix socket connections from processes of the
+// given user. The others are closed right away.
+type peerListener struct {
+	*net.UnixListener
+	uid int
+}
+
+// Accept implements net.Listener
+func (l *peerListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.AcceptUnix()
+		if err != nil {
+			return nil, err
+		}
+		uid, err := peerUID(c)
+		if err == nil && uid == l.uid {
+			return c, nil
+		}
+		if err != nil {
+			log.Debugf("httpapi: refusing connection: %s", err)
+		} else {
+			log.Debugf("httpapi: refusing connection of uid %d", uid)
+		}
+		_ = c.Close()
+	}
+}
@@ -0,0 +1,23 @@
+package httpapi
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerUID returns the user of the process at the other end of a unix
+// socket connection
+func peerUID(c *net.UnixConn) (int, error) {
+	fh, err := c.File()
+	if err != nil {
+		return -1, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	cred, err := syscall.GetsockoptUcred(int(fh.Fd()), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	if err != nil {
+		return -1, err
+	}
+	return int(cred.Uid), nil
+}
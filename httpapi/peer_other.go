@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package httpapi
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// peerUID isn't supported on this platform, so all connections are refused
+func peerUID(c *net.UnixConn) (int, error) {
+	return -1, fmt.Errorf("peer credentials are not supported on %s", runtime.GOOS)
+}
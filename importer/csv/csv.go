@@ -0,0 +1,238 @@
+// Package csv imports the passwords exported by browsers as CSV files. The
+// columns are mapped by recognizing the header of the export, see Profiles.
+package csv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/justwatchcom/gopass/password"
+)
+
+// Profile names the columns of the export of a browser
+type Profile struct {
+	Name string
+	// Required are the columns identifying the export
+	Required []string
+	// URL, User, Password, Title and Note are the columns mapped to the
+	// secret. Title and Note are optional.
+	URL      string
+	User     string
+	Password string
+	Title    string
+	Note     string
+}
+
+// Profiles are the known exports, in the order they are detected
+var Profiles = []Profile{
+	{
+		Name:     "Chrome",
+		Required: []string{"name", "url", "username", "password"},
+		URL:      "url",
+		User:     "username",
+		Password: "password",
+		Title:    "name",
+		Note:     "note",
+	},
+	{
+		Name:     "Firefox",
+		Required: []string{"url", "username", "password", "httprealm", "formactionorigin", "guid"},
+		URL:      "url",
+		User:     "username",
+		Password: "password",
+	},
+}
+
+// Entry is a password of an export
+type Entry struct {
+	// Name is the name of the secret, derived from the hostname, see Names
+	Name     string
+	URL      string
+	User     string
+	Password string
+	Title    string
+	Note     string
+	// Line is the line of the entry in the export
+	Line int
+}
+
+// Content returns the content of the secret: the password followed by the
+// url and user and the note, if there is one
+func (e Entry) Content() []byte {
+	content := e.Password + "\n"
+	if e.URL != "" {
+		content += "url: " + e.URL + "\n"
+	}
+	if e.User != "" {
+		content += "user: " + e.User + "\n"
+	}
+	if e.Note == "" {
+		return []byte(content)
+	}
+	return password.SetNotes([]byte(content), e.Note)
+}
+
+// Detect returns the first profile whose required columns are all in the
+// header. Case and surrounding whitespace are ignored.
+func Detect(header []string) (Profile, error) {
+	cols := columns(header)
+	for _, p := range Profiles {
+		found := true
+		for _, c := range p.Required {
+			if _, ok := cols[c]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return p, nil
+		}
+	}
+	names := make([]string, 0, len(Profiles))
+	for _, p := range Profiles {
+		names = append(names, p.Name)
+	}
+	return Profile{}, fmt.Errorf("unknown CSV header %q. Supported are the exports of %s", strings.Join(header, ","), strings.Join(names, ", "))
+}
+
+// Parse reads an export, detecting its profile from the header. Entries
+// without a password are skipped. The names of the entries are set, see
+// Names.
+func Parse(r io.Reader) (Profile, []Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return Profile{}, nil, fmt.Errorf("failed to read the CSV header: %s", err)
+	}
+	if len(header) > 0 {
+		// Excel and others prefix the file with a byte order mark
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	p, err := Detect(header)
+	if err != nil {
+		return Profile{}, nil, err
+	}
+	cols := columns(header)
+
+	entries := make([]Entry, 0, 50)
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return p, nil, fmt.Errorf("failed to read the CSV: %s", err)
+		}
+		raw := func(col string) string {
+			i, ok := cols[col]
+			if col == "" || !ok || i >= len(rec) {
+				return ""
+			}
+			return rec[i]
+		}
+		field := func(col string) string {
+			return strings.TrimSpace(raw(col))
+		}
+		e := Entry{
+			URL:  field(p.URL),
+			User: field(p.User),
+			// whitespace may be part of the password
+			Password: raw(p.Password),
+			Title:    field(p.Title),
+			Note:     strings.TrimRight(field(p.Note), "\n"),
+			Line:     line,
+		}
+		if e.Password == "" {
+			continue
+		}
+		if strings.ContainsAny(e.Password, "\r\n") {
+			return p, nil, fmt.Errorf("line %d: the password must be a single line", line)
+		}
+		if strings.ContainsAny(e.URL+e.User, "\r\n") {
+			return p, nil, fmt.Errorf("line %d: the url and username must be a single line", line)
+		}
+		entries = append(entries, e)
+	}
+	Names(entries)
+	return p, entries, nil
+}
+
+// Names sets the names of the entries to the hostname of their URL, e.g.
+// accounts.example.com. Entries sharing a hostname are named after the
+// hostname and their user, e.g. example.com/gopher, and numbered if even
+// that isn't unique.
+func Names(entries []Entry) {
+	hosts := make([]string, len(entries))
+	count := make(map[string]int, len(entries))
+	for i, e := range entries {
+		hosts[i] = hostname(e)
+		count[hosts[i]]++
+	}
+	seen := make(map[string]int, len(entries))
+	for i, e := range entries {
+		name := hosts[i]
+		if count[name] > 1 {
+			user := cleanName(e.User)
+			if user == "" {
+				user = "unknown"
+			}
+			name += "/" + user
+		}
+		if n := seen[name]; n > 0 {
+			seen[name]++
+			entries[i].Name = name + "-" + strconv.Itoa(n+1)
+			continue
+		}
+		seen[name] = 1
+		entries[i].Name = name
+	}
+}
+
+// hostname returns the lowercase hostname of the URL of an entry, without
+// the port. It falls back to the title or "unknown".
+func hostname(e Entry) string {
+	raw := e.URL
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		host := u.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host = cleanName(strings.ToLower(strings.Trim(host, "[]"))); host != "" {
+			return host
+		}
+	}
+	if title := cleanName(e.Title); title != "" {
+		return title
+	}
+	return "unknown"
+}
+
+// cleanName makes a string usable as a single part of a secret name
+func cleanName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '\n', '\r', '\t':
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(s))
+	return strings.Trim(s, ".")
+}
+
+// columns maps the lowercase column names to their index
+func columns(header []string) map[string]int {
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	return cols
+}
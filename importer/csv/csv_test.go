@@ -0,0 +1,83 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChrome(t *testing.T) {
+	in := "\ufeffname,url,username,password,note\n" +
+		"example.com,https://example.com/login,gopher,hunter2,\n" +
+		"accounts.example.org,https://accounts.example.org:8443/,admin,s3cr3t,\"first\nsecond\"\n" +
+		"example.com,https://www.example.com/,,nouser,\n" +
+		"Example,https://example.com/,gopher,again,\n" +
+		"empty,https://empty.example.com/,nobody,,\n" +
+		"My App,android://hash@com.example.app/,app,apppass,\n"
+	p, entries, err := Parse(strings.NewReader(in))
+	assert.NoError(t, err)
+	assert.Equal(t, "Chrome", p.Name)
+	if !assert.Len(t, entries, 5) {
+		return
+	}
+
+	// duplicate hostnames are told apart by the user, and numbered if that
+	// isn't enough
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	assert.Equal(t, []string{"example.com/gopher", "accounts.example.org", "www.example.com", "example.com/gopher-2", "com.example.app"}, names)
+
+	assert.Equal(t, "hunter2\nurl: https://example.com/login\nuser: gopher\n", string(entries[0].Content()))
+	assert.Equal(t, "s3cr3t\nurl: https://accounts.example.org:8443/\nuser: admin\nnotes: |\n  first\n  second\n", string(entries[1].Content()))
+	assert.Equal(t, "nouser\nurl: https://www.example.com/\n", string(entries[2].Content()))
+	assert.Equal(t, 5, entries[3].Line)
+}
+
+func TestFirefox(t *testing.T) {
+	in := `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"
+"https://example.com","gopher","hunter2",,"https://example.com","{a}","1500000000000","1500000000000","1500000000000"
+"https://example.com","admin","s3cr3t",,"https://example.com","{b}","1500000000000","1500000000000","1500000000000"
+"http://localhost:8080","","local",,"http://localhost:8080","{c}","1500000000000","1500000000000","1500000000000"
+`
+	p, entries, err := Parse(strings.NewReader(in))
+	assert.NoError(t, err)
+	assert.Equal(t, "Firefox", p.Name)
+	if !assert.Len(t, entries, 3) {
+		return
+	}
+	assert.Equal(t, "example.com/gopher", entries[0].Name)
+	assert.Equal(t, "example.com/admin", entries[1].Name)
+	assert.Equal(t, "localhost", entries[2].Name)
+	assert.Equal(t, "s3cr3t\nurl: https://example.com\nuser: admin\n", string(entries[1].Content()))
+}
+
+func TestWhitespacePassword(t *testing.T) {
+	in := "name,url,username,password,note\n" +
+		"example.com, https://example.com/ , gopher , hunter2 ,\n" +
+		"blank,https://blank.example.com/,nobody,\" \",\n"
+	_, entries, err := Parse(strings.NewReader(in))
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 2) {
+		return
+	}
+	assert.Equal(t, " hunter2 ", entries[0].Password)
+	assert.Equal(t, "https://example.com/", entries[0].URL)
+	assert.Equal(t, "gopher", entries[0].User)
+	assert.Equal(t, " hunter2 \nurl: https://example.com/\nuser: gopher\n", string(entries[0].Content()))
+	assert.Equal(t, " ", entries[1].Password)
+}
+
+func TestDetect(t *testing.T) {
+	p, err := Detect([]string{" URL", "Username ", "Password", "httpRealm", "formActionOrigin", "guid"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Firefox", p.Name)
+
+	_, err = Detect([]string{"title", "login", "secret"})
+	assert.Error(t, err)
+
+	_, _, err = Parse(strings.NewReader("name,url,username,password\nfoo,https://foo.com,u,\"multi\nline\"\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package keyring
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// Keychain stores secrets as generic passwords in the default macOS
+// Keychain, using the security tool
+type Keychain struct{}
+
+// keychainNotFound is the exit code of security for missing items
+const keychainNotFound = 44
+
+// Get implements Backend
+func (Keychain) Get(key string) (string, error) {
+	cmd := exec.Command("security", "find-generic-password", "-s", Service, "-a", key, "-w")
+	out, err := cmd.Output()
+	if ee, ok := err.(*exec.ExitError); ok && exitCode(ee) == keychainNotFound {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read from the Keychain: %s", err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// Set implements Backend. The secret is handed to security on stdin, so it
+// doesn't show up in the process list.
+func (Keychain) Set(key, secret string) error {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", quote(Service), quote(key), quote(secret)))
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		// security echoes the failed command, including the password
+		return log.SanitizeError(fmt.Errorf("failed to write to the Keychain: %s: %s", err, strings.TrimSpace(stderr.String())), []byte(secret), []byte(quote(secret)))
+	}
+	return nil
+}
+
+// quote quotes an argument for the interactive mode of security, which
+// reads one command per line, so line breaks are escaped as well
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	s = strings.Replace(s, "\r", `\r`, -1)
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
@@ -0,0 +1,64 @@
+// Package keyring stores passphrases in the keyring of the operating
+// system, i.e. the macOS Keychain or a freedesktop Secret Service like
+// GNOME Keyring or KWallet. The platform tools are used, so no cgo or dbus
+// library is needed.
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+// Service is the name gopass stores its entries under
+const Service = "gopass"
+
+// ErrNotFound is returned if the keyring has no entry for a key
+var ErrNotFound = errors.New("no entry in the keyring")
+
+// Backend is a keyring holding one secret per key
+type Backend interface {
+	// Get returns the secret stored for the key or ErrNotFound
+	Get(key string) (string, error)
+	// Set stores the secret for the key, replacing an existing one
+	Set(key, secret string) error
+}
+
+// Default returns the keyring of the operating system
+func Default() (Backend, error) {
+	if runtime.GOOS == "darwin" {
+		return Keychain{}, nil
+	}
+	if _, err := exec.LookPath(secretTool); err != nil {
+		return nil, fmt.Errorf("no Secret Service client found, install %s (libsecret-tools)", secretTool)
+	}
+	return SecretService{}, nil
+}
+
+// Memory is a keyring only held in memory, e.g. for tests
+type Memory map[string]string
+
+// Get implements Backend
+func (m Memory) Get(key string) (string, error) {
+	secret, found := m[key]
+	if !found {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set implements Backend
+func (m Memory) Set(key, secret string) error {
+	m[key] = secret
+	return nil
+}
+
+// exitCode returns the exit code of a failed command
+func exitCode(ee *exec.ExitError) int {
+	if ws, ok := ee.Sys().(syscall.WaitStatus); ok {
+		return ws.ExitStatus()
+	}
+	return 1
+}
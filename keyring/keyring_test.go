@@ -0,0 +1,30 @@
+package keyring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	var kr Backend = Memory{}
+	_, err := kr.Get("gpg")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.NoError(t, kr.Set("gpg", "passphrase"))
+	pass, err := kr.Get("gpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", pass)
+
+	assert.NoError(t, kr.Set("gpg", "other"))
+	pass, err = kr.Get("gpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "other", pass)
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"gopass"`, quote("gopass"))
+	assert.Equal(t, `"pass phrase"`, quote("pass phrase"))
+	assert.Equal(t, `"a\"b\\c"`, quote(`a"b\c`))
+	assert.Equal(t, `"a\nb\r\\n"`, quote("a\nb\r\\n"))
+}
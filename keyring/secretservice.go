@@ -0,0 +1,46 @@
+package keyring
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// secretTool is the command line client of libsecret
+const secretTool = "secret-tool"
+
+// SecretService stores secrets with a freedesktop Secret Service, e.g.
+// GNOME Keyring or KWallet, using secret-tool
+type SecretService struct{}
+
+// Get implements Backend
+func (SecretService) Get(key string) (string, error) {
+	cmd := exec.Command(secretTool, "lookup", "service", Service, "account", key)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	// secret-tool exits with 1 and prints nothing for missing items
+	if ee, ok := err.(*exec.ExitError); ok && exitCode(ee) == 1 && len(out) < 1 && stderr.Len() < 1 {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", log.SanitizeError(fmt.Errorf("failed to read from the Secret Service: %s: %s", err, strings.TrimSpace(stderr.String())), out)
+	}
+	return string(out), nil
+}
+
+// Set implements Backend. The secret is handed to secret-tool on stdin, so
+// it doesn't show up in the process list.
+func (SecretService) Set(key, secret string) error {
+	cmd := exec.Command(secretTool, "store", "--label", Service+": "+key, "service", Service, "account", key)
+	cmd.Stdin = strings.NewReader(secret)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return log.SanitizeError(fmt.Errorf("failed to write to the Secret Service: %s: %s", err, strings.TrimSpace(stderr.String())), []byte(secret))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Package log provides a leveled logger writing to stderr. The level is read
+// from GOPASS_LOG_LEVEL and defaults to warn. Secrets must never be logged,
+// use Redact and RedactArgs for anything that may contain one.
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvLevel is the environment variable setting the log level
+const EnvLevel = "GOPASS_LOG_LEVEL"
+
+// Level is the severity of a log message
+type Level int
+
+// Log levels, messages below the current level are dropped
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String implements fmt.Stringer
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel parses the name of a log level, e.g. "debug"
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelWarn, fmt.Errorf("unknown log level %q", name)
+}
+
+var (
+	mutex  sync.Mutex
+	level            = levelFromEnv()
+	output io.Writer = os.Stderr
+	now              = time.Now
+)
+
+// levelFromEnv returns the level set in the environment. GOPASS_DEBUG=true
+// is kept as an alias for the debug level.
+func levelFromEnv() Level {
+	if l, err := ParseLevel(os.Getenv(EnvLevel)); err == nil {
+		return l
+	}
+	if os.Getenv("GOPASS_DEBUG") == "true" {
+		return LevelDebug
+	}
+	return LevelWarn
+}
+
+// SetLevel sets the minimum level of messages written
+func SetLevel(l Level) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	level = l
+}
+
+// GetLevel returns the minimum level of messages written
+func GetLevel() Level {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return level
+}
+
+// Enabled returns true if messages of the given level are written. Use it
+// to skip expensive preparations of debug messages.
+func Enabled(l Level) bool {
+	return l >= GetLevel()
+}
+
+// Debugf logs details useful for debugging, e.g. the commands run
+func Debugf(format string, args ...interface{}) {
+	logf(LevelDebug, format, args...)
+}
+
+// Infof logs what gopass is doing
+func Infof(format string, args ...interface{}) {
+	logf(LevelInfo, format, args...)
+}
+
+// Warnf logs problems gopass can recover from
+func Warnf(format string, args ...interface{}) {
+	logf(LevelWarn, format, args...)
+}
+
+// Errorf logs problems gopass can't recover from
+func Errorf(format string, args ...interface{}) {
+	logf(LevelError, format, args...)
+}
+
+func logf(l Level, format string, args ...interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if l < level {
+		return
+	}
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	_, _ = fmt.Fprintf(output, "%s %-5s %s\n", now().Format("2006-01-02T15:04:05.000"), strings.ToUpper(l.String()), msg)
+}
@@ -72,6 +72,10 @@ func main() {
 	}
 
 	app.Action = func(c *cli.Context) error {
+		// unknown subcommands are left to plugins, like git-<name> for git
+		if path, ok := action.ResolvePlugin(c.Args().First()); ok {
+			return action.Plugin(c, path)
+		}
 		if err := action.Initialized(c); err != nil {
 			return err
 		}
@@ -87,9 +91,151 @@ func main() {
 			Name:  "clip, c",
 			Usage: "Copy the secret into the clipboard",
 		},
+		cli.BoolTFlag{
+			Name:  "verify",
+			Usage: "Read back the clipboard to verify the copy succeeded",
+		},
+		cli.BoolFlag{
+			Name:  "wait",
+			Usage: "Wait in the foreground until the clipboard is cleared",
+		},
+		cli.BoolFlag{
+			Name:  "queue",
+			Usage: "Copy the given fields one after another, advancing with gopass next",
+		},
+		cli.BoolFlag{
+			Name:  "strict",
+			Usage: "Fail instead of only warning if the secret has expired",
+		},
+		cli.BoolFlag{
+			Name:  "raw, o",
+			Usage: "Print the secret as it is stored, without pretty-printing JSON or YAML",
+		},
+		cli.BoolFlag{
+			Name:  "no-newline, n",
+			Usage: "Don't append a newline to the secret",
+		},
+		cli.StringFlag{
+			Name:  "profile",
+			Usage: "Use the gpg home, key, store and git identity of this profile",
+		},
+		cli.StringFlag{
+			Name:  "color",
+			Usage: "Color the output: auto (if stdout is a terminal), always or never",
+		},
+		cli.BoolFlag{
+			Name:  "no-color",
+			Usage: "Don't color the output, same as --color=never",
+		},
+	}
+	app.Before = func(c *cli.Context) error {
+		if err := action.UseColor(c); err != nil {
+			return err
+		}
+		return action.UseProfile(c)
 	}
 
 	app.Commands = []cli.Command{
+		{
+			Name:         "acl",
+			Usage:        "Show who can decrypt a secret",
+			Description:  "List the recipients of a secret and check that it's encrypted for exactly those keys",
+			Before:       action.Initialized,
+			Action:       action.ACL,
+			BashComplete: action.Complete,
+		},
+		{
+			Name:  "alias",
+			Usage: "Create an alias that refers to an existing secret",
+			Description: "" +
+				"Create an alias that refers to an existing secret. " +
+				"Showing the alias will show the secret it refers to.",
+			Before:       action.Initialized,
+			Action:       action.Alias,
+			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Overwrite any existing secret",
+				},
+			},
+		},
+		{
+			Name:         "attachments",
+			Usage:        "List the attachments of a secret",
+			Description:  "Secrets may have any number of named attachments, encrypted for the same recipients",
+			Before:       action.Initialized,
+			Action:       action.AttachmentsList,
+			BashComplete: action.Complete,
+			Subcommands: []cli.Command{
+				{
+					Name:         "add",
+					Usage:        "Add a file as an attachment to a secret",
+					Description:  "Encrypt a file and store it as an attachment of an existing secret",
+					Before:       action.Initialized,
+					Action:       action.AttachmentsAdd,
+					BashComplete: action.Complete,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "name",
+							Usage: "Name of the attachment, defaults to the name of the file",
+						},
+						cli.BoolFlag{
+							Name:  "no-compress",
+							Usage: "Encrypt without compression, regardless of compressalgo",
+						},
+					},
+				},
+				{
+					Name:         "show",
+					Usage:        "Write an attachment to stdout",
+					Description:  "Decrypt an attachment and write it to stdout",
+					Before:       action.Initialized,
+					Action:       action.AttachmentsShow,
+					BashComplete: action.Complete,
+				},
+			},
+		},
+		{
+			Name:  "audit",
+			Usage: "Review who has access to the secrets",
+			Subcommands: []cli.Command{
+				{
+					Name:  "key",
+					Usage: "List every secret a key can decrypt",
+					Description: "" +
+						"List every secret of all mounts encrypted for the given key or one of its subkeys. " +
+						"The recipients are read from the ciphertexts, nothing is decrypted. " +
+						"Secrets with hidden recipients are listed as possibly readable.",
+					Before:       action.Initialized,
+					Action:       action.AuditKey,
+					BashComplete: action.RecipientsComplete,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "json",
+							Usage: "Print the secrets as JSON",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "cat",
+			Usage: "Write the exact content of a secret to stdout",
+			Description: "" +
+				"Writes the decrypted content of a secret as it is, without any formatting or trailing newline. " +
+				"This is meant for binary secrets like certificates, keys or images. " +
+				"Binary content is not written to a terminal unless forced.",
+			Before:       action.Initialized,
+			Action:       action.CatSecret,
+			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Write binary content to a terminal anyway",
+				},
+			},
+		},
 		{
 			Name:        "clone",
 			Usage:       "Clone a new store",
@@ -100,6 +246,14 @@ func main() {
 					Name:  "path",
 					Usage: "Path to clone the repo to",
 				},
+				cli.BoolFlag{
+					Name:  "allow-nesting",
+					Usage: "Allow the store to be inside of another store or to contain one",
+				},
+				cli.BoolFlag{
+					Name:  "diff-driver",
+					Usage: "Make git diff decrypt secrets, see git diff-driver",
+				},
 			},
 		},
 		{
@@ -153,12 +307,57 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:  "diff",
+			Usage: "List secrets that differ between two mounts",
+			Description: "" +
+				"Compares the secrets of two mounts, use an empty name for the root store. " +
+				"Secrets present in both are compared by their ciphertext, or with --deep by their content.",
+			Before: action.Initialized,
+			Action: action.Diff,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "deep",
+					Usage: "Decrypt secrets with a different ciphertext and compare their content",
+				},
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Do not ask before decrypting",
+				},
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "Print the differences as JSON",
+				},
+			},
+		},
 		{
 			Name:         "edit",
 			Usage:        "Insert a new secret or edit an existing secret using $EDITOR.",
 			Before:       action.Initialized,
 			Action:       action.Edit,
 			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "edit-alias",
+					Usage: "Edit an alias itself instead of the secret it refers to",
+				},
+				cli.BoolFlag{
+					Name:  "no-reason",
+					Usage: "Don't ask for the reason of the change recorded in the changelog",
+				},
+				cli.BoolFlag{
+					Name:  "allow-expired",
+					Usage: "Allow encrypting for recipients whose key has expired",
+				},
+				cli.BoolFlag{
+					Name:  "no-compress",
+					Usage: "Encrypt without compression, regardless of compressalgo",
+				},
+				cli.StringSliceFlag{
+					Name:  "tag",
+					Usage: "Tag the secret, may be given more than once",
+				},
+			},
 		},
 		{
 			Name:         "find",
@@ -167,21 +366,53 @@ func main() {
 			Action:       action.Find,
 			Aliases:      []string{"search"},
 			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "porcelain",
+					Usage: "Print one name per line, sorted and undecorated, in a format that stays stable across versions",
+				},
+				cli.BoolFlag{
+					Name:  "null, z",
+					Usage: "With --porcelain, end every name with a NUL byte instead of a newline",
+				},
+			},
+		},
+		{
+			Name:        "fix-gpg-id",
+			Usage:       "Remove stale recipients",
+			Description: "Remove the recipients whose key is missing from the keyring, has been revoked or has expired from the .gpg-id of the store and the recipient overrides below the prefix, and re-encrypt the secrets affected. A .gpg-id is never emptied.",
+			Before:      action.Initialized,
+			Action:      action.FixGPGID,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Do not ask for confirmation",
+				},
+			},
+			BashComplete: action.Complete,
 		},
 		{
 			Name:        "fsck",
 			Usage:       "Check store integrity",
-			Description: "Check integrity of all stores",
+			Description: "Check integrity of all stores. Reports undecryptable secrets, missing public keys, secrets encrypted for the wrong recipients, orphaned attachments and wrong permissions. With --repair the problems are fixed after confirmation.",
 			Before:      action.Initialized,
 			Action:      action.Fsck,
 			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "repair, r",
+					Usage: "Fix the problems found after confirmation",
+				},
 				cli.BoolFlag{
 					Name:  "check, c",
-					Usage: "Only report",
+					Usage: "Only report, the default",
 				},
 				cli.BoolFlag{
 					Name:  "force, f",
-					Usage: "Auto-correct any errors, do not ask",
+					Usage: "Repair any errors, do not ask",
+				},
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "Print the report as JSON",
 				},
 			},
 		},
@@ -190,7 +421,7 @@ func main() {
 			Usage: "Generate a new password of the specified length with optionally no symbols.",
 			Description: "" +
 				"Generate a new password of the specified length with optionally no symbols. " +
-				"Optionally put it on the clipboard and clear board after 45 seconds. " +
+				"The password is put on the clipboard and the clipboard is cleared after 45 seconds. " +
 				"Prompt before overwriting existing password unless forced. " +
 				"Optionally replace only the first line of an existing file with a new password.",
 			Before:       action.Initialized,
@@ -198,8 +429,21 @@ func main() {
 			BashComplete: action.Complete,
 			Flags: []cli.Flag{
 				cli.BoolFlag{
-					Name:  "clip, c",
-					Usage: "Copy the password into the clipboard",
+					Name:   "clip, c",
+					Usage:  "Copy the password into the clipboard. This is the default now",
+					Hidden: true,
+				},
+				cli.BoolFlag{
+					Name:  "print, p",
+					Usage: "Also print the password if stdout is a terminal",
+				},
+				cli.BoolTFlag{
+					Name:  "verify",
+					Usage: "Read back the clipboard to verify the copy succeeded",
+				},
+				cli.BoolFlag{
+					Name:  "wait",
+					Usage: "Wait in the foreground until the clipboard is cleared",
 				},
 				cli.BoolFlag{
 					Name:  "force, f",
@@ -209,6 +453,26 @@ func main() {
 					Name:  "no-symbols, n",
 					Usage: "Don't use symbols in the password",
 				},
+				cli.BoolFlag{
+					Name:  "symmetric",
+					Usage: "Encrypt the password with a passphrase instead of the recipients",
+				},
+				cli.BoolFlag{
+					Name:  "deterministic",
+					Usage: "Derive the password from a master seed and the name instead of generating a random one",
+				},
+				cli.BoolFlag{
+					Name:  "no-template",
+					Usage: "Don't apply the template of the directory",
+				},
+				cli.StringFlag{
+					Name:  "password-rules",
+					Usage: "Password rules, e.g. 'minlength: 8; required: digit', stored with the secret and reused on regeneration",
+				},
+				cli.BoolFlag{
+					Name:  "allow-expired",
+					Usage: "Allow encrypting for recipients whose key has expired",
+				},
 			},
 		},
 		{
@@ -239,15 +503,104 @@ func main() {
 							Name:  "sign-key",
 							Usage: "GPG Key to sign commits",
 						},
+						cli.BoolFlag{
+							Name:  "diff-driver",
+							Usage: "Make git diff decrypt secrets, see git diff-driver",
+						},
+					},
+				},
+				{
+					Name:  "diff-driver",
+					Usage: "Make git diff show the plaintext of secrets",
+					Description: "" +
+						"Configure the local git repo of the store to decrypt secrets with gopass git-textconv " +
+						"when diffing them. This decrypts secrets on every git diff, log -p or show.",
+					Before: action.Initialized,
+					Action: action.GitDiffDriver,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "store",
+							Usage: "Store to operate on",
+						},
 					},
 				},
 			},
 		},
+		{
+			Name:   "git-textconv",
+			Usage:  "Decrypt a file for git diff",
+			Hidden: true,
+			Action: action.GitTextconv,
+		},
 		{
 			Name:   "grep",
 			Usage:  "Search for secrets files containing search-string when decrypted.",
 			Before: action.Initialized,
 			Action: action.Grep,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "regexp, r",
+					Usage: "Interpret the search-string as a regular expression",
+				},
+				cli.BoolFlag{
+					Name:  "ignore-case, i",
+					Usage: "Ignore case when searching",
+				},
+				cli.BoolFlag{
+					Name:  "unmask",
+					Usage: "Print matching passwords (first line) instead of masking them",
+				},
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Do not ask before decrypting all secrets",
+				},
+			},
+		},
+		{
+			Name:  "import",
+			Usage: "Import passwords from other password managers",
+			Subcommands: []cli.Command{
+				{
+					Name:  "csv",
+					Usage: "Import the passwords of a CSV export of Chrome or Firefox",
+					Description: "" +
+						"The columns are recognized by the header of the export. Every password is written to a secret named " +
+						"after the hostname of its URL, with the url and user below it. Passwords sharing a hostname are " +
+						"named after the hostname and their user, e.g. example.com/gopher.",
+					Before: action.Initialized,
+					Action: action.ImportCSV,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "folder",
+							Usage: "Import below this folder, e.g. browser",
+						},
+						cli.BoolFlag{
+							Name:  "force, f",
+							Usage: "Replace existing secrets",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "index",
+			Usage: "Build encrypted search indexes for grep",
+			Description: "" +
+				"Decrypts all secrets once and writes an index of the words they contain, " +
+				"encrypted for the recipients of each store. grep then only decrypts the " +
+				"index and the matching secrets. The indexes are updated on every change.",
+			Before: action.Initialized,
+			Action: action.Index,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "remove",
+					Usage: "Remove the search indexes",
+				},
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Do not ask before decrypting all secrets",
+				},
+			},
 		},
 		{
 			Name:  "init",
@@ -264,6 +617,14 @@ func main() {
 					Name:  "nogit",
 					Usage: "Do not init git repo",
 				},
+				cli.StringFlag{
+					Name:  "recipients-from",
+					Usage: "Read the recipients from a file with one key ID per line",
+				},
+				cli.BoolFlag{
+					Name:  "allow-nesting",
+					Usage: "Allow the store to be inside of another store or to contain one",
+				},
 			},
 		},
 		{
@@ -289,6 +650,70 @@ func main() {
 					Name:  "force, f",
 					Usage: "Overwrite any existing secret",
 				},
+				cli.BoolFlag{
+					Name:  "allow-large",
+					Usage: "Store secrets larger than maxsecretsize without asking",
+				},
+				cli.StringFlag{
+					Name:  "recipients-from",
+					Usage: "Encrypt for the recipients listed in this file instead of the store recipients",
+				},
+				cli.StringFlag{
+					Name:  "template-from",
+					Usage: "Copy the metadata of this secret, with a new password",
+				},
+				cli.BoolFlag{
+					Name:  "no-template",
+					Usage: "Don't apply the template of the directory",
+				},
+				cli.BoolFlag{
+					Name:  "symmetric",
+					Usage: "Encrypt the secret with a passphrase instead of the recipients",
+				},
+				cli.BoolFlag{
+					Name:  "expires",
+					Usage: "Ask when the secret expires",
+				},
+				cli.StringSliceFlag{
+					Name:  "tag",
+					Usage: "Tag the secret, may be given more than once",
+				},
+				cli.StringFlag{
+					Name:  "note",
+					Usage: "Add a note to the secret",
+				},
+				cli.BoolFlag{
+					Name:  "allow-expired",
+					Usage: "Allow encrypting for recipients whose key has expired",
+				},
+				cli.BoolFlag{
+					Name:  "no-compress",
+					Usage: "Encrypt without compression, regardless of compressalgo",
+				},
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "Insert all secrets defined in this YAML or JSON manifest",
+				},
+			},
+		},
+		{
+			Name:        "keys",
+			Usage:       "Manage recipient keys",
+			Description: "Manage the public keys of the recipients of all stores",
+			Subcommands: []cli.Command{
+				{
+					Name:        "refresh",
+					Usage:       "Refresh recipient keys from the keyserver",
+					Description: "Fetch all recipient keys of all stores from the keyserver and report new expiration dates, subkeys and revocations",
+					Before:      action.Initialized,
+					Action:      action.KeysRefresh,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "keyserver",
+							Usage: "Keyserver to use instead of the configured one",
+						},
+					},
+				},
 			},
 		},
 		{
@@ -298,6 +723,66 @@ func main() {
 			Before:       action.Initialized,
 			Action:       action.List,
 			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "aliases",
+					Usage: "Mark aliases, this requires decrypting every secret",
+				},
+				cli.StringFlag{
+					Name:  "sort",
+					Usage: "Print a flat list sorted by name or mtime (last change first)",
+				},
+				cli.StringFlag{
+					Name:  "tag",
+					Usage: "Print a flat list of the secrets with this tag",
+				},
+				cli.StringFlag{
+					Name:  "recipient",
+					Usage: "Print a flat list of the secrets encrypted for this key according to their .gpg-id",
+				},
+				cli.BoolFlag{
+					Name:  "verify",
+					Usage: "With --recipient, list the recipients of the ciphertexts instead and report where they differ",
+				},
+				cli.BoolFlag{
+					Name:  "porcelain",
+					Usage: "Print one name per line, sorted and undecorated, in a format that stays stable across versions",
+				},
+				cli.BoolFlag{
+					Name:  "null, z",
+					Usage: "With --porcelain, end every name with a NUL byte instead of a newline",
+				},
+			},
+		},
+		{
+			Name:  "migrate",
+			Usage: "Reorganize stores",
+			Subcommands: []cli.Command{
+				{
+					Name:  "split",
+					Usage: "Move a folder into a new store mounted in its place",
+					Description: "" +
+						"Moves all secrets below the prefix, with their attachments and recipient overrides, " +
+						"into a new store at the path and mounts it at the prefix. A new store is initialized " +
+						"for the recipients of the origin, secrets of an existing store with other recipients " +
+						"are re-encrypted. Files existing in the store at the path are only overwritten once " +
+						"confirmed. If the origin is a git repository, so is the new store and the " +
+						"history of the folder is carried over if possible.",
+					Before:       action.Initialized,
+					Action:       action.MigrateSplit,
+					BashComplete: action.Complete,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "keep",
+							Usage: "Keep the secrets in the origin, hidden by the new mount",
+						},
+						cli.BoolFlag{
+							Name:  "force, f",
+							Usage: "Don't ask for confirmation, overwriting existing files",
+						},
+					},
+				},
+			},
 		},
 		{
 			Name:         "move",
@@ -331,6 +816,10 @@ func main() {
 							Name:  "init, i",
 							Usage: "Init the store with the given recpient key",
 						},
+						cli.BoolFlag{
+							Name:  "allow-nesting",
+							Usage: "Allow the store to be inside of another store or to contain one",
+						},
 					},
 				},
 				{
@@ -343,12 +832,135 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:  "next",
+			Usage: "Copy the next queued field to the clipboard",
+			Description: "" +
+				"Copies the next field queued with `gopass show --queue <name> [<field>...]` to the clipboard. " +
+				"The queue is kept per terminal session and expires with the clipboard timeout.",
+			Before: action.Initialized,
+			Action: action.Next,
+			Flags: []cli.Flag{
+				cli.BoolTFlag{
+					Name:  "verify",
+					Usage: "Read back the clipboard to verify the copy succeeded",
+				},
+			},
+		},
+		{
+			Name:  "notes",
+			Usage: "Show the notes of a secret",
+			Description: "" +
+				"Secrets may have free form notes below the password and other fields. " +
+				"Notes can span multiple lines and keep blank lines.",
+			Before:       action.Initialized,
+			Action:       action.Notes,
+			BashComplete: action.Complete,
+			Subcommands: []cli.Command{
+				{
+					Name:  "append",
+					Usage: "Append a note to a secret",
+					Description: "" +
+						"Append a note to the notes of a secret, keeping its password and all other fields. " +
+						"The note is read from stdin unless it's given as arguments.",
+					Before:       action.Initialized,
+					Action:       action.NotesAppend,
+					BashComplete: action.Complete,
+				},
+			},
+		},
+		{
+			Name:  "otp",
+			Usage: "Print the current TOTP code of a secret",
+			Description: "" +
+				"Compute the time based one-time password from the otpauth:// URL or the totp: line of a secret. " +
+				"With --watch it's shown along with the time until it rotates, until Ctrl-C.",
+			Before:       action.Initialized,
+			Action:       action.OTP,
+			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "watch, w",
+					Usage: "Keep showing the current code with the time left until it rotates",
+				},
+			},
+		},
+		{
+			Name:  "profile",
+			Usage: "Manage profiles",
+			Description: "" +
+				"Profiles bundle a gpg home, a default key, a store and a git identity, e.g. for work and personal use. " +
+				"Select one with --profile or GOPASS_PROFILE, or set a default.",
+			Action: action.Profiles,
+			Subcommands: []cli.Command{
+				{
+					Name:   "add",
+					Usage:  "Add or replace a profile",
+					Action: action.ProfileAdd,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "gnupghome",
+							Usage: "The gpg home directory holding the keyrings",
+						},
+						cli.StringFlag{
+							Name:  "key",
+							Usage: "The key used for signing and for initializing stores",
+						},
+						cli.StringFlag{
+							Name:  "path",
+							Usage: "The root of the password store",
+						},
+						cli.StringFlag{
+							Name:  "git-name",
+							Usage: "The name of the author of git commits",
+						},
+						cli.StringFlag{
+							Name:  "git-email",
+							Usage: "The email of the author of git commits",
+						},
+						cli.BoolFlag{
+							Name:  "default",
+							Usage: "Use this profile unless another one is selected",
+						},
+					},
+				},
+				{
+					Name:   "remove",
+					Usage:  "Remove a profile",
+					Action: action.ProfileRemove,
+				},
+				{
+					Name:   "default",
+					Usage:  "Set the profile used unless another one is selected",
+					Action: action.ProfileDefault,
+				},
+			},
+		},
+		{
+			Name:        "prune",
+			Usage:       "Remove expired secrets",
+			Description: "Remove all secrets whose expires_at date has passed. This decrypts all secrets",
+			Before:      action.Initialized,
+			Action:      action.PruneExpired,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Do not ask before removing the expired secrets",
+				},
+			},
+		},
 		{
 			Name:        "recipients",
 			Usage:       "List Recipients",
-			Description: "To show all recipients",
+			Description: "To show all recipients, grouped by store",
 			Before:      action.Initialized,
 			Action:      action.RecipientsPrint,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "Print the recipients and their keys as JSON",
+				},
+			},
 			Subcommands: []cli.Command{
 				{
 					Name:        "add",
@@ -361,6 +973,14 @@ func main() {
 							Name:  "store",
 							Usage: "Store to operate on",
 						},
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "Add the recipients to every store",
+						},
+						cli.BoolFlag{
+							Name:  "dry-run",
+							Usage: "Only show what adding the recipients would do",
+						},
 					},
 				},
 				{
@@ -370,6 +990,35 @@ func main() {
 					Before:       action.Initialized,
 					Action:       action.RecipientsRemove,
 					BashComplete: action.RecipientsComplete,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "store",
+							Usage: "Store to operate on",
+						},
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "Remove the recipients from every store",
+						},
+						cli.BoolFlag{
+							Name:  "drop-required",
+							Usage: "Allow removing required recipients, they are no longer required afterwards",
+						},
+						cli.BoolFlag{
+							Name:  "dry-run",
+							Usage: "Only show what removing the recipients would do",
+						},
+					},
+				},
+				{
+					Name:  "pin",
+					Usage: "Pin recipients to the fingerprint of their key",
+					Description: "" +
+						"Record the fingerprint of the key of each recipient, or of every recipient of the store, in your config. " +
+						"gopass then refuses to encrypt for or import any other key for that recipient, e.g. one substituted " +
+						"by a keyserver.",
+					Before:       action.Initialized,
+					Action:       action.RecipientsPin,
+					BashComplete: action.RecipientsComplete,
 					Flags: []cli.Flag{
 						cli.StringFlag{
 							Name:  "store",
@@ -377,6 +1026,158 @@ func main() {
 						},
 					},
 				},
+				{
+					Name:  "require",
+					Usage: "Make recipients required recipients of the store",
+					Description: "" +
+						"Every secret of the store is encrypted for required recipients, e.g. a break-glass recovery key, " +
+						"and they can only be removed with recipients remove --drop-required.",
+					Before:       action.Initialized,
+					Action:       action.RecipientsRequire,
+					BashComplete: action.RecipientsComplete,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "store",
+							Usage: "Store to operate on",
+						},
+					},
+				},
+				{
+					Name:         "override",
+					Usage:        "Show or set the recipients of a single secret",
+					Description:  "Encrypt a single secret only for the given recipients instead of the recipients of its store",
+					Before:       action.Initialized,
+					Action:       action.RecipientsOverride,
+					BashComplete: action.Complete,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "clear",
+							Usage: "Remove the override and use the recipients of the store again",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "reencrypt",
+			Usage: "Re-encrypt secrets for the current keys of their recipients",
+			Description: "" +
+				"Decrypts a secret, or all secrets below a folder, and encrypts them again for their recipients. " +
+				"The content doesn't change. Use this after a recipient rotated its encryption subkey. " +
+				"Without a name the whole root store is re-encrypted.",
+			Before:       action.Initialized,
+			Action:       action.Reencrypt,
+			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "allow-expired",
+					Usage: "Allow encrypting for recipients whose key has expired",
+				},
+			},
+		},
+		{
+			Name:  "replace",
+			Usage: "Replace the value of a field in many secrets",
+			Description: "" +
+				"Replaces old with new in the given field of every secret below the prefix, or of all stores, " +
+				"e.g. when a service moves to another domain. A value matches if it contains old, or with --exact " +
+				"only if it is old. Other fields and passwords are never changed. The secrets changed are listed " +
+				"and confirmed first.",
+			Before:       action.Initialized,
+			Action:       action.Replace,
+			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "field",
+					Usage: "The field to replace the value of, e.g. url",
+				},
+				cli.BoolFlag{
+					Name:  "exact",
+					Usage: "Only replace values equal to old, not the values containing it",
+				},
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Do not ask for confirmation",
+				},
+			},
+		},
+		{
+			Name:  "rotate",
+			Usage: "Replace the password of a secret, keeping the old one in its history",
+			Description: "" +
+				"Generate a new password for an existing secret and copy it to the clipboard. The old password " +
+				"is printed once if stdout is a terminal, so it can be changed on the service, and kept in the " +
+				"previous_passwords of the secret. The password rules of the secret are reused.",
+			Before:       action.Initialized,
+			Action:       action.Rotate,
+			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "keep",
+					Usage: "Number of previous passwords to keep",
+					Value: 3,
+				},
+				cli.BoolFlag{
+					Name:  "prune",
+					Usage: "Remove the previous passwords instead of rotating",
+				},
+				cli.BoolTFlag{
+					Name:  "verify",
+					Usage: "Read back the clipboard to verify the copy succeeded",
+				},
+				cli.BoolFlag{
+					Name:  "wait",
+					Usage: "Wait in the foreground until the clipboard is cleared",
+				},
+				cli.BoolFlag{
+					Name:  "no-symbols, n",
+					Usage: "Don't use symbols in the password",
+				},
+				cli.StringFlag{
+					Name:  "password-rules",
+					Usage: "Password rules for the new password, stored with the secret",
+				},
+				cli.BoolFlag{
+					Name:  "allow-expired",
+					Usage: "Allow encrypting for recipients whose key has expired",
+				},
+			},
+		},
+		{
+			Name:  "serve",
+			Usage: "Serve secrets to local tools over HTTP",
+			Description: "" +
+				"Serves GET /ls and GET /secret/<name>[?field=<key>] on a loopback address or a unix socket (unix:<path>). " +
+				"Unix sockets only accept connections of your user. TCP clients must send the token from " +
+				"GOPASS_API_TOKEN, or the one printed on startup, as 'Authorization: Bearer <token>'.",
+			Before: action.Initialized,
+			Action: action.Serve,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "addr",
+					Value: "127.0.0.1:8421",
+					Usage: "Loopback address or unix:<path> to listen on",
+				},
+			},
+		},
+		{
+			Name:  "share",
+			Usage: "Write a copy of a secret encrypted for other recipients",
+			Description: "" +
+				"Write a copy of a secret encrypted only for the given recipients, e.g. to send it to someone " +
+				"without access to the store. The recipients are not added to the store.",
+			Before:       action.Initialized,
+			Action:       action.Share,
+			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "output, o",
+					Usage: "Write the copy to this file instead of a temporary one, - for stdout",
+				},
+				cli.StringFlag{
+					Name:  "expire",
+					Usage: "Create a signed share that unshare refuses to open after this date or duration, e.g. 24h",
+				},
 			},
 		},
 		{
@@ -393,19 +1194,145 @@ func main() {
 					Name:  "clip, c",
 					Usage: "Copy the secret into the clipboard",
 				},
+				cli.BoolTFlag{
+					Name:  "verify",
+					Usage: "Read back the clipboard to verify the copy succeeded",
+				},
+				cli.BoolFlag{
+					Name:  "wait",
+					Usage: "Wait in the foreground until the clipboard is cleared",
+				},
+				cli.BoolFlag{
+					Name:  "queue",
+					Usage: "Copy the given fields one after another, advancing with gopass next",
+				},
+				cli.BoolFlag{
+					Name:  "strict",
+					Usage: "Fail instead of only warning if the secret has expired",
+				},
+				cli.BoolFlag{
+					Name:  "raw, o",
+					Usage: "Print the secret as it is stored, without pretty-printing JSON or YAML or resolving field references",
+				},
+				cli.BoolFlag{
+					Name:  "no-newline, n",
+					Usage: "Don't append a newline to the secret",
+				},
+				cli.BoolFlag{
+					Name:  "password",
+					Usage: "Print only the password",
+				},
+				cli.BoolFlag{
+					Name:  "safe",
+					Usage: "Print everything but the password unless --password or --clip is given",
+				},
+				cli.BoolFlag{
+					Name:  "stats",
+					Usage: "Print the length and entropy of the password and whether other secrets use it, on a terminal only",
+				},
+				cli.BoolFlag{
+					Name:  "reused",
+					Usage: "With --stats, list the other secrets using the same password",
+				},
+				cli.StringFlag{
+					Name:  "as",
+					Usage: "Only report whether the secret decrypts with the given secret key, which must be in your keyring",
+				},
+				cli.IntFlag{
+					Name:  "reveal",
+					Usage: "Print the password and blank it again after this many seconds, on a terminal only",
+				},
 			},
 		},
 		{
-			Name:        "unclip",
-			Usage:       "Internal command to clear clipboard",
-			Description: "Clear the clipboard if the content matches the checksum",
-			Action:      action.Unclip,
-			Hidden:      true,
+			Name:  "sign",
+			Usage: "Create a detached signature of a secret",
+			Description: "" +
+				"Sign the content of a secret with your key, e.g. to prove you wrote it. The signature " +
+				"is ASCII armored and doesn't contain the secret. The secret is only decrypted in memory.",
+			Before:       action.Initialized,
+			Action:       action.Sign,
+			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "output, o",
+					Usage: "Write the signature to this file instead of stdout",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "Sign with this key instead of the signkey of the config",
+				},
+			},
+		},
+		{
+			Name:         "trust",
+			Usage:        "Set the ownertrust of a recipients public key",
+			Description:  "Set the ownertrust of a public key to one of unknown, never, marginal, full or ultimate",
+			Action:       action.Trust,
+			BashComplete: action.RecipientsComplete,
+		},
+		{
+			Name:        "ui",
+			Usage:       "Browse secrets interactively",
+			Description: "Browse, filter and copy secrets in an interactive terminal UI",
+			Before:      action.Initialized,
+			Action:      action.UI,
+		},
+		{
+			Name:  "unclip",
+			Usage: "Clear the clipboard if it holds a copy of gopass",
+			Description: "" +
+				"Used internally to clear the clipboard after the timeout, if the content matches the checksum. " +
+				"With --force the clipboard is cleared right away if it holds anything gopass copied recently, " +
+				"e.g. after several fields were copied one after another.",
+			Action: action.Unclip,
 			Flags: []cli.Flag{
 				cli.IntFlag{
 					Name:  "timeout",
 					Usage: "Time to wait",
 				},
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Clear any recent copy of gopass right away",
+				},
+			},
+		},
+		{
+			Name:        "unshare",
+			Usage:       "Open a share created with share --expire",
+			Description: "Verify the signature of a share and print the secret, unless the share has expired",
+			Action:      action.Unshare,
+		},
+		{
+			Name:  "verify",
+			Usage: "Verify a detached signature of a secret, or that all secrets decrypt",
+			Description: "" +
+				"Check a signature created with sign against the current content of a secret and print the signer. " +
+				"With --all every secret of all stores is decrypted with your keys and those that fail are listed. " +
+				"No plaintext is printed.",
+			Before:       action.Initialized,
+			Action:       action.Verify,
+			BashComplete: action.Complete,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "Check that every secret can be decrypted instead of a signature",
+				},
+			},
+		},
+		{
+			Name:  "whoami",
+			Usage: "Show the gpg home, key and profile gopass uses",
+			Description: "" +
+				"Print the effective GNUPGHOME, the key used for signing and offered for decryption with its " +
+				"fingerprint and user ID, whether it's on a hardware token, whether gpg-agent is running and the " +
+				"active profile.",
+			Action: action.WhoamiPrint,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "Print the identity as JSON",
+				},
 			},
 		},
 		{
@@ -416,7 +1343,35 @@ func main() {
 		},
 	}
 
+	setExitCodes(app)
+
 	if err := app.Run(os.Args); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// setExitCodes makes gopass exit with the codes defined by action.ExitCode
+// for the errors of all commands and for invalid flags
+func setExitCodes(app *cli.App) {
+	if fn, ok := app.Action.(func(*cli.Context) error); ok {
+		app.Action = action.WithExitCode(fn)
+	}
+	if app.Before != nil {
+		app.Before = action.WithExitCode(app.Before)
+	}
+	app.OnUsageError = action.UsageError("")
+	setCommandExitCodes(app.Commands)
+}
+
+func setCommandExitCodes(cmds []cli.Command) {
+	for i := range cmds {
+		if fn, ok := cmds[i].Action.(func(*cli.Context) error); ok {
+			cmds[i].Action = action.WithExitCode(fn)
+		}
+		if cmds[i].Before != nil {
+			cmds[i].Before = action.WithExitCode(cmds[i].Before)
+		}
+		cmds[i].OnUsageError = action.UsageError(cmds[i].Name)
+		setCommandExitCodes(cmds[i].Subcommands)
+	}
+}
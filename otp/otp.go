@@ -0,0 +1,172 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justwatchcom/gopass/password"
+)
+
+const (
+	// totpKey prefixes the line in a secret's body holding a base32 TOTP
+	// secret with the default parameters, e.g. `totp: JBSWY3DPEHPK3PXP`
+	totpKey = "totp"
+	// defaultDigits and defaultPeriod are those of Google Authenticator and
+	// most other apps
+	defaultDigits = 6
+	defaultPeriod = 30
+)
+
+var (
+	// ErrNoOTP is returned if a secret holds no TOTP secret
+	ErrNoOTP = errors.New("no TOTP secret found")
+)
+
+// OTP generates time based one-time passwords as defined in RFC 6238
+type OTP struct {
+	Secret []byte
+	// Digits is the length of the codes, 6 to 8
+	Digits int
+	// Period is the number of seconds a code is valid
+	Period int
+	// Algorithm is the HMAC hash, SHA1, SHA256 or SHA512
+	Algorithm string
+}
+
+// Parse returns the TOTP of a secret. It's read from an otpauth:// URL, as
+// encoded in the QR codes of most services, or a `totp:` field holding just
+// the base32 secret, see password.Field. The first line is the password and
+// never holds it.
+func Parse(content []byte) (OTP, error) {
+	lines := strings.Split(string(content), "\n")
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "otpauth://") {
+			return ParseURL(line)
+		}
+	}
+	if secret, found := password.Field(content, totpKey); found {
+		return New(secret)
+	}
+	return OTP{}, ErrNoOTP
+}
+
+// New returns the TOTP for the given base32 secret with the default
+// parameters. Spaces, padding and case are ignored.
+func New(secret string) (OTP, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return OTP{}, err
+	}
+	return OTP{
+		Secret:    key,
+		Digits:    defaultDigits,
+		Period:    defaultPeriod,
+		Algorithm: "SHA1",
+	}, nil
+}
+
+// ParseURL parses an otpauth://totp/ URL, see
+// https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func ParseURL(raw string) (OTP, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return OTP{}, fmt.Errorf("invalid otpauth URL: %s", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		return OTP{}, fmt.Errorf("unsupported otpauth URL %s://%s, only totp is supported", u.Scheme, u.Host)
+	}
+	q := u.Query()
+	o, err := New(q.Get("secret"))
+	if err != nil {
+		return OTP{}, err
+	}
+	if d := q.Get("digits"); d != "" {
+		if o.Digits, err = strconv.Atoi(d); err != nil || o.Digits < 6 || o.Digits > 8 {
+			return OTP{}, fmt.Errorf("invalid number of digits %q", d)
+		}
+	}
+	if p := q.Get("period"); p != "" {
+		if o.Period, err = strconv.Atoi(p); err != nil || o.Period < 1 {
+			return OTP{}, fmt.Errorf("invalid period %q", p)
+		}
+	}
+	if a := q.Get("algorithm"); a != "" {
+		o.Algorithm = strings.ToUpper(a)
+		if newHash(o.Algorithm) == nil {
+			return OTP{}, fmt.Errorf("unsupported algorithm %q", a)
+		}
+	}
+	return o, nil
+}
+
+// At returns the code valid at the given time
+func (o OTP) At(t time.Time) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(t.Unix()/int64(o.Period)))
+	mac := hmac.New(newHash(o.Algorithm), o.Secret)
+	_, _ = mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	off := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < o.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", o.Digits, code%mod)
+}
+
+// Now returns the current code
+func (o OTP) Now() string {
+	return o.At(time.Now())
+}
+
+// Left returns the number of seconds the code valid at the given time
+// remains valid
+func (o OTP) Left(t time.Time) int {
+	return o.Period - int(t.Unix()%int64(o.Period))
+}
+
+// newHash returns the hash of the given algorithm, nil if its unsupported.
+// SHA1 is the default.
+func newHash(algo string) func() hash.Hash {
+	switch algo {
+	case "", "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	}
+	return nil
+}
+
+// decodeSecret decodes a base32 secret
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Join(strings.Fields(secret), ""))
+	secret = strings.TrimRight(secret, "=")
+	if secret == "" {
+		return nil, fmt.Errorf("empty TOTP secret")
+	}
+	if n := len(secret) % 8; n != 0 {
+		secret += strings.Repeat("=", 8-n)
+	}
+	key, err := base32.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base32 TOTP secret: %s", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,77 @@
+package otp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRFC6238 checks the test vectors of RFC 6238, appendix B
+func TestRFC6238(t *testing.T) {
+	secrets := map[string][]byte{
+		"SHA1":   []byte("12345678901234567890"),
+		"SHA256": []byte("12345678901234567890123456789012"),
+		"SHA512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	for _, tc := range []struct {
+		unix  int64
+		codes map[string]string
+	}{
+		{59, map[string]string{"SHA1": "94287082", "SHA256": "46119246", "SHA512": "90693936"}},
+		{1111111109, map[string]string{"SHA1": "07081804", "SHA256": "68084774", "SHA512": "25091201"}},
+		{1111111111, map[string]string{"SHA1": "14050471", "SHA256": "67062674", "SHA512": "99943326"}},
+		{1234567890, map[string]string{"SHA1": "89005924", "SHA256": "91819424", "SHA512": "93441116"}},
+		{2000000000, map[string]string{"SHA1": "69279037", "SHA256": "90698825", "SHA512": "38618901"}},
+		{20000000000, map[string]string{"SHA1": "65353130", "SHA256": "77737706", "SHA512": "47863826"}},
+	} {
+		for algo, code := range tc.codes {
+			o := OTP{Secret: secrets[algo], Digits: 8, Period: 30, Algorithm: algo}
+			assert.Equal(t, code, o.At(time.Unix(tc.unix, 0)), "%s at %d", algo, tc.unix)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	at := time.Unix(59, 0)
+
+	o, err := Parse([]byte("password\nuser: gopher\ntotp: " + secret + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 30, o.Period)
+	assert.Equal(t, "287082", o.At(at))
+
+	// spaces, padding and case don't matter
+	o, err = New("gezd gnbv gy3t qojq gezd gnbv gy3t qojq")
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", o.At(at))
+
+	o, err = Parse([]byte("password\notpauth://totp/Example:gopher?secret=" + secret + "&digits=8&period=60&algorithm=sha1\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, o.Digits)
+	assert.Equal(t, 60, o.Period)
+	assert.Equal(t, "94287082", o.At(time.Unix(60, 0)))
+	assert.Equal(t, 60, o.Left(time.Unix(60, 0)))
+	assert.Equal(t, 1, o.Left(time.Unix(119, 0)))
+
+	for _, content := range []string{
+		"password\nuser: gopher\n",
+		// the password is never parsed
+		"totp: " + secret,
+	} {
+		_, err = Parse([]byte(content))
+		assert.Equal(t, ErrNoOTP, err, content)
+	}
+	for _, u := range []string{
+		"otpauth://hotp/Example?secret=" + secret,
+		"otpauth://totp/Example?secret=not-base32!",
+		"otpauth://totp/Example?secret=" + secret + "&digits=4",
+		"otpauth://totp/Example?secret=" + secret + "&period=0",
+		"otpauth://totp/Example?secret=" + secret + "&algorithm=MD5",
+		"otpauth://totp/Example",
+	} {
+		_, err = ParseURL(u)
+		assert.Error(t, err, u)
+	}
+}
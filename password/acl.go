@@ -0,0 +1,132 @@
+package password
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// ACL lists who can decrypt a secret. It compares the recipients the secret
+// should be encrypted for with the keys it actually is encrypted for.
+type ACL struct {
+	Name       string
+	Override   bool
+	Recipients []ACLEntry
+	// Extra are the IDs of keys the secret is encrypted for that don't
+	// belong to any of the recipients
+	Extra []string
+}
+
+// ACLEntry is one recipient of a secret
+type ACLEntry struct {
+	ID string
+	// Key is nil if the public key is missing from the keyring
+	Key *gpg.Key
+	// Encrypted is true if the ciphertext is encrypted for this key
+	Encrypted bool
+}
+
+// Diverges returns true if the ciphertext is not encrypted for exactly the
+// recipients
+func (a ACL) Diverges() bool {
+	if len(a.Extra) > 0 {
+		return true
+	}
+	for _, e := range a.Recipients {
+		if !e.Encrypted {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveRecipients returns the recipients the given secret must be
+// encrypted for, considering recipient overrides
+func (s *Store) EffectiveRecipients(name string) ([]string, error) {
+	return s.effectiveRecipients(name, newRecipientResolver(s))
+}
+
+func (s *Store) effectiveRecipients(name string, res *recipientResolver) ([]string, error) {
+	p := s.passfile(name)
+	if !strings.HasPrefix(p, s.path) {
+		return nil, ErrSneaky
+	}
+	if !fsutil.IsFile(p) {
+		return nil, ErrNotFound
+	}
+	if s.isSymmetric(p) {
+		return nil, fmt.Errorf("%s is encrypted with a passphrase and has no recipients", name)
+	}
+	return res.recipientsFor(name), nil
+}
+
+// ACL resolves the recipients of the given secret and checks them against
+// the keys the ciphertext is encrypted for
+func (s *Store) ACL(name string) (ACL, error) {
+	return s.acl(name, newRecipientResolver(s))
+}
+
+// acl resolves the recipients of the given secret with res, see ACL
+func (s *Store) acl(name string, res *recipientResolver) (ACL, error) {
+	keyIDs, err := gpg.ListPacketRecipients(s.passfile(name))
+	if err != nil {
+		return ACL{}, fmt.Errorf("failed to get the recipients of %s: %s", name, err)
+	}
+	return s.aclFor(name, res, keyIDs)
+}
+
+// aclFor checks the recipients of the given secret, resolved with res,
+// against the key IDs listed in its ciphertext
+func (s *Store) aclFor(name string, res *recipientResolver, keyIDs []string) (ACL, error) {
+	ids, err := s.effectiveRecipients(name, res)
+	if err != nil {
+		return ACL{}, err
+	}
+	ids = expandGroups(ids)
+
+	acl := ACL{
+		Name:       name,
+		Override:   res.hasOverride(name),
+		Recipients: make([]ACLEntry, 0, len(ids)),
+		Extra:      make([]string, 0, len(keyIDs)),
+	}
+	matched := make(map[string]bool, len(keyIDs))
+	for _, id := range ids {
+		e := ACLEntry{ID: id}
+		if kl, err := gpg.ListPublicKeys(id); err == nil && len(kl) > 0 {
+			e.Key = &kl[0]
+			for _, kid := range keyIDs {
+				if kl[0].HasID(kid) {
+					e.Encrypted = true
+					matched[kid] = true
+				}
+			}
+		}
+		acl.Recipients = append(acl.Recipients, e)
+	}
+	for _, kid := range keyIDs {
+		if !matched[kid] {
+			acl.Extra = append(acl.Extra, kid)
+		}
+	}
+	sort.Strings(acl.Extra)
+	return acl, nil
+}
+
+// EffectiveRecipients returns the recipients the given secret must be
+// encrypted for
+func (r *RootStore) EffectiveRecipients(name string) ([]string, error) {
+	store := r.getStore(name)
+	return store.EffectiveRecipients(strings.TrimPrefix(name, store.alias))
+}
+
+// ACL resolves who can decrypt the given secret
+func (r *RootStore) ACL(name string) (ACL, error) {
+	store := r.getStore(name)
+	acl, err := store.ACL(strings.TrimPrefix(name, store.alias))
+	acl.Name = name
+	return acl, err
+}
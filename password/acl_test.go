@@ -0,0 +1,106 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestACL(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass second", "second@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("second@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list second key: %s", err)
+	}
+	second := kl[0].Fingerprint
+
+	tempdir, cleanupDir := newTestDir(t, fpr, second)
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo/bar", []byte("secret")))
+
+	_, err = s.EffectiveRecipients("missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	recs, err := s.EffectiveRecipients("foo/bar")
+	assert.NoError(t, err)
+	assert.Len(t, recs, 2)
+
+	acl, err := s.ACL("foo/bar")
+	assert.NoError(t, err)
+	assert.False(t, acl.Override)
+	assert.False(t, acl.Diverges())
+	for _, e := range acl.Recipients {
+		assert.NotNil(t, e.Key)
+		assert.True(t, e.Encrypted, e.ID)
+	}
+
+	// encrypted for less recipients than it should be
+	fn := filepath.Join(tempdir, "baz.gpg")
+	assert.NoError(t, gpg.Encrypt(fn, []byte("other"), []string{fpr}, gpg.EncryptOpts{}))
+	acl, err = s.ACL("baz")
+	assert.NoError(t, err)
+	assert.True(t, acl.Diverges())
+	assert.Len(t, acl.Extra, 0)
+	assert.Len(t, acl.Recipients, 2)
+	for _, e := range acl.Recipients {
+		assert.Equal(t, e.ID == fpr, e.Encrypted, e.ID)
+	}
+
+	// the override is taken into account, the second key is an extra now
+	if err := ioutil.WriteFile(filepath.Join(tempdir, "foo", "bar"+overrideSuffix), []byte(fpr+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write override: %s", err)
+	}
+	acl, err = s.ACL("foo/bar")
+	assert.NoError(t, err)
+	assert.True(t, acl.Override)
+	assert.True(t, acl.Diverges())
+	assert.Len(t, acl.Recipients, 1)
+	assert.Len(t, acl.Extra, 1)
+}
+
+func TestACLGroup(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass second", "second@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	conf := "group team = " + fpr + " second@gopass.pw\n"
+	if err := ioutil.WriteFile(filepath.Join(os.Getenv("GNUPGHOME"), "gpg.conf"), []byte(conf), 0600); err != nil {
+		t.Fatalf("Failed to write gpg.conf: %s", err)
+	}
+
+	tempdir, cleanupDir := newTestDir(t, "team")
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, &RootStore{AlwaysTrust: true})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+
+	// gpg encrypts for both members of the group
+	acl, err := s.ACL("foo")
+	assert.NoError(t, err)
+	assert.False(t, acl.Diverges())
+	ids := make([]string, 0, len(acl.Recipients))
+	for _, e := range acl.Recipients {
+		ids = append(ids, e.ID)
+	}
+	sort.Strings(ids)
+	want := []string{fpr, "second@gopass.pw"}
+	sort.Strings(want)
+	assert.Equal(t, want, ids)
+}
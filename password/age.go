@@ -0,0 +1,180 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justwatchcom/gopass/age"
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/log"
+)
+
+const (
+	// ageRecipientsID lists the age recipients every secret of a store is
+	// encrypted for in addition to the gpg recipients. It uses the format
+	// of age recipient files, so it can be passed to age -R.
+	ageRecipientsID = ".age-recipients"
+	// ageExt replaces the .gpg extension of a secret for its age encrypted
+	// copy
+	ageExt = ".age"
+)
+
+// ageRecipientsFile returns the path of the age recipients of this store
+func (s *Store) ageRecipientsFile() string {
+	return fsutil.CleanPath(filepath.Join(s.path, ageRecipientsID))
+}
+
+// agefile returns the path of the age encrypted copy of the given secret
+func (s *Store) agefile(name string) string {
+	return strings.TrimSuffix(s.passfile(name), ".gpg") + ageExt
+}
+
+// loadAgeRecipients reads the age recipients, if the store has any. Blank
+// lines and comments are ignored, like age does.
+func (s *Store) loadAgeRecipients() ([]string, error) {
+	buf, err := ioutil.ReadFile(s.ageRecipientsFile())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return []string{}, err
+	}
+	rs := make([]string, 0, 5)
+	for _, r := range unmarshalRecipients(bytes.NewReader(buf)) {
+		if strings.HasPrefix(r, "#") {
+			continue
+		}
+		if !age.IsRecipient(r) {
+			return []string{}, fmt.Errorf("invalid age recipient %q in %s", r, s.ageRecipientsFile())
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// saveAgeRecipients writes the age recipients. Without any age recipients
+// left the file is removed.
+func (s *Store) saveAgeRecipients() error {
+	if len(s.ageRecipients) < 1 {
+		if err := os.Remove(s.ageRecipientsFile()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return s.writeFile(s.ageRecipientsFile(), marshalRecipients(s.ageRecipients))
+}
+
+// AgeRecipients returns the age recipients every secret of this store is
+// encrypted for in addition to its gpg recipients
+func (s *Store) AgeRecipients() []string {
+	rs := make([]string, len(s.ageRecipients))
+	copy(rs, s.ageRecipients)
+	return rs
+}
+
+// AgeRecipients returns the age recipients of the store holding the given
+// secret
+func (r *RootStore) AgeRecipients(name string) []string {
+	return r.getStore(name).AgeRecipients()
+}
+
+// AddAgeRecipient adds an age recipient to this store and encrypts an age
+// copy of every secret for it
+func (s *Store) AddAgeRecipient(id string) error {
+	if !age.IsRecipient(id) {
+		return fmt.Errorf("%s is not an age recipient", id)
+	}
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if contains(s.ageRecipients, id) {
+		return fmt.Errorf("Recipient already in store")
+	}
+	s.ageRecipients = append(s.ageRecipients, id)
+	if err := s.saveAgeRecipients(); err != nil {
+		return err
+	}
+	if err := s.reencrypt(); err != nil {
+		return err
+	}
+	return s.gitSave(fmt.Sprintf("Added age recipient %s", id), s.ageRecipientsFile())
+}
+
+// RemoveAgeRecipient removes an age recipient from this store. The age
+// copies are encrypted for the remaining age recipients, or removed if none
+// are left.
+func (s *Store) RemoveAgeRecipient(id string) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if !contains(s.ageRecipients, id) {
+		return fmt.Errorf("%s is not an age recipient of the store at %s", id, s.path)
+	}
+	rs := make([]string, 0, len(s.ageRecipients))
+	for _, r := range s.ageRecipients {
+		if r != id {
+			rs = append(rs, r)
+		}
+	}
+	s.ageRecipients = rs
+	if err := s.saveAgeRecipients(); err != nil {
+		return err
+	}
+	if err := s.reencrypt(); err != nil {
+		return err
+	}
+	return s.gitSave(fmt.Sprintf("Removed age recipient %s", id), s.ageRecipientsFile())
+}
+
+// AddAgeRecipient adds an age recipient to the given store
+func (r *RootStore) AddAgeRecipient(store, id string) error {
+	return r.getStore(store).AddAgeRecipient(id)
+}
+
+// RemoveAgeRecipient removes an age recipient from the given store
+func (r *RootStore) RemoveAgeRecipient(store, id string) error {
+	return r.getStore(store).RemoveAgeRecipient(id)
+}
+
+// encryptAge writes the age encrypted copy of the given secret and returns
+// the files to add to git. Without any age recipients, or without content
+// for a symmetric secret, a stale copy is removed instead.
+func (s *Store) encryptAge(name string, content []byte) ([]string, error) {
+	p := s.agefile(name)
+	if len(s.ageRecipients) < 1 || content == nil {
+		if !fsutil.IsFile(p) {
+			return nil, nil
+		}
+		if err := os.Remove(p); err != nil {
+			return nil, fmt.Errorf("failed to remove the age copy of %s: %s", name, err)
+		}
+		return []string{p}, nil
+	}
+	if err := s.withFileMode(p, func() error {
+		return age.Encrypt(p, content, s.ageRecipients)
+	}); err != nil {
+		return nil, err
+	}
+	return []string{p}, nil
+}
+
+// decryptAge decrypts the age encrypted copy of the given secret with the
+// configured identity. It's used if gpg fails to decrypt the secret.
+func (s *Store) decryptAge(name string) ([]byte, error) {
+	p := s.agefile(name)
+	if s.ageIdentity == "" || !fsutil.IsFile(p) {
+		return nil, ErrDecrypt
+	}
+	log.Debugf("age: decrypting the age copy of %s", name)
+	return age.Decrypt(p, fsutil.CleanPath(s.ageIdentity))
+}
@@ -0,0 +1,91 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/age"
+	"github.com/justwatchcom/gopass/age/agetest"
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgeRecipients(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+	rec, identity, cleanupAge := agetest.NewIdentity(t)
+	defer cleanupAge()
+
+	tempdir, cleanupDir := newTestDir(t, fpr)
+	defer cleanupDir()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, ageRecipientsID), []byte("# migration\n"+rec+"\n"), 0600))
+
+	s, err := NewStore("", tempdir, &RootStore{AgeIdentity: identity})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{rec}, s.AgeRecipients())
+	assert.Equal(t, []string{fpr}, s.recipients)
+
+	// both tools can decrypt the secret
+	assert.NoError(t, s.Set("foo/bar", []byte("secret")))
+	assert.True(t, fsutil.IsFile(s.passfile("foo/bar")))
+	assert.True(t, fsutil.IsFile(s.agefile("foo/bar")))
+	content, err := s.Get("foo/bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(content))
+	content, err = age.Decrypt(s.agefile("foo/bar"), identity)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(content))
+
+	// the age copies aren't listed as secrets
+	entries, err := s.List("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo/bar"}, entries)
+
+	// without the gpg key the age copy is decrypted
+	_, cleanupOther := gpgtest.NewKeyring(t)
+	content, err = s.Get("foo/bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(content))
+	s.ageIdentity = ""
+	_, err = s.Get("foo/bar")
+	assert.Equal(t, gpg.ErrNoSecretKey, err)
+	s.ageIdentity = identity
+	cleanupOther()
+
+	// the copy follows the secret
+	assert.NoError(t, s.Move("foo/bar", "baz"))
+	assert.False(t, fsutil.IsFile(s.agefile("foo/bar")))
+	assert.True(t, fsutil.IsFile(s.agefile("baz")))
+	assert.NoError(t, s.Delete("baz"))
+	assert.False(t, fsutil.IsFile(s.agefile("baz")))
+
+	// and is removed with the last age recipient
+	assert.NoError(t, s.Set("qux", []byte("secret")))
+	assert.Error(t, s.AddAgeRecipient(rec))
+	assert.Error(t, s.AddAgeRecipient("DEADBEEF"))
+	assert.NoError(t, s.RemoveAgeRecipient(rec))
+	assert.Len(t, s.AgeRecipients(), 0)
+	assert.False(t, fsutil.IsFile(s.agefile("qux")))
+	assert.False(t, fsutil.IsFile(filepath.Join(tempdir, ageRecipientsID)))
+	assert.NoError(t, s.AddAgeRecipient(rec))
+	assert.True(t, fsutil.IsFile(s.agefile("qux")))
+}
+
+func TestLoadAgeRecipientsInvalid(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, ageRecipientsID), []byte("DEADBEEF\n"), 0600))
+
+	_, err = NewStore("", tempdir, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// aliasPrefix starts the body of alias secrets, followed by the name of
+	// the secret they refer to
+	aliasPrefix = "gopass://"
+	// maxAliasDepth limits the length of alias chains
+	maxAliasDepth = 8
+)
+
+var (
+	// ErrAliasCycle is returned if aliases refer to each other
+	ErrAliasCycle = fmt.Errorf("Alias cycle detected")
+	// ErrAliasDepth is returned if an alias chain is too long
+	ErrAliasDepth = fmt.Errorf("Alias chain is too long")
+)
+
+// ParseAlias returns the name of the secret an alias refers to. The content
+// of an alias is a single line of the form gopass://some/secret.
+func ParseAlias(content []byte) (string, bool) {
+	content = bytes.TrimSpace(content)
+	if !bytes.HasPrefix(content, []byte(aliasPrefix)) || bytes.ContainsAny(content, "\r\n") {
+		return "", false
+	}
+	target := strings.Trim(string(content[len(aliasPrefix):]), "/")
+	if target == "" {
+		return "", false
+	}
+	return target, true
+}
+
+// formatAlias returns the content of an alias referring to target
+func formatAlias(target string) []byte {
+	return []byte(aliasPrefix + target + "\n")
+}
+
+// resolve follows aliases starting at name and returns the name and content
+// of the secret at the end of the chain
+func (r *RootStore) resolve(name string) (string, []byte, error) {
+	seen := make(map[string]struct{}, 2)
+	for i := 0; i <= maxAliasDepth; i++ {
+		seen[name] = struct{}{}
+		content, err := r.GetRaw(name)
+		if err != nil {
+			return name, content, err
+		}
+		target, ok := ParseAlias(content)
+		if !ok {
+			return name, content, nil
+		}
+		if _, found := seen[target]; found {
+			return name, []byte{}, ErrAliasCycle
+		}
+		name = target
+	}
+	return name, []byte{}, ErrAliasDepth
+}
+
+// ResolveAlias returns the name of the secret the given entry finally refers
+// to. For regular secrets this is the name itself.
+func (r *RootStore) ResolveAlias(name string) (string, error) {
+	target, _, err := r.resolve(name)
+	return target, err
+}
+
+// AliasOf returns the name of the secret the given entry directly refers to
+// or the empty string if it's not an alias
+func (r *RootStore) AliasOf(name string) (string, error) {
+	content, err := r.GetRaw(name)
+	if err != nil {
+		return "", err
+	}
+	target, _ := ParseAlias(content)
+	return target, nil
+}
+
+// SetAlias creates an alias that refers to the existing secret target
+func (r *RootStore) SetAlias(name, target string, cb RecipientCallback) error {
+	target = strings.Trim(target, "/")
+	if name == target {
+		return ErrAliasCycle
+	}
+	if found, err := r.Exists(target); err != nil || !found {
+		return fmt.Errorf("alias target %s does not exist", target)
+	}
+	final, err := r.ResolveAlias(target)
+	if err != nil {
+		return err
+	}
+	if final == name {
+		return ErrAliasCycle
+	}
+	return r.SetConfirm(name, formatAlias(target), cb)
+}
@@ -0,0 +1,57 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlias(t *testing.T) {
+	for in, out := range map[string]string{
+		"gopass://foo/bar":       "foo/bar",
+		"gopass://foo/bar\n":     "foo/bar",
+		"  gopass://foo/bar/ \n": "foo/bar",
+		"gopass://":              "",
+		"gopass://foo\nbar":      "",
+		"secret\ngopass://foo":   "",
+		"https://foo":            "",
+	} {
+		target, ok := ParseAlias([]byte(in))
+		assert.Equal(t, out, target, in)
+		assert.Equal(t, out != "", ok, in)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	rs, _, cleanup := newTestRootStore(t, "sub")
+	defer cleanup()
+
+	assert.NoError(t, rs.Set("sub/real", []byte("secret\nuser: gopher\n")))
+
+	assert.Error(t, rs.SetAlias("link", "missing", nil))
+	assert.NoError(t, rs.SetAlias("link", "sub/real", nil))
+	assert.NoError(t, rs.SetAlias("sub/other", "link", nil))
+
+	for _, name := range []string{"link", "sub/other"} {
+		content, err := rs.Get(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "secret\nuser: gopher\n", string(content), name)
+
+		target, err := rs.ResolveAlias(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "sub/real", target)
+	}
+
+	target, err := rs.AliasOf("sub/other")
+	assert.NoError(t, err)
+	assert.Equal(t, "link", target)
+	target, err = rs.AliasOf("sub/real")
+	assert.NoError(t, err)
+	assert.Equal(t, "", target)
+
+	// creating a cycle is refused and existing cycles are detected
+	assert.Equal(t, ErrAliasCycle, rs.SetAlias("sub/real", "sub/other", nil))
+	assert.NoError(t, rs.Set("sub/real", formatAlias("sub/other")))
+	_, err = rs.Get("link")
+	assert.Equal(t, ErrAliasCycle, err)
+}
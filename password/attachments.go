@@ -0,0 +1,197 @@
+package password
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/tree"
+)
+
+const (
+	attachmentsDir = ".attachments"
+)
+
+var (
+	// ErrInvalidAttachment is returned for attachment names that would
+	// escape the attachments folder
+	ErrInvalidAttachment = fmt.Errorf("Attachment names must not contain slashes or start with a dot")
+)
+
+// attachmentDir returns the folder holding the attachments of the given
+// secret
+func (s *Store) attachmentDir(name string) string {
+	return fsutil.CleanPath(filepath.Join(s.path, name, attachmentsDir))
+}
+
+// attachmentFile returns the name of the gpg file on disk for the given
+// attachment
+func (s *Store) attachmentFile(name, attachment string) (string, error) {
+	if attachment == "" || strings.ContainsRune(attachment, '/') || strings.HasPrefix(attachment, ".") {
+		return "", ErrInvalidAttachment
+	}
+	p := filepath.Join(s.attachmentDir(name), attachment+".gpg")
+	if !strings.HasPrefix(p, s.path) {
+		return "", ErrSneaky
+	}
+	return p, nil
+}
+
+// AddAttachment encrypts the given data for the recipients of this store and
+// stores it as a named attachment of an existing secret
+func (s *Store) AddAttachment(name, attachment string, data []byte) error {
+	p, err := s.attachmentFile(name, attachment)
+	if err != nil {
+		return err
+	}
+
+	found, err := s.Exists(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	opts := s.encryptOpts()
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if err := s.withFileMode(p, func() error {
+		return gpg.Encrypt(p, data, s.recipientsFor(name), opts)
+	}); err != nil {
+		return encryptError(err)
+	}
+
+	return s.gitSave(fmt.Sprintf("Save attachment %s of %s.", attachment, name), p)
+}
+
+// GetAttachment returns the plaintext of a single attachment
+func (s *Store) GetAttachment(name, attachment string) ([]byte, error) {
+	p, err := s.attachmentFile(name, attachment)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if !fsutil.IsFile(p) {
+		return []byte{}, ErrNotFound
+	}
+
+	data, err := s.decrypt(name, p)
+	if err != nil {
+		return []byte{}, decryptError(err)
+	}
+
+	return data, nil
+}
+
+// ListAttachments returns the sorted names of all attachments of the given
+// secret
+func (s *Store) ListAttachments(name string) ([]string, error) {
+	dir := s.attachmentDir(name)
+	if !strings.HasPrefix(dir, s.path) {
+		return []string{}, ErrSneaky
+	}
+	if !fsutil.IsDir(dir) {
+		return []string{}, nil
+	}
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return []string{}, err
+	}
+
+	lst := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || !strings.HasSuffix(fi.Name(), ".gpg") {
+			continue
+		}
+		lst = append(lst, strings.TrimSuffix(fi.Name(), ".gpg"))
+	}
+	sort.Strings(lst)
+
+	return lst, nil
+}
+
+// HasAttachments returns true if the given secret has any attachments
+func (s *Store) HasAttachments(name string) bool {
+	return fsutil.IsDir(s.attachmentDir(name))
+}
+
+// reencryptAttachments re-encrypts all attachments of the given secret for
+// the current recipients
+func (s *Store) reencryptAttachments(name string) error {
+	return copyAttachments(s, name, s, name)
+}
+
+// copyAttachments decrypts all attachments of a secret and encrypts them for
+// the recipients of the destination store
+func copyAttachments(src *Store, from string, dst *Store, to string) error {
+	atts, err := src.ListAttachments(from)
+	if err != nil {
+		return err
+	}
+	for _, att := range atts {
+		data, err := src.GetAttachment(from, att)
+		if err != nil {
+			return fmt.Errorf("failed to get attachment %s: %s", att, err)
+		}
+		if err := dst.AddAttachment(to, att, data); err != nil {
+			return fmt.Errorf("failed to write attachment %s: %s", att, err)
+		}
+	}
+	return nil
+}
+
+// addNotes marks all entries with attachments in the given tree. The entries
+// are expected to include the alias of this store.
+func (s *Store) addNotes(root *tree.Folder, alias string, entries []string) {
+	for _, e := range entries {
+		name := strings.TrimPrefix(strings.TrimPrefix(e, alias), "/")
+		atts, err := s.ListAttachments(name)
+		if err != nil || len(atts) < 1 {
+			continue
+		}
+		note := fmt.Sprintf("%d attachments", len(atts))
+		if len(atts) == 1 {
+			note = "1 attachment"
+		}
+		if err := root.AddNote(e, note); err != nil {
+			fmt.Printf("Failed to add note to %s: %s\n", e, err)
+		}
+	}
+}
+
+// hasVisibleEntries returns true if the folder contains any entries not
+// starting with a dot
+func hasVisibleEntries(path string) bool {
+	fh, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+
+	names, err := fh.Readdirnames(-1)
+	if err != nil {
+		return false
+	}
+	for _, n := range names {
+		if !strings.HasPrefix(n, ".") {
+			return true
+		}
+	}
+	return false
+}
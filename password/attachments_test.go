@@ -0,0 +1,66 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachments(t *testing.T) {
+	s, _, cleanup := newTestStore(t)
+	defer cleanup()
+
+	// every possible byte value
+	bin := make([]byte, 256)
+	for i := range bin {
+		bin[i] = byte(i)
+	}
+
+	assert.Equal(t, ErrNotFound, s.AddAttachment("foo", "cert.der", bin))
+	assert.NoError(t, s.Set("foo", []byte("bar")))
+	assert.NoError(t, s.AddAttachment("foo", "cert.der", bin))
+	assert.NoError(t, s.AddAttachment("foo", "notes.txt", []byte("moar")))
+	for _, att := range []string{"", ".hidden", "../escape", "a/b"} {
+		assert.Error(t, s.AddAttachment("foo", att, bin), att)
+	}
+
+	atts, err := s.ListAttachments("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cert.der", "notes.txt"}, atts)
+
+	data, err := s.GetAttachment("foo", "cert.der")
+	assert.NoError(t, err)
+	assert.Equal(t, bin, data)
+
+	_, err = s.GetAttachment("foo", "missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	// the attachments must not turn the secret into a folder
+	assert.False(t, s.IsDir("foo"))
+	lst, err := s.List("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo"}, lst)
+
+	// re-encryption must keep the attachments
+	assert.NoError(t, s.reencrypt())
+	data, err = s.GetAttachment("foo", "cert.der")
+	assert.NoError(t, err)
+	assert.Equal(t, bin, data)
+
+	// attachments follow copies and moves
+	assert.NoError(t, s.Copy("foo", "copy"))
+	data, err = s.GetAttachment("copy", "cert.der")
+	assert.NoError(t, err)
+	assert.Equal(t, bin, data)
+
+	assert.NoError(t, s.Move("copy", "moved"))
+	data, err = s.GetAttachment("moved", "cert.der")
+	assert.NoError(t, err)
+	assert.Equal(t, bin, data)
+	assert.False(t, s.HasAttachments("copy"))
+
+	// and are removed with the secret
+	assert.NoError(t, s.Delete("moved"))
+	assert.False(t, fsutil.IsDir(s.attachmentDir("moved")))
+}
@@ -0,0 +1,210 @@
+package password
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// KeyAudit lists the secrets a key can decrypt
+type KeyAudit struct {
+	Key string `json:"key"`
+	// Readable are the secrets encrypted for the key or one of its subkeys
+	Readable []string `json:"readable"`
+	// Anonymous are secrets encrypted with hidden recipients, see gpg's
+	// --throw-keyids. They might be readable by the key.
+	Anonymous []string `json:"anonymous"`
+}
+
+// SecretsForKey returns the names of all secrets encrypted for the key with
+// the given fingerprint or ID. The recipients are read from the ciphertext,
+// so this doesn't decrypt anything.
+func (s *Store) SecretsForKey(fpr string) ([]string, error) {
+	a, err := s.AuditKey(fpr)
+	if err != nil {
+		return nil, err
+	}
+	return a.Readable, nil
+}
+
+// SecretsForRecipient returns the sorted names of all secrets whose
+// recipients include the key with the given fingerprint, ID or email. The
+// recipients are resolved from the .gpg-id files and recipient overrides,
+// so unlike SecretsForKey not even the ciphertexts are read. A secret not
+// re-encrypted since its recipients changed may be listed anyway.
+// Passphrase-only secrets are skipped.
+func (s *Store) SecretsForRecipient(id string) ([]string, error) {
+	entries, err := s.List("")
+	if err != nil {
+		return nil, err
+	}
+	matches := recipientMatcher(id)
+	res := newRecipientResolver(s)
+	names := make([]string, 0, 10)
+	for _, e := range entries {
+		if s.IsSymmetric(e) {
+			continue
+		}
+		for _, r := range res.recipientsFor(e) {
+			if matches(r) {
+				names = append(names, e)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SecretsForRecipient returns the names of all secrets in this store and
+// all substores whose recipients include the given key, see
+// Store.SecretsForRecipient
+func (r *RootStore) SecretsForRecipient(id string) ([]string, error) {
+	names, err := r.collectNames(func(s *Store) ([]string, error) {
+		return s.SecretsForRecipient(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// recipientMatcher returns a func telling if a recipient, as listed in a
+// .gpg-id file, is the key with the given ID. Recipients which aren't an ID
+// of the key, e.g. emails, are looked up in the keyring once.
+func recipientMatcher(id string) func(string) bool {
+	var key *gpg.Key
+	if kl, err := gpg.ListPublicKeys(id); err == nil && len(kl) > 0 {
+		key = &kl[0]
+	}
+	seen := make(map[string]bool, 5)
+	return func(r string) bool {
+		if r == id {
+			return true
+		}
+		if key == nil || r == "" {
+			return false
+		}
+		if m, found := seen[r]; found {
+			return m
+		}
+		m := key.HasID(r)
+		if !m {
+			kl, err := gpg.ListPublicKeys(r)
+			m = err == nil && len(kl) > 0 && kl[0].Fingerprint == key.Fingerprint
+		}
+		seen[r] = m
+		return m
+	}
+}
+
+// AuditKey lists all secrets of this store which are encrypted for the
+// given key, or might be because their recipients are hidden. The
+// ciphertexts are listed in parallel batches, passphrase-only secrets are
+// skipped.
+func (s *Store) AuditKey(fpr string) (KeyAudit, error) {
+	fpr = strings.ToUpper(strings.TrimPrefix(fpr, "0x"))
+	a := KeyAudit{
+		Key:       fpr,
+		Readable:  []string{},
+		Anonymous: []string{},
+	}
+	entries, err := s.List("")
+	if err != nil {
+		return a, err
+	}
+
+	// the secrets are encrypted for one of the subkeys, which we can only
+	// match if the key is in the keyring
+	var key *gpg.Key
+	if kl, err := gpg.ListPublicKeys(fpr); err == nil && len(kl) > 0 {
+		key = &kl[0]
+	}
+	matches := func(id string) bool {
+		if key != nil {
+			return key.HasID(id)
+		}
+		return gpg.Key{Fingerprint: fpr}.HasID(id)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !s.IsSymmetric(e) {
+			names = append(names, e)
+		}
+	}
+	for i, pr := range s.packetRecipients(names) {
+		if pr.Err != nil {
+			fmt.Printf("Failed to list the recipients of %s: %s\n", names[i], pr.Err)
+			continue
+		}
+		readable, anonymous := false, false
+		for _, id := range pr.IDs {
+			if id == gpg.AnonymousKeyID {
+				anonymous = true
+			} else if matches(id) {
+				readable = true
+			}
+		}
+		if readable {
+			a.Readable = append(a.Readable, names[i])
+		} else if anonymous {
+			a.Anonymous = append(a.Anonymous, names[i])
+		}
+	}
+
+	sort.Strings(a.Readable)
+	sort.Strings(a.Anonymous)
+	return a, nil
+}
+
+// AuditKey lists the secrets the given key can decrypt in this store and
+// all substores, see Store.AuditKey
+func (r *RootStore) AuditKey(fpr string) (KeyAudit, error) {
+	a, err := r.store.AuditKey(fpr)
+	if err != nil {
+		return a, err
+	}
+	for _, alias := range r.mountPoints() {
+		substore := r.mounts[alias]
+		if substore == nil {
+			continue
+		}
+		sub, err := substore.AuditKey(fpr)
+		if err != nil {
+			return a, err
+		}
+		a.Readable = append(a.Readable, prefixNames(alias, sub.Readable)...)
+		a.Anonymous = append(a.Anonymous, prefixNames(alias, sub.Anonymous)...)
+	}
+	sort.Strings(a.Readable)
+	sort.Strings(a.Anonymous)
+	return a, nil
+}
+
+// DecryptAs decrypts a secret with the given secret key only, to test if
+// its holder can read it. It fails with a gpg.NoSecretKeyError if the key
+// isn't in the keyring, see gpg.DecryptWithKey.
+func (s *Store) DecryptAs(name, fpr string) ([]byte, error) {
+	p := s.passfile(name)
+	if !strings.HasPrefix(p, s.path) {
+		return nil, ErrSneaky
+	}
+	if !fsutil.IsFile(p) {
+		return nil, ErrNotFound
+	}
+	if s.isSymmetric(p) {
+		return nil, fmt.Errorf("%s is encrypted with a passphrase, not for keys", name)
+	}
+	return gpg.DecryptWithKey(p, fpr)
+}
+
+// DecryptAs decrypts a secret of this store or a substore with the given
+// secret key only, see Store.DecryptAs
+func (r *RootStore) DecryptAs(name, fpr string) ([]byte, error) {
+	store := r.getStore(name)
+	return store.DecryptAs(strings.TrimPrefix(name, store.alias), fpr)
+}
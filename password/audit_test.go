@@ -0,0 +1,109 @@
+package password
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditKey(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass second", "second@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("second@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list second key: %s", err)
+	}
+	second := kl[0].Fingerprint
+
+	tempdir, cleanupDir := newTestDir(t, fpr, second)
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+	assert.NoError(t, s.Set("team/bar", []byte("secret")))
+	assert.NoError(t, s.SetRecipientOverride("team/bar", []string{second}))
+	assert.NoError(t, s.SetSymmetric("sym", []byte("secret"), "passphrase"))
+
+	// hide the recipients
+	cmd := exec.Command("gpg", "--batch", "--trust-model", "always", "--throw-keyids", "--encrypt", "--recipient", second, "--output", filepath.Join(tempdir, "hidden.gpg"))
+	cmd.Stdin = bytes.NewReader([]byte("secret"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to encrypt with hidden recipients: %s: %s", err, out)
+	}
+
+	names, err := s.SecretsForKey(second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo", "team/bar"}, names)
+
+	names, err = s.SecretsForKey("0x" + fpr)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo"}, names)
+
+	a, err := s.AuditKey(fpr)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hidden"}, a.Anonymous)
+
+	// keys missing from the keyring never match a subkey
+	a, err = s.AuditKey("DEADBEEF")
+	assert.NoError(t, err)
+	assert.Len(t, a.Readable, 0)
+	assert.Equal(t, []string{"hidden"}, a.Anonymous)
+}
+
+func TestSecretsForRecipient(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass second", "second@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("second@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list second key: %s", err)
+	}
+	second := kl[0].Fingerprint
+
+	// the second key is listed by its email
+	tempdir, cleanupDir := newTestDir(t, fpr, "second@gopass.pw")
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+	assert.NoError(t, s.Set("team/bar", []byte("secret")))
+	assert.NoError(t, s.SetRecipientOverride("team/bar", []string{second[24:]}))
+	assert.NoError(t, s.SetSymmetric("sym", []byte("secret"), "passphrase"))
+
+	names, err := s.SecretsForRecipient(second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo", "team/bar"}, names)
+	names, err = s.SecretsForRecipient("second@gopass.pw")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo", "team/bar"}, names)
+	names, err = s.SecretsForRecipient(fpr)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo"}, names)
+	names, err = s.SecretsForRecipient("DEADBEEF")
+	assert.NoError(t, err)
+	assert.Len(t, names, 0)
+
+	// the ciphertext isn't read, so a secret not yet re-encrypted for a
+	// changed .gpg-id is listed anyway, unlike with SecretsForKey
+	assert.NoError(t, gpg.Encrypt(s.passfile("foo"), []byte("secret"), []string{fpr}, s.encryptOpts()))
+	names, err = s.SecretsForRecipient(second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo", "team/bar"}, names)
+	names, err = s.SecretsForKey(second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team/bar"}, names)
+}
@@ -0,0 +1,228 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/hooks"
+)
+
+// The outcomes of writing a BatchEntry
+const (
+	BatchCreated  = "created"
+	BatchReplaced = "replaced"
+	BatchSkipped  = "skipped"
+	BatchFailed   = "failed"
+)
+
+// BatchEntry is one secret written by SetBatch
+type BatchEntry struct {
+	Name    string
+	Content []byte
+	// Overwrite allows replacing an existing secret
+	Overwrite bool
+}
+
+// BatchResult is the outcome of writing one BatchEntry
+type BatchResult struct {
+	Name   string
+	Status string
+	// Err is set if the status is BatchFailed
+	Err error
+}
+
+// batchJob is an entry waiting to be written to a store
+type batchJob struct {
+	index      int
+	name       string
+	content    []byte
+	recipients []string
+}
+
+// SetBatch writes many secrets at once. Existing secrets are only replaced if
+// the entry allows it. The recipients of all secrets are shown to cb once,
+// which must return true to write them. The secrets of each store are
+// encrypted in parallel and committed together with the given message. A
+// result is returned for every entry, in order.
+func (r *RootStore) SetBatch(entries []BatchEntry, msg string, cb BulkCallback) ([]BatchResult, error) {
+	results := make([]BatchResult, len(entries))
+	jobs := make(map[*Store][]batchJob, len(r.mounts)+1)
+	resolvers := make(map[*Store]*recipientResolver, len(r.mounts)+1)
+	for i, e := range entries {
+		results[i] = BatchResult{Name: e.Name, Status: BatchCreated}
+		store := r.getStore(e.Name)
+		name := strings.TrimPrefix(e.Name, store.alias)
+		exists, err := store.Exists(name)
+		if err != nil {
+			results[i].Status, results[i].Err = BatchFailed, err
+			continue
+		}
+		if store.IsDir(name) {
+			results[i].Status, results[i].Err = BatchFailed, fmt.Errorf("a folder named %s already exists", e.Name)
+			continue
+		}
+		if err := store.checkName(name); err != nil {
+			results[i].Status, results[i].Err = BatchFailed, err
+			continue
+		}
+		if err := checkSize(e.Content); err != nil {
+			results[i].Status, results[i].Err = BatchFailed, err
+			continue
+		}
+		if exists {
+			if !e.Overwrite {
+				results[i].Status = BatchSkipped
+				continue
+			}
+			results[i].Status = BatchReplaced
+		}
+		if resolvers[store] == nil {
+			resolvers[store] = newRecipientResolver(store)
+		}
+		jobs[store] = append(jobs[store], batchJob{
+			index:      i,
+			name:       name,
+			content:    e.Content,
+			recipients: resolvers[store].recipientsFor(name),
+		})
+	}
+	if len(jobs) < 1 {
+		return results, nil
+	}
+
+	if cb != nil && !cb(batchSummary(entries, jobs)) {
+		return results, ErrAborted
+	}
+
+	for _, alias := range r.aliases() {
+		store := r.storeByAlias(alias)
+		if len(jobs[store]) < 1 {
+			continue
+		}
+		if err := store.setBatch(jobs[store], msg, results); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// batchSummary lists the secrets about to be written by their recipients
+func batchSummary(entries []BatchEntry, jobs map[*Store][]batchJob) string {
+	byRecipients := make(map[string][]string, len(jobs))
+	n := 0
+	for _, js := range jobs {
+		for _, j := range js {
+			rs := make([]string, len(j.recipients))
+			copy(rs, j.recipients)
+			sort.Strings(rs)
+			key := strings.Join(rs, ", ")
+			byRecipients[key] = append(byRecipients[key], entries[j.index].Name)
+			n++
+		}
+	}
+	keys := make([]string, 0, len(byRecipients))
+	for k := range byRecipients {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := bytes.NewBufferString(fmt.Sprintf("Writing %d secrets:\n", n))
+	for _, k := range keys {
+		names := byRecipients[k]
+		sort.Strings(names)
+		fmt.Fprintf(buf, " - encrypted for %s:\n", k)
+		for _, name := range names {
+			fmt.Fprintf(buf, "   %s\n", name)
+		}
+	}
+	return buf.String()
+}
+
+// setBatch encrypts the given secrets in parallel and commits them at once.
+// Failures are recorded in results and don't stop the others.
+func (s *Store) setBatch(jobs []batchJob, msg string, results []BatchResult) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	opts := s.encryptOpts()
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	recipients := make([]string, 0, len(s.recipients))
+	for _, j := range jobs {
+		for _, id := range j.recipients {
+			if !contains(recipients, id) {
+				recipients = append(recipients, id)
+			}
+		}
+	}
+	if err := s.verifyPins(recipients); err != nil {
+		return err
+	}
+
+	var mutex sync.Mutex
+	files := make([]string, 0, len(jobs))
+	written := make([]batchJob, 0, len(jobs))
+	forEachIndex(len(jobs), runtime.NumCPU(), func(i int) {
+		j := jobs[i]
+		p := s.passfile(j.name)
+		err := s.withFileMode(p, func() error {
+			return gpg.Encrypt(p, j.content, j.recipients, opts)
+		})
+		if err != nil {
+			err = encryptError(err)
+		}
+		var ageFiles []string
+		if err == nil {
+			ageFiles, err = s.encryptAge(j.name, j.content)
+		}
+		mutex.Lock()
+		if err != nil {
+			results[j.index].Status, results[j.index].Err = BatchFailed, err
+		} else {
+			files = append(files, p)
+			files = append(files, ageFiles...)
+			written = append(written, j)
+		}
+		mutex.Unlock()
+	})
+
+	if len(files) < 1 {
+		return nil
+	}
+	contents := make(map[string][]byte, len(written))
+	for _, j := range written {
+		contents[j.name] = j.content
+	}
+	s.indexSecrets(contents)
+	sort.Strings(files)
+	if err := s.gitSave(msg, files...); err != nil {
+		return err
+	}
+
+	sort.Sort(byBatchIndex(written))
+	for _, j := range written {
+		if err := hooks.Run(hooks.PostInsert, s.hookEnv(map[string]string{
+			"GOPASS_SECRET": j.name,
+			"GOPASS_FILE":   s.passfile(j.name),
+		})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// byBatchIndex sorts batch jobs in the order of their entries
+type byBatchIndex []batchJob
+
+func (b byBatchIndex) Len() int           { return len(b) }
+func (b byBatchIndex) Less(i, j int) bool { return b[i].index < b[j].index }
+func (b byBatchIndex) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
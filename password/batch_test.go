@@ -0,0 +1,62 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetBatch(t *testing.T) {
+	rs, fpr, cleanup := newTestRootStore(t, "work")
+	defer cleanup()
+
+	assert.NoError(t, rs.Set("existing", []byte("old")))
+	assert.NoError(t, rs.Set("work/existing", []byte("old")))
+	assert.NoError(t, rs.Set("dir/secret", []byte("old")))
+
+	entries := []BatchEntry{
+		{Name: "foo", Content: []byte("foo")},
+		{Name: "work/bar", Content: []byte("bar\nuser: gopher\n")},
+		{Name: "existing", Content: []byte("new")},
+		{Name: "work/existing", Content: []byte("new"), Overwrite: true},
+		{Name: "dir", Content: []byte("folder")},
+		{Name: "../sneaky", Content: []byte("sneaky")},
+	}
+
+	// nothing is written unless confirmed
+	summary := ""
+	_, err := rs.SetBatch(entries, "Insert.", func(s string) bool {
+		summary = s
+		return false
+	})
+	assert.Equal(t, ErrAborted, err)
+	assert.True(t, strings.HasPrefix(summary, "Writing 3 secrets:\n - encrypted for "+fpr+":\n"), summary)
+	for _, name := range []string{"foo", "work/bar", "work/existing"} {
+		assert.Contains(t, summary, "   "+name+"\n")
+	}
+	_, err = rs.Get("foo")
+	assert.Error(t, err)
+
+	results, err := rs.SetBatch(entries, "Insert.", nil)
+	assert.NoError(t, err)
+	if assert.Len(t, results, len(entries)) {
+		for i, status := range []string{BatchCreated, BatchCreated, BatchSkipped, BatchReplaced, BatchFailed, BatchFailed} {
+			assert.Equal(t, entries[i].Name, results[i].Name)
+			assert.Equal(t, status, results[i].Status, results[i].Name)
+			assert.Equal(t, status == BatchFailed, results[i].Err != nil, results[i].Name)
+		}
+		assert.Equal(t, ErrSneaky, results[5].Err)
+	}
+
+	for name, content := range map[string]string{
+		"foo":           "foo",
+		"work/bar":      "bar\nuser: gopher\n",
+		"existing":      "old",
+		"work/existing": "new",
+	} {
+		buf, err := rs.Get(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(buf), name)
+	}
+}
@@ -0,0 +1,373 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// BulkCallback is shown a summary of a recipient change affecting
+// every store and must return true to apply it
+type BulkCallback func(string) bool
+
+// bulkChange is the new set of recipients of one store
+type bulkChange struct {
+	store      *Store
+	name       string
+	recipients []string
+	warning    string
+}
+
+// AddRecipientToAll adds the given key to the recipients of every store that
+// doesn't have it yet. The change is confirmed once for all stores, affected
+// secrets are re-encrypted and each store is committed separately.
+func (r *RootStore) AddRecipientToAll(keyID string) error {
+	kl, err := gpg.ListPublicKeys(keyID)
+	if err != nil {
+		return fmt.Errorf("Failed to list public keys: %s", err)
+	}
+	if len(kl) < 1 {
+		return fmt.Errorf("no matching key found in keyring")
+	}
+	key := kl[0]
+
+	changes := make([]bulkChange, 0, len(r.mounts)+1)
+	for _, alias := range r.aliases() {
+		s := r.storeByAlias(alias)
+		if matchRecipient(key.Fingerprint, kl, s.recipients) {
+			continue
+		}
+		if err := s.verifyPins([]string{keyID}); err != nil {
+			return err
+		}
+		rs := make([]string, len(s.recipients), len(s.recipients)+1)
+		copy(rs, s.recipients)
+		changes = append(changes, bulkChange{
+			store:      s,
+			name:       storeName(alias),
+			recipients: append(rs, key.Fingerprint),
+		})
+	}
+	if len(changes) < 1 {
+		return fmt.Errorf("%s is already a recipient of every store", keyID)
+	}
+
+	summary := fmt.Sprintf("Adding %s to %d stores:", key.OneLine(), len(changes))
+	return r.applyBulk(summary, changes, fmt.Sprintf("Add recipient %s.", key.Fingerprint))
+}
+
+// RemoveRecipientFromAll removes the given key from the recipients of every
+// store. It refuses to remove the last recipient of any store and warns if
+// the change would lock the user out of a store.
+func (r *RootStore) RemoveRecipientFromAll(keyID string) error {
+	id := strings.TrimPrefix(keyID, "0x")
+	// the key may not be available on this machine, then the ID is
+	// matched literally. gpg matches any part of the user IDs, only keys
+	// with exactly this ID or email are removed.
+	kl, err := gpg.ListPublicKeys(id)
+	if err != nil {
+		kl = gpg.KeyList{}
+	}
+	kl = keysWithID(kl, id)
+
+	changes := make([]bulkChange, 0, len(r.mounts)+1)
+	for _, alias := range r.aliases() {
+		s := r.storeByAlias(alias)
+		rs := make([]string, 0, len(s.recipients))
+		for _, k := range s.recipients {
+			if matchRecipient(id, kl, []string{k}) {
+				continue
+			}
+			rs = append(rs, k)
+		}
+		if len(rs) == len(s.recipients) {
+			continue
+		}
+		if len(rs) < 1 {
+			return fmt.Errorf("refusing to remove the last recipient of %s", storeName(alias))
+		}
+		if err := s.checkRequired(s.recipients, rs); err != nil {
+			return err
+		}
+		ch := bulkChange{
+			store:      s,
+			name:       storeName(alias),
+			recipients: rs,
+		}
+		if own, err := gpg.ListPrivateKeys(rs...); err != nil || len(own) < 1 {
+			ch.warning = "Warning: You will no longer be able to decrypt any secret in this store!"
+		}
+		changes = append(changes, ch)
+	}
+	if len(changes) < 1 {
+		return fmt.Errorf("%s is not a recipient of any store", keyID)
+	}
+
+	summary := fmt.Sprintf("Removing %s from %d stores:", keyID, len(changes))
+	return r.applyBulk(summary, changes, fmt.Sprintf("Remove recipient %s.", id))
+}
+
+// applyBulk confirms the changes and applies them store by store
+func (r *RootStore) applyBulk(summary string, changes []bulkChange, msg string) error {
+	buf := bytes.NewBufferString(summary + "\n")
+	for _, ch := range changes {
+		entries, err := ch.store.List("")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, " - %s (%s): %d secrets\n", ch.name, ch.store.path, len(entries))
+		if ch.warning != "" {
+			fmt.Fprintf(buf, "   %s\n", ch.warning)
+		}
+	}
+	if r.BulkFunc != nil && !r.BulkFunc(buf.String()) {
+		return ErrAborted
+	}
+
+	for _, ch := range changes {
+		if err := ch.apply(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply saves the new recipients of a single store and re-encrypts it
+func (ch bulkChange) apply(msg string) error {
+	unlock, err := ch.store.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ch.store.recipients = ch.recipients
+	if err := ch.store.saveRecipients(); err != nil {
+		return fmt.Errorf("failed to save recipients of %s: %s", ch.name, err)
+	}
+	if err := ch.store.reencryptAll(msg); err != nil {
+		return fmt.Errorf("failed to re-encrypt %s: %s", ch.name, err)
+	}
+	return nil
+}
+
+// reencryptAll re-encrypts all secrets and attachments of this store in
+// parallel and commits them, together with the recipients, at once
+func (s *Store) reencryptAll(msg string) error {
+	entries, err := s.List("")
+	if err != nil {
+		return err
+	}
+	failed, err := s.reencryptEntries(entries, msg, s.idFile())
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to re-encrypt %d of %d secrets", failed, len(entries))
+	}
+	return nil
+}
+
+// reencryptEntries re-encrypts the given secrets and their attachments in
+// parallel and commits them, together with the given extra files, at once.
+// Failures are reported but don't stop the others, their number is returned.
+func (s *Store) reencryptEntries(entries []string, msg string, extra ...string) (int, error) {
+	opts := s.encryptOpts()
+	if err := opts.Validate(); err != nil {
+		return 0, err
+	}
+	// overridden secrets are encrypted for their own recipients, so those
+	// have to match their pins as well
+	res := newRecipientResolver(s)
+	recipients := make([]string, 0, len(s.recipients))
+	for _, e := range entries {
+		if s.IsSymmetric(e) {
+			continue
+		}
+		for _, id := range res.recipientsFor(e) {
+			if !contains(recipients, id) {
+				recipients = append(recipients, id)
+			}
+		}
+	}
+	if err := s.verifyPins(recipients); err != nil {
+		return 0, err
+	}
+
+	var mutex sync.Mutex
+	files := make([]string, 0, len(entries))
+	failed := 0
+	forEachParallel(entries, func(e string) {
+		// symmetric secrets have no recipients
+		if s.IsSymmetric(e) {
+			return
+		}
+		fs, err := s.reencryptEntry(e, opts, res)
+		mutex.Lock()
+		if err != nil {
+			fmt.Printf("Failed to re-encrypt %s: %s\n", e, err)
+			failed++
+		}
+		files = append(files, fs...)
+		mutex.Unlock()
+	})
+	s.redigestIndexed(entries)
+
+	sort.Strings(files)
+	files = append(append(make([]string, 0, len(extra)+len(files)), extra...), files...)
+	if len(files) < 1 {
+		return failed, nil
+	}
+	return failed, s.gitSave(msg, files...)
+}
+
+// reencryptEntry re-encrypts one secret and all of its attachments for the
+// current recipients without committing them. It returns the files written.
+func (s *Store) reencryptEntry(name string, opts gpg.EncryptOpts, res *recipientResolver) ([]string, error) {
+	written := make([]string, 0, 1)
+	recipients := res.recipientsFor(name)
+
+	content, err := s.Get(name)
+	if err != nil {
+		return written, err
+	}
+	p := s.passfile(name)
+	if err := s.withFileMode(p, func() error {
+		return gpg.Encrypt(p, content, recipients, opts)
+	}); err != nil {
+		return written, encryptError(err)
+	}
+	written = append(written, p)
+	ageFiles, err := s.encryptAge(name, content)
+	if err != nil {
+		return written, err
+	}
+	written = append(written, ageFiles...)
+
+	atts, err := s.ListAttachments(name)
+	if err != nil {
+		return written, err
+	}
+	for _, att := range atts {
+		data, err := s.GetAttachment(name, att)
+		if err != nil {
+			return written, err
+		}
+		p, err := s.attachmentFile(name, att)
+		if err != nil {
+			return written, err
+		}
+		if err := s.withFileMode(p, func() error {
+			return gpg.Encrypt(p, data, recipients, opts)
+		}); err != nil {
+			return written, encryptError(err)
+		}
+		written = append(written, p)
+	}
+
+	return written, nil
+}
+
+// aliases returns the aliases of all stores, the root store first
+func (r *RootStore) aliases() []string {
+	mps := r.mountPoints()
+	sort.Strings(mps)
+	return append([]string{""}, mps...)
+}
+
+// storeByAlias returns the store mounted at the given alias, the root store
+// for the empty alias
+func (r *RootStore) storeByAlias(alias string) *Store {
+	if sub, found := r.mounts[alias]; found {
+		return sub
+	}
+	return r.store
+}
+
+// fullName returns the name of a secret of the store mounted at alias, as
+// seen by the root store
+func fullName(alias, name string) string {
+	if alias == "" {
+		return name
+	}
+	return alias + "/" + name
+}
+
+// prefixNames returns the names of secrets of the store mounted at alias as
+// seen by the root store, see fullName
+func prefixNames(alias string, names []string) []string {
+	if alias == "" {
+		return names
+	}
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		out = append(out, fullName(alias, name))
+	}
+	return out
+}
+
+// collectNames calls fn for every store and returns the sorted names
+// returned, prefixed with the mount point. On the first error the names
+// collected so far are returned with it.
+func (r *RootStore) collectNames(fn func(*Store) ([]string, error)) ([]string, error) {
+	names := make([]string, 0, 10)
+	for _, alias := range r.aliases() {
+		store := r.storeByAlias(alias)
+		if store == nil {
+			continue
+		}
+		sub, err := fn(store)
+		names = append(names, prefixNames(alias, sub)...)
+		if err != nil {
+			sort.Strings(names)
+			return names, err
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// storeName returns a name for the store at the given alias suiteable for
+// displaying
+func storeName(alias string) string {
+	if alias == "" {
+		return "gopass"
+	}
+	return alias
+}
+
+// matchRecipient returns true if one of the recipients is the given ID or an
+// ID or the email of one of the given keys
+func matchRecipient(id string, kl gpg.KeyList, recipients []string) bool {
+	for _, r := range recipients {
+		if strings.EqualFold(strings.TrimPrefix(r, "0x"), strings.TrimPrefix(id, "0x")) {
+			return true
+		}
+		if len(keysWithID(kl, r)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// keysWithID returns the keys which have the given ID, see gpg.Key.HasID, or
+// an user ID with exactly this email
+func keysWithID(kl gpg.KeyList, id string) gpg.KeyList {
+	out := make(gpg.KeyList, 0, len(kl))
+	for _, k := range kl {
+		if k.HasID(id) {
+			out = append(out, k)
+			continue
+		}
+		for _, ident := range k.Identities {
+			if ident.Email != "" && strings.EqualFold(ident.Email, id) {
+				out = append(out, k)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package password
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkRecipients(t *testing.T) {
+	rs, fpr, cleanup := newTestRootStore(t, "sub")
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass second", "second@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("second@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list second key: %s", err)
+	}
+	second := kl[0].Fingerprint
+
+	tempdir := filepath.Dir(rs.Path)
+	assert.NoError(t, rs.Set("foo", []byte("bar")))
+	assert.NoError(t, rs.Set("sub/baz", []byte("zab")))
+
+	// declining the summary must not change anything
+	rs.BulkFunc = func(string) bool { return false }
+	assert.Error(t, rs.AddRecipientToAll(second))
+	assert.Equal(t, []string{fpr}, rs.ListRecipients(""))
+
+	summary := ""
+	rs.BulkFunc = func(s string) bool {
+		summary = s
+		return true
+	}
+	assert.NoError(t, rs.AddRecipientToAll(second))
+	assert.Contains(t, summary, "to 2 stores")
+	assert.Contains(t, summary, " - gopass ("+filepath.Join(tempdir, "root")+"): 1 secrets")
+	for _, store := range []string{"", "sub"} {
+		assert.Equal(t, []string{fpr, second}, rs.ListRecipients(store))
+	}
+	for _, fn := range []string{filepath.Join(tempdir, "root", "foo.gpg"), filepath.Join(tempdir, "sub", "baz.gpg")} {
+		recs, err := gpg.GetRecipients(fn)
+		assert.NoError(t, err)
+		assert.Len(t, recs, 2, fn)
+	}
+	assert.Error(t, rs.AddRecipientToAll(second))
+
+	// only exact IDs and emails match, substrings don't
+	for _, id := range []string{fpr[len(fpr)-4:], "econd@gopass.pw", "gopass second"} {
+		err = rs.RemoveRecipientFromAll(id)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is not a recipient", id)
+	}
+	assert.Equal(t, []string{fpr, second}, rs.ListRecipients(""))
+
+	assert.NoError(t, rs.RemoveRecipientFromAll(fpr))
+	assert.NotContains(t, summary, "Warning")
+	for _, store := range []string{"", "sub"} {
+		assert.Equal(t, []string{second}, rs.ListRecipients(store))
+	}
+	content, err := rs.Get("sub/baz")
+	assert.NoError(t, err)
+	assert.Equal(t, "zab", string(content))
+
+	err = rs.RemoveRecipientFromAll(second)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "last recipient"), err)
+
+	// secrets which can't be re-encrypted fail the whole operation
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, "root", "broken.gpg"), []byte("garbage"), 0600))
+	err = rs.AddRecipientToAll(fpr)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to re-encrypt 1 of 2 secrets")
+}
@@ -0,0 +1,45 @@
+package password
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// changelogKey starts the block in a secret's body recording who changed
+	// it, when and why. Unlike the git history it travels with the secret.
+	changelogKey = "changelog"
+)
+
+// ChangelogEntry formats a line of the changelog, e.g.
+// `2017-05-03T14:00:00Z John Doe <john.doe@example.com>: rotated`. The
+// reason is put on a single line and left out if it's empty.
+func ChangelogEntry(t time.Time, who, reason string) string {
+	entry := t.UTC().Format(time.RFC3339) + " " + who
+	if reason = strings.Join(strings.Fields(reason), " "); reason != "" {
+		entry += ": " + reason
+	}
+	return entry
+}
+
+// ParseChangelog returns the entries of the changelog of a secret, oldest
+// first. The first line is the password and never holds the changelog.
+func ParseChangelog(content []byte) []string {
+	text, ok := parseBlock(content, changelogKey)
+	if !ok {
+		return nil
+	}
+	entries := make([]string, 0, 5)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+// AppendChangelog returns content with entry added to the end of its
+// changelog. The password and all other lines are kept as they are.
+func AppendChangelog(content []byte, entry string) []byte {
+	return setBlock(content, changelogKey, strings.Join(append(ParseChangelog(content), entry), "\n"))
+}
@@ -0,0 +1,47 @@
+package password
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangelogEntry(t *testing.T) {
+	ts := time.Date(2017, 5, 3, 16, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	assert.Equal(t, "2017-05-03T14:00:00Z John Doe <john.doe@gopass.pw>: rotated after the leak", ChangelogEntry(ts, "John Doe <john.doe@gopass.pw>", " rotated\nafter  the leak\n"))
+	assert.Equal(t, "2017-05-03T14:00:00Z John Doe", ChangelogEntry(ts, "John Doe", ""))
+}
+
+func TestAppendChangelog(t *testing.T) {
+	content := []byte("secret\nuser: foo\nnotes: |\n  a note\n")
+	exp := time.Date(2017, 5, 3, 14, 0, 0, 0, time.UTC)
+	content = SetExpiry(content, exp)
+	assert.Len(t, ParseChangelog(content), 0)
+
+	entries := []string{
+		ChangelogEntry(exp.Add(-2*time.Hour), "John Doe", "first"),
+		ChangelogEntry(exp.Add(-3*time.Hour), "Jane Doe", "second, with an older clock"),
+		ChangelogEntry(exp, "John Doe", ""),
+	}
+	for i, e := range entries {
+		content = AppendChangelog(content, e)
+		// entries are kept in the order they were appended
+		assert.Equal(t, entries[:i+1], ParseChangelog(content))
+	}
+
+	// the password and all other metadata are kept
+	pw, _ := SplitSecret(content)
+	assert.Equal(t, "secret", string(pw))
+	notes, ok := ParseNotes(content)
+	assert.True(t, ok)
+	assert.Equal(t, "a note", notes)
+	got, ok := ParseExpiry(content)
+	assert.True(t, ok)
+	assert.Equal(t, exp, got)
+	assert.Contains(t, string(content), "\nuser: foo\n")
+
+	assert.Equal(t, "secret\nchangelog: |\n  2017-05-03T14:00:00Z John Doe\n", string(AppendChangelog([]byte("secret"), entries[2])))
+	// the password is never parsed as changelog
+	assert.Len(t, ParseChangelog([]byte("changelog: |\n  foo")), 0)
+}
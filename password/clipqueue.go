@@ -0,0 +1,94 @@
+package password
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+const (
+	// clipQueuePrefix names the clipboard queues of the root store, one per
+	// session. They're local to each clone and kept out of git.
+	clipQueuePrefix = ".gopass-clip-"
+)
+
+// ClipQueue are the fields of a secret still to be copied to the clipboard
+// one after another, e.g. to fill in a form. Only the names of the fields
+// are kept, their values are decrypted again when they're copied.
+type ClipQueue struct {
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"`
+	// Expires is when the queue is dropped if it isn't advanced
+	Expires time.Time `json:"expires"`
+}
+
+// clipQueuePath returns the path of the clipboard queue of the given session
+func (r *RootStore) clipQueuePath(session string) (string, error) {
+	if session == "" || strings.Trim(session, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("invalid clipboard session '%s'", session)
+	}
+	return filepath.Join(r.store.path, clipQueuePrefix+session), nil
+}
+
+// LoadClipQueue returns the clipboard queue of the given session or nil if
+// it has none. An expired queue is removed.
+func (r *RootStore) LoadClipQueue(session string) (*ClipQueue, error) {
+	fn, err := r.clipQueuePath(session)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	q := &ClipQueue{}
+	if err := json.Unmarshal(buf, q); err != nil {
+		return nil, fmt.Errorf("failed to read the clipboard queue: %s", err)
+	}
+	if !time.Now().Before(q.Expires) || len(q.Fields) < 1 {
+		log.Debugf("clipboard: the queue of %s expired at %s", session, q.Expires)
+		return nil, r.RemoveClipQueue(session)
+	}
+	return q, nil
+}
+
+// SaveClipQueue writes the clipboard queue of the given session. Without
+// any fields left it's removed instead.
+func (r *RootStore) SaveClipQueue(session string, q *ClipQueue) error {
+	if q == nil || len(q.Fields) < 1 {
+		return r.RemoveClipQueue(session)
+	}
+	fn, err := r.clipQueuePath(session)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	if err := r.store.gitExclude("/" + clipQueuePrefix + "*"); err != nil && err != ErrGitNotInit {
+		return err
+	}
+	return r.store.writeFile(fn, buf)
+}
+
+// RemoveClipQueue removes the clipboard queue of the given session
+func (r *RootStore) RemoveClipQueue(session string) error {
+	fn, err := r.clipQueuePath(session)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
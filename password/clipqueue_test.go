@@ -0,0 +1,57 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClipQueue(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	r := &RootStore{store: &Store{path: tempdir}}
+
+	q, err := r.LoadClipQueue("abcd")
+	assert.NoError(t, err)
+	assert.Nil(t, q)
+
+	want := &ClipQueue{Name: "web/site", Fields: []string{"password"}, Expires: time.Now().Add(time.Minute)}
+	assert.NoError(t, r.SaveClipQueue("abcd", want))
+	q, err = r.LoadClipQueue("abcd")
+	assert.NoError(t, err)
+	assert.Equal(t, want.Name, q.Name)
+	assert.Equal(t, want.Fields, q.Fields)
+
+	// sessions don't share queues
+	q, err = r.LoadClipQueue("ef01")
+	assert.NoError(t, err)
+	assert.Nil(t, q)
+
+	// expired queues are removed
+	want.Expires = time.Now().Add(-time.Second)
+	assert.NoError(t, r.SaveClipQueue("abcd", want))
+	q, err = r.LoadClipQueue("abcd")
+	assert.NoError(t, err)
+	assert.Nil(t, q)
+	assert.False(t, fsutil.IsFile(filepath.Join(tempdir, clipQueuePrefix+"abcd")))
+
+	// and so are empty ones
+	want.Expires = time.Now().Add(time.Minute)
+	assert.NoError(t, r.SaveClipQueue("abcd", want))
+	assert.NoError(t, r.SaveClipQueue("abcd", &ClipQueue{Name: "web/site"}))
+	assert.False(t, fsutil.IsFile(filepath.Join(tempdir, clipQueuePrefix+"abcd")))
+
+	_, err = r.LoadClipQueue("../foo")
+	assert.Error(t, err)
+	assert.Error(t, r.SaveClipQueue("", want))
+}
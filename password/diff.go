@@ -0,0 +1,186 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiffResult lists how the secrets of two stores differ. Names are relative
+// to the store.
+type DiffResult struct {
+	OnlyA   []string    `json:"only_a"`
+	OnlyB   []string    `json:"only_b"`
+	Changed []DiffEntry `json:"changed"`
+	// Same is the number of secrets that are the same in both stores
+	Same int `json:"same"`
+}
+
+// DiffEntry is a secret present in both stores that differs
+type DiffEntry struct {
+	Name     string    `json:"name"`
+	ModTimeA time.Time `json:"mtime_a"`
+	ModTimeB time.Time `json:"mtime_b"`
+	// Lines are the numbers of the lines that differ, starting at 1. The
+	// contents are never included. Only set for deep comparisons.
+	Lines []int `json:"lines,omitempty"`
+}
+
+// Newer returns "a" or "b" depending on which copy was modified last
+func (e DiffEntry) Newer() string {
+	if e.ModTimeB.After(e.ModTimeA) {
+		return "b"
+	}
+	return "a"
+}
+
+// Diff compares the secrets of the stores mounted at a and b. Use the empty
+// string for the root store. Secrets present in both stores are compared by
+// their ciphertext. If deep is set secrets with a different ciphertext are
+// decrypted and compared by their content, which is done in parallel.
+// Passphrase-only secrets are always compared by their ciphertext.
+func (r *RootStore) Diff(a, b string, deep bool) (DiffResult, error) {
+	res := DiffResult{
+		OnlyA:   []string{},
+		OnlyB:   []string{},
+		Changed: []DiffEntry{},
+	}
+	sa, err := r.mountedStore(a)
+	if err != nil {
+		return res, err
+	}
+	sb, err := r.mountedStore(b)
+	if err != nil {
+		return res, err
+	}
+
+	la, err := sa.List("")
+	if err != nil {
+		return res, err
+	}
+	lb, err := sb.List("")
+	if err != nil {
+		return res, err
+	}
+	inB := make(map[string]struct{}, len(lb))
+	for _, name := range lb {
+		inB[name] = struct{}{}
+	}
+
+	common := make([]string, 0, len(la))
+	for _, name := range la {
+		if _, found := inB[name]; !found {
+			res.OnlyA = append(res.OnlyA, name)
+			continue
+		}
+		delete(inB, name)
+		common = append(common, name)
+	}
+	for name := range inB {
+		res.OnlyB = append(res.OnlyB, name)
+	}
+	sort.Strings(res.OnlyA)
+	sort.Strings(res.OnlyB)
+
+	var mutex sync.Mutex
+	forEachParallel(common, func(name string) {
+		e, changed, err := diffSecret(sa, sb, name, deep)
+		if err != nil {
+			fmt.Printf("Failed to compare %s: %s\n", name, err)
+		}
+		mutex.Lock()
+		if changed {
+			res.Changed = append(res.Changed, e)
+		} else if err == nil {
+			res.Same++
+		}
+		mutex.Unlock()
+	})
+
+	sort.Sort(byDiffName(res.Changed))
+	return res, nil
+}
+
+// mountedStore returns the store mounted at the given alias, or the root
+// store for the empty string
+func (r *RootStore) mountedStore(alias string) (*Store, error) {
+	alias = strings.TrimSuffix(alias, "/")
+	if alias == "" {
+		return r.store, nil
+	}
+	sub, found := r.mounts[alias]
+	if !found || sub == nil {
+		return nil, fmt.Errorf("No mount named %s", alias)
+	}
+	return sub, nil
+}
+
+// diffSecret compares a single secret of two stores. It returns false if
+// they are the same.
+func diffSecret(sa, sb *Store, name string, deep bool) (DiffEntry, bool, error) {
+	e := DiffEntry{Name: name}
+	fa, fb := sa.passfile(name), sb.passfile(name)
+	if fi, err := os.Stat(fa); err == nil {
+		e.ModTimeA = fi.ModTime()
+	}
+	if fi, err := os.Stat(fb); err == nil {
+		e.ModTimeB = fi.ModTime()
+	}
+
+	ca, err := ioutil.ReadFile(fa)
+	if err != nil {
+		return e, false, err
+	}
+	cb, err := ioutil.ReadFile(fb)
+	if err != nil {
+		return e, false, err
+	}
+	if bytes.Equal(ca, cb) {
+		return e, false, nil
+	}
+	// we can't ask for multiple passphrases in parallel
+	if !deep || sa.isSymmetric(fa) || sb.isSymmetric(fb) {
+		return e, true, nil
+	}
+
+	pa, err := sa.Get(name)
+	if err != nil {
+		return e, true, err
+	}
+	pb, err := sb.Get(name)
+	if err != nil {
+		return e, true, err
+	}
+	e.Lines = diffLines(pa, pb)
+	return e, len(e.Lines) > 0, nil
+}
+
+// diffLines returns the numbers of the lines that differ, starting at 1
+func diffLines(a, b []byte) []int {
+	la := strings.Split(string(a), "\n")
+	lb := strings.Split(string(b), "\n")
+	n := len(la)
+	if len(lb) > n {
+		n = len(lb)
+	}
+	lines := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if i < len(la) && i < len(lb) && la[i] == lb[i] {
+			continue
+		}
+		lines = append(lines, i+1)
+	}
+	return lines
+}
+
+// byDiffName sorts diff entries by their name
+type byDiffName []DiffEntry
+
+func (d byDiffName) Len() int           { return len(d) }
+func (d byDiffName) Less(i, j int) bool { return d[i].Name < d[j].Name }
+func (d byDiffName) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
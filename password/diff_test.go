@@ -0,0 +1,54 @@
+package password
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	rs, _, cleanup := newTestRootStore(t, "backup")
+	defer cleanup()
+	tempdir := filepath.Dir(rs.Path)
+
+	for name, content := range map[string]string{
+		"only/root":          "a",
+		"backup/only/backup": "b",
+		"same":               "same",
+		"backup/same":        "same",
+		"changed":            "new\nuser: gopher\nurl: example.com",
+		"backup/changed":     "old\nuser: gopher",
+	} {
+		assert.NoError(t, rs.Set(name, []byte(content)))
+	}
+	// a byte-identical copy
+	buf, err := ioutil.ReadFile(filepath.Join(tempdir, "root", "same.gpg"))
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, "root", "copy.gpg"), buf, 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, "backup", "copy.gpg"), buf, 0600))
+
+	_, err = rs.Diff("", "missing", false)
+	assert.Error(t, err)
+
+	res, err := rs.Diff("", "backup", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"only/root"}, res.OnlyA)
+	assert.Equal(t, []string{"only/backup"}, res.OnlyB)
+	assert.Equal(t, 1, res.Same)
+	if assert.Len(t, res.Changed, 2) {
+		assert.Equal(t, "changed", res.Changed[0].Name)
+		assert.Nil(t, res.Changed[0].Lines)
+		assert.Equal(t, "same", res.Changed[1].Name)
+	}
+
+	// the same content encrypted twice only differs in the ciphertext
+	res, err = rs.Diff("", "backup", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Same)
+	if assert.Len(t, res.Changed, 1) {
+		assert.Equal(t, "changed", res.Changed[0].Name)
+		assert.Equal(t, []int{1, 3}, res.Changed[0].Lines)
+	}
+}
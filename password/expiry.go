@@ -0,0 +1,97 @@
+package password
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// expiresKey prefixes the line in a secret's body holding its expiry
+	expiresKey = "expires_at"
+)
+
+// ParseExpiry returns the expiry time stored in the body of a secret. The
+// first line is the password and never holds the expiry.
+func ParseExpiry(content []byte) (time.Time, bool) {
+	val, found := Field(content, expiresKey)
+	if !found {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SetExpiry returns content with the expiry set to t, replacing any
+// existing expiry
+func SetExpiry(content []byte, t time.Time) []byte {
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	out := make([]string, 0, len(lines)+1)
+	out = append(out, lines[0])
+	for _, line := range lines[1:] {
+		if k, _, ok := parseField(line); ok && k == expiresKey {
+			continue
+		}
+		out = append(out, line)
+	}
+	out = append(out, expiresKey+": "+t.UTC().Format(time.RFC3339))
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// IsExpired returns true if the secret has an expiry that has passed at the
+// given time
+func IsExpired(content []byte, now time.Time) bool {
+	t, ok := ParseExpiry(content)
+	return ok && !now.Before(t)
+}
+
+// ExpiredSecrets returns the names of all secrets in this store that have
+// expired. This has to decrypt every secret, which is done in parallel.
+// Secrets that can not be decrypted and passphrase-only secrets are skipped.
+func (s *Store) ExpiredSecrets() ([]string, error) {
+	entries, err := s.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var mutex sync.Mutex
+	expired := make([]string, 0, 10)
+	forEachParallel(entries, func(e string) {
+		// asking for the passphrase of every symmetric secret is not an
+		// option
+		if s.IsSymmetric(e) {
+			return
+		}
+		content, err := s.Get(e)
+		if err != nil {
+			fmt.Printf("Failed to decrypt %s: %s\n", e, err)
+			return
+		}
+		if !IsExpired(content, now) {
+			return
+		}
+		mutex.Lock()
+		expired = append(expired, e)
+		mutex.Unlock()
+	})
+
+	sort.Strings(expired)
+	return expired, nil
+}
+
+// ExpiredSecrets returns the names of all expired secrets in this store and
+// all substores
+func (r *RootStore) ExpiredSecrets() ([]string, error) {
+	expired, err := r.collectNames((*Store).ExpiredSecrets)
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
@@ -0,0 +1,56 @@
+package password
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExpiry(t *testing.T) {
+	for in, out := range map[string]string{
+		"secret\nexpires_at: 2017-05-03T14:00:00Z":        "2017-05-03T14:00:00Z",
+		"secret\nuser: foo\nexpires_at: 2017-05-03\n":     "2017-05-03T00:00:00Z",
+		"secret\nexpires_at: 2017-05-03T14:00:00+02:00\n": "2017-05-03T12:00:00Z",
+		"expires_at: 2017-05-03T14:00:00Z":                "",
+		"secret\nexpires_at: tomorrow":                    "",
+		"secret\nuser: foo":                               "",
+	} {
+		exp, ok := ParseExpiry([]byte(in))
+		assert.Equal(t, out != "", ok, in)
+		if ok {
+			assert.Equal(t, out, exp.UTC().Format(time.RFC3339), in)
+		}
+	}
+}
+
+func TestSetExpiry(t *testing.T) {
+	exp := time.Date(2017, 5, 3, 14, 0, 0, 0, time.UTC)
+	for in, out := range map[string]string{
+		"secret":              "secret\nexpires_at: 2017-05-03T14:00:00Z\n",
+		"secret\nuser: foo\n": "secret\nuser: foo\nexpires_at: 2017-05-03T14:00:00Z\n",
+		"secret\nexpires_at: 2001-01-01\nuser: foo": "secret\nuser: foo\nexpires_at: 2017-05-03T14:00:00Z\n",
+	} {
+		assert.Equal(t, out, string(SetExpiry([]byte(in), exp)), in)
+	}
+
+	content := SetExpiry([]byte("secret"), exp)
+	assert.False(t, IsExpired(content, exp.Add(-time.Second)))
+	assert.True(t, IsExpired(content, exp))
+	assert.False(t, IsExpired([]byte("secret"), exp))
+}
+
+func TestExpiredSecrets(t *testing.T) {
+	s, _, cleanup := newTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	assert.NoError(t, s.Set("expired", SetExpiry([]byte("secret"), now.Add(-time.Hour))))
+	assert.NoError(t, s.Set("foo/expired", SetExpiry([]byte("secret"), now.Add(-time.Minute))))
+	assert.NoError(t, s.Set("valid", SetExpiry([]byte("secret"), now.Add(time.Hour))))
+	assert.NoError(t, s.Set("forever", []byte("secret")))
+
+	expired, err := s.ExpiredSecrets()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"expired", "foo/expired"}, expired)
+}
@@ -2,171 +2,356 @@ package password
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/fatih/color"
 	"github.com/justwatchcom/gopass/gpg"
 )
 
-// Fsck checks this stores integrity
-func (s *Store) Fsck(check, force bool) error {
-	storeRec, err := gpg.ListPublicKeys(s.recipients...)
-	if err != nil {
-		fmt.Printf("Failed to list recipients: %s\n", err)
-	}
+// FsckReport lists the problems found by Fsck. Secrets are listed by name,
+// files and folders by their path.
+type FsckReport struct {
+	// Undecryptable are secrets none of the available secret keys can decrypt
+	Undecryptable []string `json:"undecryptable"`
+	// MissingKeys are recipients whose public key is not in the keyring
+	MissingKeys []string `json:"missing_keys"`
+	// Drifted are secrets not encrypted for exactly their recipients
+	Drifted []string `json:"drifted"`
+	// Unverifiable are secrets whose recipients can't be checked because
+	// the public key of one of them is missing, see MissingKeys
+	Unverifiable []string `json:"unverifiable"`
+	// Orphans are attachment folders of secrets that don't exist
+	Orphans []string `json:"orphans"`
+	// Permissions are files and folders accessible by group or others
+	Permissions []string `json:"permissions"`
+	// Shadowed are secrets hidden by a folder of the same name
+	Shadowed []string `json:"shadowed"`
+	// Repaired are the entries of the other lists that have been fixed
+	Repaired []string `json:"repaired"`
+}
 
-	if err := filepath.Walk(s.path, s.mkStoreWalkerFsckFunc(check, force, storeRec, s.fsckFunc)); err != nil {
-		return err
+// newFsckReport returns an empty report. The lists are never nil, so they
+// encode as empty JSON arrays.
+func newFsckReport() FsckReport {
+	return FsckReport{
+		Undecryptable: []string{},
+		MissingKeys:   []string{},
+		Drifted:       []string{},
+		Unverifiable:  []string{},
+		Orphans:       []string{},
+		Permissions:   []string{},
+		Shadowed:      []string{},
+		Repaired:      []string{},
 	}
+}
 
-	return nil
+// Problems returns the number of problems found, including repaired ones.
+// Unverifiable secrets are counted by their missing keys.
+func (r FsckReport) Problems() int {
+	return len(r.Undecryptable) + len(r.MissingKeys) + len(r.Drifted) + len(r.Orphans) + len(r.Permissions) + len(r.Shadowed)
 }
 
-// mkStoreFsckWalkerFunc create a func to walk a (sub)store, i.e. list it's content
-func (s *Store) mkStoreWalkerFsckFunc(check, force bool, storeRec gpg.KeyList, askFunc func(string) bool) func(string, os.FileInfo, error) error {
-	shadowMap := make(map[string]struct{}, 100)
-	return func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() && strings.HasPrefix(info.Name(), ".") && path != s.path {
-			return filepath.SkipDir
+// merge adds the problems found in the given store to this report. Secret
+// names of substores are prefixed with the mount point.
+func (r *FsckReport) merge(alias string, o FsckReport) {
+	r.Undecryptable = append(r.Undecryptable, prefixNames(alias, o.Undecryptable)...)
+	r.MissingKeys = appendMissing(r.MissingKeys, o.MissingKeys...)
+	r.Drifted = append(r.Drifted, prefixNames(alias, o.Drifted)...)
+	r.Unverifiable = append(r.Unverifiable, prefixNames(alias, o.Unverifiable)...)
+	r.Orphans = append(r.Orphans, o.Orphans...)
+	r.Permissions = append(r.Permissions, o.Permissions...)
+	r.Shadowed = append(r.Shadowed, prefixNames(alias, o.Shadowed)...)
+	for _, e := range o.Repaired {
+		// repaired secrets are listed by name, everything else by path
+		if !filepath.IsAbs(e) {
+			e = fullName(alias, e)
 		}
-		if info.IsDir() && (info.Name() == "." || info.Name() == "..") {
-			return filepath.SkipDir
-		}
-		if info.IsDir() && info.Name() == ".git" {
-			return filepath.SkipDir
-		}
-		if info.IsDir() {
-			return s.fsckCheckDir(path, check, force, askFunc, shadowMap)
+		r.Repaired = append(r.Repaired, e)
+	}
+}
+
+// appendMissing appends the given IDs to ids unless they are already in it
+func appendMissing(ids []string, add ...string) []string {
+OUTER:
+	for _, a := range add {
+		for _, id := range ids {
+			if id == a {
+				continue OUTER
+			}
 		}
-		return s.fsckCheckFile(path, check, force, storeRec, askFunc, shadowMap)
+		ids = append(ids, a)
 	}
+	return ids
 }
 
-// fsckCheckDir checks a directory, mostly for it's permissions
-func (s *Store) fsckCheckDir(fn string, check, force bool, askFunc func(string) bool, sh map[string]struct{}) error {
-	fi, err := os.Stat(fn)
-	if err != nil {
-		fmt.Printf("Failed to check %s: %s\n", fn, err)
-		return nil
+// Fsck checks this stores integrity. With repair set the problems that can
+// be fixed are repaired, each after confirming it with the FsckCallback.
+// Repairs of secrets are committed to git.
+func (s *Store) Fsck(repair bool, out io.Writer) (FsckReport, error) {
+	report := newFsckReport()
+
+	// fixing the store modifies it
+	if repair {
+		unlock, err := s.Lock()
+		if err != nil {
+			return report, err
+		}
+		defer unlock()
 	}
-	// check for shadowing
-	name := s.filenameToName(fn)
-	if _, found := sh[name]; found {
-		fmt.Printf("%s is shadowed by %s", name, fn)
-	}
-	sh[name] = struct{}{}
-	// check if any group or other perms are set,
-	// i.e. check for perms other than rwx------
-	if fi.Mode().Perm()&077 != 0 {
-		fmt.Println(color.CyanString("Wrong permissions for dir %s: %s", fn, fi.Mode().Perm().String()))
-		if !check && (force || askFunc == nil || askFunc("Fix permissions?")) {
-			np := uint32(fi.Mode().Perm() & 0700)
-			fmt.Println(color.GreenString("Fixing permissions from %s to %s", fi.Mode().Perm().String(), os.FileMode(np).Perm().String()))
-			if err := syscall.Chmod(fn, np); err != nil {
-				fmt.Println(color.RedString("Failed to set permissions for %s to rwx------: %s", fn, err))
-			}
+
+	for _, r := range expandGroups(s.recipients) {
+		if kl, err := gpg.ListPublicKeys(r); err != nil || len(kl) < 1 {
+			fmt.Fprintln(out, color.RedString("Public key of recipient %s not found", r))
+			report.MissingKeys = appendMissing(report.MissingKeys, r)
 		}
 	}
-	return nil
+
+	fsckr := &fsckRun{
+		store:    s,
+		resolver: newRecipientResolver(s),
+		repair:   repair,
+		out:      out,
+		report:   &report,
+		shadow:   make(map[string]struct{}, 100),
+		secrets:  make([]string, 0, 100),
+	}
+	if err := filepath.Walk(s.path, fsckr.walk); err != nil {
+		return report, err
+	}
+
+	fsckr.checkSecrets()
+	fsckr.repairSecrets()
+
+	sort.Strings(report.Undecryptable)
+	sort.Strings(report.MissingKeys)
+	sort.Strings(report.Drifted)
+	sort.Strings(report.Unverifiable)
+	return report, nil
+}
+
+// fsckRun holds the state of one run of Fsck
+type fsckRun struct {
+	store    *Store
+	resolver *recipientResolver
+	repair   bool
+	out      io.Writer
+	report   *FsckReport
+	shadow   map[string]struct{}
+	secrets  []string
+}
+
+// ask returns true if the given problem should be fixed
+func (f *fsckRun) ask(question string) bool {
+	if !f.repair {
+		return false
+	}
+	return f.store.fsckFunc == nil || f.store.fsckFunc(question)
 }
 
-func (s *Store) fsckCheckFile(fn string, check, force bool, storeRec gpg.KeyList, askFunc func(string) bool, sh map[string]struct{}) error {
-	fi, err := os.Stat(fn)
+// walk checks every file and folder of the store and collects the secrets
+func (f *fsckRun) walk(path string, info os.FileInfo, err error) error {
 	if err != nil {
-		fmt.Printf("Failed to check %s: %s\n", fn, err)
-		return nil
+		return err
 	}
-	// check if any group or other perms are set,
-	// i.e. check for perms other than rw-------
-	if fi.Mode().Perm()&0177 != 0 {
-		fmt.Println(color.CyanString("Wrong permissions for file %s: %s", fn, fi.Mode().String()))
-		if !check && (force || askFunc == nil || askFunc("Fix permissions?")) {
-			np := uint32(fi.Mode().Perm() & 0600)
-			fmt.Println(color.GreenString("Fixing permissions from %s to %s", fi.Mode().Perm().String(), os.FileMode(np).Perm().String()))
-			if err := syscall.Chmod(fn, np); err != nil {
-				fmt.Println(color.RedString("Failed to set permissions for %s to rw-------: %s", fn, err))
-			}
+	if info.IsDir() && info.Name() == attachmentsDir {
+		f.checkPerms(path, info)
+		if f.checkOrphan(path) {
+			return filepath.SkipDir
 		}
+		return nil
+	}
+	if info.IsDir() && strings.HasPrefix(info.Name(), ".") && path != f.store.path {
+		return filepath.SkipDir
 	}
+	if info.IsDir() {
+		f.checkPerms(path, info)
+		f.checkShadowing(path)
+		return nil
+	}
+	f.checkPerms(path, info)
 	// we check all files (secrets and meta-data) for permissions,
 	// but all other checks are only applied to secrets (which end in .gpg)
-	if !strings.HasSuffix(fn, ".gpg") {
+	if !strings.HasSuffix(path, ".gpg") {
 		return nil
 	}
-	// check for shadowing
-	name := s.filenameToName(fn)
-	if _, found := sh[name]; found {
-		fmt.Println(color.CyanString("%s is shadowed by %s", name, fn))
-	}
-	sh[name] = struct{}{}
-	// check that we can decrypt this file
-	if err := s.fsckCheckSelfDecrypt(fn); err != nil {
-		fmt.Println(color.RedString("No secret key available to decrypt %s. Can not fix", fn))
+	// attachments are checked along with their secret
+	if filepath.Base(filepath.Dir(path)) == attachmentsDir {
 		return nil
 	}
-	// get the IDs this file was encrypted for
-	fileRec, err := gpg.GetRecipients(fn)
+	f.checkShadowing(path)
+	f.secrets = append(f.secrets, f.store.filenameToName(path))
+	return nil
+}
+
+// checkPerms checks that group or others can't access the file or folder.
+// Folders may be rwx------ and files no more than the file mode of the
+// store, rw------- by default.
+func (f *fsckRun) checkPerms(fn string, fi os.FileInfo) {
+	mask := ^f.store.mode() & os.ModePerm
+	if fi.IsDir() {
+		mask = 077
+	}
+	if fi.Mode().Perm()&mask == 0 {
+		return
+	}
+	fmt.Fprintln(f.out, color.CyanString("Wrong permissions for %s: %s", fn, fi.Mode().Perm().String()))
+	f.report.Permissions = append(f.report.Permissions, fn)
+	if !f.ask("Fix permissions?") {
+		return
+	}
+	np := uint32(fi.Mode().Perm() &^ mask)
+	fmt.Fprintln(f.out, color.GreenString("Fixing permissions from %s to %s", fi.Mode().Perm().String(), os.FileMode(np).Perm().String()))
+	if err := syscall.Chmod(fn, np); err != nil {
+		fmt.Fprintln(f.out, color.RedString("Failed to set permissions for %s: %s", fn, err))
+		return
+	}
+	f.report.Repaired = append(f.report.Repaired, fn)
+}
+
+// checkShadowing checks if a secret and a folder have the same name. A
+// folder holding only the attachments of a secret doesn't shadow that secret.
+func (f *fsckRun) checkShadowing(fn string) {
+	if fn == f.store.path {
+		return
+	}
+	if !strings.HasSuffix(fn, ".gpg") && !hasVisibleEntries(fn) {
+		return
+	}
+	name := f.store.filenameToName(fn)
+	if _, found := f.shadow[name]; found {
+		fmt.Fprintln(f.out, color.CyanString("%s is shadowed by %s", name, fn))
+		f.report.Shadowed = append(f.report.Shadowed, name)
+	}
+	f.shadow[name] = struct{}{}
+}
+
+// checkOrphan checks that the secret an attachment folder belongs to exists.
+// It returns true if the folder has been removed.
+func (f *fsckRun) checkOrphan(dir string) bool {
+	name := f.store.filenameToName(filepath.Dir(dir))
+	if _, err := os.Stat(f.store.passfile(name)); err == nil {
+		return false
+	}
+	fmt.Fprintln(f.out, color.CyanString("Attachments of missing secret %s found in %s", name, dir))
+	f.report.Orphans = append(f.report.Orphans, dir)
+	if !f.ask(fmt.Sprintf("Remove the orphaned attachments of %s?", name)) {
+		return false
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		fmt.Fprintln(f.out, color.RedString("Failed to remove %s: %s", dir, err))
+		return false
+	}
+	// only succeeds if the folder held nothing but the attachments
+	_ = os.Remove(filepath.Dir(dir))
+	if err := f.store.gitSave(fmt.Sprintf("Remove orphaned attachments of %s.", name), dir); err != nil {
+		fmt.Fprintln(f.out, color.RedString("Failed to commit the removal of %s: %s", dir, err))
+	}
+	f.report.Repaired = append(f.report.Repaired, dir)
+	return true
+}
+
+// checkSecrets checks that every secret can be decrypted and is encrypted
+// for exactly its recipients. This has to decrypt every secret, which is
+// done in parallel. The recipients of all ciphertexts are listed in batches
+// beforehand.
+func (f *fsckRun) checkSecrets() {
+	packets := make(map[string]gpg.PacketRecipients, len(f.secrets))
+	for i, pr := range f.store.packetRecipients(f.secrets) {
+		packets[f.secrets[i]] = pr
+	}
+
+	var mutex sync.Mutex
+	forEachParallel(f.secrets, func(name string) {
+		f.checkSecret(name, packets[name], &mutex)
+	})
+}
+
+// checkSecret checks a single secret against the recipients listed in its
+// ciphertext, mutex guards the report
+func (f *fsckRun) checkSecret(name string, pr gpg.PacketRecipients, mutex *sync.Mutex) {
+	// symmetric secrets have no recipients that could be checked
+	if f.store.IsSymmetric(name) {
+		fmt.Fprintln(f.out, color.CyanString("%s is encrypted with a passphrase. Skipping recipient checks", name))
+		return
+	}
+	if _, err := f.store.Get(name); err != nil {
+		fmt.Fprintln(f.out, color.RedString("No secret key available to decrypt %s. Can not fix", name))
+		mutex.Lock()
+		f.report.Undecryptable = append(f.report.Undecryptable, name)
+		mutex.Unlock()
+		return
+	}
+	if pr.Err != nil {
+		fmt.Fprintln(f.out, color.RedString("Failed to get recipients of %s: %s", name, pr.Err))
+		return
+	}
+	acl, err := f.store.aclFor(name, f.resolver, pr.IDs)
 	if err != nil {
-		fmt.Println(color.RedString("Failed to get recipients of %s: %s\n", fn, err))
-		return nil
+		fmt.Fprintln(f.out, color.RedString("Failed to get recipients of %s: %s", name, err))
+		return
 	}
-	// check that each recipient of the file is in the current
-	// recipient list
-	for _, rec := range fileRec {
-		if _, err := storeRec.FindKey(rec); err == nil {
-			// the recipient is (still) present in the recipients file of the store
-			continue
-		}
-		// the recipient is not present in the recipients file of the store
-		fmt.Println(color.CyanString("Extra recipient found for %s: %s\n", fn, rec))
-		if !check && (force || askFunc == nil || askFunc("Fix recipients?")) {
-			if err := s.fsckFixRecipients(fn); err != nil {
-				fmt.Println(color.RedString("Failed to fix recipients for %s: %s\n", fn, err))
-			}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	missing := make([]string, 0, 1)
+	for _, e := range acl.Recipients {
+		if e.Key == nil {
+			f.report.MissingKeys = appendMissing(f.report.MissingKeys, e.ID)
+			missing = append(missing, e.ID)
 		}
 	}
-	// check that each recipient of the store can actually decrypt this file
-	for _, key := range storeRec {
-		if err := fsckCheckRecipientsInSubkeys(key, fileRec); err == nil {
-			continue
-		}
-		fmt.Println(color.CyanString("Missing recipient on %s: %s\n", s, key.OneLine()))
-		if !check && (force || askFunc == nil || askFunc("Fix recipients?")) {
-			if err := s.fsckFixRecipients(fn); err != nil {
-				fmt.Println(color.RedString("Failed to fix recipients for %s: %s\n", fn, err))
-			}
+	// without the key it's unknown which, if any, of the extra IDs belongs
+	// to the recipient, re-encrypting wouldn't help either
+	if len(missing) > 0 {
+		fmt.Fprintln(f.out, color.CyanString("Can not check the recipients of %s, the public keys of %s are missing", name, strings.Join(missing, ", ")))
+		f.report.Unverifiable = append(f.report.Unverifiable, name)
+		return
+	}
+	if !acl.Diverges() {
+		return
+	}
+	for _, id := range acl.Extra {
+		fmt.Fprintln(f.out, color.CyanString("Extra recipient found for %s: %s", name, id))
+	}
+	for _, e := range acl.Recipients {
+		if !e.Encrypted {
+			fmt.Fprintln(f.out, color.CyanString("Missing recipient on %s: %s", name, e.ID))
 		}
 	}
-	return nil
+	f.report.Drifted = append(f.report.Drifted, name)
 }
 
-func fsckCheckRecipientsInSubkeys(key gpg.Key, recipients []string) error {
-	for _, rec := range recipients {
-		for k := range key.SubKeys {
-			if strings.HasSuffix(k, rec) {
-				return nil
-			}
+// repairSecrets re-encrypts the drifted secrets for their recipients. This
+// is done one at a time to ask for each secret.
+func (f *fsckRun) repairSecrets() {
+	sort.Strings(f.report.Drifted)
+	for _, name := range f.report.Drifted {
+		if !f.ask(fmt.Sprintf("Re-encrypt %s for its recipients?", name)) {
+			continue
 		}
+		if err := f.store.fsckFixRecipients(name); err != nil {
+			fmt.Fprintln(f.out, color.RedString("Failed to fix recipients for %s: %s", name, err))
+			continue
+		}
+		f.report.Repaired = append(f.report.Repaired, name)
 	}
-	return fmt.Errorf("None of the Recipients matches a subkey")
-}
-
-func (s *Store) fsckCheckSelfDecrypt(fn string) error {
-	_, err := s.Get(s.filenameToName(fn))
-	return err
 }
 
-func (s *Store) fsckFixRecipients(fn string) error {
-	name := s.filenameToName(fn)
-	content, err := s.Get(s.filenameToName(fn))
+// fsckFixRecipients re-encrypts the secret and its attachments for the
+// current recipients
+func (s *Store) fsckFixRecipients(name string) error {
+	content, err := s.Get(name)
 	if err != nil {
 		return err
 	}
-	return s.Set(name, content)
+	if err := s.Set(name, content); err != nil {
+		return err
+	}
+	return s.reencryptAttachments(name)
 }
@@ -0,0 +1,75 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsck(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass second", "second@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("second@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list second key: %s", err)
+	}
+	second := kl[0].Fingerprint
+
+	tempdir, cleanupDir := newTestDir(t, fpr, second)
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo/bar", []byte("secret")))
+	assert.NoError(t, s.AddAttachment("foo/bar", "file", []byte("data")))
+
+	// a clean store has no problems
+	report, err := s.Fsck(false, ioutil.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Problems())
+
+	// encrypted for one of the recipients only
+	drifted := filepath.Join(tempdir, "baz.gpg")
+	assert.NoError(t, gpg.Encrypt(drifted, []byte("other"), []string{fpr}, gpg.EncryptOpts{}))
+	assert.NoError(t, os.Chmod(drifted, 0644))
+	// attachments of a secret that doesn't exist
+	orphan := filepath.Join(tempdir, "gone", attachmentsDir)
+	assert.NoError(t, os.MkdirAll(orphan, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(orphan, "file.gpg"), []byte("junk"), 0600))
+	// a recipient without a public key, foo/bar can't be checked then
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, "foo", "bar"+overrideSuffix), []byte(fpr+"\nDEADBEEF\n"), 0600))
+
+	report, err = s.Fsck(false, ioutil.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"baz"}, report.Drifted)
+	assert.Equal(t, []string{"foo/bar"}, report.Unverifiable)
+	assert.Equal(t, []string{orphan}, report.Orphans)
+	assert.Equal(t, []string{drifted}, report.Permissions)
+	assert.Equal(t, []string{"DEADBEEF"}, report.MissingKeys)
+	assert.Len(t, report.Undecryptable, 0)
+	assert.Len(t, report.Repaired, 0)
+
+	// repair everything else
+	assert.NoError(t, os.Remove(filepath.Join(tempdir, "foo", "bar"+overrideSuffix)))
+	report, err = s.Fsck(true, ioutil.Discard)
+	assert.NoError(t, err)
+	assert.Len(t, report.Repaired, 3)
+	_, err = os.Stat(orphan)
+	assert.True(t, os.IsNotExist(err))
+
+	report, err = s.Fsck(false, ioutil.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Problems())
+	acl, err := s.ACL("baz")
+	assert.NoError(t, err)
+	assert.False(t, acl.Diverges())
+}
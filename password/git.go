@@ -3,6 +3,7 @@ package password
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/hooks"
+	"github.com/justwatchcom/gopass/log"
 )
 
 var (
@@ -29,13 +32,33 @@ func (s *Store) GitInit(signKey string) error {
 		return ErrGitInit
 	}
 
-	cmd := exec.Command("git", "init")
-	cmd.Dir = s.path
+	cmd := s.gitCommand("init")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("Failed to initialize git: %s", err)
 	}
+	if err := s.gitSetup(); err != nil {
+		return err
+	}
+
+	// set GPG signkey
+	if err := s.gitSetSignKey(signKey); err != nil {
+		fmt.Printf("Failed to configure Git GPG Commit signing: %s\n", err)
+	}
+
+	return nil
+}
+
+// gitSetup commits the current contents of a new repository and configures
+// it for the store
+func (s *Store) gitSetup() error {
+	// the search index and the lock are local to each clone
+	for _, pattern := range []string{indexFile, "/" + lockFile} {
+		if err := s.gitExclude(pattern); err != nil {
+			return err
+		}
+	}
 
 	if err := s.gitAdd(s.path); err != nil {
 		return err
@@ -44,7 +67,7 @@ func (s *Store) GitInit(signKey string) error {
 		return err
 	}
 
-	if err := ioutil.WriteFile(filepath.Join(s.path, ".gitattributes"), []byte("*.gpg diff=gpg\n"), fileMode); err != nil {
+	if err := s.writeFile(filepath.Join(s.path, ".gitattributes"), []byte("*.gpg diff=gpg\n")); err != nil {
 		return fmt.Errorf("Failed to initialize git: %s", err)
 	}
 	if err := s.gitAdd(s.path + "/.gitattributes"); err != nil {
@@ -54,8 +77,7 @@ func (s *Store) GitInit(signKey string) error {
 		fmt.Println(color.YellowString("Warning: Failed to commit .gitattributes to git"))
 	}
 
-	cmd = exec.Command("git", "config", "--local", "diff.gpg.binary", "true")
-	cmd.Dir = s.path
+	cmd := s.gitCommand("config", "--local", "diff.gpg.binary", "true")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -63,11 +85,59 @@ func (s *Store) GitInit(signKey string) error {
 		fmt.Printf("Failed to initialize git: %s\n", err)
 	}
 
-	// set GPG signkey
-	if err := s.gitSetSignKey(signKey); err != nil {
-		fmt.Printf("Failed to configure Git GPG Commit signing: %s\n", err)
+	return nil
+}
+
+// gitAttributes marks secrets for the gpg diff driver
+const gitAttributes = "*.gpg diff=gpg"
+
+// GitSetupDiffDriver makes git diff show the plaintext of secrets by running
+// them through the given textconv command. It's only configured in the
+// local repository, so clones don't decrypt on diff, and only works where
+// the private key is available.
+func (s *Store) GitSetupDiffDriver(textconv string) error {
+	if !s.isGit() {
+		return ErrGitNotInit
+	}
+
+	fn := filepath.Join(s.path, ".gitattributes")
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	found := false
+	for _, line := range strings.Split(string(buf), "\n") {
+		if strings.TrimSpace(line) == gitAttributes {
+			found = true
+		}
+	}
+	if !found {
+		if len(buf) > 0 && !bytes.HasSuffix(buf, []byte("\n")) {
+			buf = append(buf, '\n')
+		}
+		buf = append(buf, []byte(gitAttributes+"\n")...)
+		if err := s.writeFile(fn, buf); err != nil {
+			return err
+		}
+		if err := s.gitAdd(fn); err != nil {
+			return err
+		}
+		if err := s.gitCommit("Configure git repository for gpg file diff."); err != nil {
+			return err
+		}
 	}
 
+	for _, kv := range [][2]string{
+		{"diff.gpg.binary", "true"},
+		{"diff.gpg.textconv", textconv},
+	} {
+		cmd := s.gitCommand("config", "--local", kv[0], kv[1])
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to set %s: %s", kv[0], err)
+		}
+	}
 	return nil
 }
 
@@ -76,8 +146,7 @@ func (s *Store) gitSetSignKey(sk string) error {
 		return fmt.Errorf("SignKey not set")
 	}
 
-	cmd := exec.Command("git", "config", "--local", "user.signkey", sk)
-	cmd.Dir = s.path
+	cmd := s.gitCommand("config", "--local", "user.signingkey", sk)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -85,8 +154,7 @@ func (s *Store) gitSetSignKey(sk string) error {
 		return err
 	}
 
-	cmd = exec.Command("git", "config", "--local", "commit.gpgsign", "true")
-	cmd.Dir = s.path
+	cmd = s.gitCommand("config", "--local", "commit.gpgsign", "true")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -95,8 +163,7 @@ func (s *Store) gitSetSignKey(sk string) error {
 
 // Git runs arbitrary git commands on this store and all substores
 func (s *Store) Git(args ...string) error {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = s.path
+	cmd := s.gitCommand(args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
@@ -108,6 +175,40 @@ func (s *Store) Git(args ...string) error {
 	return nil
 }
 
+// gitExclude adds a pattern to .git/info/exclude, so git ignores the
+// matching files of this clone
+func (s *Store) gitExclude(pattern string) error {
+	if !s.isGit() {
+		return ErrGitNotInit
+	}
+	fn := filepath.Join(s.path, ".git", "info", "exclude")
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(buf), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+	if len(buf) > 0 && !bytes.HasSuffix(buf, []byte("\n")) {
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, []byte(pattern+"\n")...)
+	if err := os.MkdirAll(filepath.Dir(fn), dirMode); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, buf, 0644)
+}
+
+// gitCommand creates a git command running in this store
+func (s *Store) gitCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.path
+	log.Debugf("git: %s (in %s)", strings.Join(log.RedactArgs(cmd.Args), " "), s.path)
+	return cmd
+}
+
 // isGit returns true if this stores has a .git folder
 func (s *Store) isGit() bool {
 	return fsutil.IsDir(filepath.Join(s.path, ".git"))
@@ -121,8 +222,7 @@ func (s *Store) gitAdd(files ...string) error {
 
 	args := []string{"add"}
 	args = append(args, files...)
-	cmd := exec.Command("git", args...)
-	cmd.Dir = s.path
+	cmd := s.gitCommand(args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -133,24 +233,156 @@ func (s *Store) gitAdd(files ...string) error {
 	return nil
 }
 
-// gitCommit creates a new git commit with the given commit message
-func (s *Store) gitCommit(msg string) error {
+// gitMove renames a single entry with git mv and commits the rename
+func (s *Store) gitMove(from, to string) error {
 	if !s.isGit() {
 		return ErrGitNotInit
 	}
 
-	cmd := exec.Command("git", "commit", "-m", msg)
-	cmd.Dir = s.path
+	pFrom := s.passfile(from)
+	pTo := s.passfile(to)
+	if !strings.HasPrefix(pFrom, s.path) || !strings.HasPrefix(pTo, s.path) {
+		return ErrSneaky
+	}
+	if !fsutil.IsFile(pFrom) {
+		return ErrNotFound
+	}
+	if s.IsDir(to) {
+		return fmt.Errorf("a folder named %s already exists", to)
+	}
+	if err := os.MkdirAll(filepath.Dir(pTo), dirMode); err != nil {
+		return err
+	}
+
+	cmd := s.gitCommand("mv", "--force", pFrom, pTo)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to commit files to git: %v", err)
+		return fmt.Errorf("failed to move %s to %s in git: %v", from, to, err)
+	}
+
+	if fsutil.IsFile(s.agefile(from)) {
+		cmd := s.gitCommand("mv", "--force", s.agefile(from), s.agefile(to))
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to move the age copy of %s to %s in git: %v", from, to, err)
+		}
+	}
+
+	if s.HasRecipientOverride(from) {
+		cmd := s.gitCommand("mv", "--force", s.overrideFile(from), s.overrideFile(to))
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to move recipient override of %s to %s in git: %v", from, to, err)
+		}
+		s.recipientsChanged()
+	} else if err := s.dropOverride(to); err != nil {
+		return err
+	}
+
+	if s.HasAttachments(from) {
+		if err := os.MkdirAll(filepath.Join(s.path, to), dirMode); err != nil {
+			return err
+		}
+		cmd := s.gitCommand("mv", "--force", s.attachmentDir(from), s.attachmentDir(to))
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to move attachments of %s to %s in git: %v", from, to, err)
+		}
+	}
+
+	if err := s.gitCommit(fmt.Sprintf("Move %s to %s.", from, to)); err != nil {
+		return err
+	}
+
+	if s.autoPush {
+		if err := s.gitPush("", ""); err != nil {
+			if err == ErrGitNoRemote {
+				return nil
+			}
+			return err
+		}
 	}
 
 	return nil
 }
 
+// gitCommit creates a new git commit with the given commit message. If commit
+// signing is enabled the commit is signed with the configured key (or the
+// first recipient of this store). A failed signature falls back to an unsigned
+// commit unless strict signing is enabled. Other failures are returned as is.
+func (s *Store) gitCommit(msg string) error {
+	if !s.isGit() {
+		return ErrGitNotInit
+	}
+
+	if err := hooks.Run(hooks.PreCommit, s.hookEnv(map[string]string{"GOPASS_COMMIT_MSG": msg})); err != nil {
+		return err
+	}
+
+	if !s.signCommits {
+		_, err := s.gitCommitArgs("-m", msg)
+		return err
+	}
+
+	stderr, err := s.gitCommitArgs("-S"+s.gitSignKey(), "-m", msg)
+	if err == nil {
+		return nil
+	}
+	if !isSignFailure(stderr) {
+		return err
+	}
+	if s.signStrict {
+		return fmt.Errorf("failed to sign commit: %v", err)
+	}
+
+	fmt.Println(color.YellowString("Warning: Failed to sign commit. Committing unsigned"))
+	_, err = s.gitCommitArgs("--no-gpg-sign", "-m", msg)
+	return err
+}
+
+// isSignFailure tells if git failed to sign a commit, judging from its output
+// on stderr, e.g. "error: gpg failed to sign the data"
+func isSignFailure(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	return strings.Contains(stderr, "gpg") || strings.Contains(stderr, "sign")
+}
+
+// gitSignKey returns the key used for signing commits. If no key is
+// configured the first recipient of this store is used. An empty string
+// lets git pick the default key.
+func (s *Store) gitSignKey() string {
+	if s.signKey != "" {
+		return s.signKey
+	}
+	if len(s.recipients) > 0 {
+		return s.recipients[0]
+	}
+	return ""
+}
+
+// gitCommitArgs runs git commit with the given arguments. The output of git
+// on stderr is returned as well.
+func (s *Store) gitCommitArgs(args ...string) (string, error) {
+	buf := &bytes.Buffer{}
+	cmd := s.gitCommand(append([]string{"commit"}, args...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, buf)
+
+	if err := cmd.Run(); err != nil {
+		return buf.String(), fmt.Errorf("failed to commit files to git: %v", err)
+	}
+
+	return buf.String(), nil
+}
+
 func (s *Store) gitConfigValue(key string) (string, error) {
 	if !s.isGit() {
 		return "", ErrGitNotInit
@@ -158,8 +390,7 @@ func (s *Store) gitConfigValue(key string) (string, error) {
 
 	buf := &bytes.Buffer{}
 
-	cmd := exec.Command("git", "config", "--get", key)
-	cmd.Dir = s.path
+	cmd := s.gitCommand("config", "--get", key)
 	cmd.Stdout = buf
 	cmd.Stderr = os.Stderr
 
@@ -194,5 +425,9 @@ func (s *Store) gitPush(remote, branch string) error {
 		}
 	}
 
-	return s.Git("push", remote, branch)
+	if err := s.Git("push", remote, branch); err != nil {
+		return err
+	}
+
+	return hooks.Run(hooks.PostSync, s.hookEnv(map[string]string{"GOPASS_REMOTE": remote}))
 }
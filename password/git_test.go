@@ -0,0 +1,41 @@
+package password
+
+import "testing"
+
+func TestGitSignKey(t *testing.T) {
+	for _, tc := range []struct {
+		signKey    string
+		recipients []string
+		out        string
+	}{
+		{"", nil, ""},
+		{"", []string{"DEADBEEF", "FEEDBEEF"}, "DEADBEEF"},
+		{"CAFEBABE", []string{"DEADBEEF"}, "CAFEBABE"},
+	} {
+		s := &Store{
+			signKey:    tc.signKey,
+			recipients: tc.recipients,
+		}
+		if got := s.gitSignKey(); got != tc.out {
+			t.Errorf("Mismatch for %+v: %s != %s", tc, got, tc.out)
+		}
+	}
+}
+
+func TestIsSignFailure(t *testing.T) {
+	for _, tc := range []struct {
+		stderr string
+		out    bool
+	}{
+		{"", false},
+		{"error: gpg failed to sign the data\nfatal: failed to write commit object\n", true},
+		{"gpg: skipped \"DEADBEEF\": No secret key\n", true},
+		{"error: cannot run gpg: No such file or directory\n", true},
+		{"fatal: Unable to create '/tmp/store/.git/index.lock': File exists.\n", false},
+		{"*** Please tell me who you are.\n", false},
+	} {
+		if got := isSignFailure(tc.stderr); got != tc.out {
+			t.Errorf("Mismatch for %q: %t != %t", tc.stderr, got, tc.out)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+const (
+	// maskedLine replaces the password line of a secret in grep results
+	maskedLine = "*****"
+)
+
+// GrepOpts are the options for Grep
+type GrepOpts struct {
+	Workers int  // number of parallel decryptions, defaults to the number of CPUs
+	Unmask  bool // include the password (first line) in the matches
+	// Query is the literal text the regular expression searches for, if
+	// any. Then only the secrets the search indexes don't rule out are
+	// decrypted, see Store.Search.
+	Query string
+}
+
+// Match is a single matching line of a secret
+type Match struct {
+	Name string // Name of the secret
+	Line int    // Line number, starting at 1
+	Text string // Text of the matching line
+}
+
+// Grep decrypts all entries of all stores and returns the lines matching the
+// regular expression. The first line of a secret is considered the password
+// and will be masked unless opts.Unmask is set. Entries that fail to decrypt
+// are reported but don't abort the search.
+func (r *RootStore) Grep(re *regexp.Regexp, opts GrepOpts) ([]Match, error) {
+	names, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Query != "" {
+		names = r.narrowByIndex(names, opts.Query)
+	}
+
+	if opts.Workers < 1 {
+		opts.Workers = runtime.NumCPU()
+	}
+
+	var mutex sync.Mutex
+	matches := make([]Match, 0, 10)
+	forEachIndex(len(names), opts.Workers, func(i int) {
+		name := names[i]
+		// we can't ask for multiple passphrases in parallel
+		if r.IsSymmetric(name) {
+			fmt.Printf("Skipping passphrase encrypted secret %s\n", name)
+			return
+		}
+		// aliases would only duplicate the matches of their target
+		content, err := r.GetRaw(name)
+		if err != nil {
+			fmt.Printf("Failed to decrypt %s: %s\n", name, err)
+			return
+		}
+		m := grepContent(name, content, re, opts.Unmask)
+		mutex.Lock()
+		matches = append(matches, m...)
+		mutex.Unlock()
+	})
+
+	sort.Sort(byNameAndLine(matches))
+	return matches, nil
+}
+
+// grepContent returns all lines of content matching the regular expression
+func grepContent(name string, content []byte, re *regexp.Regexp, unmask bool) []Match {
+	matches := make([]Match, 0, 1)
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		if !re.Match(line) {
+			continue
+		}
+		text := string(line)
+		if i == 0 && !unmask {
+			text = maskedLine
+		}
+		matches = append(matches, Match{
+			Name: name,
+			Line: i + 1,
+			Text: text,
+		})
+	}
+	return matches
+}
+
+// byNameAndLine is a list of matches that can be sorted by the name of
+// the secret and the line number
+type byNameAndLine []Match
+
+func (m byNameAndLine) Len() int      { return len(m) }
+func (m byNameAndLine) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+func (m byNameAndLine) Less(i, j int) bool {
+	if m[i].Name == m[j].Name {
+		return m[i].Line < m[j].Line
+	}
+	return m[i].Name < m[j].Name
+}
@@ -0,0 +1,24 @@
+package password
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrepContent(t *testing.T) {
+	content := []byte("secretpass\nuser: gopher\nurl: golang.org\n")
+
+	m := grepContent("foo", content, regexp.MustCompile("go"), false)
+	assert.Equal(t, []Match{
+		{Name: "foo", Line: 2, Text: "user: gopher"},
+		{Name: "foo", Line: 3, Text: "url: golang.org"},
+	}, m)
+
+	m = grepContent("foo", content, regexp.MustCompile("secret"), false)
+	assert.Equal(t, []Match{{Name: "foo", Line: 1, Text: maskedLine}}, m)
+
+	m = grepContent("foo", content, regexp.MustCompile("secret"), true)
+	assert.Equal(t, []Match{{Name: "foo", Line: 1, Text: "secretpass"}}, m)
+}
@@ -0,0 +1,111 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDir creates a store directory encrypting for the given
+// recipients, returning a function removing it again
+func newTestDir(tb testing.TB, recipients ...string) (string, func()) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		tb.Fatalf("Failed to create tempdir: %s", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(tempdir)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tempdir, gpgID), []byte(strings.Join(recipients, "\n")+"\n"), 0600); err != nil {
+		cleanup()
+		tb.Fatalf("Failed to write %s: %s", gpgID, err)
+	}
+	return tempdir, cleanup
+}
+
+// newTestStore creates a store encrypting for the key of a new test
+// keyring. It returns the store, the fingerprint of the key and a function
+// removing the store and the keyring again.
+func newTestStore(t *testing.T) (*Store, string, func()) {
+	fpr, cleanupKeyring := gpgtest.NewKeyring(t)
+	tempdir, cleanupDir := newTestDir(t, fpr)
+	cleanup := func() {
+		cleanupDir()
+		cleanupKeyring()
+	}
+	s, err := NewStore("", tempdir, nil)
+	if err != nil {
+		cleanup()
+		t.Fatalf("Failed to create store: %s", err)
+	}
+	return s, fpr, cleanup
+}
+
+// newTestRootStore is like newTestStore, but creates a root store with a
+// substore for every given mount point. The stores are placed next to each
+// other, the root store in a folder named root.
+func newTestRootStore(t *testing.T, mounts ...string) (*RootStore, string, func()) {
+	fpr, cleanupKeyring := gpgtest.NewKeyring(t)
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		cleanupKeyring()
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(tempdir)
+		cleanupKeyring()
+	}
+	for _, d := range append([]string{"root"}, mounts...) {
+		if err := os.MkdirAll(filepath.Join(tempdir, d), 0700); err != nil {
+			cleanup()
+			t.Fatalf("Failed to create %s: %s", d, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tempdir, d, gpgID), []byte(fpr+"\n"), 0600); err != nil {
+			cleanup()
+			t.Fatalf("Failed to write %s: %s", gpgID, err)
+		}
+	}
+
+	rs, err := NewRootStore(filepath.Join(tempdir, "root"))
+	if err != nil {
+		cleanup()
+		t.Fatalf("Failed to create root store: %s", err)
+	}
+	for _, alias := range mounts {
+		if err := rs.AddMount(alias, filepath.Join(tempdir, alias)); err != nil {
+			cleanup()
+			t.Fatalf("Failed to mount %s: %s", alias, err)
+		}
+	}
+	return rs, fpr, cleanup
+}
+
+// setGitIdentity sets the author and committer of git commits made by the
+// test, returning a function restoring the environment
+func setGitIdentity(t *testing.T) func() {
+	restore := make([]func(), 0, 4)
+	for _, k := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		for kv, v := range map[string]string{"_NAME": "John Doe", "_EMAIL": "john.doe@gopass.pw"} {
+			k := k + kv
+			old, found := os.LookupEnv(k)
+			assert.NoError(t, os.Setenv(k, v))
+			restore = append(restore, func() {
+				if found {
+					_ = os.Setenv(k, old)
+					return
+				}
+				_ = os.Unsetenv(k)
+			})
+		}
+	}
+	return func() {
+		for _, f := range restore {
+			f()
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/log"
+)
+
+const (
+	// indexFile holds the search index of a store, encrypted for the
+	// recipients of the store. It's local to each clone and kept out of git.
+	indexFile = ".gopass-index.gpg"
+	// indexVersion is bumped whenever the entries gain a field, so indexes
+	// written by older versions are rebuilt on their next use
+	indexVersion = 2
+)
+
+// ErrNoIndex is returned when searching a store without a search index
+var ErrNoIndex = fmt.Errorf("the store has no search index. Run: gopass index")
+
+// searchIndex maps the names of secrets to the words they contain. Secrets
+// with a recipient override and passphrase-only secrets aren't indexed, as
+// the index is readable by all recipients of the store.
+type searchIndex struct {
+	Version int                   `json:"version"`
+	Entries map[string]indexEntry `json:"entries"`
+}
+
+// indexEntry are the words and tags of one secret and the hash of its
+// password, to find reused passwords. Digest is the SHA256 of the ciphertext
+// they were read from, to notice secrets changed outside of gopass, e.g. by
+// git pull.
+type indexEntry struct {
+	Digest string   `json:"digest"`
+	Words  []string `json:"words"`
+	Tags   []string `json:"tags,omitempty"`
+	PwHash string   `json:"pwhash,omitempty"`
+}
+
+// indexPath returns the path of the search index of this store
+func (s *Store) indexPath() string {
+	return filepath.Join(s.path, indexFile)
+}
+
+// HasIndex returns true if this store has a search index
+func (s *Store) HasIndex() bool {
+	return fsutil.IsFile(s.indexPath())
+}
+
+// HasIndex returns true if every store has a search index
+func (r *RootStore) HasIndex() bool {
+	for _, alias := range r.aliases() {
+		if !r.storeByAlias(alias).HasIndex() {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildIndex decrypts all secrets of this store and writes a new search
+// index, encrypted for the recipients of the store. Once the store has an
+// index it's refreshed before every search, see refreshIndex.
+func (s *Store) BuildIndex() error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	idx := &searchIndex{Entries: make(map[string]indexEntry)}
+	if _, err := s.refreshIndex(idx); err != nil {
+		return err
+	}
+	if err := s.saveIndex(idx); err != nil {
+		return err
+	}
+	if err := s.gitExclude(indexFile); err != nil && err != ErrGitNotInit {
+		return err
+	}
+	return nil
+}
+
+// BuildIndex builds the search index of every store
+func (r *RootStore) BuildIndex() error {
+	for _, alias := range r.aliases() {
+		if err := r.storeByAlias(alias).BuildIndex(); err != nil {
+			return fmt.Errorf("failed to index %s: %s", storeName(alias), err)
+		}
+	}
+	return nil
+}
+
+// RemoveIndex removes the search index of this store
+func (s *Store) RemoveIndex() error {
+	if err := os.Remove(s.indexPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// RemoveIndex removes the search index of every store
+func (r *RootStore) RemoveIndex() error {
+	for _, alias := range r.aliases() {
+		if err := r.storeByAlias(alias).RemoveIndex(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search returns the sorted names of the secrets containing every word of
+// the query, ignoring case. A word of the query may be part of a longer word
+// of the secret. The index is refreshed first, so only the secrets changed
+// since it was written are decrypted.
+func (s *Store) Search(query string) ([]string, error) {
+	hits, _, err := s.search(query)
+	return hits, err
+}
+
+// search returns the secrets matching the query like Search and the names
+// of all indexed secrets
+func (s *Store) search(query string) ([]string, map[string]struct{}, error) {
+	unlock, err := s.Lock()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	idx, err := s.loadIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	changed, err := s.refreshIndex(idx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if changed {
+		if err := s.saveIndex(idx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	query = strings.ToLower(query)
+	qw := words([]byte(query))
+	hits := make([]string, 0, 10)
+	indexed := make(map[string]struct{}, len(idx.Entries))
+	for name, e := range idx.Entries {
+		indexed[name] = struct{}{}
+		if e.matches(qw) {
+			hits = append(hits, name)
+		}
+	}
+	sort.Strings(hits)
+	return hits, indexed, nil
+}
+
+// matches returns true if every word of the query is contained in one of
+// the words of the entry
+func (e indexEntry) matches(query []string) bool {
+	for _, q := range query {
+		found := false
+		for _, w := range e.Words {
+			if strings.Contains(w, q) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// narrowByIndex drops the secrets which can't contain the query according to
+// the search indexes of their stores. Secrets of stores without an index and
+// secrets which aren't indexed are kept.
+func (r *RootStore) narrowByIndex(names []string, query string) []string {
+	type result struct {
+		hits    map[string]struct{}
+		indexed map[string]struct{}
+	}
+	results := make(map[string]*result, len(r.mounts)+1)
+	for _, alias := range r.aliases() {
+		s := r.storeByAlias(alias)
+		if !s.HasIndex() {
+			continue
+		}
+		hits, indexed, err := s.search(query)
+		if err != nil {
+			fmt.Println(color.YellowString("Warning: failed to search the index of %s: %s", storeName(alias), err))
+			continue
+		}
+		res := &result{hits: make(map[string]struct{}, len(hits)), indexed: indexed}
+		for _, h := range hits {
+			res.hits[h] = struct{}{}
+		}
+		results[alias] = res
+	}
+
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		s := r.getStore(name)
+		res, found := results[s.alias]
+		if !found {
+			out = append(out, name)
+			continue
+		}
+		sub := strings.TrimPrefix(strings.TrimPrefix(name, s.alias), "/")
+		_, indexed := res.indexed[sub]
+		_, hit := res.hits[sub]
+		if hit || !indexed {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// loadIndex decrypts the search index of this store
+func (s *Store) loadIndex() (*searchIndex, error) {
+	if !s.HasIndex() {
+		return nil, ErrNoIndex
+	}
+	buf, err := s.decrypt("the search index", s.indexPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the search index: %s", err)
+	}
+	idx := &searchIndex{}
+	if err := json.Unmarshal(buf, idx); err != nil {
+		return nil, fmt.Errorf("failed to read the search index: %s", err)
+	}
+	if idx.Entries == nil || idx.Version < indexVersion {
+		idx.Entries = make(map[string]indexEntry)
+	}
+	return idx, nil
+}
+
+// saveIndex encrypts the search index for the recipients of this store. The
+// plaintext is handed to gpg on stdin and never written to disk.
+func (s *Store) saveIndex(idx *searchIndex) error {
+	idx.Version = indexVersion
+	buf, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	opts := s.encryptOpts()
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	rs := s.withRequired(append([]string{}, s.recipients...))
+	if err := s.withFileMode(s.indexPath(), func() error {
+		return gpg.Encrypt(s.indexPath(), buf, rs, opts)
+	}); err != nil {
+		return encryptError(err)
+	}
+	return nil
+}
+
+// refreshIndex brings the index up to date with the secrets on disk. New
+// secrets and those whose ciphertext changed are decrypted, removed ones and
+// those that must not be indexed are dropped. It returns true if the index
+// changed.
+func (s *Store) refreshIndex(idx *searchIndex) (bool, error) {
+	names, err := s.List("")
+	if err != nil {
+		return false, err
+	}
+	exists := make(map[string]struct{}, len(names))
+	stale := make([]string, 0, 10)
+	changed := false
+	for _, name := range names {
+		exists[name] = struct{}{}
+		if !s.indexable(name) {
+			if _, found := idx.Entries[name]; found {
+				delete(idx.Entries, name)
+				changed = true
+			}
+			continue
+		}
+		digest, err := fileDigest(s.passfile(name))
+		if err != nil {
+			return false, err
+		}
+		if e, found := idx.Entries[name]; found && e.Digest == digest {
+			continue
+		}
+		stale = append(stale, name)
+	}
+	for name := range idx.Entries {
+		if _, found := exists[name]; !found {
+			delete(idx.Entries, name)
+			changed = true
+		}
+	}
+	if len(stale) < 1 {
+		return changed, nil
+	}
+
+	log.Debugf("index: decrypting %d new or changed secrets", len(stale))
+	var mutex sync.Mutex
+	forEachParallel(stale, func(name string) {
+		e, err := s.newIndexEntry(name, nil)
+		mutex.Lock()
+		if err != nil {
+			fmt.Printf("Failed to index %s: %s\n", name, err)
+			delete(idx.Entries, name)
+		} else {
+			idx.Entries[name] = e
+		}
+		mutex.Unlock()
+	})
+	return true, nil
+}
+
+// findSecrets returns the sorted names of the secrets matching a query. If
+// the store has a search index the entries are matched with indexed, after
+// refreshing it, and only the secrets which aren't indexed are decrypted and
+// matched with decrypted. Without an index every secret is decrypted, in
+// parallel. Passphrase-only secrets are skipped.
+func (s *Store) findSecrets(indexed func(indexEntry) bool, decrypted func([]byte) bool) ([]string, error) {
+	if !s.HasIndex() {
+		names, err := s.List("")
+		if err != nil {
+			return nil, err
+		}
+		return s.filterSecrets(names, decrypted), nil
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	idx, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	changed, err := s.refreshIndex(idx)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := s.saveIndex(idx); err != nil {
+			return nil, err
+		}
+	}
+
+	names, err := s.List("")
+	if err != nil {
+		return nil, err
+	}
+	found := make([]string, 0, 10)
+	unindexed := make([]string, 0, 10)
+	for _, name := range names {
+		e, ok := idx.Entries[name]
+		if !ok {
+			unindexed = append(unindexed, name)
+			continue
+		}
+		if indexed(e) {
+			found = append(found, name)
+		}
+	}
+	found = append(found, s.filterSecrets(unindexed, decrypted)...)
+	sort.Strings(found)
+	return found, nil
+}
+
+// filterSecrets decrypts the given secrets in parallel and returns the
+// sorted names of those matching. Secrets that can not be decrypted and
+// passphrase-only secrets are skipped.
+func (s *Store) filterSecrets(names []string, match func([]byte) bool) []string {
+	var mutex sync.Mutex
+	found := make([]string, 0, 10)
+	forEachParallel(names, func(name string) {
+		if s.IsSymmetric(name) {
+			return
+		}
+		content, err := s.Get(name)
+		if err != nil {
+			log.Debugf("index: failed to decrypt %s: %s", name, err)
+			return
+		}
+		if !match(content) {
+			return
+		}
+		mutex.Lock()
+		found = append(found, name)
+		mutex.Unlock()
+	})
+
+	sort.Strings(found)
+	return found
+}
+
+// indexable returns true if the given secret may be added to the index,
+// i.e. it's readable by all recipients of the store
+func (s *Store) indexable(name string) bool {
+	return !s.HasRecipientOverride(name) && !s.IsSymmetric(name)
+}
+
+// newIndexEntry returns the index entry of the given secret. If content is
+// nil the secret is decrypted.
+func (s *Store) newIndexEntry(name string, content []byte) (indexEntry, error) {
+	if content == nil {
+		var err error
+		if content, err = s.Get(name); err != nil {
+			return indexEntry{}, err
+		}
+	}
+	digest, err := fileDigest(s.passfile(name))
+	if err != nil {
+		return indexEntry{}, err
+	}
+	return indexEntry{
+		Digest: digest,
+		Words:  words(bytes.ToLower(content)),
+		Tags:   ParseTags(content),
+		PwHash: passwordHash(content),
+	}, nil
+}
+
+// updateIndex applies fn to the search index, if the store has one, and
+// saves it. A failed update is only reported, since the index is refreshed
+// before every search anyway. As this decrypts and encrypts the whole index
+// it's only done by bulk operations, a single secret written, moved or
+// removed is picked up by that refresh instead.
+func (s *Store) updateIndex(fn func(idx *searchIndex) error) {
+	if !s.HasIndex() {
+		return
+	}
+	idx, err := s.loadIndex()
+	if err == nil {
+		if err = fn(idx); err == nil {
+			err = s.saveIndex(idx)
+		}
+	}
+	if err != nil {
+		fmt.Println(color.YellowString("Warning: failed to update the search index: %s", err))
+	}
+}
+
+// indexSecrets adds or replaces the given secrets, keyed by name, in the
+// search index
+func (s *Store) indexSecrets(contents map[string][]byte) {
+	s.updateIndex(func(idx *searchIndex) error {
+		for name, content := range contents {
+			if !s.indexable(name) {
+				delete(idx.Entries, name)
+				continue
+			}
+			e, err := s.newIndexEntry(name, content)
+			if err != nil {
+				return err
+			}
+			idx.Entries[name] = e
+		}
+		return nil
+	})
+}
+
+// reencryptIndex encrypts the search index for the current recipients of
+// the store
+func (s *Store) reencryptIndex() {
+	s.updateIndex(func(*searchIndex) error {
+		return nil
+	})
+}
+
+// redigestIndexed updates the digests of secrets re-encrypted without
+// changing their content
+func (s *Store) redigestIndexed(names []string) {
+	s.updateIndex(func(idx *searchIndex) error {
+		for _, name := range names {
+			e, found := idx.Entries[name]
+			if !found {
+				continue
+			}
+			digest, err := fileDigest(s.passfile(name))
+			if err != nil {
+				return err
+			}
+			e.Digest = digest
+			idx.Entries[name] = e
+		}
+		return nil
+	})
+}
+
+// words returns the sorted, distinct words of the given text. Words are runs
+// of letters and digits.
+func words(text []byte) []string {
+	seen := make(map[string]struct{}, 10)
+	for _, f := range bytes.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		seen[string(f)] = struct{}{}
+	}
+	out := make([]string, 0, len(seen))
+	for w := range seen {
+		out = append(out, w)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// fileDigest returns the hex encoded SHA256 of the given file
+func fileDigest(path string) (string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, fh); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
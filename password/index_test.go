@@ -0,0 +1,118 @@
+package password
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWords(t *testing.T) {
+	assert.Equal(t, []string{"gopher", "org", "user", "you"}, words([]byte("user: gopher\n you, gopher@org!")))
+	assert.Len(t, words([]byte(" :-) ")), 0)
+}
+
+func TestSearchIndex(t *testing.T) {
+	s, fpr, cleanup := newTestStore(t)
+	defer cleanup()
+	tempdir := s.path
+
+	assert.NoError(t, s.Set("web/golang", []byte("pass\nuser: Gopher\n")))
+	assert.NoError(t, s.Set("web/rust", []byte("pass\nuser: ferris\n")))
+
+	_, err := s.Search("gopher")
+	assert.Equal(t, ErrNoIndex, err)
+	assert.NoError(t, s.BuildIndex())
+	assert.True(t, s.HasIndex())
+
+	// the index is encrypted
+	buf, err := ioutil.ReadFile(filepath.Join(tempdir, indexFile))
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(buf, []byte("gopher")))
+	assert.False(t, bytes.Contains(buf, []byte("web/golang")))
+
+	hits, err := s.Search("GOPHER")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/golang"}, hits)
+	hits, err = s.Search("user: fer")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/rust"}, hits)
+	hits, err = s.Search("user")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/golang", "web/rust"}, hits)
+
+	// inserts, changes and deletes leave the index file alone, they are
+	// picked up by the next search
+	buf, err = ioutil.ReadFile(filepath.Join(tempdir, indexFile))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("web/python", []byte("pass\nuser: gopher too\n")))
+	assert.NoError(t, s.Set("web/golang", []byte("pass\nuser: gordon\n")))
+	assert.NoError(t, s.Delete("web/rust"))
+	unchanged, err := ioutil.ReadFile(filepath.Join(tempdir, indexFile))
+	assert.NoError(t, err)
+	assert.Equal(t, buf, unchanged)
+	hits, err = s.Search("gopher")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/python"}, hits)
+	idx, err := s.loadIndex()
+	assert.NoError(t, err)
+	assert.Len(t, idx.Entries, 2)
+	hits, err = s.Search("ferris")
+	assert.NoError(t, err)
+	assert.Len(t, hits, 0)
+
+	// so do renames
+	assert.NoError(t, s.Move("web/python", "web/snake"))
+	hits, err = s.Search("gopher")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/snake"}, hits)
+
+	// secrets changed outside of gopass, e.g. by git pull, are noticed
+	assert.NoError(t, gpg.Encrypt(s.passfile("web/golang"), []byte("pass\nuser: gopher again\n"), []string{fpr}, s.encryptOpts()))
+	assert.NoError(t, gpg.Encrypt(s.passfile("web/elixir"), []byte("pass\nuser: gopher\n"), []string{fpr}, s.encryptOpts()))
+	assert.NoError(t, os.Remove(s.passfile("web/snake")))
+	hits, err = s.Search("gopher")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/elixir", "web/golang"}, hits)
+
+	// secrets with a recipient override aren't indexed
+	assert.NoError(t, s.SetRecipientOverride("web/elixir", []string{fpr}))
+	hits, err = s.Search("gopher")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/golang"}, hits)
+
+	assert.NoError(t, s.RemoveIndex())
+	assert.False(t, s.HasIndex())
+}
+
+func TestGrepIndex(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, cleanupDir := newTestDir(t, fpr)
+	defer cleanupDir()
+
+	r, err := NewRootStore(tempdir)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Set("foo", []byte("pass\nuser: gopher\n")))
+	assert.NoError(t, r.Set("bar", []byte("pass\nuser: ferris\n")))
+	assert.NoError(t, r.BuildIndex())
+	assert.True(t, r.HasIndex())
+
+	// a secret the index doesn't know yet is still searched
+	assert.NoError(t, gpg.Encrypt(filepath.Join(tempdir, "baz.gpg"), []byte("pass\nuser: gopher\n"), []string{fpr}, gpg.EncryptOpts{}))
+	assert.Equal(t, []string{"baz", "foo"}, r.narrowByIndex([]string{"bar", "baz", "foo"}, "gopher"))
+
+	matches, err := r.Grep(regexp.MustCompile("gopher"), GrepOpts{Query: "gopher"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{
+		{Name: "baz", Line: 2, Text: "user: gopher"},
+		{Name: "foo", Line: 2, Text: "user: gopher"},
+	}, matches)
+}
@@ -0,0 +1,146 @@
+package password
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+const (
+	// lockFile is created in the root of every store that is written to
+	lockFile = ".gopass.lock"
+)
+
+var (
+	// lockTimeout is how long Lock waits for another process to release
+	// the lock
+	lockTimeout = 10 * time.Second
+	// lockRetry is the interval between two attempts to get the lock
+	lockRetry = 100 * time.Millisecond
+)
+
+// LockedError is returned by Lock if another process didn't release the lock
+// of the store within lockTimeout
+type LockedError struct {
+	Path string
+	// PID is the process holding the lock, or 0 if it's unknown
+	PID int
+}
+
+// Error implements error
+func (e *LockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("store %s is locked by PID %d", e.Path, e.PID)
+	}
+	return fmt.Sprintf("store %s is locked by another process", e.Path)
+}
+
+// storeLock is the state of the lock of a single store
+type storeLock struct {
+	sync.Mutex
+	count int
+	fh    *os.File
+}
+
+// Lock acquires an exclusive lock on the store, waiting up to lockTimeout
+// for other processes to release it. It must be held while the store is
+// modified and released by calling the returned func, preferably with defer
+// so a panic releases it as well. The lock is a flock(2) on .gopass.lock, so
+// the kernel releases it when the process is killed by a signal.
+// The lock is reentrant, i.e. operations holding it may call other locking
+// operations of the same store. It only excludes other processes and other
+// Store values of the same path: all goroutines using one Store share its
+// lock, so they must not rely on it to exclude each other, e.g. the workers
+// of a bulk operation lock the store it already holds.
+func (s *Store) Lock() (func(), error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.lock.count > 0 {
+		s.lock.count++
+		return s.unlockFunc(), nil
+	}
+
+	if err := os.MkdirAll(s.path, dirMode); err != nil {
+		return nil, err
+	}
+	fn := filepath.Join(s.path, lockFile)
+	fh, err := os.OpenFile(fn, os.O_RDWR|os.O_CREATE, fileMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %s", fn, err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err := syscall.Flock(int(fh.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if err != syscall.EWOULDBLOCK {
+			_ = fh.Close()
+			return nil, fmt.Errorf("failed to lock store %s: %s", s.path, err)
+		}
+		if time.Now().After(deadline) {
+			pid := lockOwner(fn)
+			_ = fh.Close()
+			return nil, &LockedError{Path: s.path, PID: pid}
+		}
+		time.Sleep(lockRetry)
+	}
+
+	// record the owner, so others can tell who is holding the lock
+	if err := fh.Truncate(0); err == nil {
+		_, _ = fh.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	if err := s.gitExclude("/" + lockFile); err != nil && err != ErrGitNotInit {
+		log.Debugf("failed to exclude the lock file from git: %s", err)
+	}
+
+	s.lock.fh = fh
+	s.lock.count = 1
+	return s.unlockFunc(), nil
+}
+
+// unlockFunc returns a func releasing one level of the lock. Calling it
+// more than once has no effect.
+func (s *Store) unlockFunc() func() {
+	once := sync.Once{}
+	return func() {
+		once.Do(s.unlock)
+	}
+}
+
+func (s *Store) unlock() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.lock.count--
+	if s.lock.count > 0 || s.lock.fh == nil {
+		return
+	}
+	_ = syscall.Flock(int(s.lock.fh.Fd()), syscall.LOCK_UN)
+	_ = s.lock.fh.Close()
+	s.lock.fh = nil
+}
+
+// lockOwner returns the PID written to the lock file or 0 if it can't be
+// read
+func lockOwner(fn string) int {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -0,0 +1,172 @@
+package password
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	oldTimeout := lockTimeout
+	lockTimeout = 200 * time.Millisecond
+	defer func() {
+		lockTimeout = oldTimeout
+	}()
+
+	// two stores on the same path behave like two processes
+	s1 := &Store{path: tempdir}
+	s2 := &Store{path: tempdir}
+
+	unlock, err := s1.Lock()
+	assert.NoError(t, err)
+
+	// the lock is reentrant
+	unlockInner, err := s1.Lock()
+	assert.NoError(t, err)
+	unlockInner()
+	unlockInner()
+
+	_, err = s2.Lock()
+	if assert.Error(t, err) {
+		assert.Equal(t, fmt.Sprintf("store %s is locked by PID %d", tempdir, os.Getpid()), err.Error())
+	}
+
+	unlock()
+	unlock2, err := s2.Lock()
+	assert.NoError(t, err)
+	unlock2()
+}
+
+func TestLockGitInit(t *testing.T) {
+	defer setGitIdentity(t)()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	// a store created without git already has a lock file
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, gpgID), []byte("DEADBEEF\n"), 0600))
+	s := &Store{path: tempdir}
+	unlock, err := s.Lock()
+	assert.NoError(t, err)
+	unlock()
+	assert.Equal(t, ErrGitNotInit, s.gitExclude("/"+lockFile))
+
+	// which must not be committed once git is initialized
+	assert.NoError(t, s.GitInit(""))
+	out, err := s.gitCommand("ls-files").Output()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(out), lockFile)
+	unlock, err = s.Lock()
+	assert.NoError(t, err)
+	unlock()
+	out, err = s.gitCommand("status", "--porcelain").Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "", string(out))
+}
+
+func TestLockContention(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	holders := 0
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := &Store{path: tempdir}
+			for j := 0; j < 5; j++ {
+				unlock, err := s.Lock()
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				holders++
+				assert.Equal(t, 1, holders, "lock is held by more than one store")
+				mu.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				holders--
+				mu.Unlock()
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLockShared(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	oldTimeout := lockTimeout
+	lockTimeout = 200 * time.Millisecond
+	defer func() {
+		lockTimeout = oldTimeout
+	}()
+
+	// goroutines using the same store share its lock, but still exclude
+	// another store
+	s := &Store{path: tempdir}
+	held := sync.WaitGroup{}
+	done := sync.WaitGroup{}
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		held.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			unlock, err := s.Lock()
+			held.Done()
+			if !assert.NoError(t, err) {
+				return
+			}
+			<-release
+			unlock()
+		}()
+	}
+	held.Wait()
+	assert.Equal(t, 2, s.lock.count)
+
+	other := &Store{path: tempdir}
+	_, err = other.Lock()
+	assert.Error(t, err)
+
+	close(release)
+	done.Wait()
+	unlock, err := other.Lock()
+	assert.NoError(t, err)
+	unlock()
+}
@@ -0,0 +1,92 @@
+package password
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/log"
+)
+
+// defaultPassRetries is how often the loopback pinentry asks for the key
+// passphrase, unless passretries is set
+const defaultPassRetries = 3
+
+// decrypt decrypts the given file of the named entry. If the loopback
+// pinentry is enabled gopass asks for the passphrase of the private key
+// itself, so no pinentry program is needed.
+func (s *Store) decrypt(name, p string) ([]byte, error) {
+	opts := s.decryptOpts(p)
+	if !s.useLoopback {
+		return gpg.DecryptWith(p, opts)
+	}
+	if !gpg.SupportsLoopback() {
+		fmt.Println(color.YellowString("Warning: %s does not support the loopback pinentry, gpg 2.1 or newer is required", gpg.GPGBin))
+		return gpg.DecryptWith(p, opts)
+	}
+
+	prompt := "the private key to decrypt " + name
+	var accepted func()
+	cached := false
+	ask := func(retry bool) (string, error) {
+		if s.keyPassFunc != nil {
+			kp, err := s.keyPassFunc(prompt, retry)
+			accepted, cached = kp.Accepted, kp.Cached
+			return kp.Passphrase, err
+		}
+		if s.passFunc == nil {
+			return "", fmt.Errorf("no way to ask for the passphrase of the private key")
+		}
+		return s.passFunc(prompt)
+	}
+
+	// a wrong passphrase is asked for again, e.g. if it's outdated in the OS
+	// keyring. Only typed passphrases count as attempts. Without the secret
+	// key gpg fails before asking.
+	retry := false
+	for attempts := 0; ; {
+		asked := false
+		accepted, cached = nil, false
+		out, err := gpg.DecryptLoopback(p, opts, func() (string, error) {
+			asked = true
+			return ask(retry)
+		})
+		if err == nil && accepted != nil {
+			accepted()
+		}
+		if asked && !cached {
+			attempts++
+		}
+		if err != gpg.ErrBadPassphrase || !asked || attempts >= s.passRetries {
+			return out, err
+		}
+		retry = true
+		fmt.Fprintln(os.Stderr, color.YellowString("Bad passphrase, try again (%d of %d)", attempts+1, s.passRetries))
+	}
+}
+
+// decryptOpts returns the options to decrypt the given file. gpg tries all
+// secret keys for secrets with hidden recipients, or for all secrets if
+// tryallsecrets is set. Checking for hidden recipients only reads the
+// first packets of the file.
+func (s *Store) decryptOpts(p string) gpg.DecryptOpts {
+	if s.tryAllSecrets {
+		return gpg.DecryptOpts{TryAllSecrets: true}
+	}
+	hidden, err := gpg.HasHiddenRecipients(p)
+	if err != nil {
+		log.Debugf("decrypt: failed to check %s for hidden recipients: %s", p, err)
+	}
+	return gpg.DecryptOpts{TryAllSecrets: hidden}
+}
+
+// decryptError returns the error of a failed decryption. The reasons you can
+// do something about are passed on, any other error becomes ErrDecrypt.
+func decryptError(err error) error {
+	switch err {
+	case gpg.ErrAgentUnavailable, gpg.ErrNoSecretKey, gpg.ErrBadPassphrase:
+		return err
+	}
+	return ErrDecrypt
+}
@@ -0,0 +1,148 @@
+package password
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptKeyPassphraseRetry(t *testing.T) {
+	_, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if !gpg.SupportsLoopback() {
+		t.Skip("gpg does not support the loopback pinentry")
+	}
+
+	assert.NoError(t, gpg.GenerateKey("protected", "protected@gopass.pw", "passphrase"))
+	kl, err := gpg.ListPrivateKeys("protected@gopass.pw")
+	if err != nil || len(kl) != 1 {
+		t.Fatalf("Failed to list protected key: %s", err)
+	}
+	// make sure the agent doesn't have the passphrase cached
+	_ = exec.Command("gpgconf", "--reload", "gpg-agent").Run()
+
+	tempdir, cleanupDir := newTestDir(t, kl[0].Fingerprint)
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, &RootStore{UseLoopbackPinentry: true})
+	assert.NoError(t, err)
+	assert.NoError(t, gpg.Encrypt(s.passfile("foo"), []byte("geheim"), []string{kl[0].Fingerprint}, gpg.EncryptOpts{}))
+
+	// an outdated passphrase is asked for once more, it doesn't count as an
+	// attempt
+	retries := []bool{}
+	accepted := []string{}
+	s.passRetries = 1
+	s.keyPassFunc = func(prompt string, retry bool) (KeyPassphrase, error) {
+		retries = append(retries, retry)
+		if !retry {
+			return KeyPassphrase{Passphrase: "outdated", Cached: true}, nil
+		}
+		return KeyPassphrase{Passphrase: "passphrase", Accepted: func() {
+			accepted = append(accepted, "passphrase")
+		}}, nil
+	}
+	content, err := s.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "geheim", string(content))
+	assert.Equal(t, []bool{false, true}, retries)
+	// only the right passphrase is accepted
+	assert.Equal(t, []string{"passphrase"}, accepted)
+}
+
+func TestDecryptHiddenRecipients(t *testing.T) {
+	s, fpr, cleanup := newTestStore(t)
+	defer cleanup()
+
+	cmd := exec.Command(gpg.GPGBin, "--batch", "--trust-model", "always", "--throw-keyids", "--encrypt", "--recipient", fpr, "--output", s.passfile("anon"))
+	cmd.Stdin = strings.NewReader("geheim")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to encrypt: %s: %s", err, out)
+	}
+
+	assert.True(t, s.decryptOpts(s.passfile("anon")).TryAllSecrets)
+	content, err := s.Get("anon")
+	assert.NoError(t, err)
+	assert.Equal(t, "geheim", string(content))
+
+	// normal secrets are decrypted as usual, unless tryallsecrets is set
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+	assert.False(t, s.decryptOpts(s.passfile("foo")).TryAllSecrets)
+	s.tryAllSecrets = true
+	assert.True(t, s.decryptOpts(s.passfile("foo")).TryAllSecrets)
+	content, err = s.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(content))
+}
+
+func TestDecryptPassphraseRetries(t *testing.T) {
+	_, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if !gpg.SupportsLoopback() {
+		t.Skip("gpg does not support the loopback pinentry")
+	}
+
+	assert.NoError(t, gpg.GenerateKey("protected", "protected@gopass.pw", "passphrase"))
+	kl, err := gpg.ListPrivateKeys("protected@gopass.pw")
+	if err != nil || len(kl) != 1 {
+		t.Fatalf("Failed to list protected key: %s", err)
+	}
+	protected := kl[0].Fingerprint
+	assert.NoError(t, gpg.GenerateKey("public", "public@gopass.pw", ""))
+	kl, err = gpg.ListPublicKeys("public@gopass.pw")
+	if err != nil || len(kl) != 1 {
+		t.Fatalf("Failed to list public key: %s", err)
+	}
+	public := kl[0].Fingerprint
+	if out, err := exec.Command(gpg.GPGBin, "--batch", "--yes", "--delete-secret-keys", public).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to delete the secret key: %s: %s", err, out)
+	}
+
+	tempdir, cleanupDir := newTestDir(t, protected)
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, &RootStore{UseLoopbackPinentry: true})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPassRetries, s.passRetries)
+	assert.NoError(t, gpg.Encrypt(s.passfile("foo"), []byte("geheim"), []string{protected}, gpg.EncryptOpts{}))
+	assert.NoError(t, gpg.Encrypt(s.passfile("nokey"), []byte("geheim"), []string{public}, gpg.EncryptOpts{}))
+
+	// a typo is forgiven
+	asked := 0
+	s.passFunc = func(string) (string, error) {
+		asked++
+		if asked < 2 {
+			return "typo", nil
+		}
+		return "passphrase", nil
+	}
+	_ = exec.Command("gpgconf", "--reload", "gpg-agent").Run()
+	content, err := s.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "geheim", string(content))
+	assert.Equal(t, 2, asked)
+
+	// but only so often
+	asked = 0
+	s.passFunc = func(string) (string, error) {
+		asked++
+		return "wrong", nil
+	}
+	s.passRetries = 2
+	_ = exec.Command("gpgconf", "--reload", "gpg-agent").Run()
+	_, err = s.Get("foo")
+	assert.Equal(t, gpg.ErrBadPassphrase, err)
+	assert.Equal(t, 2, asked)
+
+	// asking doesn't help without the secret key
+	asked = 0
+	_, err = s.Get("nokey")
+	assert.Equal(t, gpg.ErrNoSecretKey, err)
+	assert.Equal(t, 0, asked)
+}
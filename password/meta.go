@@ -0,0 +1,124 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SecretMeta contains the metadata of a secret that is available without
+// decrypting it
+type SecretMeta struct {
+	Name      string
+	Modified  time.Time
+	Committed time.Time
+}
+
+// Time returns the time of the last change to the secret. The commit time is
+// preferred, since checkouts don't preserve file modification times
+func (m SecretMeta) Time() time.Time {
+	if !m.Committed.IsZero() {
+		return m.Committed
+	}
+	return m.Modified
+}
+
+// ListWithMeta lists all entries in this store along with their timestamps
+func (s *Store) ListWithMeta(prefix string) ([]SecretMeta, error) {
+	// a fresh repository has no commits yet, fall back to the mtime then
+	var commits map[string]time.Time
+	if s.isGit() {
+		commits, _ = s.gitCommitTimes()
+	}
+
+	names, err := s.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	lst := make([]SecretMeta, 0, len(names))
+	for _, name := range names {
+		m := SecretMeta{
+			Name:      name,
+			Committed: commits[name],
+		}
+		if fi, err := os.Stat(s.passfile(name)); err == nil {
+			m.Modified = fi.ModTime()
+		}
+		if prefix != "" {
+			m.Name = prefix + "/" + name
+		}
+		lst = append(lst, m)
+	}
+
+	return lst, nil
+}
+
+// gitCommitTimes returns the time of the most recent commit touching each
+// secret. It uses a single git log call for the whole store.
+func (s *Store) gitCommitTimes() (map[string]time.Time, error) {
+	cmd := s.gitCommand("-c", "core.quotePath=false", "log", "--pretty=format:%x00%ct", "--name-only", "--relative", "--", ".")
+	buf := &bytes.Buffer{}
+	cmd.Stdout = buf
+
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	return parseCommitTimes(buf.String()), nil
+}
+
+// parseCommitTimes parses the output of git log. The commits are listed
+// newest first so the first time seen for a file is the most recent one.
+func parseCommitTimes(out string) map[string]time.Time {
+	times := make(map[string]time.Time, 10)
+	var cur time.Time
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\x00") {
+			ts, err := strconv.ParseInt(strings.TrimPrefix(line, "\x00"), 10, 64)
+			if err != nil {
+				cur = time.Time{}
+				continue
+			}
+			cur = time.Unix(ts, 0)
+			continue
+		}
+		if cur.IsZero() || !strings.HasSuffix(line, ".gpg") {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.ToSlash(line), ".gpg")
+		if _, found := times[name]; !found {
+			times[name] = cur
+		}
+	}
+	return times
+}
+
+// ListWithMeta returns all entries of this store and all substores along with
+// their timestamps, sorted by name
+func (r *RootStore) ListWithMeta() ([]SecretMeta, error) {
+	lst, err := r.store.ListWithMeta("")
+	if err != nil {
+		return nil, err
+	}
+	for _, alias := range r.mountPoints() {
+		substore := r.mounts[alias]
+		if substore == nil {
+			continue
+		}
+		sl, err := substore.ListWithMeta(alias)
+		if err != nil {
+			return nil, err
+		}
+		lst = append(lst, sl...)
+	}
+	sort.Sort(metaByName(lst))
+	return lst, nil
+}
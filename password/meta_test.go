@@ -0,0 +1,80 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCommitTimes(t *testing.T) {
+	out := "\x001500000200\n\nfoo/bar.gpg\n.gpg-id\n\x001500000100\n\nfoo/bar.gpg\nbaz.gpg\n\x00invalid\n\nzab.gpg\n"
+	times := parseCommitTimes(out)
+	assert.Equal(t, map[string]time.Time{
+		"foo/bar": time.Unix(1500000200, 0),
+		"baz":     time.Unix(1500000100, 0),
+	}, times)
+}
+
+func TestListWithMeta(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	mtime := time.Unix(1400000000, 0)
+	for _, fn := range []string{"foo/bar.gpg", "baz.gpg", "new.gpg"} {
+		fn = filepath.Join(tempdir, fn)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fn), 0700))
+		assert.NoError(t, ioutil.WriteFile(fn, []byte("secret"), 0600))
+		assert.NoError(t, os.Chtimes(fn, mtime, mtime))
+	}
+
+	s := &Store{path: tempdir}
+	lst, err := s.ListWithMeta("sub")
+	assert.NoError(t, err)
+	assert.Len(t, lst, 3)
+	for _, m := range lst {
+		assert.True(t, m.Committed.IsZero())
+		assert.Equal(t, mtime.Unix(), m.Time().Unix())
+	}
+
+	// only foo/bar and baz are committed
+	git := func(env []string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tempdir
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	git(nil, "init")
+	git(nil, "add", "foo/bar.gpg", "baz.gpg")
+	git([]string{
+		"GIT_AUTHOR_NAME=John Doe", "GIT_AUTHOR_EMAIL=john.doe@gopass.pw",
+		"GIT_COMMITTER_NAME=John Doe", "GIT_COMMITTER_EMAIL=john.doe@gopass.pw",
+		"GIT_COMMITTER_DATE=1500000000 +0000",
+	}, "-c", "commit.gpgsign=false", "commit", "-q", "-m", "initial")
+
+	lst, err = s.ListWithMeta("")
+	assert.NoError(t, err)
+	got := make(map[string]int64, len(lst))
+	for _, m := range lst {
+		got[m.Name] = m.Time().Unix()
+	}
+	assert.Equal(t, map[string]int64{
+		"baz":     1500000000,
+		"foo/bar": 1500000000,
+		"new":     1400000000,
+	}, got)
+}
@@ -0,0 +1,34 @@
+package password
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileNameSchema compiles the naming scheme of the config. An empty
+// scheme allows any name.
+func compileNameSchema(schema string) (*regexp.Regexp, error) {
+	if schema == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nameschema %q: %s", schema, err)
+	}
+	return re, nil
+}
+
+// checkName returns an error if the given secret doesn't exist yet and its
+// name, relative to the store, doesn't match the naming scheme. Existing
+// secrets can always be written, so secrets predating the scheme can still
+// be edited.
+func (s *Store) checkName(name string) error {
+	if s.nameSchema == nil || s.allowAnyName || s.nameSchema.MatchString(strings.TrimPrefix(name, "/")) {
+		return nil
+	}
+	if found, err := s.Exists(name); err == nil && found {
+		return nil
+	}
+	return fmt.Errorf("%s doesn't follow the naming scheme of the store, names must match %s. Use --force to use it anyway", name, s.nameSchema)
+}
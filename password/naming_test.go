@@ -0,0 +1,49 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameSchema(t *testing.T) {
+	s, _, cleanup := newTestStore(t)
+	defer cleanup()
+	tempdir := s.path
+
+	// a secret predating the naming scheme
+	assert.NoError(t, s.Set("legacy", []byte("secret")))
+
+	_, err := NewStore("", tempdir, &RootStore{NameSchema: "(unclosed"})
+	assert.Error(t, err)
+
+	s, err = NewStore("", tempdir, &RootStore{NameSchema: `^[^/]+/(dev|prod)/[^/]+$`})
+	assert.NoError(t, err)
+
+	for _, name := range []string{"web/prod/gopher", "db/dev/admin"} {
+		assert.NoError(t, s.Set(name, []byte("secret")), name)
+	}
+	for _, name := range []string{"gopher", "web/gopher", "web/staging/gopher", "web/prod/gopher/extra"} {
+		err := s.Set(name, []byte("secret"))
+		if assert.Error(t, err, name) {
+			assert.Contains(t, err.Error(), "names must match ^[^/]+/(dev|prod)/[^/]+$")
+		}
+		found, _ := s.Exists(name)
+		assert.False(t, found, name)
+	}
+	assert.Error(t, s.SetSymmetric("web/gopher", []byte("secret"), "passphrase"))
+
+	// existing secrets can be written
+	assert.NoError(t, s.Set("legacy", []byte("changed")))
+
+	// so can moving them to a conforming name, but not the other way round
+	assert.NoError(t, s.Move("legacy", "legacy/prod/gopher"))
+	assert.Error(t, s.Move("web/prod/gopher", "web/gopher"))
+	assert.NoError(t, s.Copy("db/dev/admin", "db/prod/admin"))
+	assert.Error(t, s.Copy("db/dev/admin", "db/admin"))
+
+	// unless any name is allowed
+	s.allowAnyName = true
+	assert.NoError(t, s.Set("gopher", []byte("secret")))
+	assert.NoError(t, s.Move("web/prod/gopher", "web/gopher"))
+}
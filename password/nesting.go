@@ -0,0 +1,84 @@
+package password
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+)
+
+// CheckNesting returns an error if path is inside the directory of any other
+// store or contains one. A store nested inside another one is also seen as
+// part of the outer store, so its secrets would be encrypted for the wrong
+// recipients. Set AllowNesting to skip this check.
+func (r *RootStore) CheckNesting(path string) error {
+	if r.AllowNesting {
+		return nil
+	}
+	path = fsutil.CleanPath(path)
+	existing := make([]string, 0, len(r.mounts)+1)
+	for _, s := range r.stores() {
+		if s.path != path {
+			existing = append(existing, s.path)
+		}
+	}
+	return detectStoreNesting(path, existing)
+}
+
+// stores returns the root store and all mounted stores
+func (r *RootStore) stores() []*Store {
+	stores := make([]*Store, 0, len(r.mounts)+1)
+	if r.store != nil {
+		stores = append(stores, r.store)
+	}
+	for _, s := range r.mounts {
+		stores = append(stores, s)
+	}
+	return stores
+}
+
+// detectStoreNesting returns an error if path is inside one of the existing
+// store paths or vice versa
+func detectStoreNesting(path string, existing []string) error {
+	path = resolvePath(path)
+	for _, other := range existing {
+		other = resolvePath(other)
+		switch {
+		case path == other:
+			return fmt.Errorf("%s is already used by another store", path)
+		case isSubdir(other, path):
+			return fmt.Errorf("%s is inside the store at %s. Nested stores are not supported, use --allow-nesting to override", path, other)
+		case isSubdir(path, other):
+			return fmt.Errorf("%s contains the store at %s. Nested stores are not supported, use --allow-nesting to override", path, other)
+		}
+	}
+	return nil
+}
+
+// resolvePath returns the absolute path with all symlinks resolved. The
+// path doesn't need to exist yet, in that case the symlinks in the longest
+// existing parent are resolved.
+func resolvePath(path string) string {
+	path = fsutil.CleanPath(path)
+	for dir, rest := path, ""; ; {
+		if p, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(p, rest)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+}
+
+// isSubdir returns true if path is located below base
+func isSubdir(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
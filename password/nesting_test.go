@@ -0,0 +1,74 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectStoreNesting(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	root := filepath.Join(tempdir, "root")
+	link := filepath.Join(tempdir, "link")
+	assert.NoError(t, os.MkdirAll(root, 0700))
+	assert.NoError(t, os.Symlink(root, link))
+
+	for _, tc := range []struct {
+		path     string
+		existing []string
+		ok       bool
+	}{
+		{filepath.Join(tempdir, "sub"), nil, true},
+		{filepath.Join(tempdir, "sub"), []string{root}, true},
+		{filepath.Join(tempdir, "rootsub"), []string{root}, true},
+		{filepath.Join(root, "sub"), []string{root}, false},
+		{filepath.Join(root, "a", "b"), []string{filepath.Join(tempdir, "sub"), root}, false},
+		{tempdir, []string{root}, false},
+		{root, []string{root}, false},
+		{filepath.Join(link, "sub"), []string{root}, false},
+		{filepath.Join(root, "..", "sub"), []string{root}, true},
+	} {
+		err := detectStoreNesting(tc.path, tc.existing)
+		assert.Equal(t, tc.ok, err == nil, "%s in %v: %v", tc.path, tc.existing, err)
+	}
+}
+
+func TestAddMountNested(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	_, _, err = createStore(filepath.Join(tempdir, "root"))
+	assert.NoError(t, err)
+	_, _, err = createStore(filepath.Join(tempdir, "root", "sub"))
+	assert.NoError(t, err)
+
+	rs, err := NewRootStore(filepath.Join(tempdir, "root"))
+	assert.NoError(t, err)
+
+	err = rs.AddMount("sub", filepath.Join(tempdir, "root", "sub"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is inside the store at")
+	assert.Len(t, rs.Mount, 0)
+
+	err = rs.AddMount("all", tempdir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "contains the store at")
+
+	rs.AllowNesting = true
+	assert.NoError(t, rs.AddMount("sub", filepath.Join(tempdir, "root", "sub")))
+}
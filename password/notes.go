@@ -0,0 +1,140 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// notesKey starts the block of free form notes in a secret's body
+	notesKey = "notes"
+	// blockIndent prefixes every line of a block like the notes. Blank lines
+	// of the block are indented as well, so they stay part of it.
+	blockIndent = "  "
+)
+
+// ParseNotes returns the notes stored in the body of a secret. The first line
+// is the password and never holds notes.
+func ParseNotes(content []byte) (string, bool) {
+	return parseBlock(content, notesKey)
+}
+
+// SetNotes returns content with the notes set to the given text, replacing
+// any existing notes. The password and all other lines are kept as they are,
+// new notes are added after the last line.
+func SetNotes(content []byte, notes string) []byte {
+	return setBlock(content, notesKey, notes)
+}
+
+// parseBlock returns the text of the block with the given key, see ParseNotes
+func parseBlock(content []byte, key string) (string, bool) {
+	lines := strings.Split(string(content), "\n")
+	start, end := findBlock(lines, key)
+	if start < 0 {
+		return "", false
+	}
+	text := make([]string, 0, end-start)
+	for _, line := range lines[start+1 : end] {
+		text = append(text, strings.TrimPrefix(line, blockIndent))
+	}
+	return strings.Join(text, "\n"), true
+}
+
+// setBlock returns content with the block with the given key set to text,
+// see SetNotes
+func setBlock(content []byte, key, text string) []byte {
+	block := []string{key + ": |"}
+	for _, line := range strings.Split(text, "\n") {
+		block = append(block, blockIndent+line)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	out := make([]string, 0, len(lines)+len(block)+1)
+	start, end := findBlock(lines, key)
+	if start < 0 {
+		n := len(lines)
+		for n > 1 && lines[n-1] == "" {
+			n--
+		}
+		out = append(out, lines[:n]...)
+		out = append(out, block...)
+		out = append(out, "")
+		return []byte(strings.Join(out, "\n"))
+	}
+	out = append(out, lines[:start]...)
+	out = append(out, block...)
+	out = append(out, lines[end:]...)
+	return []byte(strings.Join(out, "\n"))
+}
+
+// AddNote returns content with note appended to its notes
+func AddNote(content []byte, note string) []byte {
+	if notes, ok := ParseNotes(content); ok {
+		note = notes + "\n" + note
+	}
+	return SetNotes(content, note)
+}
+
+// findBlock returns the index of the line starting the block with the given
+// key and the index of the first line after it. The block ends at the first
+// line that isn't indented, blank lines are part of it if an indented line
+// follows. Both are -1 if there is no such block.
+func findBlock(lines []string, key string) (int, int) {
+	start := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], " ") == key+": |" {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return -1, -1
+	}
+	end := start + 1
+	for i := start + 1; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], blockIndent) {
+			end = i + 1
+			continue
+		}
+		if lines[i] != "" {
+			break
+		}
+	}
+	return start, end
+}
+
+// AppendNote appends a note to a secret. The secret is decrypted, changed
+// and encrypted again while holding the lock of the store, so no other
+// change can get lost. cb is called to confirm the recipients.
+func (s *Store) AppendNote(name, note string, cb RecipientCallback) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if s.IsSymmetric(name) {
+		return fmt.Errorf("can not add notes to %s, it is encrypted with a passphrase", name)
+	}
+	content, err := s.Get(name)
+	if err != nil {
+		return err
+	}
+	return s.SetConfirm(name, AddNote(content, note), cb)
+}
+
+// AppendNote appends a note to a secret, see Store.AppendNote. Notes added
+// to an alias are added to the secret it refers to.
+func (r *RootStore) AppendNote(name, note string, cb RecipientCallback) error {
+	name, err := r.ResolveAlias(name)
+	if err != nil {
+		return err
+	}
+	store := r.getStore(name)
+	if cb == nil {
+		return store.AppendNote(strings.TrimPrefix(name, store.alias), note, nil)
+	}
+	return store.AppendNote(strings.TrimPrefix(name, store.alias), note, func(_ string, rs []string) ([]string, error) {
+		return cb(name, rs)
+	})
+}
@@ -0,0 +1,99 @@
+package password
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotesRoundTrip(t *testing.T) {
+	for _, notes := range []string{
+		"a single line",
+		"first\n\nthird after a blank line",
+		"\nstarts and ends blank\n",
+		"  indented\n\tand tabbed",
+		"notes: |\nlooks like a header",
+		"",
+	} {
+		for _, content := range []string{
+			"secret",
+			"secret\n",
+			"secret\nuser: foo\nurl: https://example.com\n",
+			"secret\nnotes: |\n  old notes\n\n  more\nuser: foo\n",
+		} {
+			out := SetNotes([]byte(content), notes)
+			got, ok := ParseNotes(out)
+			assert.True(t, ok, content)
+			assert.Equal(t, notes, got, content)
+			// setting them again changes nothing
+			assert.Equal(t, string(out), string(SetNotes(out, notes)))
+		}
+	}
+
+	_, ok := ParseNotes([]byte("secret\nuser: foo\n"))
+	assert.False(t, ok)
+	// the password is never parsed as notes
+	_, ok = ParseNotes([]byte("notes: |\n  foo"))
+	assert.False(t, ok)
+}
+
+func TestSetNotes(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		notes string
+		out   string
+	}{
+		{"secret", "foo", "secret\nnotes: |\n  foo\n"},
+		{"secret\nuser: foo\n\n", "a\n\nb", "secret\nuser: foo\nnotes: |\n  a\n  \n  b\n"},
+		{"secret\nnotes: |\n  old\n\n  older\nuser: foo\n", "new", "secret\nnotes: |\n  new\nuser: foo\n"},
+	} {
+		assert.Equal(t, tc.out, string(SetNotes([]byte(tc.in), tc.notes)), tc.in)
+	}
+
+	// other metadata is kept around the notes
+	exp := time.Date(2017, 5, 3, 14, 0, 0, 0, time.UTC)
+	content := SetExpiry(SetNotes([]byte("secret\nuser: foo\n"), "line\n\n"), exp)
+	notes, ok := ParseNotes(content)
+	assert.True(t, ok)
+	assert.Equal(t, "line\n\n", notes)
+	got, ok := ParseExpiry(content)
+	assert.True(t, ok)
+	assert.Equal(t, exp, got.UTC())
+	assert.Equal(t, "secret", string(content[:6]))
+}
+
+func TestAddNote(t *testing.T) {
+	content := AddNote([]byte("secret\nuser: foo\n"), "first")
+	content = AddNote(content, "second\n\nthird")
+	notes, ok := ParseNotes(content)
+	assert.True(t, ok)
+	assert.Equal(t, "first\nsecond\n\nthird", notes)
+	assert.Equal(t, "secret\nuser: foo\nnotes: |\n  first\n  second\n  \n  third\n", string(content))
+}
+
+func TestAppendNote(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, cleanupDir := newTestDir(t, fpr)
+	defer cleanupDir()
+
+	r := &RootStore{Path: tempdir}
+	s, err := NewStore("", tempdir, r)
+	assert.NoError(t, err)
+	r.store = s
+
+	assert.NoError(t, s.Set("foo", []byte("secret\nuser: foo\n")))
+	assert.NoError(t, r.SetAlias("bar", "foo", nil))
+
+	assert.NoError(t, r.AppendNote("foo", "first\n", nil))
+	// notes added to an alias end up in the secret
+	assert.NoError(t, r.AppendNote("bar", "second", nil))
+	assert.Equal(t, ErrNotFound, r.AppendNote("baz", "nope", nil))
+
+	content, err := s.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nuser: foo\nnotes: |\n  first\n  \n  second\n", string(content))
+}
@@ -0,0 +1,178 @@
+package password
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/log"
+)
+
+const (
+	// overrideSuffix is appended to the name of a secret to get the name of
+	// its recipient override, i.e. foo/bar.gpg-id overrides the recipients
+	// for foo/bar.gpg
+	overrideSuffix = ".gpg-id"
+)
+
+// overrideFile returns the name of the recipient override on disk for the
+// given secret. Attachments share the override of their secret.
+func (s *Store) overrideFile(name string) string {
+	if i := strings.Index(name, "/"+attachmentsDir+"/"); i >= 0 {
+		name = name[:i]
+	}
+	return fsutil.CleanPath(filepath.Join(s.path, name) + overrideSuffix)
+}
+
+// HasRecipientOverride returns true if the given secret has its own set of
+// recipients
+func (s *Store) HasRecipientOverride(name string) bool {
+	return fsutil.IsFile(s.overrideFile(name))
+}
+
+// recipientsFor returns the recipients the given secret must be encrypted
+// for. A recipient override next to the secret takes precedence over the
+// recipients of the store.
+func (s *Store) recipientsFor(name string) []string {
+	if fn := s.overrideFile(name); strings.HasPrefix(fn, s.path) && fsutil.IsFile(fn) {
+		fh, err := os.Open(fn)
+		if err == nil {
+			defer func() {
+				_ = fh.Close()
+			}()
+			if rs := unmarshalRecipients(fh); len(rs) > 0 {
+				log.Debugf("recipients: %s uses the override %s: %s", name, fn, strings.Join(rs, ", "))
+				return s.withRequired(rs)
+			}
+		}
+		fmt.Printf("Failed to read recipient override %s. Using the recipients of the store\n", fn)
+	}
+	rs := make([]string, len(s.recipients))
+	copy(rs, s.recipients)
+	log.Debugf("recipients: %s uses the recipients of the store %s: %s", name, s.path, strings.Join(rs, ", "))
+	return s.withRequired(rs)
+}
+
+// RecipientOverride returns the recipient override of the given secret or
+// an empty list if it has none
+func (s *Store) RecipientOverride(name string) []string {
+	if !s.HasRecipientOverride(name) {
+		return []string{}
+	}
+	return s.recipientsFor(name)
+}
+
+// SetRecipientOverride encrypts the given secret, and its attachments, only
+// for the given recipients instead of the recipients of the store
+func (s *Store) SetRecipientOverride(name string, ids []string) error {
+	fn := s.overrideFile(name)
+	if !strings.HasPrefix(fn, s.path) {
+		return ErrSneaky
+	}
+	if len(ids) < 1 {
+		return fmt.Errorf("a recipient override needs at least one recipient")
+	}
+	if missing := s.missingRequired(ids); len(missing) > 0 {
+		return fmt.Errorf("a recipient override must include the required recipients of the store: %s", strings.Join(missing, ", "))
+	}
+	if found, err := s.Exists(name); err != nil || !found {
+		return ErrNotFound
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.writeFile(fn, marshalRecipients(ids)); err != nil {
+		return err
+	}
+	s.recipientsChanged()
+	return s.reencryptOverride(name, fmt.Sprintf("Override recipients of %s.", name), fn)
+}
+
+// RemoveRecipientOverride encrypts the given secret for the recipients of
+// the store again
+func (s *Store) RemoveRecipientOverride(name string) error {
+	fn := s.overrideFile(name)
+	if !strings.HasPrefix(fn, s.path) {
+		return ErrSneaky
+	}
+	if !fsutil.IsFile(fn) {
+		return ErrNotFound
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(fn); err != nil {
+		return err
+	}
+	s.recipientsChanged()
+	return s.reencryptOverride(name, fmt.Sprintf("Remove recipient override of %s.", name), fn)
+}
+
+// reencryptOverride re-encrypts a secret and its attachments after its
+// recipients changed and commits them along with the override file
+func (s *Store) reencryptOverride(name, msg, fn string) error {
+	opts := s.encryptOpts()
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	files, err := s.reencryptEntry(name, opts, newRecipientResolver(s))
+	if err != nil {
+		return err
+	}
+	return s.gitSave(msg, append(files, fn)...)
+}
+
+// dropOverride removes the recipient override of a secret, if any, and
+// stages the removal in git
+func (s *Store) dropOverride(name string) error {
+	if !s.HasRecipientOverride(name) {
+		return nil
+	}
+	if err := os.Remove(s.overrideFile(name)); err != nil {
+		return fmt.Errorf("Failed to remove recipient override: %v", err)
+	}
+	s.recipientsChanged()
+	if err := s.gitAdd(s.overrideFile(name)); err != nil && err != ErrGitNotInit {
+		return err
+	}
+	return nil
+}
+
+// copyOverride copies the recipient override of a secret, if any. An
+// override of the destination is replaced, or removed if the source has none,
+// so the copy is encrypted for the same recipients as the original.
+func copyOverride(src *Store, from string, dst *Store, to string) error {
+	if !src.HasRecipientOverride(from) {
+		return dst.dropOverride(to)
+	}
+	buf, err := ioutil.ReadFile(src.overrideFile(from))
+	if err != nil {
+		return err
+	}
+	fn := dst.overrideFile(to)
+	if !strings.HasPrefix(fn, dst.path) {
+		return ErrSneaky
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), dirMode); err != nil {
+		return err
+	}
+	if err := dst.writeFile(fn, buf); err != nil {
+		return err
+	}
+	dst.recipientsChanged()
+	if err := dst.gitAdd(fn); err != nil && err != ErrGitNotInit {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package password
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipientOverride(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass second", "second@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("second@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list second key: %s", err)
+	}
+	second := kl[0].Fingerprint
+
+	tempdir, cleanupDir := newTestDir(t, fpr, second)
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+
+	numRecipients := func(fn string) int {
+		recs, err := gpg.GetRecipients(filepath.Join(tempdir, fn))
+		assert.NoError(t, err, fn)
+		return len(recs)
+	}
+
+	assert.NoError(t, s.Set("foo/bar", []byte("secret")))
+	assert.NoError(t, s.Set("baz", []byte("other")))
+	assert.Equal(t, 2, numRecipients("foo/bar.gpg"))
+	assert.False(t, s.HasRecipientOverride("foo/bar"))
+	assert.Equal(t, []string{}, s.RecipientOverride("foo/bar"))
+
+	assert.Equal(t, ErrNotFound, s.SetRecipientOverride("missing", []string{fpr}))
+	assert.Error(t, s.SetRecipientOverride("foo/bar", []string{}))
+	assert.NoError(t, s.SetRecipientOverride("foo/bar", []string{fpr}))
+	assert.True(t, s.HasRecipientOverride("foo/bar"))
+	assert.Equal(t, []string{fpr}, s.RecipientOverride("foo/bar"))
+	assert.Equal(t, 1, numRecipients("foo/bar.gpg"))
+
+	// the override takes precedence on every write, including attachments
+	// and re-encryption, but only for this secret
+	assert.NoError(t, s.Set("foo/bar", []byte("changed")))
+	assert.Equal(t, 1, numRecipients("foo/bar.gpg"))
+	assert.NoError(t, s.AddAttachment("foo/bar", "att", []byte("data")))
+	assert.Equal(t, 1, numRecipients("foo/bar/.attachments/att.gpg"))
+	assert.NoError(t, s.reencrypt())
+	assert.Equal(t, 1, numRecipients("foo/bar.gpg"))
+	assert.Equal(t, 1, numRecipients("foo/bar/.attachments/att.gpg"))
+	assert.Equal(t, 2, numRecipients("baz.gpg"))
+
+	// the override file is not an entry
+	lst, err := s.List("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"baz", "foo/bar"}, lst)
+
+	// copies keep the override
+	assert.NoError(t, s.Copy("foo/bar", "copy"))
+	assert.True(t, s.HasRecipientOverride("copy"))
+	assert.Equal(t, 1, numRecipients("copy.gpg"))
+
+	assert.NoError(t, s.RemoveRecipientOverride("foo/bar"))
+	assert.False(t, s.HasRecipientOverride("foo/bar"))
+	assert.Equal(t, 2, numRecipients("foo/bar.gpg"))
+	assert.Equal(t, 2, numRecipients("foo/bar/.attachments/att.gpg"))
+
+	// copying over a secret replaces its override as well
+	assert.NoError(t, s.Copy("baz", "copy"))
+	assert.False(t, s.HasRecipientOverride("copy"))
+	assert.Equal(t, 2, numRecipients("copy.gpg"))
+
+	assert.NoError(t, s.SetRecipientOverride("copy", []string{fpr}))
+	assert.NoError(t, s.Delete("copy"))
+	assert.False(t, s.HasRecipientOverride("copy"))
+}
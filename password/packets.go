@@ -0,0 +1,42 @@
+package password
+
+import (
+	"runtime"
+
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// packetBatchSize is the maximum number of secrets listed by one gpg
+// process, see gpg.ListPacketRecipientsBatch
+const packetBatchSize = 500
+
+// packetRecipients returns the key IDs the given secrets are encrypted for,
+// as listed in their ciphertexts. The secrets are listed in batches, which
+// run in parallel. The results are in the order of the given names.
+func (s *Store) packetRecipients(names []string) []gpg.PacketRecipients {
+	res := make([]gpg.PacketRecipients, len(names))
+	if len(names) < 1 {
+		return res
+	}
+
+	// at least one batch per CPU, unless there are only few secrets
+	size := (len(names) + runtime.NumCPU() - 1) / runtime.NumCPU()
+	if size > packetBatchSize {
+		size = packetBatchSize
+	}
+	batches := (len(names) + size - 1) / size
+	forEachIndex(batches, runtime.NumCPU(), func(i int) {
+		start := i * size
+		end := start + size
+		if end > len(names) {
+			end = len(names)
+		}
+		paths := make([]string, 0, end-start)
+		for _, name := range names[start:end] {
+			paths = append(paths, s.passfile(name))
+		}
+		// every batch writes a distinct range of res
+		copy(res[start:end], gpg.ListPacketRecipientsBatch(paths))
+	})
+	return res
+}
@@ -0,0 +1,84 @@
+package password
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// ParseFileMode parses the octal file mode of the files of a store, e.g.
+// 0400. It must not grant more than 0600, but the owner must be able to read
+// the files. An empty mode is 0600.
+func ParseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return fileMode, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || os.FileMode(m)&^fileMode != 0 || m&0400 == 0 {
+		return 0, fmt.Errorf("invalid filemode %q. Use 0600 or a stricter mode that's readable by the owner, e.g. 0400", mode)
+	}
+	return os.FileMode(m), nil
+}
+
+// mode returns the file mode of this store
+func (s *Store) mode() os.FileMode {
+	if s.fileMode == 0 {
+		return fileMode
+	}
+	return s.fileMode
+}
+
+// writeFile writes a file of this store, see withFileMode
+func (s *Store) writeFile(path string, buf []byte) error {
+	return s.withFileMode(path, func() error {
+		return ioutil.WriteFile(path, buf, s.mode())
+	})
+}
+
+// withFileMode calls write to write the given file and then enforces the
+// file mode of the store on it, whatever the umask or the tool that wrote it,
+// e.g. gpg, made of it. Folders of the store above it are made accessible
+// by the owner only. Files which aren't writable because of a strict file
+// mode are made writable for the owner first.
+func (s *Store) withFileMode(path string, write func() error) error {
+	if fi, err := os.Stat(path); err == nil && fi.Mode().Perm()&0200 == 0 {
+		if err := os.Chmod(path, fi.Mode().Perm()|0200); err != nil {
+			return err
+		}
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	return s.enforceMode(path)
+}
+
+// enforceMode sets the file mode of the store on the given file and removes
+// the permissions of group and others from the folders above it
+func (s *Store) enforceMode(path string) error {
+	if err := os.Chmod(path, s.mode()); err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	for strings.HasPrefix(dir, s.path) {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if perm := fi.Mode().Perm(); perm&^dirMode != 0 {
+			log.Debugf("perms: restricting %s from %s", dir, perm)
+			if err := os.Chmod(dir, perm&dirMode); err != nil {
+				return err
+			}
+		}
+		if dir == s.path {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return nil
+}
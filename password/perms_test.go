@@ -0,0 +1,87 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFileMode(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		out os.FileMode
+		ok  bool
+	}{
+		{"", 0600, true},
+		{"0600", 0600, true},
+		{"600", 0600, true},
+		{"0400", 0400, true},
+		{"0644", 0, false},
+		{"0700", 0, false},
+		{"0200", 0, false},
+		{"rw", 0, false},
+	} {
+		mode, err := ParseFileMode(tc.in)
+		if !tc.ok {
+			assert.Error(t, err, tc.in)
+			continue
+		}
+		assert.NoError(t, err, tc.in)
+		assert.Equal(t, tc.out, mode, tc.in)
+	}
+}
+
+func TestFileMode(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	// the mode must not depend on the umask
+	umask := syscall.Umask(0)
+	defer syscall.Umask(umask)
+
+	tempdir, cleanupDir := newTestDir(t, fpr)
+	defer cleanupDir()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempdir, "open"), 0755))
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo/bar", []byte("secret")))
+	assert.NoError(t, s.Set("open/baz", []byte("secret")))
+	for fn, mode := range map[string]os.FileMode{
+		"foo/bar.gpg":  0600,
+		"open/baz.gpg": 0600,
+		"foo":          0700,
+		"open":         0700,
+	} {
+		fi, err := os.Stat(filepath.Join(tempdir, fn))
+		assert.NoError(t, err)
+		assert.Equal(t, mode, fi.Mode().Perm(), fn)
+	}
+
+	// a stricter mode is kept when secrets are overwritten
+	s, err = NewStore("", tempdir, &RootStore{FileMode: "0400"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo/bar", []byte("secret")))
+	assert.NoError(t, s.Set("foo/bar", []byte("secret2")))
+	fi, err := os.Stat(filepath.Join(tempdir, "foo", "bar.gpg"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0400), fi.Mode().Perm())
+	content, err := s.Get("foo/bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret2", string(content))
+
+	// fsck flags the files written with the default mode
+	report, err := s.Fsck(false, ioutil.Discard)
+	assert.NoError(t, err)
+	assert.Contains(t, report.Permissions, filepath.Join(tempdir, "open", "baz.gpg"))
+	assert.NotContains(t, report.Permissions, filepath.Join(tempdir, "foo", "bar.gpg"))
+
+	_, err = NewStore("", tempdir, &RootStore{FileMode: "0644"})
+	assert.Error(t, err)
+}
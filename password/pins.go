@@ -0,0 +1,180 @@
+package password
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// Pins map recipients to the fingerprint their key must have, so a key
+// substituted by a keyserver or an import is refused
+type Pins map[string]string
+
+// PinMismatch is a recipient whose keys don't match its pin
+type PinMismatch struct {
+	// ID is the recipient as written in the recipients of the store
+	ID string
+	// Pinned is the fingerprint it's pinned to
+	Pinned string
+	// Found are the fingerprints of the other keys found for it
+	Found []string
+}
+
+// PinError is returned if the key of a pinned recipient was substituted.
+// Nothing is encrypted for or imported from such a key.
+type PinError struct {
+	Mismatches []PinMismatch
+}
+
+// Error implements error
+func (e *PinError) Error() string {
+	lines := make([]string, 0, len(e.Mismatches))
+	for _, m := range e.Mismatches {
+		lines = append(lines, fmt.Sprintf("%s is pinned to %s, but found %s", m.ID, m.Pinned, strings.Join(m.Found, ", ")))
+	}
+	return "KEY PIN MISMATCH, the keys of these recipients may have been substituted:\n - " +
+		strings.Join(lines, "\n - ") +
+		"\nRemove the wrong keys from your keyring, or verify the new key with its owner and update keypins in your config"
+}
+
+// loadPins returns the key pins of this store. They are kept in the config
+// of the user, not in the store, so nobody with write access to a shared
+// store can change them.
+func (s *Store) loadPins() Pins {
+	pins := make(Pins, len(s.pins))
+	for id, fpr := range s.pins {
+		pins[id] = normalizeFingerprint(fpr)
+	}
+	return pins
+}
+
+// normalizeFingerprint strips the 0x prefix and spaces gpg prints in
+// fingerprints
+func normalizeFingerprint(fpr string) string {
+	return strings.ToUpper(strings.Replace(strings.TrimPrefix(fpr, "0x"), " ", "", -1))
+}
+
+// checkPin compares the keys found for a recipient against its pin. Every
+// key must have the pinned fingerprint, e.g. another key with the same
+// email is a mismatch.
+func checkPin(id, pinned string, kl gpg.KeyList) (PinMismatch, bool) {
+	m := PinMismatch{ID: id, Pinned: pinned, Found: make([]string, 0, 1)}
+	for _, k := range kl {
+		if k.Fingerprint != pinned {
+			m.Found = append(m.Found, k.Fingerprint)
+		}
+	}
+	return m, len(m.Found) < 1
+}
+
+// verifyPins checks the keys in the keyring of the given recipients which
+// are pinned. Recipients without a pin or without a key are skipped.
+func (s *Store) verifyPins(recipients []string) error {
+	pins := s.loadPins()
+	if len(pins) < 1 {
+		return nil
+	}
+	mismatches := make([]PinMismatch, 0, 1)
+	seen := make(map[string]bool, len(recipients))
+	ids := append(append([]string{}, recipients...), expandGroups(recipients)...)
+	for _, id := range ids {
+		pinned, found := pins[id]
+		if !found || seen[id] {
+			continue
+		}
+		seen[id] = true
+		kl, err := gpg.ListPublicKeys(id)
+		if err != nil {
+			return fmt.Errorf("failed to list the keys of %s: %s", id, err)
+		}
+		if m, ok := checkPin(id, pinned, kl); !ok {
+			mismatches = append(mismatches, m)
+		}
+	}
+	if len(mismatches) > 0 {
+		return &PinError{Mismatches: mismatches}
+	}
+	return nil
+}
+
+// VerifyPins checks the keys in the keyring of all pinned recipients of
+// this store against their pins. A PinError lists the mismatches.
+func (s *Store) VerifyPins() error {
+	pins := s.loadPins()
+	ids := make([]string, 0, len(pins))
+	for id := range pins {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return s.verifyPins(ids)
+}
+
+// verifyImportPin checks the key of a recipient in the given file before
+// it's imported
+func (s *Store) verifyImportPin(id, fn string) error {
+	pins := s.loadPins()
+	pinned, found := pins[id]
+	if !found || !fsutil.IsFile(fn) {
+		return nil
+	}
+	kl, err := gpg.KeysInFile(fn)
+	if err != nil {
+		return fmt.Errorf("failed to read the key of %s from %s: %s", id, fn, err)
+	}
+	if m, ok := checkPin(id, pinned, kl); !ok {
+		return &PinError{Mismatches: []PinMismatch{m}}
+	}
+	return nil
+}
+
+// PinRecipients pins the given recipients to the fingerprint of their key
+// in the keyring. Each must match exactly one key. A recipient pinned to
+// another key can't be pinned again, its pin has to be removed first.
+// The pins are only kept in memory, see RootStore.PinRecipients.
+func (s *Store) PinRecipients(ids ...string) error {
+	pins := s.loadPins()
+	for _, id := range ids {
+		kl, err := gpg.ListPublicKeys(id)
+		if err != nil {
+			return fmt.Errorf("failed to list the keys of %s: %s", id, err)
+		}
+		if len(kl) != 1 {
+			return fmt.Errorf("%s matches %d keys, pin the fingerprint of one of them in keypins instead", id, len(kl))
+		}
+		if pinned, found := pins[id]; found {
+			if m, ok := checkPin(id, pinned, kl); !ok {
+				return &PinError{Mismatches: []PinMismatch{m}}
+			}
+		}
+		pins[id] = kl[0].Fingerprint
+	}
+	s.pins = pins
+	return nil
+}
+
+// CheckRecipientPins checks the keys of the given recipients of a secret
+// against the pins of its store
+func (r *RootStore) CheckRecipientPins(name string, recipients []string) error {
+	return r.getStore(name).verifyPins(recipients)
+}
+
+// PinRecipients pins the keys of the given recipients in the given store.
+// Without any recipients all recipients of the store are pinned. The pins
+// are recorded in KeyPins, the config has to be written afterwards.
+func (r *RootStore) PinRecipients(store string, ids ...string) error {
+	sub := r.getStore(store)
+	if len(ids) < 1 {
+		ids = sub.recipients
+	}
+	if err := sub.PinRecipients(ids...); err != nil {
+		return err
+	}
+	if r.KeyPins == nil {
+		r.KeyPins = make(map[string]Pins, len(r.mounts)+1)
+	}
+	r.KeyPins[sub.alias] = sub.pins
+	return nil
+}
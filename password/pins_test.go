@@ -0,0 +1,95 @@
+package password
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPins(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, cleanupDir := newTestDir(t, gpgtest.Email)
+	defer cleanupDir()
+
+	r, err := NewRootStore(tempdir)
+	assert.NoError(t, err)
+	assert.NoError(t, r.PinRecipients(""))
+	assert.Equal(t, map[string]Pins{"": {gpgtest.Email: fpr}}, r.KeyPins)
+	// the pins are kept in the config, not in the store
+	assert.False(t, fsutil.IsFile(filepath.Join(tempdir, ".gpg-pins")))
+
+	s, err := NewStore("", tempdir, &RootStore{KeyPins: r.KeyPins})
+	assert.NoError(t, err)
+	assert.Equal(t, Pins{gpgtest.Email: fpr}, s.loadPins())
+	assert.NoError(t, s.VerifyPins())
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+
+	// the key of the recipient as exported by its owner may be imported
+	good := filepath.Join(tempdir, "good.asc")
+	assert.NoError(t, gpg.ExportPublicKey(fpr, good))
+	assert.NoError(t, s.verifyImportPin(gpgtest.Email, good))
+	assert.NoError(t, s.verifyImportPin(gpgtest.Email, filepath.Join(tempdir, "missing.asc")))
+
+	// another key with the same email is refused, for encrypting and importing
+	if err := gpg.GenerateKey("gopass mallory", gpgtest.Email, ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys(gpgtest.Email)
+	if err != nil || len(kl) != 2 {
+		t.Fatalf("Failed to list the second key: %s", err)
+	}
+	other := kl[0].Fingerprint
+	if other == fpr {
+		other = kl[1].Fingerprint
+	}
+	want := &PinError{Mismatches: []PinMismatch{{ID: gpgtest.Email, Pinned: fpr, Found: []string{other}}}}
+	assert.Equal(t, want, s.VerifyPins())
+	assert.Equal(t, want, s.Set("foo", []byte("new secret")))
+	err = s.PinRecipients(gpgtest.Email)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "matches 2 keys")
+
+	bad := filepath.Join(tempdir, "bad.asc")
+	assert.NoError(t, gpg.ExportPublicKey(other, bad))
+	assert.Equal(t, want, s.verifyImportPin(gpgtest.Email, bad))
+
+	// recipients without a pin aren't checked
+	assert.NoError(t, s.verifyPins([]string{other}))
+
+	assert.Equal(t, "AB12CD34", normalizeFingerprint("0xab12 cd34"))
+}
+
+func TestPinsOverride(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass second", "second@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("second@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list second key: %s", err)
+	}
+	second := kl[0].Fingerprint
+
+	tempdir, cleanupDir := newTestDir(t, fpr)
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+	assert.NoError(t, s.SetRecipientOverride("foo", []string{second}))
+
+	// the store recipients have no pin, but the override of foo doesn't
+	// match its pin
+	s.pins = Pins{second: fpr}
+	err = s.reencryptAll("re-encrypt")
+	assert.Error(t, err)
+	assert.IsType(t, &PinError{}, err)
+}
@@ -0,0 +1,121 @@
+package password
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// reencryptCost is roughly how long decrypting and encrypting one small
+// secret takes, used to estimate the duration of a re-encryption
+const reencryptCost = 150 * time.Millisecond
+
+// Plan is the impact of changing the recipients of a store, see
+// Store.RecipientChangePlan
+type Plan struct {
+	// Secrets is the number of secrets that are re-encrypted. Overrides of
+	// them keep their recipient override.
+	Secrets   int
+	Overrides int
+	// Symmetric secrets aren't re-encrypted
+	Symmetric int
+	// Duration is a rough estimate of how long the re-encryption takes
+	Duration time.Duration
+	Added    []string
+	Removed  []string
+	// Missing are the new recipients without a public key in the keyring
+	Missing []string
+	Expired gpg.KeyList
+	Weak    gpg.KeyList
+	// Untrusted keys are neither expired nor trusted enough for gpg
+	Untrusted gpg.KeyList
+	// RemovedRequired are required recipients left out of the new ones
+	RemovedRequired []string
+	// LocksOut is set if none of the new recipients has a secret key in the
+	// keyring, i.e. the user can't decrypt the secrets after the change
+	LocksOut bool
+}
+
+// Problems returns true if the plan found any key that can't or shouldn't be
+// encrypted for, a locked out user or removed required recipients
+func (p Plan) Problems() bool {
+	return len(p.Missing) > 0 || len(p.Expired) > 0 || len(p.Weak) > 0 ||
+		len(p.Untrusted) > 0 || len(p.RemovedRequired) > 0 || p.LocksOut
+}
+
+// RecipientChangePlan reports what changing the recipients of this store to
+// the given ones would do, without changing anything. The secrets are only
+// counted, none is decrypted, and the keys of the new recipients are checked.
+// Groups are expanded.
+func (s *Store) RecipientChangePlan(recipients []string) (Plan, error) {
+	p := Plan{
+		Added:           make([]string, 0, len(recipients)),
+		Removed:         make([]string, 0, len(s.recipients)),
+		Missing:         make([]string, 0, 1),
+		Expired:         make(gpg.KeyList, 0, 1),
+		Weak:            make(gpg.KeyList, 0, 1),
+		Untrusted:       make(gpg.KeyList, 0, 1),
+		RemovedRequired: make([]string, 0, 1),
+	}
+
+	entries, err := s.List("")
+	if err != nil {
+		return p, err
+	}
+	res := newRecipientResolver(s)
+	for _, e := range entries {
+		if s.IsSymmetric(e) {
+			p.Symmetric++
+			continue
+		}
+		p.Secrets++
+		if res.hasOverride(e) {
+			p.Overrides++
+		}
+	}
+	p.Duration = time.Duration(p.Secrets) * reencryptCost / time.Duration(runtime.NumCPU())
+
+	for _, r := range recipients {
+		if !containsRecipient(s.recipients, r, res.publicKeys) {
+			p.Added = append(p.Added, r)
+		}
+	}
+	for _, r := range s.recipients {
+		if !containsRecipient(recipients, r, res.publicKeys) {
+			p.Removed = append(p.Removed, r)
+		}
+	}
+	if !s.dropRequired {
+		p.RemovedRequired = append(p.RemovedRequired, s.missingRequiredKeys(recipients, res.publicKeys)...)
+	}
+
+	p.LocksOut = true
+	for _, r := range expandGroups(recipients) {
+		kl, err := gpg.ListPublicKeys(r)
+		if err != nil || len(kl) < 1 {
+			p.Missing = append(p.Missing, r)
+			continue
+		}
+		k := kl[0]
+		switch {
+		case k.IsExpired():
+			p.Expired = append(p.Expired, k)
+		case !k.IsUseable():
+			p.Untrusted = append(p.Untrusted, k)
+		}
+		if k.IsWeak(s.minKeyBits) {
+			p.Weak = append(p.Weak, k)
+		}
+		if sk, err := gpg.ListPrivateKeys(k.Fingerprint); err == nil && len(sk) > 0 {
+			p.LocksOut = false
+		}
+	}
+	return p, nil
+}
+
+// RecipientChangePlan reports what changing the recipients of the given
+// store would do, see Store.RecipientChangePlan
+func (r *RootStore) RecipientChangePlan(store string, recipients []string) (Plan, error) {
+	return r.getStore(store).RecipientChangePlan(recipients)
+}
@@ -0,0 +1,99 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipientChangePlan(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	// a colleague's key, only the public key is in our keyring
+	_, cleanupOther := gpgtest.NewKeyring(t)
+	if err := gpg.GenerateKey("gopass colleague", "colleague@gopass.pw", ""); err != nil {
+		cleanupOther()
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+	pub := filepath.Join(tempdir, "colleague.asc")
+	assert.NoError(t, gpg.ExportPublicKey("colleague@gopass.pw", pub))
+	cleanupOther()
+
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+	assert.NoError(t, gpg.ImportPublicKey(pub))
+	kl, err := gpg.ListPublicKeys("colleague@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list the colleague's key: %s", err)
+	}
+	other := kl[0].Fingerprint
+
+	storeDir := filepath.Join(tempdir, "store")
+	assert.NoError(t, os.MkdirAll(storeDir, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(storeDir, gpgID), []byte(fpr+"\n"), 0600))
+	s, err := NewStore("", storeDir, &RootStore{AllowExpired: true})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+	assert.NoError(t, s.Set("bar/baz", []byte("secret")))
+	assert.NoError(t, s.SetRecipientOverride("bar/baz", []string{fpr}))
+	assert.NoError(t, s.SetSymmetric("sym", []byte("secret"), "passphrase"))
+
+	// adding a recipient
+	p, err := s.RecipientChangePlan([]string{fpr, other})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, p.Secrets)
+	assert.Equal(t, 1, p.Overrides)
+	assert.Equal(t, 1, p.Symmetric)
+	assert.True(t, p.Duration > 0)
+	assert.Equal(t, []string{other}, p.Added)
+	assert.Len(t, p.Removed, 0)
+	assert.False(t, p.LocksOut)
+	// the colleague's key isn't signed by us
+	assert.Len(t, p.Untrusted, 1)
+	assert.True(t, p.Problems())
+
+	// replacing ourselves by the colleague locks us out
+	p, err = s.RecipientChangePlan([]string{other})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{other}, p.Added)
+	assert.Equal(t, []string{fpr}, p.Removed)
+	assert.True(t, p.LocksOut)
+
+	// as does a key we don't have
+	p, err = s.RecipientChangePlan([]string{"nobody@gopass.pw"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nobody@gopass.pw"}, p.Missing)
+	assert.True(t, p.LocksOut)
+
+	// keeping ourselves is fine, given by any ID of the key
+	p, err = s.RecipientChangePlan([]string{fpr[24:]})
+	assert.NoError(t, err)
+	assert.Len(t, p.Added, 0)
+	assert.Len(t, p.Removed, 0)
+	assert.False(t, p.LocksOut)
+	assert.False(t, p.Problems())
+
+	// leaving out a required recipient is reported
+	s.required = []string{fpr}
+	p, err = s.RecipientChangePlan([]string{other})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{fpr}, p.RemovedRequired)
+	s.required = []string{}
+
+	// nothing was changed
+	assert.Equal(t, []string{fpr}, s.recipients)
+	rs, err := s.readRecipients()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{fpr}, rs)
+}
@@ -5,12 +5,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/justwatchcom/gopass/audit"
 	"github.com/justwatchcom/gopass/fsutil"
 	"github.com/justwatchcom/gopass/gpg"
 )
@@ -23,6 +23,12 @@ const (
 
 // AddRecipient adds a new recipient to the list
 func (s *Store) AddRecipient(id string) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	for _, k := range s.recipients {
 		if k == id {
 			return fmt.Errorf("Recipient already in store")
@@ -40,6 +46,12 @@ func (s *Store) AddRecipient(id string) error {
 
 // RemoveRecipient will remove the given recipient from the store
 func (s *Store) RemoveRecipient(id string) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// we try to get the public key info for this ID from gpg
 	// but if this key is not available on this machine we
 	// just try to remove it literally
@@ -61,6 +73,9 @@ func (s *Store) RemoveRecipient(id string) error {
 		}
 		nk = append(nk, k)
 	}
+	if err := s.checkRequired(s.recipients, nk); err != nil {
+		return err
+	}
 	s.recipients = nk
 
 	if err := s.saveRecipients(); err != nil {
@@ -72,6 +87,26 @@ func (s *Store) RemoveRecipient(id string) error {
 
 // Load all Recipients from the .gpg-id file into a list of Recipients.
 func (s *Store) loadRecipients() ([]string, error) {
+	keys, err := s.readRecipients()
+	if err != nil {
+		return []string{}, err
+	}
+
+	if !s.loadKeys {
+		return keys, nil
+	}
+
+	for _, r := range keys {
+		if err := s.importMissingPublicKey(r); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	return keys, nil
+}
+
+// readRecipients reads the .gpg-id file without importing any keys
+func (s *Store) readRecipients() ([]string, error) {
 	// open recipient list (store/.gpg-id)
 	f, err := os.Open(s.idFile())
 	if err != nil {
@@ -84,41 +119,72 @@ func (s *Store) loadRecipients() ([]string, error) {
 		}
 	}()
 
-	keys := unmarshalRecipients(f)
+	return unmarshalRecipients(f), nil
+}
 
+// importMissingPublicKey imports the public key of the given recipient from
+// this store if it's missing from the keyring. It respects the loadkeys
+// setting and asks the user before importing any key material.
+func (s *Store) importMissingPublicKey(r string) error {
 	if !s.loadKeys {
-		return keys, nil
+		return nil
 	}
 
-	for _, r := range keys {
-		// check if this recipient is missing
-		// we could list all keys outside the loop and just do the lookup here
-		// but this way we ensure to use the exact same lookup logic as
-		// gpg does on encryption
-		kl, err := gpg.ListPublicKeys(r)
-		if err != nil {
-			fmt.Printf("Failed to get public key for %s: %s\n", r, err)
-			continue
-		}
-		if len(kl) > 0 {
-			continue
+	// check if this recipient is missing
+	// we could list all keys outside the loop and just do the lookup here
+	// but this way we ensure to use the exact same lookup logic as
+	// gpg does on encryption
+	kl, err := gpg.ListPublicKeys(r)
+	if err != nil {
+		return fmt.Errorf("Failed to get public key for %s: %s", r, err)
+	}
+	if len(kl) > 0 {
+		return nil
+	}
+
+	// we need to ask the user before importing
+	// any key material into his keyring!
+	decision := audit.AutoImported
+	if s.importFunc != nil {
+		if !s.importFunc(r) {
+			return s.logKeyImport(r, audit.Declined)
 		}
+		decision = audit.Imported
+	}
 
-		// we need to ask the user before importing
-		// any key material into his keyring!
-		if s.importFunc != nil {
-			if !s.importFunc(r) {
-				continue
-			}
+	// never import a key substituted for a pinned recipient
+	if err := s.verifyImportPin(r, filepath.Join(s.path, keyDir, r)); err != nil {
+		if lerr := s.logKeyImport(r, audit.Failed); lerr != nil {
+			fmt.Println(lerr)
 		}
+		return err
+	}
 
-		// try to load this recipient
-		if err := s.importPublicKey(r); err != nil {
-			fmt.Printf("Failed to import public key for %s: %s\n", r, err)
+	// try to load this recipient
+	if err := s.importPublicKey(r); err != nil {
+		if lerr := s.logKeyImport(r, audit.Failed); lerr != nil {
+			fmt.Println(lerr)
 		}
+		return fmt.Errorf("Failed to import public key for %s: %s", r, err)
 	}
+	return s.logKeyImport(r, decision)
+}
 
-	return keys, nil
+// logKeyImport records the decision about importing the key of the given
+// recipient from this store in the key import log
+func (s *Store) logKeyImport(r, decision string) error {
+	if !audit.Enabled() {
+		return nil
+	}
+	fn := filepath.Join(s.path, keyDir, r)
+	rec := audit.ImportRecord{Fingerprint: r, Source: fn, Decision: decision}
+	if kl, err := gpg.KeysInFile(fn); err == nil && len(kl) > 0 {
+		rec = audit.NewImportRecord(kl[0], fn, decision)
+	}
+	if err := audit.LogKeyImport(rec); err != nil {
+		return fmt.Errorf("Failed to log the import of the public key for %s: %s", r, err)
+	}
+	return nil
 }
 
 // Save all Recipients in memory to the .gpg-id file on disk.
@@ -129,9 +195,14 @@ func (s *Store) saveRecipients() error {
 	}
 
 	// save recipients to store/.gpg-id
-	if err := ioutil.WriteFile(s.idFile(), marshalRecipients(s.recipients), fileMode); err != nil {
+	if err := s.writeFile(s.idFile(), marshalRecipients(s.recipients)); err != nil {
+		return err
+	}
+	s.recipientsChanged()
+	if err := s.pruneRequired(); err != nil {
 		return err
 	}
+	s.reencryptIndex()
 
 	if !s.persistKeys {
 		return nil
@@ -241,3 +312,33 @@ func (s *Store) importPublicKey(r string) error {
 
 	return gpg.ImportPublicKey(filename)
 }
+
+// WeakRecipientKeys returns the keys of this store's recipients which are
+// considered weak, see gpg.Key.Weakness. Recipients without a public key in
+// the keyring are skipped.
+func (s *Store) WeakRecipientKeys() (gpg.KeyList, error) {
+	if len(s.recipients) < 1 {
+		return gpg.KeyList{}, nil
+	}
+	kl, err := gpg.ListPublicKeys(expandGroups(s.recipients)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recipients: %s", err)
+	}
+	weak := make(gpg.KeyList, 0, len(kl))
+	for _, k := range kl {
+		if k.IsWeak(s.minKeyBits) {
+			weak = append(weak, k)
+		}
+	}
+	return weak, nil
+}
+
+// expandGroups replaces gpg groups in the given recipients by their members.
+// gpg expands groups when encrypting, but not when listing keys.
+func expandGroups(ids []string) []string {
+	groups, err := gpg.ListGroups()
+	if err != nil {
+		return ids
+	}
+	return gpg.ExpandGroups(ids, groups)
+}
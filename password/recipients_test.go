@@ -143,3 +143,22 @@ func TestListRecipients(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, genRecs, s.recipients)
 }
+
+func TestWeakRecipientKeys(t *testing.T) {
+	s, fpr, cleanup := newTestStore(t)
+	defer cleanup()
+	tempdir := s.path
+
+	// the test key has 2048 bits
+	weak, err := s.WeakRecipientKeys()
+	assert.NoError(t, err)
+	assert.Len(t, weak, 0)
+
+	s, err = NewStore("", tempdir, &RootStore{MinKeyBits: 4096})
+	assert.NoError(t, err)
+	weak, err = s.WeakRecipientKeys()
+	assert.NoError(t, err)
+	if assert.Len(t, weak, 1) {
+		assert.Equal(t, fpr, weak[0].Fingerprint)
+	}
+}
@@ -0,0 +1,49 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reencrypt decrypts the secret named prefix, or all secrets below it, and
+// encrypts them again for their current recipients. The content and the
+// recipients don't change, but the ciphertext uses the current keys of the
+// recipients, e.g. after a new encryption subkey has been added. All secrets
+// are committed at once. An empty prefix re-encrypts the whole store.
+func (s *Store) Reencrypt(prefix string) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := s.List("")
+	if err != nil {
+		return err
+	}
+	prefix = strings.Trim(prefix, "/")
+	names := NamesBelow(entries, prefix)
+	if len(names) < 1 {
+		return ErrNotFound
+	}
+
+	msg := fmt.Sprintf("Re-encrypt %s.", prefix)
+	if prefix == "" {
+		msg = "Re-encrypt all secrets."
+	}
+	failed, err := s.reencryptEntries(names, msg)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to re-encrypt %d of %d secrets", failed, len(names))
+	}
+	return nil
+}
+
+// Reencrypt re-encrypts the secret named prefix, or all secrets below it, for
+// their current recipients
+func (r *RootStore) Reencrypt(prefix string) error {
+	store := r.getStore(prefix)
+	return store.Reencrypt(strings.TrimPrefix(prefix, store.alias))
+}
@@ -0,0 +1,53 @@
+package password
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReencrypt(t *testing.T) {
+	s, _, cleanup := newTestStore(t)
+	defer cleanup()
+	tempdir := s.path
+
+	content := map[string][]byte{
+		"foo/bar": []byte("secret\nuser: gopher\n"),
+		"foo/baz": []byte("no trailing newline"),
+		"other":   []byte("other"),
+	}
+	for name, c := range content {
+		assert.NoError(t, s.Set(name, c))
+	}
+	assert.NoError(t, s.AddAttachment("foo/bar", "file", []byte{0, 1, 2}))
+
+	ciphertext := func(fn string) []byte {
+		buf, err := ioutil.ReadFile(filepath.Join(tempdir, fn))
+		assert.NoError(t, err)
+		return buf
+	}
+	before := map[string][]byte{
+		"foo/bar.gpg":                   ciphertext("foo/bar.gpg"),
+		"foo/bar/.attachments/file.gpg": ciphertext("foo/bar/.attachments/file.gpg"),
+		"other.gpg":                     ciphertext("other.gpg"),
+	}
+
+	assert.Equal(t, ErrNotFound, s.Reencrypt("missing"))
+	assert.NoError(t, s.Reencrypt("foo"))
+
+	// the ciphertext changed, the content is the same
+	assert.False(t, bytes.Equal(before["foo/bar.gpg"], ciphertext("foo/bar.gpg")))
+	assert.False(t, bytes.Equal(before["foo/bar/.attachments/file.gpg"], ciphertext("foo/bar/.attachments/file.gpg")))
+	assert.Equal(t, before["other.gpg"], ciphertext("other.gpg"))
+	for name, c := range content {
+		got, err := s.Get(name)
+		assert.NoError(t, err)
+		assert.Equal(t, c, got, name)
+	}
+	att, err := s.GetAttachment("foo/bar", "file")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, att)
+}
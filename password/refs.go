@@ -0,0 +1,168 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxRefDepth limits the length of reference chains
+	maxRefDepth = 8
+)
+
+var (
+	// ErrRefCycle is returned if field references refer to each other
+	ErrRefCycle = fmt.Errorf("Reference cycle detected")
+	// ErrRefDepth is returned if a reference chain is too long
+	ErrRefDepth = fmt.Errorf("Reference chain is too long")
+)
+
+// FieldRef is a field of a secret whose value refers to a field of another
+// secret or of the same secret, e.g. `token: gopass://other/secret#token`
+type FieldRef struct {
+	// Key is the field holding the reference
+	Key string
+	// Secret is the secret referred to, empty for the same secret
+	Secret string
+	// Field is the field referred to, passwordField for the first line
+	Field string
+}
+
+// ParseRef parses the value of a field referring to another field. The
+// forms are gopass://some/secret#field, gopass://some/secret for the
+// password of a secret and gopass://#field for a field of the same secret.
+func ParseRef(value string) (string, string, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, aliasPrefix) {
+		return "", "", false
+	}
+	secret, field := value[len(aliasPrefix):], passwordField
+	if i := strings.LastIndex(secret, "#"); i >= 0 {
+		secret, field = secret[:i], secret[i+1:]
+	}
+	secret = strings.Trim(secret, "/")
+	if field == "" || (secret == "" && field == passwordField) {
+		return "", "", false
+	}
+	return secret, field, true
+}
+
+// ParseRefs returns the references in the key: value lines of a secret.
+// The password and an alias are never references.
+func ParseRefs(content []byte) []FieldRef {
+	refs := make([]FieldRef, 0, 1)
+	_, body := SplitSecret(content)
+	for _, line := range strings.Split(string(body), "\n") {
+		key, value, ok := parseField(line)
+		if !ok {
+			continue
+		}
+		if secret, field, ok := ParseRef(value); ok {
+			refs = append(refs, FieldRef{Key: key, Secret: secret, Field: field})
+		}
+	}
+	return refs
+}
+
+// refResolver resolves the references of a secret. Every secret referred
+// to is decrypted once.
+type refResolver struct {
+	store    *RootStore
+	contents map[string][]byte
+	// seen are the fields on the current chain, to detect cycles
+	seen map[string]struct{}
+}
+
+// content returns the content of a secret
+func (res *refResolver) content(name string) ([]byte, error) {
+	if content, found := res.contents[name]; found {
+		return content, nil
+	}
+	content, err := res.store.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", name, err)
+	}
+	res.contents[name] = content
+	return content, nil
+}
+
+// value returns the value of a field of a secret, following references
+func (res *refResolver) value(name, field string) (string, error) {
+	key := name + "#" + field
+	if _, found := res.seen[key]; found {
+		return "", ErrRefCycle
+	}
+	if len(res.seen) >= maxRefDepth {
+		return "", ErrRefDepth
+	}
+	res.seen[key] = struct{}{}
+	defer delete(res.seen, key)
+
+	content, err := res.content(name)
+	if err != nil {
+		return "", err
+	}
+	value, found := Field(content, field)
+	if !found {
+		return "", fmt.Errorf("%s has no field %s", name, field)
+	}
+	if field == passwordField {
+		return value, nil
+	}
+	secret, target, ok := ParseRef(value)
+	if !ok {
+		return value, nil
+	}
+	if secret == "" {
+		secret = name
+	}
+	return res.value(secret, target)
+}
+
+// ResolveRefs substitutes the references in the fields of the given content
+// of a secret with the values they refer to. References to the same secret
+// are resolved against the content.
+func (r *RootStore) ResolveRefs(name string, content []byte) ([]byte, error) {
+	refs := ParseRefs(content)
+	if len(refs) < 1 {
+		return content, nil
+	}
+	name = strings.Trim(name, "/")
+	res := &refResolver{
+		store:    r,
+		contents: map[string][]byte{name: content},
+		seen:     make(map[string]struct{}, maxRefDepth),
+	}
+
+	pw, body := SplitSecret(content)
+	lines := strings.Split(string(body), "\n")
+	for i, line := range lines {
+		key, value, ok := parseField(line)
+		if !ok {
+			continue
+		}
+		if _, _, ok := ParseRef(value); !ok {
+			continue
+		}
+		resolved, err := res.value(name, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s of %s: %s", key, name, err)
+		}
+		lines[i] = key + ": " + resolved
+	}
+	out := append([]byte{}, pw...)
+	out = append(out, '\n')
+	return append(out, strings.Join(lines, "\n")...), nil
+}
+
+// GetResolved returns the content of a secret like Get. If resolve is set
+// the references in its fields are substituted with the values they refer
+// to, see ResolveRefs. To change a secret use Get, which keeps the
+// references.
+func (r *RootStore) GetResolved(name string, resolve bool) ([]byte, error) {
+	content, err := r.Get(name)
+	if err != nil || !resolve {
+		return content, err
+	}
+	return r.ResolveRefs(name, content)
+}
@@ -0,0 +1,80 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRef(t *testing.T) {
+	for in, out := range map[string][2]string{
+		"gopass://foo/bar#user":  {"foo/bar", "user"},
+		" gopass://foo/bar/ ":    {"foo/bar", "password"},
+		"gopass://#user":         {"", "user"},
+		"gopass://foo#a#b":       {"foo#a", "b"},
+		"gopass://foo#":          {"", ""},
+		"gopass://":              {"", ""},
+		"gopass://#password":     {"", ""},
+		"https://foo.com/#token": {"", ""},
+	} {
+		secret, field, ok := ParseRef(in)
+		assert.Equal(t, out[0], secret, in)
+		assert.Equal(t, out[1], field, in)
+		assert.Equal(t, out[1] != "", ok, in)
+	}
+
+	assert.Equal(t, []FieldRef{
+		{Key: "token", Secret: "shared/api", Field: "token"},
+		{Key: "login", Secret: "", Field: "user"},
+	}, ParseRefs([]byte("gopass://not/a#ref\nuser: gopher\ntoken: gopass://shared/api#token\nlogin: gopass://#user\nnote gopass://foo\n")))
+}
+
+func TestResolveRefs(t *testing.T) {
+	rs, _, cleanup := newTestRootStore(t, "sub")
+	defer cleanup()
+
+	assert.NoError(t, rs.Set("sub/shared", []byte("sharedpw\ntoken: t0k3n\nchained: gopass://#token\n")))
+	literal := "secret\nuser: gopher\nlogin: gopass://#user\ntoken: gopass://sub/shared#chained\nadmin: gopass://sub/shared\n"
+	assert.NoError(t, rs.Set("app", []byte(literal)))
+
+	content, err := rs.GetResolved("app", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nuser: gopher\nlogin: gopher\ntoken: t0k3n\nadmin: sharedpw\n", string(content))
+
+	// editing works on the references
+	content, err = rs.GetResolved("app", false)
+	assert.NoError(t, err)
+	assert.Equal(t, literal, string(content))
+	content, err = rs.Get("app")
+	assert.NoError(t, err)
+	assert.Equal(t, literal, string(content))
+
+	assert.NoError(t, rs.Set("broken", []byte("secret\nuser: gopass://sub/shared#missing\n")))
+	_, err = rs.GetResolved("broken", true)
+	assert.Error(t, err)
+
+	// cycles within a secret and across secrets
+	assert.NoError(t, rs.Set("self", []byte("secret\na: gopass://#b\nb: gopass://#a\n")))
+	_, err = rs.GetResolved("self", true)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), ErrRefCycle.Error())
+	}
+	assert.NoError(t, rs.Set("ping", []byte("secret\nx: gopass://sub/pong#x\n")))
+	assert.NoError(t, rs.Set("sub/pong", []byte("secret\nx: gopass://ping#x\n")))
+	_, err = rs.GetResolved("ping", true)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), ErrRefCycle.Error())
+	}
+
+	// long chains are cut off
+	chain := "secret\n"
+	for _, f := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"} {
+		chain += f + ": gopass://#" + string(f[0]+1) + "\n"
+	}
+	chain += "j: value\n"
+	assert.NoError(t, rs.Set("chain", []byte(chain)))
+	_, err = rs.GetResolved("chain", true)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), ErrRefDepth.Error())
+	}
+}
@@ -0,0 +1,268 @@
+package password
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ReplaceReport is what ReplaceField changes, or would change
+type ReplaceReport struct {
+	// Changed are the secrets with a matching field
+	Changed []string
+	// Replaced is the number of values replaced in them
+	Replaced int
+	// Skipped are the passphrase-only secrets, which aren't searched
+	Skipped []string
+	// Failed are the secrets that couldn't be decrypted or written
+	Failed map[string]error
+}
+
+// Replacement is a value of a field to replace in the secrets below the
+// prefix
+type Replacement struct {
+	Field  string
+	Old    string
+	New    string
+	Prefix string
+	// Exact only replaces values equal to Old. Otherwise a value matches if
+	// it contains Old, so a part of it, e.g. the domain of an URL, can be
+	// replaced.
+	Exact bool
+}
+
+// validate checks that the replacement never touches a password
+func (rp Replacement) validate() error {
+	if rp.Field == "" || rp.Field == passwordField {
+		return fmt.Errorf("passwords are never replaced, give the field to replace")
+	}
+	if rp.Old == "" {
+		return fmt.Errorf("the value to replace must not be empty")
+	}
+	return nil
+}
+
+// ReplaceConfirmFunc is called with the secrets a replacement will change,
+// before anything is written. Nothing is written if it returns false.
+type ReplaceConfirmFunc func(ReplaceReport) bool
+
+// replaceJob is a secret with a matching field
+type replaceJob struct {
+	name    string
+	content []byte
+	n       int
+}
+
+func newReplaceReport() ReplaceReport {
+	return ReplaceReport{
+		Changed: make([]string, 0, 10),
+		Skipped: make([]string, 0, 1),
+		Failed:  make(map[string]error, 1),
+	}
+}
+
+// ReplaceField replaces the value of a field in all secrets below the
+// prefix of the replacement. Other fields and the password are never
+// changed. The store is locked until the changed secrets are encrypted
+// again and committed at once, so confirm sees exactly what is written. A
+// nil confirm writes without asking.
+func (s *Store) ReplaceField(rp Replacement, confirm ReplaceConfirmFunc) (ReplaceReport, error) {
+	report := newReplaceReport()
+	if err := rp.validate(); err != nil {
+		return report, err
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return report, err
+	}
+	defer unlock()
+
+	jobs, err := s.findReplacements(rp, &report)
+	if err != nil || len(jobs) < 1 {
+		return report, err
+	}
+	if confirm != nil && !confirm(report) {
+		return report, nil
+	}
+	return report, s.writeReplacements(rp, jobs, report.Failed)
+}
+
+// findReplacements decrypts the secrets below the prefix in parallel and
+// returns the changed ones. The changed, skipped and failed secrets are
+// recorded in the report.
+func (s *Store) findReplacements(rp Replacement, report *ReplaceReport) ([]replaceJob, error) {
+	names, err := s.listBelow(rp.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var mutex sync.Mutex
+	jobs := make([]replaceJob, 0, 10)
+	forEachParallel(names, func(name string) {
+		// we can't ask for multiple passphrases in parallel
+		if s.IsSymmetric(name) {
+			mutex.Lock()
+			report.Skipped = append(report.Skipped, name)
+			mutex.Unlock()
+			return
+		}
+		content, err := s.Get(name)
+		mutex.Lock()
+		if err != nil {
+			report.Failed[name] = err
+		} else if out, n := replaceFieldValue(content, rp); n > 0 {
+			jobs = append(jobs, replaceJob{name: name, content: out, n: n})
+		}
+		mutex.Unlock()
+	})
+
+	sort.Sort(byReplaceName(jobs))
+	for _, j := range jobs {
+		report.Changed = append(report.Changed, j.name)
+		report.Replaced += j.n
+	}
+	sort.Strings(report.Skipped)
+	return jobs, nil
+}
+
+// writeReplacements encrypts the changed secrets and commits them at once.
+// Failures are recorded in failed.
+func (s *Store) writeReplacements(rp Replacement, jobs []replaceJob, failed map[string]error) error {
+	resolver := newRecipientResolver(s)
+	batch := make([]batchJob, 0, len(jobs))
+	for i, j := range jobs {
+		batch = append(batch, batchJob{
+			index:      i,
+			name:       j.name,
+			content:    j.content,
+			recipients: resolver.recipientsFor(j.name),
+		})
+	}
+	results := make([]BatchResult, len(jobs))
+	if err := s.setBatch(batch, fmt.Sprintf("Replace %s in %d secrets.", rp.Field, len(jobs)), results); err != nil {
+		return err
+	}
+	for i, res := range results {
+		if res.Status == BatchFailed {
+			failed[jobs[i].name] = res.Err
+		}
+	}
+	return nil
+}
+
+// ReplaceField replaces the value of a field in the secrets below the
+// prefix, in every store if the prefix is empty. All stores involved are
+// locked until the replacement is written, and confirm is called once with
+// the secrets of all of them. See Store.ReplaceField.
+func (r *RootStore) ReplaceField(rp Replacement, confirm ReplaceConfirmFunc) (ReplaceReport, error) {
+	report := newReplaceReport()
+	if err := rp.validate(); err != nil {
+		return report, err
+	}
+	prefix := strings.Trim(rp.Prefix, "/")
+	aliases := r.aliases()
+	if prefix != "" {
+		aliases = []string{r.mountPoint(prefix)}
+	}
+
+	jobs := make(map[string][]replaceJob, len(aliases))
+	for _, alias := range aliases {
+		store := r.storeByAlias(alias)
+		unlock, err := store.Lock()
+		if err != nil {
+			return report, err
+		}
+		defer unlock()
+
+		sub := newReplaceReport()
+		subrp := rp
+		subrp.Prefix = strings.TrimPrefix(prefix, alias)
+		found, err := store.findReplacements(subrp, &sub)
+		if err == ErrNotFound && prefix == "" {
+			continue
+		}
+		report.Changed = append(report.Changed, prefixNames(alias, sub.Changed)...)
+		report.Skipped = append(report.Skipped, prefixNames(alias, sub.Skipped)...)
+		for name, ferr := range sub.Failed {
+			report.Failed[fullName(alias, name)] = ferr
+		}
+		report.Replaced += sub.Replaced
+		if err != nil {
+			return report, err
+		}
+		jobs[alias] = found
+	}
+	sort.Strings(report.Changed)
+	sort.Strings(report.Skipped)
+	if len(report.Changed) < 1 || (confirm != nil && !confirm(report)) {
+		return report, nil
+	}
+
+	for _, alias := range aliases {
+		if len(jobs[alias]) < 1 {
+			continue
+		}
+		failed := make(map[string]error, 1)
+		err := r.storeByAlias(alias).writeReplacements(rp, jobs[alias], failed)
+		for name, ferr := range failed {
+			report.Failed[fullName(alias, name)] = ferr
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// listBelow returns the names of the secrets below the prefix, or all of
+// them for an empty prefix. ErrNotFound is returned if there are none.
+func (s *Store) listBelow(prefix string) ([]string, error) {
+	entries, err := s.List("")
+	if err != nil {
+		return nil, err
+	}
+	names := NamesBelow(entries, prefix)
+	if len(names) < 1 {
+		return nil, ErrNotFound
+	}
+	return names, nil
+}
+
+// replaceFieldValue replaces the matching values of the key: value lines of
+// the body with the field of the replacement. It returns the content and the
+// number of values changed.
+func replaceFieldValue(content []byte, rp Replacement) ([]byte, int) {
+	pw, body := SplitSecret(content)
+	lines := strings.Split(string(body), "\n")
+	n := 0
+	for i, line := range lines {
+		key, value, ok := parseField(line)
+		if !ok || key != rp.Field {
+			continue
+		}
+		switch {
+		case value == rp.Old:
+			lines[i] = key + ": " + rp.New
+		case !rp.Exact && strings.Contains(value, rp.Old):
+			lines[i] = key + ": " + strings.Replace(value, rp.Old, rp.New, -1)
+		default:
+			continue
+		}
+		n++
+	}
+	if n < 1 {
+		return content, 0
+	}
+	out := append([]byte{}, pw...)
+	out = append(out, '\n')
+	return append(out, strings.Join(lines, "\n")...), n
+}
+
+// byReplaceName sorts replace jobs by the name of the secret
+type byReplaceName []replaceJob
+
+func (b byReplaceName) Len() int           { return len(b) }
+func (b byReplaceName) Less(i, j int) bool { return b[i].name < b[j].name }
+func (b byReplaceName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
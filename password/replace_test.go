@@ -0,0 +1,98 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceFieldValue(t *testing.T) {
+	in := "old.example.com\nurl: old.example.com\nlogin: https://old.example.com/login\nurl: https://www.old.example.com/\nnote: old.example.com\nurl:old.example.com\n"
+
+	// whole values and parts of them match, other fields and the password
+	// are left alone
+	out, n := replaceFieldValue([]byte(in), Replacement{Field: "url", Old: "old.example.com", New: "new.example.org"})
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "old.example.com\nurl: new.example.org\nlogin: https://old.example.com/login\nurl: https://www.new.example.org/\nnote: old.example.com\nurl: new.example.org\n", string(out))
+
+	out, n = replaceFieldValue([]byte(in), Replacement{Field: "login", Old: "https://old.example.com/login", New: "https://new.example.org/signin"})
+	assert.Equal(t, 1, n)
+	assert.Contains(t, string(out), "\nlogin: https://new.example.org/signin\n")
+
+	out, n = replaceFieldValue([]byte(in), Replacement{Field: "url", Old: "other.example.com", New: "new.example.org"})
+	assert.Equal(t, 0, n)
+	assert.Equal(t, in, string(out))
+
+	// with Exact only whole values match
+	out, n = replaceFieldValue([]byte(in), Replacement{Field: "url", Old: "old.example.com", New: "new.example.org", Exact: true})
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "old.example.com\nurl: new.example.org\nlogin: https://old.example.com/login\nurl: https://www.old.example.com/\nnote: old.example.com\nurl: new.example.org\n", string(out))
+
+	out, n = replaceFieldValue([]byte(in), Replacement{Field: "url", Old: "example.com", New: "example.org", Exact: true})
+	assert.Equal(t, 0, n)
+	assert.Equal(t, in, string(out))
+}
+
+func TestReplaceField(t *testing.T) {
+	rs, _, cleanup := newTestRootStore(t, "sub")
+	defer cleanup()
+
+	secrets := map[string]string{
+		"web/a":   "old.example.com\nurl: https://old.example.com/\n",
+		"web/b":   "secret\nurl: old.example.com\nuser: gopher\n",
+		"mail":    "secret\nurl: https://mail.example.com/\nnote: old.example.com\n",
+		"sub/api": "secret\nurl: https://api.old.example.com/v1\n",
+	}
+	for name, content := range secrets {
+		assert.NoError(t, rs.Set(name, []byte(content)))
+	}
+
+	decline := func(ReplaceReport) bool { return false }
+	_, err := rs.ReplaceField(Replacement{Field: "password", Old: "old", New: "new"}, nil)
+	assert.Error(t, err)
+
+	var confirmed ReplaceReport
+	report, err := rs.ReplaceField(Replacement{Field: "url", Old: "old.example.com", New: "new.example.org"}, func(r ReplaceReport) bool {
+		confirmed = r
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sub/api", "web/a", "web/b"}, report.Changed)
+	assert.Equal(t, report.Changed, confirmed.Changed)
+	assert.Equal(t, 3, report.Replaced)
+	assert.Len(t, report.Failed, 0)
+
+	report, err = rs.ReplaceField(Replacement{Field: "url", Old: "old.example.com", New: "new.example.org", Exact: true}, decline)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/b"}, report.Changed)
+	// nothing changed when declined
+	for name, content := range secrets {
+		got, err := rs.Get(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(got), name)
+	}
+
+	report, err = rs.ReplaceField(Replacement{Field: "url", Old: "old.example.com", New: "new.example.org", Prefix: "web"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/a", "web/b"}, report.Changed)
+	for name, content := range map[string]string{
+		"web/a":   "old.example.com\nurl: https://new.example.org/\n",
+		"web/b":   "secret\nurl: new.example.org\nuser: gopher\n",
+		"mail":    secrets["mail"],
+		"sub/api": secrets["sub/api"],
+	} {
+		got, err := rs.Get(name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(got), name)
+	}
+
+	report, err = rs.ReplaceField(Replacement{Field: "url", Old: "old.example.com", New: "new.example.org", Prefix: "sub"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sub/api"}, report.Changed)
+	got, err := rs.Get("sub/api")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nurl: https://api.new.example.org/v1\n", string(got))
+
+	_, err = rs.ReplaceField(Replacement{Field: "url", Old: "old.example.com", New: "new.example.org", Prefix: "nothing"}, nil)
+	assert.Equal(t, ErrNotFound, err)
+}
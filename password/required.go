@@ -0,0 +1,228 @@
+package password
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/log"
+)
+
+const (
+	// requiredID lists the recipients every secret of a store must be
+	// encrypted for, e.g. a break-glass recovery key
+	requiredID = ".gpg-id-required"
+)
+
+// requiredFile returns the path of the required recipients of this store
+func (s *Store) requiredFile() string {
+	return fsutil.CleanPath(filepath.Join(s.path, requiredID))
+}
+
+// loadRequired reads the required recipients, if the store has any
+func (s *Store) loadRequired() ([]string, error) {
+	fh, err := os.Open(s.requiredFile())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return []string{}, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	return unmarshalRecipients(fh), nil
+}
+
+// saveRequired writes the required recipients. Without any required
+// recipients left the file is removed. The change is staged in git, so it's
+// committed along with the recipients.
+func (s *Store) saveRequired() error {
+	if len(s.required) < 1 {
+		err := os.Remove(s.requiredFile())
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !s.isGit() {
+			return nil
+		}
+		if err := s.gitCommand("rm", "-q", "--cached", "--ignore-unmatch", s.requiredFile()).Run(); err != nil {
+			return fmt.Errorf("failed to remove %s from git: %s", requiredID, err)
+		}
+		return nil
+	}
+	if err := s.writeFile(s.requiredFile(), marshalRecipients(s.required)); err != nil {
+		return err
+	}
+	if err := s.gitAdd(s.requiredFile()); err != nil && err != ErrGitNotInit {
+		return err
+	}
+	return nil
+}
+
+// RequireRecipients makes the given recipients of this store required
+// recipients and commits the change
+func (s *Store) RequireRecipients(ids ...string) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	added := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !containsRecipient(s.recipients, id, listPublicKeys) {
+			return fmt.Errorf("%s is not a recipient of the store at %s, add it first", id, s.path)
+		}
+		if contains(s.required, id) || contains(added, id) {
+			continue
+		}
+		added = append(added, id)
+	}
+	if len(added) < 1 {
+		return fmt.Errorf("%s already required", strings.Join(ids, ", "))
+	}
+	s.required = append(s.required, added...)
+	if err := s.saveRequired(); err != nil {
+		return fmt.Errorf("failed to save the required recipients: %s", err)
+	}
+	s.recipientsChanged()
+	return s.gitSave(fmt.Sprintf("Require recipients %s.", strings.Join(added, ", ")), s.requiredFile())
+}
+
+// RequireRecipients makes the given recipients of a store required, see
+// Store.RequireRecipients
+func (r *RootStore) RequireRecipients(store string, ids ...string) error {
+	return r.getStore(store).RequireRecipients(ids...)
+}
+
+// RequiredRecipients returns the recipients every secret of this store must
+// be encrypted for. They can't be removed from the recipients unless
+// dropping them is allowed, see RootStore.SetDropRequired.
+func (s *Store) RequiredRecipients() []string {
+	rs := make([]string, len(s.required))
+	copy(rs, s.required)
+	return rs
+}
+
+// RequiredRecipients returns the required recipients of the store holding
+// the given secret
+func (r *RootStore) RequiredRecipients(name string) []string {
+	return r.getStore(name).RequiredRecipients()
+}
+
+// keyLookup returns the public keys of a recipient, see listPublicKeys and
+// recipientResolver.publicKeys
+type keyLookup func(id string) gpg.KeyList
+
+// listPublicKeys looks up the public keys of a recipient in the keyring
+func listPublicKeys(id string) gpg.KeyList {
+	kl, err := gpg.ListPublicKeys(id)
+	if err != nil {
+		return nil
+	}
+	return kl
+}
+
+// withRequired adds the required recipients missing from the given ones
+func (s *Store) withRequired(rs []string) []string {
+	return s.withRequiredKeys(rs, listPublicKeys)
+}
+
+// withRequiredKeys is withRequired looking up the keys of the recipients
+// with the given function
+func (s *Store) withRequiredKeys(rs []string, keys keyLookup) []string {
+	for _, id := range s.missingRequiredKeys(rs, keys) {
+		log.Debugf("recipients: adding the required recipient %s", id)
+		rs = append(rs, id)
+	}
+	return rs
+}
+
+// missingRequired returns the required recipients not among the given ones
+func (s *Store) missingRequired(rs []string) []string {
+	return s.missingRequiredKeys(rs, listPublicKeys)
+}
+
+// missingRequiredKeys is missingRequired looking up the keys of the
+// recipients with the given function
+func (s *Store) missingRequiredKeys(rs []string, keys keyLookup) []string {
+	missing := make([]string, 0, len(s.required))
+	for _, id := range s.required {
+		if !containsRecipient(rs, id, keys) {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// checkRequired returns an error if changing the recipients from old to rs
+// removes a required recipient, unless dropping them is allowed
+func (s *Store) checkRequired(old, rs []string) error {
+	if s.dropRequired {
+		return nil
+	}
+	before := s.missingRequired(old)
+	removed := make([]string, 0, len(s.required))
+	for _, id := range s.missingRequired(rs) {
+		if !contains(before, id) {
+			removed = append(removed, id)
+		}
+	}
+	if len(removed) < 1 {
+		return nil
+	}
+	return fmt.Errorf("%s is a required recipient of the store at %s. Use --drop-required to remove it anyway", strings.Join(removed, ", "), s.path)
+}
+
+// pruneRequired removes the required recipients that are no longer
+// recipients of the store, if dropping them is allowed
+func (s *Store) pruneRequired() error {
+	if !s.dropRequired || len(s.required) < 1 {
+		return nil
+	}
+	missing := s.missingRequired(s.recipients)
+	if len(missing) < 1 {
+		return nil
+	}
+	required := make([]string, 0, len(s.required))
+	for _, id := range s.required {
+		if !contains(missing, id) {
+			required = append(required, id)
+		}
+	}
+	s.required = required
+	if err := s.saveRequired(); err != nil {
+		return fmt.Errorf("failed to save the required recipients: %s", err)
+	}
+	return nil
+}
+
+// containsRecipient returns true if id is one of the given recipients,
+// either literally or by the fingerprint of its key
+func containsRecipient(rs []string, id string, keys keyLookup) bool {
+	if contains(rs, id) {
+		return true
+	}
+	kl := keys(id)
+	if len(kl) < 1 {
+		return false
+	}
+	return matchRecipient(id, kl, rs)
+}
+
+// contains returns true if the list holds the given string
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package password
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredRecipients(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass recovery", "recovery@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate recovery key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("recovery@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list recovery key: %s", err)
+	}
+	recovery := kl[0].Fingerprint
+
+	tempdir, cleanupDir := newTestDir(t, fpr, recovery)
+	defer cleanupDir()
+
+	// the required recipient may be given by any ID of its key
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, requiredID), []byte(recovery[24:]+"\n"), 0600))
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{recovery[24:]}, s.RequiredRecipients())
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+
+	// removing the required recipient is refused
+	err = s.RemoveRecipient(recovery)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is a required recipient")
+	rs, err := s.readRecipients()
+	assert.NoError(t, err)
+	assert.Equal(t, rs, s.recipients)
+	assert.Len(t, rs, 2)
+
+	// and so is leaving it out of a recipient override
+	assert.Error(t, s.SetRecipientOverride("foo", []string{fpr}))
+	assert.False(t, s.HasRecipientOverride("foo"))
+
+	// it's added when encrypting, even if it's missing from the recipients
+	s.recipients = []string{fpr}
+	assert.Equal(t, []string{fpr, recovery[24:]}, s.recipientsFor("foo"))
+	res := newRecipientResolver(s)
+	assert.Equal(t, []string{fpr, recovery[24:]}, res.recipientsFor("foo"))
+	// the resolver looks up the key of the required recipient only once
+	assert.Len(t, res.keys[recovery[24:]], 1)
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+	acl, err := s.ACL("foo")
+	assert.NoError(t, err)
+	assert.Len(t, acl.Recipients, 2)
+	assert.False(t, acl.Diverges())
+	s.recipients = rs
+
+	// unless dropping it is allowed, then it's no longer required
+	s.dropRequired = true
+	assert.NoError(t, s.RemoveRecipient(recovery))
+	assert.Equal(t, []string{fpr}, s.recipients)
+	assert.Len(t, s.RequiredRecipients(), 0)
+	assert.False(t, fsutil.IsFile(filepath.Join(tempdir, requiredID)))
+}
+
+func TestRequireRecipients(t *testing.T) {
+	s, fpr, cleanup := newTestStore(t)
+	defer cleanup()
+	defer setGitIdentity(t)()
+
+	assert.NoError(t, s.GitInit(""))
+	assert.NoError(t, s.Set("foo", []byte("secret")))
+
+	// only recipients of the store can be required
+	assert.Error(t, s.RequireRecipients("DEADBEEF"))
+	assert.NoError(t, s.RequireRecipients(fpr))
+	assert.Equal(t, []string{fpr}, s.RequiredRecipients())
+	assert.Error(t, s.RequireRecipients(fpr))
+	out, err := s.gitCommand("ls-files", requiredID).Output()
+	assert.NoError(t, err)
+	assert.Equal(t, requiredID+"\n", string(out))
+
+	// dropping the required recipients is committed as well
+	s.dropRequired = true
+	s.recipients = []string{"DEADBEEF"}
+	assert.NoError(t, s.saveRecipients())
+	assert.Len(t, s.RequiredRecipients(), 0)
+	assert.NoError(t, s.gitCommit("Drop the required recipients."))
+	out, err = s.gitCommand("status", "--porcelain", requiredID).Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "", string(out))
+	out, err = s.gitCommand("ls-files", requiredID).Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "", string(out))
+}
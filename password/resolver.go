@@ -0,0 +1,154 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/log"
+)
+
+var (
+	// readDir and readFile are used by recipientResolver to read the
+	// recipient overrides
+	readDir  = readDirNames
+	readFile = ioutil.ReadFile
+)
+
+// readDirNames returns the names of the entries of the given directory.
+// Unlike ioutil.ReadDir it doesn't stat every entry.
+func readDirNames(dir string) ([]string, error) {
+	fh, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	return fh.Readdirnames(-1)
+}
+
+// recipientResolver resolves the recipients of many secrets during a single
+// bulk operation. Each directory is listed once to find the recipient
+// overrides in it and each override is read once, instead of once per
+// secret. The cache is dropped as soon as any recipients file of the store
+// is written. It is safe for concurrent use.
+type recipientResolver struct {
+	store *Store
+	mutex sync.Mutex
+	gen   uint32
+	// dirs maps a directory to the overrides in it by file name
+	dirs map[string]map[string][]string
+	// keys caches the public keys of the recipients, see publicKeys
+	keys map[string]gpg.KeyList
+}
+
+// newRecipientResolver returns an empty resolver for the given store
+func newRecipientResolver(s *Store) *recipientResolver {
+	return &recipientResolver{
+		store: s,
+		gen:   atomic.LoadUint32(&s.recipientGen),
+		dirs:  make(map[string]map[string][]string, 10),
+		keys:  make(map[string]gpg.KeyList, 5),
+	}
+}
+
+// recipientsFor returns the recipients the given secret must be encrypted
+// for, see Store.recipientsFor
+func (r *recipientResolver) recipientsFor(name string) []string {
+	if rs, found := r.override(name); found {
+		return r.store.withRequiredKeys(rs, r.publicKeys)
+	}
+	rs := make([]string, len(r.store.recipients))
+	copy(rs, r.store.recipients)
+	return r.store.withRequiredKeys(rs, r.publicKeys)
+}
+
+// publicKeys returns the public keys of a recipient. They are looked up in
+// the keyring once per resolver.
+func (r *recipientResolver) publicKeys(id string) gpg.KeyList {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if kl, found := r.keys[id]; found {
+		return kl
+	}
+	kl := listPublicKeys(id)
+	r.keys[id] = kl
+	return kl
+}
+
+// hasOverride returns true if the given secret has a recipient override
+func (r *recipientResolver) hasOverride(name string) bool {
+	fn := r.store.overrideFile(name)
+	overrides := r.dir(filepath.Dir(fn))
+	_, found := overrides[filepath.Base(fn)]
+	return found
+}
+
+// override returns the recipients of the override of the given secret
+func (r *recipientResolver) override(name string) ([]string, bool) {
+	fn := r.store.overrideFile(name)
+	if !strings.HasPrefix(fn, r.store.path) {
+		return nil, false
+	}
+	rs, found := r.dir(filepath.Dir(fn))[filepath.Base(fn)]
+	if !found {
+		return nil, false
+	}
+	if len(rs) < 1 {
+		fmt.Printf("Failed to read recipient override %s. Using the recipients of the store\n", fn)
+		return nil, false
+	}
+	out := make([]string, len(rs))
+	copy(out, rs)
+	return out, true
+}
+
+// dir returns the overrides in the given directory, reading them on first use
+func (r *recipientResolver) dir(dir string) map[string][]string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if gen := atomic.LoadUint32(&r.store.recipientGen); gen != r.gen {
+		r.dirs = make(map[string]map[string][]string, len(r.dirs))
+		r.gen = gen
+	}
+	if overrides, found := r.dirs[dir]; found {
+		return overrides
+	}
+
+	overrides := make(map[string][]string, 1)
+	r.dirs[dir] = overrides
+	names, err := readDir(dir)
+	if err != nil {
+		return overrides
+	}
+	for _, name := range names {
+		// the recipients of the store are named like an override of ""
+		if name == gpgID || !strings.HasSuffix(name, overrideSuffix) {
+			continue
+		}
+		fn := filepath.Join(dir, name)
+		buf, err := readFile(fn)
+		if err != nil {
+			overrides[name] = nil
+			continue
+		}
+		overrides[name] = unmarshalRecipients(bytes.NewReader(buf))
+		log.Debugf("recipients: read the override %s", fn)
+	}
+	return overrides
+}
+
+// recipientsChanged drops the caches of all resolvers of this store. It
+// must be called whenever a recipients file is written or removed.
+func (s *Store) recipientsChanged() {
+	atomic.AddUint32(&s.recipientGen, 1)
+}
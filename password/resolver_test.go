@@ -0,0 +1,145 @@
+package password
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countReads counts the directories listed and files read by resolvers
+// until the returned func is called
+func countReads() (map[string]int, map[string]int, func()) {
+	dirs := make(map[string]int, 10)
+	files := make(map[string]int, 10)
+	oldReadDir, oldReadFile := readDir, readFile
+	readDir = func(dir string) ([]string, error) {
+		dirs[dir]++
+		return oldReadDir(dir)
+	}
+	readFile = func(fn string) ([]byte, error) {
+		files[fn]++
+		return oldReadFile(fn)
+	}
+	return dirs, files, func() {
+		readDir, readFile = oldReadDir, oldReadFile
+	}
+}
+
+func TestRecipientResolver(t *testing.T) {
+	s, fpr, cleanup := newTestStore(t)
+	defer cleanup()
+	tempdir := s.path
+
+	for _, name := range []string{"a/x", "a/y", "a/z", "b/x", "b/y", "top"} {
+		assert.NoError(t, s.Set(name, []byte("secret")))
+	}
+	assert.NoError(t, s.AddAttachment("a/x", "file", []byte("data")))
+	assert.NoError(t, s.SetRecipientOverride("a/x", []string{fpr}))
+	assert.NoError(t, s.SetRecipientOverride("b/y", []string{fpr}))
+
+	dirs, files, restore := countReads()
+	assert.NoError(t, s.Reencrypt(""))
+	restore()
+
+	// every directory holding secrets is listed and every override read once
+	assert.Equal(t, map[string]int{
+		tempdir:                     1,
+		filepath.Join(tempdir, "a"): 1,
+		filepath.Join(tempdir, "b"): 1,
+	}, dirs)
+	assert.Equal(t, map[string]int{
+		filepath.Join(tempdir, "a", "x"+overrideSuffix): 1,
+		filepath.Join(tempdir, "b", "y"+overrideSuffix): 1,
+	}, files)
+
+	dirs, files, restore = countReads()
+	defer restore()
+	res := newRecipientResolver(s)
+	for _, name := range []string{"a/x", "a/y", "a/x/" + attachmentsDir + "/file"} {
+		assert.Equal(t, s.recipientsFor(name), res.recipientsFor(name), name)
+	}
+	assert.True(t, res.hasOverride("a/x"))
+	assert.False(t, res.hasOverride("a/y"))
+	assert.Equal(t, 1, dirs[filepath.Join(tempdir, "a")])
+
+	// writing an override drops the cache, even of resolvers in use
+	assert.NoError(t, s.SetRecipientOverride("a/y", []string{fpr}))
+	listed := dirs[filepath.Join(tempdir, "a")]
+	assert.True(t, res.hasOverride("a/y"))
+	assert.True(t, res.hasOverride("a/x"))
+	assert.Equal(t, listed+1, dirs[filepath.Join(tempdir, "a")])
+	assert.NoError(t, s.RemoveRecipientOverride("a/x"))
+	read := files[filepath.Join(tempdir, "a", "y"+overrideSuffix)]
+	assert.False(t, res.hasOverride("a/x"))
+	assert.Equal(t, []string{fpr}, res.recipientsFor("a/x"))
+	assert.Equal(t, []string{fpr}, res.recipientsFor("a/y"))
+	assert.Equal(t, read+1, files[filepath.Join(tempdir, "a", "y"+overrideSuffix)])
+}
+
+// benchStore creates a store with dirs directories holding secrets secrets
+// each, every other one with a recipient override. The returned names
+// include two attachments of every secret, which are resolved like the
+// secret itself during a bulk operation.
+func benchStore(b *testing.B, dirs, secrets int) (*Store, []string, func()) {
+	tempdir, cleanup := newTestDir(b, "0xDEADBEEF")
+
+	names := make([]string, 0, 3*dirs*secrets)
+	for i := 0; i < dirs; i++ {
+		dir := filepath.Join(tempdir, fmt.Sprintf("dir%d", i))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			b.Fatalf("Failed to create %s: %s", dir, err)
+		}
+		for j := 0; j < secrets; j++ {
+			name := fmt.Sprintf("dir%d/secret%d", i, j)
+			names = append(names, name, name+"/"+attachmentsDir+"/a", name+"/"+attachmentsDir+"/b")
+			if err := ioutil.WriteFile(filepath.Join(tempdir, name+".gpg"), []byte("junk"), 0600); err != nil {
+				b.Fatalf("Failed to write %s: %s", name, err)
+			}
+			if j%2 == 0 {
+				if err := ioutil.WriteFile(filepath.Join(tempdir, name+overrideSuffix), []byte("0xCAFEBABE\n"), 0600); err != nil {
+					b.Fatalf("Failed to write override of %s: %s", name, err)
+				}
+			}
+		}
+	}
+
+	s, err := NewStore("", tempdir, nil)
+	if err != nil {
+		cleanup()
+		b.Fatalf("Failed to create store: %s", err)
+	}
+	return s, names, cleanup
+}
+
+func BenchmarkRecipientsFor(b *testing.B) {
+	s, names, cleanup := benchStore(b, 20, 50)
+	defer cleanup()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, name := range names {
+			_ = s.recipientsFor(name)
+		}
+	}
+}
+
+func BenchmarkRecipientResolver(b *testing.B) {
+	s, names, cleanup := benchStore(b, 20, 50)
+	defer cleanup()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// a new resolver for every bulk operation
+		res := newRecipientResolver(s)
+		for _, name := range names {
+			if rs := res.recipientsFor(name); len(rs) < 1 || !strings.HasPrefix(rs[0], "0x") {
+				b.Fatalf("Wrong recipients for %s: %v", name, rs)
+			}
+		}
+	}
+}
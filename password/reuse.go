@@ -0,0 +1,58 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// passwordHash returns the hex encoded SHA256 of the password of a secret,
+// or an empty string if it has none
+func passwordHash(content []byte) string {
+	pw, _ := SplitSecret(content)
+	if len(bytes.TrimSpace(pw)) < 1 {
+		return ""
+	}
+	sum := sha256.Sum256(pw)
+	return hex.EncodeToString(sum[:])
+}
+
+// SecretsWithPassword returns the sorted names of the secrets of this store
+// with the same password as content. Only the hashes of the passwords are
+// compared. If the store has a search index they're read from it, so only
+// the secrets changed since it was written and those that aren't indexed
+// are decrypted. Otherwise every secret is decrypted, in parallel.
+// Passphrase-only secrets are skipped.
+func (s *Store) SecretsWithPassword(content []byte) ([]string, error) {
+	hash := passwordHash(content)
+	if hash == "" {
+		return []string{}, nil
+	}
+	return s.findSecrets(func(e indexEntry) bool {
+		return e.PwHash == hash
+	}, func(content []byte) bool {
+		return passwordHash(content) == hash
+	})
+}
+
+// ReusedBy returns the names of the other secrets in all stores with the
+// same password as the given secret with that content, see
+// Store.SecretsWithPassword
+func (r *RootStore) ReusedBy(name string, content []byte) ([]string, error) {
+	reused := make([]string, 0, 5)
+	for _, alias := range r.aliases() {
+		names, err := r.storeByAlias(alias).SecretsWithPassword(content)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range prefixNames(alias, names) {
+			if n != strings.Trim(name, "/") {
+				reused = append(reused, n)
+			}
+		}
+	}
+	sort.Strings(reused)
+	return reused, nil
+}
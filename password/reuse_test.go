@@ -0,0 +1,53 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHash(t *testing.T) {
+	assert.Equal(t, passwordHash([]byte("secret\nuser: foo\n")), passwordHash([]byte("secret\nuser: bar\n")))
+	assert.NotEqual(t, passwordHash([]byte("secret\n")), passwordHash([]byte("secret2\n")))
+	assert.Equal(t, "", passwordHash([]byte("\nuser: foo\n")))
+	assert.Equal(t, "", passwordHash([]byte("")))
+}
+
+func TestReusedBy(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, cleanupDir := newTestDir(t, fpr)
+	defer cleanupDir()
+
+	r, err := NewRootStore(tempdir)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Set("web/golang", []byte("hunter2\nuser: gopher\n")))
+	assert.NoError(t, r.Set("web/rust", []byte("hunter2\nuser: ferris\n")))
+	assert.NoError(t, r.Set("web/python", []byte("other\n")))
+	assert.NoError(t, r.Set("web/empty", []byte("\nuser: nobody\n")))
+	assert.NoError(t, r.Set("web/empty2", []byte("\nuser: nobody\n")))
+
+	// without an index every secret is decrypted
+	reused, err := r.ReusedBy("web/golang", []byte("hunter2\nuser: gopher\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/rust"}, reused)
+	reused, err = r.ReusedBy("web/python", []byte("other\n"))
+	assert.NoError(t, err)
+	assert.Len(t, reused, 0)
+	// secrets without a password don't share one
+	reused, err = r.ReusedBy("web/empty", []byte("\nuser: nobody\n"))
+	assert.NoError(t, err)
+	assert.Len(t, reused, 0)
+
+	// with an index the hashes are read from it
+	assert.NoError(t, r.BuildIndex())
+	assert.NoError(t, r.Set("web/python", []byte("hunter2\n")))
+	reused, err = r.ReusedBy("web/golang", []byte("hunter2\nuser: gopher\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/python", "web/rust"}, reused)
+	idx, err := r.store.loadIndex()
+	assert.NoError(t, err)
+	assert.Equal(t, passwordHash([]byte("hunter2")), idx.Entries["web/rust"].PwHash)
+}
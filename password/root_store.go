@@ -3,6 +3,7 @@ package password
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
@@ -14,21 +15,60 @@ import (
 
 // RootStore is the public facing password store
 type RootStore struct {
-	AutoPush    bool              `json:"autopush"`    // push to git remote after commit
-	AutoPull    bool              `json:"autopull"`    // pull from git before push
-	AutoImport  bool              `json:"autoimport"`  // import missing public keys w/o asking
-	AlwaysTrust bool              `json:"alwaystrust"` // always trust public keys when encrypting
-	NoConfirm   bool              `json:"noconfirm"`   // do not confirm recipients when encrypting
-	PersistKeys bool              `json:"persistkeys"` // store recipient keys in store
-	LoadKeys    bool              `json:"loadkeys"`    // load missing keys from store
-	ClipTimeout int               `json:"cliptimeout"` // clear clipboard after seconds
-	Path        string            `json:"path"`        // path to the root store
-	Mount       map[string]string `json:"mounts,omitempty"`
-	Version     string            `json:"version"`
-	ImportFunc  ImportCallback    `json:"-"`
-	FsckFunc    FsckCallback      `json:"-"`
-	store       *Store
-	mounts      map[string]*Store
+	AutoPush               bool              `json:"autopush"`                       // push to git remote after commit
+	AutoPull               bool              `json:"autopull"`                       // pull from git before push
+	AutoImport             bool              `json:"autoimport"`                     // import missing public keys w/o asking
+	AlwaysTrust            bool              `json:"alwaystrust"`                    // always trust public keys when encrypting
+	NoConfirm              bool              `json:"noconfirm"`                      // do not confirm recipients when encrypting
+	PersistKeys            bool              `json:"persistkeys"`                    // store recipient keys in store
+	LoadKeys               bool              `json:"loadkeys"`                       // load missing keys from store
+	ClipTimeout            int               `json:"cliptimeout"`                    // clear clipboard after seconds
+	SignCommits            bool              `json:"signcommits"`                    // sign all git commits
+	SignKey                string            `json:"signkey"`                        // key used for signing, defaults to the first recipient
+	SignStrict             bool              `json:"signstrict"`                     // fail instead of committing unsigned if signing fails
+	CipherAlgo             string            `json:"cipheralgo"`                     // gpg cipher algorithm, e.g. AES256
+	DigestAlgo             string            `json:"digestalgo"`                     // gpg digest algorithm, e.g. SHA256
+	CompressAlgo           string            `json:"compressalgo"`                   // gpg compression algorithm, defaults to none
+	AutoDisableCompression bool              `json:"autodisablecompression"`         // encrypt secrets that don't compress, e.g. zip files, without compression
+	UseLoopbackPinentry    bool              `json:"useloopbackpinentry"`            // ask for key passphrases in gopass, using gpg's loopback pinentry
+	PassRetries            int               `json:"passretries"`                    // how often a wrong key passphrase is asked for with the loopback pinentry, defaults to 3
+	TryAllSecrets          bool              `json:"tryallsecrets"`                  // let gpg try all secret keys, not only for secrets with hidden recipients
+	Path                   string            `json:"path"`                           // path to the root store
+	HookDir                string            `json:"hookdir"`                        // directory containing hooks, defaults to ~/.gopass/hooks
+	AuditLog               string            `json:"auditlog"`                       // append-only log of all public key imports, disabled if empty
+	KeyServer              string            `json:"keyserver"`                      // keyserver used to refresh recipient keys, defaults to the one of gpg
+	MinKeyBits             int               `json:"minkeybits"`                     // minimum length of RSA keys not considered weak, defaults to 2048
+	SafeContent            bool              `json:"safecontent"`                    // never print passwords unless asked for, see show
+	NoNewline              bool              `json:"nonewline"`                      // don't append a newline to the output of show, see --no-newline
+	PreferCard             bool              `json:"prefercard"`                     // offer private keys on a hardware token first
+	UseOSKeyring           bool              `json:"useoskeyring"`                   // remember the key passphrase of the loopback pinentry in the OS keyring
+	AgeIdentity            string            `json:"ageidentity"`                    // age identity file used if gpg fails to decrypt a secret with an age copy
+	NameSchema             string            `json:"nameschema"`                     // regular expression the names of new secrets must match
+	RecipientReviewEvery   int               `json:"recipientreviewevery"`           // review the recipients on every Nth write to a store
+	FileMode               string            `json:"filemode"`                       // mode of the files of the stores, 0600 or stricter, e.g. 0400
+	Changelog              bool              `json:"changelog"`                      // record who changed a secret with edit, when and why in its changelog
+	MaxSecretSize          int               `json:"maxsecretsize"`                  // size in bytes above which inserting a secret must be confirmed, defaults to 1 MiB
+	MemFile                bool              `json:"memfile"`                        // hand secrets to the editor as a memory backed file on Linux instead of a tempfile
+	RecipientReviewCount   map[string]int    `json:"recipientreviewcount,omitempty"` // writes by store since the last recipient review
+	KeyPins                map[string]Pins   `json:"keypins,omitempty"`              // key pins of the recipients by store, see PinRecipients
+	Mount                  map[string]string `json:"mounts,omitempty"`
+	Version                string            `json:"version"`
+	ImportFunc             ImportCallback    `json:"-"`
+	FsckFunc               FsckCallback      `json:"-"`
+	BulkFunc               BulkCallback      `json:"-"`
+	AllowNesting           bool              `json:"-"` // allow stores inside of other stores
+	AllowExpired           bool              `json:"-"` // allow encrypting for recipients whose key has expired
+	NoCompress             bool              `json:"-"` // never compress, regardless of compressalgo
+	DropRequired           bool              `json:"-"` // allow removing required recipients
+	AllowAnyName           bool              `json:"-"` // allow names not following the naming scheme
+	passFunc               PassphraseCallback
+	keyPassFunc            KeyPassphraseCallback
+	store                  *Store
+	mounts                 map[string]*Store
+	// the path and signing key of the config, if a profile overrides them
+	profiled   bool
+	cfgPath    string
+	cfgSignKey string
 }
 
 // NewRootStore creates a new store
@@ -110,6 +150,35 @@ func (r *RootStore) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// MarshalJSON implements a custom JSON marshaler that keeps the path and
+// signing key of the config if a profile overrides them
+func (r *RootStore) MarshalJSON() ([]byte, error) {
+	s := rootStore(*r)
+	if r.profiled {
+		s.Path, s.SignKey = r.cfgPath, r.cfgSignKey
+	}
+	return json.Marshal(s)
+}
+
+// UseProfile switches to the store path and signing key of a profile. Empty
+// values keep the ones of the config. The config is never changed, so
+// saving it keeps the original values.
+func (r *RootStore) UseProfile(path, signKey string) error {
+	if !r.profiled {
+		r.cfgPath, r.cfgSignKey = r.Path, r.SignKey
+		r.profiled = true
+	}
+	if path != "" {
+		r.Path = path
+	}
+	if signKey != "" {
+		r.SignKey = signKey
+	}
+	r.store = nil
+	r.mounts = nil
+	return r.init()
+}
+
 // Initialized checks on disk if .gpg-id was generated and thus returns true.
 func (r *RootStore) Initialized() bool {
 	return r.store.Initialized()
@@ -118,6 +187,9 @@ func (r *RootStore) Initialized() bool {
 // Init tries to initalize a new password store location matching the object
 func (r *RootStore) Init(store string, ids ...string) error {
 	sub := r.getStore(store)
+	if err := r.CheckNesting(sub.path); err != nil {
+		return err
+	}
 	sub.persistKeys = r.PersistKeys
 	sub.loadKeys = r.LoadKeys
 	sub.alwaysTrust = r.AlwaysTrust
@@ -133,6 +205,9 @@ func (r *RootStore) AddMount(alias, path string, keys ...string) error {
 	if _, found := r.Mount[alias]; found {
 		return fmt.Errorf("%s is already mounted", alias)
 	}
+	if err := r.CheckNesting(path); err != nil {
+		return err
+	}
 	if err := r.addMount(alias, path, keys...); err != nil {
 		return err
 	}
@@ -279,6 +354,7 @@ func (r *RootStore) Tree() (*tree.Folder, error) {
 			return nil, fmt.Errorf("failed to add file: %s", err)
 		}
 		addFunc(sf...)
+		substore.addNotes(root, alias, sf)
 	}
 
 	sf, err := r.store.List("")
@@ -286,12 +362,20 @@ func (r *RootStore) Tree() (*tree.Folder, error) {
 		return nil, err
 	}
 	addFunc(sf...)
+	r.store.addNotes(root, "", sf)
 
 	return root, nil
 }
 
-// Get returns the plaintext of a single key
+// Get returns the plaintext of a single key. Aliases are resolved to the
+// secret they refer to.
 func (r *RootStore) Get(name string) ([]byte, error) {
+	_, content, err := r.resolve(name)
+	return content, err
+}
+
+// GetRaw returns the plaintext of a single key without resolving aliases
+func (r *RootStore) GetRaw(name string) ([]byte, error) {
 	// forward to substore
 	store := r.getStore(name)
 	return store.Get(strings.TrimPrefix(name, store.alias))
@@ -303,6 +387,136 @@ func (r *RootStore) Exists(name string) (bool, error) {
 	return store.Exists(strings.TrimPrefix(name, store.alias))
 }
 
+// SetPassphraseFunc sets the callback used by all stores to ask for the
+// passphrase of symmetrically encrypted secrets
+func (r *RootStore) SetPassphraseFunc(fn PassphraseCallback) {
+	r.passFunc = fn
+	if r.store != nil {
+		r.store.passFunc = fn
+	}
+	for _, sub := range r.mounts {
+		sub.passFunc = fn
+	}
+}
+
+// SetKeyPassphraseFunc sets the callback used by all stores to get the
+// passphrase of the private key with the loopback pinentry. Without it the
+// passphrase callback is used.
+func (r *RootStore) SetKeyPassphraseFunc(fn KeyPassphraseCallback) {
+	r.keyPassFunc = fn
+	if r.store != nil {
+		r.store.keyPassFunc = fn
+	}
+	for _, sub := range r.mounts {
+		sub.keyPassFunc = fn
+	}
+}
+
+// SetNoCompress sets if all stores encrypt without compression, regardless
+// of the configured compression algorithm
+func (r *RootStore) SetNoCompress(none bool) {
+	r.NoCompress = none
+	r.store.noCompress = none
+	for _, sub := range r.mounts {
+		sub.noCompress = none
+	}
+}
+
+// SetAllowExpired sets if all stores may encrypt for recipients whose key
+// has expired
+func (r *RootStore) SetAllowExpired(allow bool) {
+	r.AllowExpired = allow
+	if r.store != nil {
+		r.store.allowExpired = allow
+	}
+	for _, sub := range r.mounts {
+		sub.allowExpired = allow
+	}
+}
+
+// SetDropRequired sets if all stores may remove their required recipients
+func (r *RootStore) SetDropRequired(allow bool) {
+	r.DropRequired = allow
+	if r.store != nil {
+		r.store.dropRequired = allow
+	}
+	for _, sub := range r.mounts {
+		sub.dropRequired = allow
+	}
+}
+
+// RecipientReviewDue returns true if writing the given secret is at least the
+// RecipientReviewEvery-th write to its store since the last review of its
+// recipients, which must be reviewed then
+func (r *RootStore) RecipientReviewDue(name string) bool {
+	if r.RecipientReviewEvery < 1 {
+		return false
+	}
+	return r.RecipientReviewCount[r.getStore(name).alias]+1 >= r.RecipientReviewEvery
+}
+
+// CountWrite counts a confirmed write of the given secret towards the
+// periodic review of the recipients of its store. An accepted review starts
+// the count over.
+func (r *RootStore) CountWrite(name string, reviewed bool) {
+	if r.RecipientReviewEvery < 1 {
+		return
+	}
+	if r.RecipientReviewCount == nil {
+		r.RecipientReviewCount = make(map[string]int, len(r.mounts)+1)
+	}
+	alias := r.getStore(name).alias
+	if reviewed {
+		r.RecipientReviewCount[alias] = 0
+		return
+	}
+	r.RecipientReviewCount[alias]++
+}
+
+// SetAllowAnyName sets if all stores accept names of new secrets which don't
+// follow the naming scheme
+func (r *RootStore) SetAllowAnyName(allow bool) {
+	r.AllowAnyName = allow
+	if r.store != nil {
+		r.store.allowAnyName = allow
+	}
+	for _, sub := range r.mounts {
+		sub.allowAnyName = allow
+	}
+}
+
+// IsSymmetric returns true if the given entry is only encrypted with a
+// passphrase
+func (r *RootStore) IsSymmetric(name string) bool {
+	store := r.getStore(name)
+	return store.IsSymmetric(strings.TrimPrefix(name, store.alias))
+}
+
+// SetSymmetric encrypts a single entry with a passphrase instead of the
+// recipients of the store
+func (r *RootStore) SetSymmetric(name string, content []byte, pass string) error {
+	store := r.getStore(name)
+	return store.SetSymmetric(strings.TrimPrefix(name, store.alias), content, pass)
+}
+
+// AddAttachment stores data as a named attachment of the given secret
+func (r *RootStore) AddAttachment(name, attachment string, data []byte) error {
+	store := r.getStore(name)
+	return store.AddAttachment(strings.TrimPrefix(name, store.alias), attachment, data)
+}
+
+// GetAttachment returns the plaintext of a single attachment
+func (r *RootStore) GetAttachment(name, attachment string) ([]byte, error) {
+	store := r.getStore(name)
+	return store.GetAttachment(strings.TrimPrefix(name, store.alias), attachment)
+}
+
+// ListAttachments returns the names of all attachments of the given secret
+func (r *RootStore) ListAttachments(name string) ([]string, error) {
+	store := r.getStore(name)
+	return store.ListAttachments(strings.TrimPrefix(name, store.alias))
+}
+
 // IsDir checks if a given key is actually a folder
 func (r *RootStore) IsDir(name string) bool {
 	store := r.getStore(name)
@@ -315,10 +529,43 @@ func (r *RootStore) Set(name string, content []byte) error {
 	return store.Set(strings.TrimPrefix(name, store.alias), content)
 }
 
-// SetConfirm calls Set with confirmation callback
+// SetConfirm calls Set with confirmation callback. The callback is passed the
+// full name of the entry, including the mount point.
 func (r *RootStore) SetConfirm(name string, content []byte, cb RecipientCallback) error {
 	store := r.getStore(name)
-	return store.SetConfirm(strings.TrimPrefix(name, store.alias), content, cb)
+	if cb == nil {
+		return store.SetConfirm(strings.TrimPrefix(name, store.alias), content, nil)
+	}
+	return store.SetConfirm(strings.TrimPrefix(name, store.alias), content, func(_ string, rs []string) ([]string, error) {
+		return cb(name, rs)
+	})
+}
+
+// HasRecipientOverride returns true if the given secret has its own set of
+// recipients
+func (r *RootStore) HasRecipientOverride(name string) bool {
+	store := r.getStore(name)
+	return store.HasRecipientOverride(strings.TrimPrefix(name, store.alias))
+}
+
+// RecipientOverride returns the recipient override of the given secret
+func (r *RootStore) RecipientOverride(name string) []string {
+	store := r.getStore(name)
+	return store.RecipientOverride(strings.TrimPrefix(name, store.alias))
+}
+
+// SetRecipientOverride encrypts the given secret only for the given
+// recipients instead of the recipients of its store
+func (r *RootStore) SetRecipientOverride(name string, ids []string) error {
+	store := r.getStore(name)
+	return store.SetRecipientOverride(strings.TrimPrefix(name, store.alias), ids)
+}
+
+// RemoveRecipientOverride encrypts the given secret for the recipients of
+// its store again
+func (r *RootStore) RemoveRecipientOverride(name string) error {
+	store := r.getStore(name)
+	return store.RemoveRecipientOverride(strings.TrimPrefix(name, store.alias))
 }
 
 // Copy will copy one entry to another location. Multi-store copies are
@@ -334,10 +581,13 @@ func (r *RootStore) Copy(from, to string) error {
 		if err != nil {
 			return err
 		}
+		if err := copyOverride(subFrom, from, subTo, to); err != nil {
+			return err
+		}
 		if err := subTo.Set(to, content); err != nil {
 			return err
 		}
-		return nil
+		return copyAttachments(subFrom, from, subTo, to)
 	}
 
 	from = strings.TrimPrefix(from, subFrom.alias)
@@ -355,13 +605,20 @@ func (r *RootStore) Move(from, to string) error {
 
 	// cross-store move
 	if !subFrom.equals(subTo) {
+		fmt.Println(color.YellowString("Note: %s is moved to another store. Its git history will not follow", from))
 		content, err := subFrom.Get(from)
 		if err != nil {
 			return err
 		}
+		if err := copyOverride(subFrom, from, subTo, to); err != nil {
+			return err
+		}
 		if err := subTo.Set(to, content); err != nil {
 			return err
 		}
+		if err := copyAttachments(subFrom, from, subTo, to); err != nil {
+			return err
+		}
 		if err := subFrom.Delete(from); err != nil {
 			return err
 		}
@@ -408,49 +665,84 @@ func (r *RootStore) GitInit(store, sk string) error {
 	return r.getStore(store).GitInit(sk)
 }
 
+// GitSetupDiffDriver configures git diff to decrypt the secrets of the given
+// store with the textconv command
+func (r *RootStore) GitSetupDiffDriver(store, textconv string) error {
+	return r.getStore(store).GitSetupDiffDriver(textconv)
+}
+
 // Git runs arbitrary git commands on this store and all substores
 func (r *RootStore) Git(store string, args ...string) error {
 	return r.getStore(store).Git(args...)
 }
 
-// Fsck checks the stores integrity
-func (r *RootStore) Fsck(check, force bool) error {
+// Fsck checks the stores integrity and returns the problems found in all
+// stores
+func (r *RootStore) Fsck(repair bool, out io.Writer) (FsckReport, error) {
+	report := newFsckReport()
 	sh := make(map[string]string, 100)
 	for _, alias := range r.mountPoints() {
 		// check sub-store integrity
-		if err := r.mounts[alias].Fsck(check, force); err != nil {
-			return err
+		sub, err := r.mounts[alias].Fsck(repair, out)
+		if err != nil {
+			return report, err
 		}
-		fmt.Println(color.GreenString("Store %s (%s) OK", alias, r.Mount[alias]))
+		report.merge(alias, sub)
+		printFsckStatus(out, color.GreenString("Store %s (%s)", alias, r.Mount[alias]), sub)
 		// check shadowing
 		lst, err := r.mounts[alias].List(alias)
 		if err != nil {
-			return err
+			return report, err
 		}
 		for _, e := range lst {
 			if a, found := sh[e]; found {
-				fmt.Println(color.YellowString("Entry %s is being shadowed by %s", e, a))
+				fmt.Fprintln(out, color.YellowString("Entry %s is being shadowed by %s", e, a))
+				report.Shadowed = append(report.Shadowed, e)
 			}
 			sh[e] = alias
 		}
 	}
 
-	if err := r.store.Fsck(check, force); err != nil {
-		return err
+	root, err := r.store.Fsck(repair, out)
+	if err != nil {
+		return report, err
 	}
-	fmt.Println(color.GreenString("Store (%s) OK", r.store.path))
+	report.merge("", root)
+	printFsckStatus(out, color.GreenString("Store (%s)", r.store.path), root)
 	// check shadowing
 	lst, err := r.store.List("")
 	if err != nil {
-		return err
+		return report, err
 	}
 	for _, e := range lst {
 		if a, found := sh[e]; found {
-			fmt.Println(color.YellowString("Entry %s is being shadowed by %s", e, a))
+			fmt.Fprintln(out, color.YellowString("Entry %s is being shadowed by %s", e, a))
+			report.Shadowed = append(report.Shadowed, e)
 		}
 		sh[e] = ""
 	}
-	return nil
+	sort.Strings(report.MissingKeys)
+	return report, nil
+}
+
+// SetFsckFunc sets the callback confirming the repairs of Fsck in all stores
+func (r *RootStore) SetFsckFunc(f FsckCallback) {
+	r.FsckFunc = f
+	if r.store != nil {
+		r.store.fsckFunc = f
+	}
+	for _, sub := range r.mounts {
+		sub.fsckFunc = f
+	}
+}
+
+// printFsckStatus prints a summary of the report of a single store
+func printFsckStatus(out io.Writer, store string, report FsckReport) {
+	if report.Problems() == 0 {
+		fmt.Fprintln(out, store+color.GreenString(" OK"))
+		return
+	}
+	fmt.Fprintln(out, store+color.YellowString(" %d problems found, %d repaired", report.Problems(), len(report.Repaired)))
 }
 
 // ListRecipients lists all recipients for the given store
@@ -458,6 +750,62 @@ func (r *RootStore) ListRecipients(store string) []string {
 	return r.getStore(store).recipients
 }
 
+// AllRecipients returns the recipients of every store, keyed by the mount
+// point. The root store uses the empty string.
+func (r *RootStore) AllRecipients() (map[string][]string, error) {
+	all := make(map[string][]string, len(r.mounts)+1)
+	rs, err := r.store.readRecipients()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipients of the root store: %s", err)
+	}
+	all[""] = rs
+	for alias, sub := range r.mounts {
+		rs, err := sub.readRecipients()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recipients of %s: %s", alias, err)
+		}
+		all[alias] = rs
+	}
+	return all, nil
+}
+
+// WeakRecipientKeys returns the weak recipient keys of all stores. Keys used
+// by several stores are only listed once.
+func (r *RootStore) WeakRecipientKeys() (gpg.KeyList, error) {
+	weak, err := r.store.WeakRecipientKeys()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{}, len(weak))
+	for _, k := range weak {
+		seen[k.Fingerprint] = struct{}{}
+	}
+	for _, alias := range r.mountPoints() {
+		substore := r.mounts[alias]
+		if substore == nil {
+			continue
+		}
+		kl, err := substore.WeakRecipientKeys()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the recipients of %s: %s", alias, err)
+		}
+		for _, k := range kl {
+			if _, found := seen[k.Fingerprint]; found {
+				continue
+			}
+			seen[k.Fingerprint] = struct{}{}
+			weak = append(weak, k)
+		}
+	}
+	return weak, nil
+}
+
+// ImportMissingPublicKey imports the public key of the given recipient from
+// the given store if it's missing from the keyring
+func (r *RootStore) ImportMissingPublicKey(store, rec string) error {
+	return r.getStore(store).importMissingPublicKey(rec)
+}
+
 // AddRecipient adds a single recipient to the given store
 func (r *RootStore) AddRecipient(store, rec string) error {
 	return r.getStore(store).AddRecipient(rec)
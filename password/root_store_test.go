@@ -0,0 +1,54 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseProfile(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	for _, d := range []string{"personal", "work"} {
+		if err := os.MkdirAll(filepath.Join(tempdir, d), 0700); err != nil {
+			t.Fatalf("Failed to create %s: %s", d, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tempdir, d, gpgID), []byte(fpr+"\n"), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %s", gpgID, err)
+		}
+	}
+
+	rs, err := NewRootStore(filepath.Join(tempdir, "personal"))
+	assert.NoError(t, err)
+	rs.SignKey = "personal"
+	assert.NoError(t, rs.Set("personal", []byte("secret")))
+
+	assert.NoError(t, rs.UseProfile(filepath.Join(tempdir, "work"), "work"))
+	assert.Equal(t, "work", rs.store.signKey)
+	assert.NoError(t, rs.Set("work", []byte("secret")))
+	_, err = rs.Get("personal")
+	assert.Equal(t, ErrNotFound, err)
+	_, err = os.Stat(filepath.Join(tempdir, "work", "work.gpg"))
+	assert.NoError(t, err)
+
+	// the config keeps its own path and signing key
+	buf, err := yaml.Marshal(rs)
+	assert.NoError(t, err)
+	cfg := map[string]interface{}{}
+	assert.NoError(t, yaml.Unmarshal(buf, &cfg))
+	assert.Equal(t, filepath.Join(tempdir, "personal"), cfg["path"])
+	assert.Equal(t, "personal", cfg["signkey"])
+}
@@ -0,0 +1,49 @@
+package password
+
+import (
+	"bytes"
+	"strings"
+)
+
+const (
+	// passwordField names the first line of a secret. A password: line in
+	// the body can't be read as a field, it's shadowed by the first line.
+	passwordField = "password"
+)
+
+// SplitSecret splits the content of a secret into the password, which is
+// the first line, and the body holding everything else. The body doesn't
+// include the newline ending the password.
+func SplitSecret(content []byte) ([]byte, []byte) {
+	i := bytes.IndexByte(content, '\n')
+	if i < 0 {
+		return content, nil
+	}
+	return content[:i], content[i+1:]
+}
+
+// Field returns the value of the first key: value line of the body of a
+// secret with the given key. The first line is the password and never a
+// field, it's returned for the key password instead.
+func Field(content []byte, key string) (string, bool) {
+	pw, body := SplitSecret(content)
+	if key == passwordField {
+		return string(pw), true
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if k, v, ok := parseField(line); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// parseField splits a key: value line into the key and the value without
+// surrounding whitespace. Keys don't contain whitespace.
+func parseField(line string) (string, string, bool) {
+	p := strings.SplitN(line, ":", 2)
+	if len(p) < 2 || p[0] == "" || strings.ContainsAny(p[0], " \t") {
+		return "", "", false
+	}
+	return p[0], strings.TrimSpace(p[1]), true
+}
@@ -0,0 +1,43 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitSecret(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		pw   string
+		body string
+	}{
+		{"", "", ""},
+		{"secret", "secret", ""},
+		{"secret\n", "secret", ""},
+		{"secret\nuser: gopher\n", "secret", "user: gopher\n"},
+		{"\nuser: gopher", "", "user: gopher"},
+	} {
+		pw, body := SplitSecret([]byte(tc.in))
+		assert.Equal(t, tc.pw, string(pw), tc.in)
+		assert.Equal(t, tc.body, string(body), tc.in)
+	}
+}
+
+func TestField(t *testing.T) {
+	content := []byte("user: not a field\nuser: gopher\nurl:https://example.com\n a b: c\ntags: \npassword: shadowed\n")
+	for key, want := range map[string]string{
+		"user":     "gopher",
+		"url":      "https://example.com",
+		"tags":     "",
+		"password": "user: not a field",
+	} {
+		got, ok := Field(content, key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, got, key)
+	}
+	for _, key := range []string{"a b", " a b", "https"} {
+		_, ok := Field(content, key)
+		assert.False(t, ok, key)
+	}
+}
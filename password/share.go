@@ -0,0 +1,43 @@
+package password
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// ExportEncrypted decrypts the given secret and writes a copy encrypted only
+// for the given recipients to out. The recipients are not added to the store.
+// Missing public keys are imported from the store if loadkeys permits it.
+func (s *Store) ExportEncrypted(name string, recipients []string, out io.Writer) error {
+	if len(recipients) < 1 {
+		return fmt.Errorf("provide at least one recipient")
+	}
+
+	for _, r := range recipients {
+		ierr := s.importMissingPublicKey(r)
+		kl, err := gpg.ListPublicKeys(r)
+		if err != nil || len(kl) < 1 {
+			if ierr != nil {
+				return fmt.Errorf("no public key found for %s: %s", r, ierr)
+			}
+			return fmt.Errorf("no public key found for %s", r)
+		}
+	}
+
+	content, err := s.Get(name)
+	if err != nil {
+		return err
+	}
+
+	return gpg.EncryptTo(out, content, recipients, s.encryptOpts())
+}
+
+// ExportEncrypted writes a copy of the given secret encrypted only for the
+// given recipients to out
+func (r *RootStore) ExportEncrypted(name string, recipients []string, out io.Writer) error {
+	store := r.getStore(name)
+	return store.ExportEncrypted(strings.TrimPrefix(name, store.alias), recipients, out)
+}
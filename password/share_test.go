@@ -0,0 +1,67 @@
+package password
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportEncrypted(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass external", "external@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate external key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("external@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list external key: %s", err)
+	}
+	external := kl[0]
+
+	tempdir, cleanupDir := newTestDir(t, fpr)
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo/bar", []byte("secret")))
+
+	buf := &bytes.Buffer{}
+	assert.Error(t, s.ExportEncrypted("foo/bar", []string{}, buf))
+	assert.Error(t, s.ExportEncrypted("foo/bar", []string{"nobody@gopass.pw"}, buf))
+	assert.Equal(t, ErrNotFound, s.ExportEncrypted("missing", []string{external.Fingerprint}, buf))
+
+	assert.NoError(t, s.ExportEncrypted("foo/bar", []string{external.Fingerprint}, buf))
+	fn := filepath.Join(tempdir, "share.gpg")
+	assert.NoError(t, ioutil.WriteFile(fn, buf.Bytes(), 0600))
+
+	// only the external key is a recipient of the share
+	recs, err := gpg.GetRecipients(fn)
+	assert.NoError(t, err)
+	if assert.Len(t, recs, 1) {
+		assert.True(t, external.HasID(recs[0]))
+	}
+	content, err := gpg.Decrypt(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(content))
+
+	// the store still has its own recipients only
+	assert.Equal(t, []string{fpr}, s.recipients)
+	buf2, err := ioutil.ReadFile(filepath.Join(tempdir, gpgID))
+	assert.NoError(t, err)
+	assert.Equal(t, fpr+"\n", string(buf2))
+
+	// without the external secret key the share can not be decrypted
+	out, err := exec.Command(gpg.GPGBin, "--batch", "--yes", "--delete-secret-keys", external.Fingerprint).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to delete external secret key: %s: %s", err, out)
+	}
+	_, err = gpg.Decrypt(fn)
+	assert.Error(t, err)
+}
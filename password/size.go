@@ -0,0 +1,32 @@
+package password
+
+import "fmt"
+
+const (
+	// DefaultMaxSecretSize is the size in bytes above which storing a secret
+	// must be confirmed, unless maxsecretsize is set
+	DefaultMaxSecretSize = 1 << 20
+	// SecretSizeCap is the size in bytes no secret may exceed, regardless of
+	// maxsecretsize
+	SecretSizeCap = 32 << 20
+)
+
+// ErrSecretTooLarge is returned for secrets larger than SecretSizeCap
+var ErrSecretTooLarge = fmt.Errorf("secrets must not be larger than %d MiB, store large files as attachments instead", SecretSizeCap>>20)
+
+// checkSize refuses content larger than SecretSizeCap, before it's encrypted
+func checkSize(content []byte) error {
+	if len(content) > SecretSizeCap {
+		return ErrSecretTooLarge
+	}
+	return nil
+}
+
+// MaxSize returns the size in bytes above which storing a secret must be
+// confirmed
+func (r *RootStore) MaxSize() int {
+	if r.MaxSecretSize < 1 {
+		return DefaultMaxSecretSize
+	}
+	return r.MaxSecretSize
+}
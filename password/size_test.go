@@ -0,0 +1,26 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretSizeCap(t *testing.T) {
+	tempdir, cleanup := newTestDir(t, "DEADBEEF")
+	defer cleanup()
+
+	r, err := NewRootStore(tempdir)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultMaxSecretSize, r.MaxSize())
+	r.MaxSecretSize = 1024
+	assert.Equal(t, 1024, r.MaxSize())
+
+	// too large secrets are refused before they are encrypted
+	huge := make([]byte, SecretSizeCap+1)
+	assert.Equal(t, ErrSecretTooLarge, r.Set("foo", huge))
+	results, err := r.SetBatch([]BatchEntry{{Name: "foo", Content: huge}}, "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, BatchFailed, results[0].Status)
+	assert.Equal(t, ErrSecretTooLarge, results[0].Err)
+}
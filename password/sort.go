@@ -11,3 +11,24 @@ func (s byLen) Less(i, j int) bool { return len(s[i]) > len(s[j]) }
 
 // Swap Mount Point in the list of Mount Points.
 func (s byLen) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// metaByName is a list of secrets that can be sorted by name
+type metaByName []SecretMeta
+
+func (s metaByName) Len() int           { return len(s) }
+func (s metaByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s metaByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// MetaByTime is a list of secrets that can be sorted by their last change,
+// newest first. Secrets changed at the same time are sorted by name.
+type MetaByTime []SecretMeta
+
+func (s MetaByTime) Len() int { return len(s) }
+func (s MetaByTime) Less(i, j int) bool {
+	ti, tj := s[i].Time(), s[j].Time()
+	if ti.Equal(tj) {
+		return s[i].Name < s[j].Name
+	}
+	return ti.After(tj)
+}
+func (s MetaByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
@@ -3,6 +3,7 @@ package password
 import (
 	"sort"
 	"testing"
+	"time"
 )
 
 func TestMountPointSort(t *testing.T) {
@@ -22,3 +23,27 @@ func TestMountPointSort(t *testing.T) {
 		}
 	}
 }
+
+func TestMetaSort(t *testing.T) {
+	now := time.Now()
+	lst := []SecretMeta{
+		{Name: "b", Modified: now.Add(-time.Hour)},
+		{Name: "c", Modified: now, Committed: now.Add(-2 * time.Hour)},
+		{Name: "a", Modified: now.Add(-time.Hour)},
+		{Name: "d", Modified: now},
+	}
+
+	sort.Sort(metaByName(lst))
+	for i, v := range []string{"a", "b", "c", "d"} {
+		if lst[i].Name != v {
+			t.Errorf("Mismatch at %d: %s vs. %s", i, v, lst[i].Name)
+		}
+	}
+
+	sort.Sort(MetaByTime(lst))
+	for i, v := range []string{"d", "a", "b", "c"} {
+		if lst[i].Name != v {
+			t.Errorf("Mismatch at %d: %s vs. %s", i, v, lst[i].Name)
+		}
+	}
+}
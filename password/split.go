@@ -0,0 +1,369 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/log"
+)
+
+// SplitPlan is what moving a folder of a store into a new store mounted in
+// its place does, see RootStore.SplitPlan
+type SplitPlan struct {
+	Prefix string
+	// Origin is the path of the store the secrets are moved out of
+	Origin string
+	Path   string
+	// Secrets are the names of the secrets below the prefix, without it
+	Secrets []string
+	// Recipients of the new store. Init is set if it's initialized for the
+	// recipients of the origin, otherwise it's an existing store.
+	Recipients []string
+	Init       bool
+	// Reencrypt are the secrets re-encrypted for the recipients of an
+	// existing store. All others are copied as they are.
+	Reencrypt []string
+	// History is set if the git history of the folder is carried over
+	History bool
+	// Collisions are the files below the prefix, e.g. secrets or
+	// attachments, which already exist at Path. They are only overwritten
+	// with SplitOptions.Overwrite.
+	Collisions []string
+}
+
+// SplitOptions change how SplitMount moves the secrets
+type SplitOptions struct {
+	// Keep leaves the secrets in the origin, where the new mount shadows them
+	Keep bool
+	// Overwrite allows replacing the files of the store at the destination,
+	// see SplitPlan.Collisions
+	Overwrite bool
+}
+
+// splitStores returns the store holding the prefix, the folder of the prefix
+// in it and the store at dst
+func (r *RootStore) splitStores(prefix, dst string) (*Store, string, *Store, error) {
+	if prefix == "" {
+		return nil, "", nil, fmt.Errorf("need a folder to split off")
+	}
+	if _, found := r.mounts[prefix]; found {
+		return nil, "", nil, fmt.Errorf("%s is already mounted", prefix)
+	}
+	origin := r.getStore(prefix)
+	rel := strings.TrimPrefix(strings.TrimPrefix(prefix, origin.alias), "/")
+	if origin.alias != "" && !strings.HasPrefix(prefix, origin.alias+"/") {
+		origin, rel = r.store, prefix
+	}
+	if !fsutil.IsDir(filepath.Join(origin.path, rel)) {
+		return nil, "", nil, fmt.Errorf("%s is not a folder", prefix)
+	}
+	dst = fsutil.CleanPath(dst)
+	if dst == origin.path {
+		return nil, "", nil, fmt.Errorf("%s is the path of the store holding %s", dst, prefix)
+	}
+	if err := r.CheckNesting(dst); err != nil {
+		return nil, "", nil, err
+	}
+	sub, err := NewStore(prefix, dst, r)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return origin, rel, sub, nil
+}
+
+// SplitPlan reports what SplitMount would do, without changing anything
+func (r *RootStore) SplitPlan(prefix, dst string) (SplitPlan, error) {
+	prefix = strings.Trim(prefix, "/")
+	origin, rel, sub, err := r.splitStores(prefix, dst)
+	if err != nil {
+		return SplitPlan{}, err
+	}
+	return splitPlan(prefix, origin, rel, sub)
+}
+
+// splitPlan reports what moving the folder rel of the origin into sub does
+func splitPlan(prefix string, origin *Store, rel string, sub *Store) (SplitPlan, error) {
+	names, err := listNames("", filepath.Join(origin.path, rel))
+	if err != nil {
+		return SplitPlan{}, err
+	}
+	if len(names) < 1 {
+		return SplitPlan{}, fmt.Errorf("there are no secrets below %s", prefix)
+	}
+	p := SplitPlan{
+		Prefix:     prefix,
+		Origin:     origin.path,
+		Path:       sub.path,
+		Secrets:    names,
+		Recipients: sub.recipients,
+		Init:       !sub.Initialized(),
+		Reencrypt:  make([]string, 0, len(names)),
+		History:    origin.isGit() && isEmptyDir(sub.path),
+	}
+	if p.Collisions, err = collisions(filepath.Join(origin.path, rel), sub.path); err != nil {
+		return SplitPlan{}, err
+	}
+	if p.Init {
+		p.Recipients = origin.recipients
+		return p, nil
+	}
+	for _, name := range names {
+		src := rel + "/" + name
+		// overrides are copied along with their secret
+		if origin.IsSymmetric(src) || origin.HasRecipientOverride(src) {
+			continue
+		}
+		if !sameRecipients(origin.recipientsFor(src), sub.recipientsFor(name)) {
+			p.Reencrypt = append(p.Reencrypt, name)
+		}
+	}
+	return p, nil
+}
+
+// SplitMount moves all secrets below the prefix, with their attachments and
+// overrides, into a new store at dst and mounts it at the prefix. A new
+// store is initialized for the recipients of the origin, so the secrets are
+// copied as they are. Those of an existing store with other recipients are
+// re-encrypted. Files existing at dst are only overwritten with
+// opts.Overwrite. If the origin is a git repository the new store is one as
+// well and, if dst is empty, the history of the folder is carried over on a
+// best-effort basis. Both stores are locked until the secrets are removed
+// from the origin, which only happens once all of them have been copied.
+func (r *RootStore) SplitMount(prefix, dst string, opts SplitOptions) error {
+	prefix = strings.Trim(prefix, "/")
+	origin, rel, sub, err := r.splitStores(prefix, dst)
+	if err != nil {
+		return err
+	}
+	for _, s := range []*Store{origin, sub} {
+		unlock, err := s.Lock()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	p, err := splitPlan(prefix, origin, rel, sub)
+	if err != nil {
+		return err
+	}
+	if len(p.Collisions) > 0 && !opts.Overwrite {
+		return fmt.Errorf("%d files below %s, e.g. %s, exist in %s already", len(p.Collisions), p.Prefix, p.Collisions[0], p.Path)
+	}
+
+	wasGit := sub.isGit()
+	history := false
+	if p.History {
+		if err := sub.gitImportHistory(origin, rel); err != nil {
+			fmt.Println(color.YellowString("Warning: Failed to carry over the git history of %s: %s", p.Prefix, err))
+			_ = os.RemoveAll(filepath.Join(sub.path, ".git"))
+		} else {
+			history = true
+		}
+	}
+
+	if err := sub.copyTree(filepath.Join(origin.path, rel), sub.path); err != nil {
+		return err
+	}
+	if p.Init {
+		for _, fn := range []string{gpgID, requiredID, ageRecipientsID} {
+			src := filepath.Join(origin.path, fn)
+			if !fsutil.IsFile(src) {
+				continue
+			}
+			if err := sub.copyFile(src, filepath.Join(sub.path, fn)); err != nil {
+				return err
+			}
+		}
+		// load the recipients just copied
+		if err := sub.loadRecipientFiles(); err != nil {
+			return err
+		}
+	}
+	if len(p.Reencrypt) > 0 {
+		opts := sub.encryptOpts()
+		if err := opts.Validate(); err != nil {
+			return err
+		}
+		res := newRecipientResolver(sub)
+		for _, name := range p.Reencrypt {
+			if _, err := sub.reencryptEntry(name, opts, res); err != nil {
+				return fmt.Errorf("failed to re-encrypt %s: %s", name, err)
+			}
+		}
+	}
+
+	// never remove anything that didn't arrive
+	names, err := listNames("", sub.path)
+	if err != nil {
+		return err
+	}
+	if missing := missingNames(p.Secrets, names); len(missing) > 0 {
+		return fmt.Errorf("%d secrets, e.g. %s, are missing in %s. %s is left as it is", len(missing), missing[0], sub.path, p.Prefix)
+	}
+
+	switch {
+	case wasGit:
+		if err := sub.gitAdd(sub.path); err == nil {
+			err = sub.gitCommit(fmt.Sprintf("Add %s split off %s.", p.Prefix, origin.path))
+		}
+		if err != nil {
+			fmt.Println(color.YellowString("Warning: Failed to commit the secrets to git: %s", err))
+		}
+	case origin.isGit():
+		if err := sub.gitSplitSetup(origin, history); err != nil {
+			fmt.Println(color.YellowString("Warning: Failed to initialize git in %s: %s", sub.path, err))
+		}
+	}
+
+	if err := r.AddMount(p.Prefix, sub.path); err != nil {
+		return err
+	}
+	if opts.Keep {
+		return nil
+	}
+	return origin.Prune(rel)
+}
+
+// gitImportHistory makes this new store a git repository holding the
+// history of the given folder of the origin, see git subtree split
+func (s *Store) gitImportHistory(origin *Store, rel string) error {
+	buf := &bytes.Buffer{}
+	cmd := origin.gitCommand("subtree", "split", "-q", "--prefix="+rel)
+	cmd.Stdout = buf
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git subtree split failed: %s", err)
+	}
+	commit := strings.TrimSpace(buf.String())
+	log.Debugf("split: the history of %s ends in %s", rel, commit)
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"fetch", "-q", origin.path, commit},
+		{"reset", "-q", "--hard", "FETCH_HEAD"},
+	} {
+		cmd := s.gitCommand(args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("git %s failed: %s", args[0], err)
+		}
+	}
+	return nil
+}
+
+// gitSplitSetup configures the git repository of a store split off the
+// origin like the origin, creating it unless the history was imported
+func (s *Store) gitSplitSetup(origin *Store, history bool) error {
+	if !history {
+		cmd := s.gitCommand("init", "-q")
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+	if err := s.gitSetup(); err != nil {
+		return err
+	}
+	if sk, err := origin.gitConfigValue("user.signingkey"); err == nil && sk != "" {
+		return s.gitSetSignKey(sk)
+	}
+	return nil
+}
+
+// copyTree copies all files below src to dst as they are, enforcing the file
+// mode of this store and keeping their modification time. Symlinks are
+// skipped.
+func (s *Store) copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		to := filepath.Join(dst, strings.TrimPrefix(path, src))
+		switch {
+		case fi.IsDir():
+			return os.MkdirAll(to, dirMode)
+		case fi.Mode().IsRegular():
+			return s.copyFile(path, to)
+		}
+		log.Debugf("split: skipping %s", path)
+		return nil
+	})
+}
+
+// copyFile copies a file into this store as it is, keeping its modification
+// time
+func (s *Store) copyFile(src, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	buf, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := s.writeFile(dst, buf); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
+
+// collisions returns the files below src which copyTree would overwrite in
+// dst, relative to src
+func collisions(src, dst string) ([]string, error) {
+	found := make([]string, 0, 1)
+	err := filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(path, src), string(filepath.Separator))
+		if fsutil.IsFile(filepath.Join(dst, rel)) {
+			found = append(found, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return found, err
+}
+
+// isEmptyDir returns true if the folder doesn't exist or holds nothing but
+// the lock of the store
+func isEmptyDir(path string) bool {
+	names, err := readDirNames(path)
+	if os.IsNotExist(err) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		if name != lockFile {
+			return false
+		}
+	}
+	return true
+}
+
+// missingNames returns the names of want which aren't in have
+func missingNames(want, have []string) []string {
+	m := make(map[string]struct{}, len(have))
+	for _, n := range have {
+		m[n] = struct{}{}
+	}
+	missing := make([]string, 0, 1)
+	for _, n := range want {
+		if _, found := m[n]; !found {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,138 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitMount(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	defer setGitIdentity(t)()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	root := filepath.Join(tempdir, "root")
+	assert.NoError(t, os.MkdirAll(root, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, gpgID), []byte(fpr+"\n"), 0600))
+
+	r, err := NewRootStore(root)
+	assert.NoError(t, err)
+	assert.NoError(t, r.GitInit("", ""))
+	for _, name := range []string{"top", "other/x", "work/a", "work/b/c", "work/d"} {
+		assert.NoError(t, r.Set(name, []byte("secret of "+name)))
+	}
+	assert.NoError(t, r.AddAttachment("work/d", "file", []byte("data")))
+	before, err := r.List()
+	assert.NoError(t, err)
+	assert.Len(t, before, 5)
+
+	dst := filepath.Join(tempdir, "work")
+	p, err := r.SplitPlan("work/", dst)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b/c", "d"}, p.Secrets)
+	assert.True(t, p.Init)
+	assert.True(t, p.History)
+	assert.Len(t, p.Reencrypt, 0)
+
+	fi, err := os.Stat(filepath.Join(root, "work", "a.gpg"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chtimes(filepath.Join(root, "work", "a.gpg"), fi.ModTime().Add(-time.Hour), fi.ModTime().Add(-time.Hour)))
+	assert.NoError(t, r.SplitMount("work", dst, SplitOptions{}))
+	after, err := r.List()
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+	assert.Equal(t, dst, r.Mount["work"])
+	content, err := r.Get("work/b/c")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret of work/b/c", string(content))
+	att, err := r.GetAttachment("work/d", "file")
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(att))
+	assert.False(t, fsutil.IsDir(filepath.Join(root, "work")))
+	// the files are copied with their modification time
+	moved, err := os.Stat(filepath.Join(dst, "a.gpg"))
+	assert.NoError(t, err)
+	assert.Equal(t, fi.ModTime().Add(-time.Hour).Unix(), moved.ModTime().Unix())
+
+	// the history of the secrets came along
+	out, err := exec.Command("git", "-C", dst, "log", "--format=%s").Output()
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "Save secret to work/b/c.")
+	assert.True(t, strings.HasPrefix(string(out), "Configure git repository"))
+
+	_, err = r.SplitPlan("work", filepath.Join(tempdir, "again"))
+	assert.Error(t, err)
+	_, err = r.SplitPlan("missing", filepath.Join(tempdir, "missing"))
+	assert.Error(t, err)
+	_, err = r.SplitPlan("other", root)
+	assert.Error(t, err)
+
+	// the originals can be kept, shadowed by the mount
+	assert.NoError(t, r.SplitMount("other", filepath.Join(tempdir, "other"), SplitOptions{Keep: true}))
+	after, err = r.List()
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+	assert.True(t, fsutil.IsDir(filepath.Join(root, "other")))
+}
+
+func TestSplitMountCollisions(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	root := filepath.Join(tempdir, "root")
+	dst := filepath.Join(tempdir, "existing")
+	for _, dir := range []string{root, dst} {
+		assert.NoError(t, os.MkdirAll(dir, 0700))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, gpgID), []byte(fpr+"\n"), 0600))
+	}
+
+	r, err := NewRootStore(root)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Set("work/a", []byte("new a")))
+	assert.NoError(t, r.Set("work/b", []byte("new b")))
+	existing, err := NewStore("", dst, r)
+	assert.NoError(t, err)
+	assert.NoError(t, existing.Set("a", []byte("old a")))
+
+	p, err := r.SplitPlan("work", dst)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.gpg"}, p.Collisions)
+
+	// existing files are never overwritten silently
+	err = r.SplitMount("work", dst, SplitOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "a.gpg")
+	content, err := existing.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "old a", string(content))
+	assert.True(t, fsutil.IsDir(filepath.Join(root, "work")))
+	assert.Len(t, r.Mount, 0)
+
+	assert.NoError(t, r.SplitMount("work", dst, SplitOptions{Overwrite: true}))
+	content, err = r.Get("work/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "new a", string(content))
+}
@@ -0,0 +1,232 @@
+package password
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// The reasons a recipient is stale
+const (
+	StaleMissing = "missing"
+	StaleRevoked = "revoked"
+	StaleExpired = "expired"
+)
+
+// StaleRecipient is a recipient whose key isn't in the keyring, has been
+// revoked or has expired
+type StaleRecipient struct {
+	ID string
+	// File is the recipient file listing it, relative to the store
+	File   string
+	Reason string
+}
+
+// PruneReport is what PruneRecipients removes, or would remove
+type PruneReport struct {
+	Stale []StaleRecipient
+	// Reencrypt are the secrets encrypted for the remaining recipients
+	Reencrypt []string
+	// Lockout are the recipient files left without any recipient whose
+	// private key is in the keyring, i.e. you can't decrypt their secrets
+	// anymore
+	Lockout []string
+}
+
+// recipientFile is a file listing the recipients of a store or a secret
+type recipientFile struct {
+	path string
+	// name is the secret of an override, empty for the store
+	name       string
+	recipients []string
+}
+
+// PruneRecipients removes the stale recipients from the .gpg-id of this
+// store and the recipient overrides of the secrets below the prefix, and
+// encrypts the secrets affected for the remaining recipients. The .gpg-id of
+// the store is only checked without a prefix. A recipient file is never
+// emptied. Recipients whose public key is kept in the store aren't missing,
+// and gpg groups are left alone. With dryRun nothing is changed.
+func (s *Store) PruneRecipients(prefix string, dryRun bool) (PruneReport, error) {
+	report := PruneReport{
+		Stale:     make([]StaleRecipient, 0, 5),
+		Reencrypt: make([]string, 0, 10),
+		Lockout:   make([]string, 0, 1),
+	}
+	prefix = strings.Trim(prefix, "/")
+	files, names, err := s.recipientFiles(prefix)
+	if err != nil {
+		return report, err
+	}
+
+	pub, err := gpg.ListPublicKeys()
+	if err != nil {
+		return report, fmt.Errorf("failed to list public keys: %s", err)
+	}
+	priv, err := gpg.ListPrivateKeys()
+	if err != nil {
+		return report, fmt.Errorf("failed to list private keys: %s", err)
+	}
+	groups, err := gpg.ListGroups()
+	if err != nil {
+		groups = map[string][]string{}
+	}
+
+	changed := make([]recipientFile, 0, len(files))
+	for _, f := range files {
+		rel := strings.TrimPrefix(strings.TrimPrefix(f.path, s.path), "/")
+		remaining := make([]string, 0, len(f.recipients))
+		for _, id := range f.recipients {
+			reason := s.staleReason(id, pub, groups)
+			if reason == "" {
+				remaining = append(remaining, id)
+				continue
+			}
+			report.Stale = append(report.Stale, StaleRecipient{ID: id, File: rel, Reason: reason})
+		}
+		if len(remaining) == len(f.recipients) {
+			continue
+		}
+		if len(remaining) < 1 {
+			return report, fmt.Errorf("refusing to remove all recipients of %s: %s", rel, strings.Join(f.recipients, ", "))
+		}
+		if f.name == "" {
+			if err := s.checkRequired(f.recipients, remaining); err != nil {
+				return report, err
+			}
+		}
+		if !hasPrivateKey(gpg.ExpandGroups(remaining, groups), priv) {
+			report.Lockout = append(report.Lockout, rel)
+		}
+		changed = append(changed, recipientFile{path: f.path, name: f.name, recipients: remaining})
+	}
+
+	for _, f := range changed {
+		if f.name != "" {
+			report.Reencrypt = append(report.Reencrypt, f.name)
+			continue
+		}
+		// the secrets using the recipients of the store
+		for _, name := range names {
+			if !s.HasRecipientOverride(name) && !s.IsSymmetric(name) {
+				report.Reencrypt = append(report.Reencrypt, name)
+			}
+		}
+	}
+	sort.Strings(report.Reencrypt)
+	if dryRun || len(changed) < 1 {
+		return report, nil
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return report, err
+	}
+	defer unlock()
+
+	written := make([]string, 0, len(changed))
+	for _, f := range changed {
+		if f.name == "" {
+			s.recipients = f.recipients
+			if err := s.saveRecipients(); err != nil {
+				return report, err
+			}
+		} else if err := s.writeFile(f.path, marshalRecipients(f.recipients)); err != nil {
+			return report, err
+		}
+		written = append(written, f.path)
+	}
+	s.recipientsChanged()
+
+	failed, err := s.reencryptEntries(report.Reencrypt, fmt.Sprintf("Remove %d stale recipients.", len(report.Stale)), written...)
+	if err != nil {
+		return report, err
+	}
+	if failed > 0 {
+		return report, fmt.Errorf("failed to re-encrypt %d of %d secrets", failed, len(report.Reencrypt))
+	}
+	return report, nil
+}
+
+// PruneRecipients removes the stale recipients of the store holding the
+// prefix, see Store.PruneRecipients
+func (r *RootStore) PruneRecipients(prefix string, dryRun bool) (PruneReport, error) {
+	store := r.getStore(prefix)
+	return store.PruneRecipients(strings.TrimPrefix(prefix, store.alias), dryRun)
+}
+
+// recipientFiles returns the .gpg-id of the store, unless there is a
+// prefix, and the recipient overrides of the secrets below the prefix,
+// along with the names of all secrets below it
+func (s *Store) recipientFiles(prefix string) ([]recipientFile, []string, error) {
+	entries, err := s.List("")
+	if err != nil {
+		return nil, nil, err
+	}
+	names := NamesBelow(entries, prefix)
+	if prefix != "" && len(names) < 1 {
+		return nil, nil, ErrNotFound
+	}
+
+	files := make([]recipientFile, 0, 1)
+	if prefix == "" {
+		rs, err := s.readRecipients()
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, recipientFile{path: s.idFile(), recipients: rs})
+	}
+	for _, name := range names {
+		fn := s.overrideFile(name)
+		if !strings.HasPrefix(fn, s.path) || !fsutil.IsFile(fn) {
+			continue
+		}
+		fh, err := os.Open(fn)
+		if err != nil {
+			return nil, nil, err
+		}
+		rs := unmarshalRecipients(fh)
+		_ = fh.Close()
+		files = append(files, recipientFile{path: fn, name: name, recipients: rs})
+	}
+	return files, names, nil
+}
+
+// staleReason returns why the key of a recipient is stale, or an empty
+// string if it isn't
+func (s *Store) staleReason(id string, pub gpg.KeyList, groups map[string][]string) string {
+	if _, found := groups[id]; found {
+		return ""
+	}
+	k, err := pub.FindKey(id)
+	if err != nil {
+		// it would be imported from the store
+		if fsutil.IsFile(filepath.Join(s.path, keyDir, id)) {
+			return ""
+		}
+		return StaleMissing
+	}
+	if k.IsRevoked() {
+		return StaleRevoked
+	}
+	if k.IsExpired() {
+		return StaleExpired
+	}
+	return ""
+}
+
+// hasPrivateKey returns true if any of the recipients has a private key in
+// the given keys
+func hasPrivateKey(ids []string, priv gpg.KeyList) bool {
+	for _, id := range ids {
+		if _, err := priv.FindKey(id); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// revokedKey generates a key and revokes it with the revocation certificate
+// gpg created along with it
+func revokedKey(t *testing.T) string {
+	require.NoError(t, gpg.GenerateKey("revoked", "revoked@gopass.pw", ""))
+	kl, err := gpg.ListPublicKeys("revoked@gopass.pw")
+	require.NoError(t, err)
+	require.Len(t, kl, 1)
+	fpr := kl[0].Fingerprint
+
+	buf, err := ioutil.ReadFile(filepath.Join(os.Getenv("GNUPGHOME"), "openpgp-revocs.d", fpr+".rev"))
+	if err != nil {
+		t.Skipf("gpg didn't create a revocation certificate: %s", err)
+	}
+	// the certificate is protected from accidental imports
+	fn := filepath.Join(os.Getenv("GNUPGHOME"), "revoke.asc")
+	require.NoError(t, ioutil.WriteFile(fn, []byte(strings.Replace(string(buf), ":-----BEGIN", "-----BEGIN", 1)), 0600))
+	require.NoError(t, gpg.ImportPublicKey(fn))
+
+	kl, err = gpg.ListPublicKeys(fpr)
+	require.NoError(t, err)
+	require.True(t, kl[0].IsRevoked())
+	return fpr
+}
+
+func TestPruneRecipients(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+	revoked := revokedKey(t)
+	missing := "DEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF"
+
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	s := &Store{path: tempdir, alwaysTrust: true, recipients: []string{fpr}}
+	assert.NoError(t, s.saveRecipients())
+	for _, name := range []string{"a", "team/b", "team/c"} {
+		assert.NoError(t, s.Set(name, []byte("secret of "+name)))
+	}
+	assert.NoError(t, s.SetRecipientOverride("team/c", []string{fpr}))
+	// stale recipients can't be encrypted for, so they are added afterwards
+	// as if the keys had been removed or revoked since
+	assert.NoError(t, ioutil.WriteFile(s.idFile(), marshalRecipients([]string{fpr, revoked, missing}), 0600))
+	assert.NoError(t, ioutil.WriteFile(s.overrideFile("team/c"), marshalRecipients([]string{missing, fpr}), 0600))
+	s.recipients = []string{fpr, revoked, missing}
+
+	// the public key kept in the store counts
+	kept := "CAFEBABECAFEBABECAFEBABECAFEBABECAFEBABE"
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempdir, keyDir), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempdir, keyDir, kept), []byte("key"), 0600))
+	assert.NoError(t, ioutil.WriteFile(s.idFile(), marshalRecipients([]string{fpr, revoked, missing, kept}), 0600))
+
+	report, err := s.PruneRecipients("", true)
+	assert.NoError(t, err)
+	assert.Len(t, report.Stale, 3)
+	for _, sr := range []StaleRecipient{
+		{ID: revoked, File: gpgID, Reason: StaleRevoked},
+		{ID: missing, File: gpgID, Reason: StaleMissing},
+		{ID: missing, File: "team/c.gpg-id", Reason: StaleMissing},
+	} {
+		assert.Contains(t, report.Stale, sr)
+	}
+	assert.Equal(t, []string{"a", "team/b", "team/c"}, report.Reencrypt)
+	assert.Len(t, report.Lockout, 0)
+
+	// only the overrides below a prefix
+	report, err = s.PruneRecipients("team", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []StaleRecipient{{ID: missing, File: "team/c.gpg-id", Reason: StaleMissing}}, report.Stale)
+	assert.Equal(t, []string{"team/c"}, report.Reencrypt)
+	_, err = s.PruneRecipients("nothing", true)
+	assert.Equal(t, ErrNotFound, err)
+
+	// the kept key is made up, so it can't be encrypted for
+	assert.NoError(t, ioutil.WriteFile(s.idFile(), marshalRecipients([]string{fpr, revoked, missing}), 0600))
+	_, err = s.PruneRecipients("", false)
+	assert.NoError(t, err)
+	rs, err := s.readRecipients()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{fpr}, rs)
+	assert.Equal(t, []string{fpr}, s.RecipientOverride("team/c"))
+	content, err := s.Get("team/c")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret of team/c", string(content))
+
+	// a recipient file is never emptied
+	assert.NoError(t, ioutil.WriteFile(s.overrideFile("team/c"), marshalRecipients([]string{missing}), 0600))
+	_, err = s.PruneRecipients("team", false)
+	assert.Error(t, err)
+	assert.Equal(t, []string{missing}, s.RecipientOverride("team/c")[:1])
+
+	// leaving only recipients without a private key locks you out
+	assert.NoError(t, ioutil.WriteFile(s.overrideFile("team/c"), marshalRecipients([]string{missing, kept}), 0600))
+	report, err = s.PruneRecipients("team", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team/c.gpg-id"}, report.Lockout)
+}
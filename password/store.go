@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/justwatchcom/gopass/fsutil"
 	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/hooks"
 )
 
 const (
@@ -26,6 +28,8 @@ var (
 	ErrDecrypt = fmt.Errorf("Failed to decrypt")
 	// ErrSneaky is returned if the user passes a possible malicious path to gopass
 	ErrSneaky = fmt.Errorf("you've attempted to pass a sneaky path to gopass. go home")
+	// ErrAborted is returned if the user didn't confirm a bulk change
+	ErrAborted = fmt.Errorf("aborted")
 )
 
 // RecipientCallback is a callback to verify the list of recipients
@@ -39,19 +43,76 @@ type ImportCallback func(string) bool
 // corrective actions
 type FsckCallback func(string) bool
 
+// PassphraseCallback is a callback to ask the user for the passphrase
+// of a symmetrically encrypted secret
+type PassphraseCallback func(string) (string, error)
+
+// KeyPassphrase is a passphrase of the private key returned by a
+// KeyPassphraseCallback
+type KeyPassphrase struct {
+	Passphrase string
+	// Cached is set if the passphrase wasn't typed, e.g. it's taken from the
+	// OS keyring. A wrong cached passphrase doesn't count as an attempt.
+	Cached bool
+	// Accepted is called, unless nil, once gpg accepted the passphrase
+	Accepted func()
+}
+
+// KeyPassphraseCallback is a callback to get the passphrase of the private
+// key with the loopback pinentry. retry is set if the passphrase it returned
+// before was wrong.
+type KeyPassphraseCallback func(prompt string, retry bool) (KeyPassphrase, error)
+
 // Store is password store
 type Store struct {
-	recipients  []string
-	alias       string
-	path        string
-	autoPush    bool
-	autoPull    bool
-	autoImport  bool
-	persistKeys bool
-	loadKeys    bool
-	alwaysTrust bool
-	importFunc  ImportCallback
-	fsckFunc    FsckCallback
+	recipients   []string
+	alias        string
+	path         string
+	autoPush     bool
+	autoPull     bool
+	autoImport   bool
+	persistKeys  bool
+	loadKeys     bool
+	alwaysTrust  bool
+	signCommits  bool
+	signKey      string
+	signStrict   bool
+	cipherAlgo   string
+	digestAlgo   string
+	compressAlgo string
+	// autoCompress disables compression for incompressible content,
+	// noCompress for all content
+	autoCompress bool
+	noCompress   bool
+	useLoopback  bool
+	// passRetries is how often the loopback pinentry asks for the key
+	// passphrase
+	passRetries int
+	// tryAllSecrets makes gpg try all secret keys on every decrypt
+	tryAllSecrets bool
+	minKeyBits    int
+	allowExpired  bool
+	// required recipients can't be removed, unless dropRequired is set
+	required     []string
+	dropRequired bool
+	// an age encrypted copy of every secret is kept for the ageRecipients,
+	// which is decrypted with ageIdentity if gpg fails
+	ageRecipients []string
+	ageIdentity   string
+	// names of new secrets must match nameSchema, unless allowAnyName is set
+	nameSchema   *regexp.Regexp
+	allowAnyName bool
+	// fileMode is enforced on every file written, whatever the umask
+	fileMode os.FileMode
+	// pins are the key pins of the recipients, kept in the config
+	pins Pins
+	// recipientGen is incremented whenever a recipients file is written
+	recipientGen uint32
+	importFunc   ImportCallback
+	fsckFunc     FsckCallback
+	passFunc     PassphraseCallback
+	keyPassFunc  KeyPassphraseCallback
+	lock         storeLock
 }
 
 // NewStore creates a new store, copying settings from the given root store
@@ -62,29 +123,80 @@ func NewStore(alias, path string, r *RootStore) (*Store, error) {
 	if path == "" {
 		return nil, fmt.Errorf("Need path")
 	}
+	nameSchema, err := compileNameSchema(r.NameSchema)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := ParseFileMode(r.FileMode)
+	if err != nil {
+		return nil, err
+	}
 	s := &Store{
-		alias:       alias,
-		path:        path,
-		autoPush:    r.AutoPush,
-		autoPull:    r.AutoPull,
-		autoImport:  r.AutoImport,
-		persistKeys: r.PersistKeys,
-		loadKeys:    r.LoadKeys,
-		alwaysTrust: r.AlwaysTrust,
-		importFunc:  r.ImportFunc,
-		fsckFunc:    r.FsckFunc,
-		recipients:  make([]string, 0, 5),
+		alias:         alias,
+		path:          path,
+		autoPush:      r.AutoPush,
+		autoPull:      r.AutoPull,
+		autoImport:    r.AutoImport,
+		persistKeys:   r.PersistKeys,
+		loadKeys:      r.LoadKeys,
+		alwaysTrust:   r.AlwaysTrust,
+		signCommits:   r.SignCommits,
+		signKey:       r.SignKey,
+		signStrict:    r.SignStrict,
+		cipherAlgo:    r.CipherAlgo,
+		digestAlgo:    r.DigestAlgo,
+		compressAlgo:  r.CompressAlgo,
+		autoCompress:  r.AutoDisableCompression,
+		noCompress:    r.NoCompress,
+		useLoopback:   r.UseLoopbackPinentry,
+		passRetries:   r.PassRetries,
+		tryAllSecrets: r.TryAllSecrets,
+		minKeyBits:    r.MinKeyBits,
+		allowExpired:  r.AllowExpired,
+		dropRequired:  r.DropRequired,
+		allowAnyName:  r.AllowAnyName,
+		ageIdentity:   r.AgeIdentity,
+		importFunc:    r.ImportFunc,
+		fsckFunc:      r.FsckFunc,
+		passFunc:      r.passFunc,
+		keyPassFunc:   r.keyPassFunc,
+		recipients:    make([]string, 0, 5),
+		pins:          r.KeyPins[alias],
+		nameSchema:    nameSchema,
+		fileMode:      mode,
+	}
+	if s.passRetries < 1 {
+		s.passRetries = defaultPassRetries
+	}
+
+	if err := s.loadRecipientFiles(); err != nil {
+		return nil, err
 	}
+	return s, nil
+}
 
+// loadRecipientFiles loads the recipients, required recipients and age
+// recipients of the store from its files
+func (s *Store) loadRecipientFiles() error {
 	// only try to load recipients if the store / recipients file exist
 	if fsutil.IsFile(s.idFile()) {
 		keys, err := s.loadRecipients()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		s.recipients = keys
 	}
-	return s, nil
+	required, err := s.loadRequired()
+	if err != nil {
+		return err
+	}
+	s.required = required
+	ageRecipients, err := s.loadAgeRecipients()
+	if err != nil {
+		return err
+	}
+	s.ageRecipients = ageRecipients
+	return nil
 }
 
 // Initialized returns true if the store is properly initialized
@@ -94,6 +206,12 @@ func (s *Store) Initialized() bool {
 
 // Init tries to initalize a new password store location matching the object
 func (s *Store) Init(ids ...string) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if s.Initialized() {
 		return fmt.Errorf("Store is already initialized")
 	}
@@ -138,54 +256,9 @@ func (s *Store) idFile() string {
 	return fsutil.CleanPath(filepath.Join(s.path, gpgID))
 }
 
-// mkStoreWalkerFunc create a func to walk a (sub)store, i.e. list it's content
-func mkStoreWalkerFunc(alias, folder string, fn func(...string)) func(string, os.FileInfo, error) error {
-	return func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() && strings.HasPrefix(info.Name(), ".") && path != folder {
-			return filepath.SkipDir
-		}
-		if info.IsDir() {
-			return nil
-		}
-		if strings.HasPrefix(info.Name(), ".") {
-			return nil
-		}
-		if path == folder {
-			return nil
-		}
-		if path == filepath.Join(folder, gpgID) {
-			return nil
-		}
-		if info.Mode()&os.ModeSymlink != 0 {
-			return nil
-		}
-		s := strings.TrimPrefix(path, folder+"/")
-		s = strings.TrimSuffix(s, ".gpg")
-		if alias != "" {
-			s = alias + "/" + s
-		}
-		fn(s)
-		return nil
-	}
-}
-
-// List will list all entries in this store
+// List will list all entries in this store, sorted by name
 func (s *Store) List(prefix string) ([]string, error) {
-	lst := make([]string, 0, 10)
-	addFunc := func(in ...string) {
-		for _, s := range in {
-			lst = append(lst, s)
-		}
-	}
-
-	if err := filepath.Walk(s.path, mkStoreWalkerFunc(prefix, s.path, addFunc)); err != nil {
-		return lst, err
-	}
-
-	return lst, nil
+	return listNames(prefix, s.path)
 }
 
 // equals returns true if this store has the same on-disk path as the other
@@ -208,9 +281,17 @@ func (s *Store) Get(name string) ([]byte, error) {
 		return []byte{}, ErrNotFound
 	}
 
-	content, err := gpg.Decrypt(p)
+	if s.isSymmetric(p) {
+		return s.getSymmetric(name, p)
+	}
+
+	content, err := s.decrypt(name, p)
 	if err != nil {
-		return []byte{}, ErrDecrypt
+		// fall back to the age copy, if there is one
+		if content, aerr := s.decryptAge(name); aerr == nil {
+			return content, nil
+		}
+		return []byte{}, decryptError(err)
 	}
 
 	return content, nil
@@ -218,7 +299,16 @@ func (s *Store) Get(name string) ([]byte, error) {
 
 // IsDir returns true if the entry is folder inside the store
 func (s *Store) IsDir(name string) bool {
-	return fsutil.IsDir(filepath.Join(s.path, name))
+	path := filepath.Join(s.path, name)
+	if !fsutil.IsDir(path) {
+		return false
+	}
+	// a folder containing only attachments belongs to the secret of the
+	// same name
+	if s.HasAttachments(name) && fsutil.IsFile(s.passfile(name)) {
+		return hasVisibleEntries(path)
+	}
+	return true
 }
 
 // Exists checks the existence of a single entry
@@ -251,8 +341,15 @@ func (s *Store) SetConfirm(name string, content []byte, cb RecipientCallback) er
 		return fmt.Errorf("a folder named %s already exists", name)
 	}
 
-	recipients := make([]string, len(s.recipients))
-	copy(recipients, s.recipients)
+	if err := s.checkName(name); err != nil {
+		return err
+	}
+
+	if err := checkSize(content); err != nil {
+		return err
+	}
+
+	recipients := s.recipientsFor(name)
 
 	// confirm recipients
 	if cb != nil {
@@ -260,21 +357,67 @@ func (s *Store) SetConfirm(name string, content []byte, cb RecipientCallback) er
 		if err != nil {
 			return err
 		}
-		recipients = newRecipients
+		recipients = s.withRequired(newRecipients)
+	}
+	if err := s.verifyPins(recipients); err != nil {
+		return err
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	opts := s.encryptOpts()
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.withFileMode(p, func() error {
+		return gpg.Encrypt(p, content, recipients, opts)
+	}); err != nil {
+		return encryptError(err)
+	}
+	ageFiles, err := s.encryptAge(name, content)
+	if err != nil {
+		return err
 	}
 
-	if err := gpg.Encrypt(p, content, recipients, s.alwaysTrust); err != nil {
-		return ErrEncrypt
+	if err := s.gitSaveSecret(name, append([]string{p}, ageFiles...)...); err != nil {
+		return err
 	}
 
-	if err := s.gitAdd(p); err != nil {
+	return hooks.Run(hooks.PostInsert, s.hookEnv(map[string]string{
+		"GOPASS_SECRET": name,
+		"GOPASS_FILE":   p,
+	}))
+}
+
+// hookEnv returns the environment passed to hooks run for this store
+func (s *Store) hookEnv(env map[string]string) map[string]string {
+	env["GOPASS_STORE"] = s.path
+	env["GOPASS_MOUNT"] = s.alias
+	return env
+}
+
+// gitSaveSecret adds the files of the given secret to git, commits them and
+// pushes them if auto-push is enabled
+func (s *Store) gitSaveSecret(name string, files ...string) error {
+	return s.gitSave(fmt.Sprintf("Save secret to %s.", name), files...)
+}
+
+// gitSave adds the given files to git, commits them with the given message
+// and pushes them if auto-push is enabled
+func (s *Store) gitSave(msg string, files ...string) error {
+	if err := s.gitAdd(files...); err != nil {
 		if err == ErrGitNotInit {
 			return nil
 		}
 		return err
 	}
 
-	if err := s.gitCommit(fmt.Sprintf("Save secret to %s.", name)); err != nil {
+	if err := s.gitCommit(msg); err != nil {
 		if err == ErrGitNotInit {
 			return nil
 		}
@@ -305,6 +448,12 @@ func (s *Store) SetConfirm(name string, content []byte, cb RecipientCallback) er
 // supported. Each entry has to be decoded and encoded for the destination
 // to make sure it's encrypted for the right set of recipients.
 func (s *Store) Copy(from, to string) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// recursive copy?
 	if s.IsDir(from) {
 		if found, err := s.Exists(to); err != nil || found {
@@ -333,17 +482,27 @@ func (s *Store) Copy(from, to string) error {
 	if err != nil {
 		return err
 	}
+	// the copy must not be readable by more recipients than the original
+	if err := copyOverride(s, from, s, to); err != nil {
+		return err
+	}
 	if err := s.Set(to, content); err != nil {
 		return err
 	}
-	return nil
+	return copyAttachments(s, from, s, to)
 }
 
-// Move will move one entry from one location to another. Cross-store moves are
-// supported. Moving an entry will decode it from the old location, encode it
-// for the destination store with the right set of recipients and remove it
-// from the old location afterwards.
+// Move will move one entry from one location to another. If the store is
+// a git repository the entry is renamed with git mv to preserve its history.
+// Otherwise it will be decoded from the old location, encoded again and
+// removed from the old location afterwards.
 func (s *Store) Move(from, to string) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// recursive move?
 	if s.IsDir(from) {
 		if found, err := s.Exists(to); err != nil || found {
@@ -369,11 +528,17 @@ func (s *Store) Move(from, to string) error {
 		return nil
 	}
 
-	content, err := s.Get(from)
-	if err != nil {
+	if err := s.checkName(to); err != nil {
 		return err
 	}
-	if err := s.Set(to, content); err != nil {
+
+	// inside a single git store we can rename the ciphertext, so that
+	// git log --follow still shows the history of the entry
+	if err := s.gitMove(from, to); err != ErrGitNotInit {
+		return err
+	}
+
+	if err := s.Copy(from, to); err != nil {
 		return err
 	}
 	if err := s.Delete(from); err != nil {
@@ -396,6 +561,12 @@ func (s *Store) Prune(tree string) error {
 // RemoveFunc given. Use nil or os.Remove for the single-file mode and
 // os.RemoveAll for the recursive mode.
 func (s *Store) delete(name string, recurse bool) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	path := s.passfile(name)
 	rf := os.Remove
 	if recurse {
@@ -414,6 +585,32 @@ func (s *Store) delete(name string, recurse bool) error {
 		return fmt.Errorf("Failed to remove secret: %v", err)
 	}
 
+	if !recurse && fsutil.IsFile(s.agefile(name)) {
+		if err := os.Remove(s.agefile(name)); err != nil {
+			return fmt.Errorf("Failed to remove the age copy: %v", err)
+		}
+		if err := s.gitAdd(s.agefile(name)); err != nil && err != ErrGitNotInit {
+			return err
+		}
+	}
+
+	if !recurse {
+		if err := s.dropOverride(name); err != nil {
+			return err
+		}
+	}
+
+	if !recurse && s.HasAttachments(name) {
+		if err := os.RemoveAll(s.attachmentDir(name)); err != nil {
+			return fmt.Errorf("Failed to remove attachments: %v", err)
+		}
+		// only succeeds if the folder held nothing but the attachments
+		_ = os.Remove(filepath.Join(s.path, name))
+		if err := s.gitAdd(s.attachmentDir(name)); err != nil && err != ErrGitNotInit {
+			return err
+		}
+	}
+
 	if err := s.gitAdd(path); err != nil {
 		if err == ErrGitNotInit {
 			return nil
@@ -439,6 +636,32 @@ func (s *Store) delete(name string, recurse bool) error {
 	return nil
 }
 
+// encryptOpts returns the gpg options used to encrypt entries in this store
+func (s *Store) encryptOpts() gpg.EncryptOpts {
+	opts := gpg.EncryptOpts{
+		AlwaysTrust:            s.alwaysTrust,
+		CipherAlgo:             s.cipherAlgo,
+		DigestAlgo:             s.digestAlgo,
+		CompressAlgo:           s.compressAlgo,
+		AutoDisableCompression: s.autoCompress,
+		AllowExpired:           s.allowExpired,
+	}
+	if s.noCompress {
+		// overrides compressalgo and autodisablecompression
+		opts.CompressAlgo = "none"
+	}
+	return opts
+}
+
+// encryptError returns the error to report for a failed encryption. Expired
+// recipient keys are reported as such, everything else as ErrEncrypt.
+func encryptError(err error) error {
+	if _, ok := err.(*gpg.ExpiredKeysError); ok {
+		return err
+	}
+	return ErrEncrypt
+}
+
 // passfile returns the name of gpg file on disk, for the given key/name
 func (s *Store) passfile(name string) string {
 	return fsutil.CleanPath(filepath.Join(s.path, name) + ".gpg")
@@ -460,6 +683,10 @@ func (s *Store) reencrypt() error {
 		return err
 	}
 	for _, e := range entries {
+		// symmetric secrets have no recipients
+		if s.IsSymmetric(e) {
+			continue
+		}
 		content, err := s.Get(e)
 		if err != nil {
 			fmt.Printf("Failed to get current value for %s: %s\n", e, err)
@@ -468,6 +695,9 @@ func (s *Store) reencrypt() error {
 		if err := s.Set(e, content); err != nil {
 			fmt.Printf("Failed to write %s: %s\n", e, err)
 		}
+		if err := s.reencryptAttachments(e); err != nil {
+			fmt.Printf("Failed to re-encrypt attachments of %s: %s\n", e, err)
+		}
 	}
 	return nil
 }
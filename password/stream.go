@@ -0,0 +1,69 @@
+package password
+
+import (
+	"io"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+// GetTo writes the exact plaintext of a single secret to w. Secrets
+// decrypted with the agent's pinentry are streamed from gpg, symmetric ones
+// and those needing the loopback pinentry are decrypted into memory first.
+// Aliases are not resolved.
+func (s *Store) GetTo(name string, w io.Writer) error {
+	p := s.passfile(name)
+
+	if !strings.HasPrefix(p, s.path) {
+		return ErrSneaky
+	}
+
+	if !fsutil.IsFile(p) {
+		return ErrNotFound
+	}
+
+	if s.isSymmetric(p) || s.useLoopback {
+		content, err := s.Get(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}
+
+	sw := &streamWriter{w: w}
+	if err := gpg.DecryptTo(sw, p, s.decryptOpts(p)); err != nil {
+		if sw.used {
+			return err
+		}
+		// fall back to the age copy, if there is one
+		content, aerr := s.decryptAge(name)
+		if aerr != nil {
+			return decryptError(err)
+		}
+		_, err = w.Write(content)
+		return err
+	}
+	return nil
+}
+
+// GetTo writes the exact plaintext of a single secret to w, see
+// Store.GetTo
+func (r *RootStore) GetTo(name string, w io.Writer) error {
+	store := r.getStore(name)
+	return store.GetTo(strings.TrimPrefix(name, store.alias), w)
+}
+
+// streamWriter records if anything was written to w, after which failing
+// over to another way of decrypting would garble the output
+type streamWriter struct {
+	w    io.Writer
+	used bool
+}
+
+// Write implements io.Writer
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	sw.used = true
+	return sw.w.Write(p)
+}
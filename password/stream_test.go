@@ -0,0 +1,28 @@
+package password
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTo(t *testing.T) {
+	s, _, cleanup := newTestStore(t)
+	defer cleanup()
+
+	content := make([]byte, 0, 256*300)
+	for i := 0; i < 300; i++ {
+		for b := 0; b < 256; b++ {
+			content = append(content, byte(b))
+		}
+	}
+	assert.NoError(t, s.Set("certs/key.der", content))
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, s.GetTo("certs/key.der", buf))
+	assert.Equal(t, content, buf.Bytes())
+
+	assert.Equal(t, ErrNotFound, s.GetTo("certs/missing", buf))
+	assert.Equal(t, ErrSneaky, s.GetTo("../../etc/passwd", buf))
+}
@@ -0,0 +1,107 @@
+package password
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+var (
+	// ErrNoPassphrase is returned if a symmetric secret is accessed but
+	// there is no way to ask for its passphrase
+	ErrNoPassphrase = fmt.Errorf("No passphrase available for symmetric secret")
+)
+
+// IsSymmetric returns true if the entry is only encrypted with a passphrase
+func (s *Store) IsSymmetric(name string) bool {
+	p := s.passfile(name)
+	if !strings.HasPrefix(p, s.path) || !fsutil.IsFile(p) {
+		return false
+	}
+	return s.isSymmetric(p)
+}
+
+// isSymmetric returns true if the given file is only encrypted with a
+// passphrase
+func (s *Store) isSymmetric(p string) bool {
+	sym, err := gpg.IsSymmetric(p)
+	if err != nil {
+		return false
+	}
+	return sym
+}
+
+// getSymmetric asks for the passphrase of the given file and decrypts it
+func (s *Store) getSymmetric(name, p string) ([]byte, error) {
+	if s.passFunc == nil {
+		return []byte{}, ErrNoPassphrase
+	}
+	pass, err := s.passFunc(name)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	buf, err := ioutil.ReadFile(p)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	content, err := gpg.DecryptSymmetric(pass, buf)
+	if err != nil {
+		return []byte{}, decryptError(err)
+	}
+
+	return content, nil
+}
+
+// SetSymmetric encrypts one entry with the given passphrase, instead of the
+// recipients of this store, and writes it to disk
+func (s *Store) SetSymmetric(name string, content []byte, pass string) error {
+	p := s.passfile(name)
+
+	if !strings.HasPrefix(p, s.path) {
+		return ErrSneaky
+	}
+
+	if s.IsDir(name) {
+		return fmt.Errorf("a folder named %s already exists", name)
+	}
+
+	if err := s.checkName(name); err != nil {
+		return err
+	}
+
+	if pass == "" {
+		return fmt.Errorf("passphrase must not be empty")
+	}
+
+	buf, err := gpg.EncryptSymmetric(pass, content)
+	if err != nil {
+		return ErrEncrypt
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.MkdirAll(filepath.Dir(p), dirMode); err != nil {
+		return err
+	}
+	if err := s.writeFile(p, buf); err != nil {
+		return err
+	}
+	// age recipients can't read symmetric secrets
+	ageFiles, err := s.encryptAge(name, nil)
+	if err != nil {
+		return err
+	}
+
+	return s.gitSaveSecret(name, append([]string{p}, ageFiles...)...)
+}
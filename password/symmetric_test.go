@@ -0,0 +1,34 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMixedSymmetricStore(t *testing.T) {
+	s, _, cleanup := newTestStore(t)
+	defer cleanup()
+
+	assert.NoError(t, s.Set("asym", []byte("foo")))
+	assert.NoError(t, s.SetSymmetric("sym", []byte("bar"), "passphrase"))
+
+	lst, err := s.List("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"asym", "sym"}, lst)
+
+	assert.False(t, s.IsSymmetric("asym"))
+	assert.True(t, s.IsSymmetric("sym"))
+
+	content, err := s.Get("asym")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(content))
+
+	_, err = s.Get("sym")
+	assert.Equal(t, ErrNoPassphrase, err)
+
+	s.passFunc = func(string) (string, error) { return "passphrase", nil }
+	content, err = s.Get("sym")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", string(content))
+}
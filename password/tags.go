@@ -0,0 +1,123 @@
+package password
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// tagsKey prefixes the line in a secret's body holding its tags, e.g.
+	// `tags: work, banking`
+	tagsKey = "tags"
+)
+
+// ParseTags returns the sorted, distinct tags of a secret. Tags are
+// separated by commas or spaces, a leading # is dropped and case is
+// ignored. The first line is the password and never holds tags.
+func ParseTags(content []byte) []string {
+	_, body := SplitSecret(content)
+	tags := make([]string, 0, 5)
+	for _, line := range strings.Split(string(body), "\n") {
+		if k, v, ok := parseField(line); ok && k == tagsKey {
+			tags = append(tags, splitTags(v)...)
+		}
+	}
+	return normalizeTags(tags)
+}
+
+// SetTags returns content with its tags replaced by the given ones. Without
+// any tags the tags line is removed.
+func SetTags(content []byte, tags []string) []byte {
+	tags = normalizeTags(tags)
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	out := make([]string, 0, len(lines)+1)
+	out = append(out, lines[0])
+	for _, line := range lines[1:] {
+		if k, _, ok := parseField(line); ok && k == tagsKey {
+			continue
+		}
+		out = append(out, line)
+	}
+	if len(tags) > 0 {
+		out = append(out, tagsKey+": "+strings.Join(tags, ", "))
+	}
+	if len(out) == 1 {
+		return []byte(out[0])
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// AddTags returns content with the given tags added to its existing ones
+func AddTags(content []byte, tags []string) []byte {
+	return SetTags(content, append(ParseTags(content), tags...))
+}
+
+// HasTag returns true if the secret is tagged with the given tag
+func HasTag(content []byte, tag string) bool {
+	return containsTag(ParseTags(content), tag)
+}
+
+// splitTags splits a list of tags separated by commas or spaces
+func splitTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// normalizeTags returns the sorted, distinct, lowercase tags without a
+// leading #
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		for _, t := range splitTags(t) {
+			t = strings.ToLower(strings.TrimLeft(t, "#"))
+			if t == "" {
+				continue
+			}
+			if _, found := seen[t]; found {
+				continue
+			}
+			seen[t] = struct{}{}
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// containsTag returns true if the normalized tags hold the given tag
+func containsTag(tags []string, tag string) bool {
+	tag = strings.ToLower(strings.TrimLeft(strings.TrimSpace(tag), "#"))
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ListByTag returns the sorted names of the secrets tagged with the given
+// tag. If the store has a search index the tags are read from it, so only
+// the secrets changed since it was written and those that aren't indexed
+// are decrypted. Otherwise every secret is decrypted, in parallel.
+// Passphrase-only secrets are skipped.
+func (s *Store) ListByTag(tag string) ([]string, error) {
+	return s.findSecrets(func(e indexEntry) bool {
+		return containsTag(e.Tags, tag)
+	}, func(content []byte) bool {
+		return HasTag(content, tag)
+	})
+}
+
+// ListByTag returns the names of the secrets in this store and all
+// substores tagged with the given tag
+func (r *RootStore) ListByTag(tag string) ([]string, error) {
+	tagged, err := r.collectNames(func(s *Store) ([]string, error) {
+		return s.ListByTag(tag)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tagged, nil
+}
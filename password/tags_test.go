@@ -0,0 +1,74 @@
+package password
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"banking", "work"}, ParseTags([]byte("pass\ntags: work, #Banking work\n")))
+	assert.Equal(t, []string{"a", "b"}, ParseTags([]byte("pass\ntags: a\nuser: foo\ntags: b\n")))
+	// the password is never parsed
+	assert.Len(t, ParseTags([]byte("tags: work\nuser: foo\n")), 0)
+	assert.Len(t, ParseTags([]byte("pass\ntags:\n")), 0)
+
+	assert.True(t, HasTag([]byte("pass\ntags: work\n"), "#Work"))
+	assert.False(t, HasTag([]byte("pass\ntags: work\n"), "wor"))
+}
+
+func TestSetTags(t *testing.T) {
+	assert.Equal(t, "pass\nuser: foo\ntags: a, b\n", string(SetTags([]byte("pass\ntags: c\nuser: foo\n"), []string{"b", "a"})))
+	assert.Equal(t, "pass\ntags: work\n", string(SetTags([]byte("pass"), []string{"work"})))
+	assert.Equal(t, "pass\nuser: foo\n", string(SetTags([]byte("pass\ntags: c\nuser: foo\n"), nil)))
+	assert.Equal(t, "pass", string(SetTags([]byte("pass\ntags: c\n"), nil)))
+	assert.Equal(t, "pass\ntags: a, b, c\n", string(AddTags([]byte("pass\ntags: c, a\n"), []string{"B,a"})))
+}
+
+func TestListByTag(t *testing.T) {
+	s, fpr, cleanup := newTestStore(t)
+	defer cleanup()
+
+	assert.NoError(t, s.Set("web/golang", []byte("pass\ntags: work, oss\n")))
+	assert.NoError(t, s.Set("web/bank", []byte("pass\ntags: banking\n")))
+	assert.NoError(t, s.Set("web/mail", []byte("pass\nuser: work\n")))
+
+	// without an index every secret is decrypted
+	tagged, err := s.ListByTag("work")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/golang"}, tagged)
+
+	// with an index the tags are read from it
+	assert.NoError(t, s.BuildIndex())
+	idx, err := s.loadIndex()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"oss", "work"}, idx.Entries["web/golang"].Tags)
+	assert.NoError(t, s.Set("web/mail", []byte("pass\ntags: work\n")))
+	tagged, err = s.ListByTag("WORK")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/golang", "web/mail"}, tagged)
+
+	// secrets which aren't indexed are decrypted
+	assert.NoError(t, s.SetRecipientOverride("web/bank", []string{fpr}))
+	tagged, err = s.ListByTag("banking")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/bank"}, tagged)
+
+	// indexes written before entries had tags are rebuilt
+	digest, err := fileDigest(s.passfile("web/golang"))
+	assert.NoError(t, err)
+	buf, err := json.Marshal(map[string]interface{}{
+		"entries": map[string]indexEntry{"web/golang": {Digest: digest}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, gpg.Encrypt(s.indexPath(), buf, []string{fpr}, s.encryptOpts()))
+	tagged, err = s.ListByTag("oss")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web/golang"}, tagged)
+	idx, err = s.loadIndex()
+	assert.NoError(t, err)
+	assert.Equal(t, indexVersion, idx.Version)
+	assert.Len(t, idx.Entries, 2)
+}
@@ -0,0 +1,124 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/justwatchcom/gopass/fsutil"
+	"github.com/justwatchcom/gopass/log"
+)
+
+const (
+	// templateFile holds the default content of new secrets in its
+	// directory and all directories below it
+	templateFile = ".template"
+)
+
+// TemplateFor returns the template applied to a new secret with the given
+// name. It's the nearest .template file, looking in the directory of the
+// secret first and then walking up to the root of the store.
+func (s *Store) TemplateFor(name string) ([]byte, bool) {
+	dir := path.Dir(strings.Trim(name, "/"))
+	for {
+		fn := fsutil.CleanPath(filepath.Join(s.path, dir, templateFile))
+		if !strings.HasPrefix(fn, s.path) {
+			return nil, false
+		}
+		if fsutil.IsFile(fn) {
+			buf, err := ioutil.ReadFile(fn)
+			if err != nil {
+				log.Debugf("template: failed to read %s: %s", fn, err)
+				return nil, false
+			}
+			log.Debugf("template: %s uses %s", name, fn)
+			return buf, true
+		}
+		if dir == "." || dir == "/" {
+			return nil, false
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// TemplateFor returns the template applied to a new secret with the given
+// name, see Store.TemplateFor
+func (r *RootStore) TemplateFor(name string) ([]byte, bool) {
+	store := r.getStore(name)
+	return store.TemplateFor(strings.TrimPrefix(name, store.alias))
+}
+
+// templateData are the placeholders available in templates, e.g.
+// `url: https://{{ .Base }}`
+type templateData struct {
+	// Name is the full name of the secret, Dir its directory and Base the
+	// last part of it
+	Name string
+	Dir  string
+	Base string
+	// Password is the password of the new secret
+	Password string
+}
+
+// ApplyTemplate expands the placeholders of the template for the secret
+// with the given name and merges it with the content. The password of the
+// content is used unless it's empty, then the first line of the template
+// is. The body of the content comes first, followed by the lines of the
+// template body that aren't in it already. A key: value line of the
+// template is left out if the content has the same key.
+func ApplyTemplate(tpl []byte, name string, content []byte) ([]byte, error) {
+	pw, body := SplitSecret(content)
+	t, err := template.New(name).Option("missingkey=error").Parse(string(tpl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %s", err)
+	}
+	name = strings.Trim(name, "/")
+	data := templateData{
+		Name:     name,
+		Dir:      path.Dir(name),
+		Base:     path.Base(name),
+		Password: string(pw),
+	}
+	expanded := &bytes.Buffer{}
+	if err := t.Execute(expanded, data); err != nil {
+		return nil, fmt.Errorf("failed to expand template: %s", err)
+	}
+
+	tplPw, tplBody := SplitSecret(expanded.Bytes())
+	if len(bytes.TrimSpace(pw)) < 1 {
+		pw = tplPw
+	}
+	out := append([]byte{}, pw...)
+	out = append(out, '\n')
+	out = append(out, body...)
+	if len(body) > 0 && !bytes.HasSuffix(body, []byte("\n")) {
+		out = append(out, '\n')
+	}
+
+	keys := make(map[string]bool, 5)
+	lines := make(map[string]bool, 5)
+	for _, line := range strings.Split(string(body), "\n") {
+		lines[line] = true
+		if k, _, ok := parseField(line); ok {
+			keys[k] = true
+		}
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(tplBody), "\n"), "\n") {
+		if k, _, ok := parseField(line); ok && keys[k] {
+			continue
+		}
+		if line != "" && lines[line] {
+			continue
+		}
+		out = append(out, line+"\n"...)
+	}
+	out = bytes.TrimRight(out, "\n")
+	if bytes.IndexByte(out, '\n') >= 0 {
+		out = append(out, '\n')
+	}
+	return out, nil
+}
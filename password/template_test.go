@@ -0,0 +1,81 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateFor(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	for fn, content := range map[string]string{
+		templateFile:                          "root\n",
+		"websites/" + templateFile:            "web\n",
+		"websites/internal/" + templateFile:   "internal\n",
+		"websites/internal/deep/other.gpg-id": "",
+		"ssh/" + templateFile:                 "ssh\n",
+	} {
+		fn = filepath.Join(tempdir, fn)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fn), 0700))
+		assert.NoError(t, ioutil.WriteFile(fn, []byte(content), 0600))
+	}
+	s := &Store{path: tempdir}
+
+	for name, want := range map[string]string{
+		"toplevel":                        "root\n",
+		"misc/foo":                        "root\n",
+		"websites/example.org":            "web\n",
+		"websites/shop/example.org":       "web\n",
+		"websites/internal/wiki":          "internal\n",
+		"websites/internal/deep/down/git": "internal\n",
+		"/ssh/host":                       "ssh\n",
+	} {
+		tpl, ok := s.TemplateFor(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, string(tpl), name)
+	}
+
+	// without a template at the root only the nested ones apply
+	assert.NoError(t, os.Remove(filepath.Join(tempdir, templateFile)))
+	_, ok := s.TemplateFor("misc/foo")
+	assert.False(t, ok)
+	_, ok = s.TemplateFor("../outside/foo")
+	assert.False(t, ok)
+}
+
+func TestApplyTemplate(t *testing.T) {
+	tpl := []byte("changeme\nurl: https://{{ .Base }}\nuser: {{ .Dir }}-admin\nnotes:\n")
+
+	out, err := ApplyTemplate(tpl, "websites/example.org", []byte("hunter2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2\nurl: https://example.org\nuser: websites-admin\nnotes:\n", string(out))
+
+	// the content takes precedence over the keys of the template
+	out, err = ApplyTemplate(tpl, "websites/example.org", []byte("hunter2\nuser: gopher\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2\nuser: gopher\nurl: https://example.org\nnotes:\n", string(out))
+
+	// without a password the one of the template is used
+	out, err = ApplyTemplate(tpl, "websites/example.org", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "changeme\nurl: https://example.org\nuser: websites-admin\nnotes:\n", string(out))
+
+	// the password is available as a placeholder
+	out, err = ApplyTemplate([]byte("{{ .Password }}\npin: {{ .Password }}\n"), "sim/card", []byte("1234"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1234\npin: 1234\n", string(out))
+
+	_, err = ApplyTemplate([]byte("{{ .Unknown }}"), "foo", []byte("bar"))
+	assert.Error(t, err)
+	_, err = ApplyTemplate([]byte("{{ .Name"), "foo", []byte("bar"))
+	assert.Error(t, err)
+}
@@ -1,6 +1,41 @@
 package password
 
-import "sort"
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// forEachParallel calls fn for every name with one worker per CPU and
+// returns once all calls returned. The calls run concurrently, fn has to
+// guard any state it shares.
+func forEachParallel(names []string, fn func(name string)) {
+	forEachIndex(len(names), runtime.NumCPU(), func(i int) {
+		fn(names[i])
+	})
+}
+
+// forEachIndex calls fn for every index from 0 to n-1 with the given number
+// of workers, see forEachParallel
+func forEachIndex(n, workers int, fn func(i int)) {
+	jobs := make(chan int, n)
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
 
 // mapToSlice is convenience function converting a set to a sorted slice
 func mapToSlice(m map[string]struct{}) []string {
@@ -11,3 +46,20 @@ func mapToSlice(m map[string]struct{}) []string {
 	sort.Strings(s)
 	return s
 }
+
+// sameRecipients returns true if both lists hold the same recipients, in any
+// order
+func sameRecipients(a, b []string) bool {
+	m := make(map[string]struct{}, len(a))
+	for _, id := range a {
+		m[id] = struct{}{}
+	}
+	n := make(map[string]struct{}, len(b))
+	for _, id := range b {
+		if _, found := m[id]; !found {
+			return false
+		}
+		n[id] = struct{}{}
+	}
+	return len(m) == len(n)
+}
@@ -0,0 +1,64 @@
+package password
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// VerifyDecryptable tries to decrypt every secret of this store with the
+// keys of the user and returns the names of those that failed. The plaintext
+// is discarded right away. Passphrase-only secrets are skipped.
+func (s *Store) VerifyDecryptable() ([]string, error) {
+	return s.VerifyDecryptableContext(context.Background())
+}
+
+// VerifyDecryptableContext is like VerifyDecryptable but stops as soon as
+// ctx is done, returning the secrets found undecryptable so far. The secrets
+// are decrypted in parallel.
+func (s *Store) VerifyDecryptableContext(ctx context.Context) ([]string, error) {
+	names, err := s.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	var mutex sync.Mutex
+	failed := make([]string, 0, 10)
+	forEachParallel(names, func(name string) {
+		// the remaining names are drained without decrypting them
+		if ctx.Err() != nil {
+			return
+		}
+		// asking for the passphrase of every symmetric secret is not an
+		// option
+		if s.IsSymmetric(name) {
+			return
+		}
+		if err := s.GetTo(name, ioutil.Discard); err != nil {
+			log.Debugf("verify: failed to decrypt %s: %s", name, err)
+			mutex.Lock()
+			failed = append(failed, name)
+			mutex.Unlock()
+		}
+	})
+
+	sort.Strings(failed)
+	return failed, ctx.Err()
+}
+
+// VerifyDecryptable tries to decrypt every secret of all stores, see
+// Store.VerifyDecryptable
+func (r *RootStore) VerifyDecryptable() ([]string, error) {
+	return r.VerifyDecryptableContext(context.Background())
+}
+
+// VerifyDecryptableContext is like VerifyDecryptable but stops as soon as
+// ctx is done
+func (r *RootStore) VerifyDecryptableContext(ctx context.Context) ([]string, error) {
+	return r.collectNames(func(s *Store) ([]string, error) {
+		return s.VerifyDecryptableContext(ctx)
+	})
+}
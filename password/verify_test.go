@@ -0,0 +1,54 @@
+package password
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyDecryptable(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	if err := gpg.GenerateKey("gopass external", "external@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate external key: %s", err)
+	}
+	kl, err := gpg.ListPublicKeys("external@gopass.pw")
+	if err != nil || len(kl) < 1 {
+		t.Fatalf("Failed to list external key: %s", err)
+	}
+	external := kl[0].Fingerprint
+
+	tempdir, cleanupDir := newTestDir(t, fpr)
+	defer cleanupDir()
+
+	s, err := NewStore("", tempdir, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set("foo/bar", []byte("secret")))
+	assert.NoError(t, s.Set("foo/baz", []byte("secret")))
+	assert.NoError(t, s.SetRecipientOverride("foo/baz", []string{external}))
+
+	failed, err := s.VerifyDecryptable()
+	assert.NoError(t, err)
+	assert.Len(t, failed, 0)
+
+	// without the external secret key foo/baz can not be decrypted
+	out, err := exec.Command(gpg.GPGBin, "--batch", "--yes", "--delete-secret-keys", external).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to delete external secret key: %s: %s", err, out)
+	}
+	failed, err = s.VerifyDecryptable()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo/baz"}, failed)
+
+	// nothing is checked once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	failed, err = s.VerifyDecryptableContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+	assert.Len(t, failed, 0)
+}
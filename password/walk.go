@@ -0,0 +1,73 @@
+package password
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dirEntry is an entry of a folder as needed to list the secrets of a store
+type dirEntry struct {
+	name    string
+	dir     bool
+	symlink bool
+}
+
+// listNames returns the sorted names of all secrets below folder, prefixed
+// with alias. Folders and files starting with a dot, recipient overrides and
+// symlinks are skipped. Only the folders are read, the secrets aren't
+// opened or stat'ed where the platform avoids it, see readEntries.
+func listNames(alias, folder string) ([]string, error) {
+	prefix := ""
+	if alias != "" {
+		prefix = alias + "/"
+	}
+	lst := make([]string, 0, 10)
+	if err := walkNames(folder, prefix, &lst); err != nil {
+		return lst, err
+	}
+	sort.Strings(lst)
+	return lst, nil
+}
+
+// IsBelow returns true if the secret is the prefix or below it, i.e. in the
+// folder of that name. Every secret is below the empty prefix.
+func IsBelow(name, prefix string) bool {
+	prefix = strings.Trim(prefix, "/")
+	return prefix == "" || name == prefix || strings.HasPrefix(name, prefix+"/")
+}
+
+// NamesBelow returns the names of the secrets below the prefix, see IsBelow
+func NamesBelow(names []string, prefix string) []string {
+	below := make([]string, 0, len(names))
+	for _, name := range names {
+		if IsBelow(name, prefix) {
+			below = append(below, name)
+		}
+	}
+	return below
+}
+
+// walkNames adds the secrets of dir to lst, recursing into sub folders
+func walkNames(dir, prefix string, lst *[]string) error {
+	entries, err := readEntries(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.name, ".") || e.symlink {
+			continue
+		}
+		if e.dir {
+			if err := walkNames(filepath.Join(dir, e.name), prefix+e.name+"/", lst); err != nil {
+				return err
+			}
+			continue
+		}
+		if !strings.HasSuffix(e.name, ".gpg") {
+			continue
+		}
+		*lst = append(*lst, prefix+strings.TrimSuffix(e.name, ".gpg"))
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build !go1.16
+// +build !go1.16
+
+package password
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// readEntries returns the entries of a folder. Before Go 1.16 every entry has to
+// be stat'ed.
+func readEntries(dir string) ([]dirEntry, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]dirEntry, 0, len(fis))
+	for _, fi := range fis {
+		entries = append(entries, dirEntry{
+			name:    fi.Name(),
+			dir:     fi.IsDir(),
+			symlink: fi.Mode()&os.ModeSymlink != 0,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,24 @@
+//go:build go1.16
+// +build go1.16
+
+package password
+
+import "os"
+
+// readEntries returns the entries of a folder. os.ReadDir takes the type of the
+// entries from the folder itself, without stat'ing each of them.
+func readEntries(dir string) ([]dirEntry, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]dirEntry, 0, len(des))
+	for _, de := range des {
+		entries = append(entries, dirEntry{
+			name:    de.Name(),
+			dir:     de.IsDir(),
+			symlink: de.Type()&os.ModeSymlink != 0,
+		})
+	}
+	return entries, nil
+}
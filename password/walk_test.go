@@ -0,0 +1,124 @@
+package password
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// walkNamesStat lists the secrets with filepath.Walk, which stats every
+// entry. It's what List did before listNames and serves as a reference.
+func walkNamesStat(alias, folder string) ([]string, error) {
+	lst := make([]string, 0, 10)
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && strings.HasPrefix(info.Name(), ".") && path != folder {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") || !strings.HasSuffix(path, ".gpg") {
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		s := strings.TrimSuffix(strings.TrimPrefix(path, folder+"/"), ".gpg")
+		if alias != "" {
+			s = alias + "/" + s
+		}
+		lst = append(lst, s)
+		return nil
+	})
+	sort.Strings(lst)
+	return lst, err
+}
+
+// createSecretTree creates a store with n empty secrets in nested folders
+func createSecretTree(tb testing.TB, n int) string {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		tb.Fatalf("Failed to create tempdir: %s", err)
+	}
+	for i := 0; i < n; i++ {
+		fn := filepath.Join(tempdir, fmt.Sprintf("team%d/service%d/user%d.gpg", i%10, i%100, i))
+		if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+			tb.Fatalf("Failed to create folder: %s", err)
+		}
+		if err := ioutil.WriteFile(fn, []byte{}, 0600); err != nil {
+			tb.Fatalf("Failed to create secret: %s", err)
+		}
+	}
+	return tempdir
+}
+
+func TestListNames(t *testing.T) {
+	tempdir := createSecretTree(t, 50)
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	for fn, content := range map[string]string{
+		gpgID:                             "0xDEADBEEF",
+		"team1/service1" + overrideSuffix: "0xDEADBEEF",
+		".git/objects/foo.gpg":            "",
+		"team2/notes.txt":                 "",
+		"team3/.hidden.gpg":               "",
+	} {
+		p := filepath.Join(tempdir, fn)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0700))
+		assert.NoError(t, ioutil.WriteFile(p, []byte(content), 0600))
+	}
+	assert.NoError(t, os.Symlink(filepath.Join(tempdir, "team0/service0/user0.gpg"), filepath.Join(tempdir, "link.gpg")))
+
+	lst, err := listNames("sub", tempdir)
+	assert.NoError(t, err)
+	assert.Len(t, lst, 50)
+	assert.True(t, sort.StringsAreSorted(lst))
+	assert.Equal(t, "sub/team0/service0/user0", lst[0])
+
+	want, err := walkNamesStat("sub", tempdir)
+	assert.NoError(t, err)
+	assert.Equal(t, want, lst)
+}
+
+func TestNamesBelow(t *testing.T) {
+	names := []string{"foo", "foo/bar", "foobar", "foo/bar/baz", "zab"}
+	assert.Equal(t, names, NamesBelow(names, ""))
+	assert.Equal(t, []string{"foo", "foo/bar", "foo/bar/baz"}, NamesBelow(names, "foo"))
+	assert.Equal(t, []string{"foo/bar", "foo/bar/baz"}, NamesBelow(names, "/foo/bar/"))
+	assert.Empty(t, NamesBelow(names, "fo"))
+	assert.True(t, IsBelow("zab", "/"))
+}
+
+func BenchmarkListNamesWalk(b *testing.B) {
+	tempdir := createSecretTree(b, 10000)
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := walkNamesStat("", tempdir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListNames(b *testing.B) {
+	tempdir := createSecretTree(b, 10000)
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := listNames("", tempdir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/justwatchcom/gopass/log"
+)
+
+// Prefix is prepended to the name of a subcommand to find its plugin, like
+// git does for git-<name>
+const Prefix = "gopass-"
+
+// Resolve returns the path of the executable named gopass-<name> on the
+// PATH, if there is one. Names are made of letters, digits, - and _, so they
+// can't point anywhere else.
+func Resolve(name string) (string, bool) {
+	if !validName(name) {
+		return "", false
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return "", false
+	}
+	log.Debugf("plugin: %s is provided by %s", name, path)
+	return path, true
+}
+
+// Run runs the plugin with the given arguments, connected to the terminal.
+// It receives everything in env as additional environment variables,
+// replacing those of gopass' own environment. Callers must never put secret
+// contents into env. If the plugin fails the error is an *exec.ExitError.
+func Run(path string, args []string, env map[string]string) error {
+	cmd := exec.Command(path, args...)
+	cmd.Env = environ(os.Environ(), env)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	log.Debugf("plugin: running %s", strings.Join(log.RedactArgs(cmd.Args), " "))
+	return cmd.Run()
+}
+
+// environ returns base with the variables of env set, which are sorted to
+// have a stable order
+func environ(base []string, env map[string]string) []string {
+	out := make([]string, 0, len(base)+len(env))
+	for _, kv := range base {
+		if _, found := env[strings.SplitN(kv, "=", 2)[0]]; !found {
+			out = append(out, kv)
+		}
+	}
+	vars := make([]string, 0, len(env))
+	for k, v := range env {
+		vars = append(vars, k+"="+v)
+	}
+	sort.Strings(vars)
+	return append(out, vars...)
+}
+
+// validName returns true if name can be the name of a plugin
+func validName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
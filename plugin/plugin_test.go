@@ -0,0 +1,45 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	td, err := ioutil.TempDir("", "gopass-")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(td)
+	}()
+
+	oldPath := os.Getenv("PATH")
+	assert.NoError(t, os.Setenv("PATH", td))
+	defer func() {
+		_ = os.Setenv("PATH", oldPath)
+	}()
+
+	fn := filepath.Join(td, Prefix+"demo")
+	assert.NoError(t, ioutil.WriteFile(fn, []byte("#!/bin/sh\nexit 0\n"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(td, Prefix+"noexec"), []byte("#!/bin/sh\n"), 0644))
+
+	path, ok := Resolve("demo")
+	assert.True(t, ok)
+	assert.Equal(t, fn, path)
+
+	for _, name := range []string{"", "missing", "noexec", "../demo", "-demo", "de mo"} {
+		_, ok := Resolve(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	env := environ([]string{"HOME=/home/gopher", "GOPASS_STORE=/old", "PATH=/bin"}, map[string]string{
+		"GOPASS_STORE": "/store",
+		"GOPASS_KEY":   "BE73F104",
+	})
+	assert.Equal(t, []string{"HOME=/home/gopher", "PATH=/bin", "GOPASS_KEY=BE73F104", "GOPASS_STORE=/store"}, env)
+}
@@ -0,0 +1,141 @@
+package profile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/justwatchcom/gopass/fsutil"
+)
+
+const (
+	// EnvProfile selects the active profile, unless overridden by --profile
+	EnvProfile = "GOPASS_PROFILE"
+	// EnvFile overrides the location of the profiles file
+	EnvFile = "GOPASS_PROFILES"
+)
+
+// Profile bundles the settings of one identity, e.g. work or personal. Empty
+// settings keep the defaults.
+type Profile struct {
+	// GnupgHome is the gpg home directory holding the keyrings
+	GnupgHome string `json:"gnupghome,omitempty"`
+	// Key is used for signing and offered for encryption when a store is
+	// initialized
+	Key string `json:"key,omitempty"`
+	// Path is the root of the password store
+	Path string `json:"path,omitempty"`
+	// GitName and GitEmail are used as author and committer of commits
+	GitName  string `json:"gitname,omitempty"`
+	GitEmail string `json:"gitemail,omitempty"`
+}
+
+// Config holds all profiles and the name of the one used by default
+type Config struct {
+	Default  string             `json:"default,omitempty"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// File returns the location of the profiles file. Either reading from
+// GOPASS_PROFILES or using the default location (~/.gopass/profiles.yml)
+func File() string {
+	if fn := os.Getenv(EnvFile); fn != "" {
+		return fsutil.CleanPath(fn)
+	}
+	return filepath.Join(os.Getenv("HOME"), ".gopass", "profiles.yml")
+}
+
+// Load reads the profiles from the given file. A missing file holds no
+// profiles.
+func Load(fn string) (*Config, error) {
+	cfg := &Config{Profiles: make(map[string]Profile)}
+	buf, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(buf, cfg); err != nil {
+		return nil, fmt.Errorf("failed to read profiles from %s: %s", fn, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]Profile)
+	}
+	return cfg, nil
+}
+
+// Save writes the profiles to the given file
+func (c *Config) Save(fn string) error {
+	buf, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, buf, 0600)
+}
+
+// Names returns the sorted names of all profiles
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select returns the name of the active profile. The flag takes precedence
+// over the environment, which takes precedence over the default.
+func Select(flag, env, def string) string {
+	if flag != "" {
+		return flag
+	}
+	if env != "" {
+		return env
+	}
+	return def
+}
+
+// Active returns the name and settings of the profile selected by the given
+// flag, GOPASS_PROFILE or the default. Without any profile selected the
+// empty name and profile are returned. Selecting a profile that doesn't
+// exist is an error.
+func (c *Config) Active(flag string) (string, Profile, error) {
+	name := Select(flag, os.Getenv(EnvProfile), c.Default)
+	if name == "" {
+		return "", Profile{}, nil
+	}
+	p, found := c.Profiles[name]
+	if !found {
+		return name, p, fmt.Errorf("profile %s does not exist", name)
+	}
+	return name, p, nil
+}
+
+// Apply sets up the environment of gpg and git for this profile
+func (p Profile) Apply() error {
+	env := make(map[string]string, 5)
+	if p.GnupgHome != "" {
+		env["GNUPGHOME"] = fsutil.CleanPath(p.GnupgHome)
+	}
+	if p.GitName != "" {
+		env["GIT_AUTHOR_NAME"] = p.GitName
+		env["GIT_COMMITTER_NAME"] = p.GitName
+	}
+	if p.GitEmail != "" {
+		env["GIT_AUTHOR_EMAIL"] = p.GitEmail
+		env["GIT_COMMITTER_EMAIL"] = p.GitEmail
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
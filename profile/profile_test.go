@@ -0,0 +1,121 @@
+package profile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	for _, tc := range []struct {
+		flag, env, def string
+		out            string
+	}{
+		{"", "", "", ""},
+		{"", "", "personal", "personal"},
+		{"", "work", "personal", "work"},
+		{"other", "work", "personal", "other"},
+		{"other", "", "personal", "other"},
+		{"other", "", "", "other"},
+	} {
+		assert.Equal(t, tc.out, Select(tc.flag, tc.env, tc.def), "%+v", tc)
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "gopass-")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+	fn := filepath.Join(tempdir, "profiles", "profiles.yml")
+
+	// a missing file holds no profiles
+	cfg, err := Load(fn)
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Profiles, 0)
+
+	cfg.Default = "personal"
+	cfg.Profiles["personal"] = Profile{GnupgHome: "~/.gnupg", Key: "BE73F104"}
+	cfg.Profiles["work"] = Profile{Path: "/srv/work", GitName: "Gopher", GitEmail: "gopher@example.com"}
+	assert.NoError(t, cfg.Save(fn))
+
+	fi, err := os.Stat(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	cfg, err = Load(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "personal", cfg.Default)
+	assert.Equal(t, []string{"personal", "work"}, cfg.Names())
+	assert.Equal(t, "/srv/work", cfg.Profiles["work"].Path)
+
+	assert.NoError(t, ioutil.WriteFile(fn, []byte("profiles: [broken"), 0600))
+	_, err = Load(fn)
+	assert.Error(t, err)
+}
+
+func TestActive(t *testing.T) {
+	cfg := &Config{
+		Default: "personal",
+		Profiles: map[string]Profile{
+			"personal": {Key: "personal"},
+			"work":     {Key: "work"},
+		},
+	}
+	defer func() {
+		_ = os.Unsetenv(EnvProfile)
+	}()
+
+	for _, tc := range []struct {
+		flag, env string
+		name      string
+	}{
+		{"", "", "personal"},
+		{"", "work", "work"},
+		{"personal", "work", "personal"},
+	} {
+		assert.NoError(t, os.Setenv(EnvProfile, tc.env))
+		name, p, err := cfg.Active(tc.flag)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.name, name)
+		assert.Equal(t, tc.name, p.Key)
+	}
+
+	assert.NoError(t, os.Setenv(EnvProfile, "missing"))
+	_, _, err := cfg.Active("")
+	assert.Error(t, err)
+
+	// without a default no profile is used
+	assert.NoError(t, os.Unsetenv(EnvProfile))
+	cfg.Default = ""
+	name, _, err := cfg.Active("")
+	assert.NoError(t, err)
+	assert.Equal(t, "", name)
+}
+
+func TestApply(t *testing.T) {
+	keys := []string{"GNUPGHOME", "GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"}
+	old := make(map[string]string, len(keys))
+	for _, k := range keys {
+		old[k] = os.Getenv(k)
+	}
+	defer func() {
+		for k, v := range old {
+			_ = os.Setenv(k, v)
+		}
+	}()
+
+	p := Profile{GnupgHome: "/tmp/gnupg", GitName: "Gopher", GitEmail: "gopher@example.com"}
+	assert.NoError(t, p.Apply())
+	assert.Equal(t, "/tmp/gnupg", os.Getenv("GNUPGHOME"))
+	assert.Equal(t, "Gopher", os.Getenv("GIT_AUTHOR_NAME"))
+	assert.Equal(t, "Gopher", os.Getenv("GIT_COMMITTER_NAME"))
+	assert.Equal(t, "gopher@example.com", os.Getenv("GIT_AUTHOR_EMAIL"))
+	assert.Equal(t, "gopher@example.com", os.Getenv("GIT_COMMITTER_EMAIL"))
+}
@@ -0,0 +1,78 @@
+package pwgen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// deterministicSalt is prefixed to the path to derive the key. Changing
+	// it changes every deterministic password.
+	deterministicSalt = "gopass deterministic v1\x00"
+	// scrypt parameters, see https://godoc.org/golang.org/x/crypto/scrypt
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// GenerateDeterministic derives a password satisfying the rules from the
+// master seed and the path of the secret. The same seed and path always yield
+// the same password, so it can be recreated without having access to the
+// store. The key is derived with scrypt, the password is generated the same
+// way as GenerateWithRules does, using a stream of bytes expanded from the
+// key instead of crypto/rand.
+func GenerateDeterministic(masterSeed, path string, rules Rules) (string, error) {
+	if masterSeed == "" {
+		return "", fmt.Errorf("master seed must not be empty")
+	}
+	if path == "" {
+		return "", fmt.Errorf("path must not be empty")
+	}
+
+	key, err := scrypt.Key([]byte(masterSeed), []byte(deterministicSalt+path), scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return "", err
+	}
+
+	ks := &keyStream{key: key}
+	return generateWithRules(rules, ks.intn)
+}
+
+// keyStream is an endless stream of pseudo random bytes, produced by
+// HMAC-SHA256 of the key over a counter
+type keyStream struct {
+	key     []byte
+	counter uint64
+	buf     []byte
+}
+
+// uint32 returns the next four bytes of the stream
+func (k *keyStream) uint32() uint32 {
+	if len(k.buf) < 4 {
+		mac := hmac.New(sha256.New, k.key)
+		ctr := make([]byte, 8)
+		binary.BigEndian.PutUint64(ctr, k.counter)
+		_, _ = mac.Write(ctr)
+		k.counter++
+		k.buf = append(k.buf, mac.Sum(nil)...)
+	}
+	v := binary.BigEndian.Uint32(k.buf)
+	k.buf = k.buf[4:]
+	return v
+}
+
+// intn returns a uniformly distributed number in [0, max). Values that would
+// bias the result are rejected.
+func (k *keyStream) intn(max int) int {
+	limit := ^uint32(0) - ^uint32(0)%uint32(max)
+	for {
+		if v := k.uint32(); v < limit {
+			return int(v % uint32(max))
+		}
+	}
+}
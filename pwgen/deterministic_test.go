@@ -0,0 +1,67 @@
+package pwgen
+
+import "testing"
+
+func TestGenerateDeterministic(t *testing.T) {
+	r, err := ParseRules("maxlength: 16; required: lower; required: upper; required: digit; required: [-_]")
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %s", err)
+	}
+
+	pw, err := GenerateDeterministic("correct horse battery staple", "example.com/gopher", r)
+	if err != nil {
+		t.Fatalf("Failed to generate: %s", err)
+	}
+	if err := r.Check(pw); err != nil {
+		t.Errorf("Generated invalid password '%s': %s", pw, err)
+	}
+	if len(pw) != r.Length() {
+		t.Errorf("Length mismatch: %d != %d", len(pw), r.Length())
+	}
+
+	// the same inputs must always give the same password
+	again, err := GenerateDeterministic("correct horse battery staple", "example.com/gopher", r)
+	if err != nil {
+		t.Fatalf("Failed to generate: %s", err)
+	}
+	if pw != again {
+		t.Errorf("Same inputs gave different passwords: '%s' != '%s'", pw, again)
+	}
+
+	// also across versions, otherwise recovering the passwords fails
+	if want := "ujpynr-3L_gJtrBI"; pw != want {
+		t.Errorf("Password changed: '%s' != '%s'", pw, want)
+	}
+
+	for _, in := range [][2]string{
+		{"correct horse battery staple", "example.com/other"},
+		{"correct horse battery stapler", "example.com/gopher"},
+	} {
+		other, err := GenerateDeterministic(in[0], in[1], r)
+		if err != nil {
+			t.Fatalf("Failed to generate: %s", err)
+		}
+		if other == pw {
+			t.Errorf("Different inputs %q gave the same password", in)
+		}
+	}
+
+	if _, err := GenerateDeterministic("", "example.com/gopher", r); err == nil {
+		t.Errorf("Should fail with an empty seed")
+	}
+}
+
+func TestKeyStream(t *testing.T) {
+	ks := &keyStream{key: []byte("key")}
+	seen := make(map[int]bool, 10)
+	for i := 0; i < 1000; i++ {
+		n := ks.intn(10)
+		if n < 0 || n >= 10 {
+			t.Fatalf("Out of range: %d", n)
+		}
+		seen[n] = true
+	}
+	if len(seen) != 10 {
+		t.Errorf("Not all values were generated: %v", seen)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	"fmt"
+	"math"
 	"math/big"
 	"math/rand"
 	"os"
@@ -24,10 +25,7 @@ func init() {
 
 // GeneratePassword generates a random, hard to remember password
 func GeneratePassword(length int, symbols bool) []byte {
-	chars := digits + upper + lower
-	if symbols {
-		chars += syms
-	}
+	chars := charset(symbols)
 	pw := &bytes.Buffer{}
 	for pw.Len() < length {
 		_ = pw.WriteByte(chars[randomInteger(len(chars))])
@@ -36,6 +34,28 @@ func GeneratePassword(length int, symbols bool) []byte {
 	return pw.Bytes()
 }
 
+// Entropy returns the entropy in bits of a password generated by
+// GeneratePassword with the given arguments
+func Entropy(length int, symbols bool) float64 {
+	return entropy(length, len(charset(symbols)))
+}
+
+func charset(symbols bool) string {
+	chars := digits + upper + lower
+	if symbols {
+		chars += syms
+	}
+	return chars
+}
+
+// entropy returns the entropy of length chars picked uniformly from n chars
+func entropy(length, n int) float64 {
+	if length < 1 || n < 2 {
+		return 0
+	}
+	return float64(length) * math.Log2(float64(n))
+}
+
 func randomInteger(max int) int {
 	i, err := crand.Int(crand.Reader, big.NewInt(int64(max)))
 	if err == nil {
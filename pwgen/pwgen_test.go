@@ -12,3 +12,20 @@ func TestPwgen(t *testing.T) {
 		}
 	}
 }
+
+func TestEntropy(t *testing.T) {
+	for _, tc := range []struct {
+		length  int
+		symbols bool
+		bits    int
+	}{
+		{0, true, 0},
+		{10, false, 59},
+		{20, false, 119},
+		{20, true, 131},
+	} {
+		if got := int(Entropy(tc.length, tc.symbols)); got != tc.bits {
+			t.Errorf("Mismatch for %+v: %d != %d", tc, got, tc.bits)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package pwgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultRulesLength is the length of passwords generated with rules
+	// that don't require a different length
+	DefaultRulesLength = 24
+	// maxAttempts limits the number of candidates generated before giving up
+	maxAttempts = 1000
+)
+
+// charClasses are the named character classes of the password rules format
+var charClasses = map[string]string{
+	"upper":           upper,
+	"lower":           lower,
+	"digit":           digits,
+	"special":         syms,
+	"ascii-printable": digits + upper + lower + syms,
+}
+
+// Rules describe the constraints a website imposes on its passwords. The
+// string format follows the password rules proposed by Apple, e.g.
+// "minlength: 8; maxlength: 16; required: lower, upper; required: digit;
+// allowed: [-_]; max-consecutive: 2".
+type Rules struct {
+	MinLength      int      // MinLength is the minimal length, 0 means no limit
+	MaxLength      int      // MaxLength is the maximal length, 0 means no limit
+	Required       []string // Required contains sets of which at least one char must be used each
+	Allowed        []string // Allowed contains additional chars that may be used
+	MaxConsecutive int      // MaxConsecutive limits repeated chars, 0 means no limit
+}
+
+// ParseRules parses a password rules string
+func ParseRules(spec string) (Rules, error) {
+	r := Rules{}
+	for _, rule := range strings.Split(spec, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		p := strings.SplitN(rule, ":", 2)
+		if len(p) != 2 {
+			return r, fmt.Errorf("invalid rule '%s'", rule)
+		}
+		key := strings.ToLower(strings.TrimSpace(p[0]))
+		val := strings.TrimSpace(p[1])
+		switch key {
+		case "minlength", "maxlength", "max-consecutive":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return r, fmt.Errorf("invalid value for %s: '%s'", key, val)
+			}
+			switch key {
+			case "minlength":
+				r.MinLength = n
+			case "maxlength":
+				r.MaxLength = n
+			default:
+				r.MaxConsecutive = n
+			}
+		case "required", "allowed":
+			set, err := parseCharClasses(val)
+			if err != nil {
+				return r, err
+			}
+			if key == "required" {
+				r.Required = append(r.Required, set)
+			} else {
+				r.Allowed = append(r.Allowed, set)
+			}
+		default:
+			return r, fmt.Errorf("unknown rule '%s'", key)
+		}
+	}
+	return r, nil
+}
+
+// parseCharClasses parses a comma separated list of named classes and
+// custom sets in brackets into one set of chars
+func parseCharClasses(val string) (string, error) {
+	set := ""
+	for len(val) > 0 {
+		val = strings.TrimLeft(val, " ,")
+		if val == "" {
+			break
+		}
+		if val[0] == '[' {
+			// a custom set may contain a closing bracket as its first char
+			end := -1
+			if len(val) > 2 {
+				end = strings.Index(val[2:], "]")
+			}
+			if end < 0 {
+				return "", fmt.Errorf("unterminated character set '%s'", val)
+			}
+			end += 2
+			set += val[1:end]
+			val = val[end+1:]
+			continue
+		}
+		name := val
+		if i := strings.Index(val, ","); i >= 0 {
+			name, val = val[:i], val[i:]
+		} else {
+			val = ""
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		chars, found := charClasses[name]
+		if !found {
+			return "", fmt.Errorf("unknown character class '%s'", name)
+		}
+		set += chars
+	}
+	if set == "" {
+		return "", fmt.Errorf("empty character class")
+	}
+	return uniqueChars(set), nil
+}
+
+// String returns the password rules string of these rules
+func (r Rules) String() string {
+	out := make([]string, 0, 3+len(r.Required)+len(r.Allowed))
+	if r.MinLength > 0 {
+		out = append(out, fmt.Sprintf("minlength: %d", r.MinLength))
+	}
+	if r.MaxLength > 0 {
+		out = append(out, fmt.Sprintf("maxlength: %d", r.MaxLength))
+	}
+	for _, set := range r.Required {
+		out = append(out, "required: "+formatCharClasses(set))
+	}
+	for _, set := range r.Allowed {
+		out = append(out, "allowed: "+formatCharClasses(set))
+	}
+	if r.MaxConsecutive > 0 {
+		out = append(out, fmt.Sprintf("max-consecutive: %d", r.MaxConsecutive))
+	}
+	return strings.Join(out, "; ")
+}
+
+// formatCharClasses returns the shortest representation of the given set
+func formatCharClasses(set string) string {
+	for _, name := range []string{"ascii-printable", "upper", "lower", "digit", "special"} {
+		if set == uniqueChars(charClasses[name]) {
+			return name
+		}
+	}
+	return "[" + set + "]"
+}
+
+// Validate checks that passwords satisfying these rules can exist
+func (r Rules) Validate() error {
+	if r.MaxLength > 0 && r.MinLength > r.MaxLength {
+		return fmt.Errorf("minlength %d is bigger than maxlength %d", r.MinLength, r.MaxLength)
+	}
+	if r.MaxLength > 0 && r.MaxLength < len(r.Required) {
+		return fmt.Errorf("maxlength %d is too short for %d required character classes", r.MaxLength, len(r.Required))
+	}
+	for _, set := range append(r.Required, r.Allowed...) {
+		if set == "" {
+			return fmt.Errorf("empty character class")
+		}
+	}
+	return nil
+}
+
+// Length returns the length of passwords generated with these rules
+func (r Rules) Length() int {
+	length := DefaultRulesLength
+	if length < r.MinLength {
+		length = r.MinLength
+	}
+	if length < len(r.Required) {
+		length = len(r.Required)
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		length = r.MaxLength
+	}
+	return length
+}
+
+// Entropy returns an estimate of the entropy in bits of a password of the
+// given length generated for these rules. The required classes reduce the
+// actual entropy slightly, so this is an upper bound.
+func (r Rules) Entropy(length int) float64 {
+	return entropy(length, len(r.chars()))
+}
+
+// chars returns all chars that may be used
+func (r Rules) chars() string {
+	if len(r.Required) < 1 && len(r.Allowed) < 1 {
+		return charClasses["ascii-printable"]
+	}
+	return uniqueChars(strings.Join(r.Required, "") + strings.Join(r.Allowed, ""))
+}
+
+// Check returns an error if the password violates any of the rules
+func (r Rules) Check(pw string) error {
+	if len(pw) < r.MinLength {
+		return fmt.Errorf("password is shorter than %d chars", r.MinLength)
+	}
+	if r.MaxLength > 0 && len(pw) > r.MaxLength {
+		return fmt.Errorf("password is longer than %d chars", r.MaxLength)
+	}
+	chars := r.chars()
+	for _, c := range pw {
+		if !strings.ContainsRune(chars, c) {
+			return fmt.Errorf("password contains forbidden char '%c'", c)
+		}
+	}
+	for _, set := range r.Required {
+		if !strings.ContainsAny(pw, set) {
+			return fmt.Errorf("password contains none of '%s'", set)
+		}
+	}
+	if r.MaxConsecutive > 0 {
+		run := 0
+		for i := range pw {
+			if i > 0 && pw[i] == pw[i-1] {
+				run++
+			} else {
+				run = 1
+			}
+			if run > r.MaxConsecutive {
+				return fmt.Errorf("password repeats '%c' more than %d times", pw[i], r.MaxConsecutive)
+			}
+		}
+	}
+	return nil
+}
+
+// GenerateWithRules generates a random password satisfying the given rules.
+// Each candidate includes one char of each required class at a random
+// position, the remaining chars are picked from all allowed chars. Candidates
+// violating the rules are rejected.
+func GenerateWithRules(rules Rules) (string, error) {
+	return generateWithRules(rules, randomInteger)
+}
+
+// generateWithRules generates a password satisfying the rules, intn must
+// return a number in [0, max)
+func generateWithRules(rules Rules, intn func(max int) int) (string, error) {
+	if err := rules.Validate(); err != nil {
+		return "", err
+	}
+
+	length := rules.Length()
+	chars := rules.chars()
+	if rules.MaxConsecutive > 0 && len(chars) < 2 && length > rules.MaxConsecutive {
+		return "", fmt.Errorf("can not generate %d chars without repeating '%s' more than %d times", length, chars, rules.MaxConsecutive)
+	}
+
+	for i := 0; i < maxAttempts; i++ {
+		cand := rules.candidate(length, chars, intn)
+		if rules.Check(cand) == nil {
+			return cand, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a password satisfying '%s'", rules)
+}
+
+// candidate generates a password that contains one char of each required
+// class and tries to avoid too many consecutive chars
+func (r Rules) candidate(length int, chars string, intn func(max int) int) string {
+	pw := make([]byte, length)
+	fixed := make([]bool, length)
+	for _, set := range r.Required {
+		pos := intn(length)
+		for fixed[pos] {
+			pos = (pos + 1) % length
+		}
+		pw[pos] = set[intn(len(set))]
+		fixed[pos] = true
+	}
+	for i := range pw {
+		if fixed[i] {
+			continue
+		}
+		c := chars[intn(len(chars))]
+		for len(chars) > 1 && r.repeats(pw[:i], c) {
+			c = chars[intn(len(chars))]
+		}
+		pw[i] = c
+	}
+	return string(pw)
+}
+
+// repeats returns true if appending c to pw would exceed MaxConsecutive
+func (r Rules) repeats(pw []byte, c byte) bool {
+	if r.MaxConsecutive < 1 || len(pw) < r.MaxConsecutive {
+		return false
+	}
+	for _, p := range pw[len(pw)-r.MaxConsecutive:] {
+		if p != c {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueChars removes duplicate chars while preserving their order
+func uniqueChars(in string) string {
+	seen := make(map[rune]struct{}, len(in))
+	out := make([]rune, 0, len(in))
+	for _, c := range in {
+		if _, found := seen[c]; found {
+			continue
+		}
+		seen[c] = struct{}{}
+		out = append(out, c)
+	}
+	return string(out)
+}
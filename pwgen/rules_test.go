@@ -0,0 +1,122 @@
+package pwgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	for in, out := range map[string]string{
+		"":                            "",
+		"minlength: 8; maxlength: 16": "minlength: 8; maxlength: 16",
+		"required: lower, upper; required: digit": "required: [" + lower + upper + "]; required: digit",
+		"required: upper; allowed: [-_]":          "required: upper; allowed: [-_]",
+		"allowed: []ab]":                          "allowed: []ab]",
+		"required: special;":                      "required: special",
+	} {
+		r, err := ParseRules(in)
+		if err != nil {
+			t.Errorf("Failed to parse '%s': %s", in, err)
+			continue
+		}
+		if r.String() != out {
+			t.Errorf("Mismatch for '%s': '%s' != '%s'", in, r.String(), out)
+		}
+	}
+
+	for _, in := range []string{
+		"minlength",
+		"minlength: foo",
+		"maxlength: -1",
+		"required: unknown",
+		"allowed: [abc",
+		"foo: bar",
+	} {
+		if _, err := ParseRules(in); err == nil {
+			t.Errorf("Should fail to parse '%s'", in)
+		}
+	}
+}
+
+func TestGenerateWithRules(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"minlength: 8; maxlength: 8",
+		"maxlength: 12; required: lower; required: upper; required: digit",
+		"minlength: 30; required: digit; allowed: lower",
+		"maxlength: 4; required: [ab]; required: [cd]; required: [ef]; required: [gh]",
+		"minlength: 20; allowed: [ab]; max-consecutive: 1",
+		"required: digit; required: [-_.]; max-consecutive: 2",
+	} {
+		r, err := ParseRules(spec)
+		if err != nil {
+			t.Fatalf("Failed to parse '%s': %s", spec, err)
+		}
+		for i := 0; i < 50; i++ {
+			pw, err := GenerateWithRules(r)
+			if err != nil {
+				t.Fatalf("Failed to generate for '%s': %s", spec, err)
+			}
+			if err := r.Check(pw); err != nil {
+				t.Fatalf("Generated invalid password '%s' for '%s': %s", pw, spec, err)
+			}
+			if len(pw) != r.Length() {
+				t.Errorf("Length mismatch for '%s': %d != %d", spec, len(pw), r.Length())
+			}
+		}
+	}
+
+	// unsatisfiable rules
+	for _, spec := range []string{
+		"minlength: 10; maxlength: 8",
+		"maxlength: 2; required: lower; required: upper; required: digit",
+		"minlength: 4; allowed: [a]; max-consecutive: 1",
+	} {
+		r, err := ParseRules(spec)
+		if err != nil {
+			t.Fatalf("Failed to parse '%s': %s", spec, err)
+		}
+		if _, err := GenerateWithRules(r); err == nil {
+			t.Errorf("Should fail to generate for '%s'", spec)
+		}
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	r, err := ParseRules("minlength: 4; maxlength: 6; required: digit; allowed: lower; max-consecutive: 2")
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %s", err)
+	}
+	for pw, ok := range map[string]bool{
+		"abc1":    true,
+		"aab1":    true,
+		"aaab1":   false,
+		"abc":     false,
+		"abcd":    false,
+		"abcdef1": false,
+		"ABC1":    false,
+	} {
+		if err := r.Check(pw); (err == nil) != ok {
+			t.Errorf("Check(%s) = %v", pw, err)
+		}
+	}
+	if !strings.Contains(r.String(), "max-consecutive: 2") {
+		t.Errorf("Missing max-consecutive in %s", r)
+	}
+}
+
+func TestRulesEntropy(t *testing.T) {
+	for spec, bits := range map[string]int{
+		"required: digit":                 33,
+		"required: digit; allowed: lower": 51,
+		"":                                65,
+	} {
+		r, err := ParseRules(spec)
+		if err != nil {
+			t.Fatalf("Failed to parse rules: %s", err)
+		}
+		if got := int(r.Entropy(10)); got != bits {
+			t.Errorf("Mismatch for %s: %d != %d", spec, got, bits)
+		}
+	}
+}
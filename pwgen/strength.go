@@ -0,0 +1,61 @@
+package pwgen
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// otherChars is the number of chars assumed for chars outside of the
+// character classes, e.g. spaces or umlauts
+const otherChars = 32
+
+// Strength describes a password
+type Strength struct {
+	// Length is the number of chars of the password
+	Length int
+	// Classes are the character classes used, in the order upper, lower,
+	// digit, special and other
+	Classes []string
+	// Entropy is an estimate of the entropy in bits, see Analyze
+	Entropy float64
+}
+
+// Analyze returns the length, character classes and estimated entropy of
+// the given password. The entropy is that of a password of the same length
+// picked uniformly from all chars of the classes it uses, like Entropy. It's
+// an upper bound, a password made of words has far less. Nothing is sent to
+// any service.
+func Analyze(pw []byte) Strength {
+	st := Strength{
+		Length:  utf8.RuneCount(pw),
+		Classes: make([]string, 0, 5),
+	}
+	used := make(map[string]bool, 5)
+	for _, r := range string(pw) {
+		used[class(r)] = true
+	}
+	n := 0
+	for _, c := range []string{"upper", "lower", "digit", "special", "other"} {
+		if !used[c] {
+			continue
+		}
+		st.Classes = append(st.Classes, c)
+		if chars, found := charClasses[c]; found {
+			n += len(chars)
+		} else {
+			n += otherChars
+		}
+	}
+	st.Entropy = entropy(st.Length, n)
+	return st
+}
+
+// class returns the character class of a char
+func class(r rune) string {
+	for _, c := range []string{"upper", "lower", "digit", "special"} {
+		if strings.ContainsRune(charClasses[c], r) {
+			return c
+		}
+	}
+	return "other"
+}
@@ -0,0 +1,39 @@
+package pwgen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	for _, tc := range []struct {
+		pw      string
+		length  int
+		classes []string
+		bits    int
+	}{
+		{"", 0, []string{}, 0},
+		{"aaaaaaaaaa", 10, []string{"lower"}, 47},
+		{"Passw0rdPassw0rdPass", 20, []string{"upper", "lower", "digit"}, 119},
+		{"Passw0rd!Passw0rd!Pa", 20, []string{"upper", "lower", "digit", "special"}, 131},
+		{"pässwört", 8, []string{"lower", "other"}, 46},
+		{"correct horse", 13, []string{"lower", "other"}, 76},
+	} {
+		st := Analyze([]byte(tc.pw))
+		if st.Length != tc.length {
+			t.Errorf("Length mismatch for %q: %d != %d", tc.pw, st.Length, tc.length)
+		}
+		if !reflect.DeepEqual(st.Classes, tc.classes) {
+			t.Errorf("Classes mismatch for %q: %v != %v", tc.pw, st.Classes, tc.classes)
+		}
+		if got := int(st.Entropy); got != tc.bits {
+			t.Errorf("Entropy mismatch for %q: %d != %d", tc.pw, got, tc.bits)
+		}
+	}
+
+	// the entropy of generated passwords matches
+	pw := GeneratePassword(24, true)
+	if st := Analyze(pw); len(st.Classes) == 4 && int(st.Entropy) != int(Entropy(24, true)) {
+		t.Errorf("Entropy mismatch for %q: %f != %f", pw, st.Entropy, Entropy(24, true))
+	}
+}
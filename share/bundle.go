@@ -0,0 +1,162 @@
+// Package share implements bundles for sharing a single secret for a limited
+// time. A bundle contains the encrypted secret and metadata, like the expiry,
+// signed by the key of the sharer.
+//
+// The expiry is enforced by gopass when opening the bundle. That's best
+// effort only, the recipient can always keep the decrypted secret or extract
+// the ciphertext and decrypt it with gpg.
+package share
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/justwatchcom/gopass/gpg"
+)
+
+const (
+	// Version is the version of the bundle format
+	Version = 1
+)
+
+var (
+	// ErrExpired is returned when opening an expired bundle
+	ErrExpired = fmt.Errorf("the share has expired")
+)
+
+// Metadata describes the shared secret. It's signed along with the checksum
+// of the ciphertext, so neither can be changed without invalidating the
+// signature.
+type Metadata struct {
+	Path     string    `json:"path"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+	Checksum string    `json:"checksum"`
+}
+
+// Bundle is a shared secret along with its signed metadata
+type Bundle struct {
+	Metadata   Metadata
+	Ciphertext []byte
+	Signature  []byte
+	// metadata are the signed bytes, the JSON encoding of Metadata might
+	// differ after decoding
+	metadata []byte
+}
+
+// bundleJSON is the encoded form of a bundle
+type bundleJSON struct {
+	Version    int    `json:"version"`
+	Metadata   []byte `json:"metadata"`
+	Signature  string `json:"signature"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// New creates an unsigned bundle of the given ciphertext which expires at
+// the given time
+func New(path string, ciphertext []byte, expires time.Time) *Bundle {
+	return &Bundle{
+		Metadata: Metadata{
+			Path:     path,
+			Created:  time.Now().UTC(),
+			Expires:  expires.UTC(),
+			Checksum: checksum(ciphertext),
+		},
+		Ciphertext: ciphertext,
+	}
+}
+
+// Sign signs the metadata with the given key
+func (b *Bundle) Sign(keyID string) error {
+	meta, err := json.Marshal(b.Metadata)
+	if err != nil {
+		return err
+	}
+	sig, err := gpg.SignDetached(meta, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to sign the share: %s", err)
+	}
+	b.metadata = meta
+	b.Signature = sig
+	return nil
+}
+
+// Encode returns the serialized bundle. It must be signed before.
+func (b *Bundle) Encode() ([]byte, error) {
+	if len(b.Signature) < 1 {
+		return nil, fmt.Errorf("the share is not signed")
+	}
+	return json.MarshalIndent(bundleJSON{
+		Version:    Version,
+		Metadata:   b.metadata,
+		Signature:  string(b.Signature),
+		Ciphertext: b.Ciphertext,
+	}, "", "  ")
+}
+
+// Decode parses a serialized bundle. The signature is not checked, use Verify
+// or Open for that.
+func Decode(buf []byte) (*Bundle, error) {
+	bj := bundleJSON{}
+	if err := json.Unmarshal(buf, &bj); err != nil {
+		return nil, fmt.Errorf("not a gopass share: %s", err)
+	}
+	if bj.Version != Version {
+		return nil, fmt.Errorf("unsupported share version %d", bj.Version)
+	}
+	b := &Bundle{
+		Ciphertext: bj.Ciphertext,
+		Signature:  []byte(bj.Signature),
+		metadata:   bj.Metadata,
+	}
+	if err := json.Unmarshal(bj.Metadata, &b.Metadata); err != nil {
+		return nil, fmt.Errorf("invalid share metadata: %s", err)
+	}
+	return b, nil
+}
+
+// Verify checks the signature of the metadata and the checksum of the
+// ciphertext. It returns the fingerprint of the signing key.
+func (b *Bundle) Verify() (string, error) {
+	if len(b.Signature) < 1 {
+		return "", fmt.Errorf("the share is not signed")
+	}
+	fpr, err := gpg.Verify(b.metadata, b.Signature)
+	if err != nil {
+		return "", err
+	}
+	// the metadata must still be the signed one
+	if meta, err := json.Marshal(b.Metadata); err != nil || !bytes.Equal(meta, b.metadata) {
+		return "", fmt.Errorf("the metadata of the share has been modified")
+	}
+	if checksum(b.Ciphertext) != b.Metadata.Checksum {
+		return "", fmt.Errorf("the secret of the share has been modified")
+	}
+	return fpr, nil
+}
+
+// Expired returns true if the bundle has expired at the given time
+func (b *Bundle) Expired(now time.Time) bool {
+	return !now.Before(b.Metadata.Expires)
+}
+
+// Open verifies the bundle and decrypts the secret unless it has expired
+func (b *Bundle) Open(now time.Time) ([]byte, error) {
+	if _, err := b.Verify(); err != nil {
+		return nil, err
+	}
+	if b.Expired(now) {
+		return nil, ErrExpired
+	}
+	return gpg.DecryptFrom(bytes.NewReader(b.Ciphertext))
+}
+
+// checksum returns the hex encoded SHA256 of buf
+func checksum(buf []byte) string {
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,108 @@
+package share
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/justwatchcom/gopass/gpg"
+	"github.com/justwatchcom/gopass/gpg/gpgtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBundle(t *testing.T, fpr string, expires time.Time) *Bundle {
+	buf := &bytes.Buffer{}
+	require.NoError(t, gpg.EncryptTo(buf, []byte("secret"), []string{fpr}, gpg.EncryptOpts{}))
+	b := New("foo/bar", buf.Bytes(), expires)
+	require.NoError(t, b.Sign(fpr))
+	return b
+}
+
+func TestBundle(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	now := time.Now()
+	b := newBundle(t, fpr, now.Add(time.Hour))
+	buf, err := b.Encode()
+	require.NoError(t, err)
+
+	d, err := Decode(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "foo/bar", d.Metadata.Path)
+	assert.True(t, b.Metadata.Expires.Equal(d.Metadata.Expires))
+
+	signer, err := d.Verify()
+	assert.NoError(t, err)
+	assert.Equal(t, fpr, signer)
+
+	content, err := d.Open(now)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(content))
+
+	_, err = Decode([]byte("foo"))
+	assert.Error(t, err)
+	_, err = (&Bundle{}).Encode()
+	assert.Error(t, err)
+}
+
+func TestBundleExpiry(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	now := time.Now()
+	b := newBundle(t, fpr, now.Add(time.Hour))
+	assert.False(t, b.Expired(now))
+	assert.True(t, b.Expired(now.Add(time.Hour)))
+
+	_, err := b.Open(now.Add(2 * time.Hour))
+	assert.Equal(t, ErrExpired, err)
+
+	// the expiry can't be extended without the key of the sharer
+	buf, err := b.Encode()
+	require.NoError(t, err)
+	d, err := Decode(buf)
+	require.NoError(t, err)
+	d.Metadata.Expires = now.Add(48 * time.Hour)
+	_, err = d.Open(now.Add(2 * time.Hour))
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrExpired, err)
+}
+
+func TestBundleSignature(t *testing.T) {
+	fpr, cleanup := gpgtest.NewKeyring(t)
+	defer cleanup()
+
+	b := newBundle(t, fpr, time.Now().Add(time.Hour))
+
+	// modified ciphertext
+	other := *b
+	other.Ciphertext = append([]byte{}, b.Ciphertext...)
+	other.Ciphertext[len(other.Ciphertext)-1] ^= 0xff
+	_, err := other.Verify()
+	assert.Error(t, err)
+
+	// modified signed metadata
+	other = *b
+	other.metadata = bytes.Replace(b.metadata, []byte("foo/bar"), []byte("foo/baz"), 1)
+	_, err = other.Verify()
+	assert.Error(t, err)
+
+	// signature of another key
+	if err := gpg.GenerateKey("gopass other", "other@gopass.pw", ""); err != nil {
+		t.Fatalf("Failed to generate second key: %s", err)
+	}
+	other = *b
+	other.Signature, err = gpg.SignDetached(b.metadata, "other@gopass.pw")
+	require.NoError(t, err)
+	signer, err := other.Verify()
+	assert.NoError(t, err)
+	assert.NotEqual(t, fpr, signer)
+
+	// missing signature
+	other = *b
+	other.Signature = nil
+	_, err = other.Verify()
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlias(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.run("alias link missing")
+	assert.Error(t, err)
+	assert.Contains(t, out, "alias target missing does not exist")
+
+	out, err = ts.run("alias link fixed/secret")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "link is now an alias of fixed/secret")
+
+	out, err = ts.run("show link")
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", out)
+
+	out, err = ts.run("ls --aliases")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "link (alias of fixed/secret)")
+
+	editor := filepath.Join(ts.tempDir, "editor.sh")
+	require.NoError(t, ioutil.WriteFile(editor, []byte("#!/bin/sh\nprintf 'edited' > \"$1\"\n"), 0755))
+	oldEditor := os.Getenv("GOPASS_EDITOR")
+	require.NoError(t, os.Setenv("GOPASS_EDITOR", editor))
+	defer func() {
+		_ = os.Setenv("GOPASS_EDITOR", oldEditor)
+	}()
+
+	_, err = ts.run("config noconfirm true")
+	require.NoError(t, err)
+
+	// editing the alias edits the secret it refers to
+	out, err = ts.runCmd([]string{ts.Binary, "edit", "link"}, []byte("y\n"))
+	assert.NoError(t, err, out)
+	out, err = ts.run("show fixed/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "edited", out)
+
+	// unless the alias itself should be edited
+	out, err = ts.run("edit --edit-alias link")
+	assert.NoError(t, err, out)
+	out, err = ts.run("show link")
+	assert.NoError(t, err)
+	assert.Equal(t, "edited", out)
+	out, err = ts.run("ls --aliases")
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "alias of")
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachments(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("git init --sign-key BE73F104")
+	require.NoError(t, err, out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "web/site"}, []byte("moar"))
+	require.NoError(t, err, out)
+
+	out, err = ts.run("attachments web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "web/site has no attachments", out)
+
+	fn := filepath.Join(ts.tempDir, "cert.pem")
+	require.NoError(t, ioutil.WriteFile(fn, []byte("-----BEGIN CERTIFICATE-----\n"), 0600))
+
+	out, err = ts.run("attachments add web/site " + fn)
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Attached cert.pem to web/site")
+
+	out, err = ts.run("attachments web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "cert.pem", out)
+
+	out, err = ts.run("attachments show web/site cert.pem")
+	assert.NoError(t, err)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----", out)
+
+	out, err = ts.run("ls")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "site (1 attachment)")
+
+	// show must still display the secret, not a folder
+	out, err = ts.run("show web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", out)
+
+	out, err = ts.run("move web/site web/other")
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("attachments show web/other cert.pem")
+	assert.NoError(t, err, out)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----", out)
+}
@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditKey(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.run("audit key")
+	assert.Error(t, err)
+	assert.Equal(t, "\nError: Usage: gopass audit key <fingerprint>\n", out)
+
+	out, err = ts.run("audit key BE73F104")
+	assert.NoError(t, err)
+	assert.Equal(t, "baz\nfixed/secret\nfoo/bar\n3 secrets readable by BE73F104, 0 possibly readable (anonymous)", out)
+
+	out, err = ts.run("audit key DEADBEEF")
+	assert.NoError(t, err)
+	assert.Equal(t, "0 secrets readable by DEADBEEF, 0 possibly readable (anonymous)", out)
+}
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCat(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("cat")
+	assert.Error(t, err)
+	assert.Contains(t, out, "Usage: gopass cat [--force] <name>")
+
+	content := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff\xfe\n\n")
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "images/logo"}, content)
+	assert.NoError(t, err, out)
+
+	// the exact bytes, without a trailing newline
+	cmd := exec.Command(ts.Binary, "cat", "images/logo")
+	cmd.Dir = ts.workDir()
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	got, err := cmd.Output()
+	assert.NoError(t, err, stderr.String())
+	assert.Equal(t, content, got)
+
+	out, err = ts.run("cat images/missing")
+	assert.Error(t, err)
+}
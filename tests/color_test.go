@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColor(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	// GOPASS_NOCOLOR is set and the output is piped
+	out, err := ts.run("ls")
+	assert.NoError(t, err, out)
+	assert.NotContains(t, out, "\x1b[")
+
+	out, err = ts.run("--color=always ls")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "\x1b[")
+
+	out, err = ts.run("--no-color ls")
+	assert.NoError(t, err, out)
+	assert.NotContains(t, out, "\x1b[")
+
+	out, err = ts.run("--color=sometimes ls")
+	assert.Error(t, err)
+	assert.Contains(t, out, "invalid color mode")
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdit(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	// the editor replaces the content of the file it's given in place
+	editor := filepath.Join(ts.tempDir, "editor.sh")
+	require.NoError(t, ioutil.WriteFile(editor, []byte("#!/bin/sh\necho \"$1\" >&2\nprintf 'edited' > \"$1\"\n"), 0755))
+	oldEditor := os.Getenv("GOPASS_EDITOR")
+	require.NoError(t, os.Setenv("GOPASS_EDITOR", editor))
+	defer func() {
+		_ = os.Setenv("GOPASS_EDITOR", oldEditor)
+	}()
+
+	_, err := ts.run("config noconfirm true")
+	require.NoError(t, err)
+
+	// a tempfile is used by default
+	out, err := ts.run("edit fixed/secret")
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "/proc/self/fd/3")
+
+	out, err = ts.run("show fixed/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "edited", out)
+
+	// tags are added to what the editor saved
+	_, err = ts.run("edit --tag work --tag Banking fixed/secret")
+	assert.NoError(t, err)
+	out, err = ts.run("show fixed/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "edited\ntags: banking, work", out)
+	out, err = ts.run("ls --tag work")
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed/secret", out)
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+	_, err = ts.run("config memfile true")
+	require.NoError(t, err)
+
+	out, err = ts.run("edit foo/bar")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "/proc/self/fd/3")
+
+	out, err = ts.run("show foo/bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "edited", out)
+}
+
+func TestEditChangelog(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	editor := filepath.Join(ts.tempDir, "editor.sh")
+	require.NoError(t, ioutil.WriteFile(editor, []byte("#!/bin/sh\nprintf '%s\\nedited\\n' \"$(cat \"$1\")\" > \"$1\"\n"), 0755))
+	oldEditor := os.Getenv("GOPASS_EDITOR")
+	require.NoError(t, os.Setenv("GOPASS_EDITOR", editor))
+	defer func() {
+		_ = os.Setenv("GOPASS_EDITOR", oldEditor)
+	}()
+
+	for _, cmd := range []string{"config noconfirm true", "config changelog true"} {
+		_, err := ts.run(cmd)
+		require.NoError(t, err)
+	}
+
+	_, err := ts.runCmd([]string{ts.Binary, "edit", "fixed/secret"}, []byte("rotated after the leak\n"))
+	assert.NoError(t, err)
+	_, err = ts.run("edit --no-reason fixed/secret")
+	assert.NoError(t, err)
+
+	out, err := ts.run("show fixed/secret")
+	assert.NoError(t, err)
+	lines := strings.Split(out, "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, "moar", lines[0])
+	assert.Equal(t, "edited", lines[1])
+	assert.Equal(t, "changelog: |", lines[2])
+	assert.Regexp(t, `^  \d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ .+: rotated after the leak$`, lines[3])
+	assert.NotContains(t, lines[4], ": ")
+	assert.Equal(t, "edited", lines[5])
+}
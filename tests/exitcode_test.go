@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exitCode returns the exit code of a failed gopass run
+func exitCode(t *testing.T, err error) int {
+	if err == nil {
+		return 0
+	}
+	ee, ok := err.(*exec.ExitError)
+	require.True(t, ok, "not an exit error: %s", err)
+	return ee.Sys().(syscall.WaitStatus).ExitStatus()
+}
+
+func TestExitCodes(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	for _, tc := range []struct {
+		args string
+		code int
+	}{
+		{"show foo/bar", 0},
+		{"show --bogus foo/bar", 2},
+		{"--bogus", 2},
+		{"git init --bogus", 2},
+		{"show", 2},
+		{"copy foo", 2},
+		{"show nothing/here", 3},
+		{"delete -f nothing/here", 3},
+		{"share nothing/here BE73F104", 3},
+	} {
+		out, err := ts.run(tc.args)
+		assert.Equal(t, tc.code, exitCode(t, err), "gopass %s: %s", tc.args, out)
+	}
+
+	// a secret that can't be decrypted
+	require.NoError(t, ioutil.WriteFile(filepath.Join(ts.storeDir(), "broken.gpg"), []byte("junk"), 0600))
+	out, err := ts.run("show broken")
+	assert.Equal(t, 4, exitCode(t, err), out)
+
+	// declining a confirmation
+	_, err = ts.run("config noconfirm false")
+	require.NoError(t, err)
+	out, err = ts.runCmd([]string{ts.Binary, "grep", "moar"}, []byte("n\n"))
+	assert.Equal(t, 5, exitCode(t, err), out)
+}
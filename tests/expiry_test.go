@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiry(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	_, err := ts.run("config noconfirm true")
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "tmp/expired"}, []byte("token\nexpires_at: "+past+"\n"))
+	require.NoError(t, err, out)
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "tmp/valid"}, []byte("token\nexpires_at: "+future+"\n"))
+	require.NoError(t, err, out)
+
+	out, err = ts.run("show tmp/expired")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Warning: tmp/expired expired on")
+	assert.Contains(t, out, "token")
+
+	out, err = ts.run("show --strict tmp/expired")
+	assert.Error(t, err)
+	assert.Contains(t, out, "tmp/expired expired on")
+	assert.NotContains(t, out, "token")
+
+	out, err = ts.run("show --strict tmp/valid")
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "Warning")
+
+	// the expiry can't be asked for while reading from stdin
+	_, err = ts.runCmd([]string{ts.Binary, "insert", "--expires", "tmp/other"}, []byte("token\n"))
+	assert.Error(t, err)
+
+	out, err = ts.runCmd([]string{ts.Binary, "prune"}, []byte("n\n"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "These secrets have expired:\n - tmp/expired\n")
+
+	out, err = ts.run("prune --force")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Removed tmp/expired")
+
+	list := `
+gopass
+├── baz
+├── fixed
+│   └── secret
+├── foo
+│   └── bar
+└── tmp
+    └── valid
+`
+	out, err = ts.run("list")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(list), out)
+
+	out, err = ts.run("prune")
+	assert.NoError(t, err)
+	assert.Equal(t, "No expired secrets", out)
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"os/exec"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,3 +31,17 @@ func TestFind(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "baz\nfoo/bar", out)
 }
+
+func TestFindPorcelain(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	cmd := exec.Command(ts.Binary, "find", "--porcelain", "-z", "b")
+	cmd.Dir = ts.workDir()
+	out, err := cmd.Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "baz\x00foo/bar\x00", string(out))
+}
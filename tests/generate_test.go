@@ -24,7 +24,61 @@ func TestGenerate(t *testing.T) {
 	out, err = ts.run("generate baz 42")
 	assert.NoError(t, err)
 	lines := strings.Split(out, "\n")
+	assert.Equal(t, "Generated a password for baz with 275 bits of entropy", lines[0])
+	// there is no clipboard in the test environment
+	assert.Contains(t, out, "Use `gopass show baz` to see the password")
+
+	out, err = ts.run("show baz")
+	assert.NoError(t, err)
+	assert.Len(t, out, 42)
+
+	// stdout is never a terminal in the tests
+	out, err = ts.run("generate --print --no-symbols foo 10")
+	assert.NoError(t, err)
+	lines = strings.Split(out, "\n")
+	assert.Equal(t, "Generated a password for foo with 59 bits of entropy", lines[0])
+	assert.Equal(t, "Warning: Not printing the password, stdout is not a terminal", lines[1])
+
+	out, err = ts.runCmd([]string{ts.Binary, "generate", "foo", "12"}, []byte("n\n"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "An entry already exists for foo:\n- password: ********\n+ password: ********\n")
+	assert.Contains(t, out, "not overwriting your current password")
+}
+
+func TestGeneratePasswordRules(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("generate --password-rules 'maxlength: 2; required: lower; required: upper; required: digit' foo")
+	assert.Error(t, err)
+	assert.Contains(t, out, "maxlength 2 is too short for 3 required character classes")
+
+	out, err = ts.run("generate --password-rules 'maxlength: 8; required: digit; allowed: lower' foo")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Generated a password for foo with 41 bits of entropy")
+
+	out, err = ts.run("show foo")
+	assert.NoError(t, err)
+	lines := strings.Split(out, "\n")
 	assert.Len(t, lines, 2)
-	assert.Equal(t, "The generated password for baz is:", lines[0])
-	assert.Len(t, lines[1], 42)
+	assert.Len(t, lines[0], 8)
+	assert.Equal(t, "password-rules: maxlength: 8; required: digit; allowed: lower", lines[1])
+
+	// regenerating reuses the stored rules
+	out, err = ts.run("generate --force foo")
+	assert.NoError(t, err)
+	lines = strings.Split(out, "\n")
+	assert.Equal(t, "Using the password rules of foo: maxlength: 8; required: digit; allowed: lower", lines[0])
+
+	out, err = ts.run("show foo")
+	assert.NoError(t, err)
+	lines = strings.Split(out, "\n")
+	assert.Len(t, lines[0], 8)
+	assert.Regexp(t, "^[a-z0-9]*[0-9][a-z0-9]*$", lines[0])
+
+	out, err = ts.run("generate --force foo 12")
+	assert.Error(t, err)
+	assert.Contains(t, out, "password length 12 is not permitted")
 }
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedCommits(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("git init --sign-key BE73F104")
+	assert.NoError(t, err, out)
+
+	// make sure the signature is created by gopass and not by git
+	out, err = ts.run("git config --local --unset commit.gpgsign")
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("config signcommits true")
+	assert.NoError(t, err)
+	assert.Zero(t, out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "signed/secret"}, []byte("moar"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("git verify-commit HEAD")
+	assert.NoError(t, err, out)
+}
+
+func TestGitDiffDriver(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("git init --sign-key BE73F104 --diff-driver")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "git diff and log decrypt secrets in this store now")
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "foo"}, []byte("first"))
+	assert.NoError(t, err, out)
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "-f", "foo"}, []byte("second"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("git log -p -1 foo.gpg")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "-first")
+	assert.Contains(t, out, "+second")
+
+	out, err = ts.run("git-textconv")
+	assert.Error(t, err)
+	assert.Contains(t, out, "Usage:")
+
+	// a cloned store gets the diff driver with --diff-driver
+	dst := filepath.Join(ts.tempDir, "clone")
+	out, err = ts.run("clone --diff-driver --path " + dst + " " + ts.storeDir() + " cloned")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "git diff and log decrypt secrets in this store now")
+	out, err = ts.run("git --store cloned config --local diff.gpg.textconv")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "git-textconv")
+}
@@ -24,5 +24,49 @@ func TestGrep(t *testing.T) {
 
 	out, err = ts.run("grep moar")
 	assert.NoError(t, err)
-	assert.Equal(t, "fixed/secret:\nmoar", out)
+	assert.Equal(t, "fixed/secret:1: *****", out)
+
+	out, err = ts.run("grep --unmask MOAR")
+	assert.NoError(t, err)
+	assert.Zero(t, out)
+
+	out, err = ts.run("grep --unmask -i MOAR")
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed/secret:1: moar", out)
+
+	out, err = ts.run("grep --unmask -r '^mo+ar$'")
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed/secret:1: moar", out)
+}
+
+func TestGrepIndex(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.run("index --force")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Built the search indexes")
+
+	out, err = ts.run("grep moar")
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed/secret:1: *****", out)
+
+	_, err = ts.runCmd([]string{ts.Binary, "insert", "some/other"}, []byte("moar"))
+	assert.NoError(t, err)
+	out, err = ts.run("grep moar")
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed/secret:1: *****\nsome/other:1: *****", out)
+
+	_, err = ts.run("rm --force fixed/secret")
+	assert.NoError(t, err)
+	out, err = ts.run("grep moar")
+	assert.NoError(t, err)
+	assert.Equal(t, "some/other:1: *****", out)
+
+	out, err = ts.run("index --remove")
+	assert.NoError(t, err)
+	assert.Equal(t, "Removed the search indexes", out)
 }
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportCSV(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	fn := filepath.Join(ts.tempDir, "chrome.csv")
+	require.NoError(t, ioutil.WriteFile(fn, []byte("name,url,username,password\n"+
+		"example.com,https://example.com/login,gopher,hunter2\n"+
+		"example.com,https://example.com/,admin,s3cr3t\n"+
+		"other,https://other.example.org/,gopher,other\n"), 0600))
+
+	// nothing is written unless the plan is confirmed
+	_, err := ts.run("config noconfirm false")
+	require.NoError(t, err)
+	out, err := ts.runCmd([]string{ts.Binary, "import", "csv", "--folder", "browser", fn}, []byte("n\n"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "Importing 3 passwords of a Chrome export")
+	assert.Contains(t, out, "browser/example.com/admin <- admin at https://example.com/ (line 3)")
+	_, err = ts.run("show browser/other.example.org")
+	assert.Error(t, err)
+
+	out, err = ts.runCmd([]string{ts.Binary, "import", "csv", "--folder", "browser", fn}, []byte("y\n"))
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "3 created, 0 replaced, 0 skipped, 0 failed")
+
+	out, err = ts.run("show browser/example.com/gopher")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2\nurl: https://example.com/login\nuser: gopher", out)
+	out, err = ts.run("show browser/other.example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "other\nurl: https://other.example.org/\nuser: gopher", out)
+
+	// existing secrets are kept unless forced
+	_, err = ts.run("config noconfirm true")
+	require.NoError(t, err)
+	out, err = ts.run("import csv --folder browser " + fn)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "0 created, 0 replaced, 3 skipped, 0 failed")
+	out, err = ts.run("import csv --folder browser --force " + fn)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "0 created, 3 replaced, 0 skipped, 0 failed")
+
+	require.NoError(t, ioutil.WriteFile(fn, []byte("title,login,secret\nfoo,bar,baz\n"), 0600))
+	out, err = ts.run("import csv " + fn)
+	assert.Error(t, err)
+	assert.Contains(t, out, "unknown CSV header")
+}
@@ -1,6 +1,9 @@
 package tests
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,3 +22,227 @@ func TestInsert(t *testing.T) {
 	out, err = ts.runCmd([]string{ts.Binary, "insert", "some/secret"}, []byte("moar"))
 	assert.NoError(t, err)
 }
+
+func TestInsertRecipientsFrom(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	fn := filepath.Join(ts.tempDir, "recipients")
+	err := ioutil.WriteFile(fn, []byte("# the tester\nBE73F104\n"), 0600)
+	assert.NoError(t, err)
+
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "--recipients-from", fn, "some/secret"}, []byte("moar"))
+	assert.NoError(t, err, out)
+
+	err = ioutil.WriteFile(fn, []byte("BE73F104\nDEADBEEF\n"), 0600)
+	assert.NoError(t, err)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "--recipients-from", fn, "other/secret"}, []byte("moar"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "DEADBEEF: no public key found")
+}
+
+func TestInsertCipherAlgo(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("config cipheralgo aes256")
+	assert.NoError(t, err)
+	assert.Zero(t, out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "some/secret"}, []byte("moar"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("config cipheralgo rot13")
+	assert.NoError(t, err)
+	assert.Zero(t, out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "other/secret"}, []byte("moar"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "Unsupported cipher algorithm rot13")
+}
+
+func TestInsertManifest(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	fn := filepath.Join(ts.tempDir, "manifest.yaml")
+	err := ioutil.WriteFile(fn, []byte(`web/site:
+  password: hunter2
+  meta:
+    user: gopher
+db/prod:
+  generate: true
+  length: 16
+`), 0600)
+	assert.NoError(t, err)
+
+	out, err := ts.run("insert --from " + fn)
+	assert.NoError(t, err, out)
+	assert.Equal(t, "created: web/site\ncreated: db/prod\n2 created, 0 replaced, 0 skipped, 0 failed", out)
+
+	out, err = ts.run("show web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2\nuser: gopher", out)
+
+	out, err = ts.run("show db/prod")
+	assert.NoError(t, err)
+	assert.Len(t, out, 16)
+
+	err = ioutil.WriteFile(fn, []byte("web/site:\n  password: swordfish\n  overwrite: true\ndb/prod: other\n"), 0600)
+	assert.NoError(t, err)
+
+	out, err = ts.run("insert --from " + fn)
+	assert.NoError(t, err, out)
+	assert.Equal(t, "replaced: web/site\nskipped: db/prod (exists, set overwrite to replace it)\n0 created, 1 replaced, 1 skipped, 0 failed", out)
+
+	out, err = ts.run("show web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "swordfish", out)
+
+	err = ioutil.WriteFile(fn, []byte("web/site:\n  pasword: typo\n"), 0600)
+	assert.NoError(t, err)
+
+	out, err = ts.run("insert --from " + fn)
+	assert.Error(t, err)
+	assert.Equal(t, "\nError: manifest: web/site: unknown field pasword\n", out)
+}
+
+func TestInsertNameSchema(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("config nameschema ^[a-z]+/(dev|prod)/[a-z]+$")
+	assert.NoError(t, err, out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "web/prod/gopher"}, []byte("moar"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "web/gopher"}, []byte("moar"))
+	assert.Error(t, err)
+	assert.Equal(t, "\nError: web/gopher doesn't follow the naming scheme of the store, names must match ^[a-z]+/(dev|prod)/[a-z]+$. Use --force to use it anyway\n", out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "--force", "web/gopher"}, []byte("moar"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("mv web/prod/gopher gopher")
+	assert.Error(t, err)
+	assert.Contains(t, out, "gopher doesn't follow the naming scheme")
+
+	out, err = ts.run("config nameschema (unclosed")
+	assert.Error(t, err)
+	assert.Contains(t, out, "invalid regular expression")
+}
+
+func TestInsertTemplateFrom(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "web/site"}, []byte("hunter2\nuser: gopher\nurl: example.org\nprevious_passwords:\n  - \"hunter1\"\n"))
+	assert.NoError(t, err, out)
+	site := "hunter2\nuser: gopher\nexpires_at: 2099-01-01\ntotp: JBSWY3DPEHPK3PXP\notpauth://totp/example.org:gopher?secret=JBSWY3DPEHPK3PXP\nurl: example.org\nchangelog: |\n  2017-05-03T14:00:00Z John Doe <john.doe@example.com>: rotated\nnote: shared"
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "web/otp"}, []byte(site+"\n"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "--template-from", "web/site", "web/staging"}, []byte("s3cret\n"))
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Created web/staging from the metadata of web/site")
+
+	// the metadata is carried over, the password and its history are not
+	out, err = ts.run("show web/staging")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret\nuser: gopher\nurl: example.org", out)
+
+	out, err = ts.run("show web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2\nuser: gopher\nurl: example.org\nprevious_passwords:\n  - \"hunter1\"", out)
+
+	// per credential fields are left out as well
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "--template-from", "web/otp", "web/otp2"}, []byte("s3cret\n"))
+	assert.NoError(t, err, out)
+	out, err = ts.run("show web/otp2")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret\nuser: gopher\nurl: example.org\nnote: shared", out)
+	out, err = ts.run("show web/otp")
+	assert.NoError(t, err)
+	assert.Equal(t, site, out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "--template-from", "web/missing", "web/other"}, []byte("s3cret\n"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "web/missing does not exist")
+}
+
+func TestInsertTemplate(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	fn := filepath.Join(ts.storeDir(), "websites", ".template")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(fn), 0700))
+	assert.NoError(t, ioutil.WriteFile(fn, []byte("\nurl: https://{{ .Base }}\nuser:\n"), 0600))
+
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "websites/example.org"}, []byte("hunter2"))
+	assert.NoError(t, err, out)
+	out, err = ts.run("show websites/example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2\nurl: https://example.org\nuser:", out)
+
+	// unless it's switched off
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "--no-template", "websites/other.org"}, []byte("hunter2"))
+	assert.NoError(t, err, out)
+	out, err = ts.run("show websites/other.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", out)
+
+	// and secrets outside of the directory don't get it
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "misc/foo"}, []byte("hunter2"))
+	assert.NoError(t, err, out)
+	out, err = ts.run("show misc/foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", out)
+}
+
+func TestInsertMaxSize(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("config maxsecretsize 1024")
+	assert.NoError(t, err, out)
+
+	large := make([]byte, 2048)
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "web/cert"}, large)
+	assert.Error(t, err)
+	assert.Contains(t, out, "web/cert has 2048 bytes, more than maxsecretsize (1024 bytes)")
+	assert.Contains(t, out, "This looks like a binary file")
+	assert.Contains(t, out, "not storing web/cert read from stdin, add --allow-large to store it anyway")
+
+	// --force only overwrites existing secrets
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "--force", "web/cert"}, large)
+	assert.Error(t, err)
+	assert.Contains(t, out, "add --allow-large to store it anyway")
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "--allow-large", "web/cert"}, large)
+	assert.NoError(t, err, out)
+
+	// attachments aren't limited
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "web/site"}, []byte("moar"))
+	assert.NoError(t, err, out)
+	fn := filepath.Join(ts.tempDir, "cert.der")
+	assert.NoError(t, ioutil.WriteFile(fn, large, 0600))
+	out, err = ts.run("attachments add web/site " + fn)
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Attached cert.der to web/site")
+}
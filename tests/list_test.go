@@ -1,10 +1,16 @@
 package tests
 
 import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestList(t *testing.T) {
@@ -47,3 +53,143 @@ foo
 	assert.NoError(t, err)
 	assert.Equal(t, strings.TrimSpace(list), out)
 }
+
+func TestListSort(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		"foo/bar":      3 * time.Hour,
+		"baz":          time.Hour,
+		"fixed/secret": 2 * time.Hour,
+	} {
+		fn := filepath.Join(ts.storeDir(), name+".gpg")
+		require.NoError(t, os.Chtimes(fn, now.Add(-age), now.Add(-age)))
+	}
+
+	names := func(out string) []string {
+		var l []string
+		for _, line := range strings.Split(out, "\n") {
+			p := strings.Fields(line)
+			l = append(l, p[len(p)-1])
+		}
+		return l
+	}
+
+	out, err := ts.run("list --sort=name")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"baz", "fixed/secret", "foo/bar"}, names(out))
+
+	out, err = ts.run("list --sort=mtime")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"baz", "fixed/secret", "foo/bar"}, names(out))
+	assert.Contains(t, out, now.Add(-time.Hour).Format("2006-01-02 15:04:05")+"  baz")
+
+	require.NoError(t, os.Chtimes(filepath.Join(ts.storeDir(), "foo/bar.gpg"), now, now))
+	out, err = ts.run("list --sort=mtime")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo/bar", "baz", "fixed/secret"}, names(out))
+
+	out, err = ts.run("list --sort=mtime fixed")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fixed/secret"}, names(out))
+
+	_, err = ts.run("list --sort=size")
+	assert.Error(t, err)
+}
+
+func TestListTag(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	_, err := ts.runCmd([]string{ts.Binary, "insert", "--tag", "work", "--tag", "#Banking", "web/bank"}, []byte("secret\n"))
+	require.NoError(t, err)
+	_, err = ts.runCmd([]string{ts.Binary, "insert", "--tag", "work", "mail"}, []byte("secret\n"))
+	require.NoError(t, err)
+
+	out, err := ts.run("show web/bank")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\ntags: banking, work", out)
+
+	out, err = ts.run("list --tag work")
+	assert.NoError(t, err)
+	assert.Equal(t, "mail\nweb/bank", out)
+
+	out, err = ts.run("list --tag banking web")
+	assert.NoError(t, err)
+	assert.Equal(t, "web/bank", out)
+
+	out, err = ts.run("list --tag none")
+	assert.NoError(t, err)
+	assert.Equal(t, "", out)
+
+	out, err = ts.run("list --porcelain --tag work")
+	assert.NoError(t, err)
+	assert.Equal(t, "mail\nweb/bank", out)
+}
+
+func TestListRecipient(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.run("list --recipient BE73F104")
+	assert.NoError(t, err)
+	assert.Equal(t, "baz\nfixed/secret\nfoo/bar", out)
+
+	out, err = ts.run("list --recipient 0x82EBD945BE73F104 --verify foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo/bar", out)
+
+	out, err = ts.run("list --recipient DEADBEEF")
+	assert.NoError(t, err)
+	assert.Equal(t, "", out)
+
+	// secrets whose .gpg-id changed since they were encrypted are reported.
+	// The unknown key can't be imported, which is reported first.
+	fn := filepath.Join(ts.storeDir(), ".gpg-id")
+	require.NoError(t, ioutil.WriteFile(fn, []byte("0xDEADBEEFDEADBEEF\n"), 0600))
+	out, err = ts.run("list --recipient 0xDEADBEEFDEADBEEF")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(out, "\nbaz\nfixed/secret\nfoo/bar"), out)
+	out, err = ts.run("list --recipient BE73F104 --verify fixed")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "\nfixed/secret\nWarning: fixed/secret is still encrypted for BE73F104, but its .gpg-id doesn't list it. Run `gopass reencrypt fixed/secret`", out)
+}
+
+func TestListPorcelain(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+	_, err := ts.runCmd([]string{ts.Binary, "insert", "foo/Zed"}, []byte("moar"))
+	require.NoError(t, err)
+
+	for args, want := range map[string]string{
+		"ls --porcelain":                          "baz\nfixed/secret\nfoo/Zed\nfoo/bar\n",
+		"ls --porcelain foo/":                     "foo/Zed\nfoo/bar\n",
+		"ls --porcelain -z foo":                   "foo/Zed\x00foo/bar\x00",
+		"ls --porcelain nope":                     "",
+		"ls --porcelain --recipient BE73F104 foo": "foo/Zed\nfoo/bar\n",
+		"ls --porcelain --tag none":               "",
+	} {
+		cmd := exec.Command(ts.Binary, strings.Fields(args)...)
+		cmd.Dir = ts.workDir()
+		out, err := cmd.Output()
+		assert.NoError(t, err, args)
+		assert.Equal(t, want, string(out), args)
+	}
+
+	_, err = ts.run("ls --porcelain --sort mtime")
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateSplit(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "fixed/other"}, []byte("more"))
+	assert.NoError(t, err, out)
+	before, err := ts.run("list --sort=name")
+	assert.NoError(t, err)
+
+	out, err = ts.run("migrate split fixed")
+	assert.Error(t, err)
+	assert.Contains(t, out, "Usage: gopass migrate split")
+
+	dst := filepath.Join(ts.tempDir, "fixed")
+	out, err = ts.runCmd([]string{ts.Binary, "migrate", "split", "fixed", dst}, []byte("n\n"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "2 secrets below fixed are moved from "+ts.storeDir()+" to "+dst+", which is mounted as fixed")
+
+	out, err = ts.run("migrate split --force fixed " + dst)
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Moved 2 secrets to "+dst+" and mounted it as fixed")
+
+	after, err := ts.run("list --sort=name")
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	out, err = ts.run("show fixed/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", out)
+
+	out, err = ts.run("mounts")
+	assert.NoError(t, err)
+	assert.Contains(t, out, dst)
+
+	// files of an existing store are only overwritten once confirmed
+	out, err = ts.run("mounts remove fixed")
+	assert.NoError(t, err, out)
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "more/secret"}, []byte("new"))
+	assert.NoError(t, err, out)
+	out, err = ts.runCmd([]string{ts.Binary, "migrate", "split", "more", dst}, []byte("n\n"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "1 files exist in "+dst+" already and are overwritten")
+	assert.Contains(t, out, "   secret.gpg")
+
+	out, err = ts.run("migrate split --force more " + dst)
+	assert.NoError(t, err, out)
+	out, err = ts.run("show more/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", out)
+}
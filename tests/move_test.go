@@ -41,3 +41,24 @@ func TestMove(t *testing.T) {
 	_, err = ts.run("show baz")
 	assert.NoError(t, err)
 }
+
+func TestMoveHistory(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("git init --sign-key BE73F104")
+	assert.NoError(t, err, out)
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "old/secret"}, []byte("moar"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("move old/secret new/secret")
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("git log --follow --format=%s -- new/secret.gpg")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Move old/secret to new/secret.")
+	assert.Contains(t, out, "Save secret to old/secret.")
+}
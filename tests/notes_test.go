@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotes(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "--note", "created for the demo", "web/login"}, []byte("hunter2\nuser: admin\n"))
+	require.NoError(t, err, out)
+
+	out, err = ts.run("notes web/login")
+	assert.NoError(t, err)
+	assert.Equal(t, "created for the demo", out)
+
+	out, err = ts.run("notes append web/login rotated on monday")
+	assert.NoError(t, err, out)
+	out, err = ts.runCmd([]string{ts.Binary, "notes", "append", "web/login"}, []byte("multiple\n\nlines\n"))
+	require.NoError(t, err, out)
+
+	out, err = ts.run("notes web/login")
+	assert.NoError(t, err)
+	assert.Equal(t, "created for the demo\nrotated on monday\nmultiple\n\nlines", out)
+
+	// the password and other fields are kept
+	out, err = ts.run("show web/login")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "hunter2\nuser: admin\nnotes: |")
+
+	out, err = ts.run("notes append nothing/here foo")
+	assert.Error(t, err)
+	assert.Contains(t, out, "Entry is not in the password store")
+}
@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justwatchcom/gopass/otp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTP(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "-m", "web/login"}, []byte("hunter2\nuser: admin\notpauth://totp/Example:admin?secret="+secret+"&issuer=Example\n"))
+	require.NoError(t, err, out)
+
+	// stdout isn't a terminal, so --watch prints the code once and exits
+	for _, cmd := range []string{"otp web/login", "otp --watch web/login"} {
+		before := time.Now()
+		out, err = ts.run(cmd)
+		assert.NoError(t, err, cmd)
+		o, err := otp.New(secret)
+		require.NoError(t, err)
+		assert.Contains(t, []string{o.At(before), o.At(time.Now())}, out, cmd)
+	}
+
+	out, err = ts.run("otp")
+	assert.Error(t, err)
+	assert.Contains(t, out, "Usage")
+
+	_, err = ts.runCmd([]string{ts.Binary, "insert", "-m", "web/plain"}, []byte("hunter2\nuser: admin\n"))
+	require.NoError(t, err)
+	out, err = ts.run("otp web/plain")
+	assert.Error(t, err)
+	assert.Contains(t, out, "has no TOTP secret")
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugin(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	// a dummy plugin printing what it gets
+	bin := filepath.Join(ts.tempDir, "bin")
+	require.NoError(t, os.MkdirAll(bin, 0700))
+	script := `#!/bin/sh
+echo "args: $*"
+echo "store: $GOPASS_STORE"
+echo "key: $GOPASS_KEY"
+echo "binary: $GOPASS_BINARY"
+[ "$1" = "fail" ] && exit 7
+exit 0
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bin, "gopass-demo"), []byte(script), 0755))
+	oldPath := os.Getenv("PATH")
+	require.NoError(t, os.Setenv("PATH", bin+string(os.PathListSeparator)+oldPath))
+	defer func() {
+		_ = os.Setenv("PATH", oldPath)
+	}()
+
+	out, err := ts.run("demo --flag foo/bar")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "args: --flag foo/bar\n")
+	assert.Contains(t, out, "store: "+ts.storeDir()+"\n")
+	assert.Contains(t, out, "key: AB919DBF9BF0DE74896397F282EBD945BE73F104\n")
+	assert.NotContains(t, out, "binary: \n")
+	// no secret is decrypted for it
+	assert.False(t, strings.Contains(out, "moar"))
+
+	// the exit code of the plugin is passed on
+	out, err = ts.run("demo fail")
+	if assert.Error(t, err) {
+		if ee, ok := err.(*exec.ExitError); assert.True(t, ok) {
+			assert.Equal(t, 7, ee.Sys().(syscall.WaitStatus).ExitStatus())
+		}
+	}
+	assert.NotContains(t, out, "Error")
+
+	// secrets shadow plugins of the same name
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "demo"}, []byte("secret"))
+	assert.NoError(t, err, out)
+	out, err = ts.run("demo")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", out)
+
+	// unknown commands without a plugin are still secrets
+	_, err = ts.run("missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiles(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "personal/secret"}, []byte("personal"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("profile")
+	assert.NoError(t, err)
+	assert.Equal(t, "No profiles defined. Add one with `gopass profile add <name>`", out)
+
+	work := filepath.Join(ts.tempDir, "work")
+	out, err = ts.run("profile add --key BE73F104 --path " + work + " --git-name Gopher work")
+	assert.NoError(t, err)
+	assert.Equal(t, "Saved profile work", out)
+
+	out, err = ts.run("--profile missing show personal/secret")
+	assert.Error(t, err)
+	assert.Contains(t, out, "profile missing does not exist")
+
+	// the key of the profile is used without asking
+	out, err = ts.run("--profile work init --nogit")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Using the key BE73F104 of profile work")
+
+	out, err = ts.runCmd([]string{ts.Binary, "--profile", "work", "insert", "work/secret"}, []byte("work"))
+	assert.NoError(t, err, out)
+	_, err = os.Stat(filepath.Join(work, "work", "secret.gpg"))
+	assert.NoError(t, err)
+
+	out, err = ts.run("--profile work show work/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "work", out)
+
+	// the config keeps the path of the default store
+	out, err = ts.run("show personal/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "personal", out)
+
+	out, err = ts.run("profile default work")
+	assert.NoError(t, err)
+	assert.Zero(t, out)
+
+	out, err = ts.run("show work/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "work", out)
+
+	out, err = ts.run("profile")
+	assert.NoError(t, err)
+	assert.Equal(t, "* work (key: BE73F104, path: "+work+", git-name: Gopher) [default]", out)
+
+	// the flag takes precedence over the environment, which takes
+	// precedence over the default
+	out, err = ts.run("profile add personal")
+	assert.NoError(t, err)
+	assert.NoError(t, os.Setenv("GOPASS_PROFILE", "personal"))
+	defer func() {
+		_ = os.Unsetenv("GOPASS_PROFILE")
+	}()
+	out, err = ts.run("show personal/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "personal", out)
+
+	out, err = ts.run("--profile work show work/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "work", out)
+
+	out, err = ts.run("profile remove work")
+	assert.NoError(t, err)
+	assert.Equal(t, "Removed profile work", out)
+}
+
+func TestWhoami(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("whoami")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "GNUPGHOME: "+ts.gpgDir())
+	assert.Contains(t, out, "Profile:   none")
+	assert.Contains(t, out, "BE73F104 (recipient)")
+
+	gnupg := filepath.Join(ts.tempDir, "gnupg-work")
+	assert.NoError(t, os.Mkdir(gnupg, 0700))
+	_, err = ts.run("profile add --key BE73F104 --gnupghome " + gnupg + " work")
+	assert.NoError(t, err)
+	out, err = ts.run("--profile work whoami --json")
+	assert.NoError(t, err)
+	id := struct {
+		GnupgHome string `json:"gnupghome"`
+		Profile   string `json:"profile"`
+		Key       string `json:"key"`
+		Source    string `json:"source"`
+		Secret    bool   `json:"secret"`
+	}{}
+	// the store warns that the keyring of the profile lacks its recipient
+	if i := strings.Index(out, "{"); i > 0 {
+		out = out[i:]
+	}
+	assert.NoError(t, json.Unmarshal([]byte(out), &id), out)
+	assert.Equal(t, gnupg, id.GnupgHome)
+	assert.Equal(t, "work", id.Profile)
+	assert.Equal(t, "BE73F104", id.Key)
+	assert.Equal(t, "profile", id.Source)
+	// the keyring of the profile is empty
+	assert.False(t, id.Secret)
+}
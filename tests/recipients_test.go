@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipients(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	// don't try to import the missing key below on every invocation
+	_, err := ts.run("config loadkeys false")
+	require.NoError(t, err)
+
+	// add a recipient without a public key in the keyring
+	fh, err := os.OpenFile(filepath.Join(ts.storeDir(), ".gpg-id"), os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = fh.WriteString("DEADBEEF\n")
+	require.NoError(t, err)
+	require.NoError(t, fh.Close())
+
+	out, err := ts.run("recipients")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "gopass ("+ts.storeDir()+")")
+	assert.Contains(t, out, " - 0x82EBD945BE73F104 - ")
+	assert.Contains(t, out, " - DEADBEEF [key missing]")
+
+	out, err = ts.run("recipients --json")
+	assert.NoError(t, err)
+	stores := []struct {
+		Store      string `json:"store"`
+		Path       string `json:"path"`
+		Recipients []struct {
+			ID  string `json:"id"`
+			Key *struct {
+				Fingerprint string `json:"fingerprint"`
+			} `json:"key"`
+		} `json:"recipients"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(out), &stores), out)
+	require.Len(t, stores, 1)
+	assert.Equal(t, ts.storeDir(), stores[0].Path)
+	require.Len(t, stores[0].Recipients, 2)
+	assert.Equal(t, "AB919DBF9BF0DE74896397F282EBD945BE73F104", stores[0].Recipients[0].Key.Fingerprint)
+	assert.Equal(t, "DEADBEEF", stores[0].Recipients[1].ID)
+	assert.Nil(t, stores[0].Recipients[1].Key)
+}
+
+func TestACL(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	_, err := ts.run("config loadkeys false")
+	require.NoError(t, err)
+
+	out, err := ts.run("acl foo/bar")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "foo/bar")
+	assert.Contains(t, out, " - 0x82EBD945BE73F104 - ")
+	assert.NotContains(t, out, "not encrypted")
+
+	// a new recipient can't decrypt the secret until it's re-encrypted
+	fh, err := os.OpenFile(filepath.Join(ts.storeDir(), ".gpg-id"), os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = fh.WriteString("DEADBEEF\n")
+	require.NoError(t, err)
+	require.NoError(t, fh.Close())
+
+	out, err = ts.run("acl foo/bar")
+	assert.Error(t, err)
+	assert.Contains(t, out, " - DEADBEEF [key missing] [not encrypted for this key]")
+
+	_, err = ts.run("acl missing")
+	assert.Error(t, err)
+}
+
+func TestRecipientReview(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("config recipientreviewevery 3")
+	assert.NoError(t, err, out)
+
+	for _, name := range []string{"foo", "bar"} {
+		out, err = ts.run("generate " + name + " 12")
+		assert.NoError(t, err, out)
+		assert.NotContains(t, out, "Periodic recipient review")
+	}
+
+	// the third write has to be confirmed, declining aborts it
+	out, err = ts.runCmd([]string{ts.Binary, "generate", "baz", "12"}, []byte("n\n"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "Periodic recipient review")
+	assert.Contains(t, out, "[trust: ultimate]")
+
+	// a declined review is asked for again
+	_, err = ts.run("show baz")
+	assert.Error(t, err)
+	out, err = ts.runCmd([]string{ts.Binary, "generate", "baz", "12"}, []byte("n\n"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "Periodic recipient review")
+	out, err = ts.runCmd([]string{ts.Binary, "generate", "baz", "12"}, []byte("y\n"))
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Periodic recipient review")
+
+	// the counter starts over after an accepted review
+	for _, name := range []string{"qux", "corge"} {
+		out, err = ts.run("generate " + name + " 12")
+		assert.NoError(t, err, out)
+		assert.NotContains(t, out, "Periodic recipient review")
+	}
+	out, err = ts.runCmd([]string{ts.Binary, "generate", "quux", "12"}, []byte("y\n"))
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Periodic recipient review")
+}
+
+func TestRecipientsDryRun(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.run("recipients remove --dry-run 0x82EBD945BE73F104")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "secrets will be re-encrypted")
+	assert.Contains(t, out, " - AB919DBF9BF0DE74896397F282EBD945BE73F104")
+	assert.Contains(t, out, "You won't be able to decrypt the secrets afterwards")
+	assert.NotContains(t, out, "Removed")
+
+	out, err = ts.run("recipients")
+	assert.NoError(t, err)
+	assert.Contains(t, out, " - 0x82EBD945BE73F104 - ")
+
+	_, err = ts.run("recipients remove --dry-run --all 0x82EBD945BE73F104")
+	assert.Error(t, err)
+}
+
+func TestFixGPGID(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	_, err := ts.run("config loadkeys false")
+	require.NoError(t, err)
+	_, err = ts.runCmd([]string{ts.Binary, "insert", "foo"}, []byte("bar"))
+	require.NoError(t, err)
+
+	out, err := ts.run("fix-gpg-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "No stale recipients", out)
+
+	fh, err := os.OpenFile(filepath.Join(ts.storeDir(), ".gpg-id"), os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = fh.WriteString("DEADBEEF\n")
+	require.NoError(t, err)
+	require.NoError(t, fh.Close())
+
+	out, err = ts.run("fix-gpg-id")
+	assert.NoError(t, err)
+	assert.Contains(t, out, " - DEADBEEF (missing) in .gpg-id")
+	assert.Contains(t, out, "Removed 1 stale recipients and re-encrypted 1 secrets")
+
+	out, err = ts.run("recipients")
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "DEADBEEF")
+	out, err = ts.run("show foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", out)
+}
+
+func TestRecipientsPin(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.run("recipients pin")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "- AB919DBF9BF0DE74896397F282EBD945BE73F104: 0x82EBD945BE73F104 - ")
+	assert.Contains(t, out, "Pinned the keys of 1 recipients")
+	_, err = os.Stat(filepath.Join(ts.storeDir(), ".gpg-pins"))
+	assert.True(t, os.IsNotExist(err))
+	buf, err := ioutil.ReadFile(ts.gopassConfig())
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), "keypins:\n  \"\":\n    AB919DBF9BF0DE74896397F282EBD945BE73F104: AB919DBF9BF0DE74896397F282EBD945BE73F104\n")
+
+	_, err = ts.runCmd([]string{ts.Binary, "insert", "-f", "foo/bar"}, []byte("baz"))
+	assert.NoError(t, err)
+
+	// a substituted key blocks encrypting for it
+	cfg := strings.Replace(string(buf), "AB919DBF9BF0DE74896397F282EBD945BE73F104: AB919DBF9BF0DE74896397F282EBD945BE73F104", "AB919DBF9BF0DE74896397F282EBD945BE73F104: DEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF", 1)
+	require.NoError(t, ioutil.WriteFile(ts.gopassConfig(), []byte(cfg), 0600))
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "-f", "foo/bar"}, []byte("qux"))
+	assert.Error(t, err)
+	assert.Contains(t, out, "KEY PIN MISMATCH")
+	out, err = ts.run("show foo/bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", out)
+}
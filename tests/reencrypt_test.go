@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReencrypt(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	out, err := ts.run("git init --sign-key BE73F104")
+	require.NoError(t, err, out)
+	ts.initializeSecrets()
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "fixed/other"}, []byte("other"))
+	require.NoError(t, err, out)
+
+	_, err = ts.run("reencrypt missing")
+	assert.Error(t, err)
+
+	out, err = ts.run("git log --oneline")
+	require.NoError(t, err, out)
+	commits := len(strings.Split(out, "\n"))
+
+	out, err = ts.run("reencrypt fixed")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "Re-encrypted fixed")
+
+	// one commit for both secrets
+	out, err = ts.run("git log --oneline")
+	require.NoError(t, err, out)
+	assert.Len(t, strings.Split(out, "\n"), commits+1)
+	assert.Contains(t, out, "Re-encrypt fixed.")
+
+	out, err = ts.run("show fixed/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "moar", out)
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplace(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	for name, content := range map[string]string{
+		"work/wiki":   "old.example.com\nurl: https://wiki.old.example.com/login\nuser: gopher\n",
+		"work/mail":   "secret\nurl: https://mail.old.example.com\nnote: old.example.com\n",
+		"private/web": "secret\nurl: https://old.example.com\n",
+	} {
+		_, err := ts.runCmd([]string{ts.Binary, "insert", "-m", name}, []byte(content))
+		require.NoError(t, err)
+	}
+
+	out, err := ts.run("replace old.example.com new.example.com")
+	assert.Error(t, err)
+	assert.Contains(t, out, "Usage:")
+
+	// only whole values match with --exact
+	out, err = ts.run("replace --exact --field url https://old.example.com https://new.example.com")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "in 1 url fields of 1 secrets")
+	assert.Contains(t, out, " - private/web")
+	out, err = ts.run("show private/web")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nurl: https://new.example.com", out)
+	out, err = ts.run("replace --exact --field url old.example.com other.example.com")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "No url field is old.example.com")
+
+	out, err = ts.run("replace --field url old.example.com new.example.com work")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "in 2 url fields of 2 secrets")
+	assert.Contains(t, out, " - work/mail")
+	assert.Contains(t, out, " - work/wiki")
+	assert.NotContains(t, out, "private/web")
+	assert.NotContains(t, out, "https://")
+
+	out, err = ts.run("show work/wiki")
+	assert.NoError(t, err)
+	assert.Equal(t, "old.example.com\nurl: https://wiki.new.example.com/login\nuser: gopher", out)
+	out, err = ts.run("show work/mail")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nurl: https://mail.new.example.com\nnote: old.example.com", out)
+	out, err = ts.run("show private/web")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nurl: https://new.example.com", out)
+
+	out, err = ts.run("replace --field url nomatch other")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "No url field contains nomatch")
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotate(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.run("rotate foo")
+	assert.Error(t, err)
+	assert.Contains(t, out, "foo does not exist")
+
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "-m", "foo"}, []byte("old\nuser: gopher\n"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("rotate --no-symbols foo 32")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Rotated the password of foo")
+	// stdout is never a terminal in the tests
+	assert.Contains(t, out, "Warning: Not printing the old password, stdout is not a terminal")
+
+	out, err = ts.run("show foo")
+	assert.NoError(t, err, out)
+	lines := strings.Split(out, "\n")
+	assert.Len(t, lines[0], 32)
+	assert.Equal(t, []string{"user: gopher", "previous_passwords:", `  - "old"`}, lines[1:])
+
+	out, err = ts.run("rotate --keep 1 foo")
+	assert.NoError(t, err, out)
+	out2, err := ts.run("show foo")
+	assert.NoError(t, err, out2)
+	lines2 := strings.Split(out2, "\n")
+	assert.Equal(t, []string{"user: gopher", "previous_passwords:", `  - "` + lines[0] + `"`}, lines2[1:])
+
+	out, err = ts.run("rotate --prune foo")
+	assert.NoError(t, err, out)
+	assert.Equal(t, "Removed 1 previous passwords of foo", out)
+	out, err = ts.run("show foo")
+	assert.NoError(t, err, out)
+	assert.Equal(t, lines2[0]+"\nuser: gopher", out)
+}
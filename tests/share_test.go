@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShare(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	_, err := ts.run("share")
+	assert.Error(t, err)
+
+	_, err = ts.run("share missing BE73F104")
+	assert.Error(t, err)
+
+	_, err = ts.run("share fixed/secret nobody@example.com")
+	assert.Error(t, err)
+
+	idFile := filepath.Join(ts.storeDir(), ".gpg-id")
+	ids, err := ioutil.ReadFile(idFile)
+	require.NoError(t, err)
+
+	fn := filepath.Join(ts.tempDir, "secret.gpg")
+	out, err := ts.run("share -o " + fn + " fixed/secret BE73F104")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, fn)
+
+	buf, err := exec.Command("gpg", "--batch", "--decrypt", fn).Output()
+	require.NoError(t, err)
+	assert.Equal(t, "moar", string(buf))
+
+	// the recipients of the store are not touched
+	after, err := ioutil.ReadFile(idFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(ids), string(after))
+
+	// with -o - stdout only holds the encrypted secret
+	cmd := exec.Command(ts.Binary, "share", "-o", "-", "fixed/secret", "BE73F104")
+	cmd.Dir = ts.workDir()
+	enc, err := cmd.Output()
+	require.NoError(t, err)
+	dec := exec.Command("gpg", "--batch", "--decrypt")
+	dec.Stdin = bytes.NewReader(enc)
+	buf, err = dec.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "moar", string(buf))
+}
+
+func TestShareExpire(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	_, err := ts.run("share --expire -1h fixed/secret BE73F104")
+	assert.Error(t, err)
+
+	fn := filepath.Join(ts.tempDir, "secret.share")
+	out, err := ts.run("share --expire 24h -o " + fn + " fixed/secret BE73F104")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "The share expires on")
+
+	out, err = ts.run("unshare " + fn)
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "fixed/secret was shared by 0x82EBD945BE73F104")
+	assert.Contains(t, out, "moar")
+
+	// a plain copy is no share
+	plain := filepath.Join(ts.tempDir, "secret.gpg")
+	_, err = ts.run("share -o " + plain + " fixed/secret BE73F104")
+	require.NoError(t, err)
+	_, err = ts.run("unshare " + plain)
+	assert.Error(t, err)
+}
+
+func TestSharePins(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	_, err := ts.run("recipients pin")
+	require.NoError(t, err)
+	buf, err := ioutil.ReadFile(ts.gopassConfig())
+	require.NoError(t, err)
+
+	fn := filepath.Join(ts.tempDir, "secret.gpg")
+	out, err := ts.run("share -o " + fn + " fixed/secret AB919DBF9BF0DE74896397F282EBD945BE73F104")
+	require.NoError(t, err, out)
+
+	// a substituted key blocks sharing with it
+	cfg := strings.Replace(string(buf), "AB919DBF9BF0DE74896397F282EBD945BE73F104: AB919DBF9BF0DE74896397F282EBD945BE73F104", "AB919DBF9BF0DE74896397F282EBD945BE73F104: DEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF", 1)
+	require.NoError(t, ioutil.WriteFile(ts.gopassConfig(), []byte(cfg), 0600))
+	out, err = ts.run("share -o " + fn + " fixed/secret AB919DBF9BF0DE74896397F282EBD945BE73F104")
+	assert.Error(t, err)
+	assert.Contains(t, out, "KEY PIN MISMATCH")
+}
@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowSafeContent(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "web/site"}, []byte("secret\nuser: gopher\n"))
+	assert.NoError(t, err, out)
+
+	out, err = ts.run("show --safe web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "user: gopher", out)
+
+	out, err = ts.run("show --safe fixed/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed/secret only holds a password. Use --password or --clip to show it", out)
+
+	out, err = ts.run("show --password web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", out)
+
+	out, err = ts.run("config safecontent true")
+	assert.NoError(t, err)
+	assert.Zero(t, out)
+
+	out, err = ts.run("show web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "user: gopher", out)
+
+	out, err = ts.run("show --safe --password web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", out)
+
+	// stdout is not a terminal, so scripts can still read the secret
+	out, err = ts.run("show -o web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nuser: gopher", out)
+
+	out, err = ts.run("config safecontent false")
+	assert.NoError(t, err)
+	assert.Zero(t, out)
+
+	out, err = ts.run("show web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nuser: gopher", out)
+
+	assert.NoError(t, os.Setenv("GOPASS_SAFECONTENT", "1"))
+	defer func() {
+		_ = os.Unsetenv("GOPASS_SAFECONTENT")
+	}()
+	out, err = ts.run("show web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "user: gopher", out)
+}
+
+func TestShowNewline(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	out, err := ts.runCmd([]string{ts.Binary, "insert", "with"}, []byte("secret\n"))
+	assert.NoError(t, err, out)
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "without"}, []byte("secret"))
+	assert.NoError(t, err, out)
+
+	// the exact output, which ts.run trims
+	show := func(args ...string) string {
+		cmd := exec.Command(ts.Binary, append([]string{"show"}, args...)...)
+		cmd.Dir = ts.workDir()
+		out, err := cmd.Output()
+		assert.NoError(t, err, string(out))
+		return string(out)
+	}
+	assert.Equal(t, "secret\n", show("with"))
+	assert.Equal(t, "secret\n", show("without"))
+	assert.Equal(t, "secret\n", show("-n", "with"))
+	assert.Equal(t, "secret", show("-n", "without"))
+	assert.Equal(t, "secret", show("--no-newline", "--password", "with"))
+
+	// piping a secret into insert again doesn't change it
+	cmd := exec.Command(ts.Binary, "show", "-n", "without")
+	cmd.Dir = ts.workDir()
+	buf, err := cmd.Output()
+	assert.NoError(t, err)
+	out, err = ts.runCmd([]string{ts.Binary, "insert", "copy"}, buf)
+	assert.NoError(t, err, out)
+	assert.Equal(t, "secret", show("-n", "copy"))
+
+	out, err = ts.run("config nonewline true")
+	assert.NoError(t, err, out)
+	assert.Equal(t, "secret", show("without"))
+	assert.Equal(t, "secret", show("--password", "with"))
+	assert.Equal(t, "secret\n", show("with"))
+}
+
+func TestShowAs(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.run("show --as AB919DBF9BF0DE74896397F282EBD945BE73F104 fixed/secret")
+	assert.NoError(t, err, out)
+	assert.Equal(t, "fixed/secret decrypts with AB919DBF9BF0DE74896397F282EBD945BE73F104", out)
+
+	out, err = ts.run("show --as DEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF fixed/secret")
+	assert.Error(t, err)
+	assert.Contains(t, out, "can't test fixed/secret with DEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF, the secret key is not in your keyring")
+
+	out, err = ts.run("show --as AB919DBF9BF0DE74896397F282EBD945BE73F104 fixed/missing")
+	assert.Error(t, err)
+	assert.NotContains(t, out, "decrypts with")
+}
+
+func TestShowReveal(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	// stdout isn't a terminal, so the password is never printed
+	out, err := ts.run("show --reveal 1 fixed/secret")
+	assert.Equal(t, 2, exitCode(t, err))
+	assert.Contains(t, out, "--reveal only works if stdout is a terminal")
+	assert.NotContains(t, out, "moar")
+}
+
+func TestShowQueue(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	_, err := ts.runCmd([]string{ts.Binary, "insert", "-m", "web/site"}, []byte("secret\nurl: example.com\nuser: gopher\n"))
+	require.NoError(t, err)
+	_, err = ts.run("config cliptimeout 2")
+	require.NoError(t, err)
+
+	// a fake clipboard tool, sharing the clipboard in a file
+	bin := filepath.Join(ts.tempDir, "bin")
+	require.NoError(t, os.MkdirAll(bin, 0700))
+	clip := filepath.Join(ts.tempDir, "clipboard")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bin, "pbcopy"), []byte("#!/bin/sh\ncat > "+clip+"\n"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bin, "pbpaste"), []byte("#!/bin/sh\ncat "+clip+"\n"), 0755))
+	for k, v := range map[string]string{
+		"PATH":                     bin + string(os.PathListSeparator) + os.Getenv("PATH"),
+		"GOPASS_CLIPBOARD_BACKEND": "pbcopy",
+		"GOPASS_CLIP_SESSION":      "test",
+	} {
+		old := os.Getenv(k)
+		require.NoError(t, os.Setenv(k, v))
+		defer func(k string) {
+			_ = os.Setenv(k, old)
+		}(k)
+	}
+	clipboard := func() string {
+		buf, err := ioutil.ReadFile(clip)
+		require.NoError(t, err)
+		return string(buf)
+	}
+
+	out, err := ts.run("show --queue web/site")
+	assert.NoError(t, err)
+	assert.Equal(t, "Copied user of web/site to clipboard. Will clear in 2 seconds. Run `gopass next` to copy password.", out)
+	assert.Equal(t, "gopher", clipboard())
+	out, err = ts.run("next")
+	assert.NoError(t, err)
+	assert.Equal(t, "Copied password of web/site to clipboard. Will clear in 2 seconds.", out)
+	assert.Equal(t, "secret", clipboard())
+	_, err = ts.run("next")
+	assert.Error(t, err)
+
+	// the fields can be given, the queue is kept out of the store
+	out, err = ts.run("-c --queue web/site url user")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Run `gopass next` to copy user.")
+	assert.Equal(t, "example.com", clipboard())
+	out, err = ts.run("list")
+	assert.NoError(t, err)
+	assert.Equal(t, "gopass\n└── web\n    └── site", out)
+
+	_, err = ts.run("show --queue web/site foo")
+	assert.Error(t, err)
+
+	// the queue expires with the clipboard, which is cleared
+	time.Sleep(3 * time.Second)
+	_, err = ts.run("next")
+	assert.Error(t, err)
+	assert.Equal(t, "", clipboard())
+}
+
+func TestShowFieldRefs(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	_, err := ts.runCmd([]string{ts.Binary, "insert", "-m", "shared"}, []byte("sharedpw\ntoken: t0k3n\n"))
+	require.NoError(t, err)
+	_, err = ts.runCmd([]string{ts.Binary, "insert", "-m", "app"}, []byte("secret\nuser: gopher\nlogin: gopass://#user\ntoken: gopass://shared#token\n"))
+	require.NoError(t, err)
+
+	out, err := ts.run("show app")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nuser: gopher\nlogin: gopher\ntoken: t0k3n", out)
+
+	out, err = ts.run("show --raw app")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret\nuser: gopher\nlogin: gopass://#user\ntoken: gopass://shared#token", out)
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerify(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.run("sign fixed/secret")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "-----BEGIN PGP SIGNATURE-----")
+	assert.NotContains(t, out, "moar")
+
+	sig := filepath.Join(ts.tempDir, "secret.asc")
+	out, err = ts.run("sign --output " + sig + " fixed/secret")
+	assert.NoError(t, err, out)
+	assert.Equal(t, "Wrote the signature of fixed/secret to "+sig, out)
+	buf, err := ioutil.ReadFile(sig)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(buf), "moar")
+
+	out, err = ts.run("verify fixed/secret " + sig)
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "Good signature of fixed/secret by 0x82EBD945BE73F104")
+
+	// the signature doesn't match another secret
+	out, err = ts.run("verify baz " + sig)
+	assert.Error(t, err)
+	assert.Contains(t, out, "invalid signature")
+
+	out, err = ts.run("sign")
+	assert.Error(t, err)
+	assert.Contains(t, out, "Usage:")
+}
+
+func TestVerifyStore(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	ts.initializeSecrets()
+
+	out, err := ts.run("verify --all")
+	assert.NoError(t, err, out)
+	assert.Contains(t, out, "All secrets can be decrypted with your keys")
+	assert.NotContains(t, out, "moar")
+
+	// a secret that isn't a valid ciphertext
+	err = ioutil.WriteFile(filepath.Join(ts.storeDir(), "broken.gpg"), []byte("garbage"), 0600)
+	assert.NoError(t, err)
+	out, err = ts.run("verify --all")
+	assert.Error(t, err)
+	assert.Contains(t, out, "Can not decrypt broken")
+	assert.Contains(t, out, "1 of")
+
+	// a forgotten signature file doesn't start checking the whole store
+	for _, args := range []string{"verify", "verify foo"} {
+		out, err = ts.run(args)
+		assert.Error(t, err)
+		assert.Contains(t, out, "Usage: gopass verify <name> <signature> | --all")
+		assert.NotContains(t, out, "Decrypting")
+	}
+	out, err = ts.run("verify --all foo")
+	assert.Error(t, err)
+	assert.Contains(t, out, "takes no arguments")
+}
@@ -62,6 +62,11 @@ func newTester(t *testing.T) *tester {
 	_ = os.Setenv("GOPASS_DEBUG", "false")
 	_ = os.Setenv("GOPASS_NOCOLOR", "true")
 	_ = os.Setenv("GOPASS_CONFIG", ts.gopassConfig())
+	_ = os.Setenv("GOPASS_PROFILES", filepath.Join(ts.tempDir, "profiles.yml"))
+	for _, k := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		_ = os.Setenv(k+"_NAME", "gopass tester")
+		_ = os.Setenv(k+"_EMAIL", "tester@example.com")
+	}
 
 	// write config
 	if err := ioutil.WriteFile(ts.gopassConfig(), []byte(gopassConfig+"\npath: "+ts.storeDir()+"\n"), 0600); err != nil {
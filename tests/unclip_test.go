@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnclipDebug(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+
+	require.NoError(t, os.Setenv("GOPASS_UNCLIP_CHECKSUM", "deadbeef"))
+	defer func() {
+		_ = os.Unsetenv("GOPASS_UNCLIP_CHECKSUM")
+	}()
+
+	// the outcome depends on the availability of a clipboard
+	out, _ := ts.run("unclip --timeout 0")
+	assert.NotContains(t, out, "[unclip]")
+
+	require.NoError(t, os.Setenv("GOPASS_DEBUG_UNCLIP", "1"))
+	defer func() {
+		_ = os.Unsetenv("GOPASS_DEBUG_UNCLIP")
+	}()
+
+	out, _ = ts.run("unclip --timeout 0")
+	assert.Contains(t, out, "[unclip] expecting checksum deadbeef\n[unclip] waiting 0 seconds\n")
+	assert.Regexp(t, `\[unclip\] (failed to read clipboard|checksum mismatch)`, out)
+}
+
+func TestUnclipForce(t *testing.T) {
+	ts := newTester(t)
+	defer ts.teardown()
+
+	ts.initializeStore()
+	_, err := ts.runCmd([]string{ts.Binary, "insert", "-m", "web/site"}, []byte("secret\nuser: gopher\n"))
+	require.NoError(t, err)
+
+	// a fake clipboard tool, sharing the clipboard in a file
+	bin := filepath.Join(ts.tempDir, "bin")
+	require.NoError(t, os.MkdirAll(bin, 0700))
+	clip := filepath.Join(ts.tempDir, "clipboard")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bin, "pbcopy"), []byte("#!/bin/sh\ncat > "+clip+"\n"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bin, "pbpaste"), []byte("#!/bin/sh\ncat "+clip+"\n"), 0755))
+	for k, v := range map[string]string{
+		"PATH":                     bin + string(os.PathListSeparator) + os.Getenv("PATH"),
+		"GOPASS_CLIPBOARD_BACKEND": "pbcopy",
+		"GOPASS_CLIP_SESSION":      "test",
+		"XDG_RUNTIME_DIR":          ts.tempDir,
+	} {
+		old := os.Getenv(k)
+		require.NoError(t, os.Setenv(k, v))
+		defer func(k string) {
+			_ = os.Setenv(k, old)
+		}(k)
+	}
+	clipboard := func() string {
+		buf, err := ioutil.ReadFile(clip)
+		require.NoError(t, err)
+		return string(buf)
+	}
+
+	// both copies are recognized, not just the last one
+	_, err = ts.run("show --queue web/site")
+	require.NoError(t, err)
+	_, err = ts.run("next")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", clipboard())
+	require.NoError(t, ioutil.WriteFile(clip, []byte("gopher"), 0600))
+	out, err := ts.run("unclip --force")
+	assert.NoError(t, err)
+	assert.Equal(t, "Cleared the clipboard", out)
+	assert.Equal(t, "", clipboard())
+
+	require.NoError(t, ioutil.WriteFile(clip, []byte("other"), 0600))
+	out, err = ts.run("unclip --force")
+	assert.NoError(t, err)
+	assert.Equal(t, "The clipboard holds nothing gopass copied recently", out)
+	assert.Equal(t, "other", clipboard())
+}
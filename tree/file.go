@@ -3,10 +3,14 @@ package tree
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 )
 
 // File is a leaf node in the tree
-type File string
+type File struct {
+	Name  string   // Name is the displayed name of this file
+	Notes []string // Notes are shown after the name when pretty printing
+}
 
 // IsFile always returns true
 func (f File) IsFile() bool { return true }
@@ -19,17 +23,17 @@ func (f File) IsMount() bool { return false }
 
 // Add always returns an error
 func (f File) Add(Entry) error {
-	return fmt.Errorf("%s is a file", f)
+	return fmt.Errorf("%s is a file", f.Name)
 }
 
 // String implement fmt.Stringer
 func (f File) String() string {
-	return string(f)
+	return f.Name
 }
 
 // list returns the full path to this leaf node
 func (f File) list(prefix string) []string {
-	return []string{filepath.Join(prefix, string(f))}
+	return []string{filepath.Join(prefix, f.Name)}
 }
 
 // format will format this leaf node for pretty printing
@@ -38,5 +42,9 @@ func (f File) format(prefix string, last bool) string {
 	if last {
 		sym = symLeaf
 	}
-	return prefix + sym + string(f) + "\n"
+	out := prefix + sym + f.Name
+	if len(f.Notes) > 0 {
+		out += " " + colNote("("+strings.Join(f.Notes, ", ")+")")
+	}
+	return out + "\n"
 }
@@ -44,6 +44,24 @@ func (f *Folder) AddFile(name string) error {
 	return f.addFile(strings.Split(name, string(filepath.Separator)))
 }
 
+// AddNote adds a note to an existing file, e.g. to mark it as special. Notes
+// are only shown when pretty printing.
+func (f *Folder) AddNote(name, note string) error {
+	path := strings.Split(name, string(filepath.Separator))
+	folder := f.findFolder(path[:len(path)-1])
+	if folder == nil {
+		return fmt.Errorf("File %s not found", name)
+	}
+	e, found := folder.Entries[path[len(path)-1]]
+	if !found || !e.IsFile() {
+		return fmt.Errorf("File %s not found", name)
+	}
+	file := e.(File)
+	file.Notes = append(file.Notes, note)
+	folder.Entries[file.Name] = file
+	return nil
+}
+
 // AddMount adds a new mount
 func (f *Folder) AddMount(name, path string) error {
 	return f.addMount(strings.Split(name, string(filepath.Separator)), path)
@@ -163,7 +181,7 @@ func (f *Folder) addFile(path []string) error {
 		if _, found := f.Entries[name]; found {
 			return fmt.Errorf("File %s exists", name)
 		}
-		f.Entries[name] = File(name)
+		f.Entries[name] = File{Name: name}
 		return nil
 	}
 	next := f.getFolder(name)
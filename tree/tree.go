@@ -16,6 +16,7 @@ const (
 var (
 	colMount = color.New(color.FgRed, color.Bold).SprintfFunc()
 	colDir   = color.New(color.FgBlue, color.Bold).SprintfFunc()
+	colNote  = color.New(color.FgCyan).SprintfFunc()
 )
 
 // Entry is any kind of tree node
@@ -88,3 +88,37 @@ func TestFormatSubtree(t *testing.T) {
 		t.Errorf("Format mismatch: %s vs %s", want, got)
 	}
 }
+
+func TestAddNote(t *testing.T) {
+	color.NoColor = true
+	root := New("gopass")
+	for _, f := range []string{"a/b", "c"} {
+		if err := root.AddFile(f); err != nil {
+			t.Fatalf("failed to add file: %s", err)
+		}
+	}
+	if err := root.AddNote("a/b", "1 attachment"); err != nil {
+		t.Fatalf("failed to add note: %s", err)
+	}
+	if err := root.AddNote("c", "x"); err != nil {
+		t.Fatalf("failed to add note: %s", err)
+	}
+	if err := root.AddNote("c", "y"); err != nil {
+		t.Fatalf("failed to add note: %s", err)
+	}
+	if err := root.AddNote("a", "folder"); err == nil {
+		t.Errorf("should not add a note to a folder")
+	}
+	if err := root.AddNote("a/d", "missing"); err == nil {
+		t.Errorf("should not add a note to a missing file")
+	}
+
+	want := "gopass\n├── a\n│   └── b (1 attachment)\n└── c (x, y)\n"
+	if got := root.Format(); got != want {
+		t.Errorf("Mismatch:\n%s\n!=\n%s", got, want)
+	}
+	// notes must not be part of the flat list
+	if got := strings.Join(root.List(), ","); got != "a/b,c" {
+		t.Errorf("Mismatch: %s", got)
+	}
+}